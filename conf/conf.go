@@ -45,6 +45,7 @@ type Configuration struct {
 	BuildDate          string `validate:"require"` //description:"Display build date of copernicus"
 	DataDir            string `default:"data"`
 	Reindex            bool
+	LoadBlock          []string
 	Excessiveblocksize uint64
 
 	// Service struct {
@@ -59,8 +60,8 @@ type Configuration struct {
 		RPCCert              string   `default:""` //File containing the certificate file
 		RPCKey               string   //File containing the certificate key
 		RPCMaxClients        int      //Max number of RPC clients for standard connections
-		RPCMaxWebsockets     int      //Max number of RPC websocket connections
-		RPCMaxConcurrentReqs int      //Max number of concurrent RPC requests that may be processed concurrently
+		RPCMaxWebsockets     int      `default:"25"` //Max number of RPC websocket connections
+		RPCMaxConcurrentReqs int      `default:"20"` //Max number of concurrent RPC requests that may be processed concurrently
 		RPCQuirks            bool     //Mirror some JSON-RPC quirks of Bitcoin Core -- NOTE: Discouraged unless interoperability issues need to be worked around
 	}
 	Log struct {
@@ -77,6 +78,7 @@ type Configuration struct {
 		MaxPoolSize          int64  `default:"300000000"` // Default for MaxPoolSize, maximum megabytes of mempool memory usage
 		MaxPoolExpiry        int    `default:"336"`       // Default for -mempoolexpiry, expiration time for mempool transactions in hours
 		CheckFrequency       uint64 `default:"4294967296"`
+		PersistMempool       bool   `default:"true"` // Default for -persistmempool, save the mempool on shutdown and load it on restart
 	}
 	P2PNet struct {
 		ListenAddrs         []string `validate:"require" default:"1234"`
@@ -109,6 +111,7 @@ type Configuration struct {
 	}
 	Protocol struct {
 		NoPeerBloomFilters bool `default:"true"`
+		PeerBlockFilters   bool
 		DisableCheckpoints bool `default:"true"`
 	}
 	Script struct {
@@ -124,8 +127,16 @@ type Configuration struct {
 	}
 	Chain struct {
 		AssumeValid         string
+		AssumeUTXO          string
 		UtxoHashStartHeight int32 `default:"-1"`
 		UtxoHashEndHeight   int32 `default:"-1"`
+		TxIndex             bool
+		AddressIndex        bool
+		SpentIndex          bool
+		BlockFilterIndex    bool
+		CheckBlocks         int32 // 0 skips the check at startup
+		CheckLevel          int32 `default:"3"`
+		MaxReorgDepth       int32 `default:"10"` // 0 disables the automatic finalization
 	}
 	Mining struct {
 		BlockMinTxFee int64  // default DefaultBlockMinTxFee
@@ -143,6 +154,13 @@ type Configuration struct {
 		Enable              bool `default:"false"`
 		Broadcast           bool `default:"false"`
 		SpendZeroConfChange bool `default:"true"`
+		KeyPool             int  `default:"100"` // Number of keys derived ahead of use on each key chain
+	}
+	ZMQ struct {
+		PubHashBlock string // Enable publishing of block hashes to <address>, e.g. tcp://127.0.0.1:28332
+		PubHashTx    string // Enable publishing of transaction hashes to <address>
+		PubRawBlock  string // Enable publishing of raw blocks to <address>
+		PubRawTx     string // Enable publishing of raw transactions to <address>
 	}
 }
 
@@ -256,6 +274,7 @@ func InitConfig(args []string) *Configuration {
 	// set data dir
 	config.DataDir = DataDir
 	config.Reindex = opts.Reindex
+	config.LoadBlock = opts.LoadBlock
 	config.Excessiveblocksize = opts.Excessiveblocksize
 	config.Mempool.LimitAncestorCount = opts.Limitancestorcount
 	config.Script.PromiscuousMempoolFlags = opts.PromiscuousMempoolFlags
@@ -296,6 +315,12 @@ func InitConfig(args []string) *Configuration {
 	if opts.SpendZeroConfChange == 0 {
 		config.Wallet.SpendZeroConfChange = false
 	}
+	if opts.KeyPool > 0 {
+		config.Wallet.KeyPool = opts.KeyPool
+	}
+	if opts.PersistMempool == 0 {
+		config.Mempool.PersistMempool = false
+	}
 	if opts.BanScore > 0 {
 		config.P2PNet.BanThreshold = opts.BanScore
 	}
@@ -305,6 +330,52 @@ func InitConfig(args []string) *Configuration {
 	if len(opts.AssumeValid) > 0 {
 		config.Chain.AssumeValid = opts.AssumeValid
 	}
+	if len(opts.AssumeUTXO) > 0 {
+		config.Chain.AssumeUTXO = opts.AssumeUTXO
+	}
+	if opts.TxIndex {
+		config.Chain.TxIndex = true
+	}
+	if opts.AddressIndex {
+		config.Chain.AddressIndex = true
+	}
+	if opts.SpentIndex {
+		config.Chain.SpentIndex = true
+	}
+	if opts.BlockFilterIndex {
+		config.Chain.BlockFilterIndex = true
+	}
+	if opts.PeerBloomFilters {
+		config.Protocol.NoPeerBloomFilters = false
+	}
+	if opts.PeerBlockFilters {
+		config.Protocol.PeerBlockFilters = true
+	}
+	if config.Protocol.PeerBlockFilters && !config.Chain.BlockFilterIndex {
+		println("Error: Cannot set -peerblockfilters without -blockfilterindex")
+		return nil
+	}
+	if opts.CheckBlocks > 0 {
+		config.Chain.CheckBlocks = opts.CheckBlocks
+	}
+	if opts.CheckLevel >= 0 {
+		config.Chain.CheckLevel = opts.CheckLevel
+	}
+	if opts.MaxReorgDepth >= 0 {
+		config.Chain.MaxReorgDepth = opts.MaxReorgDepth
+	}
+	if len(opts.ZMQPubHashBlock) > 0 {
+		config.ZMQ.PubHashBlock = opts.ZMQPubHashBlock
+	}
+	if len(opts.ZMQPubHashTx) > 0 {
+		config.ZMQ.PubHashTx = opts.ZMQPubHashTx
+	}
+	if len(opts.ZMQPubRawBlock) > 0 {
+		config.ZMQ.PubRawBlock = opts.ZMQPubRawBlock
+	}
+	if len(opts.ZMQPubRawTx) > 0 {
+		config.ZMQ.PubRawTx = opts.ZMQPubRawTx
+	}
 
 	return config
 }
@@ -204,12 +204,14 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 			RPCCert              string `default:""`
 			RPCKey               string
 			RPCMaxClients        int
-			RPCMaxWebsockets     int
-			RPCMaxConcurrentReqs int
+			RPCMaxWebsockets     int `default:"25"`
+			RPCMaxConcurrentReqs int `default:"20"`
 			RPCQuirks            bool
 		}{
-			RPCCert: filepath.Join(defaultDataDir, "rpc.cert"),
-			RPCKey:  filepath.Join(defaultDataDir, "rpc.key"),
+			RPCCert:              filepath.Join(defaultDataDir, "rpc.cert"),
+			RPCKey:               filepath.Join(defaultDataDir, "rpc.key"),
+			RPCMaxWebsockets:     25,
+			RPCMaxConcurrentReqs: 20,
 		},
 		Mempool: struct {
 			MinFeeRate           int64  //
@@ -220,11 +222,13 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 			MaxPoolSize          int64  `default:"300000000"` // Default for MaxPoolSize, maximum megabytes of mempool memory usage
 			MaxPoolExpiry        int    `default:"336"`       // Default for -mempoolexpiry, expiration time for mempool transactions in hours
 			CheckFrequency       uint64 `default:"4294967296"`
+			PersistMempool       bool   `default:"true"` // Default for -persistmempool, save the mempool on shutdown and load it on restart
 		}{
 			MaxPoolSize:        300000000,
 			CheckFrequency:     4294967296,
 			LimitAncestorCount: 50000,
 			MaxPoolExpiry:      336,
+			PersistMempool:     true,
 		},
 		P2PNet: struct {
 			ListenAddrs         []string `validate:"require" default:"1234"`
@@ -269,8 +273,9 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 		},
 		Protocol: struct {
 			NoPeerBloomFilters bool `default:"true"`
+			PeerBlockFilters   bool
 			DisableCheckpoints bool `default:"true"`
-		}{NoPeerBloomFilters: true, DisableCheckpoints: true},
+		}{NoPeerBloomFilters: true, PeerBlockFilters: false, DisableCheckpoints: true},
 		Script: struct {
 			AcceptDataCarrier   bool `default:"true"`
 			MaxDatacarrierBytes uint `default:"223"`
@@ -290,12 +295,28 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 		}{DustRelayFee: 83},
 		Chain: struct {
 			AssumeValid         string
+			AssumeUTXO          string
 			UtxoHashStartHeight int32 `default:"-1"`
 			UtxoHashEndHeight   int32 `default:"-1"`
+			TxIndex             bool
+			AddressIndex        bool
+			SpentIndex          bool
+			BlockFilterIndex    bool
+			CheckBlocks         int32 // 0 skips the check at startup
+			CheckLevel          int32 `default:"3"`
+			MaxReorgDepth       int32 `default:"10"` // 0 disables the automatic finalization
 		}{
 			AssumeValid:         "",
+			AssumeUTXO:          "",
 			UtxoHashStartHeight: args.UtxoHashStartHeight,
 			UtxoHashEndHeight:   args.UtxoHashEndHeight,
+			TxIndex:             false,
+			AddressIndex:        false,
+			SpentIndex:          false,
+			BlockFilterIndex:    false,
+			CheckBlocks:         0,
+			CheckLevel:          3,
+			MaxReorgDepth:       10,
 		},
 		Mining: struct {
 			BlockMinTxFee int64  // default DefaultBlockMinTxFee
@@ -319,7 +340,14 @@ func getDefaultConfiguration(args defaultArgs) *Configuration {
 			Enable              bool `default:"false"`
 			Broadcast           bool `default:"false"`
 			SpendZeroConfChange bool `default:"true"`
-		}{Enable: false, Broadcast: false, SpendZeroConfChange: true},
+			KeyPool             int  `default:"100"`
+		}{Enable: false, Broadcast: false, SpendZeroConfChange: true, KeyPool: 100},
+		ZMQ: struct {
+			PubHashBlock string
+			PubHashTx    string
+			PubRawBlock  string
+			PubRawTx     string
+		}{},
 	}
 }
 
@@ -425,6 +453,7 @@ func TestInitConfig2(t *testing.T) {
 				UtxoHashEndHeight:   1,
 				Excessiveblocksize:  32000000,
 			})},
+		{[]string{"--datadir=/tmp/Coper", "--peerblockfilters"}, nil},
 	}
 	createTmpFile()
 	defer os.RemoveAll("/tmp/Coper")
@@ -23,18 +23,41 @@ type Opts struct {
 	Whitelists         []string `long:"whitelist" description:"whitelist"`
 	Excessiveblocksize uint64   `long:"excessiveblocksize" default:"32000000" description:"excessive block size"`
 	BanScore           uint32   `long:"banscore" default:"100" description:"Threshold for disconnecting misbehaving peers"`
+	LoadBlock          []string `long:"loadblock" description:"Imports blocks from external block files on startup"`
 
 	ReplayProtectionActivationTime int64  `long:"replayprotectionactivationtime" default:"-1"`
 	MagneticAnomalyTime            int64  `long:"magneticanomalyactivationtime" default:"-1"`
+	GreatWallActivationTime        int64  `long:"greatwallactivationtime" default:"-1"`
+	GravitonActivationTime         int64  `long:"gravitonactivationtime" default:"-1"`
+	PhononActivationTime           int64  `long:"phononactivationtime" default:"-1"`
+	AxionActivationTime            int64  `long:"axionactivationtime" default:"-1"`
+	Upgrade8ActivationTime         int64  `long:"upgrade8activationtime" default:"-1"`
+	Upgrade9ActivationTime         int64  `long:"upgrade9activationtime" default:"-1"`
 	StopAtHeight                   int32  `long:"stopatheight" default:"-1"`
 	PromiscuousMempoolFlags        string `long:"promiscuousmempoolflags"`
 	Limitancestorcount             int    `long:"limitancestorcount" default:"50000"`
 	BlockVersion                   int32  `long:"blockversion" default:"-1" description:"regtest block version"`
 	MaxMempool                     int64  `long:"maxmempool" default:"300000000"`
 	SpendZeroConfChange            uint8  `long:"spendzeroconfchange" default:"1"`
+	KeyPool                        int    `long:"keypool" description:"Set key pool size to <n>"`
+	PersistMempool                 uint8  `long:"persistmempool" default:"1" description:"Whether to save the mempool on shutdown and load on restart"`
 	MaxTimeAdjustment              uint64 `long:"maxtimeadjustment" default:"4200" description:"Maximum allowed median peer time offset adjustment. Local perspective of time may be influenced by peers forward or backward by this amount."`
 	MinimumChainWork               string `long:"minimumchainwork"`
 	AssumeValid                    string `long:"assumevalid"`
+	AssumeUTXO                     string `long:"assumeutxo" description:"Hash of the UTXO snapshot the loadtxoutset rpc call accepts"`
+	TxIndex                        bool   `long:"txindex" description:"Maintain a full transaction index, used by the getrawtransaction rpc call"`
+	AddressIndex                   bool   `long:"addressindex" description:"Maintain an index of the transactions and unspent outputs of every address, used by the getaddress* rpc calls"`
+	SpentIndex                     bool   `long:"spentindex" description:"Maintain an index of the inputs spending every output, used by the getspentinfo rpc call"`
+	BlockFilterIndex               bool   `long:"blockfilterindex" description:"Maintain an index of the basic compact block filters (BIP158), used by the getblockfilter rpc call"`
+	PeerBloomFilters               bool   `long:"peerbloomfilters" description:"Support filtering of blocks and transactions with bloom filters"`
+	PeerBlockFilters               bool   `long:"peerblockfilters" description:"Serve compact block filters to peers (BIP157), requires --blockfilterindex"`
+	CheckBlocks                    int32  `long:"checkblocks" description:"How many blocks to check at startup (0 to skip)"`
+	CheckLevel                     int32  `long:"checklevel" default:"-1" description:"How thorough the block verification of -checkblocks is (0-4, default: 3)"`
+	MaxReorgDepth                  int32  `long:"maxreorgdepth" default:"-1" description:"At what depth blocks of the active chain are finalized, 0 to disable (default: 10)"`
+	ZMQPubHashBlock                string `long:"zmqpubhashblock" description:"Enable publish hash block in <address>"`
+	ZMQPubHashTx                   string `long:"zmqpubhashtx" description:"Enable publish hash transaction in <address>"`
+	ZMQPubRawBlock                 string `long:"zmqpubrawblock" description:"Enable publish raw block in <address>"`
+	ZMQPubRawTx                    string `long:"zmqpubrawtx" description:"Enable publish raw transaction in <address>"`
 }
 
 func InitArgs(args []string) (*Opts, error) {
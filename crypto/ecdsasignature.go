@@ -11,6 +11,7 @@ const (
 	SigHashAll          = 1
 	SigHashNone         = 2
 	SigHashSingle       = 3
+	SigHashUtxos        = 0x20 // commits to the outputs spent, defined once tokens are enabled
 	SigHashForkID       = 0x40
 	SigHashAnyoneCanpay = 0x80
 
@@ -0,0 +1,148 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// HardenedKeyStart is the index of the first hardened child key.
+	HardenedKeyStart = 0x80000000
+
+	// MinSeedBytes and MaxSeedBytes bound the length of a BIP32 seed.
+	MinSeedBytes = 16
+	MaxSeedBytes = 64
+)
+
+var (
+	ErrInvalidSeedLen = errors.Errorf("seed length must be between %d and %d bytes", MinSeedBytes, MaxSeedBytes)
+	ErrUnusableSeed   = errors.New("unusable seed")
+	ErrInvalidChild   = errors.New("the extended key at this index is invalid")
+)
+
+var (
+	masterKeyHMACKey = []byte("Bitcoin seed")
+
+	// secp256k1Order is the order n of the secp256k1 group.
+	secp256k1Order, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+)
+
+// ExtendedKey is a BIP32 extended private key.
+type ExtendedKey struct {
+	key       []byte
+	chainCode []byte
+	depth     uint8
+	childNum  uint32
+}
+
+// NewMasterExtendedKey derives the BIP32 master key from a seed.
+func NewMasterExtendedKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < MinSeedBytes || len(seed) > MaxSeedBytes {
+		return nil, ErrInvalidSeedLen
+	}
+
+	mac := hmac.New(sha512.New, masterKeyHMACKey)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	keyNum := new(big.Int).SetBytes(sum[:32])
+	if keyNum.Sign() == 0 || keyNum.Cmp(secp256k1Order) >= 0 {
+		return nil, ErrUnusableSeed
+	}
+	return &ExtendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// Child derives the child extended key at the index.  Indexes from
+// HardenedKeyStart on derive hardened keys.  ErrInvalidChild is returned for
+// the rare indexes without a valid key, which should be skipped.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= HardenedKeyStart {
+		data = append(data, 0x00)
+		data = append(data, k.key...)
+	} else {
+		pubKey := k.PrivateKey().PubKey()
+		if pubKey == nil {
+			return nil, ErrInvalidChild
+		}
+		data = append(data, pubKey.SerializeCompressed()...)
+	}
+	var indexBytes [4]byte
+	binary.BigEndian.PutUint32(indexBytes[:], index)
+	data = append(data, indexBytes[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(secp256k1Order) >= 0 {
+		return nil, ErrInvalidChild
+	}
+	keyNum := new(big.Int).SetBytes(k.key)
+	keyNum.Add(keyNum, tweak)
+	keyNum.Mod(keyNum, secp256k1Order)
+	if keyNum.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+
+	childKey := make([]byte, PrivateKeyBytesLen)
+	keyBytes := keyNum.Bytes()
+	copy(childKey[PrivateKeyBytesLen-len(keyBytes):], keyBytes)
+
+	return &ExtendedKey{
+		key:       childKey,
+		chainCode: sum[32:],
+		depth:     k.depth + 1,
+		childNum:  index,
+	}, nil
+}
+
+// DerivePath derives the descendant extended key along the path of indexes.
+func (k *ExtendedKey) DerivePath(path []uint32) (*ExtendedKey, error) {
+	key := k
+	for _, index := range path {
+		var err error
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// PrivateKey returns the compressed private key of the extended key.
+func (k *ExtendedKey) PrivateKey() *PrivateKey {
+	return NewPrivateKeyFromBytes(k.key, true)
+}
+
+func (k *ExtendedKey) ChainCode() []byte {
+	return k.chainCode
+}
+
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+func (k *ExtendedKey) ChildNum() uint32 {
+	return k.childNum
+}
+
+// FormatKeyPath returns the path of indexes in the m/44'/145'/0'/0/1 notation.
+func FormatKeyPath(path []uint32) string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, index := range path {
+		if index >= HardenedKeyStart {
+			fmt.Fprintf(&b, "/%d'", index-HardenedKeyStart)
+		} else {
+			fmt.Fprintf(&b, "/%d", index)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtendedKeyDerivation(t *testing.T) {
+	InitSecp256()
+
+	tests := []struct {
+		seed      string
+		path      []uint32
+		key       string
+		chainCode string
+	}{
+		// BIP32 test vector 1
+		{
+			seed:      "000102030405060708090a0b0c0d0e0f",
+			path:      []uint32{},
+			key:       "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+			chainCode: "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508",
+		},
+		{
+			seed: "000102030405060708090a0b0c0d0e0f",
+			path: []uint32{HardenedKeyStart},
+			key:  "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+		},
+		{
+			seed: "000102030405060708090a0b0c0d0e0f",
+			path: []uint32{HardenedKeyStart, 1},
+			key:  "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368",
+		},
+		{
+			seed: "000102030405060708090a0b0c0d0e0f",
+			path: []uint32{HardenedKeyStart, 1, HardenedKeyStart + 2},
+			key:  "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca",
+		},
+		{
+			seed: "000102030405060708090a0b0c0d0e0f",
+			path: []uint32{HardenedKeyStart, 1, HardenedKeyStart + 2, 2, 1000000000},
+			key:  "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8",
+		},
+		// BIP32 test vector 2
+		{
+			seed: "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c99969390" +
+				"8d8a8784817e7b7875726f6c696663605d5a5754514e4b484542",
+			path: []uint32{},
+			key:  "4b03d6fc340455b363f51020ad3ecca4f0850280cf436c70c727923f6db46c3e",
+		},
+		{
+			seed: "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c99969390" +
+				"8d8a8784817e7b7875726f6c696663605d5a5754514e4b484542",
+			path: []uint32{0},
+			key:  "abe74a98f6c7eabee0428f53798f0ab8aa1bd37873999041703c742f15ac7e1e",
+		},
+		{
+			seed: "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c99969390" +
+				"8d8a8784817e7b7875726f6c696663605d5a5754514e4b484542",
+			path: []uint32{0, HardenedKeyStart + 2147483647},
+			key:  "877c779ad9687164e9c2f4f0f4ff0340814392330693ce95a58fe18fd52e6e93",
+		},
+	}
+
+	for i, test := range tests {
+		seed, _ := hex.DecodeString(test.seed)
+		master, err := NewMasterExtendedKey(seed)
+		assert.NoError(t, err, "test %d", i)
+
+		key, err := master.DerivePath(test.path)
+		assert.NoError(t, err, "test %d", i)
+		assert.Equal(t, test.key, hex.EncodeToString(key.PrivateKey().GetBytes()), "test %d", i)
+		assert.Equal(t, uint8(len(test.path)), key.Depth(), "test %d", i)
+		assert.True(t, key.PrivateKey().IsCompressed())
+		if test.chainCode != "" {
+			assert.Equal(t, test.chainCode, hex.EncodeToString(key.ChainCode()), "test %d", i)
+		}
+	}
+}
+
+func TestNewMasterExtendedKeySeedLen(t *testing.T) {
+	_, err := NewMasterExtendedKey(make([]byte, MinSeedBytes-1))
+	assert.Equal(t, ErrInvalidSeedLen, err)
+	_, err = NewMasterExtendedKey(make([]byte, MaxSeedBytes+1))
+	assert.Equal(t, ErrInvalidSeedLen, err)
+}
+
+func TestFormatKeyPath(t *testing.T) {
+	assert.Equal(t, "m", FormatKeyPath(nil))
+	assert.Equal(t, "m/44'/145'/0'/1/7",
+		FormatKeyPath([]uint32{HardenedKeyStart + 44, HardenedKeyStart + 145, HardenedKeyStart, 1, 7}))
+}
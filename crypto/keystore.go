@@ -90,3 +90,22 @@ func (ks *KeyStore) AddKeyPairs(keys []*KeyPair) {
 		ks.keys[keyPair.GetKeyID()] = keyPair
 	}
 }
+
+func (ks *KeyStore) GetAllKeyPairs() []*KeyPair {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	keys := make([]*KeyPair, 0, len(ks.keys))
+	for _, keyPair := range ks.keys {
+		keys = append(keys, keyPair)
+	}
+	return keys
+}
+
+// Clear removes all the keys from the key store.
+func (ks *KeyStore) Clear() {
+	ks.Lock()
+	defer ks.Unlock()
+
+	ks.keys = make(map[string]*KeyPair)
+}
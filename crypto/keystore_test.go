@@ -85,3 +85,16 @@ func TestKeyStore_AddKeyPairs(t *testing.T) {
 	keyStoreNew.AddKeyPairs(keyPairs)
 	assert.Equal(t, keyStore, keyStoreNew)
 }
+
+func TestKeyStore_Clear(t *testing.T) {
+	privateKey := getTestPrivateKey()
+	keyHash := privateKey.PubKey().ToHash160()
+
+	keyStore := NewKeyStore()
+	keyStore.AddKey(privateKey)
+	assert.Equal(t, 1, len(keyStore.GetAllKeyPairs()))
+
+	keyStore.Clear()
+	assert.Equal(t, 0, len(keyStore.GetAllKeyPairs()))
+	assert.Nil(t, keyStore.GetKeyPair(keyHash))
+}
@@ -0,0 +1,266 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/copernet/copernicus/util"
+)
+
+// SchnorrSignatureLen is the length of a Schnorr signature, without the
+// sighash type byte appended to transaction signatures.
+const SchnorrSignatureLen = 64
+
+// schnorrAlgo16 is the algorithm tag mixed into the RFC6979 nonce so that
+// Schnorr and ECDSA signatures never share a nonce for the same key and message.
+var schnorrAlgo16 = []byte("Schnorr+SHA256  ")
+
+var (
+	errSchnorrInvalidKey = errors.New("schnorr: invalid private key")
+
+	curveP  = fromHex("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f")
+	curveN  = fromHex("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+	curveGx = fromHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	curveGy = fromHex("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8")
+)
+
+func fromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex in source: " + s)
+	}
+	return n
+}
+
+// jacobianPoint is a secp256k1 point in jacobian coordinates, x = X/Z^2 and
+// y = Y/Z^3. The point at infinity has Z = 0.
+type jacobianPoint struct {
+	x, y, z *big.Int
+}
+
+func newAffinePoint(x, y *big.Int) *jacobianPoint {
+	return &jacobianPoint{x: new(big.Int).Set(x), y: new(big.Int).Set(y), z: big.NewInt(1)}
+}
+
+func (p *jacobianPoint) isInfinity() bool {
+	return p.z.Sign() == 0
+}
+
+func infinityPoint() *jacobianPoint {
+	return &jacobianPoint{x: big.NewInt(0), y: big.NewInt(0), z: big.NewInt(0)}
+}
+
+func modP(n *big.Int) *big.Int {
+	return n.Mod(n, curveP)
+}
+
+func mulP(a, b *big.Int) *big.Int {
+	return modP(new(big.Int).Mul(a, b))
+}
+
+func (p *jacobianPoint) double() *jacobianPoint {
+	if p.isInfinity() || p.y.Sign() == 0 {
+		return infinityPoint()
+	}
+	a := mulP(p.x, p.x)
+	b := mulP(p.y, p.y)
+	c := mulP(b, b)
+	d := new(big.Int).Add(p.x, b)
+	d = mulP(d, d)
+	d.Sub(d, a).Sub(d, c).Lsh(d, 1)
+	modP(d)
+	e := new(big.Int).Mul(a, big.NewInt(3))
+	f := mulP(e, e)
+
+	x3 := new(big.Int).Sub(f, new(big.Int).Lsh(d, 1))
+	modP(x3)
+	y3 := new(big.Int).Sub(d, x3)
+	y3 = mulP(e, y3)
+	y3.Sub(y3, new(big.Int).Lsh(c, 3))
+	modP(y3)
+	z3 := mulP(p.y, p.z)
+	z3.Lsh(z3, 1)
+	modP(z3)
+	return &jacobianPoint{x: x3, y: y3, z: z3}
+}
+
+func (p *jacobianPoint) add(q *jacobianPoint) *jacobianPoint {
+	if p.isInfinity() {
+		return q
+	}
+	if q.isInfinity() {
+		return p
+	}
+	z1z1 := mulP(p.z, p.z)
+	z2z2 := mulP(q.z, q.z)
+	u1 := mulP(p.x, z2z2)
+	u2 := mulP(q.x, z1z1)
+	s1 := mulP(p.y, mulP(q.z, z2z2))
+	s2 := mulP(q.y, mulP(p.z, z1z1))
+	if u1.Cmp(u2) == 0 {
+		if s1.Cmp(s2) != 0 {
+			return infinityPoint()
+		}
+		return p.double()
+	}
+	h := modP(new(big.Int).Sub(u2, u1))
+	r := modP(new(big.Int).Sub(s2, s1))
+	hh := mulP(h, h)
+	hhh := mulP(hh, h)
+	u1hh := mulP(u1, hh)
+
+	x3 := mulP(r, r)
+	x3.Sub(x3, hhh).Sub(x3, new(big.Int).Lsh(u1hh, 1))
+	modP(x3)
+	y3 := mulP(r, new(big.Int).Sub(u1hh, x3))
+	y3.Sub(y3, mulP(s1, hhh))
+	modP(y3)
+	z3 := mulP(h, mulP(p.z, q.z))
+	return &jacobianPoint{x: x3, y: y3, z: z3}
+}
+
+// mul returns k*p. It is not constant time.
+func (p *jacobianPoint) mul(k *big.Int) *jacobianPoint {
+	result := infinityPoint()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.double()
+		if k.Bit(i) == 1 {
+			result = result.add(p)
+		}
+	}
+	return result
+}
+
+// affineX returns the affine x coordinate of a non infinity point.
+func (p *jacobianPoint) affineX() *big.Int {
+	zInv := new(big.Int).ModInverse(p.z, curveP)
+	return mulP(p.x, mulP(zInv, zInv))
+}
+
+// hasSquareY reports whether the affine y coordinate is a quadratic residue,
+// using jacobi(y) = jacobi(Y*Z^3) = jacobi(Y*Z).
+func (p *jacobianPoint) hasSquareY() bool {
+	return big.Jacobi(mulP(p.y, p.z), curveP) == 1
+}
+
+func generator() *jacobianPoint {
+	return newAffinePoint(curveGx, curveGy)
+}
+
+func pubKeyPoint(publicKey *PublicKey) *jacobianPoint {
+	uncompressed := publicKey.SerializeUncompressed()
+	return newAffinePoint(new(big.Int).SetBytes(uncompressed[1:33]), new(big.Int).SetBytes(uncompressed[33:65]))
+}
+
+// schnorrChallenge computes e = SHA256(R.x || compressed(P) || m) mod n.
+func schnorrChallenge(rx []byte, pubKey []byte, hash []byte) *big.Int {
+	h := sha256.New()
+	h.Write(rx)
+	h.Write(pubKey)
+	h.Write(hash)
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, curveN)
+}
+
+func bigIntTo32Bytes(n *big.Int) []byte {
+	b := n.Bytes()
+	ret := make([]byte, 32)
+	copy(ret[32-len(b):], b)
+	return ret
+}
+
+// VerifySchnorr checks a 64 bytes Schnorr signature over hash as specified by
+// the May 2019 Bitcoin Cash upgrade.
+func (publicKey *PublicKey) VerifySchnorr(hash *util.Hash, vchSig []byte) bool {
+	if len(vchSig) != SchnorrSignatureLen || !publicKey.isValid() {
+		return false
+	}
+	r := new(big.Int).SetBytes(vchSig[:32])
+	s := new(big.Int).SetBytes(vchSig[32:])
+	if r.Cmp(curveP) >= 0 || s.Cmp(curveN) >= 0 {
+		return false
+	}
+
+	e := schnorrChallenge(vchSig[:32], publicKey.SerializeCompressed(), hash[:])
+	// R = s*G - e*P
+	negE := new(big.Int).Sub(curveN, e)
+	point := generator().mul(s).add(pubKeyPoint(publicKey).mul(negE))
+	if point.isInfinity() || !point.hasSquareY() {
+		return false
+	}
+	return point.affineX().Cmp(r) == 0
+}
+
+// SignSchnorr produces a 64 bytes Schnorr signature over hash, using a
+// deterministic RFC6979 nonce.
+func (privateKey *PrivateKey) SignSchnorr(hash []byte) ([]byte, error) {
+	d := new(big.Int).SetBytes(privateKey.bytes)
+	if len(privateKey.bytes) != PrivateKeyBytesLen || d.Sign() == 0 || d.Cmp(curveN) >= 0 {
+		return nil, errSchnorrInvalidKey
+	}
+	pubKey := privateKey.PubKey()
+	if pubKey == nil {
+		return nil, errSchnorrInvalidKey
+	}
+
+	rng := newRFC6979(privateKey.bytes, hash, schnorrAlgo16)
+	for {
+		k := new(big.Int).SetBytes(rng.generate())
+		if k.Sign() == 0 || k.Cmp(curveN) >= 0 {
+			continue
+		}
+		point := generator().mul(k)
+		if !point.hasSquareY() {
+			k.Sub(curveN, k)
+		}
+		rx := bigIntTo32Bytes(point.affineX())
+		e := schnorrChallenge(rx, pubKey.SerializeCompressed(), hash)
+
+		s := new(big.Int).Mul(e, d)
+		s.Add(s, k).Mod(s, curveN)
+		return append(rx, bigIntTo32Bytes(s)...), nil
+	}
+}
+
+// rfc6979 is the HMAC-SHA256 DRBG used by libsecp256k1 to derive nonces.
+type rfc6979 struct {
+	k, v  []byte
+	retry bool
+}
+
+func newRFC6979(key, msg, algo16 []byte) *rfc6979 {
+	seed := make([]byte, 0, len(key)+len(msg)+len(algo16))
+	seed = append(seed, key...)
+	seed = append(seed, msg...)
+	seed = append(seed, algo16...)
+
+	rng := &rfc6979{k: make([]byte, 32), v: make([]byte, 32)}
+	for i := range rng.v {
+		rng.v[i] = 0x01
+	}
+	rng.k = rng.hmac(rng.v, []byte{0x00}, seed)
+	rng.v = rng.hmac(rng.v)
+	rng.k = rng.hmac(rng.v, []byte{0x01}, seed)
+	rng.v = rng.hmac(rng.v)
+	return rng
+}
+
+func (rng *rfc6979) hmac(data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, rng.k)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+func (rng *rfc6979) generate() []byte {
+	if rng.retry {
+		rng.k = rng.hmac(rng.v, []byte{0x00})
+		rng.v = rng.hmac(rng.v)
+	}
+	rng.v = rng.hmac(rng.v)
+	rng.retry = true
+	return append([]byte(nil), rng.v...)
+}
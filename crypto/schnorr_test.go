@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// test vectors from the 2019-05-15 Bitcoin Cash Schnorr specification
+var schnorrVerifyTests = []struct {
+	name      string
+	pubKey    string
+	message   string
+	signature string
+	valid     bool
+}{
+	{
+		name:      "test vector 1",
+		pubKey:    "0279BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798",
+		message:   "0000000000000000000000000000000000000000000000000000000000000000",
+		signature: "787A848E71043D280C50470E8E1532B2DD5D20EE912A45DBDD2BD1DFBF187EF67031A98831859DC34DFFEEDDA86831842CCD0079E1F92AF177F7F22CC1DCED05",
+		valid:     true,
+	},
+	{
+		name:      "test vector 2",
+		pubKey:    "02DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		message:   "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		signature: "2A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D1E51A22CCEC35599B8F266912281F8365FFC2D035A230434A1A64DC59F7013FD",
+		valid:     true,
+	},
+	{
+		name:      "test vector 3",
+		pubKey:    "03FAC2114C2FBB091527EB7C64ECB11F8021CB45E8E7809D3C0938E4B8C0E5F84B",
+		message:   "5E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+		signature: "00DA9B08172A9B6F0466A2DEFD817F2D7AB437E0D253CB5395A963866B3574BE00880371D01766935B92D2AB4CD5C8A2A5837EC57FED7660773A05F0DE142380",
+		valid:     true,
+	},
+	{
+		name:      "R.x with leading zero bytes",
+		pubKey:    "03DEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34",
+		message:   "4DF3C3F68FCC83B27E9D42C90431A72499F17875C81A599B566C9889B9696703",
+		signature: "00000000000000000000003B78CE563F89A0ED9414F5AA28AD0D96D6795F9C6302A8DC32E64E86A333F20EF56EAC9BA30B7246D6D25E22ADB8C6BE1AEB08D49D",
+		valid:     true,
+	},
+	{
+		name:      "incorrect signature",
+		pubKey:    "02DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		message:   "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		signature: "2A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1DFA16AEE06609280A19B67A24E1977E4697712B5FD2943914ECD5F730901B4AB7",
+		valid:     false,
+	},
+	{
+		name:      "sig[0:32] is equal to field size",
+		pubKey:    "02DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		message:   "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		signature: "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F1E51A22CCEC35599B8F266912281F8365FFC2D035A230434A1A64DC59F7013FD",
+		valid:     false,
+	},
+	{
+		name:      "sig[32:64] is equal to curve order",
+		pubKey:    "02DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		message:   "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		signature: "2A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1DFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141",
+		valid:     false,
+	},
+	{
+		name:      "wrong length",
+		pubKey:    "0279BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798",
+		message:   "0000000000000000000000000000000000000000000000000000000000000000",
+		signature: "787A848E71043D280C50470E8E1532B2DD5D20EE912A45DBDD2BD1DFBF187EF67031A98831859DC34DFFEEDDA86831842CCD0079E1F92AF177F7F22CC1DCED",
+		valid:     false,
+	},
+}
+
+func TestVerifySchnorr(t *testing.T) {
+	InitSecp256()
+	for _, test := range schnorrVerifyTests {
+		pubKeyBytes, _ := hex.DecodeString(test.pubKey)
+		message, _ := hex.DecodeString(test.message)
+		signature, _ := hex.DecodeString(test.signature)
+
+		pubKey, err := ParsePubKey(pubKeyBytes)
+		assert.Nil(t, err, test.name)
+		var hash util.Hash
+		copy(hash[:], message)
+		assert.Equal(t, test.valid, pubKey.VerifySchnorr(&hash, signature), test.name)
+	}
+}
+
+func TestSignSchnorr(t *testing.T) {
+	InitSecp256()
+	keyBytes, _ := hex.DecodeString("B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF")
+	for _, compressed := range []bool{true, false} {
+		privKey := NewPrivateKeyFromBytes(keyBytes, compressed)
+		hash := util.DoubleSha256Hash([]byte("schnorr"))
+
+		signature, err := privKey.SignSchnorr(hash[:])
+		assert.Nil(t, err)
+		assert.Equal(t, SchnorrSignatureLen, len(signature))
+		assert.True(t, privKey.PubKey().VerifySchnorr(&hash, signature))
+
+		// signing is deterministic
+		signature2, err := privKey.SignSchnorr(hash[:])
+		assert.Nil(t, err)
+		assert.Equal(t, signature, signature2)
+
+		// the signature does not hold for another message
+		otherHash := util.DoubleSha256Hash([]byte("ecdsa"))
+		assert.False(t, privKey.PubKey().VerifySchnorr(&otherHash, signature))
+
+		signature[63] ^= 1
+		assert.False(t, privKey.PubKey().VerifySchnorr(&hash, signature))
+	}
+
+	_, err := NewPrivateKeyFromBytes(make([]byte, 32), true).SignSchnorr(make([]byte, 32))
+	assert.NotNil(t, err)
+}
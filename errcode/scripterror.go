@@ -78,6 +78,31 @@ const (
 	ScriptErrIllegalForkID
 	ScriptErrMustUseForkID
 
+	/* Schnorr */
+
+	ScriptErrSigBadLength
+	ScriptErrSigNonSchnorr
+
+	/* Schnorr multisig bitfield */
+
+	ScriptErrInvalidBitfieldSize
+	ScriptErrInvalidBitRange
+	ScriptErrInvalidBitCount
+
+	/* SigChecks */
+
+	ScriptErrInputSigChecks
+
+	/* Native introspection */
+
+	ScriptErrContextNotPresent
+	ScriptErrInvalidTxInputIndex
+	ScriptErrInvalidTxOutputIndex
+
+	/* 64-bit integers */
+
+	ScriptErrInvalidNumberRange64Bit
+
 	ScriptErrErrorCount
 
 	// ScriptErrSize other errcode
@@ -196,6 +221,26 @@ func scriptErrorString(scriptError ScriptErr) string {
 		return "Signature must be zero for failed CHECK(MULTI)SIG operation"
 	case ScriptErrIllegalForkID:
 		return "Illegal use of SIGHASH_FORKID"
+	case ScriptErrSigBadLength:
+		return "Signature cannot be 65 bytes in CHECKMULTISIG"
+	case ScriptErrSigNonSchnorr:
+		return "Only Schnorr signatures allowed in this operation"
+	case ScriptErrInvalidBitfieldSize:
+		return "Bitfield of unexpected size error"
+	case ScriptErrInvalidBitRange:
+		return "Bitfield's bit out of the expected range"
+	case ScriptErrInvalidBitCount:
+		return "Bitfield's bit count does not match the number of signatures"
+	case ScriptErrInputSigChecks:
+		return "Input SigChecks limit exceeded"
+	case ScriptErrContextNotPresent:
+		return "Introspection opcode used without a transaction context"
+	case ScriptErrInvalidTxInputIndex:
+		return "Specified transaction input index is out of range"
+	case ScriptErrInvalidTxOutputIndex:
+		return "Specified transaction output index is out of range"
+	case ScriptErrInvalidNumberRange64Bit:
+		return "Given operand is not a number within the valid range [-2^63 + 1, 2^63 - 1]"
 	case ScriptErrDiscourageUpgradableNops:
 		return "NOPx reserved for soft-fork upgrades"
 	case ScriptErrDiscourageUpgradableWitnessProgram:
@@ -61,6 +61,18 @@ func TestScriptErr_String(t *testing.T) {
 		// ScriptErrIllegalForkID anti replay
 		{ScriptErrIllegalForkID, "Illegal use of SIGHASH_FORKID"},
 		{ScriptErrMustUseForkID, "unknown error"},
+		/* Schnorr */
+		{ScriptErrSigBadLength, "Signature cannot be 65 bytes in CHECKMULTISIG"},
+		{ScriptErrSigNonSchnorr, "Only Schnorr signatures allowed in this operation"},
+		/* Schnorr multisig bitfield */
+		{ScriptErrInvalidBitfieldSize, "Bitfield of unexpected size error"},
+		{ScriptErrInvalidBitRange, "Bitfield's bit out of the expected range"},
+		{ScriptErrInvalidBitCount, "Bitfield's bit count does not match the number of signatures"},
+		{ScriptErrInputSigChecks, "Input SigChecks limit exceeded"},
+		{ScriptErrContextNotPresent, "Introspection opcode used without a transaction context"},
+		{ScriptErrInvalidTxInputIndex, "Specified transaction input index is out of range"},
+		{ScriptErrInvalidTxOutputIndex, "Specified transaction output index is out of range"},
+		{ScriptErrInvalidNumberRange64Bit, "Given operand is not a number within the valid range [-2^63 + 1, 2^63 - 1]"},
 		{ScriptErrErrorCount, "unknown error"},
 		// ScriptErrSize other errcode
 		{ScriptErrSize, "unknown error"},
@@ -6,10 +6,15 @@ import (
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/crypto"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/laddrindex"
+	"github.com/copernet/copernicus/logic/lblockfilter"
 	"github.com/copernet/copernicus/logic/lblockindex"
 	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/logic/lmempool"
 	"github.com/copernet/copernicus/logic/lreindex"
+	"github.com/copernet/copernicus/logic/lspentindex"
 	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/logic/ltxindex"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/mempool"
@@ -20,6 +25,7 @@ import (
 	"github.com/copernet/copernicus/persist/blkdb"
 	"github.com/copernet/copernicus/persist/db"
 	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/persist/filterdb"
 	"os"
 	"path/filepath"
 )
@@ -83,6 +89,20 @@ func appInitMain(args []string) {
 	blkdbCfg := blkdb.BlockTreeDBConfig{Do: blkDbCfg}
 	blkdb.InitBlockTreeDB(&blkdbCfg)
 
+	// a UTXO snapshot load cut short leaves a partial chain state behind
+	if err := lchain.CleanupUTXOSnapshotLoad(); err != nil {
+		panic("wipe the chain state of an incomplete UTXO snapshot load failed: " + err.Error())
+	}
+
+	if conf.Cfg.Chain.BlockFilterIndex {
+		filterDbCfg := &db.DBOption{
+			FilePath:  conf.Cfg.DataDir + "/indexes/blockfilter/basic",
+			CacheSize: (1 << 20) * 8,
+			Wipe:      conf.Cfg.Reindex,
+		}
+		filterdb.InitFilterDB(&filterdb.FilterDBConfig{Do: filterDbCfg})
+	}
+
 	persist.InitPersistGlobal()
 
 	// Load blockindex DB
@@ -94,11 +114,40 @@ func appInitMain(args []string) {
 	}
 
 	mempool.InitMempool()
+	lmempool.LoadFeeEstimator(chain.GetInstance().Height())
 	crypto.InitSecp256()
 
 	wallet.InitWallet()
 
 	ltx.ScriptVerifyInit()
+
+	if !conf.Cfg.Reindex && conf.Cfg.Chain.CheckBlocks > 0 {
+		persist.CsMain.Lock()
+		err := lchain.VerifyDB(conf.Cfg.Chain.CheckLevel, conf.Cfg.Chain.CheckBlocks)
+		persist.CsMain.Unlock()
+		if err != nil {
+			log.Error("%v", err)
+			fmt.Println("Corrupted block database detected. Please restart with --reindex to rebuild it.")
+			os.Exit(1)
+		}
+	}
+
+	// blocks connected from now on are indexed by ConnectBlock, the builder
+	// only needs to catch up with the chain loaded from disk
+	ltxindex.Start()
+	lblockfilter.Start()
+
+	// unlike the transaction index, the address and spent indexes are only
+	// built by ConnectBlock, so they cannot be turned on for blocks connected
+	// already
+	for _, start := range []func() error{laddrindex.Start, lspentindex.Start} {
+		if err := start(); err != nil {
+			log.Error("%v", err)
+			fmt.Println("Error: " + err.Error())
+			os.Exit(1)
+		}
+	}
+
 	if conf.Cfg.Reindex {
 		disk.CleanupBlockRevFiles()
 		err := lreindex.Reindex()
@@ -120,4 +169,16 @@ func appInitMain(args []string) {
     tip block index: %s
 ---------------------`, gChain.Height(), gChain.IndexMapSize(), gChain.Tip().String())
 	}
+
+	if len(conf.Cfg.LoadBlock) > 0 {
+		if _, err := lreindex.ImportBlocks(conf.Cfg.LoadBlock); err != nil {
+			log.Error("import blocks failed: %v", err)
+		}
+	}
+
+	if conf.Cfg.Mempool.PersistMempool {
+		if err := lmempool.LoadMempool(); err != nil {
+			log.Warn("load mempool failed: %v", err)
+		}
+	}
 }
@@ -0,0 +1,249 @@
+package laddrindex
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/undo"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/cashaddr"
+)
+
+// addressIndexFlag records in the block tree db whether the blocks connected
+// so far have been indexed.
+const addressIndexFlag = "addressindex"
+
+var ErrReindexRequired = errors.New("you need to rebuild the database using --reindex to change --addressindex")
+
+// MempoolDelta is a change of the balance of an address made by an output or
+// an input of a mempool transaction. PrevTxID and PrevIndex are the output
+// spent by an input.
+type MempoolDelta struct {
+	blkdb.AddressKey
+	TxID      util.Hash
+	Index     uint32
+	Spending  bool
+	Amount    int64
+	Time      int64
+	PrevTxID  util.Hash
+	PrevIndex uint32
+}
+
+// IsEnabled returns whether the node maintains the address index
+// (-addressindex).
+func IsEnabled() bool {
+	return conf.Cfg != nil && conf.Cfg.Chain.AddressIndex
+}
+
+// Start checks that the address index is in line with the configuration. The
+// index can only be turned on or off before any block is connected, so a
+// reindex is needed to change it afterwards.
+func Start() error {
+	btd := blkdb.GetInstance()
+	if btd.ReadFlag(addressIndexFlag) == IsEnabled() {
+		return nil
+	}
+	if chain.GetInstance().Height() > 0 {
+		return ErrReindexRequired
+	}
+	return btd.WriteFlag(addressIndexFlag, IsEnabled())
+}
+
+// ScriptAddress returns the address a P2PKH, P2SH or P2SH32 output script pays
+// to.
+func ScriptAddress(scriptPubKey []byte) (blkdb.AddressKey, bool) {
+	var addr blkdb.AddressKey
+	switch {
+	case len(scriptPubKey) == 25 &&
+		scriptPubKey[0] == opcodes.OP_DUP &&
+		scriptPubKey[1] == opcodes.OP_HASH160 &&
+		scriptPubKey[2] == 20 &&
+		scriptPubKey[23] == opcodes.OP_EQUALVERIFY &&
+		scriptPubKey[24] == opcodes.OP_CHECKSIG:
+		addr.Type = byte(cashaddr.P2PKH)
+		copy(addr.Hash[:], scriptPubKey[3:23])
+	case len(scriptPubKey) == 23 &&
+		scriptPubKey[0] == opcodes.OP_HASH160 &&
+		scriptPubKey[1] == 20 &&
+		scriptPubKey[22] == opcodes.OP_EQUAL:
+		addr.Type = byte(cashaddr.P2SH)
+		copy(addr.Hash[:], scriptPubKey[2:22])
+	case len(scriptPubKey) == 35 &&
+		scriptPubKey[0] == opcodes.OP_HASH256 &&
+		scriptPubKey[1] == 32 &&
+		scriptPubKey[34] == opcodes.OP_EQUAL:
+		addr.Type = blkdb.AddressTypeP2SH32
+		copy(addr.Hash[:], scriptPubKey[2:34])
+	default:
+		return addr, false
+	}
+	return addr, true
+}
+
+// IndexBlock records the address deltas of a connected block, along with the
+// unspent outputs it spends and creates. blockUndo holds the coins spent by
+// the block.
+func IndexBlock(blk *block.Block, pindex *blockindex.BlockIndex, blockUndo *undo.BlockUndo) error {
+	deltas, spent, created, err := blockChanges(blk, pindex, blockUndo)
+	if err != nil {
+		return err
+	}
+	return blkdb.GetInstance().WriteAddressIndex(deltas, spent, created)
+}
+
+// UnindexBlock reverts IndexBlock for a block disconnected from the tip.
+func UnindexBlock(blk *block.Block, pindex *blockindex.BlockIndex) error {
+	pos := pindex.GetUndoPos()
+	blockUndo, ok := disk.UndoReadFromDisk(&pos, *pindex.Prev.GetBlockHash())
+	if !ok {
+		return errors.New("read undo data of block " + pindex.GetBlockHash().String() + " failed")
+	}
+	deltas, spent, created, err := blockChanges(blk, pindex, blockUndo)
+	if err != nil {
+		return err
+	}
+	return blkdb.GetInstance().EraseAddressIndex(deltas, spent, created)
+}
+
+func blockChanges(blk *block.Block, pindex *blockindex.BlockIndex, blockUndo *undo.BlockUndo) (
+	deltas []*blkdb.AddressDelta, spent, created []*blkdb.AddressUnspent, err error) {
+
+	txUndos := blockUndo.GetTxundo()
+	if len(txUndos) != len(blk.Txs)-1 {
+		return nil, nil, nil, errors.New("block and undo data mismatch")
+	}
+	for i, transaction := range blk.Txs {
+		txid := transaction.GetHash()
+		if i > 0 {
+			coins := txUndos[i-1].GetUndoCoins()
+			ins := transaction.GetIns()
+			if len(coins) != len(ins) {
+				return nil, nil, nil, errors.New("transaction and undo data mismatch")
+			}
+			for j, in := range ins {
+				addr, ok := ScriptAddress(coins[j].GetScriptPubKey().Bytes())
+				if !ok {
+					continue
+				}
+				deltas = append(deltas, &blkdb.AddressDelta{
+					AddressKey: addr,
+					Height:     pindex.Height,
+					BlockIndex: uint32(i),
+					TxID:       txid,
+					Index:      uint32(j),
+					Spending:   true,
+					Amount:     -int64(coins[j].GetAmount()),
+				})
+				spent = append(spent, &blkdb.AddressUnspent{
+					AddressKey: addr,
+					TxID:       in.PreviousOutPoint.Hash,
+					Index:      in.PreviousOutPoint.Index,
+					Amount:     int64(coins[j].GetAmount()),
+					Height:     coins[j].GetHeight(),
+					Script:     coins[j].GetScriptPubKey().Bytes(),
+				})
+			}
+		}
+
+		for j, out := range transaction.GetOuts() {
+			addr, ok := ScriptAddress(out.GetScriptPubKey().Bytes())
+			if !ok {
+				continue
+			}
+			deltas = append(deltas, &blkdb.AddressDelta{
+				AddressKey: addr,
+				Height:     pindex.Height,
+				BlockIndex: uint32(i),
+				TxID:       txid,
+				Index:      uint32(j),
+				Amount:     int64(out.GetValue()),
+			})
+			created = append(created, &blkdb.AddressUnspent{
+				AddressKey: addr,
+				TxID:       txid,
+				Index:      uint32(j),
+				Amount:     int64(out.GetValue()),
+				Height:     pindex.Height,
+				Script:     out.GetScriptPubKey().Bytes(),
+			})
+		}
+	}
+	return deltas, spent, created, nil
+}
+
+// MempoolDeltas returns the changes the mempool transactions make to the
+// balances of addrs, in the order the transactions entered the mempool.
+func MempoolDeltas(addrs []blkdb.AddressKey) []*MempoolDelta {
+	wanted := make(map[blkdb.AddressKey]struct{}, len(addrs))
+	for _, addr := range addrs {
+		wanted[addr] = struct{}{}
+	}
+
+	pool := mempool.GetInstance()
+	pool.RLock()
+	defer pool.RUnlock()
+
+	coinsCache := utxo.GetUtxoCacheInstance()
+	deltas := make([]*MempoolDelta, 0)
+	for txid, entry := range pool.GetAllTxEntryWithoutLock() {
+		for j, in := range entry.Tx.GetIns() {
+			coin := pool.GetCoin(in.PreviousOutPoint)
+			if coin == nil {
+				coin = coinsCache.GetCoin(in.PreviousOutPoint)
+			}
+			if coin == nil {
+				continue
+			}
+			addr, ok := ScriptAddress(coin.GetScriptPubKey().Bytes())
+			if _, want := wanted[addr]; !ok || !want {
+				continue
+			}
+			deltas = append(deltas, &MempoolDelta{
+				AddressKey: addr,
+				TxID:       txid,
+				Index:      uint32(j),
+				Spending:   true,
+				Amount:     -int64(coin.GetAmount()),
+				Time:       entry.GetTime(),
+				PrevTxID:   in.PreviousOutPoint.Hash,
+				PrevIndex:  in.PreviousOutPoint.Index,
+			})
+		}
+		for j, out := range entry.Tx.GetOuts() {
+			addr, ok := ScriptAddress(out.GetScriptPubKey().Bytes())
+			if _, want := wanted[addr]; !ok || !want {
+				continue
+			}
+			deltas = append(deltas, &MempoolDelta{
+				AddressKey: addr,
+				TxID:       txid,
+				Index:      uint32(j),
+				Amount:     int64(out.GetValue()),
+				Time:       entry.GetTime(),
+			})
+		}
+	}
+
+	sort.SliceStable(deltas, func(i, j int) bool {
+		if deltas[i].Time != deltas[j].Time {
+			return deltas[i].Time < deltas[j].Time
+		}
+		if deltas[i].TxID != deltas[j].TxID {
+			return deltas[i].TxID.Cmp(&deltas[j].TxID) < 0
+		}
+		if deltas[i].Spending != deltas[j].Spending {
+			return deltas[i].Spending
+		}
+		return deltas[i].Index < deltas[j].Index
+	})
+	return deltas
+}
@@ -0,0 +1,52 @@
+package laddrindex
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/util/cashaddr"
+)
+
+func TestScriptAddress(t *testing.T) {
+	hash160 := bytes.Repeat([]byte{0x11}, 20)
+	hash256 := bytes.Repeat([]byte{0x22}, 32)
+	p2pkh := append(append([]byte{opcodes.OP_DUP, opcodes.OP_HASH160, 20}, hash160...),
+		opcodes.OP_EQUALVERIFY, opcodes.OP_CHECKSIG)
+	p2sh := append(append([]byte{opcodes.OP_HASH160, 20}, hash160...), opcodes.OP_EQUAL)
+	p2sh32 := append(append([]byte{opcodes.OP_HASH256, 32}, hash256...), opcodes.OP_EQUAL)
+
+	tests := []struct {
+		name         string
+		scriptPubKey []byte
+		ok           bool
+		addrType     byte
+		hash         []byte
+	}{
+		{"p2pkh", p2pkh, true, byte(cashaddr.P2PKH), hash160},
+		{"p2sh", p2sh, true, byte(cashaddr.P2SH), hash160},
+		{"p2sh32", p2sh32, true, blkdb.AddressTypeP2SH32, hash256},
+		{"p2sh32 with a hash160", append(append([]byte{opcodes.OP_HASH256, 20}, hash160...), opcodes.OP_EQUAL),
+			false, 0, nil},
+		{"p2sh32 with OP_EQUALVERIFY", append(p2sh32[:34:34], opcodes.OP_EQUALVERIFY), false, 0, nil},
+		{"op_return", []byte{opcodes.OP_RETURN}, false, 0, nil},
+	}
+
+	for _, test := range tests {
+		addr, ok := ScriptAddress(test.scriptPubKey)
+		if ok != test.ok {
+			t.Errorf("%s: the script should be indexed: %v, got %v\n", test.name, test.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		var want blkdb.AddressKey
+		want.Type = test.addrType
+		copy(want.Hash[:], test.hash)
+		if addr != want {
+			t.Errorf("%s: the address should be %v: %v\n", test.name, want, addr)
+		}
+	}
+}
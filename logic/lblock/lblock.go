@@ -108,12 +108,7 @@ func CheckBlock(pblock *block.Block, checkHeader, checkMerlke bool) error {
 		return errcode.NewError(errcode.RejectInvalid, "bad-blk-length")
 	}
 
-	nMaxBlockSigOps, errSig := consensus.GetMaxBlockSigOpsCount(uint64(currentBlockSize))
-	if errSig != nil {
-		return errSig
-	}
-
-	err := ltx.CheckBlockTransactions(pblock.Txs, nMaxBlockSigOps)
+	err := ltx.CheckBlockTransactions(pblock.Txs)
 	if err != nil {
 		log.Debug("ErrorBadBlkTx: %v", err)
 		return err
@@ -145,6 +140,19 @@ func ContextualCheckBlock(b *block.Block, indexPrev *blockindex.BlockIndex) erro
 		lockTimeCutoff = mediaTimePast
 	}
 
+	// Before the phonon upgrade, the sigops of a block are limited in
+	// proportion to its size. SigChecks replace them afterwards, and are
+	// only counted while connecting the block.
+	if !model.IsPhononEnabled(mediaTimePast) {
+		maxBlockSigOps, err := consensus.GetMaxBlockSigOpsCount(uint64(b.EncodeSize()))
+		if err != nil {
+			return err
+		}
+		if err := ltx.CheckBlockSigOps(b.Txs, maxBlockSigOps); err != nil {
+			return err
+		}
+	}
+
 	// Check that all transactions are finalized
 	// Enforce rule that the coinBase starts with serialized lblock height
 	err := ltx.ContextureCheckBlockTransactions(b.Txs, height, lockTimeCutoff,
@@ -156,8 +164,13 @@ func ContextualCheckBlock(b *block.Block, indexPrev *blockindex.BlockIndex) erro
 // * BLOCK_VALID_TRANSACTIONS state).
 func ReceivedBlockTransactions(pblock *block.Block,
 	pindexNew *blockindex.BlockIndex, pos *block.DiskBlockPos) {
+	gChain := chain.GetInstance()
 	pindexNew.TxCount = int32(len(pblock.Txs))
-	pindexNew.ChainTxCount = 0
+	// the snapshot base keeps the ChainTxCount of its UTXO snapshot until the
+	// blocks below it are downloaded
+	if pindexNew != gChain.SnapshotBase() {
+		pindexNew.ChainTxCount = 0
+	}
 	pindexNew.File = pos.File
 	pindexNew.DataPos = pos.Pos
 	pindexNew.UndoPos = 0
@@ -167,7 +180,6 @@ func ReceivedBlockTransactions(pblock *block.Block,
 	gPersist := persist.GetInstance()
 	gPersist.AddDirtyBlockIndex(pindexNew)
 
-	gChain := chain.GetInstance()
 	if pindexNew.IsGenesis(gChain.GetParams()) || gChain.ParentInBranch(pindexNew) {
 		// If indexNew is the genesis lblock or all parents are in branch
 		err := gChain.AddToBranch(pindexNew)
@@ -294,6 +306,14 @@ func AcceptBlockHeader(bh *block.BlockHeader) (*blockindex.BlockIndex, error) {
 			log.Debug("AcceptBlockHeader err:%d", 3100)
 			return nil, errcode.ProjectError{Code: 3100}
 		}
+		// the block must build on the finalized block, so a header below it is
+		// rejected as well
+		if finalized := gChain.FinalizedBlock(); finalized != nil &&
+			bIndex.Prev.GetAncestor(finalized.Height) != finalized {
+			log.Debug("AcceptBlockHeader: block %s forks below the finalized block %s",
+				bh.GetHash(), finalized.GetBlockHash())
+			return nil, errcode.NewError(errcode.RejectInvalid, "bad-fork-prior-finalized")
+		}
 		if err = ContextualCheckBlockHeader(bh, bIndex.Prev, util.GetAdjustedTimeSec()); err != nil {
 			log.Debug("AcceptBlockHeader err:%d", 3101)
 			return nil, err
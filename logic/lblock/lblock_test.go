@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/copernet/copernicus/conf"
@@ -16,8 +17,12 @@ import (
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/persist"
 	"github.com/copernet/copernicus/persist/blkdb"
@@ -350,3 +355,26 @@ func TestContextualCheckBlock(t *testing.T) {
 		t.Errorf("TestContextualCheckBlock test 4 check MonolithActivationTime failed")
 	}
 }
+
+func TestContextualCheckBlockSigOps(t *testing.T) {
+	blk1 := getBlock(blk1str)
+	blk0Index := blockindex.NewBlockIndex(&chain.GetInstance().GetParams().GenesisBlock.Header)
+
+	sigOpsTx := tx.NewTx(0, tx.DefaultVersion)
+	sigOpsTx.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.HashOne, 0), script.NewEmptyScript(),
+		script.SequenceFinal))
+	checkSigs := bytes.Repeat([]byte{opcodes.OP_CHECKSIG}, consensus.MaxBlockSigopsPerMb+1)
+	sigOpsTx.AddTxOut(txout.NewTxOut(0, script.NewScriptRaw(checkSigs)))
+	blk1.Txs = append(blk1.Txs, sigOpsTx)
+
+	err := ContextualCheckBlock(blk1, blk0Index)
+	if err == nil || !strings.Contains(err.Error(), "bad-blk-sigops") {
+		t.Errorf("too many sigops should be rejected before phonon, error:%v", err)
+	}
+
+	conf.Args.PhononActivationTime = blk0Index.GetMedianTimePast()
+	defer func() { conf.Args.PhononActivationTime = -1 }()
+	if err := ContextualCheckBlock(blk1, blk0Index); err != nil {
+		t.Errorf("sigops should not be limited after phonon, error:%v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package lblockfilter
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/undo"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/persist/filterdb"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/gcs"
+)
+
+const (
+	// BasicFilterP is the Golomb-Rice parameter of the basic filter.
+	BasicFilterP = 19
+
+	// BasicFilterM is the inverse of the false positive rate of the basic
+	// filter.
+	BasicFilterM = 784931
+)
+
+var (
+	ErrNotIndexed = errors.New("block filter not indexed yet")
+	ErrBadRange   = errors.New("invalid block range")
+)
+
+var (
+	builderQuit chan struct{}
+	builderWg   sync.WaitGroup
+)
+
+// IsEnabled returns whether the node maintains the block filter index
+// (-blockfilterindex).
+func IsEnabled() bool {
+	return conf.Cfg != nil && conf.Cfg.Chain.BlockFilterIndex
+}
+
+// Key returns the SipHash key of the filter of a block, the first 16 bytes of
+// its hash.
+func Key(blockHash *util.Hash) [gcs.KeySize]byte {
+	var key [gcs.KeySize]byte
+	copy(key[:], blockHash[:gcs.KeySize])
+	return key
+}
+
+// BasicFilter builds the basic filter of BIP158 of blk, made of the output
+// scripts of the block and of the scripts of the outputs it spends, found in
+// blockUndo. Empty and OP_RETURN output scripts are left out.
+func BasicFilter(blk *block.Block, blockUndo *undo.BlockUndo) (*gcs.Filter, error) {
+	elements := make(map[string]struct{})
+	for _, transaction := range blk.Txs {
+		for _, out := range transaction.GetOuts() {
+			script := out.GetScriptPubKey().Bytes()
+			if len(script) == 0 || script[0] == opcodes.OP_RETURN {
+				continue
+			}
+			elements[string(script)] = struct{}{}
+		}
+	}
+	if blockUndo != nil {
+		for _, txUndo := range blockUndo.GetTxundo() {
+			for _, coin := range txUndo.GetUndoCoins() {
+				script := coin.GetScriptPubKey().Bytes()
+				if len(script) == 0 {
+					continue
+				}
+				elements[string(script)] = struct{}{}
+			}
+		}
+	}
+
+	data := make([][]byte, 0, len(elements))
+	for element := range elements {
+		data = append(data, []byte(element))
+	}
+	blockHash := blk.GetHash()
+	return gcs.BuildGCSFilter(BasicFilterP, BasicFilterM, Key(&blockHash), data)
+}
+
+// Header returns the header of a filter, which commits to the header of the
+// filter of the previous block.
+func Header(filterHash, prevHeader *util.Hash) util.Hash {
+	data := make([]byte, 0, 2*util.Hash256Size)
+	data = append(data, filterHash[:]...)
+	data = append(data, prevHeader[:]...)
+	return util.DoubleSha256Hash(data)
+}
+
+// GetFilter returns the filter of a block, or nil if it is not indexed.
+func GetFilter(blockHash *util.Hash) (*filterdb.FilterEntry, error) {
+	return filterdb.GetInstance().ReadFilter(blockHash)
+}
+
+// IndexBlock records the filter of a connected block, blockUndo holds the
+// coins it spends. Blocks whose parent is not indexed yet are left to the
+// background builder.
+func IndexBlock(blk *block.Block, pindex *blockindex.BlockIndex, blockUndo *undo.BlockUndo) error {
+	var prevHeader util.Hash
+	if pindex.Prev != nil {
+		prev, err := GetFilter(pindex.Prev.GetBlockHash())
+		if err != nil {
+			return err
+		}
+		if prev == nil {
+			return nil
+		}
+		prevHeader = prev.Header
+	}
+
+	filter, err := BasicFilter(blk, blockUndo)
+	if err != nil {
+		return err
+	}
+	entry := &filterdb.FilterEntry{Filter: filter.NBytes()}
+	entry.FilterHash = util.DoubleSha256Hash(entry.Filter)
+	entry.Header = Header(&entry.FilterHash, &prevHeader)
+	return filterdb.GetInstance().WriteFilter(pindex.GetBlockHash(), entry)
+}
+
+// BlockRange returns the blocks from height start to the block stopHash,
+// which may be on a side chain. It returns ErrBadRange if more than maxCount
+// blocks are selected. It must be called with CsMain held.
+func BlockRange(start int32, stopHash *util.Hash, maxCount int32) ([]*blockindex.BlockIndex, error) {
+	stop := chain.GetInstance().FindBlockIndex(*stopHash)
+	if stop == nil || start < 0 || start > stop.Height || stop.Height-start >= maxCount {
+		return nil, ErrBadRange
+	}
+	indexes := make([]*blockindex.BlockIndex, stop.Height-start+1)
+	for pindex := stop; pindex != nil && pindex.Height >= start; pindex = pindex.Prev {
+		indexes[pindex.Height-start] = pindex
+	}
+	return indexes, nil
+}
+
+// Start indexes in the background the blocks of the active chain connected
+// before the index was turned on, or while it was off. Newly connected blocks
+// are indexed by ConnectBlock.
+func Start() {
+	if !IsEnabled() {
+		return
+	}
+	builderQuit = make(chan struct{})
+	builderWg.Add(1)
+	go buildIndex(builderQuit)
+}
+
+// Stop interrupts the background builder, if any, and waits for it to exit.
+func Stop() {
+	if builderQuit != nil {
+		close(builderQuit)
+		builderQuit = nil
+	}
+	builderWg.Wait()
+}
+
+func buildIndex(quit chan struct{}) {
+	defer builderWg.Done()
+
+	var pindex *blockindex.BlockIndex
+	hash, err := filterdb.GetInstance().ReadBestBlock()
+	if err != nil {
+		log.Error("blockfilterindex: read best indexed block failed: %v", err)
+		return
+	}
+	if hash != nil {
+		pindex = chain.GetInstance().FindBlockIndex(*hash)
+	}
+
+	for {
+		select {
+		case <-quit:
+			log.Info("blockfilterindex: builder interrupted")
+			return
+		default:
+		}
+
+		persist.CsMain.Lock()
+		next, err := indexNextBlock(pindex)
+		persist.CsMain.Unlock()
+		if err != nil {
+			log.Error("blockfilterindex: %v", err)
+			return
+		}
+		if next == nil {
+			log.Info("blockfilterindex: block filter index is synced")
+			return
+		}
+		if next.Height%10000 == 0 {
+			log.Info("blockfilterindex: indexed block height %d", next.Height)
+		}
+		pindex = next
+	}
+}
+
+// indexNextBlock indexes the active chain block following pindex, and returns
+// it. It returns nil once pindex is the tip. It must be called with CsMain
+// held.
+func indexNextBlock(pindex *blockindex.BlockIndex) (*blockindex.BlockIndex, error) {
+	gChain := chain.GetInstance()
+
+	var next *blockindex.BlockIndex
+	if pindex == nil {
+		next = gChain.Genesis()
+	} else {
+		// the block may have been reorganized out while the lock was released
+		next = gChain.Next(gChain.FindFork(pindex))
+	}
+	if next == nil {
+		return nil, nil
+	}
+	if entry, err := GetFilter(next.GetBlockHash()); err != nil || entry != nil {
+		return next, err
+	}
+
+	blk, ok := disk.ReadBlockFromDisk(next, gChain.GetParams())
+	if !ok {
+		return nil, errors.New("read block " + next.GetBlockHash().String() + " from disk failed")
+	}
+	var blockUndo *undo.BlockUndo
+	if next.Prev != nil {
+		pos := next.GetUndoPos()
+		blockUndo, ok = disk.UndoReadFromDisk(&pos, *next.Prev.GetBlockHash())
+		if !ok {
+			return nil, errors.New("read undo data of block " + next.GetBlockHash().String() + " failed")
+		}
+	}
+	return next, IndexBlock(blk, next, blockUndo)
+}
@@ -0,0 +1,36 @@
+package lblockfilter
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/util"
+)
+
+// TestBasicFilterGenesis checks the filter of the testnet genesis block
+// against the test vectors of BIP158.
+func TestBasicFilterGenesis(t *testing.T) {
+	blk := model.TestNetGenesisBlock
+	filter, err := BasicFilter(blk, nil)
+	if err != nil {
+		t.Fatalf("build basic filter failed: %v", err)
+	}
+	if got := hex.EncodeToString(filter.NBytes()); got != "019dfca8" {
+		t.Errorf("basic filter: want 019dfca8, got %s", got)
+	}
+
+	filterHash := util.DoubleSha256Hash(filter.NBytes())
+	header := Header(&filterHash, &util.Hash{})
+	want := "21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750"
+	if header.String() != want {
+		t.Errorf("filter header: want %s, got %s", want, header)
+	}
+
+	blockHash := blk.GetHash()
+	script := blk.Txs[0].GetTxOut(0).GetScriptPubKey().Bytes()
+	match, err := filter.Match(Key(&blockHash), script)
+	if err != nil || !match {
+		t.Errorf("basic filter doesn't match the coinbase output: %v", err)
+	}
+}
@@ -30,6 +30,15 @@ func LoadBlockIndexDB() bool {
 	if !blkdb.GetInstance().LoadBlockIndexGuts(GlobalBlockIndexMap, gChain.GetParams()) {
 		return false
 	}
+	// A chain state loaded from a UTXO snapshot knows the ChainTxCount of the
+	// snapshot base before the blocks below it are downloaded
+	snapshotBaseHash, snapshotChainTxCount, err := blkdb.GetInstance().ReadSnapshotBase()
+	if err != nil {
+		log.Error("LoadBlockIndexDB: ReadSnapshotBase err:%v", err)
+		return false
+	}
+	var snapshotBase *blockindex.BlockIndex
+
 	gPersist := persist.GetInstance()
 	sortedByHeight := make([]*blockindex.BlockIndex, 0, len(GlobalBlockIndexMap))
 	for _, index := range GlobalBlockIndexMap {
@@ -58,7 +67,11 @@ func LoadBlockIndexDB() bool {
 			log.Error("index's Txcount is < 0 ")
 			panic("index's Txcount is < 0 ")
 		}
-		if index.Prev != nil {
+		if snapshotBaseHash != nil && index.GetBlockHash().IsEqual(snapshotBaseHash) {
+			index.ChainTxCount = snapshotChainTxCount
+			branch = append(branch, index)
+			snapshotBase = index
+		} else if index.Prev != nil {
 			if index.Prev.ChainTxCount != 0 {
 				index.ChainTxCount = index.Prev.ChainTxCount + index.TxCount
 				branch = append(branch, index)
@@ -89,7 +102,6 @@ func LoadBlockIndexDB() bool {
 
 	// Load block file info
 	btd := blkdb.GetInstance()
-	var err error
 	var bfi *block.BlockFileInfo
 
 	globalLastBlockFile, err := btd.ReadLastBlockFile()
@@ -148,6 +160,15 @@ func LoadBlockIndexDB() bool {
 
 	// Build chain's active
 	gChain.InitLoad(GlobalBlockIndexMap, branch)
+	gChain.SetSnapshotBase(snapshotBase)
+	finalizedHash, err := btd.ReadFinalizedBlock()
+	if err != nil {
+		log.Error("LoadBlockIndexDB: ReadFinalizedBlock err:%v", err)
+		return false
+	}
+	if finalizedHash != nil {
+		gChain.SetFinalizedBlock(GlobalBlockIndexMap[*finalizedHash])
+	}
 	bestHash, err := utxo.GetUtxoCacheInstance().GetBestBlock()
 	log.Debug("find bestblock hash:%s and err:%v from utxo", bestHash, err)
 	if err == nil {
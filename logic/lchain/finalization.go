@@ -0,0 +1,113 @@
+package lchain
+
+import (
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/blkdb"
+)
+
+// FinalizeBlock finalizes pindex: the active chain will not be reorganized
+// below it anymore. If pindex is not on the active chain, the active chain is
+// rewound to the fork, and ActivateBestChain must be called afterwards.
+func FinalizeBlock(pindex *blockindex.BlockIndex) error {
+	if pindex.IsInvalid() {
+		return errcode.NewError(errcode.RejectInvalid, "finalize-invalid-block")
+	}
+	gChain := chain.GetInstance()
+	if finalized := gChain.FinalizedBlock(); finalized != nil && !chain.AreOnTheSameFork(pindex, finalized) {
+		return errcode.NewError(errcode.RejectInvalid, "bad-fork-prior-finalized")
+	}
+	if gChain.IsBlockFinalized(pindex) {
+		return nil
+	}
+	if err := setFinalizedBlock(pindex); err != nil {
+		return err
+	}
+
+	// The blocks of the active chain conflicting with the finalized block
+	// are invalid now.
+	if !gChain.Contains(pindex) {
+		if next := gChain.Next(gChain.FindFork(pindex)); next != nil {
+			return InvalidateBlock(next)
+		}
+	}
+	return nil
+}
+
+// InvalidateBlock marks pindex as invalid, and disconnects it and its
+// descendants from the active chain. ActivateBestChain must be called
+// afterwards.
+func InvalidateBlock(pindex *blockindex.BlockIndex) error {
+	return unwindBlock(pindex, InvalidBlockParentFound, InvalidBlockFound)
+}
+
+// ParkBlock parks pindex: it and its descendants are disconnected from the
+// active chain and are not considered for activation until they are unparked.
+// ActivateBestChain must be called afterwards.
+func ParkBlock(pindex *blockindex.BlockIndex) error {
+	return unwindBlock(pindex, parkedBlockParentFound, parkedBlockFound)
+}
+
+// UnparkBlock makes pindex and its descendants eligible for activation again.
+// ActivateBestChain must be called afterwards.
+func UnparkBlock(pindex *blockindex.BlockIndex) {
+	chain.GetInstance().ResetBlockParkedFlags(pindex)
+}
+
+func unwindBlock(pindex *blockindex.BlockIndex, markDescendant, mark func(*blockindex.BlockIndex)) error {
+	gChain := chain.GetInstance()
+	for gChain.Contains(pindex) {
+		markDescendant(gChain.Tip())
+		if err := DisconnectTip(false); err != nil {
+			return err
+		}
+	}
+	mark(pindex)
+
+	// the finalized block can not be a descendant of pindex anymore
+	if gChain.IsBlockFinalized(pindex) {
+		return setFinalizedBlock(pindex.Prev)
+	}
+	return nil
+}
+
+func parkedBlockFound(pindex *blockindex.BlockIndex) {
+	pindex.AddStatus(blockindex.BlockParked)
+	chain.GetInstance().RemoveFromBranch(pindex)
+	persist.GetInstance().AddDirtyBlockIndex(pindex)
+}
+
+func parkedBlockParentFound(pindex *blockindex.BlockIndex) {
+	pindex.AddStatus(blockindex.BlockParkedParent)
+	chain.GetInstance().RemoveFromBranch(pindex)
+	persist.GetInstance().AddDirtyBlockIndex(pindex)
+}
+
+// finalizeAtMaxReorgDepth finalizes the block -maxreorgdepth blocks below the
+// new tip pindexNew.
+func finalizeAtMaxReorgDepth(pindexNew *blockindex.BlockIndex) {
+	depth := conf.Cfg.Chain.MaxReorgDepth
+	if depth <= 0 || pindexNew.Height < depth {
+		return
+	}
+	pindex := pindexNew.GetAncestor(pindexNew.Height - depth)
+	if chain.GetInstance().IsBlockFinalized(pindex) {
+		return
+	}
+	if err := setFinalizedBlock(pindex); err != nil {
+		log.Error("finalize block %s failed: %v", pindex.GetBlockHash(), err)
+	}
+}
+
+func setFinalizedBlock(pindex *blockindex.BlockIndex) error {
+	chain.GetInstance().SetFinalizedBlock(pindex)
+	if pindex == nil {
+		return blkdb.GetInstance().WriteFinalizedBlock(nil)
+	}
+	log.Debug("finalized block %s at height %d", pindex.GetBlockHash(), pindex.Height)
+	return blkdb.GetInstance().WriteFinalizedBlock(pindex.GetBlockHash())
+}
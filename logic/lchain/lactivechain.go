@@ -215,6 +215,12 @@ func CheckBlockIndex() error {
 
 	gChain := chain.GetInstance()
 
+	// The blocks below a UTXO snapshot base are missing until they are
+	// backfilled, which the checks below do not allow for.
+	if gChain.SnapshotBase() != nil {
+		return nil
+	}
+
 	// During a reindex, we read the genesis block and call CheckBlockIndex
 	// before ActivateBestChain, so we have the genesis block in mapBlockIndex
 	// but no active chain. (A few of the tests when iterating the block tree
@@ -9,6 +9,7 @@ import (
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/metrics"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/blockindex"
@@ -18,7 +19,11 @@ import (
 	"github.com/copernet/copernicus/util"
 
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/laddrindex"
+	"github.com/copernet/copernicus/logic/lblockfilter"
+	"github.com/copernet/copernicus/logic/lspentindex"
 	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/logic/ltxindex"
 	"github.com/copernet/copernicus/logic/lundo"
 
 	"github.com/copernet/copernicus/model/undo"
@@ -32,6 +37,9 @@ import (
 	"github.com/copernet/copernicus/persist/db"
 )
 
+var blockConnectDuration = metrics.NewHistogram("copernicus_block_connect_duration_seconds",
+	"Time taken to connect a block to the tip of the active chain.", nil)
+
 // IsInitialBlockDownload Check whether we are doing an initial block download
 // (synchronizing from disk or network)
 func IsInitialBlockDownload() bool {
@@ -54,8 +62,10 @@ func ConnectBlock(pblock *block.Block, pindex *blockindex.BlockIndex, view *utxo
 	} else {
 		hashPrevBlock = pindex.Prev.GetBlockHash()
 	}
-	gUtxo := utxo.GetUtxoCacheInstance()
-	bestHash, _ := gUtxo.GetBestBlock()
+	bestHash := view.GetBestBlock()
+	if bestHash.IsNull() {
+		bestHash, _ = utxo.GetUtxoCacheInstance().GetBestBlock()
+	}
 	log.Debug("bestHash = %s, hashPrevBloc = %s", bestHash, hashPrevBlock)
 	if !hashPrevBlock.IsEqual(&bestHash) {
 		log.Debug("will panic in ConnectBlock()")
@@ -164,7 +174,7 @@ func ConnectBlock(pblock *block.Block, pindex *blockindex.BlockIndex, view *utxo
 		return errSig
 	}
 
-	coinsMap, blockUndo, err := ltx.ApplyBlockTransactions(pblock.Txs, bip30Enable, flags,
+	coinsMap, blockUndo, err := ltx.ApplyBlockTransactions(pblock.Txs, view, bip30Enable, flags,
 		fScriptChecks, blockSubSidy, pindex.Height, maxSigOps, uint32(lockTimeFlags), pindex)
 	if err != nil {
 		return err
@@ -191,16 +201,43 @@ func ConnectBlock(pblock *block.Block, pindex *blockindex.BlockIndex, view *utxo
 			pindex.RaiseValidity(blockindex.BlockValidScripts)
 			gPersist.AddDirtyBlockIndex(pindex)
 		}
-		// add this block to the view's block chain
-		*view = *coinsMap
-	}
 
-	// If we just activated the replay protection with that block, it means
-	// transaction in the mempool are now invalid. As a result, we need to clear the mempool.
-	if pindex.IsReplayProtectionJustEnabled() {
-		mempool.InitMempool()
+		if ltxindex.IsEnabled() {
+			if err := ltxindex.IndexBlock(pblock, pindex); err != nil {
+				log.Error("ConnectBlock(): write transaction index failed: %v", err)
+				return err
+			}
+		}
+		if laddrindex.IsEnabled() {
+			if err := laddrindex.IndexBlock(pblock, pindex, blockUndo); err != nil {
+				log.Error("ConnectBlock(): write address index failed: %v", err)
+				return err
+			}
+		}
+		if lspentindex.IsEnabled() {
+			if err := lspentindex.IndexBlock(pblock, pindex); err != nil {
+				log.Error("ConnectBlock(): write spent index failed: %v", err)
+				return err
+			}
+		}
+		if lblockfilter.IsEnabled() {
+			if err := lblockfilter.IndexBlock(pblock, pindex, blockUndo); err != nil {
+				log.Error("ConnectBlock(): write block filter index failed: %v", err)
+				return err
+			}
+		}
+
+		// If we just activated the replay protection with that block, it means
+		// transaction in the mempool are now invalid. As a result, we need to clear the mempool.
+		if pindex.IsReplayProtectionJustEnabled() {
+			mempool.InitMempool()
+		}
 	}
 
+	// add this block to the view's block chain
+	coinsMap.SetBestBlock(blockHash)
+	coinsMap.FlushToBase()
+
 	log.Debug("Connect block heigh:%d, hash:%s, txs: %d", pindex.Height, blockHash, len(pblock.Txs))
 	return nil
 }
@@ -303,11 +340,14 @@ func ConnectTip(pIndexNew *blockindex.BlockIndex,
 
 	// Remove conflicting transactions from the mempool.;
 	mempool.GetInstance().RemoveTxSelf(blockConnecting.Txs)
+	lmempool.RegisterBlockFees(blockConnecting, pIndexNew.Height)
 	// Update chainActive & related variables.
 	UpdateTip(pIndexNew)
+	finalizeAtMaxReorgDepth(pIndexNew)
 	nTime6 := util.GetTimeMicroSec()
 	gPersist.GlobalTimePostConnect += nTime6 - nTime5
 	gPersist.GlobalTimeTotal += nTime6 - nTime1
+	blockConnectDuration.Observe(float64(nTime6-nTime1) * 0.000001)
 	log.Print("bench", "debug", " - Connect postprocess: %.2fms [%.2fs]\n",
 		float64(nTime6-nTime5)*0.001, float64(gPersist.GlobalTimePostConnect)*0.000001)
 	log.Print("bench", "debug", " - Connect block: %.2fms [%.2fs]\n",
@@ -342,6 +382,24 @@ func DisconnectTip(fBare bool) error {
 			log.Error(fmt.Sprintf("DisconnectTip(): DisconnectBlock %s failed ", tip.GetBlockHash()))
 			return errcode.New(errcode.DisconnectTipUndoFailed)
 		}
+		if ltxindex.IsEnabled() {
+			if err := ltxindex.UnindexBlock(blk); err != nil {
+				log.Error("DisconnectTip(): erase transaction index failed: %v", err)
+				return err
+			}
+		}
+		if laddrindex.IsEnabled() {
+			if err := laddrindex.UnindexBlock(blk, tip); err != nil {
+				log.Error("DisconnectTip(): erase address index failed: %v", err)
+				return err
+			}
+		}
+		if lspentindex.IsEnabled() {
+			if err := lspentindex.UnindexBlock(blk); err != nil {
+				log.Error("DisconnectTip(): erase spent index failed: %v", err)
+				return err
+			}
+		}
 		//flushed := view.Flush(blk.Header.HashPrevBlock)
 		err := utxo.GetUtxoCacheInstance().UpdateCoins(view, &blk.Header.HashPrevBlock)
 		if err != nil {
@@ -349,6 +407,7 @@ func DisconnectTip(fBare bool) error {
 		}
 		utxo.GetUtxoCacheInstance().Flush()
 	}
+	lmempool.UnregisterBlockFees(tip.GetBlockHash())
 	// replace implement with log.Print(in C++).
 	log.Info("bench-debug - Disconnect block : %.2fms\n",
 		float64(time.Now().UnixNano()-nStart)*0.001)
@@ -365,8 +424,15 @@ func DisconnectTip(fBare bool) error {
 	//
 	// If we are deactivating Magnetic anomaly, we want to make sure we do not
 	// have transactions in the mempool that use newly introduced opcodes. As a
-	// result, we also cleanup the mempool.
-	if tip.IsReplayProtectionJustEnabled() || tip.IsMagneticAnomalyJustEnabled() {
+	// result, we also cleanup the mempool. The same goes for Schnorr signatures
+	// when deactivating the great wall fork, Schnorr multisig when
+	// deactivating the graviton fork, OP_REVERSEBYTES or SigChecks heavy
+	// transactions when deactivating the phonon fork, 64-bit integers or
+	// introspection when deactivating the May 2022 upgrade, and tokens or
+	// P2SH32 when deactivating the May 2023 upgrade.
+	if tip.IsReplayProtectionJustEnabled() || tip.IsMagneticAnomalyJustEnabled() ||
+		tip.IsGreatWallJustEnabled() || tip.IsGravitonJustEnabled() ||
+		tip.IsPhononJustEnabled() || tip.IsUpgrade8JustEnabled() || tip.IsUpgrade9JustEnabled() {
 		mempool.InitMempool()
 	}
 
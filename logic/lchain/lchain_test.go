@@ -5,10 +5,12 @@ import (
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/crypto"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lblock"
 	"github.com/copernet/copernicus/logic/lblockindex"
 	"github.com/copernet/copernicus/logic/lchain"
 	"github.com/copernet/copernicus/logic/lmerkleroot"
 	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/logic/ltxindex"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/chain"
@@ -35,6 +37,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func coinbaseScriptSigWithHeight(extraNonce uint, height int32) *script.Script {
@@ -285,3 +288,333 @@ func TestGetUTXOStats(t *testing.T) {
 	_, err = lchain.GetUTXOStats(cdb)
 	assert.Nil(t, err)
 }
+
+func TestVerifyDB(t *testing.T) {
+	// set params, don't modify!
+	model.SetRegTestParams()
+	// clear chain data of last test case
+	testDir, err := initTestEnv(t, []string{"--regtest"})
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	tChain := chain.GetInstance()
+
+	pubKey := script.NewEmptyScript()
+	pubKey.PushOpCode(opcodes.OP_TRUE)
+
+	_, err = generateDummyBlocks(pubKey, 101, 1000000, 0, nil)
+	assert.Nil(t, err)
+
+	block1, ok := disk.ReadBlockFromDisk(tChain.GetIndex(1), tChain.GetParams())
+	assert.True(t, ok)
+	transaction := tx.NewTx(0, tx.DefaultVersion)
+	preOut := outpoint.NewOutPoint(block1.Txs[0].GetHash(), 0)
+	transaction.AddTxIn(txin.NewTxIn(preOut, script.NewEmptyScript(), math.MaxUint32-1))
+	for i := 0; i < 20; i++ {
+		transaction.AddTxOut(txout.NewTxOut(1, pubKey))
+	}
+	_, err = generateDummyBlocks(pubKey, 1, 1000000, 101, []*tx.Tx{transaction})
+	assert.Nil(t, err)
+	_, err = generateDummyBlocks(pubKey, 2, 1000000, 102, nil)
+	assert.Nil(t, err)
+
+	bestHash, _ := utxo.GetUtxoCacheInstance().GetBestBlock()
+	for level := int32(0); level <= lchain.MaxCheckLevel; level++ {
+		assert.Nil(t, lchain.VerifyDB(level, lchain.DefaultCheckBlocks), "level %d", level)
+	}
+	assert.Nil(t, lchain.VerifyDB(lchain.MaxCheckLevel, 0))
+
+	// the check does not touch the UTXO set
+	hash, _ := utxo.GetUtxoCacheInstance().GetBestBlock()
+	assert.Equal(t, bestHash, hash)
+	assert.True(t, utxo.GetUtxoCacheInstance().HaveCoin(outpoint.NewOutPoint(transaction.GetHash(), 0)))
+	assert.False(t, utxo.GetUtxoCacheInstance().HaveCoin(preOut))
+
+	// spend an output of the transaction behind the back of the chain
+	view := utxo.NewEmptyCoinsMap()
+	view.SpendGlobalCoin(outpoint.NewOutPoint(transaction.GetHash(), 0))
+	assert.Nil(t, utxo.GetUtxoCacheInstance().UpdateCoins(view, &bestHash))
+
+	assert.Nil(t, lchain.VerifyDB(2, lchain.DefaultCheckBlocks))
+	assert.Nil(t, lchain.VerifyDB(3, 2))
+	assert.NotNil(t, lchain.VerifyDB(3, lchain.DefaultCheckBlocks))
+}
+
+func TestTxIndex(t *testing.T) {
+	// set params, don't modify!
+	model.SetRegTestParams()
+	// clear chain data of last test case
+	testDir, err := initTestEnv(t, []string{"--regtest", "--txindex"})
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	tChain := chain.GetInstance()
+
+	pubKey := script.NewEmptyScript()
+	pubKey.PushOpCode(opcodes.OP_TRUE)
+
+	_, err = generateDummyBlocks(pubKey, 101, 1000000, 0, nil)
+	assert.Nil(t, err)
+
+	block1, ok := disk.ReadBlockFromDisk(tChain.GetIndex(1), tChain.GetParams())
+	assert.True(t, ok)
+
+	transaction := tx.NewTx(0, tx.DefaultVersion)
+	preOut := outpoint.NewOutPoint(block1.Txs[0].GetHash(), 0)
+	transaction.AddTxIn(txin.NewTxIn(preOut, script.NewEmptyScript(), math.MaxUint32-1))
+	for i := 0; i < 20; i++ {
+		transaction.AddTxOut(txout.NewTxOut(1, pubKey))
+	}
+	_, err = generateDummyBlocks(pubKey, 1, 1000000, 101, []*tx.Tx{transaction})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(102), tChain.TipHeight())
+
+	// both the coinbase and the ordinary transaction of a block are indexed
+	tip := tChain.Tip()
+	tipBlock, ok := disk.ReadBlockFromDisk(tip, tChain.GetParams())
+	assert.True(t, ok)
+	for _, want := range tipBlock.Txs {
+		txid := want.GetHash()
+		got, hashBlock, err := ltxindex.FindTx(&txid)
+		assert.Nil(t, err)
+		if assert.NotNil(t, got) {
+			assert.Equal(t, txid, got.GetHash())
+			assert.Equal(t, *tip.GetBlockHash(), *hashBlock)
+		}
+	}
+
+	txid := block1.Txs[0].GetHash()
+	got, hashBlock, err := ltxindex.FindTx(&txid)
+	assert.Nil(t, err)
+	assert.NotNil(t, got)
+	assert.Equal(t, *tChain.GetIndex(1).GetBlockHash(), *hashBlock)
+
+	// unknown txid
+	got, hashBlock, err = ltxindex.FindTx(&util.HashOne)
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+	assert.Nil(t, hashBlock)
+
+	// reorganize the transaction out of the active chain
+	_, err = generateDummyBlocks(pubKey, 103, 1000000, 0, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(103), tChain.TipHeight())
+
+	txid = transaction.GetHash()
+	got, _, err = ltxindex.FindTx(&txid)
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+}
+
+func TestTxIndexBuilder(t *testing.T) {
+	// set params, don't modify!
+	model.SetRegTestParams()
+	// clear chain data of last test case
+	testDir, err := initTestEnv(t, []string{"--regtest"})
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+
+	tChain := chain.GetInstance()
+
+	pubKey := script.NewEmptyScript()
+	pubKey.PushOpCode(opcodes.OP_TRUE)
+
+	_, err = generateDummyBlocks(pubKey, 20, 1000000, 0, nil)
+	assert.Nil(t, err)
+
+	tipBlock, ok := disk.ReadBlockFromDisk(tChain.Tip(), tChain.GetParams())
+	assert.True(t, ok)
+	txid := tipBlock.Txs[0].GetHash()
+	got, _, err := ltxindex.FindTx(&txid)
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+
+	conf.Cfg.Chain.TxIndex = true
+	defer func() { conf.Cfg.Chain.TxIndex = false }()
+	ltxindex.Start()
+	for i := 0; i < 100 && !ltxindex.IsSynced(); i++ {
+		time.Sleep(50 * time.Millisecond)
+	}
+	ltxindex.Stop()
+	assert.True(t, ltxindex.IsSynced())
+
+	for height := int32(1); height <= tChain.Height(); height++ {
+		bindex := tChain.GetIndex(height)
+		blk, ok := disk.ReadBlockFromDisk(bindex, tChain.GetParams())
+		assert.True(t, ok)
+		txid := blk.Txs[0].GetHash()
+		got, hashBlock, err := ltxindex.FindTx(&txid)
+		assert.Nil(t, err)
+		if assert.NotNil(t, got) {
+			assert.Equal(t, *bindex.GetBlockHash(), *hashBlock)
+		}
+	}
+
+	// disabling the index resets it
+	conf.Cfg.Chain.TxIndex = false
+	ltxindex.Start()
+	assert.False(t, ltxindex.IsSynced())
+
+	// the block reorganized out while the index is off stays in it, but its
+	// transactions are no longer found once the index is rebuilt
+	otherKey := script.NewEmptyScript()
+	otherKey.PushOpCode(opcodes.OP_2)
+	_, err = generateDummyBlocks(otherKey, 2, 1000000, 19, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(21), tChain.TipHeight())
+
+	conf.Cfg.Chain.TxIndex = true
+	ltxindex.Start()
+	for i := 0; i < 100 && !ltxindex.IsSynced(); i++ {
+		time.Sleep(50 * time.Millisecond)
+	}
+	ltxindex.Stop()
+	assert.True(t, ltxindex.IsSynced())
+
+	got, hashBlock, err := ltxindex.FindTx(&txid)
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+	assert.Nil(t, hashBlock)
+}
+
+func TestUTXOSnapshot(t *testing.T) {
+	// set params, don't modify!
+	model.SetRegTestParams()
+	// clear chain data of last test case
+	sourceDir, err := initTestEnv(t, []string{"--regtest"})
+	assert.Nil(t, err)
+	defer os.RemoveAll(sourceDir)
+
+	tChain := chain.GetInstance()
+
+	pubKey := script.NewEmptyScript()
+	pubKey.PushOpCode(opcodes.OP_TRUE)
+
+	_, err = generateDummyBlocks(pubKey, 101, 1000000, 0, nil)
+	assert.Nil(t, err)
+	block1, ok := disk.ReadBlockFromDisk(tChain.GetIndex(1), tChain.GetParams())
+	assert.True(t, ok)
+	transaction := tx.NewTx(0, tx.DefaultVersion)
+	transaction.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(block1.Txs[0].GetHash(), 0),
+		script.NewEmptyScript(), math.MaxUint32-1))
+	for i := 0; i < 20; i++ {
+		transaction.AddTxOut(txout.NewTxOut(1, pubKey))
+	}
+	_, err = generateDummyBlocks(pubKey, 1, 1000000, 101, []*tx.Tx{transaction})
+	assert.Nil(t, err)
+	_, err = generateDummyBlocks(pubKey, 2, 1000000, 102, nil)
+	assert.Nil(t, err)
+
+	tip := tChain.Tip()
+	chainTxCount := tip.ChainTxCount
+	blocks := make([]*block.Block, 0, tip.Height)
+	for height := int32(1); height <= tip.Height; height++ {
+		blk, ok := disk.ReadBlockFromDisk(tChain.GetIndex(height), tChain.GetParams())
+		assert.True(t, ok)
+		blocks = append(blocks, blk)
+	}
+
+	path := filepath.Join(sourceDir, "utxo.dat")
+	snapshot, err := lchain.DumpUTXOSnapshot(path)
+	assert.Nil(t, err)
+	stat, err := lchain.GetUTXOStats(utxo.GetUtxoCacheInstance().(*utxo.CoinsLruCache).GetCoinsDB())
+	assert.Nil(t, err)
+	assert.Equal(t, *tip.GetBlockHash(), snapshot.BaseBlockHash)
+	assert.Equal(t, tip.Height, snapshot.BaseHeight)
+	assert.Equal(t, chainTxCount, snapshot.ChainTxCount)
+	assert.Equal(t, stat.TxOutsCount, snapshot.CoinsCount)
+	assert.Equal(t, stat.HashSerialized, snapshot.HashSerialized)
+	_, err = lchain.DumpUTXOSnapshot(path)
+	assert.NotNil(t, err)
+
+	// load the snapshot into a node which only has the headers
+	testDir, err := initTestEnv(t, []string{"--regtest"})
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+	for _, blk := range blocks {
+		_, err = lblock.AcceptBlockHeader(&blk.Header)
+		assert.Nil(t, err)
+	}
+
+	_, err = lchain.LoadUTXOSnapshot(path)
+	assert.NotNil(t, err)
+	conf.Cfg.Chain.AssumeUTXO = util.HashOne.String()
+	_, err = lchain.LoadUTXOSnapshot(path)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(0), tChain.TipHeight())
+
+	conf.Cfg.Chain.AssumeUTXO = snapshot.HashSerialized.String()
+	_, err = lchain.LoadUTXOSnapshot(path)
+	assert.NotNil(t, err)
+	params := tChain.GetParams()
+	params.AssumeUTXO = []*model.AssumeUTXOData{{
+		BlockHash:      snapshot.BaseBlockHash,
+		HashSerialized: snapshot.HashSerialized,
+		ChainTxCount:   chainTxCount,
+	}}
+	defer func() { params.AssumeUTXO = nil }()
+
+	// the ChainTxCount of the base comes from the chain params, not from the
+	// snapshot metadata
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	assert.Nil(t, err)
+	metadata := utxo.SnapshotMetadata{}
+	assert.Nil(t, metadata.Unserialize(file))
+	metadata.ChainTxCount += 1000
+	_, err = file.Seek(0, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, metadata.Serialize(file))
+	assert.Nil(t, file.Close())
+
+	// a load cut short is wiped on startup
+	cdb := utxo.GetUtxoCacheInstance().(*utxo.CoinsLruCache).GetCoinsDB()
+	partialCoin := []byte{db.DbCoin, 0x01}
+	assert.Nil(t, cdb.WriteSnapshotLoading(true))
+	assert.Nil(t, cdb.GetDBW().Write(partialCoin, []byte{0x01}, false))
+	_, err = lchain.LoadUTXOSnapshot(path)
+	assert.NotNil(t, err)
+	assert.Nil(t, lchain.CleanupUTXOSnapshotLoad())
+	assert.False(t, cdb.IsSnapshotLoading())
+	assert.False(t, cdb.GetDBW().Exists(partialCoin))
+
+	loaded, err := lchain.LoadUTXOSnapshot(path)
+	assert.Nil(t, err)
+	assert.Equal(t, snapshot.CoinsCount, loaded.CoinsCount)
+	assert.Equal(t, snapshot.BaseBlockHash, *tChain.Tip().GetBlockHash())
+	assert.Equal(t, tChain.Tip(), tChain.SnapshotBase())
+	assert.Equal(t, chainTxCount, tChain.Tip().ChainTxCount)
+	assert.False(t, cdb.IsSnapshotLoading())
+	assert.True(t, utxo.GetUtxoCacheInstance().HaveCoin(outpoint.NewOutPoint(transaction.GetHash(), 19)))
+	loadedStat, err := lchain.GetUTXOStats(utxo.GetUtxoCacheInstance().(*utxo.CoinsLruCache).GetCoinsDB())
+	assert.Nil(t, err)
+	assert.Equal(t, stat.HashSerialized, loadedStat.HashSerialized)
+
+	// a snapshot can only be loaded once
+	_, err = lchain.LoadUTXOSnapshot(path)
+	assert.NotNil(t, err)
+
+	// the snapshot base survives a restart
+	*tChain = *chain.NewChain()
+	assert.True(t, lblockindex.LoadBlockIndexDB())
+	assert.Equal(t, snapshot.BaseBlockHash, *tChain.Tip().GetBlockHash())
+	assert.Equal(t, tChain.Tip(), tChain.SnapshotBase())
+	assert.Equal(t, chainTxCount, tChain.Tip().ChainTxCount)
+
+	// backfill the blocks below the snapshot base
+	assert.False(t, lchain.FinishSnapshotBackfill())
+	for _, blk := range blocks {
+		fNewBlock := false
+		assert.Nil(t, service.ProcessNewBlock(blk, true, &fNewBlock))
+	}
+	assert.True(t, lchain.FinishSnapshotBackfill())
+	assert.Nil(t, tChain.SnapshotBase())
+	assert.Equal(t, chainTxCount, tChain.Tip().ChainTxCount)
+	base, _, err := blkdb.GetInstance().ReadSnapshotBase()
+	assert.Nil(t, err)
+	assert.Nil(t, base)
+
+	_, err = generateDummyBlocks(pubKey, 1, 1000000, snapshot.BaseHeight, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, snapshot.BaseHeight+1, tChain.TipHeight())
+	assert.Equal(t, chainTxCount+1, tChain.Tip().ChainTxCount)
+}
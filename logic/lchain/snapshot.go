@@ -0,0 +1,336 @@
+package lchain
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/util"
+)
+
+// snapshotBatchSize is the number of coins written to the chain state at once
+// while loading a UTXO snapshot.
+const snapshotBatchSize = 100000
+
+// UTXOSnapshot describes a UTXO snapshot which was dumped or loaded.
+type UTXOSnapshot struct {
+	utxo.SnapshotMetadata
+	BaseHeight     int32
+	HashSerialized util.Hash
+}
+
+// DumpUTXOSnapshot flushes the chain state and writes the UTXO set at the
+// chain tip to path. The snapshot is written to a temporary file first and
+// renamed once complete. persist.CsMain must be held by the caller.
+func DumpUTXOSnapshot(path string) (*UTXOSnapshot, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s already exists", path)
+	}
+	if err := disk.FlushStateToDisk(disk.FlushStateAlways, 0); err != nil {
+		return nil, err
+	}
+
+	cdb := utxo.GetUtxoCacheInstance().(*utxo.CoinsLruCache).GetCoinsDB()
+	bestHash, err := cdb.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	base := chain.GetInstance().FindBlockIndex(*bestHash)
+	if base == nil {
+		return nil, fmt.Errorf("best block %s of the chain state is not known", bestHash)
+	}
+
+	tmpPath := path + ".incomplete"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := writeUTXOSnapshot(file, cdb, base)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	log.Info("Dumped %d coins at block %s (height %d) to %s",
+		snapshot.CoinsCount, &snapshot.BaseBlockHash, snapshot.BaseHeight, path)
+	return snapshot, nil
+}
+
+func writeUTXOSnapshot(file *os.File, cdb utxo.CoinsDB, base *blockindex.BlockIndex) (*UTXOSnapshot, error) {
+	snapshot := &UTXOSnapshot{
+		SnapshotMetadata: utxo.SnapshotMetadata{
+			BaseBlockHash: *base.GetBlockHash(),
+			ChainTxCount:  base.ChainTxCount,
+		},
+		BaseHeight: base.Height,
+	}
+
+	// the coins count is filled in once all the coins are written
+	w := bufio.NewWriter(file)
+	if err := snapshot.SnapshotMetadata.Serialize(w); err != nil {
+		return nil, err
+	}
+	hasher := newUTXOHasher(snapshot.BaseBlockHash, int(base.Height))
+	err := forEachCoin(cdb, func(outPoint *outpoint.OutPoint, coin *utxo.Coin) error {
+		if err := outPoint.Serialize(w); err != nil {
+			return err
+		}
+		if err := coin.Serialize(w); err != nil {
+			return err
+		}
+		snapshot.CoinsCount++
+		return hasher.add(outPoint, coin)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = w.Flush(); err != nil {
+		return nil, err
+	}
+	stat, err := hasher.finish()
+	if err != nil {
+		return nil, err
+	}
+	snapshot.HashSerialized = stat.hashSerialized
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err = snapshot.SnapshotMetadata.Serialize(file); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// LoadUTXOSnapshot loads the UTXO set of the snapshot at path into an empty
+// chain state and makes the snapshot base block the chain tip. The snapshot
+// must hash to the configured Chain.AssumeUTXO value, the header of its base
+// block must already be known and the chain params must have an assumeutxo
+// entry for the base block with the same hash, which gives the ChainTxCount of
+// the base. The blocks below the base are downloaded in the background
+// afterwards. persist.CsMain must be held by the caller.
+//
+// The chain state is marked as loading a snapshot until the load completes,
+// so that CleanupUTXOSnapshotLoad wipes the partial chain state a crash would
+// leave behind.
+func LoadUTXOSnapshot(path string) (*UTXOSnapshot, error) {
+	if conf.Cfg.Chain.AssumeUTXO == "" {
+		return nil, errors.New("no assumeutxo hash is configured")
+	}
+	assumeUTXO, err := util.GetHashFromStr(conf.Cfg.Chain.AssumeUTXO)
+	if err != nil {
+		return nil, fmt.Errorf("invalid assumeutxo hash: %v", err)
+	}
+	if conf.Cfg.Chain.TxIndex {
+		return nil, errors.New("a UTXO snapshot cannot be loaded with txindex enabled")
+	}
+	if conf.Cfg.Chain.AddressIndex {
+		return nil, errors.New("a UTXO snapshot cannot be loaded with addressindex enabled")
+	}
+	if conf.Cfg.Chain.SpentIndex {
+		return nil, errors.New("a UTXO snapshot cannot be loaded with spentindex enabled")
+	}
+	if conf.Cfg.Chain.BlockFilterIndex {
+		return nil, errors.New("a UTXO snapshot cannot be loaded with blockfilterindex enabled")
+	}
+	gChain := chain.GetInstance()
+	genesis := gChain.Tip()
+	if genesis == nil || genesis.Height != 0 {
+		return nil, errors.New("a UTXO snapshot can only be loaded into an empty chain state")
+	}
+	cache := utxo.GetUtxoCacheInstance().(*utxo.CoinsLruCache)
+	cdb := cache.GetCoinsDB()
+	if cdb.IsSnapshotLoading() {
+		return nil, errors.New("a previous UTXO snapshot load did not complete, restart the node to wipe " +
+			"the chain state")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// the first pass checks the snapshot before anything is written
+	snapshot, base, err := readUTXOSnapshot(file, nil)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.HashSerialized != *assumeUTXO {
+		return nil, fmt.Errorf("the UTXO snapshot hash %s does not match the assumeutxo hash %s",
+			&snapshot.HashSerialized, assumeUTXO)
+	}
+	assumeUTXOData := gChain.GetParams().AssumeUTXOForBlock(&snapshot.BaseBlockHash)
+	if assumeUTXOData == nil {
+		return nil, fmt.Errorf("the chain params have no assumeutxo entry for the snapshot base block %s",
+			&snapshot.BaseBlockHash)
+	}
+	if snapshot.HashSerialized != assumeUTXOData.HashSerialized {
+		return nil, fmt.Errorf("the UTXO snapshot hash %s does not match the assumeutxo entry hash %s",
+			&snapshot.HashSerialized, &assumeUTXOData.HashSerialized)
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err = cdb.WriteSnapshotLoading(true); err != nil {
+		return nil, err
+	}
+	cm := utxo.NewEmptyCoinsMap()
+	batchSize := 0
+	_, _, err = readUTXOSnapshot(file, func(outPoint *outpoint.OutPoint, coin *utxo.Coin) error {
+		txOut := coin.GetTxOut()
+		cm.AddCoin(outPoint, utxo.NewFreshCoin(&txOut, coin.GetHeight(), coin.IsCoinBase()), false)
+		batchSize++
+		if batchSize < snapshotBatchSize {
+			return nil
+		}
+		batchSize = 0
+		if err := cache.UpdateCoins(cm, genesis.GetBlockHash()); err != nil {
+			return err
+		}
+		cache.Flush()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = cache.UpdateCoins(cm, base.GetBlockHash()); err != nil {
+		return nil, err
+	}
+	cache.Flush()
+
+	base.ChainTxCount = assumeUTXOData.ChainTxCount
+	if err = blkdb.GetInstance().WriteSnapshotBase(base.GetBlockHash(), base.ChainTxCount); err != nil {
+		return nil, err
+	}
+	gChain.SetSnapshotBase(base)
+	gChain.SetTip(base)
+	if err = disk.FlushStateToDisk(disk.FlushStateAlways, 0); err != nil {
+		return nil, err
+	}
+	if err = cdb.WriteSnapshotLoading(false); err != nil {
+		return nil, err
+	}
+
+	log.Info("Loaded %d coins from %s, new tip %s (height %d)",
+		snapshot.CoinsCount, path, &snapshot.BaseBlockHash, snapshot.BaseHeight)
+	return snapshot, nil
+}
+
+// CleanupUTXOSnapshotLoad wipes the chain state left behind by a UTXO snapshot
+// load which did not complete, along with the snapshot base it may have
+// recorded, so that the node starts over from the genesis block. It is called
+// on startup before the block index is loaded.
+func CleanupUTXOSnapshotLoad() error {
+	cdb := utxo.GetUtxoCacheInstance().(*utxo.CoinsLruCache).GetCoinsDB()
+	if !cdb.IsSnapshotLoading() {
+		return nil
+	}
+	log.Warn("A UTXO snapshot load did not complete, wiping the chain state")
+	if err := blkdb.GetInstance().WriteSnapshotBase(nil, 0); err != nil {
+		return err
+	}
+	return cdb.Wipe()
+}
+
+// readUTXOSnapshot reads a UTXO snapshot, calling fn for each of its coins if
+// fn is not nil, and returns it along with its base block.
+func readUTXOSnapshot(file *os.File, fn func(outPoint *outpoint.OutPoint, coin *utxo.Coin) error) (
+	*UTXOSnapshot, *blockindex.BlockIndex, error) {
+
+	r := bufio.NewReader(file)
+	snapshot := &UTXOSnapshot{}
+	if err := snapshot.SnapshotMetadata.Unserialize(r); err != nil {
+		return nil, nil, err
+	}
+	base := chain.GetInstance().FindBlockIndex(snapshot.BaseBlockHash)
+	if base == nil {
+		return nil, nil, fmt.Errorf("the header of the snapshot base block %s is not known",
+			&snapshot.BaseBlockHash)
+	}
+	if base.IsInvalid() {
+		return nil, nil, fmt.Errorf("the snapshot base block %s is invalid", &snapshot.BaseBlockHash)
+	}
+	snapshot.BaseHeight = base.Height
+
+	hasher := newUTXOHasher(snapshot.BaseBlockHash, int(base.Height))
+	for i := uint64(0); i < snapshot.CoinsCount; i++ {
+		outPoint := &outpoint.OutPoint{}
+		if err := outPoint.Unserialize(r); err != nil {
+			return nil, nil, fmt.Errorf("bad coin %d in the UTXO snapshot: %v", i, err)
+		}
+		coin := utxo.NewEmptyCoin()
+		if err := coin.Unserialize(r); err != nil {
+			return nil, nil, fmt.Errorf("bad coin %d in the UTXO snapshot: %v", i, err)
+		}
+		if coin.GetHeight() > base.Height {
+			return nil, nil, fmt.Errorf("coin %s in the UTXO snapshot is above the base block", outPoint)
+		}
+		if err := hasher.add(outPoint, coin); err != nil {
+			return nil, nil, err
+		}
+		if fn != nil {
+			if err := fn(outPoint, coin); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return nil, nil, errors.New("the UTXO snapshot has more coins than it claims")
+	}
+
+	stat, err := hasher.finish()
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot.HashSerialized = stat.hashSerialized
+	return snapshot, base, nil
+}
+
+// FinishSnapshotBackfill forgets the UTXO snapshot base once the blocks below
+// it have all been downloaded. It returns whether the chain state no longer
+// waits for any historical block.
+func FinishSnapshotBackfill() bool {
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	gChain := chain.GetInstance()
+	base := gChain.SnapshotBase()
+	if base == nil {
+		return true
+	}
+	for index := base; index != nil; index = index.Prev {
+		if !index.HasData() {
+			return false
+		}
+	}
+
+	if err := blkdb.GetInstance().WriteSnapshotBase(nil, 0); err != nil {
+		log.Error("FinishSnapshotBackfill: erase snapshot base failed: %v", err)
+		return false
+	}
+	gChain.SetSnapshotBase(nil)
+	log.Info("Downloaded all the blocks below the UTXO snapshot base %s", base.GetBlockHash())
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"fmt"
+	"hash"
 	"os"
 	"path/filepath"
 	"sort"
@@ -87,54 +88,95 @@ func applyStats(stat *stat, hashbuf *bytes.Buffer, txid *util.Hash, outputs map[
 	return err
 }
 
-func GetUTXOStats(cdb utxo.CoinsDB) (*UTXOStat, error) {
-	stat := &stat{}
-	b := time.Now()
-	besthash, err := cdb.GetBestBlock()
-	if err != nil {
-		log.Debug("in GetUTXOStats, GetBestBlock(), failed=%v\n", err)
-		return nil, err
+// utxoHasher computes the serialized hash of a UTXO set, along with its
+// statistics. The coins must be added in the order of the coins database.
+type utxoHasher struct {
+	stat     *stat
+	h        hash.Hash
+	prevHash util.Hash
+	outputs  map[uint32]*utxo.Coin
+}
+
+func newUTXOHasher(bestblock util.Hash, height int) *utxoHasher {
+	uh := &utxoHasher{
+		stat:    &stat{height: height, bestblock: bestblock},
+		h:       sha256.New(),
+		outputs: make(map[uint32]*utxo.Coin),
 	}
-	stat.bestblock = *besthash
-	stat.height = int(chain.GetInstance().FindBlockIndex(*besthash).Height)
+	uh.h.Write(bestblock[:])
+	return uh
+}
 
-	h := sha256.New()
-	h.Write(stat.bestblock[:])
+func (uh *utxoHasher) add(outPoint *outpoint.OutPoint, coin *utxo.Coin) error {
+	if outPoint.Hash != uh.prevHash && len(uh.outputs) > 0 {
+		if err := uh.applyOutputs(); err != nil {
+			return err
+		}
+	}
+	uh.prevHash = outPoint.Hash
+	uh.outputs[outPoint.Index] = coin
+	return nil
+}
+
+func (uh *utxoHasher) applyOutputs() error {
+	hashBuf := bytes.NewBuffer(nil)
+	if err := applyStats(uh.stat, hashBuf, &uh.prevHash, uh.outputs); err != nil {
+		return err
+	}
+	uh.h.Write(hashBuf.Bytes())
+	uh.outputs = make(map[uint32]*utxo.Coin)
+	return nil
+}
+
+// finish returns the statistics of all the coins added so far.
+func (uh *utxoHasher) finish() (*stat, error) {
+	if len(uh.outputs) > 0 {
+		if err := uh.applyOutputs(); err != nil {
+			return nil, err
+		}
+	}
+	copy(uh.stat.hashSerialized[:], uh.h.Sum(nil))
+	return uh.stat, nil
+}
 
+// forEachCoin calls fn for every coin of the coins database, in key order.
+func forEachCoin(cdb utxo.CoinsDB, fn func(outPoint *outpoint.OutPoint, coin *utxo.Coin) error) error {
 	iter := cdb.GetDBW().Iterator(nil)
 	defer iter.Close()
 	iter.Seek([]byte{db.DbCoin})
 
-	var prevHash util.Hash
-	outputs := make(map[uint32]*utxo.Coin)
 	for ; iter.Valid() && iter.GetKey()[0] == db.DbCoin; iter.Next() {
 		outPoint := &outpoint.OutPoint{}
-		if err = outPoint.Unserialize(bytes.NewBuffer(iter.GetKey()[1:])); err != nil {
-			return nil, err
+		if err := outPoint.Unserialize(bytes.NewBuffer(iter.GetKey()[1:])); err != nil {
+			return err
 		}
 		coin := utxo.NewEmptyCoin()
-		if err = coin.Unserialize(bytes.NewBuffer(iter.GetVal())); err != nil {
-			return nil, err
+		if err := coin.Unserialize(bytes.NewBuffer(iter.GetVal())); err != nil {
+			return err
 		}
-		if outPoint.Hash != prevHash && len(outputs) > 0 {
-			hashBuf := bytes.NewBuffer(nil)
-			if err = applyStats(stat, hashBuf, &prevHash, outputs); err != nil {
-				return nil, err
-			}
-			h.Write(hashBuf.Bytes())
-			outputs = make(map[uint32]*utxo.Coin)
+		if err := fn(outPoint, coin); err != nil {
+			return err
 		}
-		prevHash = outPoint.Hash
-		outputs[outPoint.Index] = coin
 	}
-	if len(outputs) > 0 {
-		hashBuf := bytes.NewBuffer(nil)
-		if err = applyStats(stat, hashBuf, &prevHash, outputs); err != nil {
-			return nil, err
-		}
-		h.Write(hashBuf.Bytes())
+	return nil
+}
+
+func GetUTXOStats(cdb utxo.CoinsDB) (*UTXOStat, error) {
+	b := time.Now()
+	besthash, err := cdb.GetBestBlock()
+	if err != nil {
+		log.Debug("in GetUTXOStats, GetBestBlock(), failed=%v\n", err)
+		return nil, err
+	}
+
+	hasher := newUTXOHasher(*besthash, int(chain.GetInstance().FindBlockIndex(*besthash).Height))
+	if err = forEachCoin(cdb, hasher.add); err != nil {
+		return nil, err
+	}
+	stat, err := hasher.finish()
+	if err != nil {
+		return nil, err
 	}
-	copy(stat.hashSerialized[:], h.Sum(nil))
 
 	utxoStat := &UTXOStat{
 		Height:         stat.height,
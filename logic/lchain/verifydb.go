@@ -0,0 +1,148 @@
+package lchain
+
+import (
+	"fmt"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lblock"
+	"github.com/copernet/copernicus/logic/lundo"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/undo"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/disk"
+)
+
+const (
+	// DefaultCheckBlocks is the number of blocks checked at startup.
+	DefaultCheckBlocks = 6
+	// DefaultCheckLevel is how thorough the startup check is.
+	DefaultCheckLevel = 3
+	// MaxCheckLevel is the most thorough check level.
+	MaxCheckLevel = 4
+)
+
+// VerifyDB checks the last checkDepth blocks of the active chain against the
+// block files, the undo files and the UTXO set. Level 0 reads the blocks from
+// disk, level 1 checks them, level 2 reads their undo data, level 3 disconnects
+// them from the tip in memory to check the UTXO set and level 4 reconnects them
+// again. Each level includes the ones below it. A checkDepth of zero or less
+// checks the whole chain. The blocks are only disconnected as long as the coins
+// they bring back fit in the coins cache budget, the level 3 and 4 checks stop
+// short of checkDepth otherwise. Nothing is written to the UTXO set,
+// persist.CsMain must be held by the caller.
+func VerifyDB(checkLevel, checkDepth int32) error {
+	gChain := chain.GetInstance()
+	tip := gChain.Tip()
+	if tip == nil || tip.Prev == nil {
+		return nil
+	}
+	params := gChain.GetParams()
+
+	if checkDepth <= 0 || checkDepth > tip.Height {
+		checkDepth = tip.Height
+	}
+	if checkLevel < 0 {
+		checkLevel = 0
+	}
+	if checkLevel > MaxCheckLevel {
+		checkLevel = MaxCheckLevel
+	}
+	log.Info("Verifying last %d blocks at level %d", checkDepth, checkLevel)
+
+	view := utxo.NewEmptyCoinsMap()
+	pindexState := tip
+	var pindexFailure *blockindex.BlockIndex
+	goodTransactions := 0
+	skippedL3Checks := false
+	for pindex := tip; pindex != nil && pindex.Prev != nil; pindex = pindex.Prev {
+		if pindex.Height <= tip.Height-checkDepth {
+			break
+		}
+		if !pindex.HasData() {
+			// the block data is not available, stop here
+			break
+		}
+
+		// check level 0: read from disk
+		blk, ok := disk.ReadBlockFromDisk(pindex, params)
+		if !ok {
+			return fmt.Errorf("VerifyDB(): *** ReadBlockFromDisk failed at %d, hash=%s",
+				pindex.Height, pindex.GetBlockHash())
+		}
+
+		// check level 1: verify block validity
+		if checkLevel >= 1 {
+			if err := lblock.CheckBlock(blk, true, true); err != nil {
+				return fmt.Errorf("VerifyDB(): *** found bad block at %d, hash=%s (%v)",
+					pindex.Height, pindex.GetBlockHash(), err)
+			}
+		}
+
+		// check level 2: verify undo validity
+		var blockUndo *undo.BlockUndo
+		if checkLevel >= 2 {
+			pos := pindex.GetUndoPos()
+			if !pos.IsNull() {
+				if blockUndo, ok = disk.UndoReadFromDisk(&pos, *pindex.Prev.GetBlockHash()); !ok {
+					return fmt.Errorf("VerifyDB(): *** found bad undo data at %d, hash=%s",
+						pindex.Height, pindex.GetBlockHash())
+				}
+			}
+		}
+
+		// check level 3: check for inconsistencies during memory-only
+		// disconnect of tip blocks
+		if checkLevel >= 3 && pindex == pindexState && view.DynamicMemoryUsage() > disk.CoinCacheUsage {
+			skippedL3Checks = true
+		}
+		if checkLevel >= 3 && pindex == pindexState && !skippedL3Checks {
+			res := undo.DisconnectFailed
+			if blockUndo != nil {
+				res = lundo.ApplyBlockUndo(blockUndo, blk, view, pindex.Height)
+			}
+			if res == undo.DisconnectFailed {
+				return fmt.Errorf("VerifyDB(): *** irrecoverable inconsistency in block data at %d, hash=%s",
+					pindex.Height, pindex.GetBlockHash())
+			}
+			pindexState = pindex.Prev
+			if res == undo.DisconnectUnclean {
+				goodTransactions = 0
+				pindexFailure = pindex
+			} else {
+				goodTransactions += len(blk.Txs)
+			}
+		}
+	}
+
+	if pindexFailure != nil {
+		return fmt.Errorf("VerifyDB(): *** coin database inconsistencies found "+
+			"(last %d blocks, %d good transactions before that)",
+			tip.Height-pindexFailure.Height+1, goodTransactions)
+	}
+
+	if skippedL3Checks {
+		log.Warn("VerifyDB(): skipped the level 3 and 4 checks below height %d, "+
+			"the disconnected coins outgrew the coins cache budget", pindexState.Height+1)
+	}
+
+	// check level 4: try reconnecting blocks
+	if checkLevel >= 4 {
+		for pindex := pindexState; pindex != tip; {
+			pindex = gChain.Next(pindex)
+			blk, ok := disk.ReadBlockFromDisk(pindex, params)
+			if !ok {
+				return fmt.Errorf("VerifyDB(): *** ReadBlockFromDisk failed at %d, hash=%s",
+					pindex.Height, pindex.GetBlockHash())
+			}
+			if err := ConnectBlock(blk, pindex, view, true); err != nil {
+				return fmt.Errorf("VerifyDB(): *** found unconnectable block at %d, hash=%s (%v)",
+					pindex.Height, pindex.GetBlockHash(), err)
+			}
+		}
+	}
+
+	log.Info("No coin database inconsistencies in last %d blocks (%d transactions)",
+		tip.Height-pindexState.Height, goodTransactions)
+	return nil
+}
@@ -0,0 +1,92 @@
+package lmempool
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/util"
+)
+
+// feeEstimatesFileName is the file of the data dir the fee estimator state
+// is kept in across restarts.
+const feeEstimatesFileName = "fee_estimates.dat"
+
+func feeEstimatesPath() string {
+	return filepath.Join(conf.Cfg.DataDir, feeEstimatesFileName)
+}
+
+// LoadFeeEstimator restores the fee estimator saved by the previous session.
+// The saved state is dropped if it does not end at tipHeight, since blocks
+// connected meanwhile were not registered with it.
+func LoadFeeEstimator(tipHeight int32) {
+	file, err := os.Open(feeEstimatesPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("open fee estimates failed: %v", err)
+		}
+		return
+	}
+	defer file.Close()
+
+	ef, err := mempool.RestoreFeeEstimator(bufio.NewReader(file))
+	if err != nil {
+		log.Warn("read fee estimates failed, starting over: %v", err)
+		return
+	}
+	if ef.LastKnownHeight() != tipHeight {
+		log.Info("fee estimates end at height %d instead of %d, starting over",
+			ef.LastKnownHeight(), tipHeight)
+		return
+	}
+	mempool.SetFeeEstimator(ef)
+}
+
+// SaveFeeEstimator writes the fee estimator state to the data dir.
+func SaveFeeEstimator() error {
+	path := feeEstimatesPath()
+	tmpPath := path + ".new"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	err = mempool.GetFeeEstimator().Save(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// RegisterBlockFees feeds the fee estimator with a block connected at height.
+func RegisterBlockFees(blk *block.Block, height int32) {
+	if err := mempool.GetFeeEstimator().RegisterBlock(blk, height); err != nil {
+		// blocks were connected without the estimator, e.g. a reorg
+		// deeper than it can roll back, start over
+		log.Debug("register block %s with fee estimator failed, resetting it: %v", blk.GetHash(), err)
+		ef := mempool.NewFeeEstimator(mempool.DefaultEstimateFeeMaxRollback,
+			mempool.DefaultEstimateFeeMinRegisteredBlocks)
+		ef.RegisterBlock(blk, height)
+		mempool.SetFeeEstimator(ef)
+	}
+}
+
+// UnregisterBlockFees reverts the effect of a disconnected block on the fee
+// estimator.
+func UnregisterBlockFees(hash *util.Hash) {
+	if err := mempool.GetFeeEstimator().Rollback(hash); err != nil {
+		log.Debug("rollback block %s from fee estimator failed: %v", hash, err)
+	}
+}
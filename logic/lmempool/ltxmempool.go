@@ -10,6 +10,7 @@ import (
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/metrics"
 	//"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/model/outpoint"
@@ -18,13 +19,46 @@ import (
 	"github.com/copernet/copernicus/util"
 )
 
+var rejectedTxs = metrics.NewCounterVec("copernicus_mempool_rejected_transactions_total",
+	"Transactions rejected by the mempool, by reason.", "reason")
+
 func AcceptTxToMemPool(txn *tx.Tx) error {
 	txEntry, err := ltx.CheckTxBeforeAcceptToMemPool(txn)
 	if err != nil {
+		countRejectedTx(err)
+		return err
+	}
+
+	err = addTxToMemPool(txEntry)
+	countRejectedTx(err)
+	return err
+}
+
+// acceptTxToMemPoolWithTime is like AcceptTxToMemPool, but the entry keeps
+// acceptTime instead of the current time.
+func acceptTxToMemPoolWithTime(txn *tx.Tx, acceptTime int64) error {
+	txEntry, err := ltx.CheckTxBeforeAcceptToMemPool(txn)
+	if err != nil {
+		countRejectedTx(err)
 		return err
 	}
+	txEntry.SetTime(acceptTime)
 
-	return addTxToMemPool(txEntry)
+	err = addTxToMemPool(txEntry)
+	countRejectedTx(err)
+	return err
+}
+
+// countRejectedTx counts err, if any, by the error code it carries.
+func countRejectedTx(err error) {
+	if err == nil {
+		return
+	}
+	reason := "other"
+	if e, ok := err.(errcode.ProjectError); ok && e.ErrorCode != nil {
+		reason = e.ErrorCode.String()
+	}
+	rejectedTxs.WithLabelValues(reason).Inc()
 }
 
 func addTxToMemPool(txe *mempool.TxEntry) error {
@@ -49,6 +83,8 @@ func addTxToMemPool(txe *mempool.TxEntry) error {
 		log.Error("add tx failed:%s", err.Error())
 		return err
 	}
+	mempool.GetFeeEstimator().ObserveTransaction(txe)
+	notifyTxAccepted(txe)
 
 	// TODO: simple implementation just for testing, remove this after complete wallet
 	if wallet.GetInstance().IsEnable() {
@@ -289,10 +325,12 @@ func CheckMempool(bestHeight int32) {
 		nCountCheck := int64(len(setAncestors)) + 1
 		nSizeCheck := int64(entry.TxSize)
 		nSigOpCheck := int64(entry.SigOpCount)
+		nSigChecksCheck := int64(entry.SigChecks)
 		nFeesCheck := entry.TxFee
 		for ancestorIt := range setAncestors {
 			nSizeCheck += int64(ancestorIt.TxSize)
 			nSigOpCheck += int64(ancestorIt.SigOpCount)
+			nSigChecksCheck += int64(ancestorIt.SigChecks)
 			nFeesCheck += ancestorIt.TxFee
 		}
 		if entry.SumTxCountWithAncestors != nCountCheck {
@@ -311,6 +349,11 @@ func CheckMempool(bestHeight int32) {
 				entry.SumTxSigOpCountWithAncestors, nSigOpCheck)
 			entry.SumTxSigOpCountWithAncestors = nSigOpCheck
 		}
+		if entry.SumTxSigChecksWithAncestors != nSigChecksCheck {
+			log.Error("the txentry's ancestors sigchecks is incorrect: entry.SumTxSigChecksWithAncestors(%d), nSigChecksCheck(%d)",
+				entry.SumTxSigChecksWithAncestors, nSigChecksCheck)
+			entry.SumTxSigChecksWithAncestors = nSigChecksCheck
+		}
 		if entry.SumTxFeeWithAncestors != nFeesCheck {
 			log.Error("the txentry's ancestors feew is incorrect: entry.SumTxFeeWithAncestors(%d), nFeesCheck(%d)",
 				entry.SumTxFeeWithAncestors, nFeesCheck)
@@ -0,0 +1,169 @@
+package lmempool
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// mempoolFileName is the file of the data dir the mempool is saved to.
+	mempoolFileName = "mempool.dat"
+
+	// mempoolDumpVersion is the version of the mempool file format.
+	mempoolDumpVersion = 1
+)
+
+// mempoolFileEntry is a transaction of the mempool file, along with the time
+// it entered the mempool.
+type mempoolFileEntry struct {
+	tx   *tx.Tx
+	time int64
+}
+
+func mempoolPath() string {
+	return filepath.Join(conf.Cfg.DataDir, mempoolFileName)
+}
+
+// writeMempool serializes entries: the format version, the number of
+// entries, then every transaction followed by its entry time.
+func writeMempool(w io.Writer, entries []mempoolFileEntry) error {
+	err := util.WriteElements(w, uint64(mempoolDumpVersion), uint64(len(entries)))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := entry.tx.Serialize(w); err != nil {
+			return err
+		}
+		if err := util.WriteElements(w, entry.time); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMempool deserializes entries written by writeMempool.
+func readMempool(r io.Reader) ([]mempoolFileEntry, error) {
+	var version, count uint64
+	if err := util.ReadElements(r, &version, &count); err != nil {
+		return nil, err
+	}
+	if version != mempoolDumpVersion {
+		return nil, fmt.Errorf("unsupported mempool file version %d", version)
+	}
+
+	// do not trust count for the allocation, the file may be corrupted
+	entries := make([]mempoolFileEntry, 0, 1024)
+	for i := uint64(0); i < count; i++ {
+		txn := tx.NewEmptyTx()
+		if err := txn.Unserialize(r); err != nil {
+			return nil, err
+		}
+		var acceptTime int64
+		if err := util.ReadElements(r, &acceptTime); err != nil {
+			return nil, err
+		}
+		entries = append(entries, mempoolFileEntry{tx: txn, time: acceptTime})
+	}
+	return entries, nil
+}
+
+// mempoolSnapshot returns the transactions of the mempool, parents before
+// their children so that they can be accepted again in order.
+func mempoolSnapshot() []mempoolFileEntry {
+	pool := mempool.GetInstance()
+	pool.RLock()
+	txEntries := make([]*mempool.TxEntry, 0, len(pool.GetAllTxEntryWithoutLock()))
+	for _, entry := range pool.GetAllTxEntryWithoutLock() {
+		txEntries = append(txEntries, entry)
+	}
+	// a transaction has more in-mempool ancestors than any of its parents
+	sort.Slice(txEntries, func(i, j int) bool {
+		if txEntries[i].SumTxCountWithAncestors != txEntries[j].SumTxCountWithAncestors {
+			return txEntries[i].SumTxCountWithAncestors < txEntries[j].SumTxCountWithAncestors
+		}
+		return txEntries[i].GetTime() < txEntries[j].GetTime()
+	})
+	entries := make([]mempoolFileEntry, 0, len(txEntries))
+	for _, entry := range txEntries {
+		entries = append(entries, mempoolFileEntry{tx: entry.Tx, time: entry.GetTime()})
+	}
+	pool.RUnlock()
+	return entries
+}
+
+// DumpMempool writes the transactions of the mempool to the data dir.
+func DumpMempool() error {
+	entries := mempoolSnapshot()
+
+	path := mempoolPath()
+	tmpPath := path + ".new"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	err = writeMempool(w, entries)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	log.Info("dumped %d transactions of the mempool", len(entries))
+	return nil
+}
+
+// LoadMempool accepts the transactions saved by DumpMempool into the mempool,
+// keeping the time they first entered it. Transactions which have expired
+// meanwhile or are no longer valid are skipped.
+func LoadMempool() error {
+	file, err := os.Open(mempoolPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	entries, err := readMempool(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+
+	expiryTime := util.GetTimeSec() - int64(conf.Cfg.Mempool.MaxPoolExpiry)*60*60
+	var accepted, failed, expired int
+	for _, entry := range entries {
+		if entry.time < expiryTime {
+			expired++
+			continue
+		}
+		if err := acceptTxToMemPoolWithTime(entry.tx, entry.time); err != nil {
+			log.Debug("load mempool tx %s failed: %v", entry.tx.GetHash(), err)
+			failed++
+			continue
+		}
+		accepted++
+	}
+	log.Info("loaded mempool: %d accepted, %d failed, %d expired", accepted, failed, expired)
+	return nil
+}
@@ -0,0 +1,61 @@
+package lmempool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+	"github.com/stretchr/testify/assert"
+)
+
+func mempoolFileTestEntries() []mempoolFileEntry {
+	var entries []mempoolFileEntry
+	for i := 0; i < 3; i++ {
+		txn := tx.NewTx(0, tx.DefaultVersion)
+		txn.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{byte(i + 1)}, uint32(i)),
+			script.NewScriptRaw([]byte{0x01, byte(i)}), 0xffffffff))
+		txn.AddTxOut(txout.NewTxOut(amount.Amount(1000*(i+1)), script.NewScriptRaw([]byte{0x51})))
+		entries = append(entries, mempoolFileEntry{tx: txn, time: 1546300800 + int64(i)})
+	}
+	return entries
+}
+
+func TestMempoolFileRoundTrip(t *testing.T) {
+	entries := mempoolFileTestEntries()
+
+	var buf bytes.Buffer
+	assert.Nil(t, writeMempool(&buf, entries))
+	read, err := readMempool(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, len(entries), len(read))
+	for i := range entries {
+		assert.Equal(t, entries[i].tx.GetHash(), read[i].tx.GetHash())
+		assert.Equal(t, entries[i].time, read[i].time)
+	}
+
+	var empty bytes.Buffer
+	assert.Nil(t, writeMempool(&empty, nil))
+	read, err = readMempool(&empty)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(read))
+}
+
+func TestMempoolFileInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, writeMempool(&buf, mempoolFileTestEntries()))
+	data := buf.Bytes()
+
+	_, err := readMempool(bytes.NewReader(data[:len(data)-4]))
+	assert.NotNil(t, err, "truncated file")
+
+	badVersion := append([]byte(nil), data...)
+	badVersion[0] = mempoolDumpVersion + 1
+	_, err = readMempool(bytes.NewReader(badVersion))
+	assert.NotNil(t, err, "unknown version")
+}
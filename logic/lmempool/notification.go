@@ -0,0 +1,34 @@
+package lmempool
+
+import (
+	"sync"
+
+	"github.com/copernet/copernicus/model/mempool"
+)
+
+// TxAcceptedCallback is called with every transaction accepted into the
+// mempool. It runs with the mempool locked, so it must not block nor call back
+// into the mempool.
+type TxAcceptedCallback func(txe *mempool.TxEntry)
+
+var (
+	txAcceptedLock      sync.RWMutex
+	txAcceptedCallbacks []TxAcceptedCallback
+)
+
+// SubscribeTxAccepted registers callback to be notified of the transactions
+// accepted into the mempool.
+func SubscribeTxAccepted(callback TxAcceptedCallback) {
+	txAcceptedLock.Lock()
+	txAcceptedCallbacks = append(txAcceptedCallbacks, callback)
+	txAcceptedLock.Unlock()
+}
+
+func notifyTxAccepted(txe *mempool.TxEntry) {
+	txAcceptedLock.RLock()
+	defer txAcceptedLock.RUnlock()
+
+	for _, callback := range txAcceptedCallbacks {
+		callback(txe)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"io"
 
 	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/net/wire"
 	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/bloom"
 	"gopkg.in/fatih/set.v0"
 )
 
@@ -42,6 +44,51 @@ func NewMerkleBlock(bk *block.Block, txids *set.Set) *MerkleBlock {
 	return &ret
 }
 
+// NewMerkleBlockWithFilter creates a merkle block of the transactions in the
+// block matching the bloom filter, updating the filter as they are matched.
+// The matched transactions are listed in MatchedTxn in block order.
+func NewMerkleBlockWithFilter(bk *block.Block, filter *bloom.Filter) *MerkleBlock {
+	ret := MerkleBlock{}
+
+	ret.Header = bk.Header
+
+	match := make([]bool, 0, len(bk.Txs))
+	hashes := make([]util.Hash, 0, len(bk.Txs))
+
+	for i, transaction := range bk.Txs {
+		txid := transaction.GetHash()
+		if filter.MatchTxAndUpdate(transaction) {
+			match = append(match, true)
+			ret.MatchedTxn = append(ret.MatchedTxn, Matched{N: i, H: txid})
+		} else {
+			match = append(match, false)
+		}
+
+		hashes = append(hashes, txid)
+	}
+	ret.Txn = NewPartialMerkleTree(hashes, match)
+
+	return &ret
+}
+
+// MsgMerkleBlock returns the merkleblock message sent to the peers.
+func (mb *MerkleBlock) MsgMerkleBlock() *wire.MsgMerkleBlock {
+	msg := wire.NewMsgMerkleBlock(&mb.Header)
+	msg.Transactions = uint32(mb.Txn.txs)
+	for i := range mb.Txn.hashes {
+		msg.AddTxHash(&mb.Txn.hashes[i])
+	}
+
+	msg.Flags = make([]byte, (len(mb.Txn.bits)+7)/8)
+	for p := 0; p < len(mb.Txn.bits); p++ {
+		if mb.Txn.bits[p] {
+			msg.Flags[p/8] |= uint8(1 << uint(p%8))
+		}
+	}
+
+	return msg
+}
+
 func (mb *MerkleBlock) Serialize(w io.Writer) (err error) {
 	err = mb.Header.Serialize(w)
 	if err != nil {
@@ -10,8 +10,10 @@ import (
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/txin"
 	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/net/wire"
 	"github.com/copernet/copernicus/persist"
 	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/bloom"
 	"github.com/magiconair/properties/assert"
 	"gopkg.in/fatih/set.v0"
 	"math"
@@ -63,3 +65,50 @@ func TestMerkleBlock(t *testing.T) {
 	assert.Equal(t, exp.Txn.hashes, mb.Txn.hashes)
 
 }
+
+func TestMerkleBlockWithFilter(t *testing.T) {
+	bk := block.NewBlock()
+	for i := 0; i < 10; i++ {
+		transaction := tx.NewTx(0, tx.DefaultVersion)
+		preOut := outpoint.NewOutPoint(*util.GetRandHash(), 0)
+		transaction.AddTxIn(txin.NewTxIn(preOut, script.NewEmptyScript(), math.MaxUint32-1))
+		pubKey := script.NewEmptyScript()
+		pubKey.PushOpCode(opcodes.OP_TRUE)
+		transaction.AddTxOut(txout.NewTxOut(10, pubKey))
+		bk.Txs = append(bk.Txs, transaction)
+	}
+	hash2 := bk.Txs[2].GetHash()
+	hash7 := bk.Txs[7].GetHash()
+
+	filter := bloom.NewFilter(10, 0, 0.000001, wire.BloomUpdateAll)
+	filter.AddHash(&hash2)
+	filter.AddHash(&hash7)
+
+	mb := NewMerkleBlockWithFilter(bk, filter)
+	assert.Equal(t, mb.MatchedTxn, []Matched{{N: 2, H: hash2}, {N: 7, H: hash7}})
+
+	// The message carries the same partial merkle tree.
+	msg := mb.MsgMerkleBlock()
+	buf := bytes.NewBuffer(nil)
+	assert.Equal(t, msg.Encode(buf, wire.ProtocolVersion, wire.BaseEncoding), nil)
+	decoded := &wire.MsgMerkleBlock{}
+	assert.Equal(t, decoded.Decode(buf, wire.ProtocolVersion, wire.BaseEncoding), nil)
+	assert.Equal(t, decoded.Header, bk.Header)
+
+	pmt := PartialMerkleTree{txs: int(decoded.Transactions)}
+	for _, hash := range decoded.Hashes {
+		pmt.hashes = append(pmt.hashes, *hash)
+	}
+	pmt.bits = make([]bool, len(decoded.Flags)*8)
+	for p := 0; p < len(pmt.bits); p++ {
+		pmt.bits[p] = decoded.Flags[p/8]&(1<<uint(p%8)) != 0
+	}
+
+	matches := make([]util.Hash, 0)
+	items := make([]int, 0)
+	root := pmt.ExtractMatches(&matches, &items)
+	expectedRoot := mb.Txn.ExtractMatches(&[]util.Hash{}, &[]int{})
+	assert.Equal(t, *root, *expectedRoot)
+	assert.Equal(t, matches, []util.Hash{hash2, hash7})
+	assert.Equal(t, items, []int{2, 7})
+}
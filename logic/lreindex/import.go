@@ -0,0 +1,267 @@
+package lreindex
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lblock"
+	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// importProgressInterval is how often the progress of an import is logged.
+	importProgressInterval = 10 * time.Second
+
+	// minBlockSize is the size of a block header.
+	minBlockSize = 80
+)
+
+var ErrImporting = errors.New("blocks are already being imported")
+
+var importing int32
+
+// IsImporting returns whether ImportBlocks is running.
+func IsImporting() bool {
+	return atomic.LoadInt32(&importing) != 0
+}
+
+// ImportBlocks reads the blocks of external block files, such as a
+// bootstrap.dat or the blk?????.dat files of another node, stores them in our
+// own block files and connects them. Each block must be prefixed by the disk
+// magic of the network and its size. Blocks may come in any order, and the ones
+// we have already are skipped. It returns the number of blocks imported, only
+// one import runs at a time.
+func ImportBlocks(filePaths []string) (int, error) {
+	if !atomic.CompareAndSwapInt32(&importing, 0, 1) {
+		return 0, ErrImporting
+	}
+	defer atomic.StoreInt32(&importing, 0)
+
+	nLoaded := 0
+	for _, filePath := range filePaths {
+		n, err := importBlockFile(filePath)
+		nLoaded += n
+		if err != nil {
+			log.Error("import blocks from %s failed: %v", filePath, err)
+			return nLoaded, err
+		}
+
+		persist.CsMain.Lock()
+		err = lchain.ActivateBestChain(nil)
+		persist.CsMain.Unlock()
+		if err != nil {
+			log.Error("ActivateBestChain failed after importing %s", filePath)
+			return nLoaded, err
+		}
+	}
+	log.Info("Imported %d blocks from %d files, chain height: %d",
+		nLoaded, len(filePaths), chain.GetInstance().Height())
+	return nLoaded, nil
+}
+
+// blockFileReader reads the blocks of an external block file.
+type blockFileReader struct {
+	file   *os.File
+	r      *bufio.Reader
+	magic  []byte
+	offset int64
+}
+
+func newBlockFileReader(file *os.File) *blockFileReader {
+	magic := make([]byte, 4)
+	binary.LittleEndian.PutUint32(magic, uint32(chain.GetInstance().GetParams().DiskMagic))
+	return &blockFileReader{
+		file:  file,
+		r:     bufio.NewReader(file),
+		magic: magic,
+	}
+}
+
+// next returns the next block of the file along with the offset of its size,
+// skipping anything which is not a block. It returns io.EOF at the end of the
+// file.
+func (bfr *blockFileReader) next() (*block.Block, int64, error) {
+	for {
+		if err := bfr.skipToMagic(); err != nil {
+			return nil, 0, err
+		}
+		offset := bfr.offset
+		blk, n, err := readBlock(bfr.r)
+		bfr.offset += n
+		if err == nil {
+			return blk, offset, nil
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, 0, io.EOF
+		}
+		log.Warn("skip bad block at offset %d: %v", offset, err)
+	}
+}
+
+func (bfr *blockFileReader) skipToMagic() error {
+	matched := 0
+	for matched < len(bfr.magic) {
+		b, err := bfr.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		bfr.offset++
+		if b == bfr.magic[matched] {
+			matched++
+		} else if b == bfr.magic[0] {
+			matched = 1
+		} else {
+			matched = 0
+		}
+	}
+	return nil
+}
+
+// readBlockAt reads the block whose size is at offset again.
+func (bfr *blockFileReader) readBlockAt(offset int64) (*block.Block, error) {
+	blk, _, err := readBlock(io.NewSectionReader(bfr.file, offset, 1<<62))
+	return blk, err
+}
+
+// readBlock reads a block prefixed by its size, returning the number of bytes
+// read.
+func readBlock(r io.Reader) (*block.Block, int64, error) {
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return nil, 0, err
+	}
+	if size < minBlockSize || uint64(size) > conf.Cfg.Excessiveblocksize {
+		return nil, 4, errors.New("bad block size")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, 4, err
+	}
+	blk := block.NewBlock()
+	if err := blk.Unserialize(bytes.NewReader(data)); err != nil {
+		return nil, 4 + int64(size), err
+	}
+	return blk, 4 + int64(size), nil
+}
+
+// importBlockFile accepts the blocks of the external block file, keeping the
+// blocks whose parent is not known yet until the parent shows up.
+func importBlockFile(filePath string) (nLoaded int, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	log.Info("Importing blocks from %s", filePath)
+	gChain := chain.GetInstance()
+	params := gChain.GetParams()
+	mapBlocksUnknownParent := make(map[util.Hash]*list.List)
+	bfr := newBlockFileReader(file)
+	nStartTime := time.Now()
+	lastProgress := nStartTime
+
+	for {
+		blk, offset, err := bfr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nLoaded, err
+		}
+
+		blkHash := blk.GetHash()
+		if blkHash != *params.GenesisHash && gChain.FindBlockIndex(blk.Header.HashPrevBlock) == nil {
+			log.Debug("Out of order block %s, parent %s not known", blkHash, blk.Header.HashPrevBlock)
+			if _, exist := mapBlocksUnknownParent[blk.Header.HashPrevBlock]; !exist {
+				mapBlocksUnknownParent[blk.Header.HashPrevBlock] = list.New()
+			}
+			mapBlocksUnknownParent[blk.Header.HashPrevBlock].PushBack(offset)
+			continue
+		}
+
+		if acceptImportedBlock(blk) {
+			nLoaded++
+		}
+
+		// Recursively process earlier encountered successors of this block
+		queue := list.New()
+		queue.PushBack(blkHash)
+		for queue.Len() > 0 {
+			head := queue.Remove(queue.Front()).(util.Hash)
+			itemList, ok := mapBlocksUnknownParent[head]
+			if !ok {
+				continue
+			}
+			delete(mapBlocksUnknownParent, head)
+			for e := itemList.Front(); e != nil; e = e.Next() {
+				child, err := bfr.readBlockAt(e.Value.(int64))
+				if err != nil {
+					log.Error("fail to read out of order block at offset %d: %v", e.Value.(int64), err)
+					continue
+				}
+				log.Debug("Processing out of order child %s of %s", child.GetHash(), head)
+				if acceptImportedBlock(child) {
+					nLoaded++
+				}
+				queue.PushBack(child.GetHash())
+			}
+		}
+
+		if time.Since(lastProgress) >= importProgressInterval {
+			lastProgress = time.Now()
+			log.Info("Imported %d blocks from %s (%.1f%%), best header height: %d",
+				nLoaded, filePath, float64(bfr.offset)*100/float64(fileInfo.Size()),
+				gChain.GetIndexBestHeader().Height)
+		}
+	}
+
+	if len(mapBlocksUnknownParent) > 0 {
+		log.Warn("%d blocks of %s have an unknown parent", len(mapBlocksUnknownParent), filePath)
+	}
+	log.Info("Loaded %d blocks from %s in %f seconds", nLoaded, filePath, time.Since(nStartTime).Seconds())
+	return nLoaded, nil
+}
+
+// acceptImportedBlock writes an imported block to our block files, returning
+// whether it was new. The genesis block is connected right away so that the
+// chain can grow on top of it.
+func acceptImportedBlock(blk *block.Block) bool {
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	gChain := chain.GetInstance()
+	blkHash := blk.GetHash()
+	if blkIndex := gChain.FindBlockIndex(blkHash); blkIndex != nil && blkIndex.HasData() {
+		return false
+	}
+
+	fNewBlock := false
+	if _, _, err := lblock.AcceptBlock(blk, true, nil, &fNewBlock); err != nil {
+		log.Error("accept imported block %s error: %v", blkHash, err)
+		return false
+	}
+	if blkHash == *gChain.GetParams().GenesisHash {
+		if err := lchain.ActivateBestChain(blk); err != nil {
+			log.Error("Activate the genesis block failed: %v", err)
+		}
+	}
+	return fNewBlock
+}
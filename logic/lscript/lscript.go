@@ -7,13 +7,53 @@ import (
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/token"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 )
 
+// ScriptExecutionMetrics holds the metrics collected while executing scripts.
+type ScriptExecutionMetrics struct {
+	// SigChecks is the number of signature checks performed, counted as
+	// defined by the SigChecks specification rather than by how many
+	// signatures were actually verified.
+	SigChecks int
+}
+
+// MaxInputSigChecks returns the maximum number of SigChecks an input with
+// the scriptSig scriptSig may perform when ScriptVerifyInputSigChecks is set.
+func MaxInputSigChecks(scriptSig *script.Script) int {
+	return (scriptSig.Size() + 60) / 43
+}
+
 func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *script.Script,
 	nIn int, value amount.Amount, flags uint32, scriptChecker Checker) error {
+	var metrics ScriptExecutionMetrics
+	return VerifyScriptWithMetrics(transaction, scriptSig, scriptPubKey, nIn, value, flags, scriptChecker, &metrics)
+}
+
+// VerifyScriptWithMetrics is VerifyScript which also accumulates the metrics
+// of the script execution into metrics.
+func VerifyScriptWithMetrics(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *script.Script,
+	nIn int, value amount.Amount, flags uint32, scriptChecker Checker, metrics *ScriptExecutionMetrics) error {
+	return verifyScript(transaction, scriptSig, scriptPubKey, nIn, value, flags, scriptChecker, metrics, nil)
+}
+
+// VerifyScriptWithContext is VerifyScriptWithMetrics for the input of context,
+// which also makes the spent coins available to the native introspection
+// opcodes.
+func VerifyScriptWithContext(context *ScriptExecutionContext, scriptSig *script.Script, scriptPubKey *script.Script,
+	flags uint32, scriptChecker Checker, metrics *ScriptExecutionMetrics) error {
+	nIn := context.InputIndex()
+	return verifyScript(context.Tx(), scriptSig, scriptPubKey, nIn, context.Coin(nIn).GetAmount(), flags,
+		scriptChecker, metrics, context)
+}
+
+func verifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *script.Script, nIn int,
+	value amount.Amount, flags uint32, scriptChecker Checker, metrics *ScriptExecutionMetrics,
+	context *ScriptExecutionContext) error {
+	sigChecks := metrics.SigChecks
 	if flags&script.ScriptEnableSigHashForkID == script.ScriptEnableSigHashForkID {
 		flags |= script.ScriptVerifyStrictEnc
 	}
@@ -22,12 +62,12 @@ func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *sc
 		return errcode.New(errcode.ScriptErrSigPushOnly)
 	}
 	stack := util.NewStack()
-	err := EvalScript(stack, scriptSig, transaction, nIn, value, flags, scriptChecker)
+	err := evalScript(stack, scriptSig, transaction, nIn, value, flags, scriptChecker, metrics, context)
 	if err != nil {
 		return err
 	}
 	stackCopy := stack.Copy()
-	err = EvalScript(stack, scriptPubKey, transaction, nIn, value, flags, scriptChecker)
+	err = evalScript(stack, scriptPubKey, transaction, nIn, value, flags, scriptChecker, metrics, context)
 	if err != nil {
 		return err
 	}
@@ -41,7 +81,7 @@ func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *sc
 		return errcode.New(errcode.ScriptErrEvalFalse)
 	}
 
-	if flags&script.ScriptVerifyP2SH == script.ScriptVerifyP2SH && scriptPubKey.IsPayToScriptHash() {
+	if flags&script.ScriptVerifyP2SH == script.ScriptVerifyP2SH && scriptPubKey.IsPayToScriptHashWithFlags(flags) {
 		if !scriptSig.IsPushOnly() {
 			log.Debug("ScriptErrScriptSigNotPushOnly")
 			return errcode.New(errcode.ScriptErrSigPushOnly)
@@ -50,7 +90,15 @@ func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *sc
 		topBytes := stack.Top(-1)
 		stack.Pop()
 		scriptPubKey2 := script.NewScriptRaw(topBytes.([]byte))
-		err = EvalScript(stack, scriptPubKey2, transaction, nIn, value, flags, scriptChecker)
+
+		// Bail out early if ScriptAllowSegwitRecovery is set, the redeem
+		// script is a p2sh segwit program, and it was the only item pushed
+		// onto the stack.
+		if flags&script.ScriptAllowSegwitRecovery != 0 && stack.Empty() && scriptPubKey2.IsWitnessProgram() {
+			return nil
+		}
+
+		err = evalScript(stack, scriptPubKey2, transaction, nIn, value, flags, scriptChecker, metrics, context)
 		if err != nil {
 			return err
 		}
@@ -81,11 +129,27 @@ func VerifyScript(transaction *tx.Tx, scriptSig *script.Script, scriptPubKey *sc
 			return errcode.New(errcode.ScriptErrCleanStack)
 		}
 	}
+
+	// The SigChecks of an input are limited relatively to the size of its
+	// scriptSig, so that the signature checks a block can require are
+	// bounded by its size.
+	if flags&script.ScriptVerifyInputSigChecks != 0 &&
+		metrics.SigChecks-sigChecks > MaxInputSigChecks(scriptSig) {
+		log.Debug("ScriptErrInputSigChecks")
+		return errcode.New(errcode.ScriptErrInputSigChecks)
+	}
 	return nil
 }
 
 func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int,
 	money amount.Amount, flags uint32, scriptChecker Checker) error {
+	var metrics ScriptExecutionMetrics
+	return evalScript(stack, s, transaction, nIn, money, flags, scriptChecker, &metrics, nil)
+}
+
+func evalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int,
+	money amount.Amount, flags uint32, scriptChecker Checker, metrics *ScriptExecutionMetrics,
+	context *ScriptExecutionContext) error {
 
 	if s.GetBadOpCode() {
 		log.Debug("ScriptErrBadOpCode, txid: %s, input: %d", transaction.GetHash().String(), nIn)
@@ -111,6 +175,11 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 		fRequireMinimal = false
 	}
 
+	maxNumSize := script.DefaultMaxNumSize
+	if flags&script.ScriptEnable64BitIntegers != 0 {
+		maxNumSize = script.MaxNumSize64Bit
+	}
+
 	var fExec bool
 	stackExec := util.NewStack()
 	stackAlt := util.NewStack()
@@ -606,7 +675,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				scriptNum, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				scriptNum, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 
@@ -788,15 +857,16 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
+				ok := true
 				switch e.OpValue {
 				case opcodes.OP_1ADD:
-					bn.Value += bnOne.Value
+					bn.Value, ok = script.SafeAdd(bn.Value, bnOne.Value)
 				case opcodes.OP_1SUB:
-					bn.Value -= bnOne.Value
+					bn.Value, ok = script.SafeSub(bn.Value, bnOne.Value)
 				case opcodes.OP_NEGATE:
 					bn.Value = -bn.Value
 				case opcodes.OP_ABS:
@@ -807,6 +877,10 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidOpCode")
 					return errcode.New(errcode.ScriptErrInvalidOpCode)
 				}
+				if !ok {
+					log.Debug("ScriptErrInvalidNumberRange64Bit")
+					return errcode.New(errcode.ScriptErrInvalidNumberRange64Bit)
+				}
 				stack.Pop()
 				stack.Push(bn.Serialize())
 
@@ -823,7 +897,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
@@ -851,6 +925,8 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				fallthrough
 			case opcodes.OP_SUB:
 				fallthrough
+			case opcodes.OP_MUL:
+				fallthrough
 			case opcodes.OP_DIV:
 				fallthrough
 			case opcodes.OP_MOD:
@@ -873,20 +949,23 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
-				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
 				bn := script.NewScriptNum(0)
+				ok := true
 				switch e.OpValue {
 				case opcodes.OP_ADD:
-					bn.Value = bn1.Value + bn2.Value
+					bn.Value, ok = script.SafeAdd(bn1.Value, bn2.Value)
 				case opcodes.OP_SUB:
-					bn.Value = bn1.Value - bn2.Value
+					bn.Value, ok = script.SafeSub(bn1.Value, bn2.Value)
+				case opcodes.OP_MUL:
+					bn.Value, ok = script.SafeMul(bn1.Value, bn2.Value)
 				case opcodes.OP_DIV:
 					// denominator must not be 0
 					if bn2.Value == 0 {
@@ -919,6 +998,10 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidOpCode")
 					return errcode.New(errcode.ScriptErrInvalidOpCode)
 				}
+				if !ok {
+					log.Debug("ScriptErrInvalidNumberRange64Bit")
+					return errcode.New(errcode.ScriptErrInvalidNumberRange64Bit)
+				}
 				stack.Pop()
 				stack.Pop()
 				stack.Push(bn.Serialize())
@@ -956,11 +1039,11 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
-				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
@@ -1060,15 +1143,15 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn1, err := script.GetScriptNum(vch1.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
-				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn2, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
-				bn3, err := script.GetScriptNum(vch3.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				bn3, err := script.GetScriptNum(vch3.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
@@ -1168,10 +1251,14 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				scriptCode.FindAndDelete(vchScript)*/
 				scriptCode = scriptCode.RemoveOpcodeByData(vchSigBytes)
 
-				fSuccess, err := scriptChecker.CheckSig(transaction, vchSigBytes, vchPubkey.([]byte), scriptCode, nIn, money, flags)
+				fSuccess, err := scriptChecker.CheckSig(transaction, vchSigBytes, vchPubkey.([]byte), scriptCode, nIn,
+					money, flags, context)
 				if err != nil {
 					return err
 				}
+				if len(vchSigBytes) > 0 {
+					metrics.SigChecks++
+				}
 
 				if !fSuccess &&
 					(flags&script.ScriptVerifyNullFail == script.ScriptVerifyNullFail) &&
@@ -1236,10 +1323,11 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				success := false
 				if len(vchSigBytes) > 0 {
 					vchHashs := util.Sha256Hash(vchMessage.([]byte))
-					success, err = scriptChecker.VerifySignature(vchSigBytes, ppubKey, &vchHashs)
+					success, err = scriptChecker.VerifySignature(vchSigBytes, ppubKey, &vchHashs, flags)
 					if err != nil {
 						log.Debug("verify error")
 					}
+					metrics.SigChecks++
 				}
 
 				if !success && ((flags & script.ScriptVerifyNullFail) != 0) && len(
@@ -1268,70 +1356,55 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				fallthrough
 			case opcodes.OP_CHECKMULTISIGVERIFY:
 
-				// ([sig ...] num_of_signatures [pubkey ...]
+				// ([dummy] [sig ...] num_of_signatures [pubkey ...]
 				// num_of_pubkeys -- bool)
-				i := 1
-				if stack.Size() < i {
-					log.Debug("ScriptErrInvalidStackOperation")
-					return errcode.New(errcode.ScriptErrInvalidStackOperation)
-				}
-
-				vch := stack.Top(-i)
-				if vch == nil {
+				idxKeyCount := 1
+				if stack.Size() < idxKeyCount {
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
 
 				// ScriptSig1 ScriptSig2...ScriptSigM M PubKey1 PubKey2...PubKey N
-				pubKeysNum, err := script.GetScriptNum(vch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				pubKeysNum, err := script.GetScriptNum(stack.Top(-idxKeyCount).([]byte), fRequireMinimal,
+					maxNumSize)
 				if err != nil {
-					//log.Debug("ScriptErrInvalidStackOperation")
 					return err
 				}
-				pubKeysCount := pubKeysNum.ToInt32()
+				pubKeysCount := int(pubKeysNum.ToInt32())
 				if pubKeysCount < 0 || pubKeysCount > script.MaxPubKeysPerMultiSig {
-					log.Debug("ScriptErrOpCount")
+					log.Debug("ScriptErrPubKeyCount")
 					return errcode.New(errcode.ScriptErrPubKeyCount)
 				}
-				nOpCount += int(pubKeysCount)
+				nOpCount += pubKeysCount
 				if nOpCount > script.MaxOpsPerScript {
 					log.Debug("ScriptErrOpCount")
 					return errcode.New(errcode.ScriptErrOpCount)
 				}
-				// skip N
-				i++
-				// PubKey start position
-				iPubKey := i
-				// iKey2 is the position of last non-signature item in
-				// the stack. Top stack item = 1. With
-				// ScriptVerifyNullFail, this is used for cleanup if
-				// operation fails.
-				iKey2 := pubKeysCount + 2
-				i += int(pubKeysCount)
-				if stack.Size() < i {
-					log.Debug("ScriptErrInvalidStackOperation")
-					return errcode.New(errcode.ScriptErrInvalidStackOperation)
-				}
-				sigsNumVch := stack.Top(-i)
-				if err != nil {
+
+				// stack depth of the top pubkey
+				idxTopKey := idxKeyCount + 1
+				// stack depth of the number of signatures
+				idxSigCount := idxTopKey + pubKeysCount
+				if stack.Size() < idxSigCount {
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				nSigsNum, err := script.GetScriptNum(sigsNumVch.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				nSigsNum, err := script.GetScriptNum(stack.Top(-idxSigCount).([]byte), fRequireMinimal,
+					maxNumSize)
 				if err != nil {
-					//log.Debug("ScriptErrInvalidStackOperation")
 					return err
 				}
-				nSigsCount := nSigsNum.ToInt32()
+				nSigsCount := int(nSigsNum.ToInt32())
 				if nSigsCount < 0 || nSigsCount > pubKeysCount {
 					log.Debug("ScriptErrSigCount")
 					return errcode.New(errcode.ScriptErrSigCount)
 				}
-				i++
-				/// Sig start position
-				iSig := i
-				i += int(nSigsCount)
-				if stack.Size() < i {
+
+				// stack depth of the top signature
+				idxTopSig := idxSigCount + 1
+				// stack depth of the dummy element
+				idxDummy := idxTopSig + nSigsCount
+				if stack.Size() < idxDummy {
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
@@ -1339,91 +1412,134 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				// Subset of script starting at the most recent codeSeparator
 				scriptCode := script.NewScriptOps(s.ParsedOpCodes[beginCodeHash:])
 
-				// Drop the signature in pre-segwit scripts but not segwit scripts
-				for k := 0; k < int(nSigsCount); k++ {
-					vchSig := stack.Top(-iSig - k)
-					if vchSig == nil {
-						log.Debug("ScriptErrInvalidStackOperation")
-						return errcode.New(errcode.ScriptErrInvalidStackOperation)
-					}
-					scriptCode = scriptCode.RemoveOpcodeByData(vchSig.([]byte))
-				}
 				fSuccess := true
-				for fSuccess && nSigsCount > 0 {
-					vchSig := stack.Top(-iSig)
-					if vchSig == nil {
-						log.Debug("ScriptErrInvalidStackOperation")
-						return errcode.New(errcode.ScriptErrInvalidStackOperation)
-					}
-					vchPubkey := stack.Top(-iPubKey)
-					if vchPubkey == nil {
-						log.Debug("ScriptErrInvalidStackOperation")
-						return errcode.New(errcode.ScriptErrInvalidStackOperation)
-					}
-					// Note how this makes the exact order of
-					// pubkey/signature evaluation distinguishable by
-					// CHECKMULTISIG NOT if the STRICTENC flag is set.
-					// See the script_(in)valid tests for details.
-					err := script.CheckTransactionSignatureEncoding(vchSig.([]byte), flags)
+				dummy := stack.Top(-idxDummy).([]byte)
+				if flags&script.ScriptEnableSchnorrMultisig != 0 && len(dummy) != 0 {
+					// Schnorr multisig: the dummy element is a bitfield
+					// selecting the public keys to check, in order,
+					// against the signatures.
+					checkBits, err := script.DecodeBitfield(dummy, pubKeysCount)
 					if err != nil {
 						return err
 					}
-					err = script.CheckPubKeyEncoding(vchPubkey.([]byte), flags)
-					if err != nil {
-						return err
+					if script.CountBits(checkBits) != nSigsCount {
+						log.Debug("ScriptErrInvalidBitCount")
+						return errcode.New(errcode.ScriptErrInvalidBitCount)
 					}
-					fOk, err := scriptChecker.CheckSig(transaction, vchSig.([]byte), vchPubkey.([]byte), scriptCode, nIn, money, flags)
-					if err != nil {
-						return err
+
+					idxBottomKey := idxTopKey + pubKeysCount - 1
+					idxBottomSig := idxTopSig + nSigsCount - 1
+					iKey := 0
+					for iSig := 0; iSig < nSigsCount; iSig++ {
+						// Find the next selected key, the bit count
+						// guarantees there is one.
+						for (checkBits>>uint(iKey))&0x01 == 0 {
+							iKey++
+						}
+
+						vchSig := stack.Top(-idxBottomSig + iSig).([]byte)
+						vchPubkey := stack.Top(-idxBottomKey + iKey).([]byte)
+						// Only the public keys selected by the bitfield
+						// are checked for validity.
+						if err := script.CheckTransactionSchnorrSignatureEncoding(vchSig, flags); err != nil {
+							return err
+						}
+						if err := script.CheckPubKeyEncoding(vchPubkey, flags); err != nil {
+							return err
+						}
+						fOk, err := scriptChecker.CheckSig(transaction, vchSig, vchPubkey, scriptCode, nIn, money,
+							flags|script.ScriptEnableSchnorr, context)
+						if err != nil {
+							return err
+						}
+						if !fOk {
+							// The signature may be empty, it is a null
+							// fail as well because the bitfield should
+							// have been null then.
+							log.Debug("ScriptErrSigNullFail")
+							return errcode.New(errcode.ScriptErrSigNullFail)
+						}
+						iKey++
 					}
-					if fOk {
-						iSig++
-						nSigsCount--
+					metrics.SigChecks += nSigsCount
+				} else {
+					// Legacy multisig, with ECDSA or null signatures.
+					//
+					// Drop the signature in pre-segwit scripts but not segwit scripts
+					for k := 0; k < nSigsCount; k++ {
+						scriptCode = scriptCode.RemoveOpcodeByData(stack.Top(-idxTopSig - k).([]byte))
 					}
-					iPubKey++
-					pubKeysCount--
-					// If there are more signatures left than keys left,
-					// then too many signatures have failed. Exit early,
-					// without checking any further signatures.
-					if nSigsCount > pubKeysCount {
-						fSuccess = false
+
+					nSigsRemaining := nSigsCount
+					nKeysRemaining := pubKeysCount
+					for fSuccess && nSigsRemaining > 0 {
+						vchSig := stack.Top(-idxTopSig - (nSigsCount - nSigsRemaining)).([]byte)
+						vchPubkey := stack.Top(-idxTopKey - (pubKeysCount - nKeysRemaining)).([]byte)
+						// Note how this makes the exact order of
+						// pubkey/signature evaluation distinguishable by
+						// CHECKMULTISIG NOT if the STRICTENC flag is set.
+						// See the script_(in)valid tests for details.
+						if err := script.CheckTransactionECDSASignatureEncoding(vchSig, flags); err != nil {
+							return err
+						}
+						if err := script.CheckPubKeyEncoding(vchPubkey, flags); err != nil {
+							return err
+						}
+						fOk, err := scriptChecker.CheckSig(transaction, vchSig, vchPubkey, scriptCode, nIn, money, flags,
+							context)
+						if err != nil {
+							return err
+						}
+						if fOk {
+							nSigsRemaining--
+						}
+						nKeysRemaining--
+						// If there are more signatures left than keys left,
+						// then too many signatures have failed. Exit early,
+						// without checking any further signatures.
+						if nSigsRemaining > nKeysRemaining {
+							fSuccess = false
+						}
 					}
-				}
-				// Clean up stack of actual arguments
-				for i > 1 {
+
 					// If the operation failed, we require that all
 					// signatures must be empty vector
-					if !fSuccess && (flags&script.ScriptVerifyNullFail == script.ScriptVerifyNullFail) &&
-						iKey2 == 0 && len(stack.Top(-1).([]byte)) > 0 {
+					areAllSignaturesNull := true
+					for k := 0; k < nSigsCount; k++ {
+						if len(stack.Top(-idxTopSig-k).([]byte)) > 0 {
+							areAllSignaturesNull = false
+							break
+						}
+					}
+					if !fSuccess && flags&script.ScriptVerifyNullFail == script.ScriptVerifyNullFail &&
+						!areAllSignaturesNull {
 						log.Debug("ScriptErrSigNullFail")
 						return errcode.New(errcode.ScriptErrSigNullFail)
+					}
 
+					// A legacy multisig counts as many SigChecks as it
+					// has public keys, unless all its signatures are
+					// null.
+					if !areAllSignaturesNull {
+						metrics.SigChecks += pubKeysCount
 					}
-					if iKey2 > 0 {
-						iKey2--
+
+					// A bug causes CHECKMULTISIG to consume one extra
+					// argument whose contents were not checked in any way.
+					//
+					// Unfortunately this is a potential source of
+					// mutability, so optionally verify it is exactly equal
+					// to zero.
+					if flags&script.ScriptVerifyNullDummy == script.ScriptVerifyNullDummy && len(dummy) > 0 {
+						log.Debug("ScriptErrSigNullDummy")
+						return errcode.New(errcode.ScriptErrSigNullDummy)
 					}
-					stack.Pop()
-					i--
-				}
-				// A bug causes CHECKMULTISIG to consume one extra
-				// argument whose contents were not checked in any way.
-				//
-				// Unfortunately this is a potential source of
-				// mutability, so optionally verify it is exactly equal
-				// to zero prior to removing it from the stack.
-				if stack.Size() < 1 {
-					log.Debug("ScriptErrInvalidStackOperation")
-					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
-				if flags&script.ScriptVerifyNullDummy == script.ScriptVerifyNullDummy &&
-					len(stack.Top(-1).([]byte)) > 0 {
-					log.Debug("ScriptErrSigNullDummy")
-					return errcode.New(errcode.ScriptErrSigNullDummy)
 
+				// Clean up stack of all the arguments, the dummy included
+				for k := 0; k < idxDummy; k++ {
+					stack.Pop()
 				}
-
-				//pop bug byte op_0, format: op0 sig1 sig2 m pubkey1 pubk2 pubkey3 n checkmultisig
-				stack.Pop()
 				if fSuccess {
 					stack.Push(bnTrue.Serialize())
 				} else {
@@ -1469,7 +1585,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 
 				vch1 := stack.Top(-2)
 				vch2 := stack.Top(-1)
-				scriptNum, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				scriptNum, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					log.Debug("ScriptErrInvalidStackOperation")
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
@@ -1501,7 +1617,7 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 					return errcode.New(errcode.ScriptErrInvalidStackOperation)
 				}
 				vch2 := stack.Top(-1)
-				scriptNum, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, script.DefaultMaxNumSize)
+				scriptNum, err := script.GetScriptNum(vch2.([]byte), fRequireMinimal, maxNumSize)
 				if err != nil {
 					return err
 				}
@@ -1555,12 +1671,209 @@ func EvalScript(stack *util.Stack, s *script.Script, transaction *tx.Tx, nIn int
 				vchEncode := script.MinimallyEncode(vch.([]byte))
 
 				// The resulting number must be a valid number.
-				if !script.IsMinimallyEncoded(vchEncode, script.DefaultMaxNumSize) {
+				if !script.IsMinimallyEncoded(vchEncode, int64(maxNumSize)) {
 					log.Debug("ScriptErrInvalidNumberRange")
 					return errcode.New(errcode.ScriptErrInvalidNumberRange)
 				}
 				stack.Pop()
 				stack.Push(vchEncode)
+			case opcodes.OP_REVERSEBYTES:
+				// Make sure this remains an error before activation
+				if flags&script.ScriptEnableOpReverseBytes == 0 {
+					log.Debug("ScriptErrBadOpCode")
+					return errcode.New(errcode.ScriptErrBadOpCode)
+				}
+
+				// (in -- out)
+				if stack.Size() < 1 {
+					log.Debug("ScriptErrInvalidStackOperation")
+					return errcode.New(errcode.ScriptErrInvalidStackOperation)
+				}
+
+				vch := stack.Top(-1).([]byte)
+				reversed := make([]byte, len(vch))
+				for k, b := range vch {
+					reversed[len(vch)-1-k] = b
+				}
+				stack.Pop()
+				stack.Push(reversed)
+
+				//
+				// Native introspection
+				//
+			case opcodes.OP_INPUTINDEX, opcodes.OP_ACTIVEBYTECODE, opcodes.OP_TXVERSION,
+				opcodes.OP_TXINPUTCOUNT, opcodes.OP_TXOUTPUTCOUNT, opcodes.OP_TXLOCKTIME:
+				// Make sure these remain errors before activation
+				if flags&script.ScriptEnableNativeIntrospection == 0 {
+					log.Debug("ScriptErrBadOpCode")
+					return errcode.New(errcode.ScriptErrBadOpCode)
+				}
+				if context == nil {
+					log.Debug("ScriptErrContextNotPresent")
+					return errcode.New(errcode.ScriptErrContextNotPresent)
+				}
+
+				// (-- out)
+				switch e.OpValue {
+				case opcodes.OP_INPUTINDEX:
+					stack.Push(script.NewScriptNum(int64(context.InputIndex())).Serialize())
+				case opcodes.OP_ACTIVEBYTECODE:
+					// The active bytecode starts after the last executed
+					// OP_CODESEPARATOR.
+					ops := s.ParsedOpCodes[beginCodeHash:]
+					if len(ops) > 0 && ops[0].OpValue == opcodes.OP_CODESEPARATOR {
+						ops = ops[1:]
+					}
+					activeBytecode := script.NewScriptOps(ops).GetData()
+					if len(activeBytecode) > script.MaxScriptElementSize {
+						log.Debug("ScriptErrPushSize")
+						return errcode.New(errcode.ScriptErrPushSize)
+					}
+					stack.Push(activeBytecode)
+				case opcodes.OP_TXVERSION:
+					stack.Push(script.NewScriptNum(int64(context.Tx().GetVersion())).Serialize())
+				case opcodes.OP_TXINPUTCOUNT:
+					stack.Push(script.NewScriptNum(int64(context.Tx().GetInsCount())).Serialize())
+				case opcodes.OP_TXOUTPUTCOUNT:
+					stack.Push(script.NewScriptNum(int64(context.Tx().GetOutsCount())).Serialize())
+				case opcodes.OP_TXLOCKTIME:
+					stack.Push(script.NewScriptNum(int64(context.Tx().GetLockTime())).Serialize())
+				}
+
+			case opcodes.OP_UTXOVALUE, opcodes.OP_UTXOBYTECODE, opcodes.OP_OUTPOINTTXHASH,
+				opcodes.OP_OUTPOINTINDEX, opcodes.OP_INPUTBYTECODE, opcodes.OP_INPUTSEQUENCENUMBER,
+				opcodes.OP_OUTPUTVALUE, opcodes.OP_OUTPUTBYTECODE:
+				// Make sure these remain errors before activation
+				if flags&script.ScriptEnableNativeIntrospection == 0 {
+					log.Debug("ScriptErrBadOpCode")
+					return errcode.New(errcode.ScriptErrBadOpCode)
+				}
+				if context == nil {
+					log.Debug("ScriptErrContextNotPresent")
+					return errcode.New(errcode.ScriptErrContextNotPresent)
+				}
+
+				// (index -- out)
+				if stack.Size() < 1 {
+					log.Debug("ScriptErrInvalidStackOperation")
+					return errcode.New(errcode.ScriptErrInvalidStackOperation)
+				}
+				indexNum, err := script.GetScriptNum(stack.Top(-1).([]byte), fRequireMinimal, maxNumSize)
+				if err != nil {
+					return err
+				}
+				index := indexNum.Value
+				transaction := context.Tx()
+
+				var result []byte
+				switch e.OpValue {
+				case opcodes.OP_UTXOVALUE, opcodes.OP_UTXOBYTECODE, opcodes.OP_OUTPOINTTXHASH,
+					opcodes.OP_OUTPOINTINDEX, opcodes.OP_INPUTBYTECODE, opcodes.OP_INPUTSEQUENCENUMBER:
+					if index < 0 || index >= int64(transaction.GetInsCount()) {
+						log.Debug("ScriptErrInvalidTxInputIndex")
+						return errcode.New(errcode.ScriptErrInvalidTxInputIndex)
+					}
+					in := transaction.GetTxIn(int(index))
+					switch e.OpValue {
+					case opcodes.OP_UTXOVALUE:
+						result = script.NewScriptNum(int64(context.Coin(int(index)).GetAmount())).Serialize()
+					case opcodes.OP_UTXOBYTECODE:
+						result = context.Coin(int(index)).GetScriptPubKeyWithFlags(flags).GetData()
+					case opcodes.OP_OUTPOINTTXHASH:
+						result = in.PreviousOutPoint.Hash.GetCloneBytes()
+					case opcodes.OP_OUTPOINTINDEX:
+						result = script.NewScriptNum(int64(in.PreviousOutPoint.Index)).Serialize()
+					case opcodes.OP_INPUTBYTECODE:
+						result = in.GetScriptSig().GetData()
+					case opcodes.OP_INPUTSEQUENCENUMBER:
+						result = script.NewScriptNum(int64(in.Sequence)).Serialize()
+					}
+				default:
+					if index < 0 || index >= int64(transaction.GetOutsCount()) {
+						log.Debug("ScriptErrInvalidTxOutputIndex")
+						return errcode.New(errcode.ScriptErrInvalidTxOutputIndex)
+					}
+					out := transaction.GetTxOut(int(index))
+					if e.OpValue == opcodes.OP_OUTPUTVALUE {
+						result = script.NewScriptNum(int64(out.GetValue())).Serialize()
+					} else {
+						result = out.GetScriptPubKeyWithFlags(flags).GetData()
+					}
+				}
+
+				if len(result) > script.MaxScriptElementSize {
+					log.Debug("ScriptErrPushSize")
+					return errcode.New(errcode.ScriptErrPushSize)
+				}
+				// Copy the result, the stack must not alias the transaction.
+				stack.Pop()
+				stack.Push(append([]byte{}, result...))
+
+				//
+				// Token introspection
+				//
+			case opcodes.OP_UTXOTOKENCATEGORY, opcodes.OP_UTXOTOKENCOMMITMENT, opcodes.OP_UTXOTOKENAMOUNT,
+				opcodes.OP_OUTPUTTOKENCATEGORY, opcodes.OP_OUTPUTTOKENCOMMITMENT, opcodes.OP_OUTPUTTOKENAMOUNT:
+				// Make sure these remain errors before activation
+				if flags&script.ScriptEnableTokens == 0 {
+					log.Debug("ScriptErrBadOpCode")
+					return errcode.New(errcode.ScriptErrBadOpCode)
+				}
+				if context == nil {
+					log.Debug("ScriptErrContextNotPresent")
+					return errcode.New(errcode.ScriptErrContextNotPresent)
+				}
+
+				// (index -- out)
+				if stack.Size() < 1 {
+					log.Debug("ScriptErrInvalidStackOperation")
+					return errcode.New(errcode.ScriptErrInvalidStackOperation)
+				}
+				indexNum, err := script.GetScriptNum(stack.Top(-1).([]byte), fRequireMinimal, maxNumSize)
+				if err != nil {
+					return err
+				}
+				index := indexNum.Value
+				transaction := context.Tx()
+
+				var tokenData *token.OutputData
+				switch e.OpValue {
+				case opcodes.OP_UTXOTOKENCATEGORY, opcodes.OP_UTXOTOKENCOMMITMENT, opcodes.OP_UTXOTOKENAMOUNT:
+					if index < 0 || index >= int64(transaction.GetInsCount()) {
+						log.Debug("ScriptErrInvalidTxInputIndex")
+						return errcode.New(errcode.ScriptErrInvalidTxInputIndex)
+					}
+					tokenData = context.Coin(int(index)).GetTokenData()
+				default:
+					if index < 0 || index >= int64(transaction.GetOutsCount()) {
+						log.Debug("ScriptErrInvalidTxOutputIndex")
+						return errcode.New(errcode.ScriptErrInvalidTxOutputIndex)
+					}
+					tokenData = transaction.GetTxOut(int(index)).GetTokenData()
+				}
+
+				// Without tokens, every opcode pushes an empty item, which
+				// is also the number 0 of OP_*TOKENAMOUNT.
+				var result []byte
+				if tokenData != nil {
+					switch e.OpValue {
+					case opcodes.OP_UTXOTOKENCATEGORY, opcodes.OP_OUTPUTTOKENCATEGORY:
+						// The capability of a mutable or minting non-fungible
+						// token is appended to the category.
+						result = tokenData.Category.GetCloneBytes()
+						if tokenData.HasNFT && tokenData.Capability != token.None {
+							result = append(result, byte(tokenData.Capability))
+						}
+					case opcodes.OP_UTXOTOKENCOMMITMENT, opcodes.OP_OUTPUTTOKENCOMMITMENT:
+						result = tokenData.Commitment
+					default:
+						result = script.NewScriptNum(tokenData.Amount).Serialize()
+					}
+				}
+
+				// Copy the result, the stack must not alias the transaction.
+				stack.Pop()
+				stack.Push(append([]byte{}, result...))
 			default:
 				return errcode.New(errcode.ScriptErrBadOpCode)
 			}
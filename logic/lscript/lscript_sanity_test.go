@@ -1275,3 +1275,47 @@ func TestScriptuint32(t *testing.T) {
 	}
 
 }
+
+func TestSegwitRecovery(t *testing.T) {
+	flags := uint32(script.ScriptVerifyP2SH | script.ScriptVerifyCleanStack | script.ScriptAllowSegwitRecovery)
+
+	// OP_0 <20 bytes>, a p2sh wrapped p2wpkh redeem script
+	p2wpkh := NewScriptBuilder().PushOPCode(OP_0).PushBytesWithOP(bytes.Repeat([]byte{0x01}, 20)).Script()
+	// OP_16 <40 bytes>, the largest witness program
+	maxProgram := NewScriptBuilder().PushOPCode(OP_16).PushBytesWithOP(bytes.Repeat([]byte{0x02}, 40)).Script()
+	// OP_0 <41 bytes> is too long to be a witness program
+	tooLong := NewScriptBuilder().PushOPCode(OP_0).PushBytesWithOP(bytes.Repeat([]byte{0x03}, 41)).Script()
+	// OP_1NEGATE is not a valid witness version
+	badVersion := NewScriptBuilder().PushOPCode(OP_1NEGATE).PushBytesWithOP(bytes.Repeat([]byte{0x04}, 20)).Script()
+
+	tests := []*TestBuilder{
+		NewTestBuilder(p2wpkh, "P2SH segwit recovery", flags, true, 0).PushRedeem(),
+		NewTestBuilder(maxProgram, "P2SH segwit recovery, version 16 program", flags, true, 0).PushRedeem(),
+		NewTestBuilder(p2wpkh, "P2SH segwit recovery, without recovery flag",
+			uint32(script.ScriptVerifyP2SH|script.ScriptVerifyCleanStack), true, 0).
+			PushRedeem().
+			ScriptError(errcode.ScriptErrCleanStack),
+		NewTestBuilder(p2wpkh, "P2SH segwit recovery, extra item on the stack", flags, true, 0).
+			Num(1).
+			PushRedeem().
+			ScriptError(errcode.ScriptErrCleanStack),
+		NewTestBuilder(tooLong, "P2SH segwit recovery, not a witness program", flags, true, 0).
+			PushRedeem().
+			ScriptError(errcode.ScriptErrCleanStack),
+		NewTestBuilder(badVersion, "P2SH segwit recovery, invalid witness version", flags, true, 0).
+			PushRedeem().
+			ScriptError(errcode.ScriptErrCleanStack),
+	}
+
+	for _, test := range tests {
+		test.Test(t)
+	}
+
+	// the redeem script has to match the p2sh hash
+	scriptPubKey := NewScriptBuilder().PushOPCode(OP_HASH160).
+		PushBytesWithOP(util.Hash160(maxProgram.Bytes())).
+		PushOPCode(OP_EQUAL).Script()
+	scriptSig := NewScriptBuilder().PushBytesWithOP(p2wpkh.Bytes()).Script()
+	DoTest(t, scriptPubKey, scriptSig, flags, "P2SH segwit recovery, redeem script mismatch",
+		errcode.ScriptErrEvalFalse, 0)
+}
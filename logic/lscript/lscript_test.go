@@ -12,9 +12,11 @@ import (
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/token"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/txin"
 	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 	"io/ioutil"
@@ -121,6 +123,15 @@ var scriptFlagMap = map[string]uint32{
 	"SIGHASH_FORKID":             script.ScriptEnableSigHashForkID,
 	"REPLAY_PROTECTION":          script.ScriptEnableReplayProtection,
 	"CHECKDATASIG":               script.ScriptEnableCheckDataSig,
+	"SCHNORR":                    script.ScriptEnableSchnorr,
+	"ALLOW_SEGWIT_RECOVERY":      script.ScriptAllowSegwitRecovery,
+	"SCHNORR_MULTISIG":           script.ScriptEnableSchnorrMultisig,
+	"INPUT_SIGCHECKS":            script.ScriptVerifyInputSigChecks,
+	"REVERSEBYTES":               script.ScriptEnableOpReverseBytes,
+	"64_BIT_INTEGERS":            script.ScriptEnable64BitIntegers,
+	"NATIVE_INTROSPECTION":       script.ScriptEnableNativeIntrospection,
+	"TOKENS":                     script.ScriptEnableTokens,
+	"P2SH_32":                    script.ScriptEnableP2SH32,
 }
 
 type scriptErrChecker struct {
@@ -312,7 +323,10 @@ func doScriptJSONTest(t *testing.T, itest []interface{}, sec scriptErrChecker) (
 	trax.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(pretx.GetHash(), 0), scriptSig, script.SequenceFinal))
 	trax.AddTxOut(txout.NewTxOut(amount.Amount(nValue), script.NewScriptRaw([]byte{})))
 
-	err = VerifyScript(trax, scriptSig, scriptPubKey, 0, amount.Amount(nValue), flags, NewScriptRealChecker())
+	coins := []*utxo.Coin{utxo.NewFreshCoin(pretx.GetTxOut(0), 1, false)}
+	var metrics ScriptExecutionMetrics
+	err = VerifyScriptWithContext(NewScriptExecutionContext(trax, 0, coins), scriptSig, scriptPubKey, flags,
+		NewScriptRealChecker(), &metrics)
 
 	if err = sec.check(err, scriptErrorString); err != nil {
 		for _, v := range test {
@@ -559,3 +573,99 @@ func TestIsPushOnlyOnInvalidScripts(t *testing.T) {
 		t.Errorf("IsPushOnly should return false on invalid scripts")
 	}
 }
+
+func TestTokenIntrospection(t *testing.T) {
+	category := util.Hash{0x01, 0x02, 0x03}
+	mutable := &token.OutputData{Category: category, Amount: 500, HasNFT: true, Capability: token.Mutable,
+		Commitment: []byte{0xcc}}
+	fungible := &token.OutputData{Category: category, Amount: 300}
+	immutable := &token.OutputData{Category: category, HasNFT: true, Commitment: []byte{0xdd, 0xee}}
+
+	newOut := func(tokenData *token.OutputData) *txout.TxOut {
+		out := txout.NewTxOut(1000, script.NewScriptRaw([]byte{opcodes.OP_TRUE}))
+		out.SetTokenData(tokenData)
+		return out
+	}
+
+	trax := tx.NewTx(0, 2)
+	trax.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{0x0a}, 0), script.NewEmptyScript(), script.SequenceFinal))
+	trax.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{0x0b}, 0), script.NewEmptyScript(), script.SequenceFinal))
+	trax.AddTxOut(newOut(fungible))
+	trax.AddTxOut(newOut(immutable))
+	trax.AddTxOut(newOut(nil))
+	coins := []*utxo.Coin{utxo.NewFreshCoin(newOut(mutable), 1, false), utxo.NewFreshCoin(newOut(nil), 1, false)}
+	context := NewScriptExecutionContext(trax, 0, coins)
+
+	tests := []struct {
+		opcode int
+		index  int64
+		expect []byte
+	}{
+		{opcodes.OP_UTXOTOKENCATEGORY, 0, append(category.GetCloneBytes(), byte(token.Mutable))},
+		{opcodes.OP_UTXOTOKENCOMMITMENT, 0, []byte{0xcc}},
+		{opcodes.OP_UTXOTOKENAMOUNT, 0, script.NewScriptNum(500).Serialize()},
+		{opcodes.OP_UTXOTOKENCATEGORY, 1, []byte{}},
+		{opcodes.OP_UTXOTOKENCOMMITMENT, 1, []byte{}},
+		{opcodes.OP_UTXOTOKENAMOUNT, 1, []byte{}},
+		{opcodes.OP_OUTPUTTOKENCATEGORY, 0, category.GetCloneBytes()},
+		{opcodes.OP_OUTPUTTOKENCOMMITMENT, 0, []byte{}},
+		{opcodes.OP_OUTPUTTOKENAMOUNT, 0, script.NewScriptNum(300).Serialize()},
+		// The capability of an immutable non-fungible token is not pushed.
+		{opcodes.OP_OUTPUTTOKENCATEGORY, 1, category.GetCloneBytes()},
+		{opcodes.OP_OUTPUTTOKENCOMMITMENT, 1, []byte{0xdd, 0xee}},
+		{opcodes.OP_OUTPUTTOKENAMOUNT, 1, []byte{}},
+		{opcodes.OP_OUTPUTTOKENCATEGORY, 2, []byte{}},
+	}
+
+	for _, test := range tests {
+		s := script.NewEmptyScript()
+		s.PushInt64(test.index)
+		s.PushOpCode(test.opcode)
+
+		stack := util.NewStack()
+		var metrics ScriptExecutionMetrics
+		err := evalScript(stack, s, trax, 0, 0, script.ScriptEnableTokens, NewScriptEmptyChecker(), &metrics, context)
+		if err != nil {
+			t.Errorf("%s %d: unexpected error %v", opcodes.GetOpName(test.opcode), test.index, err)
+			continue
+		}
+		if stack.Size() != 1 || !bytes.Equal(stack.Top(-1).([]byte), test.expect) {
+			t.Errorf("%s %d: expect %x, actual %x", opcodes.GetOpName(test.opcode), test.index, test.expect,
+				stack.Top(-1))
+		}
+	}
+
+	// The script must get a copy of the commitment.
+	s := script.NewEmptyScript()
+	s.PushInt64(0)
+	s.PushOpCode(opcodes.OP_UTXOTOKENCOMMITMENT)
+	stack := util.NewStack()
+	var metrics ScriptExecutionMetrics
+	if err := evalScript(stack, s, trax, 0, 0, script.ScriptEnableTokens, NewScriptEmptyChecker(), &metrics,
+		context); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	stack.Top(-1).([]byte)[0] = 0x00
+	if mutable.Commitment[0] != 0xcc {
+		t.Errorf("the token commitment was modified through the stack")
+	}
+
+	// Until tokens are enabled, the token prefix is part of the locking script.
+	for _, flags := range []uint32{script.ScriptEnableNativeIntrospection,
+		script.ScriptEnableNativeIntrospection | script.ScriptEnableTokens} {
+		s = script.NewEmptyScript()
+		s.PushInt64(0)
+		s.PushOpCode(opcodes.OP_UTXOBYTECODE)
+		stack = util.NewStack()
+		if err := evalScript(stack, s, trax, 0, 0, flags, NewScriptEmptyChecker(), &metrics, context); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		expect := coins[0].GetScriptPubKeyWithFlags(flags).GetData()
+		if !bytes.Equal(stack.Top(-1).([]byte), expect) {
+			t.Errorf("flags %x: expect %x, actual %x", flags, expect, stack.Top(-1))
+		}
+		if tokens := flags&script.ScriptEnableTokens != 0; tokens != (len(expect) == 1) {
+			t.Errorf("flags %x: unexpected locking script %x", flags, expect)
+		}
+	}
+}
@@ -12,6 +12,6 @@ type Checker interface {
 	CheckLockTime(lockTime int64, txLockTime int64, sequence uint32) bool
 	CheckSequence(sequence int64, txToSequence int64, txVersion uint32) bool
 	CheckSig(transaction *tx.Tx, signature []byte, pubKey []byte, scriptCode *script.Script,
-		nIn int, money amount.Amount, flags uint32) (bool, error)
-	VerifySignature(vchSig []byte, pubKey *crypto.PublicKey, sigHash *util.Hash) (bool, error)
+		nIn int, money amount.Amount, flags uint32, context *ScriptExecutionContext) (bool, error)
+	VerifySignature(vchSig []byte, pubKey *crypto.PublicKey, sigHash *util.Hash, flags uint32) (bool, error)
 }
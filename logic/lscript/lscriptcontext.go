@@ -0,0 +1,55 @@
+package lscript
+
+import (
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+)
+
+// ScriptExecutionContext is the transaction an input script is executed in,
+// along with the coins spent by every input of that transaction. It is what
+// the native introspection opcodes read from.
+type ScriptExecutionContext struct {
+	tx    *tx.Tx
+	nIn   int
+	coins []*utxo.Coin
+}
+
+// NewScriptExecutionContext returns the context of the input nIn of
+// transaction, coins being the coins spent by its inputs, in order.
+func NewScriptExecutionContext(transaction *tx.Tx, nIn int, coins []*utxo.Coin) *ScriptExecutionContext {
+	if len(coins) != transaction.GetInsCount() {
+		panic("the script execution context needs the coin spent by every input")
+	}
+	if nIn < 0 || nIn >= transaction.GetInsCount() {
+		panic("the script execution context input index is out of range")
+	}
+
+	return &ScriptExecutionContext{tx: transaction, nIn: nIn, coins: coins}
+}
+
+// Tx returns the transaction the script is executed in.
+func (ctx *ScriptExecutionContext) Tx() *tx.Tx {
+	return ctx.tx
+}
+
+// InputIndex returns the index of the input the script is executed for.
+func (ctx *ScriptExecutionContext) InputIndex() int {
+	return ctx.nIn
+}
+
+// Coin returns the coin spent by the input at index.
+func (ctx *ScriptExecutionContext) Coin(index int) *utxo.Coin {
+	return ctx.coins[index]
+}
+
+// SpentOutputs returns the outputs spent by every input of the transaction,
+// in order, for SIGHASH_UTXOS and the token prefix of the signature hash.
+func (ctx *ScriptExecutionContext) SpentOutputs() []*txout.TxOut {
+	outs := make([]*txout.TxOut, len(ctx.coins))
+	for index, coin := range ctx.coins {
+		out := coin.GetTxOut()
+		outs[index] = &out
+	}
+	return outs
+}
@@ -13,7 +13,7 @@ type EmptyChecker struct {
 }
 
 func (sec *EmptyChecker) CheckSig(transaction *tx.Tx, signature []byte, pubKey []byte, scriptCode *script.Script,
-	nIn int, money amount.Amount, flags uint32) (bool, error) {
+	nIn int, money amount.Amount, flags uint32, context *ScriptExecutionContext) (bool, error) {
 	return false, errcode.New(errcode.ScriptErrInvalidOpCode)
 }
 
@@ -26,7 +26,7 @@ func (sec *EmptyChecker) CheckSequence(sequence int64, txToSequence int64, txVer
 	return false
 }
 
-func (sec *EmptyChecker) VerifySignature(vchSig []byte, pubKey *crypto.PublicKey, sigHash *util.Hash) (bool, error) {
+func (sec *EmptyChecker) VerifySignature(vchSig []byte, pubKey *crypto.PublicKey, sigHash *util.Hash, flags uint32) (bool, error) {
 	return false, nil
 }
 
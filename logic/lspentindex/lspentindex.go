@@ -0,0 +1,95 @@
+package lspentindex
+
+import (
+	"errors"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/persist/blkdb"
+)
+
+// spentIndexFlag records in the block tree db whether the blocks connected
+// so far have been indexed.
+const spentIndexFlag = "spentindex"
+
+var ErrReindexRequired = errors.New("you need to rebuild the database using --reindex to change --spentindex")
+
+// IsEnabled returns whether the node maintains the spent index (-spentindex).
+func IsEnabled() bool {
+	return conf.Cfg != nil && conf.Cfg.Chain.SpentIndex
+}
+
+// Start checks that the spent index is in line with the configuration, it
+// can only be turned on or off before any block is connected.
+func Start() error {
+	btd := blkdb.GetInstance()
+	if btd.ReadFlag(spentIndexFlag) == IsEnabled() {
+		return nil
+	}
+	if chain.GetInstance().Height() > 0 {
+		return ErrReindexRequired
+	}
+	return btd.WriteFlag(spentIndexFlag, IsEnabled())
+}
+
+// IndexBlock records the inputs of a connected block by the outputs they
+// spend.
+func IndexBlock(blk *block.Block, pindex *blockindex.BlockIndex) error {
+	spents := make(map[outpoint.OutPoint]blkdb.SpentInfo)
+	for _, transaction := range blk.Txs[1:] {
+		txid := transaction.GetHash()
+		for i, in := range transaction.GetIns() {
+			spents[*in.PreviousOutPoint] = blkdb.SpentInfo{
+				TxID:       txid,
+				InputIndex: uint32(i),
+				Height:     pindex.Height,
+			}
+		}
+	}
+	return blkdb.GetInstance().WriteSpentIndex(spents)
+}
+
+// UnindexBlock reverts IndexBlock for a block disconnected from the tip.
+func UnindexBlock(blk *block.Block) error {
+	outs := make([]outpoint.OutPoint, 0)
+	for _, transaction := range blk.Txs[1:] {
+		for _, in := range transaction.GetIns() {
+			outs = append(outs, *in.PreviousOutPoint)
+		}
+	}
+	return blkdb.GetInstance().EraseSpentIndex(outs)
+}
+
+// GetSpentInfo returns the input spending out, looking in the mempool first.
+// It returns nil if out is unspent.
+func GetSpentInfo(out *outpoint.OutPoint) (*blkdb.SpentInfo, error) {
+	if info := mempoolSpentInfo(out); info != nil {
+		return info, nil
+	}
+	return blkdb.GetInstance().ReadSpentIndex(out)
+}
+
+func mempoolSpentInfo(out *outpoint.OutPoint) *blkdb.SpentInfo {
+	pool := mempool.GetInstance()
+	pool.RLock()
+	defer pool.RUnlock()
+
+	entry := pool.HasSPentOutWithoutLock(out)
+	if entry == nil {
+		return nil
+	}
+	for i, in := range entry.Tx.GetIns() {
+		if *in.PreviousOutPoint == *out {
+			return &blkdb.SpentInfo{
+				TxID:       entry.Tx.GetHash(),
+				InputIndex: uint32(i),
+				Height:     -1,
+			}
+		}
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/model/undo"
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/util"
@@ -38,6 +39,7 @@ type ScriptVerifyJob struct {
 	Flags                  uint32
 	ScriptChecker          lscript.Checker
 	ScriptVerifyResultChan chan ScriptVerifyResult
+	Context                *lscript.ScriptExecutionContext
 }
 
 type ScriptVerifyResult struct {
@@ -45,6 +47,7 @@ type ScriptVerifyResult struct {
 	ScriptSig    *script.Script
 	ScriptPubKey *script.Script
 	InputNum     int
+	SigChecks    int
 	Err          error
 }
 
@@ -53,8 +56,8 @@ type SignError struct {
 	ErrMsg string
 }
 
-func verifyResult(j ScriptVerifyJob, err error) ScriptVerifyResult {
-	return ScriptVerifyResult{j.Tx.GetHash(), j.ScriptSig, j.ScriptPubKey, j.IputNum, err}
+func verifyResult(j ScriptVerifyJob, sigChecks int, err error) ScriptVerifyResult {
+	return ScriptVerifyResult{j.Tx.GetHash(), j.ScriptSig, j.ScriptPubKey, j.IputNum, sigChecks, err}
 }
 
 const (
@@ -94,6 +97,19 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 		}
 	}
 
+	// Before the May 2023 upgrade, token prefixes and P2SH32 are nothing but
+	// unusual locking scripts, which are not relayed.
+	if !model.IsUpgrade9Enabled(chain.GetInstance().Tip().GetMedianTimePast()) {
+		for _, out := range txn.GetOuts() {
+			if out.GetTokenData() != nil || out.HasInvalidTokenPrefix() {
+				return nil, errcode.NewError(errcode.RejectNonstandard, "txn-tokens-before-activation")
+			}
+			if out.GetScriptPubKey().IsPayToScriptHash32() {
+				return nil, errcode.NewError(errcode.RejectNonstandard, "scriptpubkey")
+			}
+		}
+	}
+
 	// check common locktime, sequence final can disable it
 	err := ContextualCheckTransactionForCurrentBlock(txn, int(tx.StandardLockTimeVerifyFlags))
 	if err != nil {
@@ -149,9 +165,11 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 		return nil, errcode.NewError(errcode.RejectNonstandard, "non-BIP68-final")
 	}
 
+	tip := chain.GetInstance().Tip()
+
 	//check standard inputs
 	if model.ActiveNetParams.RequireStandard {
-		if !AreInputsStandard(txn, inputCoins) {
+		if !AreInputsStandard(txn, inputCoins, model.IsPhononEnabled(tip.GetMedianTimePast())) {
 			return nil, errcode.NewError(errcode.RejectNonstandard, "bad-txns-nonstandard-inputs")
 		}
 	}
@@ -160,9 +178,11 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 	// sigops, making it impossible to mine. Since the coinbase transaction
 	// itself can contain sigops MAX_STANDARD_TX_SIGOPS is less than
 	// MAX_BLOCK_SIGOPS_PER_MB; we still consider this an invalid rather
-	// than merely non-standard transaction.
+	// than merely non-standard transaction. Once the phonon upgrade is
+	// activated, sigops are no longer limited and SigChecks are checked
+	// below instead.
 	sigOpsCount := GetTransactionSigOpCount(txn, uint32(script.StandardScriptVerifyFlags), inputCoins)
-	if uint(sigOpsCount) > tx.MaxStandardTxSigOps {
+	if !model.IsPhononEnabled(tip.GetMedianTimePast()) && uint(sigOpsCount) > tx.MaxStandardTxSigOps {
 		return nil, errcode.NewError(errcode.RejectNonstandard, "bad-txns-too-many-sigops")
 	}
 
@@ -176,7 +196,6 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 	//TODO: check absurdly-high-fee (nFees > nAbsurdFee)
 
 	var extraFlags uint32 = script.ScriptVerifyNone
-	tip := chain.GetInstance().Tip()
 
 	if model.IsReplayProtectionEnabled(tip.GetMedianTimePast()) {
 		extraFlags |= script.ScriptEnableReplayProtection
@@ -186,20 +205,57 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 		extraFlags |= script.ScriptEnableCheckDataSig
 	}
 
+	if model.IsGreatWallEnabled(tip.GetMedianTimePast()) {
+		extraFlags |= script.ScriptEnableSchnorr
+	}
+
+	if model.IsGravitonEnabled(tip.GetMedianTimePast()) {
+		extraFlags |= script.ScriptEnableSchnorrMultisig
+	}
+
+	if model.IsPhononEnabled(tip.GetMedianTimePast()) {
+		extraFlags |= script.ScriptEnableOpReverseBytes
+	}
+
+	if model.IsUpgrade8Enabled(tip.GetMedianTimePast()) {
+		extraFlags |= script.ScriptEnable64BitIntegers
+		extraFlags |= script.ScriptEnableNativeIntrospection
+	}
+
+	if model.IsUpgrade9Enabled(tip.GetMedianTimePast()) {
+		extraFlags |= script.ScriptEnableTokens
+		extraFlags |= script.ScriptEnableP2SH32
+	}
+
 	//check inputs
 	var scriptVerifyFlags = uint32(script.StandardScriptVerifyFlags)
 	if !model.ActiveNetParams.RequireStandard {
 		scriptVerifyFlags = promiscuousMempoolFlags() | script.ScriptEnableSigHashForkID
 	}
+	// Segwit recovery spends carry no signature, anyone relaying one could
+	// redirect the coins, so they are only valid in blocks and never standard.
+	scriptVerifyFlags &^= script.ScriptAllowSegwitRecovery
 	scriptVerifyFlags |= extraFlags
 
+	if model.IsUpgrade9Enabled(tip.GetMedianTimePast()) {
+		if err := checkTxTokens(txn, spentCoins(txn, inputCoins)); err != nil {
+			return nil, err
+		}
+	}
+
 	// Check against previous transactions. This is done last to help
 	// prevent CPU exhaustion denial-of-service attacks.
-	err = checkInputs(txn, inputCoins, scriptVerifyFlags, txScriptVerifyResultChan)
+	sigChecks, err := checkInputs(txn, inputCoins, scriptVerifyFlags, txScriptVerifyResultChan)
 	if err != nil {
 		return nil, err
 	}
 
+	// Check that the transaction doesn't perform an excessive number of
+	// signature checks, as counted while executing its scripts above.
+	if sigChecks > tx.MaxStandardTxSigChecks {
+		return nil, errcode.NewError(errcode.RejectNonstandard, "bad-txns-too-many-sigchecks")
+	}
+
 	// Check again against the current block tip's script verification flags
 	// to cache our script execution flags. This is, of course, useless if
 	// the next block has different script flags from the previous one, but
@@ -216,12 +272,12 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 	// invalid blocks (using TestBlockValidity), however allowing such
 	// transactions into the mempool can be exploited as a DoS attack.
 	var currentBlockScriptVerifyFlags = chain.GetInstance().GetBlockScriptFlags(tip)
-	err = checkInputs(txn, inputCoins, currentBlockScriptVerifyFlags, txScriptVerifyResultChan)
+	_, err = checkInputs(txn, inputCoins, currentBlockScriptVerifyFlags, txScriptVerifyResultChan)
 	if err != nil {
 		if ((^scriptVerifyFlags) & currentBlockScriptVerifyFlags) == 0 {
 			return nil, errcode.New(errcode.ScriptCheckInputsBug)
 		}
-		err = checkInputs(txn, inputCoins, uint32(script.MandatoryScriptVerifyFlags)|extraFlags, txScriptVerifyResultChan)
+		_, err = checkInputs(txn, inputCoins, uint32(script.MandatoryScriptVerifyFlags)|extraFlags, txScriptVerifyResultChan)
 		if err != nil {
 			return nil, err
 		}
@@ -231,7 +287,7 @@ func CheckTxBeforeAcceptToMemPool(txn *tx.Tx) (*mempool.TxEntry, error) {
 	}
 
 	txEntry := mempool.NewTxentry(txn, txFee, util.GetTimeSec(),
-		chain.GetInstance().Height(), *lp, sigOpsCount, spendCoinbase)
+		chain.GetInstance().Height(), *lp, sigOpsCount, sigChecks, spendCoinbase)
 
 	return txEntry, nil
 }
@@ -262,7 +318,7 @@ func promiscuousMempoolFlags() uint32 {
 }
 
 // CheckBlockTransactions block service use these 3 func to check transactions or to apply transaction while connecting block to active chain
-func CheckBlockTransactions(txs []*tx.Tx, maxBlockSigOps uint64) error {
+func CheckBlockTransactions(txs []*tx.Tx) error {
 	txsLen := len(txs)
 	if txsLen == 0 {
 		log.Debug("block has no transactions")
@@ -272,16 +328,9 @@ func CheckBlockTransactions(txs []*tx.Tx, maxBlockSigOps uint64) error {
 	if err != nil {
 		return err
 	}
-	sigOps := txs[0].GetSigOpCountWithoutP2SH(uint32(script.StandardScriptVerifyFlags))
 
 	TxsInputOutpoint := make(map[outpoint.OutPoint]bool)
-	for i, transaction := range txs[1:] {
-		sigOps += txs[i+1].GetSigOpCountWithoutP2SH(uint32(script.StandardScriptVerifyFlags))
-		if uint64(sigOps) > maxBlockSigOps {
-			log.Debug("block has too many sigOps:%d", sigOps)
-			return errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops")
-		}
-
+	for _, transaction := range txs[1:] {
 		err := transaction.CheckRegularTransactionWhenNewBlock(TxsInputOutpoint)
 		if err != nil {
 			return err
@@ -290,6 +339,20 @@ func CheckBlockTransactions(txs []*tx.Tx, maxBlockSigOps uint64) error {
 	return nil
 }
 
+// CheckBlockSigOps checks the legacy sigops of the transactions of a block,
+// P2SH excluded, against the limit of the blocks before the phonon upgrade.
+func CheckBlockSigOps(txs []*tx.Tx, maxBlockSigOps uint64) error {
+	sigOps := 0
+	for _, transaction := range txs {
+		sigOps += transaction.GetSigOpCountWithoutP2SH(uint32(script.StandardScriptVerifyFlags))
+		if uint64(sigOps) > maxBlockSigOps {
+			log.Debug("block has too many sigOps:%d", sigOps)
+			return errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops")
+		}
+	}
+	return nil
+}
+
 func ContextureCheckBlockTransactions(txs []*tx.Tx, blockHeight int32, blockLockTime, mediaTimePast int64) error {
 	txsLen := len(txs)
 	if txsLen == 0 {
@@ -326,14 +389,16 @@ func ContextureCheckBlockTransactions(txs []*tx.Tx, blockHeight int32, blockLock
 	return nil
 }
 
-func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uint32,
+// ApplyBlockTransactions checks and applies the transactions of a block on top
+// of view, returning the resulting coins in a new map based on view.
+func ApplyBlockTransactions(txs []*tx.Tx, view *utxo.CoinsMap, bip30Enable bool, scriptCheckFlags uint32,
 	needCheckScript bool, blockSubSidy amount.Amount, blockHeight int32, blockMaxSigOpsCount uint64,
 	lockTimeFlags uint32, pindex *blockindex.BlockIndex) (coinMap *utxo.CoinsMap, bundo *undo.BlockUndo, err error) {
 
 	// make view
-	coinsMap := utxo.NewEmptyCoinsMap()
-	utxoCache := utxo.GetUtxoCacheInstance()
+	coinsMap := utxo.NewCoinsMapWithBase(view)
 	sigOpsCount := 0
+	sigChecksCount := 0
 	var fees amount.Amount
 	bundo = undo.NewBlockUndo(0)
 
@@ -342,7 +407,7 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 		for _, transaction := range txs {
 			outs := transaction.GetOuts()
 			for i := range outs {
-				if utxoCache.HaveCoin(outpoint.NewOutPoint(transaction.GetHash(), uint32(i))) {
+				if coinsMap.HaveCoin(outpoint.NewOutPoint(transaction.GetHash(), uint32(i))) {
 					log.Debug("tried to overwrite transaction")
 					return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txns-BIP30")
 				}
@@ -353,17 +418,17 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 	txUndoList := make([]*undo.TxUndo, 0, len(txs)-1)
 	isMagneticAnomalyEnabled := model.IsMagneticAnomalyEnabled(pindex.GetMedianTimePast())
 
+	// Once the phonon upgrade is activated, the signature checks a block
+	// requires are limited by counting SigChecks during script execution
+	// rather than legacy sigops.
+	isPhononEnabled := model.IsPhononEnabled(pindex.Prev.GetMedianTimePast())
+	isUpgrade9Enabled := model.IsUpgrade9Enabled(pindex.Prev.GetMedianTimePast())
+	blockMaxSigChecksCount := consensus.GetMaxBlockSigChecksCount(conf.Cfg.Excessiveblocksize)
+
 	for _, ptx := range txs {
-		//pos := block.DiskTxPos{
-		//	BlockIn:    &blkPos,
-		//	TxOffsetIn: util.VarIntSerializeSize(uint64(len(txs))),
-		//}
-		//vPos = make(map[util.Hash]block.DiskTxPos)
-		//pos = vPos[ptx.GetHash()]
-		//pos.TxOffsetIn += ptx.EncodeSize()
-
-		if ptx.IsCoinBase() {
-			// We've already checked for sigops count before P2SH in CheckBlock.
+		if ptx.IsCoinBase() && !isPhononEnabled {
+			// We've already checked for sigops count before P2SH in
+			// ContextualCheckBlock.
 			sigOpsCount += ptx.GetSigOpCountWithoutP2SH(scriptCheckFlags)
 		}
 
@@ -394,32 +459,54 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 			return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txns-nonfinal")
 		}
 
-		// GetTransactionSigOpCount counts 2 types of sigops:
-		// * legacy (always)
-		// * p2sh (when P2SH enabled in flags and excludes coinbase)
-		sigsCount := GetTransactionSigOpCount(transaction, scriptCheckFlags, coinsMap)
-		if sigsCount > tx.MaxTxSigOpsCounts {
-			log.Debug("transaction has too many sigops")
-			return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txn-sigops")
-		}
-		sigOpsCount += sigsCount
-		if sigOpsCount > int(blockMaxSigOpsCount) {
-			log.Debug("block has too many sigops at %d transaction", i)
-			return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops")
+		if !isPhononEnabled {
+			// GetTransactionSigOpCount counts 2 types of sigops:
+			// * legacy (always)
+			// * p2sh (when P2SH enabled in flags and excludes coinbase)
+			sigsCount := GetTransactionSigOpCount(transaction, scriptCheckFlags, coinsMap)
+			if sigsCount > tx.MaxTxSigOpsCounts {
+				log.Debug("transaction has too many sigops")
+				return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txn-sigops")
+			}
+			sigOpsCount += sigsCount
+			if sigOpsCount > int(blockMaxSigOpsCount) {
+				log.Debug("block has too many sigops at %d transaction", i)
+				return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops")
+			}
 		}
 
 		fee := coinsMap.GetValueIn(transaction) - transaction.GetValueOut()
 		fees += fee
 
+		// The tokens are checked with the values, whether the scripts are
+		// checked or not.
+		if isUpgrade9Enabled {
+			if err := checkTxTokens(transaction, spentCoins(transaction, coinsMap)); err != nil {
+				return nil, nil, err
+			}
+		}
+
 		if needCheckScript {
 			//check inputs
-			err := checkInputs(transaction, coinsMap, scriptCheckFlags, blockScriptVerifyResultChan)
+			sigChecks, err := checkInputs(transaction, coinsMap, scriptCheckFlags, blockScriptVerifyResultChan)
 			if err != nil {
 				if strings.Contains(err.Error(), "script-verify") {
 					return nil, nil, errcode.NewError(errcode.RejectInvalid, "blk-bad-inputs")
 				}
 				return nil, nil, err
 			}
+
+			if isPhononEnabled {
+				if sigChecks > consensus.MaxTxSigChecks {
+					log.Debug("transaction has too many sigchecks")
+					return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-txn-sigchecks")
+				}
+				sigChecksCount += sigChecks
+				if uint64(sigChecksCount) > blockMaxSigChecksCount {
+					log.Debug("block has too many sigchecks at %d transaction", i)
+					return nil, nil, errcode.NewError(errcode.RejectInvalid, "bad-blk-sigchecks")
+				}
+			}
 		}
 
 		//update temp coinsMap
@@ -478,13 +565,21 @@ func ContextualCheckTransaction(txn *tx.Tx, nBlockHeight int32, nLockTimeCutoff,
 	//}
 
 	if model.IsMagneticAnomalyEnabled(mediaTimePast) {
+		minTxSize := uint32(consensus.MinTxSize)
+		if model.IsUpgrade9Enabled(mediaTimePast) {
+			minTxSize = consensus.MinTxSizeUpgrade9
+		}
 		txnsize := txn.SerializeSize()
-		if txnsize < consensus.MinTxSize {
+		if txnsize < minTxSize {
 			e := fmt.Sprintf("bad-txns-undersize: tx(%d) should be equal to or greater than %d",
-				txnsize, consensus.MinTxSize)
+				txnsize, minTxSize)
 			return errcode.NewError(errcode.RejectInvalid, e)
 		}
 	}
+
+	if model.IsUpgrade9Enabled(mediaTimePast) {
+		return checkTokenOutputs(txn)
+	}
 	return nil
 }
 
@@ -557,8 +652,8 @@ func getP2SHSigOpCount(txn *tx.Tx, flags uint32, coinMap *utxo.CoinsMap) int {
 			panic("can't find coin in temp coinsmap")
 		}
 
-		scriptPubKey := coin.GetScriptPubKey()
-		if scriptPubKey.IsPayToScriptHash() {
+		scriptPubKey := coin.GetScriptPubKeyWithFlags(flags)
+		if scriptPubKey.IsPayToScriptHashWithFlags(flags) {
 			sigsCount := scriptPubKey.GetPubKeyP2SHSigOpCount(flags, txin.GetScriptSig())
 			n += sigsCount
 		}
@@ -598,7 +693,11 @@ func ContextualCheckTransactionForCurrentBlock(transaction *tx.Tx, flags int) er
 		nLockTimeCutoff, activeChain.Tip().GetMedianTimePast())
 }
 
-func AreInputsStandard(transaction *tx.Tx, coinsMap *utxo.CoinsMap) bool {
+// AreInputsStandard checks that the coins spent by transaction are standard
+// and, before the phonon upgrade, that the redeem scripts of its P2SH inputs
+// don't have too many sigops. Once it is activated, sigops are no longer
+// limited, the SigChecks of every input are.
+func AreInputsStandard(transaction *tx.Tx, coinsMap *utxo.CoinsMap, phononEnabled bool) bool {
 	ins := transaction.GetIns()
 	for i, e := range ins {
 		coin := coinsMap.GetCoin(e.PreviousOutPoint)
@@ -627,6 +726,9 @@ func AreInputsStandard(transaction *tx.Tx, coinsMap *utxo.CoinsMap) bool {
 				return false
 			}
 
+			if phononEnabled {
+				continue
+			}
 			subScript := script.NewScriptRaw(scriptSig.ParsedOpCodes[len(scriptSig.ParsedOpCodes)-1].Data)
 			opCount := subScript.GetSigOpCount(uint32(script.StandardCheckDataSigVerifyFlags), true)
 			if uint(opCount) > tx.MaxP2SHSigOps {
@@ -639,24 +741,32 @@ func AreInputsStandard(transaction *tx.Tx, coinsMap *utxo.CoinsMap) bool {
 	return true
 }
 
+// checkInputs checks the money and the scripts of the inputs of tx, returning
+// the number of SigChecks the scripts performed.
 func checkInputs(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32,
-	scriptVerifyResultChan chan ScriptVerifyResult) error {
+	scriptVerifyResultChan chan ScriptVerifyResult) (int, error) {
 	//check inputs money range
 	bestBlockHash, _ := utxo.GetUtxoCacheInstance().GetBestBlock()
 	spendHeight := chain.GetInstance().GetSpendHeight(&bestBlockHash)
 	if spendHeight == -1 {
 		log.Debug("indexMap can`t find bestblock")
-		return errcode.New(errcode.RejectInvalid)
+		return 0, errcode.New(errcode.RejectInvalid)
 	}
 
 	err := CheckInputsMoney(tx, tempCoinMap, spendHeight)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	sigChecks := 0
+
 	ins := tx.GetIns()
 	insLen := len(ins)
 
+	// The coins spent by every input are part of the context of each script,
+	// for the native introspection opcodes.
+	coins := spentCoins(tx, tempCoinMap)
+
 	batches := insLen / MaxScriptVerifyJobNum
 	reminder := insLen % MaxScriptVerifyJobNum
 	if reminder > 0 {
@@ -673,15 +783,13 @@ func checkInputs(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32,
 		for j := 0; j < jobNum; j++ {
 			index := batch*MaxScriptVerifyJobNum + j
 
-			coin := tempCoinMap.GetCoin(ins[index].PreviousOutPoint)
-			if coin == nil {
-				panic("can't find coin in temp coinsmap")
-			}
-			scriptPubKey := coin.GetScriptPubKey()
+			coin := coins[index]
+			scriptPubKey := coin.GetScriptPubKeyWithFlags(flags)
 			scriptSig := ins[index].GetScriptSig()
 			log.Debug("Push Script verify job txid: %s, inex: %d", tx.GetHash().String(), index)
 			scriptVerifyJobChan <- ScriptVerifyJob{tx, scriptSig, scriptPubKey, index,
-				coin.GetAmount(), flags, lscript.NewScriptRealChecker(), scriptVerifyResultChan}
+				coin.GetAmount(), flags, lscript.NewScriptRealChecker(), scriptVerifyResultChan,
+				lscript.NewScriptExecutionContext(tx, index, coins)}
 		}
 
 		var err error
@@ -689,6 +797,7 @@ func checkInputs(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32,
 		//drain all result from channel
 		for k := 0; k < jobNum; k++ {
 			result := <-scriptVerifyResultChan
+			sigChecks += result.SigChecks
 			if result.Err != nil {
 				log.Debug("Read script verify err result: %v, tx hash: %s, index: %d, "+
 					"len of scriptVerifyResultChan: %d", result.Err, result.TxHash.String(),
@@ -700,35 +809,39 @@ func checkInputs(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32,
 		}
 
 		if err != nil {
-			return err
+			return 0, err
 		}
 	}
 
-	return nil
+	return sigChecks, nil
 }
 
 func checkScript() {
 	for {
 		j := <-scriptVerifyJobChan
 
-		err1 := lscript.VerifyScript(j.Tx, j.ScriptSig, j.ScriptPubKey, j.IputNum, j.Value, j.Flags, j.ScriptChecker)
+		var metrics lscript.ScriptExecutionMetrics
+		err1 := lscript.VerifyScriptWithContext(j.Context, j.ScriptSig, j.ScriptPubKey, j.Flags,
+			j.ScriptChecker, &metrics)
 		if err1 != nil {
 
 			hasNonMandatoryFlags := (j.Flags & uint32(script.StandardNotMandatoryVerifyFlags)) != 0
 			if hasNonMandatoryFlags {
 				fallbackFlags := uint32(uint64(j.Flags) & uint64(^script.StandardNotMandatoryVerifyFlags))
-				err2 := lscript.VerifyScript(j.Tx, j.ScriptSig, j.ScriptPubKey, j.IputNum, j.Value, fallbackFlags, j.ScriptChecker)
+				var fallbackMetrics lscript.ScriptExecutionMetrics
+				err2 := lscript.VerifyScriptWithContext(j.Context, j.ScriptSig, j.ScriptPubKey, fallbackFlags,
+					j.ScriptChecker, &fallbackMetrics)
 				if err2 == nil {
-					j.ScriptVerifyResultChan <- verifyResult(j, errorNonMandatoryPass(j, err1))
+					j.ScriptVerifyResultChan <- verifyResult(j, 0, errorNonMandatoryPass(j, err1))
 					continue
 				}
 			}
 
-			j.ScriptVerifyResultChan <- verifyResult(j, errorMandatoryFailed(j, err1))
+			j.ScriptVerifyResultChan <- verifyResult(j, 0, errorMandatoryFailed(j, err1))
 			continue
 		}
 
-		j.ScriptVerifyResultChan <- verifyResult(j, nil)
+		j.ScriptVerifyResultChan <- verifyResult(j, metrics.SigChecks, nil)
 	}
 }
 
@@ -951,6 +1064,18 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 	mergedTx := transactions[0]
 	hashSingle := int(hashType) & ^(crypto.SigHashAnyoneCanpay|crypto.SigHashForkID) == crypto.SigHashSingle
 
+	// The signature hash commits to the outputs spent by the transaction, so
+	// the signatures are made and checked along with the coins of all inputs.
+	coins := make([]*utxo.Coin, 0, mergedTx.GetInsCount())
+	for _, in := range mergedTx.GetIns() {
+		coin := coinsMap.GetCoin(in.PreviousOutPoint)
+		if !isCoinValid(coin, in.PreviousOutPoint) {
+			coins = nil
+			break
+		}
+		coins = append(coins, coin)
+	}
+
 	for index, in := range mergedTx.GetIns() {
 		coin := coinsMap.GetCoin(in.PreviousOutPoint)
 		if !isCoinValid(coin, in.PreviousOutPoint) {
@@ -964,18 +1089,24 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 		scriptSig := script.NewEmptyScript()
 		scriptPubKey := coin.GetScriptPubKey()
 		value := coin.GetAmount()
+		var context *lscript.ScriptExecutionContext
+		var spentOutputs []*txout.TxOut
+		if coins != nil {
+			context = lscript.NewScriptExecutionContext(mergedTx, index, coins)
+			spentOutputs = context.SpentOutputs()
+		}
 
 		// Only sign SIGHASH_SINGLE if there's a corresponding output
 		if !hashSingle || index < mergedTx.GetOutsCount() {
 			redeemScript := redeemScripts[*in.PreviousOutPoint]
 			// Sign what we can
-			sigData, err := mergedTx.SignStep(index, keyStore, redeemScript, hashType, scriptPubKey, value)
+			sigData, err := mergedTx.SignStep(index, keyStore, redeemScript, hashType, scriptPubKey, value,
+				spentOutputs)
 			if err != nil {
 				log.Info("SignStep error:%s", err.Error())
 			} else {
 				scriptSig.PushMultData(sigData)
-				err = lscript.VerifyScript(mergedTx, scriptSig, scriptPubKey, index, value,
-					uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
+				err = verifySignature(context, mergedTx, scriptSig, scriptPubKey, index, value)
 				if err != nil {
 					scriptSig = script.NewEmptyScript()
 					log.Info("VerifyScript error:%s", err.Error())
@@ -986,9 +1117,16 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 		// ... and merge in other signatures
 		for _, transaction := range transactions {
 			if len(transaction.GetIns()) > index {
-				scriptSig, err = CombineSignature(transaction, scriptPubKey, scriptSig,
-					transaction.GetIns()[index].GetScriptSig(), index, value,
-					uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
+				if context != nil {
+					scriptSig, err = CombineSignatureWithContext(context, scriptPubKey, scriptSig,
+						transaction.GetIns()[index].GetScriptSig(),
+						uint32(script.StandardScriptVerifyFlags)|script.ScriptEnableTokens,
+						lscript.NewScriptRealChecker())
+				} else {
+					scriptSig, err = CombineSignature(transaction, scriptPubKey, scriptSig,
+						transaction.GetIns()[index].GetScriptSig(), index, value,
+						uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
+				}
 				if err != nil {
 					log.Info("CombineSignature error:%s", err.Error())
 				}
@@ -1000,8 +1138,7 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 			log.Info("UpdateInScript error:%s", err.Error())
 		}
 
-		err = lscript.VerifyScript(mergedTx, scriptSig, scriptPubKey, index, value,
-			uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
+		err = verifySignature(context, mergedTx, scriptSig, scriptPubKey, index, value)
 		if err != nil {
 			signErrors = append(signErrors, &SignError{
 				TxIn:   in,
@@ -1013,9 +1150,40 @@ func SignRawTransaction(transactions []*tx.Tx, redeemScripts map[outpoint.OutPoi
 	return signErrors
 }
 
+// verifySignature verifies the signed scriptSig of the input nIn, with the
+// token prefix of the spent outputs in the signature hash if context has them.
+func verifySignature(context *lscript.ScriptExecutionContext, transaction *tx.Tx, scriptSig *script.Script,
+	scriptPubKey *script.Script, nIn int, value amount.Amount) error {
+	flags := uint32(script.StandardScriptVerifyFlags)
+	if context == nil {
+		return lscript.VerifyScript(transaction, scriptSig, scriptPubKey, nIn, value, flags,
+			lscript.NewScriptRealChecker())
+	}
+	var metrics lscript.ScriptExecutionMetrics
+	return lscript.VerifyScriptWithContext(context, scriptSig, scriptPubKey, flags|script.ScriptEnableTokens,
+		lscript.NewScriptRealChecker(), &metrics)
+}
+
 func CombineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *script.Script,
 	txOldScriptSig *script.Script, nIn int, money amount.Amount, flags uint32,
 	scriptChecker lscript.Checker) (*script.Script, error) {
+	return combineSignature(transaction, prevPubKey, scriptSig, txOldScriptSig, nIn, money, flags, scriptChecker,
+		nil)
+}
+
+// CombineSignatureWithContext is CombineSignature for the input of context,
+// whose signatures are checked along with the coins spent by the transaction.
+func CombineSignatureWithContext(context *lscript.ScriptExecutionContext, prevPubKey *script.Script,
+	scriptSig *script.Script, txOldScriptSig *script.Script, flags uint32,
+	scriptChecker lscript.Checker) (*script.Script, error) {
+	nIn := context.InputIndex()
+	return combineSignature(context.Tx(), prevPubKey, scriptSig, txOldScriptSig, nIn, context.Coin(nIn).GetAmount(),
+		flags, scriptChecker, context)
+}
+
+func combineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *script.Script,
+	txOldScriptSig *script.Script, nIn int, money amount.Amount, flags uint32,
+	scriptChecker lscript.Checker, context *lscript.ScriptExecutionContext) (*script.Script, error) {
 	if scriptSig == nil {
 		scriptSig = script.NewEmptyScript()
 	}
@@ -1058,7 +1226,8 @@ func CombineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *
 				if okSigs[string(pubKey)] != nil {
 					continue
 				}
-				ok, err := scriptChecker.CheckSig(transaction, opCode.Data, pubKey, prevPubKey, nIn, money, flags)
+				ok, err := scriptChecker.CheckSig(transaction, opCode.Data, pubKey, prevPubKey, nIn, money,
+					flags, context)
 				if err == nil && ok {
 					okSigs[string(pubKey)] = opCode.Data
 					break
@@ -1096,8 +1265,8 @@ func CombineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *
 		redeemScript := script.NewScriptRaw(scriptSig.ParsedOpCodes[len(scriptSig.ParsedOpCodes)-1].Data)
 		scriptSig = scriptSig.RemoveOpCodeByIndex(len(scriptSig.ParsedOpCodes) - 1)
 		txOldScriptSig = txOldScriptSig.RemoveOpCodeByIndex(len(txOldScriptSig.ParsedOpCodes) - 1)
-		scriptResult, err := CombineSignature(transaction, redeemScript, scriptSig,
-			txOldScriptSig, nIn, money, flags, scriptChecker)
+		scriptResult, err := combineSignature(transaction, redeemScript, scriptSig,
+			txOldScriptSig, nIn, money, flags, scriptChecker, context)
 		scriptResult.PushSingleData(redeemScript.GetData())
 		return scriptResult, err
 	}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"os"
 	"reflect"
@@ -1667,6 +1668,10 @@ func txWithTooManyScriptOps(prevout util.Hash, variant uint) *tx.Tx {
 func Test_tx_with_too_many_script_ops_should_NOT_be_accepted_into_mempool(t *testing.T) {
 	defer initTestEnv()()
 
+	// sigops are only limited before the phonon upgrade
+	conf.Args.PhononActivationTime = math.MaxInt64
+	defer func() { conf.Args.PhononActivationTime = -1 }()
+
 	blocks := generateTestBlocks(t)
 	txn := txWithTooManyScriptOps(blocks[0].Txs[0].GetHash(), 0)
 	err := lmempool.AcceptTxToMemPool(txn)
@@ -1708,7 +1713,7 @@ func Test_block_txns__should_contains_one_coinbase_tx(t *testing.T) {
 	txn := mainNetTx(1)
 	txns := []*tx.Tx{txn}
 
-	err := ltx.CheckBlockTransactions(txns, 0)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-cb-missing"), err)
 }
@@ -1728,7 +1733,7 @@ func newCoinbaseTx() *tx.Tx {
 func Test_block_txns__should_at_least_contains_one_txn(t *testing.T) {
 	txns := []*tx.Tx{}
 
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-cb-missing"), err)
 }
@@ -1737,7 +1742,7 @@ func Test_block_txns__should_contains_one_coinbase_tx__happy_case(t *testing.T)
 	coinbaseTx := newCoinbaseTx()
 	txns := []*tx.Tx{coinbaseTx}
 
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.NoError(t, err)
 }
@@ -1746,7 +1751,7 @@ func Test_block_txns__should_only_has_one_coinbase_tx(t *testing.T) {
 	coinbaseTx := newCoinbaseTx()
 	txns := []*tx.Tx{coinbaseTx, coinbaseTx}
 
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-tx-coinbase"), err)
 }
@@ -1760,9 +1765,11 @@ func Test_block_txns__should_not_contains_too_much_script_ops__in_total(t *testi
 	txn5 := txWithTooManyScriptOps(util.HashOne, 5)
 
 	txns := []*tx.Tx{coinbaseTx, txn1, txn2, txn3, txn4, txn5}
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockSigOps(txns, consensus.MaxBlockSigopsPerMb)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-blk-sigops"), err)
+	assert.NoError(t, ltx.CheckBlockSigOps(txns[:4], consensus.MaxBlockSigopsPerMb))
+	assert.NoError(t, ltx.CheckBlockTransactions(txns))
 }
 
 func Test_block_txns__should_not_contains_duplicate_prev_outpoints(t *testing.T) {
@@ -1778,7 +1785,7 @@ func Test_block_txns__should_not_contains_duplicate_prev_outpoints(t *testing.T)
 	txOut := makeOuts()[0]
 	txn2.AddTxOut(txOut)
 	txns := []*tx.Tx{coinbaseTx, txn1, txn2}
-	err := ltx.CheckBlockTransactions(txns, consensus.MaxBlockSigopsPerMb)
+	err := ltx.CheckBlockTransactions(txns)
 
 	assert.Equal(t, errcode.NewError(errcode.RejectInvalid, "bad-txns-inputs-duplicate"), err)
 }
@@ -2061,7 +2068,7 @@ func TestAreInputStandard_for_P2PK_and_P2PKH(t *testing.T) {
 
 	txn.AddTxOut(txout.NewTxOut(amount.Amount(90*util.COIN), script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
 
-	assert.True(t, ltx.AreInputsStandard(txn, coins))
+	assert.True(t, ltx.AreInputsStandard(txn, coins, false))
 }
 
 func TestAreInputStandard_for_checkmultisig(t *testing.T) {
@@ -2072,7 +2079,7 @@ func TestAreInputStandard_for_checkmultisig(t *testing.T) {
 	txn.AddTxIn(txin1)
 
 	txn.AddTxOut(txout.NewTxOut(amount.Amount(10*util.COIN), script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
-	assert.True(t, ltx.AreInputsStandard(txn, coins))
+	assert.True(t, ltx.AreInputsStandard(txn, coins, false))
 }
 
 func TestAreInputStandard_empty_script_sig___is_non_standard(t *testing.T) {
@@ -2083,7 +2090,7 @@ func TestAreInputStandard_empty_script_sig___is_non_standard(t *testing.T) {
 	txn.AddTxIn(txin1)
 	txn.AddTxOut(txout.NewTxOut(amount.Amount(10*util.COIN), script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
 
-	assert.False(t, ltx.AreInputsStandard(txn, coins))
+	assert.False(t, ltx.AreInputsStandard(txn, coins, false))
 }
 
 func TestAreInputStandard_too_large_scriptsig__is_non_standard(t *testing.T) {
@@ -2096,7 +2103,7 @@ func TestAreInputStandard_too_large_scriptsig__is_non_standard(t *testing.T) {
 	txn.AddTxIn(txin1)
 	txn.AddTxOut(txout.NewTxOut(amount.Amount(10*util.COIN), script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
 
-	assert.False(t, ltx.AreInputsStandard(txn, coins))
+	assert.False(t, ltx.AreInputsStandard(txn, coins, false))
 }
 
 func TestAreInputStandard_scriptsig_with_too_much_extra_sigops_is_not_standard(t *testing.T) {
@@ -2113,5 +2120,8 @@ func TestAreInputStandard_scriptsig_with_too_much_extra_sigops_is_not_standard(t
 	txn.AddTxIn(txin1)
 
 	txn.AddTxOut(txout.NewTxOut(amount.Amount(10*util.COIN), script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
-	assert.False(t, ltx.AreInputsStandard(txn, coins))
+	assert.False(t, ltx.AreInputsStandard(txn, coins, false))
+
+	// SigChecks replace the sigops limit after the phonon upgrade.
+	assert.True(t, ltx.AreInputsStandard(txn, coins, true))
 }
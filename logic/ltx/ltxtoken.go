@@ -0,0 +1,156 @@
+package ltx
+
+import (
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/token"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+)
+
+// categoryTokens is the tokens of one category spent by the inputs of a
+// transaction.
+type categoryTokens struct {
+	amount     int64
+	minting    bool
+	mutables   int
+	immutables map[string]int
+}
+
+// checkTokenOutputs checks the token prefix of every output of a transaction,
+// coinbase included, once the May 2023 upgrade is active.
+func checkTokenOutputs(txn *tx.Tx) error {
+	for _, out := range txn.GetOuts() {
+		if out.HasInvalidTokenPrefix() {
+			log.Debug("tx %s has an output with an invalid token prefix", txn.GetHash())
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-vout-invalid-token-prefix")
+		}
+		if txn.IsCoinBase() && out.GetTokenData() != nil {
+			log.Debug("coinbase tx %s has an output with tokens", txn.GetHash())
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-coinbase-has-tokens")
+		}
+	}
+	return nil
+}
+
+// isTokenCoinBeforeActivation reports whether coin carries tokens although it
+// was created before the May 2023 upgrade, when its token prefix was nothing
+// but locking script bytes anyone could have written.
+func isTokenCoinBeforeActivation(coin *utxo.Coin) bool {
+	if coin.GetTokenData() == nil || coin.IsMempoolCoin() {
+		return false
+	}
+	prevIndex := chain.GetInstance().GetIndex(coin.GetHeight() - 1)
+	return prevIndex == nil || !model.IsUpgrade9Enabled(prevIndex.GetMedianTimePast())
+}
+
+// spentCoins returns the coins spent by the inputs of a transaction, which
+// should all be in view.
+func spentCoins(txn *tx.Tx, view *utxo.CoinsMap) []*utxo.Coin {
+	coins := make([]*utxo.Coin, 0, len(txn.GetIns()))
+	for _, in := range txn.GetIns() {
+		coin := view.GetCoin(in.PreviousOutPoint)
+		if coin == nil {
+			panic("can't find coin in temp coinsmap")
+		}
+		coins = append(coins, coin)
+	}
+	return coins
+}
+
+// checkTxTokens checks that the tokens sent to the outputs of a transaction
+// are those spent by its inputs, or are created by it: fungible tokens and
+// non-fungible tokens of any capability for the categories of the inputs
+// spending an output 0, non-fungible tokens for the categories of the minting
+// tokens spent, and one non-fungible token per mutable token spent.
+func checkTxTokens(txn *tx.Tx, coins []*utxo.Coin) error {
+	genesis := make(map[util.Hash]bool)
+	spent := make(map[util.Hash]*categoryTokens)
+
+	for index, in := range txn.GetIns() {
+		if in.PreviousOutPoint.Index == 0 {
+			genesis[in.PreviousOutPoint.Hash] = true
+		}
+
+		data := coins[index].GetTokenData()
+		if data == nil {
+			continue
+		}
+		if isTokenCoinBeforeActivation(coins[index]) {
+			log.Debug("tx %s spends tokens created before activation", txn.GetHash())
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-vin-token-before-activation")
+		}
+
+		tokens, ok := spent[data.Category]
+		if !ok {
+			tokens = &categoryTokens{immutables: make(map[string]int)}
+			spent[data.Category] = tokens
+		}
+		if data.Amount > token.MaxAmount-tokens.amount {
+			log.Debug("tx %s spends too many tokens of category %s", txn.GetHash(), data.Category)
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-token-amount-outofrange")
+		}
+		tokens.amount += data.Amount
+		switch {
+		case data.IsMintingNFT():
+			tokens.minting = true
+		case data.IsMutableNFT():
+			tokens.mutables++
+		case data.IsImmutableNFT():
+			tokens.immutables[string(data.Commitment)]++
+		}
+	}
+
+	sent := make(map[util.Hash]int64)
+	unmatched := make([]*token.OutputData, 0)
+	for _, out := range txn.GetOuts() {
+		data := out.GetTokenData()
+		if data == nil {
+			continue
+		}
+
+		if data.Amount > token.MaxAmount-sent[data.Category] {
+			log.Debug("tx %s sends too many tokens of category %s", txn.GetHash(), data.Category)
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-token-amount-outofrange")
+		}
+		sent[data.Category] += data.Amount
+
+		if !data.HasNFT || genesis[data.Category] {
+			continue
+		}
+		tokens := spent[data.Category]
+		if tokens != nil && tokens.minting {
+			continue
+		}
+		// An immutable token is best matched by an identical one, mutable
+		// tokens can stand in for anything else.
+		if data.IsImmutableNFT() && tokens != nil && tokens.immutables[string(data.Commitment)] > 0 {
+			tokens.immutables[string(data.Commitment)]--
+			continue
+		}
+		unmatched = append(unmatched, data)
+	}
+
+	for _, data := range unmatched {
+		tokens := spent[data.Category]
+		if data.IsMintingNFT() || tokens == nil || tokens.mutables == 0 {
+			log.Debug("tx %s sends a non-fungible token of category %s from nothing", txn.GetHash(), data.Category)
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-token-nft-ex-nihilo")
+		}
+		tokens.mutables--
+	}
+
+	for category, amount := range sent {
+		if genesis[category] {
+			continue
+		}
+		if tokens := spent[category]; tokens == nil || tokens.amount < amount {
+			log.Debug("tx %s sends more tokens of category %s than it spends", txn.GetHash(), category)
+			return errcode.NewError(errcode.RejectInvalid, "bad-txns-token-in-belowout")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package ltx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/logic/lscript"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/token"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+)
+
+var (
+	tokenPrevTxID = util.Hash{0x01}
+	tokenCategory = util.Hash{0x02}
+)
+
+func newTokenOut(data *token.OutputData) *txout.TxOut {
+	out := txout.NewTxOut(1000, script.NewScriptRaw([]byte{0x51}))
+	out.SetTokenData(data)
+	return out
+}
+
+// newTokenTx returns a transaction spending the output 1 of tokenPrevTxID
+// with the tokens spent, or its output 0 if genesis, to the tokens sent.
+func newTokenTx(genesis bool, spent, sent []*token.OutputData) (*tx.Tx, []*utxo.Coin) {
+	transaction := tx.NewTx(0, tx.DefaultVersion)
+	coins := make([]*utxo.Coin, 0, len(spent)+1)
+	for i, data := range append([]*token.OutputData{nil}, spent...) {
+		index := uint32(i + 1)
+		if genesis && i == 0 {
+			index = 0
+		}
+		transaction.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(tokenPrevTxID, index), script.NewEmptyScript(), 0))
+		coins = append(coins, utxo.NewMempoolCoin(newTokenOut(data)))
+	}
+	for _, data := range sent {
+		transaction.AddTxOut(newTokenOut(data))
+	}
+	return transaction, coins
+}
+
+func TestCheckTxTokens(t *testing.T) {
+	ft := func(category util.Hash, amount int64) *token.OutputData {
+		return &token.OutputData{Category: category, Amount: amount}
+	}
+	nft := func(category util.Hash, capability token.Capability, commitment string) *token.OutputData {
+		return &token.OutputData{Category: category, HasNFT: true, Capability: capability, Commitment: []byte(commitment)}
+	}
+
+	tests := []struct {
+		name    string
+		genesis bool
+		spent   []*token.OutputData
+		sent    []*token.OutputData
+		valid   bool
+	}{
+		{"no tokens", false, nil, nil, true},
+		{"genesis", true, nil, []*token.OutputData{ft(tokenPrevTxID, token.MaxAmount),
+			nft(tokenPrevTxID, token.Minting, "")}, true},
+		{"genesis of another category", true, nil, []*token.OutputData{ft(tokenCategory, 1)}, false},
+		{"genesis amount overflow", true, nil, []*token.OutputData{ft(tokenPrevTxID, token.MaxAmount),
+			ft(tokenPrevTxID, 1)}, false},
+		{"send amount", false, []*token.OutputData{ft(tokenCategory, 10)},
+			[]*token.OutputData{ft(tokenCategory, 4), ft(tokenCategory, 6)}, true},
+		{"burn amount", false, []*token.OutputData{ft(tokenCategory, 10)}, []*token.OutputData{ft(tokenCategory, 9)}, true},
+		{"inflate amount", false, []*token.OutputData{ft(tokenCategory, 10)},
+			[]*token.OutputData{ft(tokenCategory, 4), ft(tokenCategory, 7)}, false},
+		{"amount of another category", false, []*token.OutputData{ft(tokenCategory, 10)},
+			[]*token.OutputData{ft(tokenPrevTxID, 1)}, false},
+		{"send immutable", false, []*token.OutputData{nft(tokenCategory, token.None, "a")},
+			[]*token.OutputData{nft(tokenCategory, token.None, "a")}, true},
+		{"alter immutable", false, []*token.OutputData{nft(tokenCategory, token.None, "a")},
+			[]*token.OutputData{nft(tokenCategory, token.None, "b")}, false},
+		{"duplicate immutable", false, []*token.OutputData{nft(tokenCategory, token.None, "a")},
+			[]*token.OutputData{nft(tokenCategory, token.None, "a"), nft(tokenCategory, token.None, "a")}, false},
+		{"alter mutable", false, []*token.OutputData{nft(tokenCategory, token.Mutable, "a")},
+			[]*token.OutputData{nft(tokenCategory, token.Mutable, "b")}, true},
+		{"mutable to immutable", false, []*token.OutputData{nft(tokenCategory, token.Mutable, "a")},
+			[]*token.OutputData{nft(tokenCategory, token.None, "b")}, true},
+		{"mutable to minting", false, []*token.OutputData{nft(tokenCategory, token.Mutable, "a")},
+			[]*token.OutputData{nft(tokenCategory, token.Minting, "")}, false},
+		{"immutable matched before mutable", false, []*token.OutputData{nft(tokenCategory, token.Mutable, ""),
+			nft(tokenCategory, token.None, "a")},
+			[]*token.OutputData{nft(tokenCategory, token.Mutable, "b"), nft(tokenCategory, token.None, "a")}, true},
+		{"mint", false, []*token.OutputData{nft(tokenCategory, token.Minting, "")},
+			[]*token.OutputData{nft(tokenCategory, token.Minting, ""), nft(tokenCategory, token.Mutable, "a"),
+				nft(tokenCategory, token.None, "b")}, true},
+		{"mint amount", false, []*token.OutputData{nft(tokenCategory, token.Minting, "")},
+			[]*token.OutputData{ft(tokenCategory, 1)}, false},
+	}
+
+	for _, test := range tests {
+		transaction, coins := newTokenTx(test.genesis, test.spent, test.sent)
+		err := checkTxTokens(transaction, coins)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expect an error", test.name)
+		}
+	}
+}
+
+func TestCheckTokenOutputs(t *testing.T) {
+	transaction, _ := newTokenTx(false, nil, nil)
+	transaction.AddTxOut(txout.NewTxOut(1000, script.NewScriptRaw([]byte{token.PrefixToken, 0x51})))
+	if checkTokenOutputs(transaction) == nil {
+		t.Errorf("an invalid token prefix should be rejected")
+	}
+
+	transaction, _ = newTokenTx(false, nil, nil)
+	out := txout.NewTxOut(1000, script.NewScriptRaw([]byte{token.PrefixToken}))
+	out.SetTokenData(&token.OutputData{Category: tokenCategory, Amount: 1})
+	transaction.AddTxOut(out)
+	if err := checkTokenOutputs(transaction); err != nil {
+		t.Errorf("a locking script starting with the token prefix byte after a valid prefix: %v", err)
+	}
+
+	coinbase := tx.NewTx(0, tx.DefaultVersion)
+	coinbase.AddTxIn(txin.NewTxIn(outpoint.NewDefaultOutPoint(), script.NewScriptRaw([]byte{0x51, 0x51}), 0))
+	coinbase.AddTxOut(txout.NewTxOut(1000, script.NewScriptRaw([]byte{0x51})))
+	if err := checkTokenOutputs(coinbase); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	coinbase.AddTxOut(newTokenOut(&token.OutputData{Category: tokenCategory, Amount: 1}))
+	if checkTokenOutputs(coinbase) == nil {
+		t.Errorf("coinbase tokens should be rejected")
+	}
+}
+
+func TestApplyBlockTransactionsTokens(t *testing.T) {
+	model.SetRegTestParams()
+	prevIndex := blockindex.NewBlockIndex(&block.BlockHeader{Time: uint32(model.ActiveNetParams.Upgrade9ActivationTime)})
+	index := blockindex.NewBlockIndex(&block.BlockHeader{Time: prevIndex.Header.Time})
+	index.Prev = prevIndex
+	index.Height = 1
+
+	chain.InitGlobalChain()
+	gChain := chain.GetInstance()
+	gChain.SetTip(prevIndex)
+	defer func() { *gChain = *chain.NewChain() }()
+
+	coinbase := tx.NewTx(0, tx.DefaultVersion)
+	coinbase.AddTxIn(txin.NewTxIn(outpoint.NewDefaultOutPoint(), script.NewScriptRaw([]byte{0x51, 0x51}), 0))
+	coinbase.AddTxOut(txout.NewTxOut(0, script.NewScriptRaw([]byte{0x51})))
+
+	ft := &token.OutputData{Category: tokenCategory, Amount: 10}
+	tests := []struct {
+		name  string
+		sent  int64
+		valid bool
+	}{
+		{"send amount", 10, true},
+		{"inflate amount", 11, false},
+	}
+	for _, test := range tests {
+		transaction, coins := newTokenTx(false, []*token.OutputData{ft},
+			[]*token.OutputData{{Category: tokenCategory, Amount: test.sent}})
+		view := utxo.NewEmptyCoinsMap()
+		for i, in := range transaction.GetIns() {
+			view.AddCoin(in.PreviousOutPoint, coins[i], false)
+		}
+
+		// the tokens are checked even if the scripts are not
+		_, _, err := ApplyBlockTransactions([]*tx.Tx{coinbase, transaction}, view, false, 0, false,
+			0, index.Height, 0, 0, index)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expect an error", test.name)
+		}
+	}
+}
+
+func TestSignRawTransactionTokens(t *testing.T) {
+	privateKey := crypto.PrivateKeyFromBytes(bytes.Repeat([]byte{0x01}, 32))
+	keyStore := crypto.NewKeyStore()
+	keyStore.AddKey(privateKey)
+	pubKey := privateKey.PubKey().ToBytes()
+
+	p2PKHLockingScript := script.NewEmptyScript()
+	p2PKHLockingScript.PushOpCode(opcodes.OP_DUP)
+	p2PKHLockingScript.PushOpCode(opcodes.OP_HASH160)
+	p2PKHLockingScript.PushSingleData(util.Hash160(pubKey))
+	p2PKHLockingScript.PushOpCode(opcodes.OP_EQUALVERIFY)
+	p2PKHLockingScript.PushOpCode(opcodes.OP_CHECKSIG)
+
+	multiSigLockingScript := script.NewEmptyScript()
+	multiSigLockingScript.PushInt64(1)
+	multiSigLockingScript.PushSingleData(pubKey)
+	multiSigLockingScript.PushInt64(1)
+	multiSigLockingScript.PushOpCode(opcodes.OP_CHECKMULTISIG)
+
+	transaction := tx.NewTx(0, tx.DefaultVersion)
+	transaction.AddTxOut(txout.NewTxOut(1000, script.NewScriptRaw([]byte{0x51})))
+	coinsMap := utxo.NewEmptyCoinsMap()
+	coins := make([]*utxo.Coin, 0, 2)
+	for index, lockingScript := range []*script.Script{p2PKHLockingScript, multiSigLockingScript} {
+		out := txout.NewTxOut(1000, lockingScript)
+		out.SetTokenData(&token.OutputData{Category: tokenCategory, Amount: 10})
+		prevOut := outpoint.NewOutPoint(tokenPrevTxID, uint32(index))
+		transaction.AddTxIn(txin.NewTxIn(prevOut, script.NewEmptyScript(), script.SequenceFinal))
+		coin := utxo.NewFreshCoin(out, 1, false)
+		coinsMap.AddCoin(prevOut, coin, true)
+		coins = append(coins, coin)
+	}
+
+	// The signatures commit to the token prefix of the spent outputs.
+	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
+	signErrors := SignRawTransaction([]*tx.Tx{transaction}, nil, keyStore, coinsMap, hashType)
+	for _, signError := range signErrors {
+		t.Errorf("input %s: %s", signError.TxIn.PreviousOutPoint.String(), signError.ErrMsg)
+	}
+	flags := uint32(script.StandardScriptVerifyFlags) | script.ScriptEnableTokens
+	for index, in := range transaction.GetIns() {
+		var metrics lscript.ScriptExecutionMetrics
+		err := lscript.VerifyScriptWithContext(lscript.NewScriptExecutionContext(transaction, index, coins),
+			in.GetScriptSig(), coins[index].GetScriptPubKey(), flags, lscript.NewScriptRealChecker(), &metrics)
+		if err != nil {
+			t.Errorf("input %d: %v", index, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package ltxindex
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/util"
+)
+
+// txIndexFlag is written to the block tree db once every block of the active
+// chain has been indexed.
+const txIndexFlag = "txindex"
+
+var (
+	builderQuit chan struct{}
+	builderWg   sync.WaitGroup
+)
+
+// IsEnabled returns whether the node maintains the transaction index (-txindex).
+func IsEnabled() bool {
+	return conf.Cfg != nil && conf.Cfg.Chain.TxIndex
+}
+
+// IsSynced returns whether the transaction index covers the whole active chain.
+func IsSynced() bool {
+	return blkdb.GetInstance().ReadFlag(txIndexFlag)
+}
+
+// IndexBlock records the disk position of every transaction in blk, which must
+// already have been written to disk at pindex's block position.
+func IndexBlock(blk *block.Block, pindex *blockindex.BlockIndex) error {
+	blockPos := pindex.GetBlockPos()
+	txPos := make(map[util.Hash]block.DiskTxPos, len(blk.Txs))
+	// offsets are relative to the end of the block header
+	offset := util.VarIntSerializeSize(uint64(len(blk.Txs)))
+	for _, transaction := range blk.Txs {
+		txPos[transaction.GetHash()] = *block.NewDiskTxPos(&blockPos, offset)
+		offset += transaction.EncodeSize()
+	}
+	return blkdb.GetInstance().WriteTxIndex(txPos)
+}
+
+// UnindexBlock removes the transactions of a disconnected block from the index.
+func UnindexBlock(blk *block.Block) error {
+	txids := make([]util.Hash, 0, len(blk.Txs))
+	for _, transaction := range blk.Txs {
+		txids = append(txids, transaction.GetHash())
+	}
+	return blkdb.GetInstance().EraseTxIndex(txids)
+}
+
+// FindTx looks up a confirmed transaction in the index. It returns a nil
+// transaction without error if the txid is not indexed, or if the block it is
+// indexed in is no longer in the active chain, as blocks disconnected while the
+// index was turned off are left in it.
+func FindTx(txid *util.Hash) (*tx.Tx, *util.Hash, error) {
+	txPos, err := blkdb.GetInstance().ReadTxIndex(txid)
+	if err != nil || txPos == nil {
+		return nil, nil, err
+	}
+	txn, header, err := disk.ReadTxFromDisk(txPos)
+	if err != nil {
+		return nil, nil, err
+	}
+	if txn.GetHash() != *txid {
+		log.Error("txindex: txid mismatch, want %s, read %s", txid, txn.GetHash())
+		return nil, nil, errors.New("txid mismatch")
+	}
+	blockHash := header.GetHash()
+	gChain := chain.GetInstance()
+	if pindex := gChain.FindBlockIndex(blockHash); pindex == nil || !gChain.Contains(pindex) {
+		log.Debug("txindex: tx %s is indexed in block %s out of the active chain", txid, blockHash)
+		return nil, nil, nil
+	}
+	return txn, &blockHash, nil
+}
+
+// Start brings the transaction index in line with the configuration. When the
+// index is enabled but incomplete, blocks connected before it was turned on are
+// indexed by a background goroutine; newly connected blocks are indexed by
+// ConnectBlock.
+func Start() {
+	btd := blkdb.GetInstance()
+	if !IsEnabled() {
+		// the index falls behind while disabled, so it has to be rebuilt
+		// from scratch next time it is turned on
+		if btd.ReadFlag(txIndexFlag) {
+			btd.WriteFlag(txIndexFlag, false)
+		}
+		btd.WriteTxIndexBestBlock(nil)
+		return
+	}
+	if btd.ReadFlag(txIndexFlag) {
+		return
+	}
+
+	builderQuit = make(chan struct{})
+	builderWg.Add(1)
+	go buildIndex(builderQuit)
+}
+
+// Stop interrupts the background builder, if any, and waits for it to exit.
+// Progress is kept and resumed on next start.
+func Stop() {
+	if builderQuit != nil {
+		close(builderQuit)
+		builderQuit = nil
+	}
+	builderWg.Wait()
+}
+
+func buildIndex(quit chan struct{}) {
+	defer builderWg.Done()
+
+	var pindex *blockindex.BlockIndex
+	hash, err := blkdb.GetInstance().ReadTxIndexBestBlock()
+	if err != nil {
+		log.Error("txindex: read best indexed block failed: %v", err)
+		return
+	}
+	if hash != nil {
+		pindex = chain.GetInstance().FindBlockIndex(*hash)
+	}
+	log.Info("txindex: building transaction index in background")
+
+	for {
+		select {
+		case <-quit:
+			log.Info("txindex: builder interrupted")
+			return
+		default:
+		}
+
+		persist.CsMain.Lock()
+		next, done, err := indexNextBlock(pindex)
+		persist.CsMain.Unlock()
+		if err != nil {
+			log.Error("txindex: %v", err)
+			return
+		}
+		if done {
+			log.Info("txindex: transaction index is synced")
+			return
+		}
+		if next.Height%10000 == 0 {
+			log.Info("txindex: indexed block height %d", next.Height)
+		}
+		pindex = next
+	}
+}
+
+// indexNextBlock indexes the active chain block following pindex, or marks the
+// index as synced when pindex is the tip. It must be called with CsMain held.
+func indexNextBlock(pindex *blockindex.BlockIndex) (*blockindex.BlockIndex, bool, error) {
+	gChain := chain.GetInstance()
+	btd := blkdb.GetInstance()
+
+	var next *blockindex.BlockIndex
+	if pindex == nil {
+		// the genesis coinbase is not an ordinary transaction, skip it
+		next = gChain.GetIndex(1)
+	} else {
+		// the block may have been reorganized out while the lock was released
+		next = gChain.Next(gChain.FindFork(pindex))
+	}
+	if next == nil {
+		if err := btd.WriteFlag(txIndexFlag, true); err != nil {
+			return nil, false, err
+		}
+		return nil, true, btd.WriteTxIndexBestBlock(nil)
+	}
+
+	blk, ok := disk.ReadBlockFromDisk(next, gChain.GetParams())
+	if !ok {
+		return nil, false, errors.New("read block " + next.GetBlockHash().String() + " from disk failed")
+	}
+	if err := IndexBlock(blk, next); err != nil {
+		return nil, false, err
+	}
+	return next, false, btd.WriteTxIndexBestBlock(next.GetBlockHash())
+}
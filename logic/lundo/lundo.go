@@ -51,8 +51,13 @@ func ApplyBlockUndo(blockUndo *undo.BlockUndo, blk *block.Block, cm *utxo.CoinsM
 				continue
 			}
 			coin := cm.SpendGlobalCoin(outpoint.NewOutPoint(txID, uint32(j)))
+			if coin == nil {
+				// missing transaction output
+				clean = false
+				continue
+			}
 			coinOut := coin.GetTxOut()
-			if coin == nil || !ptx.GetTxOut(j).IsEqual(&coinOut) ||
+			if !ptx.GetTxOut(j).IsEqual(&coinOut) ||
 				isCoinBase != coin.IsCoinBase() || height != coin.GetHeight() {
 				// transaction output mismatch
 				clean = false
@@ -60,7 +65,9 @@ func ApplyBlockUndo(blockUndo *undo.BlockUndo, blk *block.Block, cm *utxo.CoinsM
 		}
 	}
 
-	cm.Flush(blk.GetBlockHeader().HashPrevBlock)
+	// The view now corresponds to the previous block, it is up to the caller
+	// to write it to the coins cache.
+	cm.SetBestBlock(blk.GetBlockHeader().HashPrevBlock)
 	if clean {
 		return undo.DisconnectOk
 	}
@@ -160,6 +160,19 @@ func TestBlockUndo__2_normal_tx_and_1_unspendable_opreturn_tx(t *testing.T) {
 	assert.False(t, HasSpendableCoin(txn3.GetHash()), "already undo the block, so no coins should exists.")
 }
 
+func TestBlockUndo__should_be_unclean__when_block_outputs_are_missing(t *testing.T) {
+	block := block.NewBlock()
+
+	coinsMap := utxo.NewEmptyCoinsMap()
+	block.Header.HashPrevBlock = updateTipHashInUtxo(coinsMap, t)
+	block.Txs = []*tx.Tx{makeCoinbaseTx()}
+
+	ret := ApplyBlockUndo(undo.NewBlockUndo(0), block, coinsMap, 100)
+
+	assert.Equal(t, undo.DisconnectUnclean, ret)
+	assert.Equal(t, block.Header.HashPrevBlock, coinsMap.GetBestBlock())
+}
+
 func makeNormalTx() *tx.Tx {
 	txn := tx.NewEmptyTx()
 	Ins2 := txin.NewTxIn(outpoint.NewOutPoint(*util.GetRandHash(), 0), script.NewEmptyScript(), script.SequenceFinal)
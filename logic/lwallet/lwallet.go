@@ -44,25 +44,15 @@ func IsWalletEnable() bool {
 }
 
 func GetNewAddress(account string, isLegacyAddr bool) (string, error) {
-	pubKey, err := wallet.GetInstance().GenerateNewKey()
+	pubKey, err := wallet.GetInstance().ReserveKeyFromKeyPool(false)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 	pubKeyHash := pubKey.ToHash160()
 
-	var address string
-	if isLegacyAddr {
-		legacyAddr, err := script.AddressFromHash160(pubKeyHash, script.AddressVerPubKey())
-		if err != nil {
-			return "", err
-		}
-		address = legacyAddr.String()
-	} else {
-		cashAddr, err := cashaddr.NewCashAddressPubKeyHash(pubKeyHash, chain.GetInstance().GetParams())
-		if err != nil {
-			return "", err
-		}
-		address = cashAddr.String()
+	address, err := encodeP2PKHAddress(pubKeyHash, isLegacyAddr)
+	if err != nil {
+		return "", err
 	}
 
 	wallet.GetInstance().SetAddressBook(pubKeyHash, account, "receive")
@@ -70,13 +60,30 @@ func GetNewAddress(account string, isLegacyAddr bool) (string, error) {
 	return address, nil
 }
 
+func GetRawChangeAddress() (string, error) {
+	pubKey, err := wallet.GetInstance().ReserveKeyFromKeyPool(true)
+	if err != nil {
+		return "", err
+	}
+	return encodeP2PKHAddress(pubKey.ToHash160(), false)
+}
+
 func GetMiningAddress() (string, error) {
-	pubKey, err := wallet.GetInstance().GetReservedKey()
+	pubKey, err := wallet.GetInstance().GetReservedKey(false)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
+	return encodeP2PKHAddress(pubKey.ToHash160(), false)
+}
 
-	pubKeyHash := pubKey.ToHash160()
+func encodeP2PKHAddress(pubKeyHash []byte, isLegacyAddr bool) (string, error) {
+	if isLegacyAddr {
+		legacyAddr, err := script.AddressFromHash160(pubKeyHash, script.AddressVerPubKey())
+		if err != nil {
+			return "", err
+		}
+		return legacyAddr.String(), nil
+	}
 	cashAddr, err := cashaddr.NewCashAddressPubKeyHash(pubKeyHash, chain.GetInstance().GetParams())
 	if err != nil {
 		return "", err
@@ -84,6 +91,18 @@ func GetMiningAddress() (string, error) {
 	return cashAddr.String(), nil
 }
 
+func RefillKeyPool(size int) error {
+	return wallet.GetInstance().TopUpKeyPool(size)
+}
+
+func GetKeyPoolSize() int {
+	return wallet.GetInstance().GetKeyPoolSize()
+}
+
+func GetKeyMetadata(pubKeyHash []byte) *wallet.KeyMetadata {
+	return wallet.GetInstance().GetKeyMetadata(pubKeyHash)
+}
+
 func GetKeyPair(pubKeyHash []byte) *crypto.KeyPair {
 	return wallet.GetInstance().GetKeyPair(pubKeyHash)
 }
@@ -92,6 +111,14 @@ func GetKeyPairs(pubKeyHashList [][]byte) []*crypto.KeyPair {
 	return wallet.GetInstance().GetKeyPairs(pubKeyHashList)
 }
 
+func GetPubKey(pubKeyHash []byte) *crypto.PublicKey {
+	return wallet.GetInstance().GetPubKey(pubKeyHash)
+}
+
+func IsWalletLocked() bool {
+	return wallet.GetInstance().IsLocked()
+}
+
 func CheckFinalTx(txn *tx.Tx) bool {
 	err := ltx.ContextualCheckTransactionForCurrentBlock(txn, int(tx.StandardLockTimeVerifyFlags))
 	return err == nil
@@ -202,6 +229,12 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 		return nil, 0, errors.New("Transaction must have at least one recipient")
 	}
 
+	// The inputs are signed even to only estimate the fee, which needs the
+	// private keys.
+	if IsWalletLocked() {
+		return nil, 0, wallet.ErrWalletLocked
+	}
+
 	value := amount.Amount(0)
 	changePosRequest := *changePosInOut
 	subtractFeeCount := 0
@@ -316,7 +349,7 @@ func CreateTransaction(recipients []*wallet.Recipient, changePosInOut *int, sign
 			// unknown transactions that were written with keys of ours
 			// to recover post-backup change.
 
-			reservedKey, err := wallet.GetInstance().GetReservedKey()
+			reservedKey, err := wallet.GetInstance().GetReservedKey(true)
 			if err != nil || reservedKey == nil {
 				return nil, 0, errors.New("Keypool ran out, please call keypoolrefill first")
 			}
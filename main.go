@@ -16,9 +16,14 @@ import (
 	"runtime/debug"
 
 	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/logic/lblockfilter"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/logic/ltxindex"
+	"github.com/copernet/copernicus/metrics"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/net/limits"
 	"github.com/copernet/copernicus/net/server"
+	"github.com/copernet/copernicus/net/zmq"
 	"github.com/copernet/copernicus/rpc"
 	"github.com/copernet/copernicus/util"
 	"net"
@@ -42,6 +47,8 @@ func bchMain(ctx context.Context, args []string) error {
 		fmt.Printf("Profile server listening on %s\n", listenAddr)
 		profileRedirect := http.RedirectHandler("/debug/pprof", http.StatusSeeOther)
 		http.Handle("/", profileRedirect)
+		registerNodeMetrics()
+		http.Handle("/metrics", metrics.Handler())
 		err := fmt.Errorf("%v", http.ListenAndServe(listenAddr, nil))
 		fmt.Println(err.Error())
 	}()
@@ -65,6 +72,14 @@ func bchMain(ctx context.Context, args []string) error {
 		rpcServer.Start()
 	}
 
+	zmqNotifier, err := zmq.InitNotifier()
+	if err != nil {
+		return fmt.Errorf("failed to init zmq: %v", err)
+	}
+	if zmqNotifier != nil {
+		zmqNotifier.Start()
+	}
+
 	server.SetMsgHandle(context.TODO(), s.MsgChan, s)
 	if interruptRequested(interrupt) {
 		return nil
@@ -72,6 +87,19 @@ func bchMain(ctx context.Context, args []string) error {
 	s.Start()
 	defer func() {
 		s.Stop()
+		ltxindex.Stop()
+		lblockfilter.Stop()
+		if zmqNotifier != nil {
+			zmqNotifier.Stop()
+		}
+		if conf.Cfg.Mempool.PersistMempool {
+			if err := lmempool.DumpMempool(); err != nil {
+				log.Error("dump mempool failed: %v", err)
+			}
+		}
+		if err := lmempool.SaveFeeEstimator(); err != nil {
+			log.Error("save fee estimates failed: %v", err)
+		}
 		// Shutdown the RPC server if it's not disabled.
 		if !conf.Cfg.P2PNet.DisableRPC {
 			rpcServer.Stop()
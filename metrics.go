@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/copernet/copernicus/metrics"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist"
+)
+
+// registerNodeMetrics registers the metrics sampling the state of the node
+// each time /metrics is scraped. The metrics updated as events happen are
+// defined by the packages emitting them.
+func registerNodeMetrics() {
+	metrics.NewGaugeFunc("copernicus_chain_tip_height",
+		"Height of the tip of the active chain.", func() float64 {
+			persist.CsMain.Lock()
+			defer persist.CsMain.Unlock()
+			return float64(chain.GetInstance().Height())
+		})
+	metrics.NewGaugeFunc("copernicus_chain_header_height",
+		"Height of the best known header.", func() float64 {
+			persist.CsMain.Lock()
+			defer persist.CsMain.Unlock()
+			if best := chain.GetInstance().GetIndexBestHeader(); best != nil {
+				return float64(best.Height)
+			}
+			return -1
+		})
+
+	metrics.NewGaugeFunc("copernicus_mempool_transactions",
+		"Number of transactions in the mempool.", func() float64 {
+			return float64(mempool.GetInstance().Size())
+		})
+	metrics.NewGaugeFunc("copernicus_mempool_bytes",
+		"Total size of the transactions in the mempool, in bytes.", func() float64 {
+			return float64(mempool.GetInstance().GetPoolAllTxSize(true))
+		})
+	metrics.NewGaugeFunc("copernicus_mempool_usage_bytes",
+		"Memory usage of the mempool, in bytes.", func() float64 {
+			return float64(mempool.GetInstance().GetPoolUsage())
+		})
+	metrics.NewGaugeFunc("copernicus_mempool_orphans",
+		"Number of orphan transactions.", func() float64 {
+			persist.CsMain.Lock()
+			defer persist.CsMain.Unlock()
+			return float64(mempool.GetInstance().OrphanSize())
+		})
+
+	metrics.NewGaugeFunc("copernicus_utxo_cache_coins",
+		"Number of coins in the UTXO cache.", func() float64 {
+			persist.CsMain.Lock()
+			defer persist.CsMain.Unlock()
+			return float64(utxo.GetUtxoCacheInstance().GetCacheSize())
+		})
+	metrics.NewGaugeFunc("copernicus_utxo_cache_usage_bytes",
+		"Memory usage of the UTXO cache, in bytes.", func() float64 {
+			persist.CsMain.Lock()
+			defer persist.CsMain.Unlock()
+			return float64(utxo.GetUtxoCacheInstance().DynamicMemoryUsage())
+		})
+}
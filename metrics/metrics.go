@@ -0,0 +1,363 @@
+// Package metrics implements counters, gauges and histograms exposed in the
+// Prometheus text format.
+//
+// Like expvar, every metric is registered globally when it is created, and
+// Handler serves all of them. Creating two metrics with the same name panics.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default buckets of a histogram, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]metric)
+)
+
+func register(name string, m metric) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := registry[name]; ok {
+		panic("metrics: reuse of metric name " + name)
+	}
+	registry[name] = m
+}
+
+// WriteTo writes all the metrics in the Prometheus text format, sorted by
+// name.
+func WriteTo(w io.Writer) error {
+	registryLock.RLock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	metrics := make([]metric, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		metrics[i] = registry[name]
+	}
+	registryLock.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns a handler serving all the metrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteTo(w)
+	})
+}
+
+// value is a float64 which can be updated atomically.
+type value struct {
+	bits uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&v.bits)
+		new := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(&v.bits, old, new) {
+			return
+		}
+	}
+}
+
+func (v *value) set(val float64) {
+	atomic.StoreUint64(&v.bits, math.Float64bits(val))
+}
+
+func (v *value) get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&v.bits))
+}
+
+// Counter is a value which only goes up.
+type Counter struct {
+	*value
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	c.add(1)
+}
+
+// Add adds delta, which must not be negative, to the counter.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.add(delta)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() float64 {
+	return c.get()
+}
+
+// Gauge is a value which can go up and down.
+type Gauge struct {
+	*value
+}
+
+// Set sets the gauge to val.
+func (g *Gauge) Set(val float64) {
+	g.set(val)
+}
+
+// Inc increments the gauge by 1.
+func (g *Gauge) Inc() {
+	g.add(1)
+}
+
+// Dec decrements the gauge by 1.
+func (g *Gauge) Dec() {
+	g.add(-1)
+}
+
+// Add adds delta to the gauge.
+func (g *Gauge) Add(delta float64) {
+	g.add(delta)
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 {
+	return g.get()
+}
+
+type single struct {
+	name, help, typ string
+	value           func() float64
+}
+
+func (s *single) write(w io.Writer) {
+	writeHeader(w, s.name, s.help, s.typ)
+	writeSample(w, s.name, "", s.value())
+}
+
+// NewCounter creates and registers a counter.
+func NewCounter(name, help string) *Counter {
+	c := &Counter{new(value)}
+	register(name, &single{name: name, help: help, typ: "counter", value: c.Value})
+	return c
+}
+
+// NewGauge creates and registers a gauge.
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{new(value)}
+	register(name, &single{name: name, help: help, typ: "gauge", value: g.Value})
+	return g
+}
+
+// NewGaugeFunc registers a gauge whose value is returned by fn each time the
+// metrics are collected.
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(name, &single{name: name, help: help, typ: "gauge", value: fn})
+}
+
+// vec holds the children of a metric partitioned by label values.
+type vec struct {
+	name, help, typ string
+	labels          []string
+
+	lock     sync.RWMutex
+	children map[string]*value
+}
+
+func newVec(name, help, typ string, labels []string) *vec {
+	v := &vec{
+		name:     name,
+		help:     help,
+		typ:      typ,
+		labels:   labels,
+		children: make(map[string]*value),
+	}
+	register(name, v)
+	return v
+}
+
+func (v *vec) child(values []string) *value {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	var pairs []string
+	for i, label := range v.labels {
+		pairs = append(pairs, label+"=\""+escapeLabel(values[i])+"\"")
+	}
+	key := strings.Join(pairs, ",")
+
+	v.lock.RLock()
+	c, ok := v.children[key]
+	v.lock.RUnlock()
+	if ok {
+		return c
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if c, ok = v.children[key]; !ok {
+		c = new(value)
+		v.children[key] = c
+	}
+	return c
+}
+
+func (v *vec) write(w io.Writer) {
+	v.lock.RLock()
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	v.lock.RUnlock()
+	sort.Strings(keys)
+
+	writeHeader(w, v.name, v.help, v.typ)
+	for _, key := range keys {
+		v.lock.RLock()
+		c := v.children[key]
+		v.lock.RUnlock()
+		writeSample(w, v.name, key, c.get())
+	}
+}
+
+// CounterVec is a set of counters partitioned by label values.
+type CounterVec struct {
+	*vec
+}
+
+// NewCounterVec creates and registers a set of counters with the labels
+// labels.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{newVec(name, help, "counter", labels)}
+}
+
+// WithLabelValues returns the counter of the label values values, creating
+// it if needed.
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	return &Counter{v.child(values)}
+}
+
+// GaugeVec is a set of gauges partitioned by label values.
+type GaugeVec struct {
+	*vec
+}
+
+// NewGaugeVec creates and registers a set of gauges with the labels labels.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{newVec(name, help, "gauge", labels)}
+}
+
+// WithLabelValues returns the gauge of the label values values, creating it
+// if needed.
+func (v *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return &Gauge{v.child(values)}
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	name, help string
+	upper      []float64
+
+	lock   sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates and registers a histogram with the upper bounds
+// buckets, which must be sorted. DefBuckets is used if buckets is empty.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	h := &Histogram{
+		name:   name,
+		help:   help,
+		upper:  buckets,
+		counts: make([]uint64, len(buckets)),
+	}
+	register(name, h)
+	return h
+}
+
+// Observe adds an observation to the histogram.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upper, v)
+
+	h.lock.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+	h.lock.Unlock()
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.lock.Lock()
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	count, sum := h.count, h.sum
+	h.lock.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	var cumulative uint64
+	for i, upper := range h.upper {
+		cumulative += counts[i]
+		writeSample(w, h.name+"_bucket", "le=\""+formatFloat(upper)+"\"", float64(cumulative))
+	}
+	writeSample(w, h.name+"_bucket", "le=\"+Inf\"", float64(count))
+	writeSample(w, h.name+"_sum", "", sum)
+	writeSample(w, h.name+"_count", "", float64(count))
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	help = strings.Replace(strings.Replace(help, "\\", "\\\\", -1), "\n", "\\n", -1)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeSample(w io.Writer, name, labels string, val float64) {
+	if labels != "" {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(val))
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(val))
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func escapeLabel(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	s = strings.Replace(s, "\"", "\\\"", -1)
+	return strings.Replace(s, "\n", "\\n", -1)
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	counter := NewCounter("test_counter_total", "A test counter.")
+	counter.Inc()
+	counter.Add(2.5)
+
+	gauge := NewGauge("test_gauge", "A test gauge.")
+	gauge.Set(10)
+	gauge.Dec()
+
+	NewGaugeFunc("test_gauge_func", "A test gauge func.", func() float64 { return 42 })
+
+	vec := NewCounterVec("test_counter_vec_total", "A test counter vec.", "reason")
+	vec.WithLabelValues("b").Inc()
+	vec.WithLabelValues("a").Add(3)
+	vec.WithLabelValues("b").Inc()
+
+	hist := NewHistogram("test_histogram_seconds", "A test histogram.", []float64{0.1, 1})
+	hist.Observe(0.05)
+	hist.Observe(0.5)
+	hist.Observe(5)
+
+	var buf bytes.Buffer
+	if err := WriteTo(&buf); err != nil {
+		t.Fatalf("write metrics failed: %v", err)
+	}
+	want := `# HELP test_counter_total A test counter.
+# TYPE test_counter_total counter
+test_counter_total 3.5
+# HELP test_counter_vec_total A test counter vec.
+# TYPE test_counter_vec_total counter
+test_counter_vec_total{reason="a"} 3
+test_counter_vec_total{reason="b"} 2
+# HELP test_gauge A test gauge.
+# TYPE test_gauge gauge
+test_gauge 9
+# HELP test_gauge_func A test gauge func.
+# TYPE test_gauge_func gauge
+test_gauge_func 42
+# HELP test_histogram_seconds A test histogram.
+# TYPE test_histogram_seconds histogram
+test_histogram_seconds_bucket{le="0.1"} 1
+test_histogram_seconds_bucket{le="1"} 2
+test_histogram_seconds_bucket{le="+Inf"} 3
+test_histogram_seconds_sum 5.55
+test_histogram_seconds_count 3
+`
+	if buf.String() != want {
+		t.Errorf("metrics should be:\n%s\ngot:\n%s", want, buf.String())
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") || rec.Body.String() != want {
+		t.Errorf("handler response is wrong: %s", rec.Body.String())
+	}
+}
+
+func TestDuplicateName(t *testing.T) {
+	NewGauge("test_duplicate", "")
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice should panic")
+		}
+	}()
+	NewCounter("test_duplicate", "")
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"errors"
 	"github.com/copernet/copernicus/conf"
+	"math"
 	"math/big"
 	"time"
 
@@ -62,6 +63,7 @@ type BitcoinParams struct {
 	MinDiffReductionTime     time.Duration
 	GenerateSupported        bool
 	Checkpoints              []*Checkpoint
+	AssumeUTXO               []*AssumeUTXOData
 	MineBlocksOnDemands      bool
 
 	// Enforce current block version once network has
@@ -95,6 +97,17 @@ func (param *BitcoinParams) TxData() *ChainTxData {
 	return &param.chainTxData
 }
 
+// AssumeUTXOForBlock returns the assumeutxo entry of the block with the given
+// hash, or nil if a UTXO snapshot taken at the block is not known.
+func (param *BitcoinParams) AssumeUTXOForBlock(hash *util.Hash) *AssumeUTXOData {
+	for _, data := range param.AssumeUTXO {
+		if data.BlockHash == *hash {
+			return data
+		}
+	}
+	return nil
+}
+
 var MainNetParams = BitcoinParams{
 	Param: consensus.Param{
 		GenesisHash:            &GenesisBlockHash,
@@ -111,6 +124,7 @@ var MainNetParams = BitcoinParams{
 		PowLimit:                      mainPowLimit,
 		TargetTimespan:                60 * 60 * 24 * 14,
 		TargetTimePerBlock:            60 * 10,
+		ASERTHalfLife:                 2 * 24 * 60 * 60,
 		FPowAllowMinDifficultyBlocks:  false,
 		FPowNoRetargeting:             false,
 		RuleChangeActivationThreshold: 1916,
@@ -141,10 +155,26 @@ var MainNetParams = BitcoinParams{
 
 		// Wed, 15 May 2019 12:00:00 UTC hard fork
 		GreatWallActivationTime: 1557921600,
+
+		// Nov 15, 2019 12:00:00 UTC hard fork
+		GravitonActivationTime: 1573819200,
+
+		// May 15, 2020 12:00:00 UTC hard fork
+		PhononActivationTime: 1589544000,
+
+		// Nov 15, 2020 12:00:00 UTC hard fork
+		AxionActivationTime: 1605441600,
+
+		// May 15, 2022 12:00:00 UTC hard fork
+		Upgrade8ActivationTime: 1652616000,
+
+		// May 15, 2023 12:00:00 UTC hard fork
+		Upgrade9ActivationTime: 1684152000,
 	},
 
 	Name:        "main",
 	BitcoinNet:  wire.MainNet,
+	DiskMagic:   wire.MainDiskMagic,
 	DefaultPort: "8333",
 	DNSSeeds: []DNSSeed{
 		{Host: "seed.bitcoinabc.org", HasFiltering: true},                  // Pieter Wuille
@@ -230,6 +260,7 @@ var TestNetParams = BitcoinParams{
 		PowLimit:                      testNetPowLimit,
 		TargetTimespan:                60 * 60 * 24 * 14,
 		TargetTimePerBlock:            60 * 10,
+		ASERTHalfLife:                 60 * 60,
 		FPowAllowMinDifficultyBlocks:  true,
 		FPowNoRetargeting:             false,
 		RuleChangeActivationThreshold: 1512,
@@ -258,6 +289,16 @@ var TestNetParams = BitcoinParams{
 		MagneticAnomalyActivationTime: 1542300000,
 		// Wed, 15 May 2019 12:00:00 UTC hard fork
 		GreatWallActivationTime: 1557921600,
+		// Nov 15, 2019 12:00:00 UTC hard fork
+		GravitonActivationTime: 1573819200,
+		// May 15, 2020 12:00:00 UTC hard fork
+		PhononActivationTime: 1589544000,
+		// Nov 15, 2020 12:00:00 UTC hard fork
+		AxionActivationTime: 1605441600,
+		// May 15, 2022 12:00:00 UTC hard fork
+		Upgrade8ActivationTime: 1652616000,
+		// May 15, 2023 12:00:00 UTC hard fork
+		Upgrade9ActivationTime: 1684152000,
 		//CashHardForkActivationTime: 1510600000,
 		GenesisHash: &TestNetGenesisHash,
 		//CashaddrPrefix: "xbctest",
@@ -327,6 +368,7 @@ var RegressionNetParams = BitcoinParams{
 		PowLimit:                      regressingPowLimit,
 		TargetTimespan:                60 * 60 * 24 * 14,
 		TargetTimePerBlock:            60 * 10,
+		ASERTHalfLife:                 2 * 24 * 60 * 60,
 		FPowAllowMinDifficultyBlocks:  true,
 		FPowNoRetargeting:             true,
 		RuleChangeActivationThreshold: 108,
@@ -356,10 +398,26 @@ var RegressionNetParams = BitcoinParams{
 
 		// Wed, 15 May 2019 12:00:00 UTC hard fork
 		GreatWallActivationTime: 1557921600,
+
+		// Nov 15, 2019 12:00:00 UTC hard fork
+		GravitonActivationTime: 1573819200,
+
+		// May 15, 2020 12:00:00 UTC hard fork
+		PhononActivationTime: 1589544000,
+
+		// Nov 15, 2020 12:00:00 UTC hard fork
+		AxionActivationTime: 1605441600,
+
+		// May 15, 2022 12:00:00 UTC hard fork
+		Upgrade8ActivationTime: 1652616000,
+
+		// May 15, 2023 12:00:00 UTC hard fork
+		Upgrade9ActivationTime: 1684152000,
 	},
 
 	Name:         "regtest",
 	BitcoinNet:   wire.RegTestNet,
+	DiskMagic:    wire.RegDiskMagic,
 	DefaultPort:  "18444",
 	DNSSeeds:     []DNSSeed{},
 	GenesisBlock: RegTestGenesisBlock,
@@ -464,8 +522,59 @@ func IsMagneticAnomalyEnabled(mediaTimePast int64) bool {
 	return mediaTimePast >= activeTime
 }
 
+func IsGreatWallEnabled(medianTimePast int64) bool {
+	activeTime := ActiveNetParams.GreatWallActivationTime
+	if conf.Args.GreatWallActivationTime > 0 {
+		activeTime = conf.Args.GreatWallActivationTime
+	}
+	return medianTimePast >= activeTime
+}
+
+func IsGravitonEnabled(medianTimePast int64) bool {
+	activeTime := ActiveNetParams.GravitonActivationTime
+	if conf.Args.GravitonActivationTime > 0 {
+		activeTime = conf.Args.GravitonActivationTime
+	}
+	return medianTimePast >= activeTime
+}
+
+func IsPhononEnabled(medianTimePast int64) bool {
+	activeTime := ActiveNetParams.PhononActivationTime
+	if conf.Args.PhononActivationTime > 0 {
+		activeTime = conf.Args.PhononActivationTime
+	}
+	return medianTimePast >= activeTime
+}
+
+func IsAxionEnabled(medianTimePast int64) bool {
+	activeTime := ActiveNetParams.AxionActivationTime
+	if conf.Args.AxionActivationTime > 0 {
+		activeTime = conf.Args.AxionActivationTime
+	}
+	return medianTimePast >= activeTime
+}
+
+func IsUpgrade8Enabled(medianTimePast int64) bool {
+	activeTime := ActiveNetParams.Upgrade8ActivationTime
+	if conf.Args.Upgrade8ActivationTime > 0 {
+		activeTime = conf.Args.Upgrade8ActivationTime
+	}
+	return medianTimePast >= activeTime
+}
+
+func IsUpgrade9Enabled(medianTimePast int64) bool {
+	activeTime := ActiveNetParams.Upgrade9ActivationTime
+	if conf.Args.Upgrade9ActivationTime > 0 {
+		activeTime = conf.Args.Upgrade9ActivationTime
+	}
+	return medianTimePast >= activeTime
+}
+
+// IsReplayProtectionEnabled reports whether the replay protection of the next,
+// not yet scheduled, upgrade is enabled. It never is unless its activation
+// time is configured.
 func IsReplayProtectionEnabled(medianTimePast int64) bool {
-	time := ActiveNetParams.GreatWallActivationTime
+	time := int64(math.MaxInt64)
 	if conf.Args.ReplayProtectionActivationTime > 0 {
 		time = conf.Args.ReplayProtectionActivationTime
 	}
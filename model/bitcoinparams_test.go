@@ -114,6 +114,60 @@ func TestIsMagneticAnomalyEnabled(t *testing.T) {
 		ActiveNetParams.MagneticAnomalyActivationTime))
 }
 
+func TestIsGreatWallEnabled(t *testing.T) {
+	for _, params := range []*BitcoinParams{&MainNetParams, &TestNetParams, &RegressionNetParams} {
+		ActiveNetParams = params
+		assert.False(t, IsGreatWallEnabled(ActiveNetParams.MagneticAnomalyActivationTime))
+		assert.False(t, IsGreatWallEnabled(ActiveNetParams.GreatWallActivationTime-1))
+		assert.True(t, IsGreatWallEnabled(ActiveNetParams.GreatWallActivationTime))
+	}
+}
+
+func TestIsGravitonEnabled(t *testing.T) {
+	for _, params := range []*BitcoinParams{&MainNetParams, &TestNetParams, &RegressionNetParams} {
+		ActiveNetParams = params
+		assert.False(t, IsGravitonEnabled(ActiveNetParams.GreatWallActivationTime))
+		assert.False(t, IsGravitonEnabled(ActiveNetParams.GravitonActivationTime-1))
+		assert.True(t, IsGravitonEnabled(ActiveNetParams.GravitonActivationTime))
+	}
+}
+
+func TestIsPhononEnabled(t *testing.T) {
+	for _, params := range []*BitcoinParams{&MainNetParams, &TestNetParams, &RegressionNetParams} {
+		ActiveNetParams = params
+		assert.False(t, IsPhononEnabled(ActiveNetParams.GravitonActivationTime))
+		assert.False(t, IsPhononEnabled(ActiveNetParams.PhononActivationTime-1))
+		assert.True(t, IsPhononEnabled(ActiveNetParams.PhononActivationTime))
+	}
+}
+
+func TestIsAxionEnabled(t *testing.T) {
+	for _, params := range []*BitcoinParams{&MainNetParams, &TestNetParams, &RegressionNetParams} {
+		ActiveNetParams = params
+		assert.False(t, IsAxionEnabled(ActiveNetParams.PhononActivationTime))
+		assert.False(t, IsAxionEnabled(ActiveNetParams.AxionActivationTime-1))
+		assert.True(t, IsAxionEnabled(ActiveNetParams.AxionActivationTime))
+	}
+}
+
+func TestIsUpgrade8Enabled(t *testing.T) {
+	for _, params := range []*BitcoinParams{&MainNetParams, &TestNetParams, &RegressionNetParams} {
+		ActiveNetParams = params
+		assert.False(t, IsUpgrade8Enabled(ActiveNetParams.AxionActivationTime))
+		assert.False(t, IsUpgrade8Enabled(ActiveNetParams.Upgrade8ActivationTime-1))
+		assert.True(t, IsUpgrade8Enabled(ActiveNetParams.Upgrade8ActivationTime))
+	}
+}
+
+func TestIsUpgrade9Enabled(t *testing.T) {
+	for _, params := range []*BitcoinParams{&MainNetParams, &TestNetParams, &RegressionNetParams} {
+		ActiveNetParams = params
+		assert.False(t, IsUpgrade9Enabled(ActiveNetParams.Upgrade8ActivationTime))
+		assert.False(t, IsUpgrade9Enabled(ActiveNetParams.Upgrade9ActivationTime-1))
+		assert.True(t, IsUpgrade9Enabled(ActiveNetParams.Upgrade9ActivationTime))
+	}
+}
+
 func TestIsDAAEnabled(t *testing.T) {
 	ActiveNetParams = &MainNetParams
 
@@ -137,6 +191,32 @@ func TestIsReplayProtectionEnabled(t *testing.T) {
 
 	isEnable = IsReplayProtectionEnabled(MainNetParams.MagneticAnomalyActivationTime)
 	assert.False(t, isEnable)
+
+	isEnable = IsReplayProtectionEnabled(MainNetParams.GravitonActivationTime)
+	assert.False(t, isEnable)
+
+	isEnable = IsReplayProtectionEnabled(MainNetParams.PhononActivationTime)
+	assert.False(t, isEnable)
+
+	isEnable = IsReplayProtectionEnabled(MainNetParams.AxionActivationTime)
+	assert.False(t, isEnable)
+
+	isEnable = IsReplayProtectionEnabled(MainNetParams.Upgrade8ActivationTime)
+	assert.False(t, isEnable)
+
+	isEnable = IsReplayProtectionEnabled(MainNetParams.Upgrade9ActivationTime)
+	assert.False(t, isEnable)
+
+	isEnable = IsReplayProtectionEnabled(time.Now().Unix())
+	assert.False(t, isEnable)
+
+	if conf.Args != nil {
+		conf.Args.ReplayProtectionActivationTime = MainNetParams.Upgrade9ActivationTime
+		defer func() { conf.Args.ReplayProtectionActivationTime = -1 }()
+
+		isEnable = IsReplayProtectionEnabled(MainNetParams.Upgrade9ActivationTime)
+		assert.True(t, isEnable)
+	}
 }
 
 func TestGetBlockSubsidy(t *testing.T) {
@@ -67,6 +67,9 @@ type BlockIndex struct {
 	// (memory only) Sequential id assigned to distinguish order in which
 	// blocks are received.
 	SequenceID uint64
+	// (memory only) First block of the chain of this block to activate the
+	// aserti3-2d DAA, once looked up from this block.
+	ASERTAnchor *BlockIndex
 	// (memory only) Maximum time in the chain upto and including this block.
 	TimeMax   uint32
 	isGenesis bool
@@ -91,6 +94,7 @@ func (bIndex *BlockIndex) SetNull() {
 	bIndex.Status = 0
 	bIndex.SequenceID = 0
 	bIndex.TimeMax = 0
+	bIndex.ASERTAnchor = nil
 }
 
 //func (bIndex *BlockIndex) WaitingData() bool {
@@ -226,6 +230,11 @@ func (bIndex *BlockIndex) IsInvalid() bool {
 	return bIndex.Status&BlockInvalidMask != 0
 }
 
+// IsParked checks whether this block or one of its parents has been parked.
+func (bIndex *BlockIndex) IsParked() bool {
+	return bIndex.Status&BlockParkedMask != 0
+}
+
 func (bIndex *BlockIndex) getValidity() uint32 {
 	return bIndex.Status & BlockValidMask
 }
@@ -319,6 +328,51 @@ func (bIndex *BlockIndex) IsReplayProtectionJustEnabled() bool {
 		model.IsReplayProtectionEnabled(bIndex.GetMedianTimePast())
 }
 
+func (bIndex *BlockIndex) IsGreatWallJustEnabled() bool {
+	if bIndex.Prev == nil {
+		return false
+	}
+
+	return !model.IsGreatWallEnabled(bIndex.Prev.GetMedianTimePast()) &&
+		model.IsGreatWallEnabled(bIndex.GetMedianTimePast())
+}
+
+func (bIndex *BlockIndex) IsGravitonJustEnabled() bool {
+	if bIndex.Prev == nil {
+		return false
+	}
+
+	return !model.IsGravitonEnabled(bIndex.Prev.GetMedianTimePast()) &&
+		model.IsGravitonEnabled(bIndex.GetMedianTimePast())
+}
+
+func (bIndex *BlockIndex) IsPhononJustEnabled() bool {
+	if bIndex.Prev == nil {
+		return false
+	}
+
+	return !model.IsPhononEnabled(bIndex.Prev.GetMedianTimePast()) &&
+		model.IsPhononEnabled(bIndex.GetMedianTimePast())
+}
+
+func (bIndex *BlockIndex) IsUpgrade8JustEnabled() bool {
+	if bIndex.Prev == nil {
+		return false
+	}
+
+	return !model.IsUpgrade8Enabled(bIndex.Prev.GetMedianTimePast()) &&
+		model.IsUpgrade8Enabled(bIndex.GetMedianTimePast())
+}
+
+func (bIndex *BlockIndex) IsUpgrade9JustEnabled() bool {
+	if bIndex.Prev == nil {
+		return false
+	}
+
+	return !model.IsUpgrade9Enabled(bIndex.Prev.GetMedianTimePast()) &&
+		model.IsUpgrade9Enabled(bIndex.GetMedianTimePast())
+}
+
 func (bIndex *BlockIndex) IsMagneticAnomalyJustEnabled() bool {
 	if bIndex.Prev == nil {
 		return false
@@ -131,6 +131,17 @@ func TestFailed(t *testing.T) {
 	}
 }
 
+func TestParked(t *testing.T) {
+	var bIndex BlockIndex
+	if bIndex.IsParked() {
+		t.Errorf("IsParked is wrong")
+	}
+	bIndex.Status = BlockParkedParent
+	if !bIndex.IsParked() || bIndex.IsInvalid() {
+		t.Errorf("IsParked is wrong")
+	}
+}
+
 func TestGetUndoPos(t *testing.T) {
 	var bIndex BlockIndex
 	testInt := int32(34536)
@@ -45,4 +45,11 @@ const (
 	BlockFailedParent uint32 = 64
 	// BlockInvalidMask Mask used to check if the block failed.
 	BlockInvalidMask = BlockFailed | BlockFailedParent
+
+	// BlockParked The block is parked, it is not considered for activation.
+	BlockParked uint32 = 128
+	// BlockParkedParent The block has a parked parent.
+	BlockParkedParent uint32 = 256
+	// BlockParkedMask Mask used to check if the block is parked.
+	BlockParkedMask = BlockParked | BlockParkedParent
 )
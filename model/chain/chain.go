@@ -30,6 +30,13 @@ type Chain struct {
 	receiveID uint64
 	params    *model.BitcoinParams
 
+	// the base of the UTXO snapshot the chain state was loaded from, while
+	// the blocks below it are being downloaded
+	snapshotBase *blockindex.BlockIndex
+
+	// the last finalized block, the active chain is never reorganized below it
+	finalized *blockindex.BlockIndex
+
 	// The notifications field stores a slice of callbacks to be executed on
 	// certain blockchain events.
 	notificationsLock sync.RWMutex
@@ -272,6 +279,44 @@ func (c *Chain) GetBlockScriptFlags(pindex *blockindex.BlockIndex) uint32 {
 		flags |= script.ScriptVerifyCleanStack
 	}
 
+	// When the great wall fork is enabled, we start accepting Schnorr
+	// signatures in OP_CHECK(DATA)SIG and reject 65 bytes signatures in
+	// OP_CHECKMULTISIG. Coins sent to p2sh wrapped segwit addresses also
+	// become recoverable, see ScriptAllowSegwitRecovery.
+	if model.IsGreatWallEnabled(pindex.GetMedianTimePast()) {
+		flags |= script.ScriptEnableSchnorr
+		flags |= script.ScriptAllowSegwitRecovery
+	}
+
+	// When the graviton fork is enabled, OP_CHECKMULTISIG accepts Schnorr
+	// signatures selected by a bitfield dummy element, and minimal data
+	// pushes and numbers become a consensus rule.
+	if model.IsGravitonEnabled(pindex.GetMedianTimePast()) {
+		flags |= script.ScriptEnableSchnorrMultisig
+		flags |= script.ScriptVerifyMinmalData
+	}
+
+	// When the phonon fork is enabled, OP_REVERSEBYTES becomes available and
+	// the SigChecks of each input are limited by the size of its scriptSig.
+	if model.IsPhononEnabled(pindex.GetMedianTimePast()) {
+		flags |= script.ScriptEnableOpReverseBytes
+		flags |= script.ScriptVerifyInputSigChecks
+	}
+
+	// When the May 2022 upgrade is enabled, script numbers are 64-bit wide,
+	// OP_MUL comes back and the native introspection opcodes are available.
+	if model.IsUpgrade8Enabled(pindex.GetMedianTimePast()) {
+		flags |= script.ScriptEnable64BitIntegers
+		flags |= script.ScriptEnableNativeIntrospection
+	}
+
+	// When the May 2023 upgrade is enabled, outputs carry tokens, the token
+	// introspection opcodes are available and P2SH can use a 32 bytes hash.
+	if model.IsUpgrade9Enabled(pindex.GetMedianTimePast()) {
+		flags |= script.ScriptEnableTokens
+		flags |= script.ScriptEnableP2SH32
+	}
+
 	// We make sure this node will have replay protection during the next hard
 	// fork.
 	if model.IsReplayProtectionEnabled(pindex.GetMedianTimePast()) {
@@ -538,6 +583,81 @@ func (c *Chain) AddToBranch(bis *blockindex.BlockIndex) error {
 	return nil
 }
 
+// SnapshotBase returns the base block of the UTXO snapshot the chain state
+// was loaded from, or nil once all the blocks below it have been downloaded.
+func (c *Chain) SnapshotBase() *blockindex.BlockIndex {
+	return c.snapshotBase
+}
+
+// SetSnapshotBase sets the base block of a loaded UTXO snapshot and puts it in
+// the branch without recomputing its ChainTxCount, since the blocks below it
+// are not available yet. A nil index forgets the snapshot base.
+func (c *Chain) SetSnapshotBase(index *blockindex.BlockIndex) {
+	c.snapshotBase = index
+	if index != nil && !c.InBranch(index) {
+		index.SequenceID = c.GetReceivedID()
+		c.insertToBranch(index)
+	}
+}
+
+// FinalizedBlock returns the last finalized block, or nil if no block has
+// been finalized yet.
+func (c *Chain) FinalizedBlock() *blockindex.BlockIndex {
+	return c.finalized
+}
+
+// SetFinalizedBlock sets the last finalized block, a nil index forgets it.
+func (c *Chain) SetFinalizedBlock(index *blockindex.BlockIndex) {
+	c.finalized = index
+}
+
+// IsBlockFinalized returns whether index is the finalized block or one of its
+// ancestors.
+func (c *Chain) IsBlockFinalized(index *blockindex.BlockIndex) bool {
+	return c.finalized != nil && index != nil && c.finalized.GetAncestor(index.Height) == index
+}
+
+// AreOnTheSameFork returns whether one of the two blocks is an ancestor of the
+// other.
+func AreOnTheSameFork(a, b *blockindex.BlockIndex) bool {
+	return a.GetAncestor(b.Height) == b || b.GetAncestor(a.Height) == a
+}
+
+// IsOnParkedChain returns whether bi or one of its ancestors above the fork
+// with the active chain is parked.
+func (c *Chain) IsOnParkedChain(bi *blockindex.BlockIndex) bool {
+	for pindex := bi; pindex != nil && !c.Contains(pindex); pindex = pindex.Prev {
+		if pindex.IsParked() {
+			return true
+		}
+	}
+	return false
+}
+
+// canActivate returns whether the chain of bi may become the active chain: it
+// must descend from the finalized block and must not be parked.
+func (c *Chain) canActivate(bi *blockindex.BlockIndex) bool {
+	if c.finalized != nil && bi.GetAncestor(c.finalized.Height) != c.finalized {
+		return false
+	}
+	return !c.IsOnParkedChain(bi)
+}
+
+func (c *Chain) ResetBlockParkedFlags(targetBI *blockindex.BlockIndex) {
+	for _, bi := range c.indexMap {
+		if bi.IsParked() && bi.GetAncestor(targetBI.Height) == targetBI {
+			bi.SubStatus(blockindex.BlockParkedMask)
+			persist.GetInstance().AddDirtyBlockIndex(bi)
+
+			if bi.IsValid(blockindex.BlockValidTransactions) && bi.ChainTxCount > 0 {
+				if !c.InBranch(bi) {
+					c.insertToBranch(bi)
+				}
+			}
+		}
+	}
+}
+
 func (c *Chain) RemoveFromBranch(bis *blockindex.BlockIndex) error {
 	if bis == nil {
 		return errors.New("nil blockIndex")
@@ -553,8 +673,10 @@ func (c *Chain) RemoveFromBranch(bis *blockindex.BlockIndex) error {
 }
 
 func (c *Chain) FindMostWorkChain() *blockindex.BlockIndex {
-	if len(c.branch) > 0 {
-		return c.branch[len(c.branch)-1]
+	for i := len(c.branch) - 1; i >= 0; i-- {
+		if c.canActivate(c.branch[i]) {
+			return c.branch[i]
+		}
 	}
 	return nil
 }
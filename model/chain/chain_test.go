@@ -174,6 +174,61 @@ func TestChain_Fork(t *testing.T) {
 
 }
 
+func TestChain_FinalizedAndParked(t *testing.T) {
+	InitGlobalChain()
+	tChain := GetInstance()
+
+	tChain.indexMap = make(map[util.Hash]*blockindex.BlockIndex)
+	tChain.active = make([]*blockindex.BlockIndex, 0)
+	tChain.branch = make([]*blockindex.BlockIndex, 0)
+	mainIndex := make([]*blockindex.BlockIndex, 11)
+	forkIndex := make([]*blockindex.BlockIndex, 14)
+	initBits := model.ActiveNetParams.PowLimitBits
+	timePerBlock := int64(model.ActiveNetParams.TargetTimePerBlock)
+
+	mainIndex[0] = blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	tChain.AddToIndexMap(mainIndex[0])
+	tChain.AddToBranch(mainIndex[0])
+	tChain.active = append(tChain.active, mainIndex[0])
+	for height := 1; height < 11; height++ {
+		mainIndex[height] = getBlockIndex(mainIndex[height-1], timePerBlock, initBits)
+		tChain.AddToIndexMap(mainIndex[height])
+		tChain.AddToBranch(mainIndex[height])
+		tChain.active = append(tChain.active, mainIndex[height])
+	}
+	// a fork from height 5 with more work than the active chain
+	forkIndex[5] = mainIndex[5]
+	for height := 6; height < 14; height++ {
+		forkIndex[height] = getBlockIndex(forkIndex[height-1], timePerBlock-1, initBits)
+		tChain.AddToIndexMap(forkIndex[height])
+		tChain.AddToBranch(forkIndex[height])
+	}
+	if tChain.FindMostWorkChain() != forkIndex[13] {
+		t.Errorf("FindMostWorkChain should return the fork tip")
+	}
+
+	if !AreOnTheSameFork(mainIndex[3], mainIndex[10]) || AreOnTheSameFork(forkIndex[13], mainIndex[7]) {
+		t.Errorf("AreOnTheSameFork Error")
+	}
+	tChain.SetFinalizedBlock(mainIndex[7])
+	if !tChain.IsBlockFinalized(mainIndex[3]) || tChain.IsBlockFinalized(forkIndex[6]) {
+		t.Errorf("IsBlockFinalized Error")
+	}
+	if tChain.FindMostWorkChain() != mainIndex[10] {
+		t.Errorf("FindMostWorkChain should not return a fork below the finalized block")
+	}
+	tChain.SetFinalizedBlock(nil)
+
+	forkIndex[8].AddStatus(blockindex.BlockParked)
+	if tChain.FindMostWorkChain() != mainIndex[10] {
+		t.Errorf("FindMostWorkChain should not return a parked chain")
+	}
+	tChain.ResetBlockParkedFlags(forkIndex[8])
+	if forkIndex[8].IsParked() || tChain.FindMostWorkChain() != forkIndex[13] {
+		t.Errorf("ResetBlockParkedFlags Error")
+	}
+}
+
 func TestChain_InitLoad(t *testing.T) {
 	InitGlobalChain()
 	tChain := GetInstance()
@@ -6,3 +6,11 @@ type Checkpoint struct {
 	Height int32
 	Hash   *util.Hash
 }
+
+// AssumeUTXOData is what a UTXO snapshot taken at a block is known to hold:
+// the hash of its coins and the number of transactions up to the block.
+type AssumeUTXOData struct {
+	BlockHash      util.Hash
+	HashSerialized util.Hash
+	ChainTxCount   int32
+}
@@ -21,11 +21,22 @@ const (
 	/*MaxTxSigOpsCount allowed number of signature check operations per transaction. */
 	MaxTxSigOpsCount = 20000
 
+	/*MaxTxSigChecks allowed number of SigChecks per transaction once the phonon upgrade is activated. */
+	MaxTxSigChecks = 3000
+
+	/*BlockMaxBytesMaxSigChecksRatio The ratio between the maximum allowable block size and the maximum
+	* allowable SigChecks (executed signature check operations) in the block. (network rule) */
+	BlockMaxBytesMaxSigChecksRatio = 141
+
 	// CoinbaseMaturity means Coinbase transaction outputs can only be spent after this number of new
 	// blocks (network rule)
 	CoinbaseMaturity = 100
 
 	MinTxSize = 100
+
+	// MinTxSizeUpgrade9 is the minimum transaction size in bytes since the
+	// May 2023 upgrade. (network rule)
+	MinTxSizeUpgrade9 = 65
 )
 
 const (
@@ -48,3 +59,9 @@ func GetMaxBlockSigOpsCount(blockSize uint64) (uint64, error) {
 	roundedUp := 1 + ((blockSize - 1) / OneMegaByte)
 	return roundedUp * MaxBlockSigopsPerMb, nil
 }
+
+// GetMaxBlockSigChecksCount Compute the maximum number of SigChecks that can be contained in a block
+// given the maximum block size as parameter.
+func GetMaxBlockSigChecksCount(maxBlockSize uint64) uint64 {
+	return maxBlockSize / BlockMaxBytesMaxSigChecksRatio
+}
@@ -55,6 +55,16 @@ type Param struct {
 	MagneticAnomalyActivationTime int64
 	// Unix time used for MTP activation of 15 May 2019 12:00:00 UTC upgrade */
 	GreatWallActivationTime int64
+	// Unix time used for MTP activation of 15 Nov 2019 12:00:00 UTC upgrade
+	GravitonActivationTime int64
+	// Unix time used for MTP activation of 15 May 2020 12:00:00 UTC upgrade
+	PhononActivationTime int64
+	// Unix time used for MTP activation of 15 Nov 2020 12:00:00 UTC upgrade
+	AxionActivationTime int64
+	// Unix time used for MTP activation of 15 May 2022 12:00:00 UTC upgrade
+	Upgrade8ActivationTime int64
+	// Unix time used for MTP activation of 15 May 2023 12:00:00 UTC upgrade
+	Upgrade9ActivationTime int64
 
 	// Minimum blocks including miner confirmation of the total of 2016 blocks
 	// in a retargeting period, (nPowTargetTimespan / nPowTargetSpacing) which
@@ -73,6 +83,8 @@ type Param struct {
 	FPowNoRetargeting            bool
 	TargetTimePerBlock           time.Duration
 	TargetTimespan               time.Duration
+	// Half-life in seconds of the aserti3-2d difficulty adjustment
+	ASERTHalfLife int64
 
 	// The best chain should have at least this much work.
 	MinimumChainWork util.Hash
@@ -0,0 +1,679 @@
+package mempool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// estimateFeeDepth is the maximum number of blocks before a transaction
+	// is confirmed that we want to track.
+	estimateFeeDepth = 25
+
+	// estimateFeeBinSize is the number of txs stored in each bin.
+	estimateFeeBinSize = 100
+
+	// estimateFeeMaxReplacements is the max number of replacements that
+	// can be made by the txs found in a given block.
+	estimateFeeMaxReplacements = 10
+
+	// DefaultEstimateFeeMaxRollback is the default number of rollbacks
+	// allowed by the fee estimator for orphaned blocks.
+	DefaultEstimateFeeMaxRollback = 2
+
+	// DefaultEstimateFeeMinRegisteredBlocks is the default minimum
+	// number of blocks which must be observed by the fee estimator before
+	// it will provide fee estimations.
+	DefaultEstimateFeeMinRegisteredBlocks = 3
+
+	// EstimateFeeDepth is the largest confirmation target we can estimate.
+	EstimateFeeDepth = estimateFeeDepth
+
+	// unminedHeight is the height used for transactions not mined yet.
+	unminedHeight = math.MaxInt32
+
+	// estimateFeeSaveVersion is the version of the serialized fee estimator.
+	// If the format changes, previous states are not upgraded, the
+	// estimation just starts over.
+	estimateFeeSaveVersion = 1
+)
+
+var (
+	// ErrNotEnoughBlocks is returned while the fee estimator has not seen
+	// enough blocks to provide estimations.
+	ErrNotEnoughBlocks = errors.New("not enough blocks have been observed")
+
+	errEstimateFeeZeroBlocks = errors.New("cannot confirm transaction in zero blocks")
+)
+
+var gFeeEstimator *FeeEstimator
+
+// GetFeeEstimator returns the fee estimator fed by the mempool and the
+// connected blocks.
+func GetFeeEstimator() *FeeEstimator {
+	if gFeeEstimator == nil {
+		gFeeEstimator = NewFeeEstimator(DefaultEstimateFeeMaxRollback, DefaultEstimateFeeMinRegisteredBlocks)
+	}
+	return gFeeEstimator
+}
+
+// SetFeeEstimator replaces the global fee estimator, e.g. with one restored
+// from disk.
+func SetFeeEstimator(ef *FeeEstimator) {
+	gFeeEstimator = ef
+}
+
+// observedTransaction represents an observed transaction and some
+// additional data required for the fee estimation algorithm.
+type observedTransaction struct {
+	// A transaction hash.
+	hash util.Hash
+
+	// The fee per byte of the transaction in satoshis.
+	feeRate float64
+
+	// The block height when it was observed.
+	observed int32
+
+	// The height of the block in which it was mined.
+	// If the transaction has not yet been mined, it is unminedHeight.
+	mined int32
+}
+
+// registeredBlock has the hash of a block and the list of transactions
+// it mined which had been previously observed by the FeeEstimator. It
+// is used if Rollback is called to reverse the effect of registering
+// a block.
+type registeredBlock struct {
+	hash         util.Hash
+	transactions []*observedTransaction
+}
+
+// FeeEstimator tracks the time transactions spend in the mempool, as the
+// number of blocks between their acceptance and their confirmation, and
+// derives from it the fee rate needed to be confirmed within a given number
+// of blocks. It is safe for concurrent access.
+type FeeEstimator struct {
+	maxRollback uint32
+	binSize     int32
+
+	// The maximum number of replacements that can be made in a single
+	// bin per block.
+	maxReplacements int32
+
+	// The minimum number of blocks that can be registered with the fee
+	// estimator before it will provide answers.
+	minRegisteredBlocks uint32
+
+	// The last known height.
+	lastKnownHeight int32
+
+	// The number of blocks that have been registered.
+	numBlocksRegistered uint32
+
+	mtx      sync.Mutex
+	observed map[util.Hash]*observedTransaction
+	bin      [estimateFeeDepth][]*observedTransaction
+
+	// The cached estimates, in satoshis per byte.
+	cached []float64
+
+	// Transactions that have been removed from the bins. This allows us to
+	// revert in case of an orphaned block.
+	dropped []*registeredBlock
+}
+
+// NewFeeEstimator creates a FeeEstimator for which at most maxRollback blocks
+// can be unregistered and which returns an error unless minRegisteredBlocks
+// have been registered with it.
+func NewFeeEstimator(maxRollback, minRegisteredBlocks uint32) *FeeEstimator {
+	return &FeeEstimator{
+		maxRollback:         maxRollback,
+		minRegisteredBlocks: minRegisteredBlocks,
+		lastKnownHeight:     unminedHeight,
+		binSize:             estimateFeeBinSize,
+		maxReplacements:     estimateFeeMaxReplacements,
+		observed:            make(map[util.Hash]*observedTransaction),
+		dropped:             make([]*registeredBlock, 0, maxRollback),
+	}
+}
+
+// ObserveTransaction is called when a new transaction enters the mempool.
+func (ef *FeeEstimator) ObserveTransaction(entry *TxEntry) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	// If we haven't seen a block yet we don't know when this one arrived,
+	// so we ignore it.
+	if ef.lastKnownHeight == unminedHeight || entry.TxSize == 0 {
+		return
+	}
+
+	hash := entry.Tx.GetHash()
+	if _, ok := ef.observed[hash]; !ok {
+		ef.observed[hash] = &observedTransaction{
+			hash:     hash,
+			feeRate:  float64(entry.TxFee) / float64(entry.TxSize),
+			observed: entry.TxHeight,
+			mined:    unminedHeight,
+		}
+	}
+}
+
+// RegisterBlock informs the fee estimator of a new block connected at height.
+func (ef *FeeEstimator) RegisterBlock(blk *block.Block, height int32) error {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	// The previous sorted list is invalid, so delete it.
+	ef.cached = nil
+
+	if height != ef.lastKnownHeight+1 && ef.lastKnownHeight != unminedHeight {
+		return fmt.Errorf("intermediate block not recorded; current height is %d; new height is %d",
+			ef.lastKnownHeight, height)
+	}
+
+	// Update the last known height.
+	ef.lastKnownHeight = height
+	ef.numBlocksRegistered++
+
+	// Count the number of replacements we make per bin so that we don't
+	// replace too many.
+	var replacementCounts [estimateFeeDepth]int
+
+	// Keep track of which txs were dropped in case of an orphan block.
+	dropped := &registeredBlock{
+		hash:         blk.GetHash(),
+		transactions: make([]*observedTransaction, 0, 100),
+	}
+
+	// Go through the txs in the block, in random order since the bins are
+	// limited in size.
+	for _, i := range rand.Perm(len(blk.Txs)) {
+		hash := blk.Txs[i].GetHash()
+
+		// Have we observed this tx in the mempool?
+		o, ok := ef.observed[hash]
+		if !ok {
+			continue
+		}
+
+		// This shouldn't happen if the fee estimator works correctly,
+		// but return an error if it does.
+		if o.mined != unminedHeight {
+			log.Error("Estimate fee: transaction %s has already been mined", hash)
+			return errors.New("transaction has already been mined")
+		}
+
+		// Put the observed tx in the appropriate bin. Ignore txs
+		// observed too long ago, or with a bogus height.
+		blocksToConfirm := height - o.observed - 1
+		if blocksToConfirm < 0 || blocksToConfirm >= estimateFeeDepth {
+			continue
+		}
+
+		// Make sure we do not replace too many transactions per bin.
+		if replacementCounts[blocksToConfirm] == int(ef.maxReplacements) {
+			continue
+		}
+
+		o.mined = height
+
+		replacementCounts[blocksToConfirm]++
+
+		bin := ef.bin[blocksToConfirm]
+
+		// Remove a random element and replace it with this new tx.
+		if len(bin) == int(ef.binSize) {
+			// Don't drop transactions we have just added from this same block.
+			l := int(ef.binSize) - replacementCounts[blocksToConfirm]
+			drop := rand.Intn(l)
+			dropped.transactions = append(dropped.transactions, bin[drop])
+
+			bin[drop] = bin[l-1]
+			bin[l-1] = o
+		} else {
+			bin = append(bin, o)
+		}
+		ef.bin[blocksToConfirm] = bin
+	}
+
+	// Go through the mempool for txs that have been in too long.
+	for hash, o := range ef.observed {
+		if o.mined == unminedHeight && height-o.observed >= estimateFeeDepth {
+			delete(ef.observed, hash)
+		}
+	}
+
+	// Add dropped list to history.
+	if ef.maxRollback == 0 {
+		return nil
+	}
+
+	if uint32(len(ef.dropped)) == ef.maxRollback {
+		ef.dropped = append(ef.dropped[1:], dropped)
+	} else {
+		ef.dropped = append(ef.dropped, dropped)
+	}
+
+	return nil
+}
+
+// LastKnownHeight returns the height of the last block which was registered.
+func (ef *FeeEstimator) LastKnownHeight() int32 {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	return ef.lastKnownHeight
+}
+
+// Rollback unregisters a recently registered block from the FeeEstimator.
+// This can be used to reverse the effect of a disconnected block on the fee
+// estimator. The maximum number of rollbacks allowed is given by
+// maxRollback.
+//
+// Note: not everything can be rolled back because some transactions are
+// deleted if they have been observed too long ago. That means the result
+// of Rollback won't always be exactly the same as if the last block had not
+// happened, but it should be close enough.
+func (ef *FeeEstimator) Rollback(hash *util.Hash) error {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	// Find this block in the stack of recent registered blocks.
+	var n int
+	for n = 1; n <= len(ef.dropped); n++ {
+		if ef.dropped[len(ef.dropped)-n].hash.IsEqual(hash) {
+			break
+		}
+	}
+
+	if n > len(ef.dropped) {
+		return errors.New("no such block was recently registered")
+	}
+
+	for i := 0; i < n; i++ {
+		ef.rollback()
+	}
+
+	return nil
+}
+
+// rollback rolls back the effect of the last block in the stack
+// of registered blocks.
+func (ef *FeeEstimator) rollback() {
+	// The previous sorted list is invalid, so delete it.
+	ef.cached = nil
+
+	// pop the last list of dropped txs from the stack.
+	last := len(ef.dropped) - 1
+	if last == -1 {
+		// Cannot really happen because the exported calling function
+		// only rolls back a block already known to be in the list
+		// of dropped transactions.
+		return
+	}
+
+	dropped := ef.dropped[last]
+
+	// where we are in each bin as we replace txs?
+	var replacementCounters [estimateFeeDepth]int
+
+	// Go through the txs in the dropped block.
+	for _, o := range dropped.transactions {
+		// Which bin was this tx in?
+		blocksToConfirm := o.mined - o.observed - 1
+
+		bin := ef.bin[blocksToConfirm]
+
+		var counter = replacementCounters[blocksToConfirm]
+
+		// Continue to go through that bin where we left off.
+		for {
+			if counter >= len(bin) {
+				// Panic, as we have entered an unrecoverable invalid state.
+				panic(errors.New("illegal state: cannot rollback dropped transaction"))
+			}
+
+			prev := bin[counter]
+
+			if prev.mined == ef.lastKnownHeight {
+				prev.mined = unminedHeight
+
+				bin[counter] = o
+
+				counter++
+				break
+			}
+
+			counter++
+		}
+
+		replacementCounters[blocksToConfirm] = counter
+	}
+
+	// Continue going through bins to find other txs to remove
+	// which did not replace any other when they were entered.
+	for i, j := range replacementCounters {
+		for {
+			l := len(ef.bin[i])
+			if j >= l {
+				break
+			}
+
+			prev := ef.bin[i][j]
+
+			if prev.mined == ef.lastKnownHeight {
+				prev.mined = unminedHeight
+				ef.bin[i] = append(ef.bin[i][0:j], ef.bin[i][j+1:l]...)
+				continue
+			}
+
+			j++
+		}
+	}
+
+	ef.dropped = ef.dropped[0:last]
+
+	// The number of blocks the fee estimator has seen is decremented.
+	ef.numBlocksRegistered--
+	ef.lastKnownHeight--
+}
+
+// estimateFeeSet is a set of txs that can that is sorted
+// by the fee per byte rate.
+type estimateFeeSet struct {
+	feeRate []float64
+	bin     [estimateFeeDepth]uint32
+}
+
+func (b *estimateFeeSet) Len() int { return len(b.feeRate) }
+
+func (b *estimateFeeSet) Less(i, j int) bool {
+	return b.feeRate[i] > b.feeRate[j]
+}
+
+func (b *estimateFeeSet) Swap(i, j int) {
+	b.feeRate[i], b.feeRate[j] = b.feeRate[j], b.feeRate[i]
+}
+
+// estimateFee returns the estimated fee per byte for a transaction to confirm
+// in confirmations blocks from now, given the data set we have collected.
+func (b *estimateFeeSet) estimateFee(confirmations int) float64 {
+	if confirmations <= 0 {
+		return math.Inf(1)
+	}
+
+	if confirmations > estimateFeeDepth {
+		return 0
+	}
+
+	// We don't have any transactions!
+	if len(b.feeRate) == 0 {
+		return 0
+	}
+
+	var min, max int
+	for i := 0; i < confirmations-1; i++ {
+		min += int(b.bin[i])
+	}
+
+	max = min + int(b.bin[confirmations-1]) - 1
+	if max < min {
+		max = min
+	}
+	feeIndex := (min + max) / 2
+	if feeIndex >= len(b.feeRate) {
+		feeIndex = len(b.feeRate) - 1
+	}
+
+	return b.feeRate[feeIndex]
+}
+
+// newEstimateFeeSet creates a temporary data structure that
+// can be used to find all fee estimates.
+func (ef *FeeEstimator) newEstimateFeeSet() *estimateFeeSet {
+	set := &estimateFeeSet{}
+
+	capacity := 0
+	for i, b := range ef.bin {
+		l := len(b)
+		set.bin[i] = uint32(l)
+		capacity += l
+	}
+
+	set.feeRate = make([]float64, 0, capacity)
+	for _, b := range ef.bin {
+		for _, o := range b {
+			set.feeRate = append(set.feeRate, o.feeRate)
+		}
+	}
+
+	sort.Sort(set)
+
+	return set
+}
+
+// estimates returns the set of all fee estimates from 1 to estimateFeeDepth
+// confirmations from now.
+func (ef *FeeEstimator) estimates() []float64 {
+	set := ef.newEstimateFeeSet()
+
+	estimates := make([]float64, estimateFeeDepth)
+	for i := 0; i < estimateFeeDepth; i++ {
+		estimates[i] = set.estimateFee(i + 1)
+	}
+
+	return estimates
+}
+
+func (ef *FeeEstimator) estimateFee(numBlocks uint32) (float64, error) {
+	// If the number of registered blocks is below the minimum, return
+	// an error.
+	if ef.numBlocksRegistered < ef.minRegisteredBlocks {
+		return 0, ErrNotEnoughBlocks
+	}
+
+	if numBlocks == 0 {
+		return 0, errEstimateFeeZeroBlocks
+	}
+
+	if numBlocks > estimateFeeDepth {
+		return 0, fmt.Errorf("can only estimate fees for up to %d blocks from now",
+			estimateFeeDepth)
+	}
+
+	// If there are no cached results, generate them.
+	if ef.cached == nil {
+		ef.cached = ef.estimates()
+	}
+
+	return ef.cached[int(numBlocks)-1], nil
+}
+
+// EstimateFee estimates the fee rate needed to have a tx confirmed within
+// numBlocks blocks. A zero fee rate means no transaction confirmed within
+// numBlocks has been observed.
+func (ef *FeeEstimator) EstimateFee(numBlocks uint32) (util.FeeRate, error) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	rate, err := ef.estimateFee(numBlocks)
+	if err != nil {
+		return util.FeeRate{}, err
+	}
+	return util.FeeRate{SataoshisPerK: int64(rate * 1000)}, nil
+}
+
+// EstimateSmartFee is like EstimateFee, but when there is no estimate for
+// numBlocks it looks for the nearest longer target which has one. It returns
+// the target actually used, which is zero when no estimate is available.
+func (ef *FeeEstimator) EstimateSmartFee(numBlocks uint32) (util.FeeRate, uint32, error) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	if numBlocks > estimateFeeDepth {
+		numBlocks = estimateFeeDepth
+	}
+	for target := numBlocks; target <= estimateFeeDepth; target++ {
+		rate, err := ef.estimateFee(target)
+		if err != nil {
+			return util.FeeRate{}, 0, err
+		}
+		if rate > 0 {
+			return util.FeeRate{SataoshisPerK: int64(rate * 1000)}, target, nil
+		}
+	}
+	return util.FeeRate{}, 0, nil
+}
+
+// Save writes the state of the FeeEstimator to w, to be restored with
+// RestoreFeeEstimator in a later session.
+func (ef *FeeEstimator) Save(w io.Writer) error {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	// Put all the observed transactions in a sorted list, so that the same
+	// state always gives the same bytes.
+	ots := make([]*observedTransaction, 0, len(ef.observed))
+	for _, ot := range ef.observed {
+		ots = append(ots, ot)
+	}
+	sort.Slice(ots, func(i, j int) bool {
+		return ots[i].hash.Cmp(&ots[j].hash) < 0
+	})
+
+	indexes := make(map[*observedTransaction]uint32, len(ots))
+	data := []interface{}{
+		uint32(estimateFeeSaveVersion),
+		ef.maxRollback, ef.binSize, ef.maxReplacements, ef.minRegisteredBlocks,
+		ef.lastKnownHeight, ef.numBlocksRegistered,
+		uint32(len(ots)),
+	}
+	for i, ot := range ots {
+		indexes[ot] = uint32(i)
+		data = append(data, ot.hash, ot.feeRate, ot.observed, ot.mined)
+	}
+
+	// Bins and dropped transactions refer to the observed transactions by
+	// index in the list above.
+	for _, bin := range ef.bin {
+		data = append(data, uint32(len(bin)))
+		for _, o := range bin {
+			data = append(data, indexes[o])
+		}
+	}
+	data = append(data, uint32(len(ef.dropped)))
+	for _, registered := range ef.dropped {
+		data = append(data, registered.hash, uint32(len(registered.transactions)))
+		for _, o := range registered.transactions {
+			data = append(data, indexes[o])
+		}
+	}
+
+	for _, element := range data {
+		if err := binary.Write(w, binary.BigEndian, element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreFeeEstimator reads a FeeEstimator previously written by Save.
+func RestoreFeeEstimator(r io.Reader) (*FeeEstimator, error) {
+	var version uint32
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != estimateFeeSaveVersion {
+		return nil, fmt.Errorf("incorrect version: expected %d found %d", estimateFeeSaveVersion, version)
+	}
+
+	ef := &FeeEstimator{
+		observed: make(map[util.Hash]*observedTransaction),
+	}
+	var numObserved uint32
+	err := readElements(r, &ef.maxRollback, &ef.binSize, &ef.maxReplacements,
+		&ef.minRegisteredBlocks, &ef.lastKnownHeight, &ef.numBlocksRegistered, &numObserved)
+	if err != nil {
+		return nil, err
+	}
+
+	observed := make([]*observedTransaction, numObserved)
+	for i := range observed {
+		ot := &observedTransaction{}
+		if err := readElements(r, &ot.hash, &ot.feeRate, &ot.observed, &ot.mined); err != nil {
+			return nil, err
+		}
+		observed[i] = ot
+		ef.observed[ot.hash] = ot
+	}
+
+	readTxList := func() ([]*observedTransaction, error) {
+		var count uint32
+		if err := readElements(r, &count); err != nil {
+			return nil, err
+		}
+		if count > numObserved {
+			return nil, fmt.Errorf("invalid transaction count %d", count)
+		}
+		txs := make([]*observedTransaction, count)
+		for i := range txs {
+			var index uint32
+			if err := readElements(r, &index); err != nil {
+				return nil, err
+			}
+			if index >= numObserved {
+				return nil, fmt.Errorf("invalid transaction reference %d", index)
+			}
+			txs[i] = observed[index]
+		}
+		return txs, nil
+	}
+
+	for i := range ef.bin {
+		if ef.bin[i], err = readTxList(); err != nil {
+			return nil, err
+		}
+	}
+
+	var numDropped uint32
+	if err := readElements(r, &numDropped); err != nil {
+		return nil, err
+	}
+	if numDropped > ef.maxRollback {
+		return nil, fmt.Errorf("invalid dropped block count %d", numDropped)
+	}
+	ef.dropped = make([]*registeredBlock, numDropped, ef.maxRollback)
+	for i := range ef.dropped {
+		rb := &registeredBlock{}
+		if err := readElements(r, &rb.hash); err != nil {
+			return nil, err
+		}
+		if rb.transactions, err = readTxList(); err != nil {
+			return nil, err
+		}
+		ef.dropped[i] = rb
+	}
+
+	return ef, nil
+}
+
+func readElements(r io.Reader, elements ...interface{}) error {
+	for _, element := range elements {
+		if err := binary.Read(r, binary.BigEndian, element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
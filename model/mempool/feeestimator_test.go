@@ -0,0 +1,192 @@
+package mempool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/util"
+)
+
+// estimatorTestTx returns a mempool entry paying feePerByte, which entered
+// the mempool at height.
+func estimatorTestTx(index uint32, feePerByte int64, height int32) *TxEntry {
+	txn := tx.NewTx(0, tx.DefaultVersion)
+	txn.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{3}, index), script.NewEmptyScript(), 0xffffffff))
+	return &TxEntry{Tx: txn, TxSize: 1000, TxFee: feePerByte * 1000, TxHeight: height}
+}
+
+func estimatorTestBlock(entries ...*TxEntry) *block.Block {
+	blk := block.NewBlock()
+	blk.Header.Nonce = uint32(len(entries))
+	for _, entry := range entries {
+		blk.Txs = append(blk.Txs, entry.Tx)
+	}
+	return blk
+}
+
+func TestFeeEstimatorEstimates(t *testing.T) {
+	ef := NewFeeEstimator(DefaultEstimateFeeMaxRollback, DefaultEstimateFeeMinRegisteredBlocks)
+
+	// transactions are ignored until a first block is seen
+	ef.ObserveTransaction(estimatorTestTx(0, 100, 0))
+	if len(ef.observed) != 0 {
+		t.Fatalf("transaction observed before any block")
+	}
+
+	for height := int32(1); height <= 2; height++ {
+		if err := ef.RegisterBlock(estimatorTestBlock(), height); err != nil {
+			t.Fatalf("RegisterBlock(%d) failed: %v", height, err)
+		}
+	}
+	if _, err := ef.EstimateFee(1); err != ErrNotEnoughBlocks {
+		t.Errorf("EstimateFee with too few blocks: got %v, want %v", err, ErrNotEnoughBlocks)
+	}
+
+	fast := []*TxEntry{estimatorTestTx(1, 10, 2), estimatorTestTx(2, 20, 2), estimatorTestTx(3, 30, 2)}
+	slow := []*TxEntry{estimatorTestTx(4, 1, 2), estimatorTestTx(5, 2, 2)}
+	for _, entry := range append(fast, slow...) {
+		ef.ObserveTransaction(entry)
+	}
+	if err := ef.RegisterBlock(estimatorTestBlock(fast...), 3); err != nil {
+		t.Fatalf("RegisterBlock(3) failed: %v", err)
+	}
+
+	feeRate, err := ef.EstimateFee(1)
+	if err != nil || feeRate.SataoshisPerK != 20000 {
+		t.Errorf("EstimateFee(1): got %v %v, want 20000", feeRate, err)
+	}
+	if _, err := ef.EstimateFee(0); err == nil {
+		t.Errorf("EstimateFee(0) succeeded")
+	}
+	if _, err := ef.EstimateFee(EstimateFeeDepth + 1); err == nil {
+		t.Errorf("EstimateFee(%d) succeeded", EstimateFeeDepth+1)
+	}
+
+	// a skipped block is an error
+	if err := ef.RegisterBlock(estimatorTestBlock(), 5); err == nil {
+		t.Errorf("RegisterBlock with a gap succeeded")
+	}
+
+	// the slow transactions confirm two blocks later
+	if err := ef.RegisterBlock(estimatorTestBlock(), 4); err != nil {
+		t.Fatalf("RegisterBlock(4) failed: %v", err)
+	}
+	if err := ef.RegisterBlock(estimatorTestBlock(slow...), 5); err != nil {
+		t.Fatalf("RegisterBlock(5) failed: %v", err)
+	}
+	feeRate, err = ef.EstimateFee(3)
+	if err != nil || feeRate.SataoshisPerK != 2000 {
+		t.Errorf("EstimateFee(3): got %v %v, want 2000", feeRate, err)
+	}
+	feeRate, blocks, err := ef.EstimateSmartFee(1)
+	if err != nil || blocks != 1 || feeRate.SataoshisPerK != 20000 {
+		t.Errorf("EstimateSmartFee(1): got %v %d %v, want 20000 in 1 block", feeRate, blocks, err)
+	}
+	_, blocks, err = ef.EstimateSmartFee(EstimateFeeDepth + 10)
+	if err != nil || blocks != EstimateFeeDepth {
+		t.Errorf("EstimateSmartFee above depth: got target %d %v, want %d", blocks, err, EstimateFeeDepth)
+	}
+}
+
+func TestFeeEstimatorNoData(t *testing.T) {
+	ef := NewFeeEstimator(DefaultEstimateFeeMaxRollback, 1)
+	if err := ef.RegisterBlock(estimatorTestBlock(), 1); err != nil {
+		t.Fatalf("RegisterBlock failed: %v", err)
+	}
+	feeRate, blocks, err := ef.EstimateSmartFee(2)
+	if err != nil || blocks != 0 || feeRate.SataoshisPerK != 0 {
+		t.Errorf("EstimateSmartFee without data: got %v %d %v, want no estimate", feeRate, blocks, err)
+	}
+}
+
+func TestFeeEstimatorRollback(t *testing.T) {
+	ef := NewFeeEstimator(DefaultEstimateFeeMaxRollback, 1)
+	if err := ef.RegisterBlock(estimatorTestBlock(), 10); err != nil {
+		t.Fatalf("RegisterBlock failed: %v", err)
+	}
+	entries := []*TxEntry{estimatorTestTx(1, 10, 10), estimatorTestTx(2, 20, 10)}
+	for _, entry := range entries {
+		ef.ObserveTransaction(entry)
+	}
+	blk := estimatorTestBlock(entries...)
+	if err := ef.RegisterBlock(blk, 11); err != nil {
+		t.Fatalf("RegisterBlock failed: %v", err)
+	}
+	if feeRate, _ := ef.EstimateFee(1); feeRate.SataoshisPerK == 0 {
+		t.Fatalf("no estimate after confirmation")
+	}
+
+	hash := blk.GetHash()
+	if err := ef.Rollback(&hash); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if ef.LastKnownHeight() != 10 {
+		t.Errorf("LastKnownHeight after rollback: got %d, want 10", ef.LastKnownHeight())
+	}
+	if feeRate, _ := ef.EstimateFee(1); feeRate.SataoshisPerK != 0 {
+		t.Errorf("estimate left after rollback: %v", feeRate)
+	}
+	if err := ef.Rollback(&hash); err == nil {
+		t.Errorf("second Rollback succeeded")
+	}
+
+	// the transactions can be mined again in the replacing block
+	if err := ef.RegisterBlock(estimatorTestBlock(entries[0]), 11); err != nil {
+		t.Fatalf("RegisterBlock after rollback failed: %v", err)
+	}
+	if feeRate, _ := ef.EstimateFee(1); feeRate.SataoshisPerK != 10000 {
+		t.Errorf("EstimateFee(1) after reorg: got %v, want 10000", feeRate)
+	}
+}
+
+func TestFeeEstimatorSaveRestore(t *testing.T) {
+	ef := NewFeeEstimator(DefaultEstimateFeeMaxRollback, 1)
+	ef.RegisterBlock(estimatorTestBlock(), 1)
+	var entries []*TxEntry
+	for i := 0; i < 20; i++ {
+		entry := estimatorTestTx(uint32(i), int64(i+1), 1)
+		ef.ObserveTransaction(entry)
+		entries = append(entries, entry)
+	}
+	ef.RegisterBlock(estimatorTestBlock(entries[:10]...), 2)
+	ef.RegisterBlock(estimatorTestBlock(entries[10:15]...), 3)
+
+	var buf bytes.Buffer
+	if err := ef.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	saved := buf.Bytes()
+
+	restored, err := RestoreFeeEstimator(bytes.NewReader(saved))
+	if err != nil {
+		t.Fatalf("RestoreFeeEstimator failed: %v", err)
+	}
+	var buf2 bytes.Buffer
+	if err := restored.Save(&buf2); err != nil {
+		t.Fatalf("Save of restored estimator failed: %v", err)
+	}
+	if !bytes.Equal(saved, buf2.Bytes()) {
+		t.Errorf("restored estimator differs from the saved one")
+	}
+
+	want, _ := ef.EstimateFee(2)
+	got, err := restored.EstimateFee(2)
+	if err != nil || got != want {
+		t.Errorf("restored EstimateFee(2): got %v %v, want %v", got, err, want)
+	}
+
+	// the restored estimator can still roll back the last block
+	hash := estimatorTestBlock(entries[10:15]...).GetHash()
+	if err := restored.Rollback(&hash); err != nil {
+		t.Errorf("Rollback of restored estimator failed: %v", err)
+	}
+
+	if _, err := RestoreFeeEstimator(bytes.NewReader(saved[:len(saved)-3])); err == nil {
+		t.Errorf("RestoreFeeEstimator of truncated data succeeded")
+	}
+}
@@ -24,13 +24,13 @@ func TestTxentry(t *testing.T) {
 	tx1.AddTxOut(txout.NewTxOut(amount.Amount(10*util.COIN), script.NewScriptRaw([]byte{opcodes.OP_11, opcodes.OP_EQUAL})))
 
 	txentry := &TxEntry{
-		Tx:         tx1,
-		TxSize:     120,
-		TxFee:      1000,
-		TxHeight:   10000,
-		SigOpCount: 1,
-		time:       1540177584,
-		usageSize:  10,
+		Tx:        tx1,
+		TxSize:    120,
+		TxFee:     1000,
+		TxHeight:  10000,
+		SigChecks: 1,
+		time:      1540177584,
+		usageSize: 10,
 		lp: struct {
 			Height        int32
 			Time          int64
@@ -38,10 +38,14 @@ func TestTxentry(t *testing.T) {
 		}{Height: 10, Time: 1540177584, MaxInputBlock: nil},
 		spendsCoinbase: true,
 	}
-	txentry.SumTxSigOpCountWithAncestors = 10
+	txentry.SumTxSigOpCountWithAncestors = 5
+	txentry.SumTxSigChecksWithAncestors = 10
 
 	sigOpCount := txentry.GetSigOpCountWithAncestors()
-	assert.Equal(t, sigOpCount, int64(10))
+	assert.Equal(t, sigOpCount, int64(5))
+
+	sigChecks := txentry.GetSigChecksWithAncestors()
+	assert.Equal(t, sigChecks, int64(10))
 
 	usageSize := txentry.GetUsageSize()
 	assert.Equal(t, usageSize, int64(10))
@@ -239,6 +239,14 @@ func (m *TxMempool) Size() int {
 	return len(m.poolData)
 }
 
+// OrphanSize returns the number of orphan transactions.
+func (m *TxMempool) OrphanSize() int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return len(m.OrphanTransactions)
+}
+
 func (m *TxMempool) GetAllTxEntry() map[util.Hash]*TxEntry {
 	m.RLock()
 	ret := make(map[util.Hash]*TxEntry, len(m.poolData))
@@ -472,6 +480,7 @@ func (m *TxMempool) updateForRemoveFromMempool(entriesToRemove map[*TxEntry]stru
 			modifySize := -removeIt.TxSize
 			modifyFee := -removeIt.TxFee
 			modifySigOps := -removeIt.SigOpCount
+			modifySigChecks := -removeIt.SigChecks
 
 			for dit := range setDescendants {
 				// Google's btree library use binary search and Less() to find item.However we want to do
@@ -479,7 +488,7 @@ func (m *TxMempool) updateForRemoveFromMempool(entriesToRemove map[*TxEntry]stru
 				// its key also change which looks like dead lock:(.So temporarily use delete and insert to instead.
 				m.timeSortData.Delete(dit)
 				m.txByAncestorFeeRateSort.Delete((*EntryAncestorFeeRateSort)(dit))
-				dit.UpdateAncestorState(-1, modifySize, modifySigOps, modifyFee)
+				dit.UpdateAncestorState(-1, modifySize, modifySigOps, modifySigChecks, modifyFee)
 				m.timeSortData.ReplaceOrInsert(dit)
 				m.txByAncestorFeeRateSort.ReplaceOrInsert((*EntryAncestorFeeRateSort)(dit))
 			}
@@ -592,13 +601,15 @@ func (m *TxMempool) updateEntryForAncestors(entry *TxEntry, setAncestors map[*Tx
 	updateSize := 0
 	updateFee := int64(0)
 	updateSigOpsCount := 0
+	updateSigChecks := 0
 
 	for ancestorIt := range setAncestors {
 		updateFee += ancestorIt.TxFee
 		updateSigOpsCount += ancestorIt.SigOpCount
+		updateSigChecks += ancestorIt.SigChecks
 		updateSize += ancestorIt.TxSize
 	}
-	entry.UpdateAncestorState(updateCount, updateSize, updateSigOpsCount, updateFee)
+	entry.UpdateAncestorState(updateCount, updateSize, updateSigOpsCount, updateSigChecks, updateFee)
 }
 
 // CalculateMemPoolAncestors get tx all ancestors transaction in mempool.
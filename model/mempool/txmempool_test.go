@@ -70,7 +70,7 @@ func (t *TestMemPoolEntry) FromTxToEntry(tx *tx.Tx) *TxEntry {
 	if t.lp != nil {
 		lp = *(t.lp)
 	}
-	entry := NewTxentry(tx, int64(t.Fee), t.Time, t.Height, lp, int(t.SigOpCost), t.SpendsCoinbase)
+	entry := NewTxentry(tx, int64(t.Fee), t.Time, t.Height, lp, int(t.SigOpCost), int(t.SigOpCost), t.SpendsCoinbase)
 	return entry
 }
 
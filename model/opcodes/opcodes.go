@@ -139,6 +139,33 @@ const (
 	OP_CHECKDATASIG       = 0xba
 	OP_CHECKDATASIGVERIFY = 0xbb
 
+	// additional byte string operations
+	OP_REVERSEBYTES = 0xbc
+
+	// native introspection
+	OP_INPUTINDEX          = 0xc0
+	OP_ACTIVEBYTECODE      = 0xc1
+	OP_TXVERSION           = 0xc2
+	OP_TXINPUTCOUNT        = 0xc3
+	OP_TXOUTPUTCOUNT       = 0xc4
+	OP_TXLOCKTIME          = 0xc5
+	OP_UTXOVALUE           = 0xc6
+	OP_UTXOBYTECODE        = 0xc7
+	OP_OUTPOINTTXHASH      = 0xc8
+	OP_OUTPOINTINDEX       = 0xc9
+	OP_INPUTBYTECODE       = 0xca
+	OP_INPUTSEQUENCENUMBER = 0xcb
+	OP_OUTPUTVALUE         = 0xcc
+	OP_OUTPUTBYTECODE      = 0xcd
+
+	// token introspection
+	OP_UTXOTOKENCATEGORY     = 0xce
+	OP_UTXOTOKENCOMMITMENT   = 0xcf
+	OP_UTXOTOKENAMOUNT       = 0xd0
+	OP_OUTPUTTOKENCATEGORY   = 0xd1
+	OP_OUTPUTTOKENCOMMITMENT = 0xd2
+	OP_OUTPUTTOKENAMOUNT     = 0xd3
+
 	// The first op_code value after all defined opcodes
 	FIRST_UNDEFINED_OP_VALUE
 
@@ -399,6 +426,50 @@ func GetOpName(opcode int) string {
 	case OP_CHECKDATASIGVERIFY:
 		return "OP_CHECKDATASIGVERIFY"
 
+	case OP_REVERSEBYTES:
+		return "OP_REVERSEBYTES"
+
+	case OP_INPUTINDEX:
+		return "OP_INPUTINDEX"
+	case OP_ACTIVEBYTECODE:
+		return "OP_ACTIVEBYTECODE"
+	case OP_TXVERSION:
+		return "OP_TXVERSION"
+	case OP_TXINPUTCOUNT:
+		return "OP_TXINPUTCOUNT"
+	case OP_TXOUTPUTCOUNT:
+		return "OP_TXOUTPUTCOUNT"
+	case OP_TXLOCKTIME:
+		return "OP_TXLOCKTIME"
+	case OP_UTXOVALUE:
+		return "OP_UTXOVALUE"
+	case OP_UTXOBYTECODE:
+		return "OP_UTXOBYTECODE"
+	case OP_OUTPOINTTXHASH:
+		return "OP_OUTPOINTTXHASH"
+	case OP_OUTPOINTINDEX:
+		return "OP_OUTPOINTINDEX"
+	case OP_INPUTBYTECODE:
+		return "OP_INPUTBYTECODE"
+	case OP_INPUTSEQUENCENUMBER:
+		return "OP_INPUTSEQUENCENUMBER"
+	case OP_OUTPUTVALUE:
+		return "OP_OUTPUTVALUE"
+	case OP_OUTPUTBYTECODE:
+		return "OP_OUTPUTBYTECODE"
+	case OP_UTXOTOKENCATEGORY:
+		return "OP_UTXOTOKENCATEGORY"
+	case OP_UTXOTOKENCOMMITMENT:
+		return "OP_UTXOTOKENCOMMITMENT"
+	case OP_UTXOTOKENAMOUNT:
+		return "OP_UTXOTOKENAMOUNT"
+	case OP_OUTPUTTOKENCATEGORY:
+		return "OP_OUTPUTTOKENCATEGORY"
+	case OP_OUTPUTTOKENCOMMITMENT:
+		return "OP_OUTPUTTOKENCOMMITMENT"
+	case OP_OUTPUTTOKENAMOUNT:
+		return "OP_OUTPUTTOKENAMOUNT"
+
 		// Note:
 		//  The template matching params OP_SMALLINTEGER/etc are defined in opcodetype enum
 		//  as kind of implementation hack, they are *NOT* real opcodes.  If found in real
@@ -0,0 +1,140 @@
+package pow
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+)
+
+// asertAnchorLock guards the ASERTAnchor of the block indexes, which blocks
+// validated and templates mined concurrently may both look up.
+var asertAnchorLock sync.Mutex
+
+// getASERTAnchorBlock returns the first block whose median time past activates
+// axion, as seen from indexPrev. The block before it is the last one built
+// with the cw-144 DAA, and its timestamp is the reference time of aserti3-2d.
+//
+// The anchor is remembered on indexPrev, so that looking it up from the next
+// block only takes one step back.
+func getASERTAnchorBlock(indexPrev *blockindex.BlockIndex) *blockindex.BlockIndex {
+	if indexPrev == nil {
+		panic("the ASERT anchor block cannot be looked up from a nil block index")
+	}
+
+	asertAnchorLock.Lock()
+	defer asertAnchorLock.Unlock()
+
+	// Jump back with the skip list while the upgrade is still active, then
+	// finish walking back one block at a time. Any block on the way shares
+	// the anchor of indexPrev, so stop at the first one that knows it.
+	anchor := indexPrev
+	for anchor.ASERTAnchor == nil && anchor.Prev != nil {
+		if anchor.Skip != nil && model.IsAxionEnabled(anchor.Skip.GetMedianTimePast()) {
+			anchor = anchor.Skip
+			continue
+		}
+		if !model.IsAxionEnabled(anchor.Prev.GetMedianTimePast()) {
+			break
+		}
+		anchor = anchor.Prev
+	}
+	if anchor.ASERTAnchor != nil {
+		anchor = anchor.ASERTAnchor
+	}
+
+	indexPrev.ASERTAnchor = anchor
+	return anchor
+}
+
+// getNextASERTWorkRequired Compute the next required proof of work using the
+// absolutely scheduled exponentially weighted target (aserti3-2d).
+//
+// The target only depends on the anchor block and on the time and height
+// elapsed since, so every block can be checked in constant time and errors
+// do not accumulate along the chain.
+func (pow *Pow) getNextASERTWorkRequired(indexPrev *blockindex.BlockIndex, blHeader *block.BlockHeader,
+	params *model.BitcoinParams) uint32 {
+	if indexPrev == nil {
+		panic("This cannot handle the genesis block and early blocks in general.")
+	}
+
+	// Special difficulty rule for testnet:
+	// If the new block's timestamp is more than 2* 10 minutes then allow
+	// mining of a min-difficulty block.
+	if params.FPowAllowMinDifficultyBlocks && (blHeader.Time > indexPrev.GetBlockTime()+uint32(2*params.TargetTimePerBlock)) {
+		return BigToCompact(params.PowLimit)
+	}
+
+	anchor := getASERTAnchorBlock(indexPrev)
+
+	// The reference time is the timestamp of the anchor's parent, so that the
+	// anchor itself is expected one target spacing after it.
+	anchorTime := int64(anchor.GetBlockTime())
+	if anchor.Prev != nil {
+		anchorTime = int64(anchor.Prev.GetBlockTime())
+	}
+
+	timeDiff := int64(indexPrev.GetBlockTime()) - anchorTime
+	heightDiff := int64(indexPrev.Height - anchor.Height)
+	refTarget := CompactToBig(anchor.Header.Bits)
+
+	nextTarget := calculateASERT(refTarget, int64(params.TargetTimePerBlock), timeDiff, heightDiff,
+		params.PowLimit, params.ASERTHalfLife)
+
+	return BigToCompact(nextTarget)
+}
+
+// calculateASERT returns refTarget scaled by 2^((timeDiff - spacing*(heightDiff+1)) / halfLife).
+//
+// The exponent is computed in 16.16 fixed point and 2^frac is approximated by
+// a cubic polynomial, so the result is integer exact on every platform. It is
+// clamped to [1, powLimit].
+func calculateASERT(refTarget *big.Int, targetSpacing int64, timeDiff int64, heightDiff int64,
+	powLimit *big.Int, halfLife int64) *big.Int {
+	if refTarget.Sign() <= 0 || refTarget.Cmp(powLimit) > 0 {
+		panic("the ASERT reference target should be in (0, powLimit]")
+	}
+	if heightDiff < 0 {
+		panic("the ASERT height difference should not be negative")
+	}
+
+	// Go integer division truncates toward zero, as the reference
+	// implementation does.
+	exponent := ((timeDiff - targetSpacing*(heightDiff+1)) * 65536) / halfLife
+
+	// Arithmetic shift, rounding toward negative infinity, so that frac is
+	// always the positive remainder.
+	shifts := exponent >> 16
+	frac := uint64(uint16(exponent))
+
+	// 2^x ~= 1 + 0.695502049*x + 0.2262698*x^2 + 0.0782318*x^3 for 0 <= x < 1,
+	// with x = frac / 65536.
+	factor := 65536 + ((195766423245049*frac + 971821376*frac*frac + 5127*frac*frac*frac + (1 << 47)) >> 48)
+
+	nextTarget := new(big.Int).Mul(refTarget, new(big.Int).SetUint64(factor))
+
+	// Remove the 16 bits of precision of the factor.
+	shifts -= 16
+	if shifts <= 0 {
+		nextTarget.Rsh(nextTarget, uint(-shifts))
+	} else {
+		// Anything shifted this far is above any proof of work limit.
+		if shifts > 256 {
+			return new(big.Int).Set(powLimit)
+		}
+		nextTarget.Lsh(nextTarget, uint(shifts))
+	}
+
+	if nextTarget.Sign() == 0 {
+		// The target can never be 0, otherwise no block would be valid.
+		return big.NewInt(1)
+	}
+	if nextTarget.Cmp(powLimit) > 0 {
+		return new(big.Int).Set(powLimit)
+	}
+
+	return nextTarget
+}
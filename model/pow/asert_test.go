@@ -0,0 +1,254 @@
+package pow
+
+import (
+	"bufio"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+)
+
+func TestMain(m *testing.M) {
+	conf.Cfg = conf.InitConfig([]string{})
+	os.Exit(m.Run())
+}
+
+func hexToBig(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex number " + s)
+	}
+	return n
+}
+
+func TestCalculateASERT(t *testing.T) {
+	powLimit := model.MainNetParams.PowLimit
+	halfLife := model.MainNetParams.ASERTHalfLife
+	initialTarget := new(big.Int).Rsh(powLimit, 4)
+
+	// The time difference is measured from the parent of the anchor block,
+	// which is ideally one target spacing before it.
+	const parentTimeDiff = 600
+
+	tests := []struct {
+		name       string
+		refTarget  *big.Int
+		timeDiff   int64
+		heightDiff int64
+		expect     *big.Int
+	}{
+		{"on schedule", initialTarget, parentTimeDiff + 600, 1, initialTarget},
+		{"far on schedule", initialTarget, parentTimeDiff + 600*1000, 1000, initialTarget},
+		{"half life behind", initialTarget, parentTimeDiff + 288*1200, 288, new(big.Int).Lsh(initialTarget, 1)},
+		{"half life ahead", initialTarget, parentTimeDiff, 288, new(big.Int).Rsh(initialTarget, 1)},
+		{"two half lives behind", initialTarget, parentTimeDiff + 288*1800, 288, new(big.Int).Lsh(initialTarget, 2)},
+		{"one hour behind", initialTarget, 600*101 + 3600, 100,
+			hexToBig("103bbffffffffffffffffffffffffffffffffffffffffffffffffffe")},
+		{"one hour ahead", initialTarget, 600*101 - 3600, 100,
+			hexToBig("fc56fffffffffffffffffffffffffffffffffffffffffffffffffff")},
+		{"one day behind", initialTarget, 600*101 + 86400, 100,
+			hexToBig("16a01ffffffffffffffffffffffffffffffffffffffffffffffffffe")},
+		{"clamped to pow limit", powLimit, parentTimeDiff + 300, 0, powLimit},
+		{"clamped to pow limit from 1", big.NewInt(1), 600 * 2 * 256 * 144, 0, powLimit},
+		{"lowest target", powLimit, 0, 2 * 225 * 144, big.NewInt(1)},
+		{"never below 1", big.NewInt(1), 0, 2 * (256 - 32) * 144, big.NewInt(1)},
+	}
+
+	for _, test := range tests {
+		actual := calculateASERT(test.refTarget, 600, test.timeDiff, test.heightDiff, powLimit, halfLife)
+		if actual.Cmp(test.expect) != 0 {
+			t.Errorf("%s: expect target 0x%x, actual 0x%x", test.name, test.expect, actual)
+		}
+	}
+}
+
+// asertRun is a run of aserti3-2d test vectors: the anchor block, and the
+// bits expected after blocks at given heights and times.
+type asertRun struct {
+	anchorHeight     int64
+	anchorParentTime int64
+	anchorBits       uint32
+	blocks           []asertRunBlock
+}
+
+type asertRunBlock struct {
+	height int64
+	time   int64
+	bits   uint32
+}
+
+func readASERTRun(name string) (*asertRun, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	run := &asertRun{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "##") {
+			fields := strings.SplitN(strings.TrimPrefix(line, "##"), ":", 2)
+			if len(fields) != 2 {
+				continue
+			}
+			value := strings.TrimSpace(fields[1])
+			switch strings.TrimSpace(fields[0]) {
+			case "anchor height":
+				run.anchorHeight, err = strconv.ParseInt(value, 10, 64)
+			case "anchor parent time":
+				run.anchorParentTime, err = strconv.ParseInt(value, 10, 64)
+			case "anchor nBits":
+				var bits uint64
+				bits, err = strconv.ParseUint(value, 0, 32)
+				run.anchorBits = uint32(bits)
+			}
+		} else if line != "" && !strings.HasPrefix(line, "#") {
+			var iteration int
+			var block asertRunBlock
+			_, err = fmt.Sscanf(line, "%d %d %d 0x%x", &iteration, &block.height, &block.time, &block.bits)
+			run.blocks = append(run.blocks, block)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q: %v", name, line, err)
+		}
+	}
+	return run, scanner.Err()
+}
+
+// TestCalculateASERTRuns checks the runs of testdata/asert, written in the
+// format of the aserti3-2d reference test vectors.
+func TestCalculateASERTRuns(t *testing.T) {
+	params := &model.MainNetParams
+	names, err := filepath.Glob(filepath.Join("testdata", "asert", "run*"))
+	if err != nil || len(names) == 0 {
+		t.Fatalf("no aserti3-2d test vectors found: %v", err)
+	}
+
+	for _, name := range names {
+		run, err := readASERTRun(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refTarget := CompactToBig(run.anchorBits)
+		for _, block := range run.blocks {
+			target := calculateASERT(refTarget, int64(params.TargetTimePerBlock), block.time-run.anchorParentTime,
+				block.height-run.anchorHeight, params.PowLimit, params.ASERTHalfLife)
+			if bits := BigToCompact(target); bits != block.bits {
+				t.Errorf("%s: height %d time %d: expect bits 0x%08x, actual 0x%08x", name, block.height,
+					block.time, block.bits, bits)
+			}
+		}
+	}
+}
+
+func newASERTTestChain(prev *blockindex.BlockIndex, count int, timeInterval int64,
+	bits uint32) []*blockindex.BlockIndex {
+	blocks := make([]*blockindex.BlockIndex, count)
+	for i := range blocks {
+		blocks[i] = getBlockIndex(prev, timeInterval, bits)
+		blocks[i].BuildSkip()
+		prev = blocks[i]
+	}
+	return blocks
+}
+
+func TestPowGetNextASERTWorkRequired(t *testing.T) {
+	model.ActiveNetParams = &model.MainNetParams
+
+	initialTarget := new(big.Int).Rsh(model.ActiveNetParams.PowLimit, 4)
+	initialBits := BigToCompact(initialTarget)
+	activation := model.ActiveNetParams.AxionActivationTime
+
+	genesis := new(blockindex.BlockIndex)
+	genesis.SetNull()
+	genesis.Header.Time = uint32(activation - 600*100)
+	genesis.Header.Bits = initialBits
+	genesis.ChainWork = *GetBlockProof(genesis)
+
+	// blocks[i] is at height i+1, one block every 10 minutes. The median time
+	// past reaches the activation at height 105.
+	blocks := newASERTTestChain(genesis, 200, 600, initialBits)
+	anchor := blocks[104]
+	if model.IsAxionEnabled(anchor.Prev.GetMedianTimePast()) || !model.IsAxionEnabled(anchor.GetMedianTimePast()) {
+		t.Fatalf("the block at height %d should be the first one to activate axion", anchor.Height)
+	}
+
+	if actual := getASERTAnchorBlock(blocks[199]); actual != anchor {
+		t.Errorf("expect anchor at height %d, actual %d", anchor.Height, actual.Height)
+	}
+	if blocks[199].ASERTAnchor != anchor {
+		t.Errorf("the anchor should be remembered on the block it was looked up from")
+	}
+	if actual := getASERTAnchorBlock(anchor); actual != anchor {
+		t.Errorf("expect anchor at height %d, actual %d", anchor.Height, actual.Height)
+	}
+
+	pow := Pow{}
+	blkHeaderDummy := block.BlockHeader{}
+
+	// Blocks on schedule keep the target of the anchor block.
+	if bits := pow.GetNextWorkRequired(blocks[199], &blkHeaderDummy, model.ActiveNetParams); bits != initialBits {
+		t.Errorf("expect bits 0x%x, actual 0x%x", initialBits, bits)
+	}
+
+	// One half-life behind the schedule doubles the target.
+	late := getBlockIndex(blocks[199], 600+model.ActiveNetParams.ASERTHalfLife, initialBits)
+	expect := BigToCompact(new(big.Int).Lsh(CompactToBig(initialBits), 1))
+	if bits := pow.GetNextWorkRequired(late, &blkHeaderDummy, model.ActiveNetParams); bits != expect {
+		t.Errorf("expect bits 0x%x, actual 0x%x", expect, bits)
+	}
+
+	// A fork after the anchor shares it.
+	fork := newASERTTestChain(blocks[119], 10, 300, initialBits)
+	if actual := getASERTAnchorBlock(fork[9]); actual != anchor {
+		t.Errorf("expect anchor at height %d, actual %d", anchor.Height, actual.Height)
+	}
+
+	// A fork before the anchor activates at another block, the anchor of the
+	// other branch must not be reused.
+	fork = newASERTTestChain(blocks[97], 20, 3000, initialBits)
+	if actual := getASERTAnchorBlock(fork[19]); actual != fork[5] {
+		t.Errorf("expect anchor at height %d, actual %d", fork[5].Height, actual.Height)
+	}
+
+	// Before the activation the cw-144 DAA is still in use.
+	if model.IsAxionEnabled(blocks[102].GetMedianTimePast()) {
+		t.Errorf("axion should not be enabled at height %d", blocks[102].Height)
+	}
+}
+
+func TestPowGetNextASERTWorkRequiredMinDifficulty(t *testing.T) {
+	model.ActiveNetParams = &model.TestNetParams
+	defer func() { model.ActiveNetParams = &model.MainNetParams }()
+
+	initialBits := BigToCompact(new(big.Int).Rsh(model.ActiveNetParams.PowLimit, 4))
+
+	genesis := new(blockindex.BlockIndex)
+	genesis.SetNull()
+	genesis.Header.Time = uint32(model.ActiveNetParams.AxionActivationTime - 600*10)
+	genesis.Header.Bits = initialBits
+	genesis.ChainWork = *GetBlockProof(genesis)
+	blocks := newASERTTestChain(genesis, 40, 600, initialBits)
+
+	pow := Pow{}
+	header := block.BlockHeader{Time: blocks[39].GetBlockTime() + 2*600}
+	if bits := pow.GetNextWorkRequired(blocks[39], &header, model.ActiveNetParams); bits != initialBits {
+		t.Errorf("expect bits 0x%x, actual 0x%x", initialBits, bits)
+	}
+
+	header.Time++
+	powLimitBits := BigToCompact(model.ActiveNetParams.PowLimit)
+	if bits := pow.GetNextWorkRequired(blocks[39], &header, model.ActiveNetParams); bits != powLimitBits {
+		t.Errorf("expect bits 0x%x, actual 0x%x", powLimitBits, bits)
+	}
+}
@@ -23,6 +23,10 @@ func (pow *Pow) GetNextWorkRequired(indexPrev *blockindex.BlockIndex, blHeader *
 		return indexPrev.Header.Bits
 	}
 
+	if model.IsAxionEnabled(indexPrev.GetMedianTimePast()) {
+		return pow.getNextASERTWorkRequired(indexPrev, blHeader, params)
+	}
+
 	if model.IsDAAEnabled(indexPrev.Height) {
 		return pow.getNextCashWorkRequired(indexPrev, blHeader, params)
 	}
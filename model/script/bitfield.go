@@ -0,0 +1,39 @@
+package script
+
+import (
+	"github.com/copernet/copernicus/errcode"
+)
+
+// DecodeBitfield decodes the little endian bitfield vch, which must select
+// elements out of size ones, as the dummy element of a Schnorr multisig does
+// with public keys.
+func DecodeBitfield(vch []byte, size int) (uint32, error) {
+	if size > 32 {
+		return 0, errcode.New(errcode.ScriptErrInvalidBitfieldSize)
+	}
+
+	if len(vch) != (size+7)/8 {
+		return 0, errcode.New(errcode.ScriptErrInvalidBitfieldSize)
+	}
+
+	var bitfield uint32
+	for i, b := range vch {
+		bitfield |= uint32(b) << (8 * uint(i))
+	}
+
+	mask := uint32((uint64(1) << uint(size)) - 1)
+	if bitfield&mask != bitfield {
+		return 0, errcode.New(errcode.ScriptErrInvalidBitRange)
+	}
+
+	return bitfield, nil
+}
+
+// CountBits returns the number of bits set in v.
+func CountBits(v uint32) int {
+	count := 0
+	for ; v != 0; v &= v - 1 {
+		count++
+	}
+	return count
+}
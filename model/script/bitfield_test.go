@@ -0,0 +1,60 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/errcode"
+)
+
+func TestDecodeBitfield(t *testing.T) {
+	tests := []struct {
+		vch      string
+		size     int
+		bitfield uint32
+		err      errcode.ScriptErr
+	}{
+		{"", 0, 0, errcode.ScriptErrOK},
+		{"00", 0, 0, errcode.ScriptErrInvalidBitfieldSize},
+		{"", 1, 0, errcode.ScriptErrInvalidBitfieldSize},
+		{"01", 1, 0x01, errcode.ScriptErrOK},
+		{"02", 1, 0, errcode.ScriptErrInvalidBitRange},
+		{"ff", 8, 0xff, errcode.ScriptErrOK},
+		{"ff", 9, 0, errcode.ScriptErrInvalidBitfieldSize},
+		{"ff01", 9, 0x1ff, errcode.ScriptErrOK},
+		{"ff03", 9, 0, errcode.ScriptErrInvalidBitRange},
+		{"ffff0f", 20, 0x0fffff, errcode.ScriptErrOK},
+		{"ffff1f", 20, 0, errcode.ScriptErrInvalidBitRange},
+		{"ffffffff", 32, 0xffffffff, errcode.ScriptErrOK},
+		{"ffffffff00", 33, 0, errcode.ScriptErrInvalidBitfieldSize},
+	}
+
+	for i, test := range tests {
+		bitfield, err := DecodeBitfield(hexToBytes(test.vch), test.size)
+		if test.err == errcode.ScriptErrOK {
+			if err != nil || bitfield != test.bitfield {
+				t.Errorf("test %d: got %x, %v, want %x", i, bitfield, err, test.bitfield)
+			}
+		} else if !errcode.IsErrorCode(err, test.err) {
+			t.Errorf("test %d: got %v, want %v", i, err, test.err)
+		}
+	}
+}
+
+func TestCountBits(t *testing.T) {
+	tests := []struct {
+		v     uint32
+		count int
+	}{
+		{0, 0},
+		{1, 1},
+		{0x80000000, 1},
+		{0x0f0f, 8},
+		{0xffffffff, 32},
+	}
+
+	for _, test := range tests {
+		if count := CountBits(test.v); count != test.count {
+			t.Errorf("CountBits(%x) = %d, want %d", test.v, count, test.count)
+		}
+	}
+}
@@ -164,6 +164,50 @@ const (
 	//
 	ScriptEnableCheckDataSig = (1 << 18)
 
+	// Are Schnorr signatures enabled for OP_CHECK(DATA)SIG(VERIFY) and
+	// 65 bytes signatures forbidden in OP_CHECKMULTISIG(VERIFY).
+	//
+	ScriptEnableSchnorr = (1 << 19)
+
+	// Allow segwit recovery: a P2SH scriptSig which pushes exactly a
+	// witness program redeem script is exempt from the clean stack rule.
+	//
+	ScriptAllowSegwitRecovery = (1 << 20)
+
+	// Is the dummy element of OP_CHECKMULTISIG(VERIFY) used as a bitfield
+	// selecting the public keys checked against Schnorr signatures, when it
+	// is not null.
+	//
+	ScriptEnableSchnorrMultisig = (1 << 21)
+
+	// Limit the number of signature checks performed by an input relatively
+	// to the size of its scriptSig, see MaxInputSigChecks.
+	//
+	ScriptVerifyInputSigChecks = (1 << 22)
+
+	// Is OP_REVERSEBYTES enabled.
+	//
+	ScriptEnableOpReverseBytes = (1 << 23)
+
+	// Script numbers may be up to 8 bytes long, arithmetic overflowing the
+	// [-2^63 + 1, 2^63 - 1] range fails, and OP_MUL is enabled.
+	//
+	ScriptEnable64BitIntegers = (1 << 24)
+
+	// Are the native introspection opcodes (OP_INPUTINDEX, OP_UTXOVALUE...)
+	// enabled.
+	//
+	ScriptEnableNativeIntrospection = (1 << 25)
+
+	// Do outputs carry tokens, are the token introspection opcodes
+	// (OP_UTXOTOKENCATEGORY...) enabled.
+	//
+	ScriptEnableTokens = (1 << 26)
+
+	// Is OP_HASH256 <32 bytes> OP_EQUAL evaluated as pay to script hash.
+	//
+	ScriptEnableP2SH32 = (1 << 27)
+
 	ScriptMaxOpReturnRelay uint = 223
 )
 
@@ -186,7 +230,7 @@ const (
 	// MandatoryScriptVerifyFlags failing one of these tests may trigger a DoS ban - see CheckInputs() for
 	// details.
 	MandatoryScriptVerifyFlags uint = ScriptVerifyP2SH | ScriptVerifyStrictEnc | ScriptEnableSigHashForkID |
-		ScriptVerifyLowS | ScriptVerifyNullFail
+		ScriptVerifyLowS | ScriptVerifyNullFail | ScriptVerifyMinmalData
 
 	//StandardScriptVerifyFlags standard script verification flags that standard transactions will comply
 	// with. However scripts violating these flags may still be present in valid
@@ -195,7 +239,8 @@ const (
 		ScriptVerifyNullDummy | ScriptVerifySigPushOnly |
 		ScriptVerifyMinmalData | ScriptVerifyDiscourageUpgradableNops |
 		ScriptVerifyCleanStack | ScriptVerifyCheckLockTimeVerify |
-		ScriptVerifyCheckSequenceVerify | ScriptVerifyNullFail
+		ScriptVerifyCheckSequenceVerify | ScriptVerifyNullFail |
+		ScriptVerifyInputSigChecks
 
 	//StandardNotMandatoryVerifyFlags for convenience, standard but not mandatory verify flags.
 	StandardNotMandatoryVerifyFlags uint = StandardScriptVerifyFlags & (^MandatoryScriptVerifyFlags)
@@ -517,8 +562,9 @@ func (s *Script) IsStandardScriptPubKey() (pubKeyType int, pubKeys [][]byte, isS
 
 	// Shortcut for pay-to-script-hash, which are more constrained than the
 	// other types:
-	// it is always OP_HASH160 20 [20 byte hash] OP_EQUAL
-	if s.IsPayToScriptHash() {
+	// it is always OP_HASH160 20 [20 byte hash] OP_EQUAL, or
+	// OP_HASH256 32 [32 byte hash] OP_EQUAL
+	if s.IsPayToScriptHash() || s.IsPayToScriptHash32() {
 		return ScriptHash, [][]byte{s.ParsedOpCodes[1].Data}, true
 	}
 
@@ -643,6 +689,35 @@ func (s *Script) IsPayToScriptHash() bool {
 		s.data[22] == opcodes.OP_EQUAL
 }
 
+// IsPayToScriptHash32 returns whether the script is OP_HASH256 32 [32 byte
+// hash] OP_EQUAL, pay to script hash with a double sha256 of the redeem script.
+func (s *Script) IsPayToScriptHash32() bool {
+	size := len(s.data)
+	return size == 35 &&
+		s.data[0] == opcodes.OP_HASH256 &&
+		s.data[1] == 0x20 &&
+		s.data[34] == opcodes.OP_EQUAL
+}
+
+// IsPayToScriptHashWithFlags returns whether the script is evaluated as pay to
+// script hash under flags, ScriptEnableP2SH32 allowing the 32 bytes form.
+func (s *Script) IsPayToScriptHashWithFlags(flags uint32) bool {
+	return s.IsPayToScriptHash() || (flags&ScriptEnableP2SH32 != 0 && s.IsPayToScriptHash32())
+}
+
+// IsWitnessProgram returns whether the script is a version byte (OP_0 to
+// OP_16) followed by a single 2 to 40 bytes push, as used by segwit.
+func (s *Script) IsWitnessProgram() bool {
+	size := len(s.data)
+	if size < 4 || size > 42 {
+		return false
+	}
+	if s.data[0] != opcodes.OP_0 && (s.data[0] < opcodes.OP_1 || s.data[0] > opcodes.OP_16) {
+		return false
+	}
+	return int(s.data[1])+2 == size
+}
+
 func (s *Script) IsUnspendable() bool {
 	return (s.Size() > 0 && s.data[0] == opcodes.OP_RETURN) || s.Size() > MaxScriptSize
 }
@@ -692,7 +767,7 @@ func (s *Script) GetSigOpCount(flags uint32, accurate bool) int {
 }
 
 func (s *Script) GetPubKeyP2SHSigOpCount(flags uint32, scriptSig *Script) int {
-	if flags&ScriptVerifyP2SH == 0 || !s.IsPayToScriptHash() {
+	if flags&ScriptVerifyP2SH == 0 || !s.IsPayToScriptHashWithFlags(flags) {
 		return s.GetSigOpCount(flags, true)
 	}
 
@@ -895,8 +970,43 @@ func CheckTransactionSignatureEncoding(vchSig []byte, flags uint32) error {
 		return err
 	}
 
+	return checkSigHashEncoding(vchSig, flags)
+}
+
+// CheckTransactionECDSASignatureEncoding is used in contexts where only ECDSA
+// signatures are accepted, i.e. OP_CHECKMULTISIG(VERIFY).
+func CheckTransactionECDSASignatureEncoding(vchSig []byte, flags uint32) error {
+	if len(vchSig) == 0 {
+		return nil
+	}
+
+	ok, err := checkRawECDSASignatureEncoding(vchSig[:len(vchSig)-1], flags)
+	if !ok {
+		return err
+	}
+
+	return checkSigHashEncoding(vchSig, flags)
+}
+
+// CheckTransactionSchnorrSignatureEncoding is used in contexts where only
+// Schnorr signatures are accepted, i.e. OP_CHECKMULTISIG(VERIFY) with a non
+// null dummy element.
+func CheckTransactionSchnorrSignatureEncoding(vchSig []byte, flags uint32) error {
+	// An empty signature is allowed, it makes the signature check fail.
+	if len(vchSig) == 0 {
+		return nil
+	}
+
+	if !IsSchnorrSig(vchSig[:len(vchSig)-1]) {
+		return errcode.New(errcode.ScriptErrSigNonSchnorr)
+	}
+
+	return checkSigHashEncoding(vchSig, flags)
+}
+
+func checkSigHashEncoding(vchSig []byte, flags uint32) error {
 	if (flags & ScriptVerifyStrictEnc) != 0 {
-		if !crypto.IsDefineHashtypeSignature(vchSig) {
+		if !isDefinedHashType(vchSig[len(vchSig)-1], flags) {
 			log.Debug("ScriptErrSigHashType")
 			return errcode.New(errcode.ScriptErrSigHashType)
 		}
@@ -920,7 +1030,42 @@ func CheckTransactionSignatureEncoding(vchSig []byte, flags uint32) error {
 	return nil
 }
 
+// isDefinedHashType reports whether the sighash type of a signature is defined
+// under the script flags. SIGHASH_UTXOS is defined once tokens are enabled, but
+// not along with ANYONECANPAY since it commits to the outputs spent by every
+// input.
+func isDefinedHashType(hashType byte, flags uint32) bool {
+	if hashType&crypto.SigHashUtxos != 0 {
+		if flags&ScriptEnableTokens == 0 || hashType&crypto.SigHashAnyoneCanpay != 0 {
+			return false
+		}
+		hashType &^= crypto.SigHashUtxos
+	}
+	return crypto.IsDefineHashtypeSignature([]byte{hashType})
+}
+
+// IsSchnorrSig reports whether a raw signature, without sighash type, has the
+// length of a Schnorr signature.
+func IsSchnorrSig(vchSig []byte) bool {
+	return len(vchSig) == crypto.SchnorrSignatureLen
+}
+
 func checkRawSignatureEncoding(vchSig []byte, flags uint32) (bool, error) {
+	if IsSchnorrSig(vchSig) && (flags&ScriptEnableSchnorr) != 0 {
+		// Schnorr signatures have no encoding rule beyond their length
+		return true, nil
+	}
+
+	return checkRawECDSASignatureEncoding(vchSig, flags)
+}
+
+func checkRawECDSASignatureEncoding(vchSig []byte, flags uint32) (bool, error) {
+	// In an ECDSA-only context, 64 bytes signatures are forbidden once
+	// Schnorr is enabled.
+	if IsSchnorrSig(vchSig) && (flags&ScriptEnableSchnorr) != 0 {
+		return false, errcode.New(errcode.ScriptErrSigBadLength)
+	}
+
 	if ((flags & (ScriptVerifyDersig | ScriptVerifyLowS | ScriptVerifyStrictEnc)) != 0) && !crypto.
 		IsValidSignatureEncoding(vchSig) {
 		return false, errcode.New(errcode.ScriptErrSigDer)
@@ -955,8 +1100,10 @@ func CheckPubKeyEncoding(vchPubKey []byte, flags uint32) error {
 func IsOpCodeDisabled(opCode byte, flags uint32) bool {
 	switch opCode {
 	case opcodes.OP_INVERT, opcodes.OP_2MUL, opcodes.OP_2DIV,
-		opcodes.OP_MUL, opcodes.OP_LSHIFT, opcodes.OP_RSHIFT:
+		opcodes.OP_LSHIFT, opcodes.OP_RSHIFT:
 		return true
+	case opcodes.OP_MUL:
+		return flags&ScriptEnable64BitIntegers == 0
 	default:
 		return false
 	}
@@ -316,6 +316,30 @@ func TestScript_IsCommitment(t *testing.T) {
 	assert.Equal(t, false, result3)
 }
 
+func TestScript_IsWitnessProgram(t *testing.T) {
+	program := func(version byte, size int) []byte {
+		return append([]byte{version, byte(size)}, bytes.Repeat([]byte{0xab}, size)...)
+	}
+	tests := []struct {
+		raw  []byte
+		want bool
+	}{
+		{program(OP_0, 20), true},
+		{program(OP_0, 32), true},
+		{program(OP_1, 2), true},
+		{program(OP_16, 40), true},
+		{program(OP_0, 1), false},
+		{program(OP_0, 41), false},
+		{program(OP_1NEGATE, 20), false},
+		{program(OP_NOP, 20), false},
+		{append(program(OP_0, 20), OP_0), false},
+		{[]byte{OP_0, 0x15, 0x00, 0x00}, false},
+	}
+	for i, test := range tests {
+		assert.Equal(t, test.want, NewScriptRaw(test.raw).IsWitnessProgram(), "case %d", i)
+	}
+}
+
 func TestBytesToBool(t *testing.T) {
 	tests := []struct {
 		in   []byte
@@ -989,6 +1013,8 @@ func TestCheckSignatureEncoding(t *testing.T) {
 	//errSig := hexToBytes("3045022100d83c96e2656d8c91bf508c4dda68e13f6ea55cfd728e9f55d841d9e32d9325d30221673c42ba6b6546bda1fa0e072c5a423cb02d156406c8a5b59310aa86cab4af701")
 	notDefinedHashTypeSig := hexToBytes("3045022100d83c96e2656d8c91bf508c4dda68e13f6ea55cfd728e9f55d841d9e32d9325d302201673c42ba6b6546bda1fa0e072c5a423cb02d156406c8a5b59310aa86cab4af700")
 	validSigWithSigHashForkID := hexToBytes("3045022100d83c96e2656d8c91bf508c4dda68e13f6ea55cfd728e9f55d841d9e32d9325d302201673c42ba6b6546bda1fa0e072c5a423cb02d156406c8a5b59310aa86cab4af741")
+	validSigWithSigHashUtxos := hexToBytes("3045022100d83c96e2656d8c91bf508c4dda68e13f6ea55cfd728e9f55d841d9e32d9325d302201673c42ba6b6546bda1fa0e072c5a423cb02d156406c8a5b59310aa86cab4af761")
+	anyoneCanPaySigWithSigHashUtxos := hexToBytes("3045022100d83c96e2656d8c91bf508c4dda68e13f6ea55cfd728e9f55d841d9e32d9325d302201673c42ba6b6546bda1fa0e072c5a423cb02d156406c8a5b59310aa86cab4af7e1")
 
 	tests := []struct {
 		vchSig      []byte
@@ -1022,6 +1048,24 @@ func TestCheckSignatureEncoding(t *testing.T) {
 			errcode.New(errcode.ScriptErrMustUseForkID),
 			"Signature is valid, flag is ScriptEnableSigHashForkID xor ScriptVerifyStrictEnc, should return error",
 		},
+		{
+			validSigWithSigHashUtxos,
+			ScriptEnableSigHashForkID | ScriptVerifyStrictEnc,
+			errcode.New(errcode.ScriptErrSigHashType),
+			"Signature with SigHashUtxos before tokens are enabled, should return error.",
+		},
+		{
+			validSigWithSigHashUtxos,
+			ScriptEnableSigHashForkID | ScriptVerifyStrictEnc | ScriptEnableTokens,
+			nil,
+			"Signature with SigHashUtxos once tokens are enabled, should return nil.",
+		},
+		{
+			anyoneCanPaySigWithSigHashUtxos,
+			ScriptEnableSigHashForkID | ScriptVerifyStrictEnc | ScriptEnableTokens,
+			errcode.New(errcode.ScriptErrSigHashType),
+			"Signature with SigHashUtxos and SigHashAnyoneCanpay, should return error.",
+		},
 		{
 			validSig,
 			0,
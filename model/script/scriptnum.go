@@ -1,6 +1,8 @@
 package script
 
 import (
+	"math"
+
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 )
@@ -8,6 +10,10 @@ import (
 const (
 	DefaultMaxNumSize = 4
 
+	// MaxNumSize64Bit is the maximum size of a script number once
+	// ScriptEnable64BitIntegers is set.
+	MaxNumSize64Bit = 8
+
 	MaxInt32 = 1<<31 - 1
 	MinInt32 = -1 << 31
 )
@@ -264,6 +270,43 @@ func IsMinimallyEncoded(data []byte, nMaxNumSize int64) bool {
 	return true
 }
 
+// IsValid64BitRange reports whether v fits in an 8 bytes script number, that
+// is whether it is in [-2^63 + 1, 2^63 - 1].
+func IsValid64BitRange(v int64) bool {
+	return v != math.MinInt64
+}
+
+// SafeAdd returns a + b, and false if the sum is not a valid 64-bit script
+// number.
+func SafeAdd(a, b int64) (int64, bool) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, false
+	}
+	return a + b, IsValid64BitRange(a + b)
+}
+
+// SafeSub returns a - b, and false if the difference is not a valid 64-bit
+// script number.
+func SafeSub(a, b int64) (int64, bool) {
+	if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+		return 0, false
+	}
+	return a - b, IsValid64BitRange(a - b)
+}
+
+// SafeMul returns a * b, and false if the product is not a valid 64-bit
+// script number.
+func SafeMul(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return product, IsValid64BitRange(product)
+}
+
 func NewScriptNum(v int64) *ScriptNum {
 	return &ScriptNum{Value: v}
 }
@@ -87,6 +87,8 @@ func TestGetScriptNum(t *testing.T) {
 		{hexToBytes("ffffffffffffff80"), ScriptNum{0}, DefaultMaxNumSize, true, errNumOverflow},
 		{hexToBytes("ffffffffffffff7f"), ScriptNum{0}, DefaultMaxNumSize, true, errNumOverflow},
 		{hexToBytes("ffffffffffffffff"), ScriptNum{0}, DefaultMaxNumSize, true, errNumOverflow},
+		{hexToBytes("ffffffffffffffff00"), ScriptNum{0}, MaxNumSize64Bit, true, errNumOverflow},
+		{hexToBytes("ffffffffffffffff80"), ScriptNum{0}, MaxNumSize64Bit, true, errNumOverflow},
 
 		// Non-minimally encoded, but otherwise valid values with
 		// minimal encoding flag.  Should error and return 0.
@@ -248,6 +250,40 @@ func TestScriptNumInt32(t *testing.T) {
 	}
 }
 
+func TestScriptNumSafeArithmetic(t *testing.T) {
+	const maxValue = 9223372036854775807
+
+	tests := []struct {
+		name string
+		op   func(a, b int64) (int64, bool)
+		a, b int64
+		want int64
+		ok   bool
+	}{
+		{"add", SafeAdd, 2, 3, 5, true},
+		{"add to max", SafeAdd, maxValue - 1, 1, maxValue, true},
+		{"add overflow", SafeAdd, maxValue, 1, 0, false},
+		{"add to min", SafeAdd, -maxValue, -1, 0, false},
+		{"sub", SafeSub, 2, 3, -1, true},
+		{"sub to -max", SafeSub, -maxValue + 1, 1, -maxValue, true},
+		{"sub to min", SafeSub, -maxValue, 1, 0, false},
+		{"sub overflow", SafeSub, maxValue, -1, 0, false},
+		{"mul", SafeMul, -3, 4, -12, true},
+		{"mul by 0", SafeMul, maxValue, 0, 0, true},
+		{"mul to max", SafeMul, maxValue, -1, -maxValue, true},
+		{"mul overflow", SafeMul, 1 << 62, 2, 0, false},
+		{"mul to min", SafeMul, -(1 << 62), 2, 0, false},
+		{"mul large overflow", SafeMul, maxValue, maxValue, 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := test.op(test.a, test.b)
+		if ok != test.ok || (ok && got != test.want) {
+			t.Errorf("%s: got %d, %v, want %d, %v", test.name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
 func TestScriptNum_Bytes(t *testing.T) {
 	tests := []struct {
 		in   ScriptNum
@@ -0,0 +1,293 @@
+package token
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// PrefixToken is the first byte of the token prefix of an output. It is
+	// an undefined opcode, so no locking script created before the upgrade
+	// could ever have been spent with it in front.
+	PrefixToken = 0xef
+
+	// MaxCommitmentLength is the maximum length of the commitment of a
+	// non-fungible token. (network rule)
+	MaxCommitmentLength = 40
+
+	// MaxAmount is the maximum fungible token amount of an output, and of the
+	// sum of the amounts of a category in a transaction. (network rule)
+	MaxAmount = math.MaxInt64
+)
+
+// The token bitfield is the byte following the category in the token prefix.
+const (
+	bitfieldReserved            = 0x80
+	bitfieldHasCommitmentLength = 0x40
+	bitfieldHasNFT              = 0x20
+	bitfieldHasAmount           = 0x10
+	bitfieldCapabilityMask      = 0x0f
+)
+
+// Capability is the capability of a non-fungible token, the low nibble of the
+// token bitfield.
+type Capability byte
+
+const (
+	// None is an immutable non-fungible token.
+	None Capability = 0x00
+	// Mutable non-fungible tokens can be replaced by one other non-fungible
+	// token of their category when spent.
+	Mutable Capability = 0x01
+	// Minting non-fungible tokens can create any number of non-fungible
+	// tokens of their category when spent.
+	Minting Capability = 0x02
+)
+
+func (c Capability) String() string {
+	switch c {
+	case None:
+		return "none"
+	case Mutable:
+		return "mutable"
+	case Minting:
+		return "minting"
+	}
+	return fmt.Sprintf("unknown(%d)", byte(c))
+}
+
+var (
+	errNoToken          = errors.New("the token prefix encodes neither an amount nor a non-fungible token")
+	errReservedBit      = errors.New("the token prefix uses the reserved bit")
+	errBadCapability    = errors.New("the token prefix capability is unknown")
+	errCapabilityNoNFT  = errors.New("the token prefix has a capability without a non-fungible token")
+	errCommitmentNoNFT  = errors.New("the token prefix has a commitment without a non-fungible token")
+	errEmptyCommitment  = errors.New("the token prefix has an empty commitment")
+	errLongCommitment   = errors.New("the token prefix commitment is too long")
+	errBadAmount        = errors.New("the token prefix amount is out of range")
+	errMissingPrefix    = errors.New("the token data does not start with the token prefix")
+	errTruncatedPrefix  = errors.New("the token prefix is truncated")
+	errInvalidTokenData = errors.New("the token data cannot be encoded")
+)
+
+// OutputData is the tokens carried by a transaction output: an amount of
+// fungible tokens and/or one non-fungible token, all of a single category.
+type OutputData struct {
+	// Category is the hash of the transaction whose first input created the
+	// category, in the same byte order as an outpoint hash.
+	Category util.Hash
+	// Amount is the fungible token amount, 0 if there is none.
+	Amount int64
+	// HasNFT reports whether there is a non-fungible token.
+	HasNFT bool
+	// Capability is the capability of the non-fungible token.
+	Capability Capability
+	// Commitment is the commitment of the non-fungible token, it may be empty.
+	Commitment []byte
+}
+
+func (data *OutputData) bitfield() byte {
+	var bitfield byte
+	if len(data.Commitment) > 0 {
+		bitfield |= bitfieldHasCommitmentLength
+	}
+	if data.HasNFT {
+		bitfield |= bitfieldHasNFT | byte(data.Capability)
+	}
+	if data.Amount != 0 {
+		bitfield |= bitfieldHasAmount
+	}
+	return bitfield
+}
+
+// HasAmount reports whether the output carries fungible tokens.
+func (data *OutputData) HasAmount() bool {
+	return data.Amount != 0
+}
+
+// IsMutableNFT reports whether the output carries a mutable non-fungible token.
+func (data *OutputData) IsMutableNFT() bool {
+	return data.HasNFT && data.Capability == Mutable
+}
+
+// IsMintingNFT reports whether the output carries a minting non-fungible token.
+func (data *OutputData) IsMintingNFT() bool {
+	return data.HasNFT && data.Capability == Minting
+}
+
+// IsImmutableNFT reports whether the output carries an immutable non-fungible
+// token.
+func (data *OutputData) IsImmutableNFT() bool {
+	return data.HasNFT && data.Capability == None
+}
+
+// CheckValid returns an error if the token data cannot be encoded in a valid
+// token prefix.
+func (data *OutputData) CheckValid() error {
+	if !data.HasNFT && data.Amount == 0 {
+		return errNoToken
+	}
+	if data.Amount < 0 {
+		return errBadAmount
+	}
+	if !data.HasNFT {
+		if data.Capability != None {
+			return errCapabilityNoNFT
+		}
+		if len(data.Commitment) > 0 {
+			return errCommitmentNoNFT
+		}
+	}
+	if data.Capability > Minting {
+		return errBadCapability
+	}
+	if len(data.Commitment) > MaxCommitmentLength {
+		return errLongCommitment
+	}
+	return nil
+}
+
+// EncodeSize returns the size of the token prefix, PrefixToken included.
+func (data *OutputData) EncodeSize() uint32 {
+	size := 1 + uint32(util.Hash256Size) + 1
+	if len(data.Commitment) > 0 {
+		size += util.VarIntSerializeSize(uint64(len(data.Commitment))) + uint32(len(data.Commitment))
+	}
+	if data.Amount != 0 {
+		size += util.VarIntSerializeSize(uint64(data.Amount))
+	}
+	return size
+}
+
+// Encode writes the token prefix, PrefixToken included.
+func (data *OutputData) Encode(writer io.Writer) error {
+	if data.CheckValid() != nil {
+		return errInvalidTokenData
+	}
+	if _, err := writer.Write([]byte{PrefixToken}); err != nil {
+		return err
+	}
+	if _, err := writer.Write(data.Category[:]); err != nil {
+		return err
+	}
+	if _, err := writer.Write([]byte{data.bitfield()}); err != nil {
+		return err
+	}
+	if len(data.Commitment) > 0 {
+		if err := util.WriteVarBytes(writer, data.Commitment); err != nil {
+			return err
+		}
+	}
+	if data.Amount != 0 {
+		return util.WriteVarInt(writer, uint64(data.Amount))
+	}
+	return nil
+}
+
+// Decode reads a token prefix, PrefixToken included. Only the canonical
+// encoding of valid token data is accepted, so that encoding the result gives
+// back the exact same bytes.
+func (data *OutputData) Decode(reader io.Reader) error {
+	var prefix [1]byte
+	if _, err := io.ReadFull(reader, prefix[:]); err != nil {
+		return err
+	}
+	if prefix[0] != PrefixToken {
+		return errMissingPrefix
+	}
+	if _, err := io.ReadFull(reader, data.Category[:]); err != nil {
+		return err
+	}
+	var bitfield [1]byte
+	if _, err := io.ReadFull(reader, bitfield[:]); err != nil {
+		return err
+	}
+	if bitfield[0]&bitfieldReserved != 0 {
+		return errReservedBit
+	}
+
+	data.HasNFT = bitfield[0]&bitfieldHasNFT != 0
+	data.Capability = Capability(bitfield[0] & bitfieldCapabilityMask)
+	if data.Capability > Minting {
+		return errBadCapability
+	}
+	if !data.HasNFT && data.Capability != None {
+		return errCapabilityNoNFT
+	}
+
+	data.Commitment = nil
+	if bitfield[0]&bitfieldHasCommitmentLength != 0 {
+		if !data.HasNFT {
+			return errCommitmentNoNFT
+		}
+		commitment, err := util.ReadVarBytes(reader, MaxCommitmentLength, "token commitment")
+		if err != nil {
+			return err
+		}
+		if len(commitment) == 0 {
+			return errEmptyCommitment
+		}
+		data.Commitment = commitment
+	}
+
+	data.Amount = 0
+	if bitfield[0]&bitfieldHasAmount != 0 {
+		amount, err := util.ReadVarInt(reader)
+		if err != nil {
+			return err
+		}
+		if amount == 0 || amount > MaxAmount {
+			return errBadAmount
+		}
+		data.Amount = int64(amount)
+	}
+
+	if !data.HasNFT && data.Amount == 0 {
+		return errNoToken
+	}
+	return nil
+}
+
+// IsEqual reports whether both token data are the same, nil being no tokens.
+func (data *OutputData) IsEqual(other *OutputData) bool {
+	if data == nil || other == nil {
+		return data == other
+	}
+	return data.Category == other.Category && data.Amount == other.Amount && data.HasNFT == other.HasNFT &&
+		data.Capability == other.Capability && bytes.Equal(data.Commitment, other.Commitment)
+}
+
+func (data *OutputData) String() string {
+	if !data.HasNFT {
+		return fmt.Sprintf("Category:%s Amount:%d", data.Category, data.Amount)
+	}
+	return fmt.Sprintf("Category:%s Amount:%d Capability:%s Commitment:%s", data.Category, data.Amount,
+		data.Capability, hex.EncodeToString(data.Commitment))
+}
+
+// UnwrapScriptPubKey splits the bytes serialized in place of the locking
+// script of an output into its token data and its locking script. The token
+// data is nil if the bytes do not start with PrefixToken. If they do but the
+// token prefix is invalid, the error is returned along with the bytes as a
+// whole, which is then the locking script of the output.
+func UnwrapScriptPubKey(wrapped []byte) (*OutputData, []byte, error) {
+	if len(wrapped) == 0 || wrapped[0] != PrefixToken {
+		return nil, wrapped, nil
+	}
+
+	reader := bytes.NewReader(wrapped)
+	data := new(OutputData)
+	if err := data.Decode(reader); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = errTruncatedPrefix
+		}
+		return nil, wrapped, err
+	}
+	return data, wrapped[len(wrapped)-reader.Len():], nil
+}
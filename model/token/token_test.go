@@ -0,0 +1,147 @@
+package token
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+)
+
+var testCategory = util.Hash{
+	0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+	0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+	0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+	0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+}
+
+func testPrefix(tail string) []byte {
+	b, err := hex.DecodeString("ef" + hex.EncodeToString(testCategory[:]) + tail)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestOutputDataEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		data OutputData
+		tail string
+	}{
+		{"amount", OutputData{Category: testCategory, Amount: 1}, "1001"},
+		{"large amount", OutputData{Category: testCategory, Amount: 0xfd}, "10fdfd00"},
+		{"max amount", OutputData{Category: testCategory, Amount: MaxAmount}, "10ffffffffffffffff7f"},
+		{"immutable nft", OutputData{Category: testCategory, HasNFT: true}, "20"},
+		{"mutable nft", OutputData{Category: testCategory, HasNFT: true, Capability: Mutable}, "21"},
+		{"minting nft", OutputData{Category: testCategory, HasNFT: true, Capability: Minting}, "22"},
+		{"nft commitment", OutputData{Category: testCategory, HasNFT: true, Commitment: []byte{0xcc}}, "6001cc"},
+		{"everything", OutputData{Category: testCategory, Amount: 10, HasNFT: true, Capability: Minting,
+			Commitment: []byte{0xcc, 0xdd}}, "7202ccdd0a"},
+	}
+
+	for _, test := range tests {
+		expect := testPrefix(test.tail)
+
+		if test.data.EncodeSize() != uint32(len(expect)) {
+			t.Errorf("%s: expect size %d, actual %d", test.name, len(expect), test.data.EncodeSize())
+		}
+		buf := new(bytes.Buffer)
+		if err := test.data.Encode(buf); err != nil {
+			t.Fatalf("%s: encode error %v", test.name, err)
+		}
+		if !bytes.Equal(buf.Bytes(), expect) {
+			t.Errorf("%s: expect %x, actual %x", test.name, expect, buf.Bytes())
+		}
+
+		var decoded OutputData
+		if err := decoded.Decode(bytes.NewReader(expect)); err != nil {
+			t.Fatalf("%s: decode error %v", test.name, err)
+		}
+		if !decoded.IsEqual(&test.data) {
+			t.Errorf("%s: expect %s, actual %s", test.name, test.data.String(), decoded.String())
+		}
+	}
+}
+
+func TestOutputDataDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		tail string
+	}{
+		{"no token", "00"},
+		{"reserved bit", "b001"},
+		{"unknown capability", "23"},
+		{"capability without nft", "1101"},
+		{"commitment without nft", "5001cc01"},
+		{"empty commitment", "6000"},
+		{"long commitment", "6029" + strings.Repeat("cc", 41)},
+		{"zero amount", "1000"},
+		{"amount overflow", "10ff0000000000000080"},
+		{"non canonical amount", "10fd0100"},
+		{"truncated", "6002cc"},
+	}
+
+	for _, test := range tests {
+		var data OutputData
+		if err := data.Decode(bytes.NewReader(testPrefix(test.tail))); err == nil {
+			t.Errorf("%s: expect a decode error", test.name)
+		}
+	}
+
+	if err := new(OutputData).Decode(bytes.NewReader([]byte{0x76})); err != errMissingPrefix {
+		t.Errorf("expect error %v, actual %v", errMissingPrefix, err)
+	}
+}
+
+func TestOutputDataEncodeInvalid(t *testing.T) {
+	tests := []OutputData{
+		{Category: testCategory},
+		{Category: testCategory, Amount: -1},
+		{Category: testCategory, Amount: 1, Capability: Mutable},
+		{Category: testCategory, Amount: 1, Commitment: []byte{0xcc}},
+		{Category: testCategory, HasNFT: true, Capability: 3},
+		{Category: testCategory, HasNFT: true, Commitment: make([]byte, MaxCommitmentLength+1)},
+	}
+
+	for i, data := range tests {
+		if err := data.Encode(new(bytes.Buffer)); err == nil {
+			t.Errorf("test %d: expect an encode error", i)
+		}
+	}
+}
+
+func TestUnwrapScriptPubKey(t *testing.T) {
+	locking := []byte{0x76, 0xa9}
+
+	data, scriptPubKey, err := UnwrapScriptPubKey(locking)
+	if data != nil || err != nil || !bytes.Equal(scriptPubKey, locking) {
+		t.Errorf("a locking script without prefix should be returned as is")
+	}
+
+	wrapped := append(testPrefix("1001"), locking...)
+	data, scriptPubKey, err = UnwrapScriptPubKey(wrapped)
+	if err != nil {
+		t.Fatalf("unwrap error %v", err)
+	}
+	if !data.IsEqual(&OutputData{Category: testCategory, Amount: 1}) || !bytes.Equal(scriptPubKey, locking) {
+		t.Errorf("expect an amount of 1 locked by %x, actual %s locked by %x", locking, data, scriptPubKey)
+	}
+
+	data, scriptPubKey, err = UnwrapScriptPubKey(testPrefix("1001"))
+	if err != nil || data == nil || len(scriptPubKey) != 0 {
+		t.Errorf("the locking script after the token prefix may be empty")
+	}
+
+	invalid := append(testPrefix("1000"), locking...)
+	data, scriptPubKey, err = UnwrapScriptPubKey(invalid)
+	if err == nil || data != nil || !bytes.Equal(scriptPubKey, invalid) {
+		t.Errorf("an invalid token prefix should be kept in the locking script")
+	}
+
+	data, scriptPubKey, err = UnwrapScriptPubKey([]byte{PrefixToken, 0x01})
+	if err != errTruncatedPrefix || data != nil || len(scriptPubKey) != 2 {
+		t.Errorf("expect error %v, actual %v", errTruncatedPrefix, err)
+	}
+}
@@ -71,6 +71,9 @@ const (
 	/*MaxStandardTxSigOps the maximum number of sigops we're willing to relay/mine in a single tx */
 	MaxStandardTxSigOps = uint(consensus.MaxTxSigOpsCount / 5)
 
+	/*MaxStandardTxSigChecks the maximum number of SigChecks we're willing to relay/mine in a single tx */
+	MaxStandardTxSigChecks = 3000
+
 	/*DefaultMaxMemPoolSize default for -maxMemPool, maximum megabytes of memPool memory usage */
 	//DefaultMaxMemPoolSize uint = 300
 
@@ -284,7 +287,7 @@ func (tx *Tx) GetSigOpCountWithoutP2SH(flags uint32) int {
 		n += in.GetScriptSig().GetSigOpCount(flags, false)
 	}
 	for _, out := range tx.outs {
-		n += out.GetScriptPubKey().GetSigOpCount(flags, false)
+		n += out.GetScriptPubKeyWithFlags(flags).GetSigOpCount(flags, false)
 	}
 	return n
 }
@@ -471,8 +474,11 @@ func (tx *Tx) GetValueOut() amount.Amount {
 	return valueOut
 }
 
+// SignStep returns the signature data of the input nIn, spentOutputs being the
+// outputs spent by every input of the transaction, in order, which the signature
+// hash commits to once tokens are enabled.
 func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.Script, hashType uint32,
-	scriptPubKey *script.Script, value amount.Amount) (sigData [][]byte, err error) {
+	scriptPubKey *script.Script, value amount.Amount, spentOutputs []*txout.TxOut) (sigData [][]byte, err error) {
 	pubKeyType, pubKeys, isStandard := scriptPubKey.IsStandardScriptPubKey()
 	if !isStandard || pubKeyType == script.ScriptNonStandard || pubKeyType == script.ScriptNullData {
 		log.Debug("SignStep IsStandardScriptPubKey err")
@@ -499,7 +505,7 @@ func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.
 		if keyPair == nil {
 			return nil, errors.New("private key not found")
 		}
-		signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value)
+		signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value, spentOutputs)
 		if err != nil {
 			return nil, err
 		}
@@ -512,7 +518,7 @@ func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.
 		if keyPair == nil {
 			return nil, errors.New("private key not found")
 		}
-		signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value)
+		signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value, spentOutputs)
 		if err != nil {
 			return nil, err
 		}
@@ -532,7 +538,7 @@ func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.
 				log.Info("Private key not found:%s", hex.EncodeToString(pubKey))
 				continue
 			}
-			signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value)
+			signature, err := tx.signOne(scriptPubKeySign, keyPair.GetPrivateKey(), hashType, nIn, value, spentOutputs)
 			if err != nil {
 				log.Info("getSignatureData error:%s", err.Error())
 				continue
@@ -562,9 +568,10 @@ func (tx *Tx) SignStep(nIn int, keyStore *crypto.KeyStore, redeemScript *script.
 }
 
 func (tx *Tx) signOne(scriptPubKey *script.Script, privateKey *crypto.PrivateKey, hashType uint32,
-	nIn int, value amount.Amount) (signature *crypto.Signature, err error) {
+	nIn int, value amount.Amount, spentOutputs []*txout.TxOut) (signature *crypto.Signature, err error) {
 
-	hash, err := SignatureHash(tx, scriptPubKey, hashType, nIn, value, script.ScriptEnableSigHashForkID)
+	hash, err := SignatureHashWithSpentOutputs(tx, scriptPubKey, hashType, nIn, value,
+		script.ScriptEnableSigHashForkID|script.ScriptEnableTokens, spentOutputs)
 	if err != nil {
 		return nil, err
 	}
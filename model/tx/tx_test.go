@@ -832,11 +832,16 @@ func TestSignStepP2PKH(t *testing.T) {
 	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
 
 	// Single signature case:
-	sigData, err := v.spender.SignStep(0, v.keyStore, nil, hashType, scriptPubKey, 1)
+	sigData, err := v.spender.SignStep(0, v.keyStore, nil, hashType, scriptPubKey, 1, v.prevHolder.GetOuts())
 	assert.Nil(t, err)
 	// <signature> <pubkey>
 	assert.Equal(t, len(sigData), 2)
 	assert.Equal(t, sigData[1], v.pubKeys[0].ToBytes())
+
+	// The signature hash commits to the token prefix of the spent output, so
+	// the input can't be signed without the spent outputs.
+	_, err = v.spender.SignStep(0, v.keyStore, nil, hashType, scriptPubKey, 1, nil)
+	assert.Error(t, err)
 }
 
 func TestSignStepP2SH(t *testing.T) {
@@ -868,7 +873,7 @@ func TestSignStepP2SH(t *testing.T) {
 	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
 
 	// Single signature case:
-	sigData, err := v.spender.SignStep(0, v.keyStore, pubKey, hashType, scriptPubKey, 1)
+	sigData, err := v.spender.SignStep(0, v.keyStore, pubKey, hashType, scriptPubKey, 1, v.prevHolder.GetOuts())
 	assert.Nil(t, err)
 	// <signature> <redeemscript>
 	assert.Equal(t, len(sigData), 2)
@@ -902,7 +907,7 @@ func TestSignStepMultiSig(t *testing.T) {
 	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
 
 	// Multiple signature case:
-	sigData, err := v.spender.SignStep(0, v.keyStore, nil, hashType, scriptPubKey, 1)
+	sigData, err := v.spender.SignStep(0, v.keyStore, nil, hashType, scriptPubKey, 1, v.prevHolder.GetOuts())
 	assert.Nil(t, err)
 	// <OP_0> <signature0> ... <signatureM>
 	assert.Equal(t, len(sigData), 3)
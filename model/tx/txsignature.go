@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 
 	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/script"
@@ -15,6 +16,15 @@ import (
 
 func SignatureHash(transaction *Tx, s *script.Script, hashType uint32, nIn int,
 	money amount.Amount, flags uint32) (result util.Hash, err error) {
+	return SignatureHashWithSpentOutputs(transaction, s, hashType, nIn, money, flags, nil)
+}
+
+// SignatureHashWithSpentOutputs is SignatureHash given the outputs spent by
+// every input of the transaction, in order. Once tokens are enabled, they are
+// needed for SIGHASH_UTXOS, and the token prefix of the output spent by input
+// nIn is part of its signature hash.
+func SignatureHashWithSpentOutputs(transaction *Tx, s *script.Script, hashType uint32, nIn int,
+	money amount.Amount, flags uint32, spentOutputs []*txout.TxOut) (result util.Hash, err error) {
 
 	var hashBuffer bytes.Buffer
 	var sigHashAnyOneCanPay = false
@@ -40,9 +50,26 @@ func SignatureHash(transaction *Tx, s *script.Script, hashType uint32, nIn int,
 	if hashType&crypto.SigHashForkID == crypto.SigHashForkID &&
 		flags&script.ScriptEnableSigHashForkID == script.ScriptEnableSigHashForkID {
 		var hashPrevouts util.Hash
+		var hashUtxos util.Hash
 		var hashSequence util.Hash
 		var hashOutputs util.Hash
 
+		// once tokens are enabled, the hash commits to the token prefix of
+		// the spent output, which can't be left out for want of it
+		tokensEnabled := flags&script.ScriptEnableTokens != 0
+		if tokensEnabled && spentOutputs == nil {
+			log.Debug("txSignature:tokens enabled without the spent outputs")
+			return util.HashOne, errcode.New(errcode.ScriptErrContextNotPresent)
+		}
+		if spentOutputs != nil && len(spentOutputs) != transaction.GetInsCount() {
+			log.Error("txSignature:%d spent outputs for %d inputs", len(spentOutputs), transaction.GetInsCount())
+			return util.HashOne, errcode.New(errcode.ScriptErrContextNotPresent)
+		}
+		sigHashUtxos := tokensEnabled && hashType&crypto.SigHashUtxos == crypto.SigHashUtxos
+		if sigHashUtxos {
+			hashUtxos, _ = GetOutputsHash(spentOutputs)
+		}
+
 		if !sigHashAnyOneCanPay {
 			hashPrevouts = GetPreviousOutHash(transaction)
 		}
@@ -65,6 +92,13 @@ func SignatureHash(transaction *Tx, s *script.Script, hashType uint32, nIn int,
 			log.Error("txSignature:write hashPrevouts failed: %v", err)
 			return util.HashOne, err
 		}
+		if sigHashUtxos {
+			_, err = hashBuffer.Write(hashUtxos[:])
+			if err != nil {
+				log.Error("txSignature:write hashUtxos failed: %v", err)
+				return util.HashOne, err
+			}
+		}
 		_, err = hashBuffer.Write(hashSequence[:])
 		if err != nil {
 			log.Error("txSignature:write hashSequence failed: %v", err)
@@ -75,6 +109,14 @@ func SignatureHash(transaction *Tx, s *script.Script, hashType uint32, nIn int,
 			log.Error("txSignature:Previous OutPoint encode failed: %v", err)
 			return util.HashOne, err
 		}
+		// the token prefix of the spent output comes before the script code
+		if tokensEnabled && spentOutputs[nIn].GetTokenData() != nil {
+			err = spentOutputs[nIn].GetTokenData().Encode(&hashBuffer)
+			if err != nil {
+				log.Error("txSignature:spent output token data encode failed: %v", err)
+				return util.HashOne, err
+			}
+		}
 		err = s.Serialize(&hashBuffer)
 		if err != nil {
 			log.Error("txSignature:serialize hashBuffer failed: %v", err)
@@ -321,3 +363,15 @@ func CheckSig(signHash util.Hash, vchSigIn []byte, vchPubKey []byte) bool {
 	ret := sign.Verify(signHash.GetCloneBytes(), publicKey)
 	return ret
 }
+
+// CheckSchnorrSig verifies a 64 bytes Schnorr signature, without sighash type.
+func CheckSchnorrSig(signHash util.Hash, vchSig []byte, vchPubKey []byte) bool {
+	if len(vchPubKey) == 0 || len(vchSig) != crypto.SchnorrSignatureLen {
+		return false
+	}
+	publicKey, err := crypto.ParsePubKey(vchPubKey)
+	if err != nil {
+		return false
+	}
+	return publicKey.VerifySchnorr(&signHash, vchSig)
+}
@@ -5,14 +5,18 @@ import (
 	"encoding/hex"
 	"github.com/stretchr/testify/assert"
 	"math"
+	"strings"
 	"testing"
 
 	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/token"
 	"github.com/copernet/copernicus/model/txin"
 	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
 )
 
 var testTxs = []struct {
@@ -770,6 +774,75 @@ func Test_SignatureHash(t *testing.T) {
 	assert.Equal(t, "29326e5bd1237ef68ea1ceaad654d08dc987f568b175aedf3054e17d307359a1", txHash.String())
 }
 
+func Test_SignatureHashWithSpentOutputs(t *testing.T) {
+	category := util.Hash{}
+	copy(category[:], bytes.Repeat([]byte{0x44}, 32))
+	newOut := func(value int64, lockingScript string, tokenData *token.OutputData) *txout.TxOut {
+		raw, _ := hex.DecodeString(lockingScript)
+		out := txout.NewTxOut(amount.Amount(value), script.NewScriptRaw(raw))
+		out.SetTokenData(tokenData)
+		return out
+	}
+
+	transaction := NewTx(0, 2)
+	transaction.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11,
+		0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11,
+		0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11}, 0), script.NewEmptyScript(), 0xffffffff))
+	transaction.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22,
+		0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22,
+		0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22}, 1), script.NewEmptyScript(), 0xfffffffe))
+	transaction.AddTxOut(newOut(1000, "76a914"+strings.Repeat("33", 20)+"88ac",
+		&token.OutputData{Category: category, Amount: 100}))
+	transaction.AddTxOut(newOut(2000, "51", nil))
+	spentOutputs := []*txout.TxOut{
+		newOut(5000, "a914"+strings.Repeat("55", 20)+"87", &token.OutputData{Category: category, Amount: 200,
+			HasNFT: true, Capability: token.Mutable, Commitment: []byte{0xca, 0xfe}}),
+		newOut(6000, "51", nil),
+	}
+	scriptCode := script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+	forkIDFlags := uint32(script.ScriptEnableSigHashForkID)
+	tokenFlags := forkIDFlags | script.ScriptEnableTokens
+
+	// The expected digests follow the CashTokens signing serialization: the
+	// hashUtxos commitment after hashPrevouts for SIGHASH_UTXOS, and the token
+	// prefix of the spent output before the script code.
+	tests := []struct {
+		nIn      int
+		hashType uint32
+		flags    uint32
+		expect   string
+	}{
+		{0, 0x41, forkIDFlags, "e88c4f1d81f8ebf258c6dabbd2f0a942faa2573accaf3c5fbc18ea55d22a553d"},
+		{0, 0x41, tokenFlags, "8f3ad0fe074ae1716e78322e49a074997053a1414a64b8120f9ab8e3fb15add8"},
+		{0, 0x61, tokenFlags, "6325fa2405450582c394534ff08567dbbc75355a9b51a14556608fc50b941fd9"},
+		{0, 0x63, tokenFlags, "4b38c1b12034c26ccc587c6f6c7ae4495ac55ff84bc3585705f7b1b4daee96f9"},
+		{0, 0xc1, tokenFlags, "18e9ef6824a49e320e950d3598f20bee2819f93962a0f736b8e39a95ca4f120b"},
+		{1, 0x61, tokenFlags, "78a29af674c1c1320dc383954619fd2ed22f98c4db38217fe79f204edddcd9c5"},
+		{1, 0x42, tokenFlags, "fca1d5318c873ca42b55f9943dffab565044df5c725f4c3b95cc149f09a41e17"},
+	}
+	for _, test := range tests {
+		money := spentOutputs[test.nIn].GetValue()
+		hash, err := SignatureHashWithSpentOutputs(transaction, scriptCode, test.hashType, test.nIn, money,
+			test.flags, spentOutputs)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expect, hex.EncodeToString(hash[:]), "input %d, hash type %x", test.nIn, test.hashType)
+	}
+
+	// Without tokens enabled, neither SIGHASH_UTXOS nor the token prefix are
+	// part of the signature hash.
+	hash, err := SignatureHashWithSpentOutputs(transaction, scriptCode, 0x41, 0, 5000, forkIDFlags, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "e88c4f1d81f8ebf258c6dabbd2f0a942faa2573accaf3c5fbc18ea55d22a553d", hex.EncodeToString(hash[:]))
+
+	// Once tokens are enabled, the signature hash needs the spent outputs.
+	_, err = SignatureHashWithSpentOutputs(transaction, scriptCode, 0x41, 0, 5000, tokenFlags, nil)
+	assert.Error(t, err)
+	_, err = SignatureHashWithSpentOutputs(transaction, scriptCode, 0x61, 0, 5000, tokenFlags, nil)
+	assert.Error(t, err)
+	_, err = SignatureHashWithSpentOutputs(transaction, scriptCode, 0x61, 0, 5000, tokenFlags, spentOutputs[:1])
+	assert.Error(t, err)
+}
+
 func Test_GetPreviousOutHash(t *testing.T) {
 	h := GetPreviousOutHash(&testTxs[0].tx)
 	assert.Equal(t, "284316979cb69928ffb64d41cc0d1f4491ccc094afbaef034daaefa3bec2263a", h.String())
@@ -281,6 +281,16 @@ func (tc *TxoutCompressor) Serialize(w io.Writer) error {
 	if err := util.WriteVarLenInt(w, count); err != nil {
 		return err
 	}
+	// The special scripts cannot hold a token prefix, outputs with tokens are
+	// stored uncompressed with their token prefix.
+	if tc.txout.tokenData != nil {
+		wrapped := tc.txout.wrappedScriptPubKey()
+		if err := util.WriteVarLenInt(w, uint64(len(wrapped)+numSpecialScripts)); err != nil {
+			return err
+		}
+		_, err := w.Write(wrapped)
+		return err
+	}
 	return tc.sc.Serialize(w)
 }
 
@@ -294,5 +304,12 @@ func (tc *TxoutCompressor) Unserialize(r io.Reader) error {
 		return err
 	}
 	tc.txout.value = DecompressAmount(count)
-	return tc.sc.Unserialize(r)
+	tc.txout.tokenData = nil
+	if err := tc.sc.Unserialize(r); err != nil {
+		return err
+	}
+	if tc.txout.HasInvalidTokenPrefix() {
+		tc.txout.setWrappedScriptPubKey(tc.txout.scriptPubKey.GetData())
+	}
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/token"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 	"github.com/stretchr/testify/assert"
@@ -299,3 +300,22 @@ func Test_TxoutCompressor_Unserialize(t *testing.T) {
 	txoutCompressor.Serialize(&buf)
 	assert.NoError(t, txoutCompressor.Unserialize(&buf))
 }
+
+func Test_TxoutCompressor_TokenData(t *testing.T) {
+	txout := NewTxOut(0x33a478, getTestScript())
+	txout.SetTokenData(&token.OutputData{Category: util.Hash{0x01}, Amount: 10})
+
+	var buf bytes.Buffer
+	assert.NoError(t, NewTxoutCompressor(txout).Serialize(&buf))
+
+	txoutRead := NewTxOut(0, nil)
+	assert.NoError(t, NewTxoutCompressor(txoutRead).Unserialize(&buf))
+	assert.True(t, txout.IsEqual(txoutRead))
+
+	// Reusing an output with tokens for one without must drop them.
+	buf.Reset()
+	assert.NoError(t, NewTxoutCompressor(NewTxOut(0x33a478, getTestScript())).Serialize(&buf))
+	assert.NoError(t, NewTxoutCompressor(txoutRead).Unserialize(&buf))
+	assert.Nil(t, txoutRead.GetTokenData())
+	assert.Equal(t, getTestScript().GetData(), txoutRead.GetScriptPubKey().GetData())
+}
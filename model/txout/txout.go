@@ -1,6 +1,7 @@
 package txout
 
 import (
+	"bytes"
 	"io"
 
 	"encoding/binary"
@@ -8,7 +9,9 @@ import (
 	"fmt"
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/token"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 )
@@ -16,6 +19,9 @@ import (
 type TxOut struct {
 	value        amount.Amount
 	scriptPubKey *script.Script
+	// tokenData is serialized as a prefix of scriptPubKey, nil if the output
+	// carries no tokens.
+	tokenData *token.OutputData
 }
 
 func (txOut *TxOut) SerializeSize() uint32 {
@@ -31,7 +37,14 @@ func (txOut *TxOut) Unserialize(reader io.Reader) error {
 }
 
 func (txOut *TxOut) EncodeSize() uint32 {
-	return 8 + txOut.scriptPubKey.EncodeSize()
+	if txOut.tokenData == nil {
+		return 8 + txOut.scriptPubKey.EncodeSize()
+	}
+	size := txOut.tokenData.EncodeSize()
+	if txOut.scriptPubKey != nil {
+		size += uint32(txOut.scriptPubKey.Size())
+	}
+	return 8 + util.VarIntSerializeSize(uint64(size)) + size
 }
 
 func (txOut *TxOut) Encode(writer io.Writer) error {
@@ -39,6 +52,9 @@ func (txOut *TxOut) Encode(writer io.Writer) error {
 	if err != nil {
 		return err
 	}
+	if txOut.tokenData != nil {
+		return util.WriteVarBytes(writer, txOut.wrappedScriptPubKey())
+	}
 	if txOut.scriptPubKey == nil {
 		return util.WriteVarInt(writer, 0)
 	}
@@ -50,9 +66,40 @@ func (txOut *TxOut) Decode(reader io.Reader) error {
 	if err != nil {
 		return err
 	}
-	bytes, err := script.ReadScript(reader, script.MaxMessagePayload, "tx output script")
-	txOut.scriptPubKey = script.NewScriptRaw(bytes)
-	return err
+	wrapped, err := script.ReadScript(reader, script.MaxMessagePayload, "tx output script")
+	if err != nil {
+		txOut.scriptPubKey = script.NewScriptRaw(wrapped)
+		return err
+	}
+	txOut.setWrappedScriptPubKey(wrapped)
+	return nil
+}
+
+// wrappedScriptPubKey returns the token prefix followed by the locking script,
+// as they are serialized in place of the locking script.
+func (txOut *TxOut) wrappedScriptPubKey() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, txOut.tokenData.EncodeSize()))
+	if err := txOut.tokenData.Encode(buf); err != nil {
+		panic("the token data of an output should always be valid")
+	}
+	if txOut.scriptPubKey != nil {
+		buf.Write(txOut.scriptPubKey.GetData())
+	}
+	return buf.Bytes()
+}
+
+// setWrappedScriptPubKey splits the bytes serialized in place of the locking
+// script into the token data and the locking script. Bytes starting with an
+// invalid token prefix are kept as a whole as the locking script, such an
+// output is rejected by consensus once tokens are enabled. Before that, the
+// whole bytes are the locking script, see GetScriptPubKeyWithFlags.
+func (txOut *TxOut) setWrappedScriptPubKey(wrapped []byte) {
+	tokenData, scriptPubKey, err := token.UnwrapScriptPubKey(wrapped)
+	if err != nil {
+		log.Debug("tx output script has an invalid token prefix: %v", err)
+	}
+	txOut.tokenData = tokenData
+	txOut.scriptPubKey = script.NewScriptRaw(scriptPubKey)
 }
 
 func (txOut *TxOut) IsDust(minRelayTxFee *util.FeeRate) bool {
@@ -128,6 +175,33 @@ func (txOut *TxOut) SetScriptPubKey(s *script.Script) {
 	txOut.scriptPubKey = s
 }
 
+// GetScriptPubKeyWithFlags returns the locking script of the output as seen
+// under the script flags. Until tokens are enabled, a token prefix is nothing
+// but the first bytes of the locking script, which starts with a bad opcode.
+func (txOut *TxOut) GetScriptPubKeyWithFlags(flags uint32) *script.Script {
+	if txOut.tokenData == nil || flags&script.ScriptEnableTokens != 0 {
+		return txOut.scriptPubKey
+	}
+	return script.NewScriptRaw(txOut.wrappedScriptPubKey())
+}
+
+// GetTokenData returns the tokens carried by the output, nil if there are none.
+func (txOut *TxOut) GetTokenData() *token.OutputData {
+	return txOut.tokenData
+}
+func (txOut *TxOut) SetTokenData(tokenData *token.OutputData) {
+	txOut.tokenData = tokenData
+}
+
+// HasInvalidTokenPrefix returns whether the output has no tokens although its
+// locking script starts with the token prefix byte, which only happens if the
+// token prefix could not be parsed. The locking script following a valid token
+// prefix may start with that byte too.
+func (txOut *TxOut) HasInvalidTokenPrefix() bool {
+	return txOut.tokenData == nil && txOut.scriptPubKey != nil && txOut.scriptPubKey.Size() > 0 &&
+		txOut.scriptPubKey.GetData()[0] == token.PrefixToken
+}
+
 // IsSpendable returns whether the TxOut can be spent or not,
 // but doesn't care whether it has already been spent or not
 
@@ -141,12 +215,17 @@ func (txOut *TxOut) IsSpendable() bool {
 func (txOut *TxOut) SetNull() {
 	txOut.value = -1
 	txOut.scriptPubKey = nil
+	txOut.tokenData = nil
 }
 
 func (txOut *TxOut) IsNull() bool {
 	return txOut.value == -1 //&& txOut.scriptPubKey == nil
 }
 func (txOut *TxOut) String() string {
+	if txOut.tokenData != nil {
+		return fmt.Sprintf("Value :%d Script:%s Token:%s", txOut.value,
+			hex.EncodeToString(txOut.scriptPubKey.GetData()), txOut.tokenData)
+	}
 	return fmt.Sprintf("Value :%d Script:%s", txOut.value, hex.EncodeToString(txOut.scriptPubKey.GetData()))
 }
 
@@ -154,6 +233,9 @@ func (txOut *TxOut) IsEqual(out *TxOut) bool {
 	if txOut.value != out.value {
 		return false
 	}
+	if !txOut.tokenData.IsEqual(out.tokenData) {
+		return false
+	}
 
 	return txOut.scriptPubKey.IsEqual(out.scriptPubKey)
 }
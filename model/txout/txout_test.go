@@ -15,6 +15,7 @@ import (
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/token"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 	"os"
@@ -400,3 +401,77 @@ func TestTxOut_IsEqual(t *testing.T) {
 	txout2.value = 8
 	assert.False(t, txout.IsEqual(txout2))
 }
+
+func newTestTokenData() *token.OutputData {
+	return &token.OutputData{
+		Category:   util.Hash{0x01, 0x02, 0x03},
+		Amount:     1000,
+		HasNFT:     true,
+		Capability: token.Mutable,
+		Commitment: []byte{0xcc, 0xdd},
+	}
+}
+
+func TestTxOut_TokenData(t *testing.T) {
+	txout := NewTxOut(9, script1)
+	txout.SetTokenData(newTestTokenData())
+
+	var buf bytes.Buffer
+	assert.NoError(t, txout.Serialize(&buf))
+	assert.Equal(t, uint32(buf.Len()), txout.SerializeSize())
+	// value, wrapped script length, token prefix, locking script
+	assert.Equal(t, 8+1+int(newTestTokenData().EncodeSize())+len(myscript), buf.Len())
+	assert.Equal(t, byte(token.PrefixToken), buf.Bytes()[9])
+
+	txOutRead := &TxOut{}
+	assert.NoError(t, txOutRead.Unserialize(bytes.NewReader(buf.Bytes())))
+	assert.True(t, txout.IsEqual(txOutRead))
+	assert.True(t, newTestTokenData().IsEqual(txOutRead.GetTokenData()))
+	assert.Equal(t, myscript, txOutRead.GetScriptPubKey().GetData())
+	assert.False(t, txOutRead.HasInvalidTokenPrefix())
+
+	assert.False(t, txout.IsEqual(NewTxOut(9, script1)))
+	assert.True(t, strings.HasSuffix(txout.String(), "Token:"+newTestTokenData().String()))
+}
+
+func TestTxOut_ScriptPubKeyWithFlags(t *testing.T) {
+	txout := NewTxOut(9, script1)
+	assert.Equal(t, myscript, txout.GetScriptPubKeyWithFlags(0).GetData())
+
+	txout.SetTokenData(newTestTokenData())
+	assert.Equal(t, myscript, txout.GetScriptPubKeyWithFlags(script.ScriptEnableTokens).GetData())
+
+	// Before tokens are enabled, the token prefix is part of the locking script.
+	var buf bytes.Buffer
+	assert.NoError(t, txout.Serialize(&buf))
+	assert.Equal(t, buf.Bytes()[9:], txout.GetScriptPubKeyWithFlags(0).GetData())
+}
+
+func TestTxOut_InvalidTokenPrefix(t *testing.T) {
+	// A zero fungible token amount is not a valid token prefix.
+	wrapped := append([]byte{token.PrefixToken}, make([]byte, 32)...)
+	wrapped = append(wrapped, 0x10, 0x00)
+	wrapped = append(wrapped, myscript...)
+
+	var buf bytes.Buffer
+	assert.NoError(t, NewTxOut(9, script.NewScriptRaw(wrapped)).Serialize(&buf))
+
+	txOutRead := &TxOut{}
+	assert.NoError(t, txOutRead.Unserialize(bytes.NewReader(buf.Bytes())))
+	assert.Nil(t, txOutRead.GetTokenData())
+	assert.Equal(t, wrapped, txOutRead.GetScriptPubKey().GetData())
+	assert.True(t, txOutRead.HasInvalidTokenPrefix())
+
+	// A valid token prefix may be followed by a locking script starting with
+	// the token prefix byte.
+	txout := NewTxOut(9, script.NewScriptRaw([]byte{token.PrefixToken}))
+	txout.SetTokenData(newTestTokenData())
+	buf.Reset()
+	assert.NoError(t, txout.Serialize(&buf))
+
+	txOutRead = &TxOut{}
+	assert.NoError(t, txOutRead.Unserialize(bytes.NewReader(buf.Bytes())))
+	assert.True(t, newTestTokenData().IsEqual(txOutRead.GetTokenData()))
+	assert.Equal(t, []byte{token.PrefixToken}, txOutRead.GetScriptPubKey().GetData())
+	assert.False(t, txOutRead.HasInvalidTokenPrefix())
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/token"
 	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
@@ -55,16 +56,28 @@ func (coin *Coin) GetScriptPubKey() *script.Script {
 	return txOut.GetScriptPubKey()
 }
 
+// GetScriptPubKeyWithFlags returns the locking script of the coin as seen
+// under the script flags, see txout.TxOut.GetScriptPubKeyWithFlags.
+func (coin *Coin) GetScriptPubKeyWithFlags(flags uint32) *script.Script {
+	return coin.txOut.GetScriptPubKeyWithFlags(flags)
+}
+
 func (coin *Coin) GetAmount() amount.Amount {
 	return coin.txOut.GetValue()
 }
 
+// GetTokenData returns the tokens of the coin, nil if there are none.
+func (coin *Coin) GetTokenData() *token.OutputData {
+	return coin.txOut.GetTokenData()
+}
+
 func (coin *Coin) DeepCopy() *Coin {
 	newCoin := Coin{height: coin.height, isCoinBase: coin.isCoinBase, dirty: coin.dirty, fresh: coin.fresh, isMempoolCoin: coin.isMempoolCoin}
 	outScript := coin.txOut.GetScriptPubKey()
 	if coin.txOut.GetScriptPubKey() != nil {
 		newOutScript := script.NewScriptRaw(outScript.GetData())
 		newOut := txout.NewTxOut(coin.txOut.GetValue(), newOutScript)
+		newOut.SetTokenData(coin.txOut.GetTokenData())
 		newCoin.txOut = *newOut
 	}
 	return &newCoin
@@ -9,7 +9,9 @@ import (
 	"bytes"
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/token"
 	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
 	"github.com/davecgh/go-spew/spew"
 	"reflect"
 )
@@ -121,6 +123,25 @@ func TestCoinSec(t *testing.T) {
 	}
 }
 
+func TestCoinTokenData(t *testing.T) {
+	scriptPK := script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+	out := txout.NewTxOut(amount.Amount(1000), scriptPK)
+	tokenData := &token.OutputData{Category: util.Hash{0x01}, HasNFT: true, Capability: token.Minting}
+	out.SetTokenData(tokenData)
+	coin := NewFreshCoin(out, 10, false)
+
+	assert.True(t, tokenData.IsEqual(coin.DeepCopy().GetTokenData()))
+
+	w := bytes.NewBuffer(nil)
+	assert.NoError(t, coin.Serialize(w))
+
+	var target Coin
+	assert.NoError(t, target.Unserialize(bytes.NewReader(w.Bytes())))
+	assert.True(t, tokenData.IsEqual(target.GetTokenData()))
+	assert.Equal(t, scriptPK.GetData(), target.GetScriptPubKey().GetData())
+	assert.Equal(t, amount.Amount(1000), target.GetAmount())
+}
+
 func TestMempoolCoin(t *testing.T) {
 	scriptM := script.NewEmptyScript()
 	txoutM := txout.NewTxOut(1, scriptM)
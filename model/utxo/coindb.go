@@ -9,6 +9,9 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
+// wipeBatchSize is the size of the batches erasing the coins in Wipe.
+const wipeBatchSize = 16 << 20
+
 type CoinsDB struct {
 	dbw *db.DBWrapper
 }
@@ -109,6 +112,43 @@ func (coinsViewDB *CoinsDB) BatchWrite(cm map[outpoint.OutPoint]*Coin, hashBlock
 	return ret
 }
 
+// IsSnapshotLoading reports whether a UTXO snapshot was being loaded into the
+// coins database, which then holds only part of its coins if the load did not
+// complete.
+func (coinsViewDB *CoinsDB) IsSnapshotLoading() bool {
+	return coinsViewDB.dbw.Exists([]byte{db.DbSnapshotLoading})
+}
+
+func (coinsViewDB *CoinsDB) WriteSnapshotLoading(loading bool) error {
+	if !loading {
+		return coinsViewDB.dbw.Erase([]byte{db.DbSnapshotLoading}, true)
+	}
+	return coinsViewDB.dbw.Write([]byte{db.DbSnapshotLoading}, []byte{1}, true)
+}
+
+// Wipe erases all the coins and the best block from the coins database. The
+// snapshot loading marker is erased last, once the coins are gone.
+func (coinsViewDB *CoinsDB) Wipe() error {
+	iter := coinsViewDB.dbw.Prefix([]byte{db.DbCoin})
+	defer iter.Close()
+
+	batch := db.NewBatchWrapper(coinsViewDB.dbw)
+	for iter.SeekToFirst(); iter.Valid(); iter.Next() {
+		batch.Erase(iter.GetKey())
+		if batch.SizeEstimate() > wipeBatchSize {
+			if err := coinsViewDB.dbw.WriteBatch(batch, false); err != nil {
+				return err
+			}
+			batch.Clear()
+		}
+	}
+	batch.Erase([]byte{db.DbBestBlock})
+	if err := coinsViewDB.dbw.WriteBatch(batch, true); err != nil {
+		return err
+	}
+	return coinsViewDB.WriteSnapshotLoading(false)
+}
+
 func (coinsViewDB *CoinsDB) EstimateSize() uint64 {
 	return coinsViewDB.dbw.EstimateSize([]byte{db.DbCoin}, []byte{db.DbCoin + 1})
 }
@@ -10,10 +10,15 @@ import (
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/util"
 	"runtime"
+	"unsafe"
 )
 
 type CoinsMap struct {
 	cacheCoins map[outpoint.OutPoint]*Coin
+	// base is consulted for coins missing from the map before the global
+	// coins cache, it lets a view be stacked on top of another one.
+	base      *CoinsMap
+	hashBlock util.Hash
 }
 
 func (cm *CoinsMap) GetMap() map[outpoint.OutPoint]*Coin {
@@ -28,12 +33,43 @@ func (cm *CoinsMap) DeepCopy() *CoinsMap {
 	return newcm
 }
 
+// DynamicMemoryUsage estimates the memory used by the coins of the map.
+func (cm *CoinsMap) DynamicMemoryUsage() int64 {
+	usage := int64(0)
+	for _, coin := range cm.cacheCoins {
+		usage += int64(unsafe.Sizeof(outpoint.OutPoint{}) + unsafe.Sizeof(*coin))
+		if scriptPubKey := coin.txOut.GetScriptPubKey(); scriptPubKey != nil {
+			usage += int64(len(scriptPubKey.Bytes()))
+		}
+	}
+	return usage
+}
+
 func NewEmptyCoinsMap() *CoinsMap {
 	cm := new(CoinsMap)
 	cm.cacheCoins = make(map[outpoint.OutPoint]*Coin)
 	return cm
 }
 
+// NewCoinsMapWithBase returns an empty view on top of base. The coins it
+// fetches are looked up in base before the global coins cache, and FlushToBase
+// writes its changes back to base.
+func NewCoinsMapWithBase(base *CoinsMap) *CoinsMap {
+	cm := NewEmptyCoinsMap()
+	cm.base = base
+	return cm
+}
+
+// GetBestBlock returns the hash of the block the view corresponds to, or a
+// null hash if the view has not been bound to a block.
+func (cm *CoinsMap) GetBestBlock() util.Hash {
+	return cm.hashBlock
+}
+
+func (cm *CoinsMap) SetBestBlock(hash util.Hash) {
+	cm.hashBlock = hash
+}
+
 func (cm *CoinsMap) AccessCoin(outpoint *outpoint.OutPoint) *Coin {
 	entry := cm.GetCoin(outpoint)
 	if entry == nil {
@@ -136,6 +172,19 @@ func (cm *CoinsMap) FetchCoin(out *outpoint.OutPoint) *Coin {
 	if coin != nil {
 		return coin
 	}
+	if cm.base != nil {
+		coin = cm.base.FetchCoin(out)
+		if coin == nil || coin.IsSpent() {
+			return nil
+		}
+		// The coin exists in the base view, spending it must leave a spent
+		// entry behind rather than dropping it.
+		newCoin := coin.DeepCopy()
+		newCoin.fresh = false
+		newCoin.dirty = false
+		cm.cacheCoins[*out] = newCoin
+		return newCoin
+	}
 	coin = GetUtxoCacheInstance().GetCoin(out)
 	if coin == nil {
 		_, file, line, _ := runtime.Caller(1)
@@ -150,6 +199,30 @@ func (cm *CoinsMap) FetchCoin(out *outpoint.OutPoint) *Coin {
 	return newCoin
 }
 
+// HaveCoin reports whether the view, its base or the global coins cache hold an
+// unspent coin for the outpoint.
+func (cm *CoinsMap) HaveCoin(out *outpoint.OutPoint) bool {
+	if coin := cm.GetCoin(out); coin != nil {
+		return !coin.IsSpent()
+	}
+	if cm.base != nil {
+		return cm.base.HaveCoin(out)
+	}
+	return GetUtxoCacheInstance().HaveCoin(out)
+}
+
+// FlushToBase moves the coins of the view into its base view.
+func (cm *CoinsMap) FlushToBase() {
+	if cm.base == nil {
+		panic("flush a coins map without base")
+	}
+	for point, coin := range cm.cacheCoins {
+		cm.base.cacheCoins[point] = coin
+	}
+	cm.base.hashBlock = cm.hashBlock
+	cm.cacheCoins = make(map[outpoint.OutPoint]*Coin)
+}
+
 // SpendGlobalCoin different from GetCoin, if not get coin, FetchCoin will get coin from global cache
 func (cm *CoinsMap) SpendGlobalCoin(out *outpoint.OutPoint) *Coin {
 	coin := cm.FetchCoin(out)
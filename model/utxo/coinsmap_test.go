@@ -91,6 +91,22 @@ func TestCoinsMap_DeepCopy(t *testing.T) {
 	assert.Equal(t, necm, cnecm)
 }
 
+func TestCoinsMap_DynamicMemoryUsage(t *testing.T) {
+	cm := NewEmptyCoinsMap()
+	assert.Equal(t, int64(0), cm.DynamicMemoryUsage())
+
+	point, coin := getTestCoin()
+	coin.fresh = false
+	cm.AddCoin(point, coin, false)
+	usage := cm.DynamicMemoryUsage()
+	assert.True(t, usage > 0)
+
+	// a spent coin which is not fresh stays in the map, without its script
+	cm.SpendCoin(point)
+	assert.True(t, cm.DynamicMemoryUsage() > 0)
+	assert.True(t, cm.DynamicMemoryUsage() < usage)
+}
+
 func TestCoinsMap_GetValueIn(t *testing.T) {
 	necm := NewEmptyCoinsMap()
 
@@ -168,6 +184,30 @@ func TestCoinsMap_spend_fresh_added_coin(t *testing.T) {
 	assert.Nil(t, cm.GetCoin(opt))
 }
 
+func TestCoinsMap_base(t *testing.T) {
+	base := NewEmptyCoinsMap()
+	opt := outpoint.NewOutPoint(util.HashOne, 0)
+	coin := NewFreshCoin(txout.NewTxOut(amount.Amount(50), script.NewEmptyScript()), 1, true)
+	base.AddCoin(opt, coin, false)
+	base.SetBestBlock(util.HashOne)
+
+	cm := NewCoinsMapWithBase(base)
+	assert.True(t, cm.HaveCoin(opt))
+	assert.Equal(t, amount.Amount(50), cm.FetchCoin(opt).GetAmount())
+
+	// spending the coin of the base leaves a spent entry in the view
+	assert.NotNil(t, cm.SpendCoin(opt))
+	assert.False(t, cm.HaveCoin(opt))
+	assert.True(t, base.HaveCoin(opt))
+
+	cm.SetBestBlock(util.HashZero)
+	cm.FlushToBase()
+	assert.Equal(t, 0, len(cm.GetMap()))
+	assert.False(t, base.HaveCoin(opt))
+	assert.Nil(t, NewCoinsMapWithBase(base).FetchCoin(opt))
+	assert.Equal(t, util.HashZero, base.GetBestBlock())
+}
+
 func TestCoinsMapGetValueIn(t *testing.T) {
 	cm := NewEmptyCoinsMap()
 
@@ -0,0 +1,52 @@
+package utxo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/copernet/copernicus/util"
+)
+
+// SnapshotVersion is the version of the UTXO snapshot format written by
+// dumptxoutset.
+const SnapshotVersion uint16 = 1
+
+var snapshotMagic = []byte{'u', 't', 'x', 'o', 0xff}
+
+var ErrSnapshotMagic = errors.New("not a UTXO snapshot file")
+
+// SnapshotMetadata is the header of a UTXO snapshot. It is followed by
+// CoinsCount pairs of a serialized outpoint and coin, in the order they are
+// stored in the coins database.
+type SnapshotMetadata struct {
+	BaseBlockHash util.Hash
+	CoinsCount    uint64
+	ChainTxCount  int32
+}
+
+func (sm *SnapshotMetadata) Serialize(w io.Writer) error {
+	if _, err := w.Write(snapshotMagic); err != nil {
+		return err
+	}
+	return util.WriteElements(w, SnapshotVersion, &sm.BaseBlockHash, sm.CoinsCount, sm.ChainTxCount)
+}
+
+func (sm *SnapshotMetadata) Unserialize(r io.Reader) error {
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return err
+	}
+	if !bytes.Equal(magic, snapshotMagic) {
+		return ErrSnapshotMagic
+	}
+	var version uint16
+	if err := util.ReadElements(r, &version); err != nil {
+		return err
+	}
+	if version != SnapshotVersion {
+		return fmt.Errorf("unsupported UTXO snapshot version %d", version)
+	}
+	return util.ReadElements(r, &sm.BaseBlockHash, &sm.CoinsCount, &sm.ChainTxCount)
+}
@@ -0,0 +1,32 @@
+package utxo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotMetadataSerialize(t *testing.T) {
+	metadata := &SnapshotMetadata{
+		BaseBlockHash: util.HashOne,
+		CoinsCount:    1234,
+		ChainTxCount:  567,
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, metadata.Serialize(buf))
+	raw := buf.Bytes()
+
+	unserialized := new(SnapshotMetadata)
+	assert.NoError(t, unserialized.Unserialize(bytes.NewReader(raw)))
+	assert.Equal(t, metadata, unserialized)
+
+	bad := append([]byte{}, raw...)
+	bad[0] = 'x'
+	assert.Equal(t, ErrSnapshotMagic, new(SnapshotMetadata).Unserialize(bytes.NewReader(bad)))
+
+	bad = append([]byte{}, raw...)
+	bad[len(snapshotMagic)] = 2
+	assert.Error(t, new(SnapshotMetadata).Unserialize(bytes.NewReader(bad)))
+}
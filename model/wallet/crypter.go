@@ -0,0 +1,162 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"errors"
+	"io"
+
+	"github.com/copernet/copernicus/util"
+)
+
+// The wallet encryption follows the scheme of Bitcoin Core: the private keys
+// are encrypted with AES-256-CBC under a random master key, and the master key
+// is itself encrypted under a key derived from the passphrase.
+const (
+	walletCryptoKeySize  = 32
+	walletCryptoSaltSize = 8
+	walletCryptoIVSize   = 16
+
+	// derivationMethodSHA512AES is the EVP_BytesToKey style derivation of
+	// the key and iv by iterating SHA-512 over the passphrase and salt.
+	derivationMethodSHA512AES = 0
+
+	// defaultDeriveIterations is the number of SHA-512 rounds used to derive
+	// the key from the passphrase.
+	defaultDeriveIterations = 25000
+
+	maxCryptedKeySize = 1024
+)
+
+var errInvalidPadding = errors.New("invalid padding")
+
+// MasterKey is the master key of an encrypted wallet, encrypted under a key
+// derived from the passphrase.
+type MasterKey struct {
+	CryptedKey       []byte
+	Salt             []byte
+	DerivationMethod uint32
+	DeriveIterations uint32
+}
+
+func (mk *MasterKey) Serialize(writer io.Writer) error {
+	if err := util.WriteVarBytes(writer, mk.CryptedKey); err != nil {
+		return err
+	}
+	if err := util.WriteVarBytes(writer, mk.Salt); err != nil {
+		return err
+	}
+	return util.WriteElements(writer, mk.DerivationMethod, mk.DeriveIterations)
+}
+
+func (mk *MasterKey) Unserialize(reader io.Reader) error {
+	var err error
+	if mk.CryptedKey, err = util.ReadVarBytes(reader, maxCryptedKeySize, "CryptedKey"); err != nil {
+		return err
+	}
+	if mk.Salt, err = util.ReadVarBytes(reader, maxCryptedKeySize, "Salt"); err != nil {
+		return err
+	}
+	return util.ReadElements(reader, &mk.DerivationMethod, &mk.DeriveIterations)
+}
+
+// deriveKey derives the key and iv used to encrypt the master key from the
+// passphrase.
+func (mk *MasterKey) deriveKey(passphrase []byte) ([]byte, []byte, error) {
+	if mk.DerivationMethod != derivationMethodSHA512AES || mk.DeriveIterations < 1 ||
+		len(mk.Salt) != walletCryptoSaltSize {
+		return nil, nil, errors.New("unsupported key derivation")
+	}
+
+	hasher := sha512.New()
+	hasher.Write(passphrase)
+	hasher.Write(mk.Salt)
+	buf := hasher.Sum(nil)
+	for i := uint32(1); i < mk.DeriveIterations; i++ {
+		sum := sha512.Sum512(buf)
+		buf = sum[:]
+	}
+
+	return buf[:walletCryptoKeySize], buf[walletCryptoKeySize : walletCryptoKeySize+walletCryptoIVSize], nil
+}
+
+// encrypt encrypts the plaintext master key under the passphrase.
+func (mk *MasterKey) encrypt(passphrase []byte, masterKey []byte) error {
+	key, iv, err := mk.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	mk.CryptedKey, err = encryptAES256CBC(key, iv, masterKey)
+	return err
+}
+
+// decrypt returns the plaintext master key.  A wrong passphrase is detected
+// by an invalid padding most of the time, the callers must still check the
+// master key against a known private key.
+func (mk *MasterKey) decrypt(passphrase []byte) ([]byte, error) {
+	key, iv, err := mk.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	masterKey, err := decryptAES256CBC(key, iv, mk.CryptedKey)
+	if err != nil || len(masterKey) != walletCryptoKeySize {
+		return nil, ErrWalletPassphraseIncorrect
+	}
+	return masterKey, nil
+}
+
+// encryptSecret encrypts a private key under the master key.  The iv is
+// derived from the public key, which is stored along with the encrypted key.
+func encryptSecret(masterKey []byte, secret []byte, pubKey []byte) ([]byte, error) {
+	iv := util.DoubleSha256Bytes(pubKey)[:walletCryptoIVSize]
+	return encryptAES256CBC(masterKey, iv, secret)
+}
+
+// decryptSecret decrypts a private key encrypted by encryptSecret.
+func decryptSecret(masterKey []byte, cryptedSecret []byte, pubKey []byte) ([]byte, error) {
+	iv := util.DoubleSha256Bytes(pubKey)[:walletCryptoIVSize]
+	return decryptAES256CBC(masterKey, iv, cryptedSecret)
+}
+
+func encryptAES256CBC(key, iv, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS#7 padding, as used by OpenSSL.
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := make([]byte, len(plaintext), len(plaintext)+padding)
+	copy(data, plaintext)
+	data = append(data, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	ciphertext := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, data)
+	return ciphertext, nil
+}
+
+func decryptAES256CBC(key, iv, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errInvalidPadding
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errInvalidPadding
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, errInvalidPadding
+		}
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
@@ -0,0 +1,221 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	hdChainVersion = 1
+	hdSeedSize     = 32
+
+	// Keys are derived along m/44'/145'/0'/chain/index, chain being 0 for
+	// receiving addresses and 1 for change.
+	bip44Purpose  = 44
+	bip44CoinType = 145
+	bip44Account  = 0
+	externalChain = 0
+	internalChain = 1
+
+	// hdSeedKeyPath marks the metadata of the seed key itself.
+	hdSeedKeyPath = "s"
+
+	maxKeyPathSize = 256
+)
+
+// HDChain records the seed of the deterministic key chains and how many keys
+// have been derived from each of them.
+type HDChain struct {
+	Version         uint32
+	SeedID          []byte
+	ExternalCounter uint32
+	InternalCounter uint32
+}
+
+func (hc *HDChain) Serialize(writer io.Writer) error {
+	if err := util.WriteElements(writer, hc.Version); err != nil {
+		return err
+	}
+	if err := util.WriteVarBytes(writer, hc.SeedID); err != nil {
+		return err
+	}
+	return util.WriteElements(writer, hc.ExternalCounter, hc.InternalCounter)
+}
+
+func (hc *HDChain) Unserialize(reader io.Reader) error {
+	var err error
+	if err = util.ReadElements(reader, &hc.Version); err != nil {
+		return err
+	}
+	if hc.SeedID, err = util.ReadVarBytes(reader, maxKeyPathSize, "SeedID"); err != nil {
+		return err
+	}
+	return util.ReadElements(reader, &hc.ExternalCounter, &hc.InternalCounter)
+}
+
+// KeyMetadata describes where a wallet key comes from.
+type KeyMetadata struct {
+	CreateTime    int64
+	HDKeyPath     string
+	HDMasterKeyID []byte
+}
+
+func (km *KeyMetadata) Serialize(writer io.Writer) error {
+	if err := util.WriteElements(writer, km.CreateTime); err != nil {
+		return err
+	}
+	if err := util.WriteVarString(writer, km.HDKeyPath); err != nil {
+		return err
+	}
+	return util.WriteVarBytes(writer, km.HDMasterKeyID)
+}
+
+func (km *KeyMetadata) Unserialize(reader io.Reader) error {
+	var err error
+	if err = util.ReadElements(reader, &km.CreateTime); err != nil {
+		return err
+	}
+	if km.HDKeyPath, err = util.ReadVarString(reader); err != nil {
+		return err
+	}
+	km.HDMasterKeyID, err = util.ReadVarBytes(reader, maxKeyPathSize, "HDMasterKeyID")
+	return err
+}
+
+// GetKeyMetadata returns the metadata of the key with the public key hash, or
+// nil for keys created before the wallet recorded it.
+func (w *Wallet) GetKeyMetadata(pubKeyHash []byte) *KeyMetadata {
+	w.keyLock.Lock()
+	defer w.keyLock.Unlock()
+
+	return w.keyMetadata[string(pubKeyHash)]
+}
+
+// GetHDMasterKeyID returns the hex id of the seed the wallet derives its keys
+// from, or an empty string if the wallet has no seed yet.
+func (w *Wallet) GetHDMasterKeyID() string {
+	w.keyLock.Lock()
+	defer w.keyLock.Unlock()
+
+	if w.hdChain == nil {
+		return ""
+	}
+	return hex.EncodeToString(w.hdChain.SeedID)
+}
+
+// setupHDChain generates a new random seed, stored as a regular wallet key so
+// that it is encrypted along with the other keys. keyLock must be held.
+func (w *Wallet) setupHDChain() error {
+	var seedKey *crypto.PrivateKey
+	for {
+		secret := make([]byte, hdSeedSize)
+		if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+			return err
+		}
+		seedKey = crypto.NewPrivateKeyFromBytes(secret, true)
+		if _, err := crypto.NewMasterExtendedKey(secret); err == nil && seedKey.PubKey() != nil {
+			break
+		}
+	}
+	if err := w.addKey(seedKey); err != nil {
+		return err
+	}
+
+	seedID := seedKey.PubKey().ToHash160()
+	metadata := &KeyMetadata{
+		CreateTime:    time.Now().Unix(),
+		HDKeyPath:     hdSeedKeyPath,
+		HDMasterKeyID: seedID,
+	}
+	if err := w.saveKeyMetadata(seedID, metadata); err != nil {
+		return err
+	}
+
+	hdChain := &HDChain{Version: hdChainVersion, SeedID: seedID}
+	if err := w.wdb.saveHDChain(hdChain); err != nil {
+		return err
+	}
+	w.hdChain = hdChain
+	return nil
+}
+
+// deriveNewKey derives the next unused key of the external or internal chain.
+// keyLock must be held.
+func (w *Wallet) deriveNewKey(internal bool) (*crypto.PrivateKey, *KeyMetadata, error) {
+	if w.hdChain == nil {
+		if err := w.setupHDChain(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	seedKeyPair := w.KeyStore.GetKeyPair(w.hdChain.SeedID)
+	if seedKeyPair == nil {
+		if w.IsLocked() {
+			return nil, nil, ErrWalletLocked
+		}
+		return nil, nil, errors.New("the hd seed is missing from the wallet")
+	}
+	master, err := crypto.NewMasterExtendedKey(seedKeyPair.GetPrivateKey().GetBytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chain := uint32(externalChain)
+	counter := &w.hdChain.ExternalCounter
+	if internal {
+		chain = internalChain
+		counter = &w.hdChain.InternalCounter
+	}
+	chainPath := []uint32{
+		bip44Purpose + crypto.HardenedKeyStart,
+		bip44CoinType + crypto.HardenedKeyStart,
+		bip44Account + crypto.HardenedKeyStart,
+		chain,
+	}
+	chainKey, err := master.DerivePath(chainPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		index := *counter
+		if index >= crypto.HardenedKeyStart {
+			return nil, nil, errors.New("the hd chain is exhausted")
+		}
+		*counter++
+
+		childKey, err := chainKey.Child(index)
+		if err == crypto.ErrInvalidChild {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		privateKey := childKey.PrivateKey()
+		if w.HaveKey(privateKey.PubKey().ToHash160()) {
+			continue
+		}
+
+		metadata := &KeyMetadata{
+			CreateTime:    time.Now().Unix(),
+			HDKeyPath:     crypto.FormatKeyPath(append(chainPath, index)),
+			HDMasterKeyID: w.hdChain.SeedID,
+		}
+		return privateKey, metadata, nil
+	}
+}
+
+// saveKeyMetadata records the metadata of a key. keyLock must be held.
+func (w *Wallet) saveKeyMetadata(pubKeyHash []byte, metadata *KeyMetadata) error {
+	if err := w.wdb.saveKeyMetadata(pubKeyHash, metadata); err != nil {
+		return err
+	}
+	w.keyMetadata[string(pubKeyHash)] = metadata
+	return nil
+}
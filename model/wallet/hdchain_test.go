@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHDChainSerialize(t *testing.T) {
+	hdChain := &HDChain{
+		Version:         hdChainVersion,
+		SeedID:          bytes.Repeat([]byte{0x11}, 20),
+		ExternalCounter: 7,
+		InternalCounter: 3,
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, hdChain.Serialize(buf))
+	unserialized := new(HDChain)
+	assert.NoError(t, unserialized.Unserialize(buf))
+	assert.Equal(t, hdChain, unserialized)
+
+	metadata := &KeyMetadata{
+		CreateTime:    1500000000,
+		HDKeyPath:     "m/44'/145'/0'/0/7",
+		HDMasterKeyID: hdChain.SeedID,
+	}
+	buf.Reset()
+	assert.NoError(t, metadata.Serialize(buf))
+	unserializedMetadata := new(KeyMetadata)
+	assert.NoError(t, unserializedMetadata.Unserialize(buf))
+	assert.Equal(t, metadata, unserializedMetadata)
+}
+
+func TestHDKeyDerivation(t *testing.T) {
+	w, teardown := newTestWallet(t)
+	defer teardown()
+
+	assert.Equal(t, "", w.GetHDMasterKeyID())
+	external0, err := w.GenerateNewKey(false)
+	assert.NoError(t, err)
+	external1, err := w.GenerateNewKey(false)
+	assert.NoError(t, err)
+	internal0, err := w.GenerateNewKey(true)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "", w.GetHDMasterKeyID())
+
+	seedID := w.hdChain.SeedID
+	assert.Equal(t, hdSeedKeyPath, w.GetKeyMetadata(seedID).HDKeyPath)
+	assert.Equal(t, "m/44'/145'/0'/0/0", w.GetKeyMetadata(external0.ToHash160()).HDKeyPath)
+	assert.Equal(t, "m/44'/145'/0'/0/1", w.GetKeyMetadata(external1.ToHash160()).HDKeyPath)
+	assert.Equal(t, "m/44'/145'/0'/1/0", w.GetKeyMetadata(internal0.ToHash160()).HDKeyPath)
+	assert.Equal(t, seedID, w.GetKeyMetadata(internal0.ToHash160()).HDMasterKeyID)
+
+	// The keys can be derived again from the seed alone.
+	seed := w.GetKeyPair(seedID).GetPrivateKey().GetBytes()
+	master, err := crypto.NewMasterExtendedKey(seed)
+	assert.NoError(t, err)
+	key, err := master.DerivePath([]uint32{
+		44 + crypto.HardenedKeyStart, 145 + crypto.HardenedKeyStart, crypto.HardenedKeyStart, 0, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, external1.ToBytes(), key.PrivateKey().PubKey().ToBytes())
+
+	hdChain, err := w.wdb.loadHDChain()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), hdChain.ExternalCounter)
+	assert.Equal(t, uint32(1), hdChain.InternalCounter)
+	assert.Equal(t, seedID, hdChain.SeedID)
+
+	keyMetadata, err := w.wdb.loadKeyMetadata()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(keyMetadata))
+	assert.Equal(t, w.GetKeyMetadata(external1.ToHash160()), keyMetadata[string(external1.ToHash160())])
+}
+
+func TestHDKeyDerivationLocked(t *testing.T) {
+	w, teardown := newTestWallet(t)
+	defer teardown()
+
+	_, err := w.GenerateNewKey(false)
+	assert.NoError(t, err)
+	assert.NoError(t, w.EncryptWallet([]byte("pass")))
+
+	// The seed is encrypted along with the other keys.
+	_, err = w.GenerateNewKey(false)
+	assert.Equal(t, ErrWalletLocked, err)
+
+	// Unlocking derives the three keys of the keypool first.
+	assert.NoError(t, w.Unlock([]byte("pass"), 0))
+	pubKey, err := w.GenerateNewKey(false)
+	assert.NoError(t, err)
+	assert.Equal(t, "m/44'/145'/0'/0/4", w.GetKeyMetadata(pubKey.ToHash160()).HDKeyPath)
+}
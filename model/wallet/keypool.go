@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/util"
+)
+
+// DefaultKeyPoolSize is the number of keys derived ahead of use on each of
+// the external and internal chains.
+const DefaultKeyPoolSize = 100
+
+var ErrKeyPoolRanOut = errors.New("keypool ran out, please call keypoolrefill first")
+
+// KeyPoolEntry is a key derived ahead of use, waiting in the keypool.
+type KeyPoolEntry struct {
+	Time     int64
+	Internal bool
+	PubKey   []byte
+}
+
+func (kpe *KeyPoolEntry) Serialize(writer io.Writer) error {
+	if err := util.WriteElements(writer, kpe.Time, kpe.Internal); err != nil {
+		return err
+	}
+	return util.WriteVarBytes(writer, kpe.PubKey)
+}
+
+func (kpe *KeyPoolEntry) Unserialize(reader io.Reader) error {
+	var err error
+	if err = util.ReadElements(reader, &kpe.Time, &kpe.Internal); err != nil {
+		return err
+	}
+	kpe.PubKey, err = util.ReadVarBytes(reader, maxKeyPathSize, "PubKey")
+	return err
+}
+
+// keyPoolKey is a keypool entry along with its position in the keypool.
+type keyPoolKey struct {
+	index  int64
+	pubKey *crypto.PublicKey
+}
+
+// loadKeyPool loads the keypool entries, ordered by index.
+func (w *Wallet) loadKeyPool() (int, error) {
+	indexes, entries, err := w.wdb.loadKeyPool()
+	if err != nil {
+		return 0, err
+	}
+	for i, entry := range entries {
+		pubKey, err := crypto.ParsePubKey(entry.PubKey)
+		if err != nil {
+			return 0, err
+		}
+		key := &keyPoolKey{index: indexes[i], pubKey: pubKey}
+		if entry.Internal {
+			w.internalKeyPool = append(w.internalKeyPool, key)
+		} else {
+			w.externalKeyPool = append(w.externalKeyPool, key)
+		}
+		if indexes[i] > w.maxKeyPoolIndex {
+			w.maxKeyPoolIndex = indexes[i]
+		}
+	}
+	return len(entries), nil
+}
+
+// GetKeyPoolSize returns the number of keys left in the external keypool.
+func (w *Wallet) GetKeyPoolSize() int {
+	w.keyLock.Lock()
+	defer w.keyLock.Unlock()
+
+	return len(w.externalKeyPool)
+}
+
+// TopUpKeyPool derives keys until both the external and internal keypools
+// hold at least size keys.  A zero size uses the configured keypool size.
+func (w *Wallet) TopUpKeyPool(size int) error {
+	w.keyLock.Lock()
+	defer w.keyLock.Unlock()
+
+	return w.topUpKeyPool(size)
+}
+
+func (w *Wallet) topUpKeyPool(size int) error {
+	if size <= 0 {
+		size = w.keyPoolSize
+	}
+	if size <= 0 {
+		size = DefaultKeyPoolSize
+	}
+	if w.IsLocked() {
+		return ErrWalletLocked
+	}
+
+	for _, internal := range []bool{false, true} {
+		pool := &w.externalKeyPool
+		if internal {
+			pool = &w.internalKeyPool
+		}
+		for len(*pool) < size {
+			pubKey, err := w.generateNewKey(internal)
+			if err != nil {
+				return err
+			}
+			entry := &KeyPoolEntry{
+				Time:     time.Now().Unix(),
+				Internal: internal,
+				PubKey:   pubKey.ToBytes(),
+			}
+			index := w.maxKeyPoolIndex + 1
+			if err := w.wdb.saveKeyPoolEntry(index, entry); err != nil {
+				log.Error("TopUpKeyPool save to db fail. error:%s", err.Error())
+				return err
+			}
+			w.maxKeyPoolIndex = index
+			*pool = append(*pool, &keyPoolKey{index: index, pubKey: pubKey})
+		}
+	}
+	return nil
+}
+
+// ReserveKeyFromKeyPool takes the oldest key of the external or internal
+// keypool, refilling the keypool if the wallet is unlocked.
+func (w *Wallet) ReserveKeyFromKeyPool(internal bool) (*crypto.PublicKey, error) {
+	w.keyLock.Lock()
+	defer w.keyLock.Unlock()
+
+	if err := w.topUpKeyPool(0); err != nil && err != ErrWalletLocked {
+		return nil, err
+	}
+
+	pool := &w.externalKeyPool
+	if internal {
+		pool = &w.internalKeyPool
+	}
+	if len(*pool) == 0 {
+		return nil, ErrKeyPoolRanOut
+	}
+
+	key := (*pool)[0]
+	if err := w.wdb.eraseKeyPoolEntry(key.index); err != nil {
+		log.Error("ReserveKeyFromKeyPool erase from db fail. error:%s", err.Error())
+		return nil, err
+	}
+	*pool = (*pool)[1:]
+
+	if err := w.topUpKeyPool(0); err != nil && err != ErrWalletLocked {
+		log.Warn("ReserveKeyFromKeyPool top up keypool fail. error:%s", err.Error())
+	}
+	return key.pubKey, nil
+}
@@ -0,0 +1,81 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPoolEntrySerialize(t *testing.T) {
+	entry := &KeyPoolEntry{
+		Time:     1500000000,
+		Internal: true,
+		PubKey:   bytes.Repeat([]byte{0x02}, 33),
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, entry.Serialize(buf))
+	unserialized := new(KeyPoolEntry)
+	assert.NoError(t, unserialized.Unserialize(buf))
+	assert.Equal(t, entry, unserialized)
+}
+
+func TestKeyPool(t *testing.T) {
+	w, teardown := newTestWallet(t)
+	defer teardown()
+
+	assert.NoError(t, w.TopUpKeyPool(0))
+	assert.Equal(t, 3, w.GetKeyPoolSize())
+	assert.Equal(t, 3, len(w.internalKeyPool))
+
+	// Keys are handed out in derivation order and the keypool is refilled.
+	pubKey, err := w.ReserveKeyFromKeyPool(false)
+	assert.NoError(t, err)
+	assert.Equal(t, "m/44'/145'/0'/0/0", w.GetKeyMetadata(pubKey.ToHash160()).HDKeyPath)
+	assert.Equal(t, 3, w.GetKeyPoolSize())
+	changeKey, err := w.ReserveKeyFromKeyPool(true)
+	assert.NoError(t, err)
+	assert.Equal(t, "m/44'/145'/0'/1/0", w.GetKeyMetadata(changeKey.ToHash160()).HDKeyPath)
+
+	assert.NoError(t, w.TopUpKeyPool(5))
+	assert.Equal(t, 5, w.GetKeyPoolSize())
+
+	// The keypool survives a reload.
+	reloaded := &Wallet{wdb: w.wdb}
+	count, err := reloaded.loadKeyPool()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, count)
+	assert.Equal(t, len(w.externalKeyPool), len(reloaded.externalKeyPool))
+	for i, key := range w.externalKeyPool {
+		assert.Equal(t, key.index, reloaded.externalKeyPool[i].index)
+		assert.Equal(t, key.pubKey.ToBytes(), reloaded.externalKeyPool[i].pubKey.ToBytes())
+	}
+	assert.Equal(t, w.maxKeyPoolIndex, reloaded.maxKeyPoolIndex)
+}
+
+func TestKeyPoolLocked(t *testing.T) {
+	w, teardown := newTestWallet(t)
+	defer teardown()
+
+	assert.NoError(t, w.TopUpKeyPool(0))
+	assert.NoError(t, w.EncryptWallet([]byte("pass")))
+	assert.Equal(t, ErrWalletLocked, w.TopUpKeyPool(0))
+
+	// A locked wallet still hands out the keys of the keypool.
+	var pubKey *crypto.PublicKey
+	for i := 0; i < 3; i++ {
+		var err error
+		pubKey, err = w.ReserveKeyFromKeyPool(false)
+		assert.NoError(t, err)
+	}
+	assert.True(t, w.HaveKey(pubKey.ToHash160()))
+	_, err := w.ReserveKeyFromKeyPool(false)
+	assert.Equal(t, ErrKeyPoolRanOut, err)
+
+	assert.NoError(t, w.Unlock([]byte("pass"), 0))
+	assert.Equal(t, 3, w.GetKeyPoolSize())
+	pubKey, err = w.ReserveKeyFromKeyPool(false)
+	assert.NoError(t, err)
+	assert.Equal(t, "m/44'/145'/0'/0/3", w.GetKeyMetadata(pubKey.ToHash160()).HDKeyPath)
+}
@@ -3,9 +3,8 @@
 package wallet
 
 import (
-	"crypto/rand"
-	"io"
 	"sync"
+	"time"
 
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/crypto"
@@ -33,6 +32,25 @@ type Wallet struct {
 	payTxFee     *util.FeeRate
 	wdb          WalletDB
 
+	// cryptLock protects the encryption state of the wallet. The master
+	// key is nil if the wallet is not encrypted, and the plaintext master
+	// key is nil while the wallet is locked.
+	cryptLock      sync.Mutex
+	masterKey      *MasterKey
+	plainMasterKey []byte
+	cryptedKeys    map[string]*cryptedKey
+	relockTimer    *time.Timer
+
+	// keyLock protects the deterministic key chains, the key metadata and
+	// the keypool. It is taken before cryptLock.
+	keyLock         sync.Mutex
+	keyPoolSize     int
+	hdChain         *HDChain
+	keyMetadata     map[string]*KeyMetadata
+	externalKeyPool []*keyPoolKey
+	internalKeyPool []*keyPoolKey
+	maxKeyPoolIndex int64
+
 	*crypto.KeyStore
 	*ScriptStore
 	*AddressBook
@@ -47,6 +65,10 @@ var globalWallet *Wallet
  */
 var fallbackFee = util.NewFeeRate(20000)
 
+// txConfirmTarget is the number of blocks the wallet aims at for its
+// transactions to be confirmed when estimating fees.
+const txConfirmTarget = 6
+
 func InitWallet() {
 	defer func() {
 		if globalWallet == nil {
@@ -65,6 +87,7 @@ func InitWallet() {
 		walletTxns:  make(map[util.Hash]*WalletTx),
 		lockedCoins: make(map[outpoint.OutPoint]struct{}),
 		payTxFee:    util.NewFeeRate(0),
+		keyPoolSize: conf.Cfg.Wallet.KeyPool,
 	}
 
 	if err := walletInstance.Init(); err != nil {
@@ -87,12 +110,20 @@ func (w *Wallet) Init() error {
 	w.KeyStore = crypto.NewKeyStore()
 	w.ScriptStore = NewScriptStore()
 	w.AddressBook = NewAddressBook()
+	w.cryptedKeys = make(map[string]*cryptedKey)
+	w.keyMetadata = make(map[string]*KeyMetadata)
 
 	w.wdb.initDB()
 	if err := w.loadFromDB(); err != nil {
 		log.Error("Load wallet fail. error:" + err.Error())
 		return err
 	}
+
+	// A locked wallet tops up its keypool once it is unlocked.
+	if err := w.TopUpKeyPool(0); err != nil && err != ErrWalletLocked {
+		log.Error("Top up keypool fail. error:" + err.Error())
+		return err
+	}
 	return nil
 }
 
@@ -107,6 +138,22 @@ func (w *Wallet) loadFromDB() error {
 		w.KeyStore.AddKey(privateKey)
 	}
 
+	cryptedKeys, err := w.loadCryptedKeys()
+	if err != nil {
+		return err
+	}
+
+	if w.hdChain, err = w.wdb.loadHDChain(); err != nil {
+		return err
+	}
+	if w.keyMetadata, err = w.wdb.loadKeyMetadata(); err != nil {
+		return err
+	}
+	keyPoolSize, err := w.loadKeyPool()
+	if err != nil {
+		return err
+	}
+
 	scripts, err := w.wdb.loadScripts()
 	if err != nil {
 		return err
@@ -131,28 +178,56 @@ func (w *Wallet) loadFromDB() error {
 	for _, wtx := range transactions {
 		w.walletTxns[wtx.Tx.GetHash()] = wtx
 	}
-	log.Info("load wallet from db successfully. keys:%v, scripts:%v, addressbook:%v, txns:%v",
-		len(secrets), len(scripts), len(addressBook), len(transactions))
+	log.Info("load wallet from db successfully. keys:%v, crypted keys:%v, keypool:%v, scripts:%v, "+
+		"addressbook:%v, txns:%v", len(secrets), cryptedKeys, keyPoolSize, len(scripts), len(addressBook),
+		len(transactions))
 	return nil
 }
 
-func (w *Wallet) GenerateNewKey() (*crypto.PublicKey, error) {
-	secret := make([]byte, 32)
-	io.ReadFull(rand.Reader, secret)
-	privateKey := crypto.NewPrivateKeyFromBytes(secret, true)
-	w.AddKey(privateKey)
-	err := w.wdb.saveSecret(secret)
+// GenerateNewKey derives the next key of the external or internal chain,
+// bypassing the keypool.
+func (w *Wallet) GenerateNewKey(internal bool) (*crypto.PublicKey, error) {
+	w.keyLock.Lock()
+	defer w.keyLock.Unlock()
+
+	return w.generateNewKey(internal)
+}
+
+func (w *Wallet) generateNewKey(internal bool) (*crypto.PublicKey, error) {
+	privateKey, metadata, err := w.deriveNewKey(internal)
+	if err == ErrWalletLocked {
+		return nil, err
+	}
+	if err != nil {
+		log.Error("GenerateNewKey derive key fail. error:%s", err.Error())
+		return nil, err
+	}
+
+	err = w.addKey(privateKey)
+	if err == ErrWalletLocked {
+		return nil, err
+	}
 	if err != nil {
 		log.Error("GenerateNewKey save to db fail. error:%s", err.Error())
 		return nil, err
 	}
-	return privateKey.PubKey(), nil
+
+	pubKey := privateKey.PubKey()
+	if err := w.saveKeyMetadata(pubKey.ToHash160(), metadata); err != nil {
+		log.Error("GenerateNewKey save metadata to db fail. error:%s", err.Error())
+		return nil, err
+	}
+	if err := w.wdb.saveHDChain(w.hdChain); err != nil {
+		log.Error("GenerateNewKey save hd chain to db fail. error:%s", err.Error())
+		return nil, err
+	}
+	return pubKey, nil
 }
 
-func (w *Wallet) GetReservedKey() (*crypto.PublicKey, error) {
-	// wallet function is only for testing. The keypool is not supported yet.
-	// generate new key each time
-	reservedKey, err := w.GenerateNewKey()
+// GetReservedKey takes a key from the keypool for a transaction output or a
+// coinbase, using the internal chain for change.
+func (w *Wallet) GetReservedKey(internal bool) (*crypto.PublicKey, error) {
+	reservedKey, err := w.ReserveKeyFromKeyPool(internal)
 	if err != nil {
 		return nil, err
 	}
@@ -327,14 +402,20 @@ func (w *Wallet) GetMinimumFee(byteSize int) int64 {
 	feeNeeded := w.payTxFee.GetFee(byteSize)
 	// User didn't set tx fee
 	if feeNeeded == 0 {
-		minFeeRate := mempool.GetInstance().GetMinFeeRate()
-		feeNeeded = minFeeRate.GetFee(byteSize)
+		feeRate, _, err := mempool.GetFeeEstimator().EstimateSmartFee(txConfirmTarget)
+		if err == nil {
+			feeNeeded = feeRate.GetFee(byteSize)
+		}
 
 		// ... unless we don't have enough mempool data for estimatefee, then
 		// use fallbackFee.
 		if feeNeeded == 0 {
 			feeNeeded = fallbackFee.GetFee(byteSize)
 		}
+
+		// Obey the mempool min fee when using the estimation.
+		minFeeRate := mempool.GetInstance().GetMinFeeRate()
+		feeNeeded = util.MaxI(feeNeeded, minFeeRate.GetFee(byteSize))
 	}
 
 	// Prevent user from paying a fee below minRelayTxFee or minTxFee.
@@ -387,10 +468,10 @@ func IsUnlockable(scriptPubKey *script.Script) bool {
 
 	if pubKeyType == script.ScriptPubkey {
 		pubKeyHash := util.Hash160(pubKeys[0])
-		return globalWallet.GetKeyPair(pubKeyHash) != nil
+		return globalWallet.HaveKey(pubKeyHash)
 
 	} else if pubKeyType == script.ScriptPubkeyHash {
-		return globalWallet.GetKeyPair(pubKeys[0]) != nil
+		return globalWallet.HaveKey(pubKeys[0])
 
 	} else if pubKeyType == script.ScriptMultiSig {
 		// Only consider transactions "mine" if we own ALL the keys
@@ -401,7 +482,7 @@ func IsUnlockable(scriptPubKey *script.Script) bool {
 		for _, pubKey := range pubKeys {
 			if len(pubKey) >= 32 {
 				pubKeyHash := util.Hash160(pubKey)
-				if globalWallet.GetKeyPair(pubKeyHash) == nil {
+				if !globalWallet.HaveKey(pubKeyHash) {
 					return false
 				}
 			}
@@ -0,0 +1,311 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/log"
+)
+
+var (
+	ErrWalletLocked              = errors.New("wallet is locked")
+	ErrWalletNotEncrypted        = errors.New("wallet is not encrypted")
+	ErrWalletAlreadyEncrypted    = errors.New("wallet is already encrypted")
+	ErrWalletPassphraseIncorrect = errors.New("the wallet passphrase entered was incorrect")
+)
+
+// cryptedKey is a private key encrypted under the master key.
+type cryptedKey struct {
+	pubKey *crypto.PublicKey
+	secret []byte
+}
+
+// IsCrypted returns whether the private keys of the wallet are encrypted.
+func (w *Wallet) IsCrypted() bool {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	return w.masterKey != nil
+}
+
+// IsLocked returns whether the wallet is encrypted and its private keys are
+// not available.
+func (w *Wallet) IsLocked() bool {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	return w.masterKey != nil && w.plainMasterKey == nil
+}
+
+// HaveKey returns whether the wallet owns the private key of the public key
+// hash, even if the wallet is locked.
+func (w *Wallet) HaveKey(pubKeyHash []byte) bool {
+	if w.KeyStore.GetKeyPair(pubKeyHash) != nil {
+		return true
+	}
+
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	_, ok := w.cryptedKeys[string(pubKeyHash)]
+	return ok
+}
+
+// GetPubKey returns the public key of the public key hash if the wallet
+// owns its private key, even if the wallet is locked.
+func (w *Wallet) GetPubKey(pubKeyHash []byte) *crypto.PublicKey {
+	if keyPair := w.KeyStore.GetKeyPair(pubKeyHash); keyPair != nil {
+		return keyPair.GetPublicKey()
+	}
+
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if key, ok := w.cryptedKeys[string(pubKeyHash)]; ok {
+		return key.pubKey
+	}
+	return nil
+}
+
+// loadCryptedKeys loads the encryption state of the wallet.  An encrypted
+// wallet starts locked.
+func (w *Wallet) loadCryptedKeys() (int, error) {
+	masterKey, err := w.wdb.loadMasterKey()
+	if err != nil {
+		return 0, err
+	}
+
+	cryptedKeys := w.wdb.loadCryptedKeys()
+	for pubKeyBytes, secret := range cryptedKeys {
+		pubKey, err := crypto.ParsePubKey([]byte(pubKeyBytes))
+		if err != nil {
+			return 0, err
+		}
+		w.cryptedKeys[string(pubKey.ToHash160())] = &cryptedKey{pubKey: pubKey, secret: secret}
+	}
+
+	if masterKey == nil && len(cryptedKeys) > 0 {
+		return 0, errors.New("wallet has encrypted keys but no master key")
+	}
+	w.masterKey = masterKey
+	return len(cryptedKeys), nil
+}
+
+// EncryptWallet encrypts the private keys of the wallet under the passphrase
+// and locks the wallet.
+func (w *Wallet) EncryptWallet(passphrase []byte) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if w.masterKey != nil {
+		return ErrWalletAlreadyEncrypted
+	}
+
+	plainMasterKey := make([]byte, walletCryptoKeySize)
+	if _, err := io.ReadFull(rand.Reader, plainMasterKey); err != nil {
+		return err
+	}
+	masterKey := &MasterKey{
+		Salt:             make([]byte, walletCryptoSaltSize),
+		DerivationMethod: derivationMethodSHA512AES,
+		DeriveIterations: defaultDeriveIterations,
+	}
+	if _, err := io.ReadFull(rand.Reader, masterKey.Salt); err != nil {
+		return err
+	}
+	if err := masterKey.encrypt(passphrase, plainMasterKey); err != nil {
+		return err
+	}
+
+	keyPairs := w.KeyStore.GetAllKeyPairs()
+	secrets := make([][]byte, 0, len(keyPairs))
+	pubKeys := make([][]byte, 0, len(keyPairs))
+	cryptedSecrets := make([][]byte, 0, len(keyPairs))
+	for _, keyPair := range keyPairs {
+		secret := keyPair.GetPrivateKey().GetBytes()
+		pubKey := keyPair.GetPublicKey().ToBytes()
+		cryptedSecret, err := encryptSecret(plainMasterKey, secret, pubKey)
+		if err != nil {
+			return err
+		}
+		secrets = append(secrets, secret)
+		pubKeys = append(pubKeys, pubKey)
+		cryptedSecrets = append(cryptedSecrets, cryptedSecret)
+	}
+
+	if err := w.wdb.encryptKeys(secrets, pubKeys, cryptedSecrets, masterKey); err != nil {
+		log.Error("EncryptWallet save to db fail. error:%s", err.Error())
+		return err
+	}
+
+	for i, keyPair := range keyPairs {
+		w.cryptedKeys[keyPair.GetKeyID()] = &cryptedKey{
+			pubKey: keyPair.GetPublicKey(),
+			secret: cryptedSecrets[i],
+		}
+	}
+	w.masterKey = masterKey
+	w.lock()
+
+	log.Info("wallet encrypted. keys:%d", len(keyPairs))
+	return nil
+}
+
+// Unlock decrypts the private keys of the wallet with the passphrase and tops
+// up the keypool.  The wallet is locked again after the timeout, unless it is
+// zero.
+func (w *Wallet) Unlock(passphrase []byte, timeout time.Duration) error {
+	if err := w.unlock(passphrase, timeout); err != nil {
+		return err
+	}
+	if err := w.TopUpKeyPool(0); err != nil && err != ErrWalletLocked {
+		log.Error("Unlock top up keypool fail. error:%s", err.Error())
+	}
+	return nil
+}
+
+func (w *Wallet) unlock(passphrase []byte, timeout time.Duration) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if w.masterKey == nil {
+		return ErrWalletNotEncrypted
+	}
+
+	plainMasterKey, keyPairs, err := w.decryptKeys(passphrase)
+	if err != nil {
+		return err
+	}
+
+	w.KeyStore.AddKeyPairs(keyPairs)
+	w.plainMasterKey = plainMasterKey
+
+	if w.relockTimer != nil {
+		w.relockTimer.Stop()
+		w.relockTimer = nil
+	}
+	if timeout > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(timeout, func() {
+			w.cryptLock.Lock()
+			defer w.cryptLock.Unlock()
+
+			// Ignore a timer replaced by a later unlock.
+			if w.relockTimer == timer {
+				w.lock()
+			}
+		})
+		w.relockTimer = timer
+	}
+	return nil
+}
+
+// Lock removes the decrypted private keys from memory.
+func (w *Wallet) Lock() error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if w.masterKey == nil {
+		return ErrWalletNotEncrypted
+	}
+	w.lock()
+	return nil
+}
+
+func (w *Wallet) lock() {
+	if w.relockTimer != nil {
+		w.relockTimer.Stop()
+		w.relockTimer = nil
+	}
+	w.plainMasterKey = nil
+	w.KeyStore.Clear()
+}
+
+// ChangePassphrase encrypts the master key under a new passphrase.  The lock
+// state of the wallet is left unchanged.
+func (w *Wallet) ChangePassphrase(oldPassphrase []byte, newPassphrase []byte) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if w.masterKey == nil {
+		return ErrWalletNotEncrypted
+	}
+
+	plainMasterKey, _, err := w.decryptKeys(oldPassphrase)
+	if err != nil {
+		return err
+	}
+
+	masterKey := &MasterKey{
+		Salt:             make([]byte, walletCryptoSaltSize),
+		DerivationMethod: derivationMethodSHA512AES,
+		DeriveIterations: w.masterKey.DeriveIterations,
+	}
+	if _, err := io.ReadFull(rand.Reader, masterKey.Salt); err != nil {
+		return err
+	}
+	if err := masterKey.encrypt(newPassphrase, plainMasterKey); err != nil {
+		return err
+	}
+	if err := w.wdb.saveMasterKey(masterKey); err != nil {
+		log.Error("ChangePassphrase save to db fail. error:%s", err.Error())
+		return err
+	}
+	w.masterKey = masterKey
+	return nil
+}
+
+// decryptKeys decrypts the master key and all the private keys, checking the
+// decrypted private keys match their public keys.
+func (w *Wallet) decryptKeys(passphrase []byte) ([]byte, []*crypto.KeyPair, error) {
+	plainMasterKey, err := w.masterKey.decrypt(passphrase)
+	if err != nil {
+		return nil, nil, ErrWalletPassphraseIncorrect
+	}
+
+	keyPairs := make([]*crypto.KeyPair, 0, len(w.cryptedKeys))
+	for _, key := range w.cryptedKeys {
+		secret, err := decryptSecret(plainMasterKey, key.secret, key.pubKey.ToBytes())
+		if err != nil {
+			return nil, nil, ErrWalletPassphraseIncorrect
+		}
+		privateKey := crypto.NewPrivateKeyFromBytes(secret, key.pubKey.Compressed)
+		pubKey := privateKey.PubKey()
+		if pubKey == nil || !bytes.Equal(pubKey.ToBytes(), key.pubKey.ToBytes()) {
+			return nil, nil, ErrWalletPassphraseIncorrect
+		}
+		keyPairs = append(keyPairs, crypto.NewKeyPair(privateKey))
+	}
+	return plainMasterKey, keyPairs, nil
+}
+
+// addKey adds a new private key to the wallet, encrypting it if the wallet is
+// encrypted.
+func (w *Wallet) addKey(privateKey *crypto.PrivateKey) error {
+	w.cryptLock.Lock()
+	defer w.cryptLock.Unlock()
+
+	if w.masterKey == nil {
+		w.KeyStore.AddKey(privateKey)
+		return w.wdb.saveSecret(privateKey.GetBytes())
+	}
+
+	if w.plainMasterKey == nil {
+		return ErrWalletLocked
+	}
+	pubKey := privateKey.PubKey()
+	cryptedSecret, err := encryptSecret(w.plainMasterKey, privateKey.GetBytes(), pubKey.ToBytes())
+	if err != nil {
+		return err
+	}
+	if err := w.wdb.saveCryptedKey(pubKey.ToBytes(), cryptedSecret); err != nil {
+		return err
+	}
+	w.cryptedKeys[string(pubKey.ToHash160())] = &cryptedKey{pubKey: pubKey, secret: cryptedSecret}
+	w.KeyStore.AddKey(privateKey)
+	return nil
+}
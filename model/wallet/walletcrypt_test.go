@@ -0,0 +1,203 @@
+package wallet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasterKey(t *testing.T) {
+	plainMasterKey := bytes.Repeat([]byte{0x42}, walletCryptoKeySize)
+	masterKey := &MasterKey{
+		Salt:             bytes.Repeat([]byte{0x01}, walletCryptoSaltSize),
+		DerivationMethod: derivationMethodSHA512AES,
+		DeriveIterations: 100,
+	}
+	assert.NoError(t, masterKey.encrypt([]byte("passphrase"), plainMasterKey))
+	assert.Equal(t, 48, len(masterKey.CryptedKey))
+
+	decrypted, err := masterKey.decrypt([]byte("passphrase"))
+	assert.NoError(t, err)
+	assert.Equal(t, plainMasterKey, decrypted)
+
+	_, err = masterKey.decrypt([]byte("wrong"))
+	assert.Equal(t, ErrWalletPassphraseIncorrect, err)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, masterKey.Serialize(buf))
+	unserialized := new(MasterKey)
+	assert.NoError(t, unserialized.Unserialize(buf))
+	assert.Equal(t, masterKey, unserialized)
+
+	masterKey.DerivationMethod = 1
+	_, err = masterKey.decrypt([]byte("passphrase"))
+	assert.Error(t, err)
+}
+
+func TestEncryptSecret(t *testing.T) {
+	plainMasterKey := bytes.Repeat([]byte{0x42}, walletCryptoKeySize)
+	secret := bytes.Repeat([]byte{0x07}, 32)
+	pubKey := []byte{0x02, 0x03}
+
+	cryptedSecret, err := encryptSecret(plainMasterKey, secret, pubKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 48, len(cryptedSecret))
+	assert.NotEqual(t, secret, cryptedSecret[:32])
+
+	decrypted, err := decryptSecret(plainMasterKey, cryptedSecret, pubKey)
+	assert.NoError(t, err)
+	assert.Equal(t, secret, decrypted)
+
+	// The iv depends on the public key.
+	decrypted, _ = decryptSecret(plainMasterKey, cryptedSecret, []byte{0x02, 0x04})
+	assert.NotEqual(t, secret, decrypted)
+
+	_, err = decryptAES256CBC(plainMasterKey, make([]byte, walletCryptoIVSize), []byte{1, 2, 3})
+	assert.Equal(t, errInvalidPadding, err)
+}
+
+func newTestWallet(t *testing.T) (*Wallet, func()) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	w := newTestWalletInDir(t, dir)
+	return w, func() {
+		w.wdb.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestWalletInDir(t *testing.T, dir string) *Wallet {
+	var err error
+	w := &Wallet{
+		enable:      true,
+		KeyStore:    crypto.NewKeyStore(),
+		ScriptStore: NewScriptStore(),
+		AddressBook: NewAddressBook(),
+		cryptedKeys: make(map[string]*cryptedKey),
+		keyMetadata: make(map[string]*KeyMetadata),
+		keyPoolSize: 3,
+	}
+	w.wdb.DBWrapper, err = db.NewDBWrapper(&db.DBOption{FilePath: dir, CacheSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("open wallet db: %v", err)
+	}
+	return w
+}
+
+func TestWalletEncryption(t *testing.T) {
+	w, teardown := newTestWallet(t)
+	defer teardown()
+
+	pubKey, err := w.GenerateNewKey(false)
+	assert.NoError(t, err)
+	keyHash := pubKey.ToHash160()
+	assert.False(t, w.IsCrypted())
+	assert.False(t, w.IsLocked())
+	assert.Equal(t, ErrWalletNotEncrypted, w.Lock())
+	assert.Equal(t, ErrWalletNotEncrypted, w.Unlock([]byte("pass"), 0))
+
+	assert.NoError(t, w.EncryptWallet([]byte("pass")))
+	assert.Equal(t, ErrWalletAlreadyEncrypted, w.EncryptWallet([]byte("pass")))
+	assert.True(t, w.IsCrypted())
+	assert.True(t, w.IsLocked())
+	assert.Equal(t, 0, len(w.wdb.loadSecrets()))
+
+	// The keys are still known while locked, but not usable.
+	assert.True(t, w.HaveKey(keyHash))
+	assert.Equal(t, pubKey.ToBytes(), w.GetPubKey(keyHash).ToBytes())
+	assert.Nil(t, w.GetKeyPair(keyHash))
+	_, err = w.GenerateNewKey(false)
+	assert.Equal(t, ErrWalletLocked, err)
+
+	assert.Equal(t, ErrWalletPassphraseIncorrect, w.Unlock([]byte("wrong"), 0))
+	assert.True(t, w.IsLocked())
+
+	assert.NoError(t, w.Unlock([]byte("pass"), 0))
+	assert.False(t, w.IsLocked())
+	assert.NotNil(t, w.GetKeyPair(keyHash))
+	newPubKey, err := w.GenerateNewKey(false)
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.Lock())
+	assert.True(t, w.IsLocked())
+	assert.Nil(t, w.GetKeyPair(keyHash))
+	assert.True(t, w.HaveKey(newPubKey.ToHash160()))
+
+	assert.Equal(t, ErrWalletPassphraseIncorrect, w.ChangePassphrase([]byte("wrong"), []byte("new")))
+	assert.NoError(t, w.ChangePassphrase([]byte("pass"), []byte("new")))
+	assert.True(t, w.IsLocked())
+	assert.Equal(t, ErrWalletPassphraseIncorrect, w.Unlock([]byte("pass"), 0))
+
+	// The encryption state survives a reload, starting locked.
+	reloaded := &Wallet{
+		KeyStore:    crypto.NewKeyStore(),
+		cryptedKeys: make(map[string]*cryptedKey),
+		keyMetadata: make(map[string]*KeyMetadata),
+		keyPoolSize: 3,
+		wdb:         w.wdb,
+	}
+	count, err := reloaded.loadCryptedKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, len(w.cryptedKeys), count)
+	reloaded.hdChain, err = reloaded.wdb.loadHDChain()
+	assert.NoError(t, err)
+	assert.True(t, reloaded.IsLocked())
+	assert.NoError(t, reloaded.Unlock([]byte("new"), 0))
+	assert.NotNil(t, reloaded.GetKeyPair(keyHash))
+	assert.NotNil(t, reloaded.GetKeyPair(newPubKey.ToHash160()))
+}
+
+func TestWalletEncryptionErasesPlaintextKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	w := newTestWalletInDir(t, dir)
+	defer w.wdb.Close()
+
+	_, err = w.GenerateNewKey(false)
+	assert.NoError(t, err)
+	secrets := w.wdb.loadSecrets()
+	assert.NotEmpty(t, secrets)
+	assert.NoError(t, w.EncryptWallet([]byte("pass")))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		assert.NoError(t, err)
+		for _, secret := range secrets {
+			assert.False(t, bytes.Contains(data, secret), "plaintext key left in %s", file.Name())
+		}
+	}
+}
+
+func TestWalletRelock(t *testing.T) {
+	w, teardown := newTestWallet(t)
+	defer teardown()
+
+	_, err := w.GenerateNewKey(false)
+	assert.NoError(t, err)
+	assert.NoError(t, w.EncryptWallet([]byte("pass")))
+
+	assert.NoError(t, w.Unlock([]byte("pass"), 50*time.Millisecond))
+	assert.False(t, w.IsLocked())
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, w.IsLocked())
+
+	// A later unlock without timeout cancels the relock.
+	assert.NoError(t, w.Unlock([]byte("pass"), 50*time.Millisecond))
+	assert.NoError(t, w.Unlock([]byte("pass"), 0))
+	time.Sleep(200 * time.Millisecond)
+	assert.False(t, w.IsLocked())
+}
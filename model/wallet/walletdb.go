@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"bytes"
+	"encoding/binary"
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/persist/db"
@@ -37,6 +38,95 @@ func (wdb *WalletDB) loadSecrets() [][]byte {
 	return secrets
 }
 
+// loadCryptedKeys returns the encrypted private keys keyed by their
+// serialized public key.
+func (wdb *WalletDB) loadCryptedKeys() map[string][]byte {
+	itr := wdb.Iterator(nil)
+	defer itr.Close()
+	itr.Seek([]byte{db.DbWalletCryptedKey})
+
+	cryptedKeys := make(map[string][]byte)
+	for ; itr.Valid() && itr.GetKey()[0] == db.DbWalletCryptedKey; itr.Next() {
+		secret := make([]byte, len(itr.GetVal()))
+		copy(secret, itr.GetVal())
+		cryptedKeys[string(itr.GetKey()[1:])] = secret
+	}
+	return cryptedKeys
+}
+
+// loadMasterKey returns the master key of an encrypted wallet, or nil if the
+// wallet is not encrypted.
+func (wdb *WalletDB) loadMasterKey() (*MasterKey, error) {
+	key := []byte{db.DbWalletMasterKey}
+	if !wdb.Exists(key) {
+		return nil, nil
+	}
+	data, err := wdb.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	masterKey := new(MasterKey)
+	if err := masterKey.Unserialize(bytes.NewBuffer(data)); err != nil {
+		return nil, err
+	}
+	return masterKey, nil
+}
+
+// loadHDChain returns the deterministic key chains of the wallet, or nil if
+// the wallet has no seed yet.
+func (wdb *WalletDB) loadHDChain() (*HDChain, error) {
+	key := []byte{db.DbWalletHDChain}
+	if !wdb.Exists(key) {
+		return nil, nil
+	}
+	data, err := wdb.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	hdChain := new(HDChain)
+	if err := hdChain.Unserialize(bytes.NewBuffer(data)); err != nil {
+		return nil, err
+	}
+	return hdChain, nil
+}
+
+// loadKeyMetadata returns the key metadata keyed by public key hash.
+func (wdb *WalletDB) loadKeyMetadata() (map[string]*KeyMetadata, error) {
+	itr := wdb.Iterator(nil)
+	defer itr.Close()
+	itr.Seek([]byte{db.DbWalletKeyMeta})
+
+	keyMetadata := make(map[string]*KeyMetadata)
+	for ; itr.Valid() && itr.GetKey()[0] == db.DbWalletKeyMeta; itr.Next() {
+		metadata := new(KeyMetadata)
+		if err := metadata.Unserialize(bytes.NewBuffer(itr.GetVal())); err != nil {
+			return nil, err
+		}
+		keyMetadata[string(itr.GetKey()[1:])] = metadata
+	}
+	return keyMetadata, nil
+}
+
+// loadKeyPool returns the keypool entries along with their indexes, in
+// ascending index order.
+func (wdb *WalletDB) loadKeyPool() ([]int64, []*KeyPoolEntry, error) {
+	itr := wdb.Iterator(nil)
+	defer itr.Close()
+	itr.Seek([]byte{db.DbWalletKeyPool})
+
+	indexes := make([]int64, 0)
+	entries := make([]*KeyPoolEntry, 0)
+	for ; itr.Valid() && itr.GetKey()[0] == db.DbWalletKeyPool; itr.Next() {
+		entry := new(KeyPoolEntry)
+		if err := entry.Unserialize(bytes.NewBuffer(itr.GetVal())); err != nil {
+			return nil, nil, err
+		}
+		indexes = append(indexes, int64(binary.BigEndian.Uint64(itr.GetKey()[1:])))
+		entries = append(entries, entry)
+	}
+	return indexes, entries, nil
+}
+
 func (wdb *WalletDB) loadScripts() ([]*script.Script, error) {
 	itr := wdb.Iterator(nil)
 	defer itr.Close()
@@ -91,6 +181,72 @@ func (wdb *WalletDB) saveSecret(secret []byte) error {
 	return wdb.Write(key, []byte{}, true)
 }
 
+func (wdb *WalletDB) saveCryptedKey(pubKey []byte, cryptedSecret []byte) error {
+	key := getDBKey(db.DbWalletCryptedKey, pubKey)
+	return wdb.Write(key, cryptedSecret, true)
+}
+
+func (wdb *WalletDB) saveMasterKey(masterKey *MasterKey) error {
+	w := new(bytes.Buffer)
+	if err := masterKey.Serialize(w); err != nil {
+		return err
+	}
+	return wdb.Write([]byte{db.DbWalletMasterKey}, w.Bytes(), true)
+}
+
+// encryptKeys atomically replaces the plaintext private keys with their
+// encrypted version and stores the master key. The whole database is then
+// rewritten, as the plaintext keys, which are part of the erased records' keys,
+// would otherwise stay in its files, compacted or not.
+func (wdb *WalletDB) encryptKeys(secrets [][]byte, pubKeys [][]byte, cryptedSecrets [][]byte,
+	masterKey *MasterKey) error {
+
+	w := new(bytes.Buffer)
+	if err := masterKey.Serialize(w); err != nil {
+		return err
+	}
+
+	batch := db.NewBatchWrapper(wdb.DBWrapper)
+	for i := range secrets {
+		batch.Write(getDBKey(db.DbWalletCryptedKey, pubKeys[i]), cryptedSecrets[i])
+		batch.Erase(getDBKey(db.DbWalletKey, secrets[i]))
+	}
+	batch.Write([]byte{db.DbWalletMasterKey}, w.Bytes())
+	if err := wdb.WriteBatch(batch, true); err != nil {
+		return err
+	}
+	return wdb.Rewrite()
+}
+
+func (wdb *WalletDB) saveHDChain(hdChain *HDChain) error {
+	w := new(bytes.Buffer)
+	if err := hdChain.Serialize(w); err != nil {
+		return err
+	}
+	return wdb.Write([]byte{db.DbWalletHDChain}, w.Bytes(), true)
+}
+
+func (wdb *WalletDB) saveKeyMetadata(keyHash []byte, metadata *KeyMetadata) error {
+	w := new(bytes.Buffer)
+	if err := metadata.Serialize(w); err != nil {
+		return err
+	}
+	key := getDBKey(db.DbWalletKeyMeta, keyHash)
+	return wdb.Write(key, w.Bytes(), true)
+}
+
+func (wdb *WalletDB) saveKeyPoolEntry(index int64, entry *KeyPoolEntry) error {
+	w := new(bytes.Buffer)
+	if err := entry.Serialize(w); err != nil {
+		return err
+	}
+	return wdb.Write(getKeyPoolDBKey(index), w.Bytes(), true)
+}
+
+func (wdb *WalletDB) eraseKeyPoolEntry(index int64) error {
+	return wdb.Erase(getKeyPoolDBKey(index), true)
+}
+
 func (wdb *WalletDB) saveScript(sc *script.Script) error {
 	w := new(bytes.Buffer)
 	err := sc.Serialize(w)
@@ -136,3 +292,11 @@ func getDBKey(dbID byte, orgKey []byte) []byte {
 	dbKey = append(dbKey, orgKey...)
 	return dbKey
 }
+
+// getKeyPoolDBKey encodes the index big endian so that the entries are
+// iterated in index order.
+func getKeyPoolDBKey(index int64) []byte {
+	var indexBytes [8]byte
+	binary.BigEndian.PutUint64(indexBytes[:], uint64(index))
+	return getDBKey(db.DbWalletKeyPool, indexBytes[:])
+}
@@ -28,8 +28,11 @@ import (
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/lblock"
+	"github.com/copernet/copernicus/logic/lblockfilter"
 	"github.com/copernet/copernicus/logic/lchain"
 	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/logic/lmerkleblock"
+	"github.com/copernet/copernicus/metrics"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/blockindex"
@@ -74,6 +77,14 @@ const (
 
 	// REVERT_TO_INV_DIFF when peer is neer to tip, set its revertToInv to false back
 	REVERT_TO_INV_DIFF = 7
+
+	// maxCmpctBlockDepth is the depth from the tip up to which blocks are
+	// served as compact blocks, deeper ones are sent in full.
+	maxCmpctBlockDepth = 5
+
+	// maxBlockTxnDepth is the depth from the tip up to which getblocktxn
+	// requests are answered, deeper ones are answered with the full block.
+	maxBlockTxnDepth = 10
 )
 
 var (
@@ -87,6 +98,15 @@ var (
 	userAgentVersion = fmt.Sprintf("%d.%d.%d", conf.AppMajor, conf.AppMinor, conf.AppPatch)
 )
 
+var (
+	peersGauge = metrics.NewGaugeVec("copernicus_peers",
+		"Number of connected peers.", "direction")
+	bytesReceived = metrics.NewCounterVec("copernicus_p2p_received_bytes_total",
+		"Bytes received from peers, by message type.", "command")
+	bytesSent = metrics.NewCounterVec("copernicus_p2p_sent_bytes_total",
+		"Bytes sent to peers, by message type.", "command")
+)
+
 // zeroHash is the zero value hash (all zeros).  It is defined as a convenience.
 var zeroHash util.Hash
 
@@ -519,15 +539,16 @@ func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
 	txDescs := mempool.GetInstance().GetAllTxEntry()
 	invMsg := wire.NewMsgInvSizeHint(uint(len(txDescs)))
 
-	for hash := range txDescs {
+	for hash, txEntry := range txDescs {
 		// Either add all transactions when there is no bloom filter,
 		// or only the transactions that match the filter when there is
 		// one.
-		// if !sp.filter.IsLoaded() || sp.filter.MatchTxAndUpdate(&hash) {
-		iv := wire.NewInvVect(wire.InvTypeTx, &hash)
-		invMsg.AddInvVect(iv)
-		if len(invMsg.InvList)+1 > wire.MaxInvPerMsg {
-			break
+		if !sp.filter.IsLoaded() || sp.filter.MatchTxAndUpdate(txEntry.Tx) {
+			iv := wire.NewInvVect(wire.InvTypeTx, &hash)
+			invMsg.AddInvVect(iv)
+			if len(invMsg.InvList)+1 > wire.MaxInvPerMsg {
+				break
+			}
 		}
 	}
 
@@ -603,6 +624,65 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte, done
 	sp.server.syncManager.QueueBlock(block, buf, sp.Peer, done)
 }
 
+// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin message.
+// It blocks until the compact block has been processed by the sync manager.
+func (sp *serverPeer) OnCmpctBlock(_ *peer.Peer, msg *wire.MsgCmpctBlock, done chan<- struct{}) {
+	hash := msg.Header.GetHash()
+	iv := wire.NewInvVect(wire.InvTypeBlock, &hash)
+	sp.AddKnownInventory(iv)
+
+	sp.server.syncManager.QueueCmpctBlock(msg, sp.Peer, done)
+}
+
+// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin message, in
+// answer to a getblocktxn we sent to complete a compact block.
+func (sp *serverPeer) OnBlockTxn(_ *peer.Peer, msg *wire.MsgBlockTxn, done chan<- struct{}) {
+	sp.server.syncManager.QueueBlockTxn(msg, sp.Peer, done)
+}
+
+// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin message
+// and is used to deliver the transactions a peer misses to rebuild a compact
+// block we sent.
+func (sp *serverPeer) OnGetBlockTxn(_ *peer.Peer, msg *wire.MsgGetBlockTxn, done chan<- struct{}) {
+	go sp.doGetBlockTxn(msg, done)
+}
+
+func (sp *serverPeer) doGetBlockTxn(msg *wire.MsgGetBlockTxn, done chan<- struct{}) {
+	blkIndex, send := findBlockIndex(&msg.BlockHash)
+	if !send || !blkIndex.HasData() {
+		log.Debug("peer %v requested transactions of unknown block %s", sp, msg.BlockHash)
+		done <- struct{}{}
+		return
+	}
+
+	// Honest peers only ask for the transactions of recent blocks they
+	// are rebuilding, send the whole block otherwise.
+	if chain.GetInstance().Height()-blkIndex.Height >= maxBlockTxnDepth {
+		log.Debug("peer %v requested transactions of too old block %s", sp, msg.BlockHash)
+		sp.server.pushBlockMsg(sp, &msg.BlockHash, done, nil, wire.BaseEncoding)
+		return
+	}
+
+	bl, err := lblock.GetBlockByIndex(blkIndex, sp.server.chainParams)
+	if err != nil {
+		log.Trace("Unable to fetch requested block hash %v: %v", msg.BlockHash, err)
+		done <- struct{}{}
+		return
+	}
+
+	txs := make([]*tx.Tx, 0, len(msg.Indexes))
+	for _, index := range msg.Indexes {
+		if int(index) >= len(bl.Txs) {
+			log.Warn("peer %v sent getblocktxn with out of bounds tx index %d", sp, index)
+			sp.addBanScore(100, 0, "getblocktxn-out-of-bounds")
+			done <- struct{}{}
+			return
+		}
+		txs = append(txs, bl.Txs[index])
+	}
+	sp.QueueMessage(wire.NewMsgBlockTxn(&msg.BlockHash, txs), done)
+}
+
 // OnInv is invoked when a peer receives an inv bitcoin message and is
 // used to examine the inventory being advertised by the remote peer and react
 // accordingly.  We pass the message down to blockmanager which will call
@@ -687,8 +767,10 @@ func (sp *serverPeer) doGetData(msg *wire.MsgGetData, done chan<- struct{}) {
 			err = sp.server.pushTxMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
 		case wire.InvTypeBlock:
 			err = sp.server.pushBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
-			// case wire.InvTypeFilteredBlock:
-			// 	err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
+		case wire.InvTypeCompatedBlock:
+			err = sp.server.pushCmpctBlockMsg(sp, &iv.Hash, c, waitChan)
+		case wire.InvTypeFilteredBlock:
+			err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
 		default:
 			log.Warn("Unknown type in inventory request %d",
 				iv.Type)
@@ -824,6 +906,139 @@ func (sp *serverPeer) OnGetHeaders(_ *peer.Peer, msg *wire.MsgGetHeaders) {
 	sp.QueueMessage(&wire.MsgHeaders{Headers: blockHeaders}, nil)
 }
 
+// enforceNodeCFFlag disconnects the peer if the server is not configured to
+// serve compact block filters of filterType.
+func (sp *serverPeer) enforceNodeCFFlag(cmd string, filterType wire.FilterType) bool {
+	if sp.server.services&wire.SFNodeCF != wire.SFNodeCF || filterType != wire.GCSFilterBasic {
+		log.Debug("%s sent an unsupported %s request -- disconnecting", sp, cmd)
+		sp.Disconnect()
+		return false
+	}
+
+	return true
+}
+
+// cfRequestBlocks returns the blocks from height start to stopHash of a
+// compact filter request. The peer is disconnected if the range is invalid or
+// spans more than maxCount blocks. It must be called with CsMain held.
+func (sp *serverPeer) cfRequestBlocks(cmd string, start uint32, stopHash *util.Hash,
+	maxCount int32) []*blockindex.BlockIndex {
+
+	if start > math.MaxInt32 {
+		start = math.MaxInt32
+	}
+	indexes, err := lblockfilter.BlockRange(int32(start), stopHash, maxCount)
+	if err != nil {
+		log.Debug("%s sent an invalid %s request: %v -- disconnecting", sp, cmd, err)
+		sp.Disconnect()
+		return nil
+	}
+	return indexes
+}
+
+// OnGetCFilters is invoked when a peer receives a getcfilters bitcoin
+// message. The filters of the requested blocks which are already indexed are
+// sent in cfilter messages.
+func (sp *serverPeer) OnGetCFilters(_ *peer.Peer, msg *wire.MsgGetCFilters) {
+	if !sp.enforceNodeCFFlag(msg.Command(), msg.FilterType) {
+		return
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	indexes := sp.cfRequestBlocks(msg.Command(), msg.StartHeight, &msg.StopHash,
+		wire.MaxGetCFiltersReqRange)
+	for _, pindex := range indexes {
+		entry, err := lblockfilter.GetFilter(pindex.GetBlockHash())
+		if err != nil {
+			log.Error("read block filter of %s failed: %v", pindex.GetBlockHash(), err)
+			return
+		}
+		if entry == nil {
+			return
+		}
+		sp.QueueMessage(wire.NewMsgCFilter(msg.FilterType, pindex.GetBlockHash(), entry.Filter), nil)
+	}
+}
+
+// OnGetCFHeaders is invoked when a peer receives a getcfheaders bitcoin
+// message. It replies with the hashes of the filters of the requested blocks,
+// along with the filter header of the block before them.
+func (sp *serverPeer) OnGetCFHeaders(_ *peer.Peer, msg *wire.MsgGetCFHeaders) {
+	if !sp.enforceNodeCFFlag(msg.Command(), msg.FilterType) {
+		return
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	indexes := sp.cfRequestBlocks(msg.Command(), msg.StartHeight, &msg.StopHash,
+		wire.MaxCFHeadersPerMsg)
+	if len(indexes) == 0 {
+		return
+	}
+
+	headersMsg := wire.NewMsgCFHeaders()
+	headersMsg.FilterType = msg.FilterType
+	headersMsg.StopHash = msg.StopHash
+	if prev := indexes[0].Prev; prev != nil {
+		entry, err := lblockfilter.GetFilter(prev.GetBlockHash())
+		if err != nil || entry == nil {
+			log.Debug("block filter of %s is not indexed: %v", prev.GetBlockHash(), err)
+			return
+		}
+		headersMsg.PrevFilterHeader = entry.Header
+	}
+	for _, pindex := range indexes {
+		entry, err := lblockfilter.GetFilter(pindex.GetBlockHash())
+		if err != nil || entry == nil {
+			log.Debug("block filter of %s is not indexed: %v", pindex.GetBlockHash(), err)
+			return
+		}
+		filterHash := entry.FilterHash
+		headersMsg.AddCFHash(&filterHash)
+	}
+	sp.QueueMessage(headersMsg, nil)
+}
+
+// OnGetCFCheckpt is invoked when a peer receives a getcfcheckpt bitcoin
+// message. It replies with the filter headers of the ancestors of the stop
+// block every wire.CFCheckptInterval blocks.
+func (sp *serverPeer) OnGetCFCheckpt(_ *peer.Peer, msg *wire.MsgGetCFCheckpt) {
+	if !sp.enforceNodeCFFlag(msg.Command(), msg.FilterType) {
+		return
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	stop := chain.GetInstance().FindBlockIndex(msg.StopHash)
+	if stop == nil {
+		log.Debug("%s sent a %s request for unknown block %s -- disconnecting",
+			sp, msg.Command(), msg.StopHash)
+		sp.Disconnect()
+		return
+	}
+
+	count := int(stop.Height) / wire.CFCheckptInterval
+	checkptMsg := wire.NewMsgCFCheckpt(msg.FilterType, &msg.StopHash, count)
+	for i := 1; i <= count; i++ {
+		pindex := stop.GetAncestor(int32(i * wire.CFCheckptInterval))
+		entry, err := lblockfilter.GetFilter(pindex.GetBlockHash())
+		if err != nil || entry == nil {
+			log.Debug("block filter of %s is not indexed: %v", pindex.GetBlockHash(), err)
+			return
+		}
+		header := entry.Header
+		if err := checkptMsg.AddCFHeader(&header); err != nil {
+			log.Error("build cfcheckpt message failed: %v", err)
+			return
+		}
+	}
+	sp.QueueMessage(checkptMsg, nil)
+}
+
 // enforceNodeBloomFlag disconnects the peer if the server is not configured to
 // allow bloom filters.  Additionally, if the peer has negotiated to a protocol
 // version  that is high enough to observe the bloom filter service support bit,
@@ -1029,12 +1244,31 @@ func (sp *serverPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 // the bytes received by the server.
 func (sp *serverPeer) OnRead(_ *peer.Peer, bytesRead int, msg wire.Message, err error) {
 	sp.server.AddBytesReceived(uint64(bytesRead))
+	bytesReceived.WithLabelValues(messageCommand(msg)).Add(float64(bytesRead))
 }
 
 // OnWrite is invoked when a peer sends a message and it is used to update
 // the bytes sent by the server.
 func (sp *serverPeer) OnWrite(_ *peer.Peer, bytesWritten int, msg wire.Message, err error) {
 	sp.server.AddBytesSent(uint64(bytesWritten))
+	bytesSent.WithLabelValues(messageCommand(msg)).Add(float64(bytesWritten))
+}
+
+// messageCommand returns the command of msg, which is nil when it could not
+// be decoded.
+func messageCommand(msg wire.Message) string {
+	if msg == nil {
+		return "unknown"
+	}
+	return msg.Command()
+}
+
+// peerDirection returns the label of the direction of sp.
+func peerDirection(sp *serverPeer) string {
+	if sp.Inbound() {
+		return "inbound"
+	}
+	return "outbound"
 }
 
 // randomUint16Number returns a random uint16 in a specified input range.  Note
@@ -1195,6 +1429,38 @@ func (s *Server) pushBlockMsg(sp *serverPeer, hash *util.Hash, doneChan chan<- s
 	return nil
 }
 
+// pushCmpctBlockMsg sends a cmpctblock message for the provided block hash to
+// the connected peer. Blocks too deep in the chain to be rebuilt from the
+// mempool are sent in full. An error is returned if the block hash is not
+// known.
+func (s *Server) pushCmpctBlockMsg(sp *serverPeer, hash *util.Hash, doneChan chan<- struct{},
+	waitChan <-chan struct{}) error {
+
+	blkIndex, send := findBlockIndex(hash)
+	if !send || !blkIndex.HasData() ||
+		chain.GetInstance().Height()-blkIndex.Height >= maxCmpctBlockDepth {
+		return s.pushBlockMsg(sp, hash, doneChan, waitChan, wire.BaseEncoding)
+	}
+
+	bl, err := lblock.GetBlockByIndex(blkIndex, s.chainParams)
+	if err == nil {
+		var msg *wire.MsgCmpctBlock
+		if msg, err = wire.NewMsgCmpctBlock(bl); err == nil {
+			if waitChan != nil {
+				<-waitChan
+			}
+			sp.QueueMessage(msg, doneChan)
+			return nil
+		}
+	}
+
+	log.Trace("Unable to build compact block %v: %v", hash, err)
+	if doneChan != nil {
+		doneChan <- struct{}{}
+	}
+	return err
+}
+
 func findBlockIndex(hash *util.Hash) (blkIndex *blockindex.BlockIndex, send bool) {
 	persist.CsMain.Lock() //to protect chain.indexMap
 	defer persist.CsMain.Unlock()
@@ -1223,62 +1489,67 @@ func findBlockIndex(hash *util.Hash) (blkIndex *blockindex.BlockIndex, send bool
 // the connected peer.  Since a merkle block requires the peer to have a filter
 // loaded, this call will simply be ignored if there is no filter loaded.  An
 // error is returned if the block hash is not known.
-//func (s *Server) pushMerkleBlockMsg(sp *serverPeer, hash *util.Hash,
-//	doneChan chan<- struct{}, waitChan <-chan struct{}, encoding wire.MessageEncoding) error {
-
-// Do not send a response if the peer doesn't have a filter loaded.
-//if !sp.filter.IsLoaded() {
-//if doneChan != nil {
-//	doneChan <- struct{}{}
-//}
-//return nil
-//}
-
-// // Fetch the raw block bytes from the database.
-// blk, err := lblock.GetBlock(hash)
-// if err != nil {
-// 	log.Trace("Unable to fetch requested block hash %v: %v",
-// 		hash, err)
-
-// 	if doneChan != nil {
-// 		doneChan <- struct{}{}
-// 	}
-// 	return err
-// }
-
-// // Generate a merkle block by filtering the requested block according
-// // to the filter for the peer.
-// merkle, matchedTxIndices := bloom.NewMerkleBlock(blk, sp.filter)
-
-// // Once we have fetched data wait for any previous operation to finish.
-// if waitChan != nil {
-// 	<-waitChan
-// }
-
-// // Send the merkleblock.  Only send the done channel with this message
-// // if no transactions will be sent afterwards.
-// var dc chan<- struct{}
-// if len(matchedTxIndices) == 0 {
-// 	dc = doneChan
-// }
-// sp.QueueMessage(merkle, dc)
-
-// // Finally, send any matched transactions.
-// blkTransactions := blk.Txs
-// for i, txIndex := range matchedTxIndices {
-// 	// Only send the done channel on the final transaction.
-// 	var dc chan<- struct{}
-// 	if i == len(matchedTxIndices)-1 {
-// 		dc = doneChan
-// 	}
-// 	if txIndex < uint32(len(blkTransactions)) {
-// 		sp.QueueMessageWithEncoding(blkTransactions[txIndex], dc,
-// 			encoding)
-// 	}
-// }
-
-// return nil
-//}
+func (s *Server) pushMerkleBlockMsg(sp *serverPeer, hash *util.Hash,
+	doneChan chan<- struct{}, waitChan <-chan struct{}, encoding wire.MessageEncoding) error {
+
+	// Do not send a response if the peer doesn't have a filter loaded.
+	if !sp.filter.IsLoaded() {
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return nil
+	}
+
+	blkIndex, send := findBlockIndex(hash)
+	if !send || !blkIndex.HasData() {
+		log.Trace("data for block(%s) is not ready: send(%v)", hash, send)
+
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return fmt.Errorf("data for block(%s) is not ready", hash)
+	}
+
+	blk, err := lblock.GetBlockByIndex(blkIndex, s.chainParams)
+	if err != nil {
+		log.Trace("Unable to fetch requested block hash %v: %v",
+			hash, err)
+
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+
+	// Generate a merkle block by filtering the requested block according
+	// to the filter for the peer.
+	merkle := lmerkleblock.NewMerkleBlockWithFilter(blk, sp.filter)
+
+	// Once we have fetched data wait for any previous operation to finish.
+	if waitChan != nil {
+		<-waitChan
+	}
+
+	// Send the merkleblock.  Only send the done channel with this message
+	// if no transactions will be sent afterwards.
+	var dc chan<- struct{}
+	if len(merkle.MatchedTxn) == 0 {
+		dc = doneChan
+	}
+	sp.QueueMessage(merkle.MsgMerkleBlock(), dc)
+
+	// Finally, send any matched transactions.
+	for i, matched := range merkle.MatchedTxn {
+		// Only send the done channel on the final transaction.
+		var dc chan<- struct{}
+		if i == len(merkle.MatchedTxn)-1 {
+			dc = doneChan
+		}
+		sp.QueueMessageWithEncoding((*wire.MsgTx)(blk.Txs[matched.N]), dc, encoding)
+	}
+
+	return nil
+}
 
 // handleUpdatePeerHeight updates the heights of all peers who were known to
 // announce a block we recently accepted.
@@ -1385,6 +1656,7 @@ func (s *Server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 			state.outboundPeers[sp.ID()] = sp
 		}
 	}
+	peersGauge.WithLabelValues(peerDirection(sp)).Inc()
 
 	return true
 }
@@ -1408,6 +1680,7 @@ func (s *Server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
 			s.connManager.Disconnect(sp.connReq.ID())
 		}
 		delete(list, sp.ID())
+		peersGauge.WithLabelValues(peerDirection(sp)).Dec()
 		log.Debug("Removed peer %s", sp)
 		return
 	}
@@ -1654,6 +1927,16 @@ func (s *Server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 			return
 		}
 
+		// Compact blocks are only pushed to the peers which asked for
+		// them, the others get the regular announcement of the block.
+		if cmpctBlock, ok := msg.data.(*wire.MsgCmpctBlock); ok {
+			if sp.WantsCmpctBlocks() && !sp.IsKnownInventory(msg.invVect) {
+				sp.AddKnownInventory(msg.invVect)
+				sp.QueueMessage(cmpctBlock, nil)
+			}
+			return
+		}
+
 		// If the inventory is a block and the peer prefers headers,
 		// use QueueHeaders logic to handle it
 		if msg.invVect.Type == wire.InvTypeBlock {
@@ -1932,9 +2215,16 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			OnGetHeaders: sp.OnGetHeaders,
 			OnFeeFilter:  sp.OnFeeFilter,
 			OnReject:     sp.OnReject,
-			//OnFilterAdd:   sp.OnFilterAdd,
-			//OnFilterClear: sp.OnFilterClear,
-			//OnFilterLoad:  sp.OnFilterLoad,
+
+			OnCmpctBlock:               sp.OnCmpctBlock,
+			OnGetBlockTxn:              sp.OnGetBlockTxn,
+			OnBlockTxn:                 sp.OnBlockTxn,
+			OnFilterAdd:                sp.OnFilterAdd,
+			OnFilterClear:              sp.OnFilterClear,
+			OnFilterLoad:               sp.OnFilterLoad,
+			OnGetCFilters:              sp.OnGetCFilters,
+			OnGetCFHeaders:             sp.OnGetCFHeaders,
+			OnGetCFCheckpt:             sp.OnGetCFCheckpt,
 			OnGetAddr:                  sp.OnGetAddr,
 			OnAddr:                     sp.OnAddr,
 			OnRead:                     sp.OnRead,
@@ -2585,6 +2875,9 @@ func NewServer(chainParams *model.BitcoinParams, ts *util.MedianTime, interrupt
 	if cfg.Protocol.NoPeerBloomFilters {
 		services &^= wire.SFNodeBloom
 	}
+	if cfg.Protocol.PeerBlockFilters {
+		services |= wire.SFNodeCF
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, net.LookupIP)
 
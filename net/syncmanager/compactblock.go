@@ -0,0 +1,134 @@
+package syncmanager
+
+import (
+	"errors"
+
+	"github.com/copernet/copernicus/logic/lmerkleroot"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/net/wire"
+	"github.com/copernet/copernicus/util"
+)
+
+var (
+	// errCmpctBlockInvalid means the peer sent a malformed compact block or
+	// blocktxn message, which no honest peer does.
+	errCmpctBlockInvalid = errors.New("invalid compact block")
+
+	// errCmpctBlockFailed means the block could not be rebuilt, e.g. on a
+	// short id collision, and has to be downloaded in full.
+	errCmpctBlockFailed = errors.New("compact block reconstruction failed")
+)
+
+// partialBlock is a block being rebuilt from a compact block. Transactions
+// found in the mempool or prefilled by the peer are filled in, the others are
+// nil until they are received in a blocktxn message.
+type partialBlock struct {
+	header block.BlockHeader
+	txs    []*tx.Tx
+}
+
+// newPartialBlock matches the short ids of cmpct against the transactions of
+// pool and returns the resulting partial block.
+func newPartialBlock(cmpct *wire.MsgCmpctBlock, pool map[util.Hash]*mempool.TxEntry) (*partialBlock, error) {
+	txCount := cmpct.BlockTxCount()
+	if txCount == 0 {
+		return nil, errCmpctBlockInvalid
+	}
+
+	pb := &partialBlock{
+		header: cmpct.Header,
+		txs:    make([]*tx.Tx, txCount),
+	}
+	for _, prefilled := range cmpct.PrefilledTxs {
+		if int(prefilled.Index) >= txCount || prefilled.Tx == nil ||
+			pb.txs[prefilled.Index] != nil {
+			return nil, errCmpctBlockInvalid
+		}
+		pb.txs[prefilled.Index] = prefilled.Tx
+	}
+
+	// Map every short id to the index of the block slot it stands for,
+	// skipping the prefilled slots.
+	slots := make(map[uint64]int, len(cmpct.ShortTxIDs))
+	slot := 0
+	for _, shortID := range cmpct.ShortTxIDs {
+		for pb.txs[slot] != nil {
+			slot++
+		}
+		if _, exists := slots[shortID]; exists {
+			// Two transactions of the block share a short id, we
+			// cannot tell which one is which.
+			return nil, errCmpctBlockFailed
+		}
+		slots[shortID] = slot
+		slot++
+	}
+
+	k0, k1 := cmpct.ShortIDKeys()
+	found := make(map[int]bool, len(slots))
+	for txid, entry := range pool {
+		index, ok := slots[wire.ShortTxID(k0, k1, &txid)]
+		if !ok {
+			continue
+		}
+		if found[index] {
+			// Several mempool transactions match the same short id,
+			// leave the slot to be requested from the peer.
+			pb.txs[index] = nil
+			continue
+		}
+		found[index] = true
+		pb.txs[index] = entry.Tx
+	}
+
+	return pb, nil
+}
+
+// hash returns the hash of the block being rebuilt.
+func (pb *partialBlock) hash() util.Hash {
+	return pb.header.GetHash()
+}
+
+// missingIndexes returns the indexes of the transactions still unknown.
+func (pb *partialBlock) missingIndexes() []uint32 {
+	var missing []uint32
+	for i, txn := range pb.txs {
+		if txn == nil {
+			missing = append(missing, uint32(i))
+		}
+	}
+	return missing
+}
+
+// fillBlock completes the partial block with txs, the missing transactions in
+// block order, and returns the rebuilt block once its merkle root checks out.
+func (pb *partialBlock) fillBlock(txs []*tx.Tx) (*block.Block, error) {
+	blk := &block.Block{
+		Header: pb.header,
+		Txs:    make([]*tx.Tx, len(pb.txs)),
+	}
+	next := 0
+	for i, txn := range pb.txs {
+		if txn == nil {
+			if next >= len(txs) {
+				return nil, errCmpctBlockInvalid
+			}
+			txn = txs[next]
+			next++
+		}
+		blk.Txs[i] = txn
+	}
+	if next != len(txs) {
+		return nil, errCmpctBlockInvalid
+	}
+
+	// A short id collision with a mempool transaction gives a block with a
+	// bad merkle root, which is not the peer's fault.
+	mutated := false
+	if lmerkleroot.BlockMerkleRoot(blk.Txs, &mutated) != blk.Header.MerkleRoot || mutated {
+		return nil, errCmpctBlockFailed
+	}
+	return blk, nil
+}
@@ -0,0 +1,123 @@
+package syncmanager
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/logic/lmerkleroot"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/net/wire"
+	"github.com/copernet/copernicus/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCmpctTestBlock(txCount int) *block.Block {
+	blk := block.NewBlock()
+	for i := 0; i < txCount; i++ {
+		txn := tx.NewTx(0, tx.DefaultVersion)
+		prevOut := outpoint.NewOutPoint(util.Hash{2}, uint32(i))
+		if i == 0 {
+			prevOut = outpoint.NewDefaultOutPoint()
+		}
+		txn.AddTxIn(txin.NewTxIn(prevOut, script.NewScriptRaw([]byte{0x01, byte(i)}), 0xffffffff))
+		txn.AddTxOut(txout.NewTxOut(1000, script.NewScriptRaw([]byte{0x51})))
+		blk.Txs = append(blk.Txs, txn)
+	}
+	mutated := false
+	blk.Header.MerkleRoot = lmerkleroot.BlockMerkleRoot(blk.Txs, &mutated)
+	return blk
+}
+
+func newTestPool(txs ...*tx.Tx) map[util.Hash]*mempool.TxEntry {
+	pool := make(map[util.Hash]*mempool.TxEntry)
+	for _, txn := range txs {
+		pool[txn.GetHash()] = &mempool.TxEntry{Tx: txn}
+	}
+	return pool
+}
+
+func TestPartialBlockFromMempool(t *testing.T) {
+	blk := newCmpctTestBlock(5)
+	cmpct, err := wire.NewMsgCmpctBlock(blk)
+	assert.Nil(t, err)
+
+	pb, err := newPartialBlock(cmpct, newTestPool(blk.Txs[1:]...))
+	assert.Nil(t, err)
+	assert.Equal(t, blk.GetHash(), pb.hash())
+	assert.Empty(t, pb.missingIndexes())
+
+	rebuilt, err := pb.fillBlock(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, blk.Txs, rebuilt.Txs)
+	assert.Equal(t, blk.GetHash(), rebuilt.GetHash())
+
+	_, err = pb.fillBlock(blk.Txs[1:2])
+	assert.Equal(t, errCmpctBlockInvalid, err)
+}
+
+func TestPartialBlockMissingTxs(t *testing.T) {
+	blk := newCmpctTestBlock(6)
+	cmpct, err := wire.NewMsgCmpctBlock(blk)
+	assert.Nil(t, err)
+
+	pb, err := newPartialBlock(cmpct, newTestPool(blk.Txs[1], blk.Txs[3], blk.Txs[5]))
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{2, 4}, pb.missingIndexes())
+
+	// too few transactions
+	_, err = pb.fillBlock(blk.Txs[2:3])
+	assert.Equal(t, errCmpctBlockInvalid, err)
+
+	// wrong transactions, the merkle root does not match
+	_, err = pb.fillBlock([]*tx.Tx{blk.Txs[4], blk.Txs[2]})
+	assert.Equal(t, errCmpctBlockFailed, err)
+
+	rebuilt, err := pb.fillBlock([]*tx.Tx{blk.Txs[2], blk.Txs[4]})
+	assert.Nil(t, err)
+	assert.Equal(t, blk.Txs, rebuilt.Txs)
+}
+
+func TestPartialBlockPrefilled(t *testing.T) {
+	blk := newCmpctTestBlock(4)
+	cmpct, err := wire.NewMsgCmpctBlock(blk)
+	assert.Nil(t, err)
+
+	// prefill the third transaction too
+	cmpct.PrefilledTxs = append(cmpct.PrefilledTxs, wire.PrefilledTx{Index: 2, Tx: blk.Txs[2]})
+	cmpct.ShortTxIDs = append(cmpct.ShortTxIDs[:1], cmpct.ShortTxIDs[2:]...)
+
+	pb, err := newPartialBlock(cmpct, newTestPool(blk.Txs[3]))
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1}, pb.missingIndexes())
+
+	rebuilt, err := pb.fillBlock(blk.Txs[1:2])
+	assert.Nil(t, err)
+	assert.Equal(t, blk.GetHash(), rebuilt.GetHash())
+}
+
+func TestPartialBlockInvalid(t *testing.T) {
+	blk := newCmpctTestBlock(3)
+	cmpct, err := wire.NewMsgCmpctBlock(blk)
+	assert.Nil(t, err)
+
+	// prefilled index out of the block
+	cmpct.PrefilledTxs[0].Index = 3
+	_, err = newPartialBlock(cmpct, newTestPool())
+	assert.Equal(t, errCmpctBlockInvalid, err)
+
+	// empty block
+	_, err = newPartialBlock(&wire.MsgCmpctBlock{Header: blk.Header}, newTestPool())
+	assert.Equal(t, errCmpctBlockInvalid, err)
+
+	// short ids colliding within the block cannot be resolved
+	cmpct, err = wire.NewMsgCmpctBlock(blk)
+	assert.Nil(t, err)
+	cmpct.ShortTxIDs[1] = cmpct.ShortTxIDs[0]
+	_, err = newPartialBlock(cmpct, newTestPool(blk.Txs[1:]...))
+	assert.Equal(t, errCmpctBlockFailed, err)
+}
@@ -66,6 +66,10 @@ const (
 	// BLOCK_STALLING_TIMEOUT in microsecond during which a peer must stall block
 	// download progress before being disconnected
 	BLOCK_STALLING_TIMEOUT = 2 * 1000000
+
+	// maxHighBandwidthPeers is the number of peers asked to announce new
+	// blocks with cmpctblock messages, without a prior inv or headers.
+	maxHighBandwidthPeers = 3
 )
 
 // zeroHash is the zero value hash (all zeros).  It is defined as a convenience.
@@ -85,6 +89,22 @@ type blockMsg struct {
 	reply chan<- struct{}
 }
 
+// cmpctBlockMsg packages a bitcoin cmpctblock message and the peer it came from
+// together so the block handler has access to that information.
+type cmpctBlockMsg struct {
+	cmpctBlock *wire.MsgCmpctBlock
+	peer       *peer.Peer
+	reply      chan<- struct{}
+}
+
+// blockTxnMsg packages a bitcoin blocktxn message and the peer it came from
+// together so the block handler has access to that information.
+type blockTxnMsg struct {
+	blockTxn *wire.MsgBlockTxn
+	peer     *peer.Peer
+	reply    chan<- struct{}
+}
+
 type minedBlockMsg struct {
 	block *block.Block
 	reply chan<- error
@@ -197,6 +217,10 @@ type peerSyncState struct {
 	requestedTxns       map[util.Hash]struct{}
 	requestedBlocks     map[util.Hash]struct{}
 	unconnectingHeaders int
+
+	// partialBlock is the compact block waiting for the blocktxn answer of
+	// this peer, if any.
+	partialBlock *partialBlock
 }
 
 // SyncManager is used to communicate block related messages with peers. The
@@ -221,6 +245,14 @@ type SyncManager struct {
 	syncPeer        *peer.Peer
 	peerStates      map[*peer.Peer]*peerSyncState
 
+	// backfillHeight is the lowest height below the UTXO snapshot base
+	// whose block may still be missing.
+	backfillHeight int32
+
+	// highBandwidthPeers are the peers which announce new blocks with
+	// cmpctblock messages, the most recently useful last.
+	highBandwidthPeers []*peer.Peer
+
 	// callback for transaction And block process
 	ProcessTransactionCallBack func(*tx.Tx, map[util.Hash]struct{}, int64) ([]*tx.Tx, []util.Hash, []util.Hash, error)
 	ProcessBlockCallBack       func(*block.Block, bool) (bool, error)
@@ -444,6 +476,7 @@ func (sm *SyncManager) clearSyncPeerState(peer *peer.Peer) {
 // is invoked from the syncHandler goroutine.
 func (sm *SyncManager) handleDonePeerMsg(peer *peer.Peer) {
 	sm.clearSyncPeerState(peer)
+	sm.removeHighBandwidthPeer(peer)
 
 	// Attempt to find a new peer to sync from if the quitting peer is the
 	// sync peer.  Also, reset the headers-first state if in headers-first
@@ -657,7 +690,12 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
+	if sm.current() {
+		sm.updateHighBandwidthPeers(peer)
+	}
+
 	sm.fetchHeaderBlocks(peer)
+	sm.fetchSnapshotBlocks(peer)
 }
 
 func (sm *SyncManager) handleMinedBlockMsg(mbmsg *minedBlockMsg) {
@@ -676,6 +714,156 @@ func (sm *SyncManager) handleMinedBlockMsg(mbmsg *minedBlockMsg) {
 	log.Debug("process mined block(%v) done via submitblock", &hash)
 }
 
+// handleCmpctBlockMsg handles cmpctblock messages from all peers. The block is
+// rebuilt from the mempool when it extends our tip, the missing transactions
+// are requested with getblocktxn, and the full block is downloaded instead if
+// the reconstruction fails.
+func (sm *SyncManager) handleCmpctBlockMsg(cmsg *cmpctBlockMsg) {
+	gChain := chain.GetInstance()
+	peer := cmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warn("Received cmpctblock message from unknown peer %s", peer.Addr())
+		return
+	}
+
+	header := &cmsg.cmpctBlock.Header
+	if gChain.FindBlockIndex(header.HashPrevBlock) == nil {
+		sm.fetchHeadersToConnect(peer, state)
+		return
+	}
+
+	headers := []*block.BlockHeader{header}
+	peerTip := sm.updatePeerState(headers, peer, gChain)
+	if err := sm.ProcessBlockHeadCallBack(headers, nil); err != nil {
+		log.Warn("process cmpctblock header %s from peer %s failed: %v", peerTip, peer.Addr(), err)
+		// Peers are allowed to relay compact blocks before fully
+		// validating them since InvalidCBNoBanVersion.
+		if peer.ProtocolVersion() < wire.InvalidCBNoBanVersion {
+			sm.misbehaving(peer.Addr(), 100, "invalid-cmpctblock-header")
+		}
+		return
+	}
+
+	pindex := gChain.FindBlockIndex(peerTip)
+	if pindex == nil || pindex.HasData() {
+		return
+	}
+
+	// Only blocks extending our tip can be rebuilt from the mempool, and
+	// there is no point racing the peer the block was requested from.
+	inFlightPeer, inFlight := sm.requestedBlocks[peerTip]
+	if pindex.Prev != gChain.Tip() || inFlight && inFlightPeer != peer {
+		sm.fetchHeaderBlocks(peer)
+		return
+	}
+
+	pb, err := newPartialBlock(cmsg.cmpctBlock, mempool.GetInstance().GetAllTxEntry())
+	if err != nil {
+		if err == errCmpctBlockInvalid {
+			sm.misbehaving(peer.Addr(), 100, "invalid-cmpctblock")
+			return
+		}
+		log.Debug("rebuild cmpctblock %s from peer %s failed: %v", peerTip, peer.Addr(), err)
+		sm.requestFullBlock(peer, state, &peerTip)
+		return
+	}
+
+	sm.requestedBlocks[peerTip] = peer
+	state.requestedBlocks[peerTip] = struct{}{}
+
+	missing := pb.missingIndexes()
+	if len(missing) > 0 {
+		log.Debug("cmpctblock %s from peer %s misses %d of %d transactions",
+			peerTip, peer.Addr(), len(missing), len(pb.txs))
+		state.partialBlock = pb
+		peer.QueueMessage(wire.NewMsgGetBlockTxn(&peerTip, missing), nil)
+		return
+	}
+
+	blk, err := pb.fillBlock(nil)
+	if err != nil {
+		log.Debug("rebuild cmpctblock %s from peer %s failed: %v", peerTip, peer.Addr(), err)
+		sm.requestFullBlock(peer, state, &peerTip)
+		return
+	}
+	sm.handleBlockMsg(&blockMsg{block: blk, peer: peer})
+}
+
+// handleBlockTxnMsg handles blocktxn messages, which complete the compact
+// block previously received from the same peer.
+func (sm *SyncManager) handleBlockTxnMsg(bmsg *blockTxnMsg) {
+	peer := bmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warn("Received blocktxn message from unknown peer %s", peer.Addr())
+		return
+	}
+
+	pb := state.partialBlock
+	if pb == nil || pb.hash() != bmsg.blockTxn.BlockHash {
+		log.Debug("Ignore unrequested blocktxn %s from peer %s", bmsg.blockTxn.BlockHash, peer.Addr())
+		return
+	}
+	state.partialBlock = nil
+
+	blockHash := bmsg.blockTxn.BlockHash
+	blk, err := pb.fillBlock(bmsg.blockTxn.Txs)
+	if err != nil {
+		if err == errCmpctBlockInvalid {
+			sm.misbehaving(peer.Addr(), 100, "invalid-blocktxn")
+			return
+		}
+		log.Debug("rebuild cmpctblock %s from peer %s failed: %v", blockHash, peer.Addr(), err)
+		sm.requestFullBlock(peer, state, &blockHash)
+		return
+	}
+	sm.handleBlockMsg(&blockMsg{block: blk, peer: peer})
+}
+
+// requestFullBlock falls back to downloading a block which could not be
+// rebuilt from its compact version.
+func (sm *SyncManager) requestFullBlock(peer *peer.Peer, state *peerSyncState, hash *util.Hash) {
+	sm.requestedBlocks[*hash] = peer
+	state.requestedBlocks[*hash] = struct{}{}
+
+	gdmsg := wire.NewMsgGetData()
+	gdmsg.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, hash))
+	peer.QueueMessage(gdmsg, nil)
+}
+
+// updateHighBandwidthPeers moves peer, which just provided a new block, to the
+// peers asked to announce blocks with cmpctblock messages. The least recently
+// useful one is switched back to low bandwidth mode when there are too many.
+func (sm *SyncManager) updateHighBandwidthPeers(peer *peer.Peer) {
+	if !peer.SupportsCmpctBlocks() {
+		return
+	}
+	for i, p := range sm.highBandwidthPeers {
+		if p == peer {
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers[:i], sm.highBandwidthPeers[i+1:]...)
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers, peer)
+			return
+		}
+	}
+
+	if len(sm.highBandwidthPeers) >= maxHighBandwidthPeers {
+		sm.highBandwidthPeers[0].PushSendCmpctMsg(false)
+		sm.highBandwidthPeers = sm.highBandwidthPeers[1:]
+	}
+	peer.PushSendCmpctMsg(true)
+	sm.highBandwidthPeers = append(sm.highBandwidthPeers, peer)
+}
+
+func (sm *SyncManager) removeHighBandwidthPeer(peer *peer.Peer) {
+	for i, p := range sm.highBandwidthPeers {
+		if p == peer {
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers[:i], sm.highBandwidthPeers[i+1:]...)
+			return
+		}
+	}
+}
+
 func lastAccouncedBlock(peer *peer.Peer) *blockindex.BlockIndex {
 	pindexBestKnownHash := peer.LastAnnouncedBlock()
 	if pindexBestKnownHash == nil {
@@ -846,6 +1034,59 @@ out:
 	}
 }
 
+// fetchSnapshotBlocks requests the blocks below the UTXO snapshot base from
+// the peer, lowest first, to backfill the chain after loadtxoutset.
+func (sm *SyncManager) fetchSnapshotBlocks(peer *peer.Peer) {
+	gChain := chain.GetInstance()
+	base := gChain.SnapshotBase()
+	if base == nil {
+		return
+	}
+
+	for sm.backfillHeight <= base.Height && gChain.GetIndex(sm.backfillHeight).HasData() {
+		sm.backfillHeight++
+	}
+	if sm.backfillHeight > base.Height {
+		lchain.FinishSnapshotBackfill()
+		return
+	}
+
+	peerState, exists := sm.peerStates[peer]
+	if !exists || !peerState.syncCandidate || !peer.VerAckReceived() {
+		return
+	}
+
+	// Only the peers which have the snapshot base have the blocks below it.
+	pindexBestKnownBlock := lastAccouncedBlock(peer)
+	if pindexBestKnownBlock == nil || pindexBestKnownBlock.GetAncestor(base.Height) != base {
+		return
+	}
+
+	gdmsg := wire.NewMsgGetData()
+	nWindowEnd := util.MinI32(sm.backfillHeight+BLOCK_DOWNLOAD_WINDOW, base.Height)
+	for height := sm.backfillHeight; height <= nWindowEnd; height++ {
+		if len(peerState.requestedBlocks) >= MAX_BLOCKS_IN_TRANSIT_PER_PEER {
+			break
+		}
+		pindex := gChain.GetIndex(height)
+		if pindex.HasData() {
+			continue
+		}
+		if _, exists := sm.requestedBlocks[*pindex.GetBlockHash()]; exists {
+			continue
+		}
+		iv := wire.NewInvVect(wire.InvTypeBlock, pindex.GetBlockHash())
+		sm.requestedBlocks[*pindex.GetBlockHash()] = peer
+		peerState.requestedBlocks[*pindex.GetBlockHash()] = struct{}{}
+		gdmsg.AddInvVect(iv)
+	}
+
+	if len(gdmsg.InvList) > 0 {
+		log.Debug("request %d blocks below the snapshot base from peer(%d)", len(gdmsg.InvList), peer.ID())
+		peer.QueueMessage(gdmsg, nil)
+	}
+}
+
 func (sm *SyncManager) fetchHeadersToConnect(peer *peer.Peer, state *peerSyncState) {
 	gChain := chain.GetInstance()
 
@@ -980,8 +1221,16 @@ func (sm *SyncManager) fetchBlocks(vToFetch *list.List, state *peerSyncState, pe
 			break
 		}
 
-		hash := *(e.Value.(*blockindex.BlockIndex).GetBlockHash())
-		iv := wire.NewInvVect(wire.InvTypeBlock, &hash)
+		pindex := e.Value.(*blockindex.BlockIndex)
+		hash := *pindex.GetBlockHash()
+		invType := wire.InvTypeBlock
+		// A lone block on top of our tip is likely made of transactions
+		// we already have, ask for it as a compact block.
+		if vToFetch.Len() == 1 && pindex.Prev == chain.GetInstance().Tip() &&
+			peer.SupportsCmpctBlocks() {
+			invType = wire.InvTypeCompatedBlock
+		}
+		iv := wire.NewInvVect(invType, &hash)
 		gdmsg.AddInvVect(iv)
 
 		sm.requestedBlocks[hash] = peer
@@ -1242,6 +1491,7 @@ func (sm *SyncManager) scanToFetchHeaderBlocks() {
 		// try fetch
 		if len(state.requestedBlocks) < MAX_BLOCKS_IN_TRANSIT_PER_PEER {
 			sm.fetchHeaderBlocks(peer)
+			sm.fetchSnapshotBlocks(peer)
 		}
 	}
 }
@@ -1276,6 +1526,14 @@ out:
 				sm.handleBlockMsg(msg)
 				msg.reply <- struct{}{}
 
+			case *cmpctBlockMsg:
+				sm.handleCmpctBlockMsg(msg)
+				msg.reply <- struct{}{}
+
+			case *blockTxnMsg:
+				sm.handleBlockTxnMsg(msg)
+				msg.reply <- struct{}{}
+
 			case *invMsg:
 				sm.handleInvMsg(msg)
 
@@ -1348,8 +1606,14 @@ func (sm *SyncManager) handleBlockchainNotification(notification *chain.Notifica
 			break
 		}
 
-		// Generate the inventory vector and relay it.
+		// Generate the inventory vector and relay it. Peers which asked
+		// for compact blocks get it first, before it is even connected.
 		iv := wire.NewInvVect(wire.InvTypeBlock, &block.Header.Hash)
+		if cmpctBlock, err := wire.NewMsgCmpctBlock(block); err == nil {
+			sm.peerNotifier.RelayInventory(iv, cmpctBlock)
+		} else {
+			log.Warn("build compact block %s failed: %v", block.Header.Hash, err)
+		}
 		sm.peerNotifier.RelayInventory(iv, &block.Header)
 
 	// A block has been connected to the main block chain.
@@ -1444,6 +1708,31 @@ func (sm *SyncManager) QueueBlock(block *block.Block, buf []byte, peer *peer.Pee
 	sm.processBusinessChan <- &blockMsg{block: block, buf: buf, peer: peer, reply: done}
 }
 
+// QueueCmpctBlock adds the passed cmpctblock message and peer to the block
+// handling queue. Responds to the done channel argument after the message is
+// processed.
+func (sm *SyncManager) QueueCmpctBlock(cmpctBlock *wire.MsgCmpctBlock, peer *peer.Peer, done chan<- struct{}) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	sm.processBusinessChan <- &cmpctBlockMsg{cmpctBlock: cmpctBlock, peer: peer, reply: done}
+}
+
+// QueueBlockTxn adds the passed blocktxn message and peer to the block handling
+// queue. Responds to the done channel argument after the message is processed.
+func (sm *SyncManager) QueueBlockTxn(blockTxn *wire.MsgBlockTxn, peer *peer.Peer, done chan<- struct{}) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	sm.processBusinessChan <- &blockTxnMsg{blockTxn: blockTxn, peer: peer, reply: done}
+}
+
 func (sm *SyncManager) QueueMessgePool(pool *wire.MsgMemPool, peer *peer.Peer, done chan<- struct{}) {
 	// Don't accept more blocks if we're shutting down.
 	if atomic.LoadInt32(&sm.shutdown) != 0 {
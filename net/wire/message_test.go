@@ -90,6 +90,12 @@ func TestMessage(t *testing.T) {
 	bh.Time = uint32(time.Now().Unix())
 	msgMerkleBlock := NewMsgMerkleBlock(bh)
 	msgReject := NewMsgReject("block", errcode.RejectDuplicate, "duplicate block")
+	msgGetCFilters := NewMsgGetCFilters(GCSFilterBasic, 0, &util.Hash{})
+	msgGetCFHeaders := NewMsgGetCFHeaders(GCSFilterBasic, 0, &util.Hash{})
+	msgGetCFCheckpt := NewMsgGetCFCheckpt(GCSFilterBasic, &util.Hash{})
+	msgCFilter := NewMsgCFilter(GCSFilterBasic, &util.Hash{}, []byte("payload"))
+	msgCFHeaders := NewMsgCFHeaders()
+	msgCFCheckpt := NewMsgCFCheckpt(GCSFilterBasic, &util.Hash{}, 0)
 
 	tests := []struct {
 		in     Message    // Value to encode
@@ -119,6 +125,12 @@ func TestMessage(t *testing.T) {
 		{msgFilterLoad, msgFilterLoad, pver, MainNet, 35},
 		{msgMerkleBlock, msgMerkleBlock, pver, MainNet, 110},
 		{msgReject, msgReject, pver, MainNet, 79},
+		{msgGetCFilters, msgGetCFilters, pver, MainNet, 61},
+		{msgGetCFHeaders, msgGetCFHeaders, pver, MainNet, 61},
+		{msgGetCFCheckpt, msgGetCFCheckpt, pver, MainNet, 57},
+		{msgCFilter, msgCFilter, pver, MainNet, 65},
+		{msgCFHeaders, msgCFHeaders, pver, MainNet, 90},
+		{msgCFCheckpt, msgCFCheckpt, pver, MainNet, 58},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -0,0 +1,138 @@
+// Copyright (c) 2017 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// MaxCFHeaderPayload is the maximum byte size of a committed
+	// filter header.
+	MaxCFHeaderPayload = util.Hash256Size
+
+	// MaxCFHeadersPerMsg is the maximum number of committed filter headers
+	// that can be in a single bitcoin cfheaders message.
+	MaxCFHeadersPerMsg = 2000
+)
+
+// MsgCFHeaders implements the Message interface and represents a bitcoin
+// cfheaders message. It is used to deliver committed filter header information
+// in response to a getcfheaders message (MsgGetCFHeaders). The maximum number
+// of committed filter headers per message is currently 2000. See
+// MsgGetCFHeaders for details on requesting the headers.
+type MsgCFHeaders struct {
+	FilterType       FilterType
+	StopHash         util.Hash
+	PrevFilterHeader util.Hash
+	FilterHashes     []*util.Hash
+}
+
+// AddCFHash adds a new filter hash to the message.
+func (msg *MsgCFHeaders) AddCFHash(hash *util.Hash) error {
+	if len(msg.FilterHashes)+1 > MaxCFHeadersPerMsg {
+		str := fmt.Sprintf("too many block headers in message [max %v]",
+			MaxBlockHeadersPerMsg)
+		return messageError("MsgCFHeaders.AddCFHash", str)
+	}
+
+	msg.FilterHashes = append(msg.FilterHashes, hash)
+	return nil
+}
+
+// Decode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCFHeaders) Decode(r io.Reader, pver uint32, _ MessageEncoding) error {
+	err := util.ReadElements(r, &msg.FilterType, &msg.StopHash, &msg.PrevFilterHeader)
+	if err != nil {
+		return err
+	}
+
+	// Read number of filter headers.
+	count, err := util.ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+
+	// Limit to max committed filter headers per message.
+	if count > MaxCFHeadersPerMsg {
+		str := fmt.Sprintf("too many committed filter headers for "+
+			"message [count %v, max %v]", count,
+			MaxBlockHeadersPerMsg)
+		return messageError("MsgCFHeaders.Decode", str)
+	}
+
+	// Create a contiguous slice of hashes to deserialize into in order to
+	// reduce the number of allocations.
+	msg.FilterHashes = make([]*util.Hash, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var cfh util.Hash
+		err := util.ReadElements(r, &cfh)
+		if err != nil {
+			return err
+		}
+		msg.AddCFHash(&cfh)
+	}
+
+	return nil
+}
+
+// Encode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCFHeaders) Encode(w io.Writer, pver uint32, _ MessageEncoding) error {
+	// Limit to max committed headers per message.
+	count := len(msg.FilterHashes)
+	if count > MaxCFHeadersPerMsg {
+		str := fmt.Sprintf("too many committed filter headers for "+
+			"message [count %v, max %v]", count,
+			MaxBlockHeadersPerMsg)
+		return messageError("MsgCFHeaders.Encode", str)
+	}
+
+	err := util.WriteElements(w, msg.FilterType, &msg.StopHash, &msg.PrevFilterHeader)
+	if err != nil {
+		return err
+	}
+
+	err = util.WriteVarInt(w, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, cfh := range msg.FilterHashes {
+		err := util.WriteElements(w, cfh)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgCFHeaders) Command() string {
+	return CmdCFHeaders
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver. This is part of the Message interface implementation.
+func (msg *MsgCFHeaders) MaxPayloadLength(pver uint32) uint64 {
+	// Hash size + filter type + num headers (varInt) +
+	// (header size * max headers).
+	return 1 + util.Hash256Size + util.Hash256Size + MaxVarIntPayload +
+		(MaxCFHeaderPayload * MaxCFHeadersPerMsg)
+}
+
+// NewMsgCFHeaders returns a new bitcoin cfheaders message that conforms to
+// the Message interface. See MsgCFHeaders for details.
+func NewMsgCFHeaders() *MsgCFHeaders {
+	return &MsgCFHeaders{
+		FilterHashes: make([]*util.Hash, 0, MaxCFHeadersPerMsg),
+	}
+}
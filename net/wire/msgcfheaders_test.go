@@ -0,0 +1,89 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestCFHeadersWire tests the MsgCFHeaders wire encode and decode.
+func TestCFHeadersWire(t *testing.T) {
+	stopHash := util.HashFromString("000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943")
+	msg := NewMsgCFHeaders()
+	msg.FilterType = GCSFilterBasic
+	msg.StopHash = *stopHash
+	msg.PrevFilterHeader = util.Hash{0x01}
+	for i := 0; i < 3; i++ {
+		if err := msg.AddCFHash(&util.Hash{byte(i + 2)}); err != nil {
+			t.Fatalf("AddCFHash failed: %v", err)
+		}
+	}
+	if cmd := msg.Command(); cmd != "cfheaders" {
+		t.Errorf("NewMsgCFHeaders: wrong command - got %v want %v",
+			cmd, "cfheaders")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("encode of MsgCFHeaders failed %v", err)
+	}
+	if want := 1 + 32 + 32 + 1 + 3*32; buf.Len() != want {
+		t.Errorf("Encode: wrong size - got %v, want %v", buf.Len(), want)
+	}
+
+	var readmsg MsgCFHeaders
+	if err := readmsg.Decode(bytes.NewReader(buf.Bytes()), ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("decode of MsgCFHeaders failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("Decode: wrong msg - got %v, want %v",
+			spew.Sdump(&readmsg), spew.Sdump(msg))
+	}
+
+	// Too many filter hashes are refused.
+	for i := len(msg.FilterHashes); i < MaxCFHeadersPerMsg; i++ {
+		msg.AddCFHash(&util.Hash{})
+	}
+	if err := msg.AddCFHash(&util.Hash{}); err == nil {
+		t.Errorf("AddCFHash succeeded beyond %d hashes", MaxCFHeadersPerMsg)
+	}
+	msg.FilterHashes = append(msg.FilterHashes, &util.Hash{})
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err == nil {
+		t.Errorf("encode of MsgCFHeaders succeeded with %d hashes", len(msg.FilterHashes))
+	}
+}
+
+// TestCFCheckptWire tests the MsgCFCheckpt wire encode and decode.
+func TestCFCheckptWire(t *testing.T) {
+	stopHash := util.HashFromString("000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943")
+	msg := NewMsgCFCheckpt(GCSFilterBasic, stopHash, 2)
+	msg.AddCFHeader(&util.Hash{0x01})
+	msg.AddCFHeader(&util.Hash{0x02})
+	if err := msg.AddCFHeader(&util.Hash{0x03}); err == nil {
+		t.Errorf("AddCFHeader succeeded beyond the capacity of the message")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("encode of MsgCFCheckpt failed %v", err)
+	}
+	var readmsg MsgCFCheckpt
+	if err := readmsg.Decode(bytes.NewReader(buf.Bytes()), ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("decode of MsgCFCheckpt failed %v", err)
+	}
+	if readmsg.StopHash != *stopHash || !reflect.DeepEqual(readmsg.FilterHeaders, msg.FilterHeaders) {
+		t.Errorf("Decode: wrong msg - got %v, want %v",
+			spew.Sdump(&readmsg), spew.Sdump(msg))
+	}
+
+	// An insane number of headers is refused.
+	insane := []byte{byte(GCSFilterBasic)}
+	insane = append(insane, stopHash[:]...)
+	insane = append(insane, 0xfe, 0xff, 0xff, 0xff, 0x00)
+	if err := readmsg.Decode(bytes.NewReader(insane), ProtocolVersion, BaseEncoding); err != ErrInsaneCFHeaderCount {
+		t.Errorf("Decode: want %v, got %v", ErrInsaneCFHeaderCount, err)
+	}
+}
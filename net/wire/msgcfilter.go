@@ -0,0 +1,90 @@
+// Copyright (c) 2017 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/copernet/copernicus/util"
+)
+
+// FilterType is used to represent a filter type.
+type FilterType uint8
+
+const (
+	// GCSFilterBasic is the basic filter type of BIP0158, built from the
+	// output scripts and the scripts of the outputs spent by a block.
+	GCSFilterBasic FilterType = iota
+)
+
+const (
+	// MaxCFilterDataSize is the maximum byte size of a committed filter.
+	// The maximum size is currently defined as 256KiB.
+	MaxCFilterDataSize = 256 * 1024
+)
+
+// MsgCFilter implements the Message interface and represents a bitcoin cfilter
+// message. It is used to deliver a committed filter in response to a
+// getcfilters (MsgGetCFilters) message.
+type MsgCFilter struct {
+	FilterType FilterType
+	BlockHash  util.Hash
+	Data       []byte
+}
+
+// Decode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCFilter) Decode(r io.Reader, pver uint32, _ MessageEncoding) error {
+	err := util.ReadElements(r, &msg.FilterType, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	msg.Data, err = util.ReadVarBytes(r, MaxCFilterDataSize, "cfilter data")
+	return err
+}
+
+// Encode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCFilter) Encode(w io.Writer, pver uint32, _ MessageEncoding) error {
+	size := len(msg.Data)
+	if size > MaxCFilterDataSize {
+		str := fmt.Sprintf("cfilter size too large for message "+
+			"[size %v, max %v]", size, MaxCFilterDataSize)
+		return messageError("MsgCFilter.Encode", str)
+	}
+
+	err := util.WriteElements(w, msg.FilterType, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	return util.WriteVarBytes(w, msg.Data)
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgCFilter) Command() string {
+	return CmdCFilter
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver. This is part of the Message interface implementation.
+func (msg *MsgCFilter) MaxPayloadLength(pver uint32) uint64 {
+	return uint64(util.VarIntSerializeSize(MaxCFilterDataSize)) +
+		MaxCFilterDataSize + util.Hash256Size + 1
+}
+
+// NewMsgCFilter returns a new bitcoin cfilter message that conforms to the
+// Message interface. See MsgCFilter for details.
+func NewMsgCFilter(filterType FilterType, blockHash *util.Hash,
+	data []byte) *MsgCFilter {
+	return &MsgCFilter{
+		FilterType: filterType,
+		BlockHash:  *blockHash,
+		Data:       data,
+	}
+}
@@ -1,6 +1,5 @@
 package wire
 
-/*
 import (
 	"bytes"
 	"encoding/binary"
@@ -8,91 +7,81 @@ import (
 	"io"
 	"math"
 
+	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/util"
 )
 
-const ShortTxIDsLength = 6
+// ShortTxIDLen is the length in bytes of a BIP152 short transaction id.
+const ShortTxIDLen = 6
 
-type PreFilledTransaction struct {
-	Tx    *MsgTx
-	Index uint16
+// PrefilledTx is a transaction sent in full within a compact block, along
+// with its absolute index in the block. On the wire the index is encoded as
+// the difference from the previous prefilled index.
+type PrefilledTx struct {
+	Index uint32
+	Tx    *tx.Tx
 }
 
+// MsgCmpctBlock implements the Message interface and represents a bitcoin
+// cmpctblock message (BIP152). It carries a block header and the short ids of
+// its transactions, so that the receiver can rebuild the block from its own
+// mempool. The coinbase, which the receiver cannot know, is always prefilled.
 type MsgCmpctBlock struct {
-	shortTxidk0  uint64
-	shortTxidk1  uint64
-	Nonce        uint64
-	ShortTxids   []uint64
-	PreFilledTxn []PreFilledTransaction
 	Header       block.BlockHeader
+	Nonce        uint64
+	ShortTxIDs   []uint64
+	PrefilledTxs []PrefilledTx
 }
 
-func NewMsgCmpctBlock(block *MsgBlock) *MsgCmpctBlock {
-	nonce, _ := util.RandomUint64()
-	shortids := make([]uint64, len(block.Txs)-1)
-	prefilledTxn := make([]PreFilledTransaction, 1)
-	header := block.Header
-
-	id0, id1, err := fillShortTxIDSelector(&header, nonce)
+// NewMsgCmpctBlock returns a new bitcoin cmpctblock message for blk, with a
+// random nonce and only the coinbase prefilled.
+func NewMsgCmpctBlock(blk *block.Block) (*MsgCmpctBlock, error) {
+	nonce, err := util.RandomUint64()
 	if err != nil {
-		return nil
-	}
-	prefilledTxn[0].Index = 0
-	prefilledTxn[0].Tx = (*MsgTx)(block.Txs[0])
-	for i := 1; i < len(block.Txs); i++ {
-		txhash := block.Txs[i].GetHash()
-		shortids[i-1] = getShortID(id0, id1, &txhash)
-	}
-	return &MsgCmpctBlock{
-		shortTxidk0:  id0,
-		shortTxidk1:  id1,
-		Nonce:        nonce,
-		ShortTxids:   shortids,
-		PreFilledTxn: prefilledTxn,
-		Header:       header,
+		return nil, err
 	}
+	return newMsgCmpctBlockWithNonce(blk, nonce), nil
 }
 
-func fillShortTxIDSelector(h *block.BlockHeader, nonce uint64) (uint64, uint64, error) {
-	bw := bytes.NewBuffer(nil)
-	if err := h.Serialize(bw); err != nil {
-		return 0, 0, err
+func newMsgCmpctBlockWithNonce(blk *block.Block, nonce uint64) *MsgCmpctBlock {
+	msg := &MsgCmpctBlock{
+		Header:       blk.Header,
+		Nonce:        nonce,
+		ShortTxIDs:   make([]uint64, 0, len(blk.Txs)),
+		PrefilledTxs: []PrefilledTx{{Index: 0, Tx: blk.Txs[0]}},
 	}
-	if err := util.WriteElements(bw, nonce); err != nil {
-		return 0, 0, err
+	k0, k1 := msg.ShortIDKeys()
+	for _, txn := range blk.Txs[1:] {
+		txid := txn.GetHash()
+		msg.ShortTxIDs = append(msg.ShortTxIDs, ShortTxID(k0, k1, &txid))
 	}
-	hb := util.Sha256Bytes(bw.Bytes())
-	return binary.LittleEndian.Uint64(hb[0:8]), binary.LittleEndian.Uint64(hb[8:16]), nil
+	return msg
 }
 
-func getShortID(id0, id1 uint64, hash *util.Hash) uint64 {
-	return util.SipHash(id0, id1, (*hash)[:]) & 0xffffffffffff
+// BlockTxCount returns the number of transactions of the announced block.
+func (msg *MsgCmpctBlock) BlockTxCount() int {
+	return len(msg.ShortTxIDs) + len(msg.PrefilledTxs)
 }
 
-func (pft *PreFilledTransaction) Decode(r io.Reader, pver uint32, enc MessageEncoding) error {
-	idx, err := util.ReadVarInt(r)
-	if err != nil {
-		return err
-	}
-	if idx > math.MaxUint16 {
-		return messageError("MsgCmpctBlock.Decode", fmt.Sprintf("index overflowed 16-bits"))
-	}
-	pft.Index = uint16(idx)
-	err = pft.Tx.Decode(r, pver, enc)
-	return err
+// ShortIDKeys returns the SipHash keys used to compute the short ids of this
+// block: the first two little endian 64 bits words of SHA256(header || nonce).
+func (msg *MsgCmpctBlock) ShortIDKeys() (uint64, uint64) {
+	buf := bytes.NewBuffer(make([]byte, 0, MaxBlockHeaderPayload+8))
+	msg.Header.Serialize(buf)
+	util.WriteElements(buf, msg.Nonce)
+	hash := util.Sha256Bytes(buf.Bytes())
+	return binary.LittleEndian.Uint64(hash[0:8]), binary.LittleEndian.Uint64(hash[8:16])
 }
 
-func (pft *PreFilledTransaction) Encode(w io.Writer, pver uint32, enc MessageEncoding) error {
-	if err := util.WriteVarInt(w, uint64(pft.Index)); err != nil {
-		return err
-	}
-	if err := pft.Tx.Encode(w, pver, enc); err != nil {
-		return err
-	}
-	return nil
+// ShortTxID computes the 6 bytes short id of txid for the given SipHash keys.
+func ShortTxID(k0, k1 uint64, txid *util.Hash) uint64 {
+	return util.SipHash(k0, k1, txid[:]) & 0xffffffffffff
 }
 
+// Decode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
 func (msg *MsgCmpctBlock) Decode(r io.Reader, pver uint32, enc MessageEncoding) error {
 	if pver < ShortIdsBlocksVersion {
 		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
@@ -105,42 +94,56 @@ func (msg *MsgCmpctBlock) Decode(r io.Reader, pver uint32, enc MessageEncoding)
 	if err := util.ReadElements(r, &msg.Nonce); err != nil {
 		return err
 	}
-	shortIDSize, err := util.ReadVarInt(r)
+
+	count, err := util.ReadVarInt(r)
 	if err != nil {
 		return err
 	}
-	ids := make([]uint64, shortIDSize)
-	for i := 0; i < len(ids); i++ {
-		lsb := uint32(0)
-		msb := uint16(0)
-		if err := util.ReadElements(r, &lsb); err != nil {
-			return err
-		}
-		if err := util.ReadElements(r, &msb); err != nil {
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many short ids in cmpctblock [%v]", count)
+		return messageError("MsgCmpctBlock.Decode", str)
+	}
+	msg.ShortTxIDs = make([]uint64, count)
+	var id [ShortTxIDLen]byte
+	for i := range msg.ShortTxIDs {
+		if _, err := io.ReadFull(r, id[:]); err != nil {
 			return err
 		}
-		ids[i] = (uint64(msb) << 32) | uint64(lsb)
+		msg.ShortTxIDs[i] = uint64(binary.LittleEndian.Uint32(id[:4])) |
+			uint64(binary.LittleEndian.Uint16(id[4:]))<<32
 	}
-	msg.ShortTxids = append(msg.ShortTxids, ids...)
-	pftLen, err := util.ReadVarInt(r)
+
+	count, err = util.ReadVarInt(r)
 	if err != nil {
 		return err
 	}
-	vpft := make([]PreFilledTransaction, pftLen)
-	for i := 0; i < len(vpft); i++ {
-		if err := vpft[i].Decode(r, pver, enc); err != nil {
+	if count > maxTxPerBlock-uint64(len(msg.ShortTxIDs)) {
+		str := fmt.Sprintf("too many prefilled transactions in cmpctblock [%v]", count)
+		return messageError("MsgCmpctBlock.Decode", str)
+	}
+	msg.PrefilledTxs = make([]PrefilledTx, count)
+	var next uint64
+	for i := range msg.PrefilledTxs {
+		diff, err := util.ReadVarInt(r)
+		if err != nil {
 			return err
 		}
+		index := next + diff
+		if diff > math.MaxUint32 || index > math.MaxUint32 {
+			return messageError("MsgCmpctBlock.Decode", "prefilled index overflowed 32 bits")
+		}
+		txn := tx.NewTx(0, tx.DefaultVersion)
+		if err := txn.Unserialize(r); err != nil {
+			return err
+		}
+		msg.PrefilledTxs[i] = PrefilledTx{Index: uint32(index), Tx: txn}
+		next = index + 1
 	}
-	id0, id1, err := fillShortTxIDSelector(&msg.Header, msg.Nonce)
-	if err != nil {
-		return err
-	}
-	msg.shortTxidk0 = id0
-	msg.shortTxidk1 = id1
 	return nil
 }
 
+// Encode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
 func (msg *MsgCmpctBlock) Encode(w io.Writer, pver uint32, enc MessageEncoding) error {
 	if pver < ShortIdsBlocksVersion {
 		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
@@ -150,37 +153,49 @@ func (msg *MsgCmpctBlock) Encode(w io.Writer, pver uint32, enc MessageEncoding)
 	if err := msg.Header.Serialize(w); err != nil {
 		return err
 	}
-	if err := util.WriteElements(w, &msg.Nonce); err != nil {
+	if err := util.WriteElements(w, msg.Nonce); err != nil {
 		return err
 	}
-	if err := util.WriteVarInt(w, uint64(len(msg.ShortTxids))); err != nil {
+
+	if err := util.WriteVarInt(w, uint64(len(msg.ShortTxIDs))); err != nil {
 		return err
 	}
-	for i := 0; i < len(msg.ShortTxids); i++ {
-		var lsb uint32
-		var msb uint16
-		lsb = uint32(msg.ShortTxids[i] & 0xffffffff)
-		msb = uint16((msg.ShortTxids[i] >> 32) & 0xffff)
-		if err := util.WriteElements(w, lsb); err != nil {
+	var id [ShortTxIDLen]byte
+	for _, shortID := range msg.ShortTxIDs {
+		binary.LittleEndian.PutUint32(id[:4], uint32(shortID))
+		binary.LittleEndian.PutUint16(id[4:], uint16(shortID>>32))
+		if _, err := w.Write(id[:]); err != nil {
 			return err
 		}
-		if err := util.WriteElements(w, msb); err != nil {
+	}
+
+	if err := util.WriteVarInt(w, uint64(len(msg.PrefilledTxs))); err != nil {
+		return err
+	}
+	var next uint32
+	for i, prefilled := range msg.PrefilledTxs {
+		if i > 0 && prefilled.Index < next {
+			return messageError("MsgCmpctBlock.Encode", "prefilled indexes are not increasing")
+		}
+		if err := util.WriteVarInt(w, uint64(prefilled.Index-next)); err != nil {
 			return err
 		}
-	}
-	for i := 0; i < len(msg.PreFilledTxn); i++ {
-		if err := msg.PreFilledTxn[i].Encode(w, pver, enc); err != nil {
+		if err := prefilled.Tx.Serialize(w); err != nil {
 			return err
 		}
+		next = prefilled.Index + 1
 	}
 	return nil
 }
 
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
 func (msg *MsgCmpctBlock) Command() string {
 	return CmdCmpctBlock
 }
 
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
 func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint64 {
-	return 80 + 8 + 3 + uint64(len(msg.ShortTxids))*6 + 3 + uint64(len(msg.PreFilledTxn))*(3+MaxBlockPayload)
+	return conf.Cfg.Excessiveblocksize
 }
-*/
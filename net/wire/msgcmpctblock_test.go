@@ -0,0 +1,138 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+// newTestTxs returns count distinct transactions, the first one a coinbase.
+func newTestTxs(count int) []*tx.Tx {
+	txs := make([]*tx.Tx, count)
+	for i := range txs {
+		txn := tx.NewTx(0, tx.DefaultVersion)
+		prevOut := outpoint.NewOutPoint(util.Hash{1}, uint32(i))
+		if i == 0 {
+			prevOut = outpoint.NewDefaultOutPoint()
+		}
+		txn.AddTxIn(txin.NewTxIn(prevOut, script.NewScriptRaw([]byte{0x01, byte(i)}), 0xffffffff))
+		txn.AddTxOut(txout.NewTxOut(amount.Amount(1000+i), script.NewScriptRaw([]byte{0x51})))
+		txs[i] = txn
+	}
+	return txs
+}
+
+// TestCmpctBlockWire tests the MsgCmpctBlock wire encode and decode.
+func TestCmpctBlockWire(t *testing.T) {
+	blk := block.NewBlock()
+	blk.Header.Version = 1
+	blk.Header.Time = 1231006505
+	blk.Txs = newTestTxs(4)
+
+	msg := newMsgCmpctBlockWithNonce(blk, 0x0102030405060708)
+	if cmd := msg.Command(); cmd != "cmpctblock" {
+		t.Errorf("NewMsgCmpctBlock: wrong command - got %v want %v",
+			cmd, "cmpctblock")
+	}
+	if msg.BlockTxCount() != len(blk.Txs) {
+		t.Errorf("BlockTxCount: got %d, want %d", msg.BlockTxCount(), len(blk.Txs))
+	}
+	if len(msg.PrefilledTxs) != 1 || msg.PrefilledTxs[0].Index != 0 ||
+		msg.PrefilledTxs[0].Tx != blk.Txs[0] {
+		t.Errorf("NewMsgCmpctBlock: coinbase is not prefilled")
+	}
+
+	k0, k1 := msg.ShortIDKeys()
+	for i, shortID := range msg.ShortTxIDs {
+		txid := blk.Txs[i+1].GetHash()
+		if shortID != ShortTxID(k0, k1, &txid) {
+			t.Errorf("short id %d mismatch", i)
+		}
+		if shortID>>(ShortTxIDLen*8) != 0 {
+			t.Errorf("short id %d is longer than %d bytes", i, ShortTxIDLen)
+		}
+	}
+
+	// Prefill another transaction to check the differential indexes.
+	msg.PrefilledTxs = append(msg.PrefilledTxs, PrefilledTx{Index: 2, Tx: blk.Txs[2]})
+	msg.ShortTxIDs = append(msg.ShortTxIDs[:1], msg.ShortTxIDs[2:]...)
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("encode of MsgCmpctBlock failed %v", err)
+	}
+	wantLen := MaxBlockHeaderPayload + 8 + 1 + 2*ShortTxIDLen + 1 +
+		1 + int(blk.Txs[0].EncodeSize()) + 1 + int(blk.Txs[2].EncodeSize())
+	if buf.Len() != wantLen {
+		t.Errorf("Encode: wrong length - got %d, want %d", buf.Len(), wantLen)
+	}
+
+	var readmsg MsgCmpctBlock
+	if err := readmsg.Decode(bytes.NewReader(buf.Bytes()), ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("decode of MsgCmpctBlock failed %v", err)
+	}
+	if readmsg.Header.GetHash() != blk.GetHash() || readmsg.Nonce != msg.Nonce {
+		t.Errorf("Decode: wrong header or nonce")
+	}
+	if len(readmsg.ShortTxIDs) != 2 || readmsg.ShortTxIDs[0] != msg.ShortTxIDs[0] ||
+		readmsg.ShortTxIDs[1] != msg.ShortTxIDs[1] {
+		t.Errorf("Decode: wrong short ids %v, want %v", readmsg.ShortTxIDs, msg.ShortTxIDs)
+	}
+	if len(readmsg.PrefilledTxs) != 2 {
+		t.Fatalf("Decode: got %d prefilled txs, want 2", len(readmsg.PrefilledTxs))
+	}
+	for i, prefilled := range readmsg.PrefilledTxs {
+		want := msg.PrefilledTxs[i]
+		if prefilled.Index != want.Index || prefilled.Tx.GetHash() != want.Tx.GetHash() {
+			t.Errorf("Decode: prefilled tx %d mismatch", i)
+		}
+	}
+
+	// Prefilled indexes must be strictly increasing.
+	msg.PrefilledTxs[1].Index = 0
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err == nil {
+		t.Errorf("encode of MsgCmpctBlock with duplicated indexes succeeded")
+	}
+
+	pver := ShortIdsBlocksVersion - 1
+	if err := msg.Encode(&buf, pver, BaseEncoding); err == nil {
+		t.Errorf("encode of MsgCmpctBlock succeeded for protocol version %d", pver)
+	}
+}
+
+// TestBlockTxnWire tests the MsgBlockTxn wire encode and decode.
+func TestBlockTxnWire(t *testing.T) {
+	hash := util.Hash{0xab}
+	txs := newTestTxs(3)[1:]
+	msg := NewMsgBlockTxn(&hash, txs)
+	if cmd := msg.Command(); cmd != "blocktxn" {
+		t.Errorf("NewMsgBlockTxn: wrong command - got %v want %v",
+			cmd, "blocktxn")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("encode of MsgBlockTxn failed %v", err)
+	}
+
+	var readmsg MsgBlockTxn
+	if err := readmsg.Decode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("decode of MsgBlockTxn failed %v", err)
+	}
+	if readmsg.BlockHash != hash || len(readmsg.Txs) != len(txs) {
+		t.Fatalf("Decode: wrong msg %v", readmsg)
+	}
+	for i, txn := range readmsg.Txs {
+		if txn.GetHash() != txs[i].GetHash() {
+			t.Errorf("Decode: tx %d mismatch", i)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetBlockTxnWire tests the MsgGetBlockTxn wire encode and decode, in
+// particular the differential encoding of the indexes.
+func TestGetBlockTxnWire(t *testing.T) {
+	hash := util.HashFromString("000000000000000000c2ba3b0e1bbd0ccd2b1dfa3f47c2cf2d7e4ae2c0cf86c8")
+	msg := NewMsgGetBlockTxn(hash, []uint32{1, 2, 5, 300})
+	if cmd := msg.Command(); cmd != "getblocktxn" {
+		t.Errorf("NewMsgGetBlockTxn: wrong command - got %v want %v",
+			cmd, "getblocktxn")
+	}
+
+	wantBuf := append(hash[:], 0x04, 0x01, 0x00, 0x02, 0xfd, 0x26, 0x01)
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("encode of MsgGetBlockTxn failed %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), wantBuf) {
+		t.Errorf("Encode: wrong bytes - got %v, want %v",
+			spew.Sdump(buf.Bytes()), spew.Sdump(wantBuf))
+	}
+
+	var readmsg MsgGetBlockTxn
+	if err := readmsg.Decode(bytes.NewReader(wantBuf), ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("decode of MsgGetBlockTxn failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("Decode: wrong msg - got %v, want %v",
+			spew.Sdump(&readmsg), spew.Sdump(msg))
+	}
+
+	// Indexes must be strictly increasing.
+	msg = NewMsgGetBlockTxn(hash, []uint32{2, 2})
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err == nil {
+		t.Errorf("encode of MsgGetBlockTxn with duplicated indexes succeeded")
+	}
+
+	// An index which overflows 32 bits is rejected.
+	overflowBuf := append(hash[:], 0x02, 0xfe, 0xff, 0xff, 0xff, 0xff, 0x00)
+	if err := readmsg.Decode(bytes.NewReader(overflowBuf), ProtocolVersion, BaseEncoding); err == nil {
+		t.Errorf("decode of MsgGetBlockTxn with overflowing index succeeded")
+	}
+}
@@ -1,6 +1,5 @@
 package wire
 
-/*
 import (
 	"fmt"
 	"io"
@@ -8,20 +7,32 @@ import (
 	"github.com/copernet/copernicus/util"
 )
 
+// CmpctBlockVersion is the only compact block encoding version we support, as
+// specified by BIP152 for nodes without segwit.
+const CmpctBlockVersion uint64 = 1
+
+// MsgSendCmpct implements the Message interface and represents a bitcoin
+// sendcmpct message. It is used to negotiate compact block relay (BIP152) and
+// to ask the peer to announce new blocks with cmpctblock messages directly
+// (high bandwidth mode) rather than with inv or headers (low bandwidth mode).
 type MsgSendCmpct struct {
 	AnnounceUsingCmpctBlock bool
 	CmpctBlockVersion       uint64
 }
 
+// Decode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
 func (msg *MsgSendCmpct) Decode(r io.Reader, pver uint32, enc MessageEncoding) error {
 	if pver < ShortIdsBlocksVersion {
 		str := fmt.Sprintf("sendcmpct message invalid for protocol "+
 			"version %d", pver)
-		return messageError("MsgSendCmpt.Decode", str)
+		return messageError("MsgSendCmpct.Decode", str)
 	}
 	return util.ReadElements(r, &msg.AnnounceUsingCmpctBlock, &msg.CmpctBlockVersion)
 }
 
+// Encode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
 func (msg *MsgSendCmpct) Encode(w io.Writer, pver uint32, enc MessageEncoding) error {
 	if pver < ShortIdsBlocksVersion {
 		str := fmt.Sprintf("sendcmpct message invalid for protocol "+
@@ -31,18 +42,24 @@ func (msg *MsgSendCmpct) Encode(w io.Writer, pver uint32, enc MessageEncoding) e
 	return util.WriteElements(w, msg.AnnounceUsingCmpctBlock, msg.CmpctBlockVersion)
 }
 
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
 func (msg *MsgSendCmpct) Command() string {
 	return CmdSendCmpct
 }
 
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
 func (msg *MsgSendCmpct) MaxPayloadLength(pver uint32) uint64 {
+	// announce flag 1 byte + version 8 bytes
 	return 9
 }
 
+// NewMsgSendCmpct returns a new bitcoin sendcmpct message that conforms to
+// the Message interface.  See MsgSendCmpct for details.
 func NewMsgSendCmpct(announce bool, version uint64) *MsgSendCmpct {
 	return &MsgSendCmpct{
 		AnnounceUsingCmpctBlock: announce,
 		CmpctBlockVersion:       version,
 	}
 }
-*/
@@ -0,0 +1,50 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestSendCmpctWire tests the MsgSendCmpct wire encode and decode.
+func TestSendCmpctWire(t *testing.T) {
+	msg := NewMsgSendCmpct(true, CmpctBlockVersion)
+	if cmd := msg.Command(); cmd != "sendcmpct" {
+		t.Errorf("NewMsgSendCmpct: wrong command - got %v want %v",
+			cmd, "sendcmpct")
+	}
+	if maxPayload := msg.MaxPayloadLength(ProtocolVersion); maxPayload != 9 {
+		t.Errorf("MaxPayloadLength: wrong max payload length - got %v, want %v",
+			maxPayload, 9)
+	}
+
+	wantBuf := []byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("encode of MsgSendCmpct failed %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), wantBuf) {
+		t.Errorf("Encode: wrong bytes - got %v, want %v",
+			spew.Sdump(buf.Bytes()), spew.Sdump(wantBuf))
+	}
+
+	var readmsg MsgSendCmpct
+	if err := readmsg.Decode(bytes.NewReader(wantBuf), ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("decode of MsgSendCmpct failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("Decode: wrong msg - got %v, want %v",
+			spew.Sdump(&readmsg), spew.Sdump(msg))
+	}
+
+	// sendcmpct is unknown before short ids blocks version.
+	pver := ShortIdsBlocksVersion - 1
+	if err := msg.Encode(&buf, pver, BaseEncoding); err == nil {
+		t.Errorf("encode of MsgSendCmpct succeeded for protocol version %d", pver)
+	}
+	if err := readmsg.Decode(bytes.NewReader(wantBuf), pver, BaseEncoding); err == nil {
+		t.Errorf("decode of MsgSendCmpct succeeded for protocol version %d", pver)
+	}
+}
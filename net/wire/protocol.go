@@ -12,7 +12,7 @@ import (
 
 const (
 	// ProtocolVersion is the latest protocol version this package supports.
-	ProtocolVersion uint32 = 70013
+	ProtocolVersion uint32 = 70015
 
 	// MultipleAddressVersion is the protocol version which added multiple
 	// addresses per message (pver >= MultipleAddressVersion).
@@ -88,6 +88,10 @@ const (
 	// needed.
 	SFNodeCash
 
+	// SFNodeCF is a flag used to indicate a peer supports serving the
+	// committed block filters of BIP0157.
+	SFNodeCF ServiceFlag = 1 << 6
+
 	// Bits 24-31 are reserved for temporary experiments. Just pick a bit that
 	// isn't getting used, or one not being used much, and notify the
 	// bitcoin-development mailing list. Remember that service bits are just
@@ -104,6 +108,7 @@ var sfStrings = map[ServiceFlag]string{
 	SFNodeBloom:   "SFNodeBloom",
 	SFNodeXthin:   "SFNodeXthin",
 	SFNodeCash:    "SFNodeCash",
+	SFNodeCF:      "SFNodeCF",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -114,6 +119,7 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeBloom,
 	SFNodeXthin,
 	SFNodeCash,
+	SFNodeCF,
 }
 
 // String returns the ServiceFlag in human-readable form.
@@ -18,7 +18,8 @@ func TestServiceFlagStringer(t *testing.T) {
 		{SFNodeBloom, "SFNodeBloom"},
 		{SFNodeXthin, "SFNodeXthin"},
 		{SFNodeCash, "SFNodeCash"},
-		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|SFNodeXthin|SFNodeCash|0xffffffe0"},
+		{SFNodeCF, "SFNodeCF"},
+		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|SFNodeXthin|SFNodeCash|SFNodeCF|0xffffffa0"},
 	}
 
 	t.Logf("Running %d tests", len(tests))
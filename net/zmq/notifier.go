@@ -0,0 +1,222 @@
+// Package zmq publishes the blocks connected to the main chain and the
+// transactions accepted into the mempool over the ZeroMQ PUB/SUB protocol.
+//
+// The messages use the same layout as the other Bitcoin Cash nodes, so that
+// existing subscribers work unchanged.  Each message is made of three frames:
+// the topic, the body and a 4 bytes little endian sequence number that is
+// incremented for every message published on the topic.  The topics are:
+//
+//	hashblock: the 32 bytes hash of the block, in display order
+//	hashtx:    the 32 bytes hash of the transaction, in display order
+//	rawblock:  the serialized block
+//	rawtx:     the serialized transaction
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/util"
+)
+
+// Topics of the published messages.
+const (
+	TopicHashBlock = "hashblock"
+	TopicHashTx    = "hashtx"
+	TopicRawBlock  = "rawblock"
+	TopicRawTx     = "rawtx"
+)
+
+// Notifier publishes the chain and mempool events on the configured
+// endpoints.  Several topics may share the same endpoint.
+type Notifier struct {
+	// publishers maps the endpoints to their publisher.
+	publishers map[string]*publisher
+
+	// topics maps the enabled topics to their publisher.
+	topics map[string]*publisher
+
+	mtx       sync.Mutex
+	sequences map[string]uint32
+	started   bool
+	stopped   bool
+}
+
+// NewNotifier binds the endpoints of the passed topics, which map a topic to
+// a tcp endpoint such as tcp://127.0.0.1:28332.
+func NewNotifier(endpoints map[string]string) (*Notifier, error) {
+	n := &Notifier{
+		publishers: make(map[string]*publisher),
+		topics:     make(map[string]*publisher),
+		sequences:  make(map[string]uint32),
+	}
+
+	for topic, endpoint := range endpoints {
+		p, ok := n.publishers[endpoint]
+		if !ok {
+			var err error
+			p, err = newPublisher(endpoint)
+			if err != nil {
+				n.close()
+				return nil, err
+			}
+			n.publishers[endpoint] = p
+		}
+		n.topics[topic] = p
+	}
+
+	return n, nil
+}
+
+// InitNotifier creates a notifier for the topics enabled in the
+// configuration.  It returns nil when none is enabled.
+func InitNotifier() (*Notifier, error) {
+	endpoints := make(map[string]string)
+	for topic, endpoint := range map[string]string{
+		TopicHashBlock: conf.Cfg.ZMQ.PubHashBlock,
+		TopicHashTx:    conf.Cfg.ZMQ.PubHashTx,
+		TopicRawBlock:  conf.Cfg.ZMQ.PubRawBlock,
+		TopicRawTx:     conf.Cfg.ZMQ.PubRawTx,
+	} {
+		if endpoint != "" {
+			endpoints[topic] = endpoint
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+
+	return NewNotifier(endpoints)
+}
+
+// Start begins accepting subscribers and publishing the blocks connected to
+// the main chain and the transactions accepted into the mempool.
+func (n *Notifier) Start() {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if n.started {
+		return
+	}
+	n.started = true
+
+	for endpoint, p := range n.publishers {
+		p.start()
+		log.Info("zmq: publishing on %s", endpoint)
+	}
+
+	chain.GetInstance().Subscribe(n.handleBlockchainNotification)
+	lmempool.SubscribeTxAccepted(n.handleTxAccepted)
+}
+
+// Stop disconnects the subscribers and stops publishing.
+func (n *Notifier) Stop() {
+	n.mtx.Lock()
+	if n.stopped {
+		n.mtx.Unlock()
+		return
+	}
+	n.stopped = true
+	n.mtx.Unlock()
+
+	n.close()
+}
+
+func (n *Notifier) close() {
+	for _, p := range n.publishers {
+		p.stop()
+	}
+}
+
+// handleBlockchainNotification publishes the connected blocks along with
+// their transactions.
+func (n *Notifier) handleBlockchainNotification(notification *chain.Notification) {
+	if notification.Type != chain.NTBlockConnected {
+		return
+	}
+	blk, ok := notification.Data.(*block.Block)
+	if !ok {
+		log.Warn("zmq: chain notification is not a block.")
+		return
+	}
+
+	for _, txn := range blk.Txs {
+		n.NotifyTransaction(txn)
+	}
+	n.NotifyBlock(blk)
+}
+
+// handleTxAccepted publishes the transactions accepted into the mempool.
+func (n *Notifier) handleTxAccepted(txe *mempool.TxEntry) {
+	n.NotifyTransaction(txe.Tx)
+}
+
+// NotifyBlock publishes a block on the hashblock and rawblock topics.
+func (n *Notifier) NotifyBlock(blk *block.Block) {
+	if n.enabled(TopicHashBlock) {
+		hash := blk.GetHash()
+		n.publish(TopicHashBlock, reverseHash(&hash))
+	}
+	if n.enabled(TopicRawBlock) {
+		buf := bytes.NewBuffer(make([]byte, 0, blk.SerializeSize()))
+		if err := blk.Serialize(buf); err != nil {
+			log.Error("zmq: serialize block %s failed: %v", blk.GetHash(), err)
+			return
+		}
+		n.publish(TopicRawBlock, buf.Bytes())
+	}
+}
+
+// NotifyTransaction publishes a transaction on the hashtx and rawtx topics.
+func (n *Notifier) NotifyTransaction(txn *tx.Tx) {
+	if n.enabled(TopicHashTx) {
+		hash := txn.GetHash()
+		n.publish(TopicHashTx, reverseHash(&hash))
+	}
+	if n.enabled(TopicRawTx) {
+		buf := bytes.NewBuffer(make([]byte, 0, txn.SerializeSize()))
+		if err := txn.Serialize(buf); err != nil {
+			log.Error("zmq: serialize transaction %s failed: %v", txn.GetHash(), err)
+			return
+		}
+		n.publish(TopicRawTx, buf.Bytes())
+	}
+}
+
+func (n *Notifier) enabled(topic string) bool {
+	_, ok := n.topics[topic]
+	return ok
+}
+
+// publish sends the body on the topic along with the next sequence number of
+// the topic.  The lock keeps the messages of a topic queued in sequence order.
+func (n *Notifier) publish(topic string, body []byte) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if n.stopped {
+		return
+	}
+	var seq [4]byte
+	binary.LittleEndian.PutUint32(seq[:], n.sequences[topic])
+	n.sequences[topic]++
+
+	n.topics[topic].publish(topic, [][]byte{[]byte(topic), body, seq[:]})
+}
+
+// reverseHash returns the bytes of the hash in display order.
+func reverseHash(hash *util.Hash) []byte {
+	b := make([]byte, len(hash))
+	for i := range hash {
+		b[len(hash)-1-i] = hash[i]
+	}
+	return b
+}
@@ -0,0 +1,175 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/stretchr/testify/assert"
+)
+
+// testSubscriber is a minimal ZeroMQ SUB socket.
+type testSubscriber struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialSubscriber(t *testing.T, addr string) *testSubscriber {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial %s: %v", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	s := &testSubscriber{conn: conn, reader: bufio.NewReader(conn)}
+
+	assert.NoError(t, writeGreeting(conn))
+	assert.NoError(t, readGreeting(s.reader))
+	ready := encodeMetadata(map[string]string{"Socket-Type": "SUB"})
+	assert.NoError(t, writeCommand(conn, cmdReady, ready))
+
+	f, err := readFrame(s.reader)
+	assert.NoError(t, err)
+	name, data, err := parseCommand(f.body)
+	assert.NoError(t, err)
+	assert.Equal(t, cmdReady, name)
+	props, err := decodeMetadata(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "PUB", props["Socket-Type"])
+
+	return s
+}
+
+// subscribe sends a ZMTP 3.0 style subscription message.
+func (s *testSubscriber) subscribe(t *testing.T, topic string) {
+	assert.NoError(t, writeFrame(s.conn, 0, append([]byte{1}, topic...)))
+}
+
+// receive reads a multipart message.
+func (s *testSubscriber) receive(t *testing.T) [][]byte {
+	var parts [][]byte
+	for {
+		f, err := readFrame(s.reader)
+		if err != nil {
+			t.Fatalf("read frame: %v", err)
+		}
+		parts = append(parts, f.body)
+		if !f.hasMore() {
+			return parts
+		}
+	}
+}
+
+// waitSubscribed waits until a subscriber of the publisher has subscribed to
+// the topic, since subscriptions are processed asynchronously.
+func waitSubscribed(t *testing.T, p *publisher, topic string) {
+	for i := 0; i < 500; i++ {
+		p.mtx.Lock()
+		for s := range p.subscribers {
+			if s.subscribed(topic) {
+				p.mtx.Unlock()
+				return
+			}
+		}
+		p.mtx.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no subscriber for topic %s", topic)
+}
+
+func newTestNotifier(t *testing.T, topics ...string) (*Notifier, string) {
+	// The topics share a single publisher bound to an ephemeral port.
+	n, err := NewNotifier(map[string]string{topics[0]: "tcp://127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	p := n.topics[topics[0]]
+	for _, topic := range topics[1:] {
+		n.topics[topic] = p
+	}
+	p.start()
+	return n, p.listener.Addr().String()
+}
+
+func TestNotifyTransaction(t *testing.T) {
+	n, addr := newTestNotifier(t, TopicHashTx, TopicRawTx)
+	defer n.Stop()
+
+	sub := dialSubscriber(t, addr)
+	defer sub.conn.Close()
+	sub.subscribe(t, TopicHashTx)
+	sub.subscribe(t, TopicRawTx)
+	waitSubscribed(t, n.topics[TopicRawTx], TopicRawTx)
+
+	txn := tx.NewTx(0, tx.DefaultVersion)
+	hash := txn.GetHash()
+	var raw bytes.Buffer
+	assert.NoError(t, txn.Serialize(&raw))
+
+	for seq := uint32(0); seq < 2; seq++ {
+		n.NotifyTransaction(txn)
+
+		msg := sub.receive(t)
+		assert.Equal(t, 3, len(msg))
+		assert.Equal(t, TopicHashTx, string(msg[0]))
+		assert.Equal(t, reverseHash(&hash), msg[1])
+		assert.Equal(t, hash.String(), hex.EncodeToString(msg[1]))
+		assert.Equal(t, seq, binary.LittleEndian.Uint32(msg[2]))
+
+		msg = sub.receive(t)
+		assert.Equal(t, 3, len(msg))
+		assert.Equal(t, TopicRawTx, string(msg[0]))
+		assert.Equal(t, raw.Bytes(), msg[1])
+		assert.Equal(t, seq, binary.LittleEndian.Uint32(msg[2]))
+	}
+}
+
+func TestNotifyBlockFiltersTopics(t *testing.T) {
+	n, addr := newTestNotifier(t, TopicHashBlock, TopicRawBlock)
+	defer n.Stop()
+
+	// A prefix subscription only matches hashblock.
+	sub := dialSubscriber(t, addr)
+	defer sub.conn.Close()
+	sub.subscribe(t, "hash")
+	waitSubscribed(t, n.topics[TopicHashBlock], TopicHashBlock)
+
+	blk := block.NewBlock()
+	blk.Txs = []*tx.Tx{tx.NewTx(0, tx.DefaultVersion)}
+	hash := blk.GetHash()
+
+	n.NotifyBlock(blk)
+	n.NotifyBlock(blk)
+
+	for seq := uint32(0); seq < 2; seq++ {
+		msg := sub.receive(t)
+		assert.Equal(t, 3, len(msg))
+		assert.Equal(t, TopicHashBlock, string(msg[0]))
+		assert.Equal(t, reverseHash(&hash), msg[1])
+		assert.Equal(t, seq, binary.LittleEndian.Uint32(msg[2]))
+	}
+}
+
+func TestNotifierStopped(t *testing.T) {
+	n, addr := newTestNotifier(t, TopicHashTx)
+	sub := dialSubscriber(t, addr)
+	defer sub.conn.Close()
+	sub.subscribe(t, "")
+	waitSubscribed(t, n.topics[TopicHashTx], TopicHashTx)
+
+	n.Stop()
+	n.NotifyTransaction(tx.NewTx(0, tx.DefaultVersion))
+
+	_, err := readFrame(sub.reader)
+	assert.Error(t, err)
+}
+
+func TestInvalidEndpoint(t *testing.T) {
+	_, err := NewNotifier(map[string]string{TopicHashTx: "ipc:///tmp/zmq"})
+	assert.Error(t, err)
+}
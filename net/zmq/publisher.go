@@ -0,0 +1,347 @@
+package zmq
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/log"
+)
+
+const (
+	// sendQueueSize is the number of messages queued for a subscriber.
+	// Like the high water mark of a ZeroMQ PUB socket, messages are
+	// dropped for the subscribers that fall further behind.
+	sendQueueSize = 1000
+
+	// handshakeTimeout is the time allowed to a subscriber to complete the
+	// ZMTP handshake.
+	handshakeTimeout = 10 * time.Second
+)
+
+// message is either a multipart message or a command queued for a
+// subscriber.
+type message struct {
+	command string
+	parts   [][]byte
+}
+
+// subscriber is a connected ZeroMQ SUB socket.
+type subscriber struct {
+	conn  net.Conn
+	queue chan *message
+
+	mtx    sync.RWMutex
+	topics map[string]struct{}
+}
+
+// subscribed reports whether the subscriber has a subscription matching the
+// passed topic.  Subscriptions match by prefix, the empty one matches all.
+func (s *subscriber) subscribed(topic string) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	for prefix := range s.topics {
+		if strings.HasPrefix(topic, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *subscriber) subscribe(topic string) {
+	s.mtx.Lock()
+	s.topics[topic] = struct{}{}
+	s.mtx.Unlock()
+}
+
+func (s *subscriber) unsubscribe(topic string) {
+	s.mtx.Lock()
+	delete(s.topics, topic)
+	s.mtx.Unlock()
+}
+
+// publisher is a ZeroMQ PUB socket bound to a tcp address.
+type publisher struct {
+	addr     string
+	listener net.Listener
+
+	mtx         sync.Mutex
+	subscribers map[*subscriber]struct{}
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// parseEndpoint returns the host:port of a ZeroMQ tcp endpoint such as
+// tcp://127.0.0.1:28332.  It is the only transport supported.
+func parseEndpoint(endpoint string) (string, error) {
+	const scheme = "tcp://"
+	if !strings.HasPrefix(endpoint, scheme) {
+		return "", fmt.Errorf("zmq: unsupported endpoint %s, only %s is supported",
+			endpoint, scheme)
+	}
+	return strings.TrimPrefix(endpoint, scheme), nil
+}
+
+// newPublisher binds a publisher to the passed endpoint.
+func newPublisher(endpoint string) (*publisher, error) {
+	addr, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &publisher{
+		addr:        endpoint,
+		listener:    listener,
+		subscribers: make(map[*subscriber]struct{}),
+		quit:        make(chan struct{}),
+	}, nil
+}
+
+// start accepts subscribers until the publisher is stopped.
+func (p *publisher) start() {
+	p.wg.Add(1)
+	go p.acceptHandler()
+}
+
+// stop closes the listener and disconnects all the subscribers.
+func (p *publisher) stop() {
+	close(p.quit)
+	p.listener.Close()
+
+	p.mtx.Lock()
+	for s := range p.subscribers {
+		s.conn.Close()
+	}
+	p.mtx.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *publisher) acceptHandler() {
+	defer p.wg.Done()
+
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			select {
+			case <-p.quit:
+			default:
+				log.Error("zmq: accept on %s failed: %v", p.addr, err)
+			}
+			return
+		}
+
+		p.wg.Add(1)
+		go p.serve(conn)
+	}
+}
+
+// serve performs the handshake with a subscriber, then processes its
+// subscriptions while a separate goroutine writes the published messages.
+func (p *publisher) serve(conn net.Conn) {
+	defer p.wg.Done()
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := handshake(conn, reader); err != nil {
+		log.Debug("zmq: handshake with %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	s := &subscriber{
+		conn:   conn,
+		queue:  make(chan *message, sendQueueSize),
+		topics: make(map[string]struct{}),
+	}
+	p.mtx.Lock()
+	p.subscribers[s] = struct{}{}
+	p.mtx.Unlock()
+	log.Debug("zmq: new subscriber %s on %s", conn.RemoteAddr(), p.addr)
+
+	done := make(chan struct{})
+	p.wg.Add(1)
+	go p.writeHandler(s, done)
+
+	for {
+		f, err := readFrame(reader)
+		if err != nil {
+			break
+		}
+		if err := handleFrame(s, f); err != nil {
+			log.Debug("zmq: subscriber %s: %v", conn.RemoteAddr(), err)
+			break
+		}
+	}
+
+	p.mtx.Lock()
+	delete(p.subscribers, s)
+	p.mtx.Unlock()
+	close(done)
+	log.Debug("zmq: subscriber %s disconnected from %s", conn.RemoteAddr(), p.addr)
+}
+
+// handshake exchanges the greetings and the READY commands with a peer,
+// checking it is a SUB socket.
+func handshake(conn net.Conn, reader *bufio.Reader) error {
+	if err := writeGreeting(conn); err != nil {
+		return err
+	}
+	if err := readGreeting(reader); err != nil {
+		return err
+	}
+
+	ready := encodeMetadata(map[string]string{"Socket-Type": socketTypePub})
+	if err := writeCommand(conn, cmdReady, ready); err != nil {
+		return err
+	}
+
+	f, err := readFrame(reader)
+	if err != nil {
+		return err
+	}
+	if !f.isCommand() {
+		return errBadCommand
+	}
+	name, data, err := parseCommand(f.body)
+	if err != nil {
+		return err
+	}
+	if name != cmdReady {
+		return fmt.Errorf("zmq: unexpected %s command during handshake", name)
+	}
+	props, err := decodeMetadata(data)
+	if err != nil {
+		return err
+	}
+	if socketType := props["Socket-Type"]; socketType != "SUB" && socketType != "XSUB" {
+		reason := "invalid socket type"
+		writeCommand(conn, cmdError, append([]byte{byte(len(reason))}, reason...))
+		return fmt.Errorf("zmq: incompatible socket type %q", socketType)
+	}
+	return nil
+}
+
+// handleFrame processes a frame sent by a subscriber.  ZMTP 3.0 peers send
+// their subscriptions as messages, ZMTP 3.1 peers as commands.
+func handleFrame(s *subscriber, f *frame) error {
+	if f.isCommand() {
+		name, data, err := parseCommand(f.body)
+		if err != nil {
+			return err
+		}
+		switch name {
+		case cmdSubscribe:
+			s.subscribe(string(data))
+		case cmdCancel:
+			s.unsubscribe(string(data))
+		case cmdPing:
+			// Reply with the context following the 2 bytes TTL.
+			if len(data) < 2 {
+				return errBadCommand
+			}
+			s.queueCommand(cmdPong, data[2:])
+		}
+		return nil
+	}
+
+	if len(f.body) == 0 || f.hasMore() {
+		return nil
+	}
+	switch f.body[0] {
+	case 1:
+		s.subscribe(string(f.body[1:]))
+	case 0:
+		s.unsubscribe(string(f.body[1:]))
+	}
+	return nil
+}
+
+// queueCommand queues a command for the subscriber.
+func (s *subscriber) queueCommand(name string, data []byte) {
+	select {
+	case s.queue <- &message{command: name, parts: [][]byte{data}}:
+	default:
+	}
+}
+
+func (p *publisher) writeHandler(s *subscriber, done chan struct{}) {
+	defer p.wg.Done()
+
+	writer := bufio.NewWriter(s.conn)
+	for {
+		select {
+		case msg := <-s.queue:
+			if err := writeMessage(writer, msg); err != nil {
+				s.conn.Close()
+				return
+			}
+			// Batch the messages already queued into a single write.
+			if len(s.queue) == 0 {
+				if err := writer.Flush(); err != nil {
+					s.conn.Close()
+					return
+				}
+			}
+
+		case <-done:
+			return
+		}
+	}
+}
+
+// writeMessage writes a queued multipart message or command.
+func writeMessage(w *bufio.Writer, msg *message) error {
+	if msg.command != "" {
+		return writeCommand(w, msg.command, msg.parts[0])
+	}
+	for i, part := range msg.parts {
+		var flags byte
+		if i < len(msg.parts)-1 {
+			flags = flagMore
+		}
+		if err := writeFrame(w, flags, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish queues a multipart message for the subscribers of its topic.  It
+// never blocks, the message is dropped for the subscribers whose queue is
+// full.
+func (p *publisher) publish(topic string, parts [][]byte) {
+	msg := &message{parts: parts}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for s := range p.subscribers {
+		if !s.subscribed(topic) {
+			continue
+		}
+		select {
+		case s.queue <- msg:
+		default:
+			log.Debug("zmq: dropping %s message for slow subscriber %s",
+				topic, s.conn.RemoteAddr())
+		}
+	}
+}
+
+// numSubscribers returns the number of connected subscribers.
+func (p *publisher) numSubscribers() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.subscribers)
+}
@@ -0,0 +1,198 @@
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// This file implements the subset of ZMTP 3.0 (https://rfc.zeromq.org/spec/23/)
+// needed by a PUB socket using the NULL security mechanism, so that stock
+// ZeroMQ SUB sockets can subscribe to the node without linking libzmq.
+
+const (
+	greetingSize = 64
+
+	zmtpVersionMajor = 3
+	zmtpVersionMinor = 0
+
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+
+	// maxFrameSize bounds the frames accepted from subscribers, which only
+	// ever send small commands and subscriptions.
+	maxFrameSize = 1 << 16
+
+	mechanismNull = "NULL"
+
+	socketTypePub = "PUB"
+
+	cmdReady     = "READY"
+	cmdSubscribe = "SUBSCRIBE"
+	cmdCancel    = "CANCEL"
+	cmdPing      = "PING"
+	cmdPong      = "PONG"
+	cmdError     = "ERROR"
+)
+
+var (
+	errBadGreeting  = errors.New("zmq: invalid greeting")
+	errFrameTooLong = errors.New("zmq: frame too long")
+	errBadCommand   = errors.New("zmq: malformed command")
+)
+
+// frame is a single ZMTP frame.
+type frame struct {
+	flags byte
+	body  []byte
+}
+
+func (f *frame) isCommand() bool {
+	return f.flags&flagCommand != 0
+}
+
+func (f *frame) hasMore() bool {
+	return f.flags&flagMore != 0
+}
+
+// writeGreeting writes the greeting of a NULL mechanism server.
+func writeGreeting(w io.Writer) error {
+	var greeting [greetingSize]byte
+	greeting[0] = 0xff
+	greeting[9] = 0x7f
+	greeting[10] = zmtpVersionMajor
+	greeting[11] = zmtpVersionMinor
+	copy(greeting[12:32], mechanismNull)
+	greeting[32] = 1 // as-server
+	_, err := w.Write(greeting[:])
+	return err
+}
+
+// readGreeting reads the greeting of the peer and checks it is a ZMTP 3.x
+// peer using the NULL mechanism.
+func readGreeting(r io.Reader) error {
+	var greeting [greetingSize]byte
+	if _, err := io.ReadFull(r, greeting[:]); err != nil {
+		return err
+	}
+	if greeting[0] != 0xff || greeting[9]&0x01 == 0 {
+		return errBadGreeting
+	}
+	if greeting[10] < zmtpVersionMajor {
+		return fmt.Errorf("zmq: unsupported ZMTP version %d.%d",
+			greeting[10], greeting[11])
+	}
+	mechanism := string(bytes.TrimRight(greeting[12:32], "\x00"))
+	if mechanism != mechanismNull {
+		return fmt.Errorf("zmq: unsupported security mechanism %q", mechanism)
+	}
+	return nil
+}
+
+// writeFrame writes a frame, using the long size encoding when needed.
+func writeFrame(w io.Writer, flags byte, body []byte) error {
+	var header [9]byte
+	var n int
+	if len(body) > 255 {
+		header[0] = flags | flagLong
+		binary.BigEndian.PutUint64(header[1:], uint64(len(body)))
+		n = 9
+	} else {
+		header[0] = flags
+		header[1] = byte(len(body))
+		n = 2
+	}
+	if _, err := w.Write(header[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// readFrame reads a frame of at most maxFrameSize bytes.
+func readFrame(r io.Reader) (*frame, error) {
+	var flags [1]byte
+	if _, err := io.ReadFull(r, flags[:]); err != nil {
+		return nil, err
+	}
+
+	var size uint64
+	if flags[0]&flagLong != 0 {
+		var buf [8]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		size = binary.BigEndian.Uint64(buf[:])
+	} else {
+		var buf [1]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		size = uint64(buf[0])
+	}
+	if size > maxFrameSize {
+		return nil, errFrameTooLong
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &frame{flags: flags[0] &^ flagLong, body: body}, nil
+}
+
+// writeCommand writes a command frame.
+func writeCommand(w io.Writer, name string, data []byte) error {
+	body := make([]byte, 0, 1+len(name)+len(data))
+	body = append(body, byte(len(name)))
+	body = append(body, name...)
+	body = append(body, data...)
+	return writeFrame(w, flagCommand, body)
+}
+
+// parseCommand splits the body of a command frame into its name and data.
+func parseCommand(body []byte) (string, []byte, error) {
+	if len(body) < 1 || len(body) < 1+int(body[0]) {
+		return "", nil, errBadCommand
+	}
+	n := int(body[0])
+	return string(body[1 : 1+n]), body[1+n:], nil
+}
+
+// encodeMetadata encodes the properties of a READY command.
+func encodeMetadata(props map[string]string) []byte {
+	var buf bytes.Buffer
+	for name, value := range props {
+		buf.WriteByte(byte(len(name)))
+		buf.WriteString(name)
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(value)))
+		buf.Write(size[:])
+		buf.WriteString(value)
+	}
+	return buf.Bytes()
+}
+
+// decodeMetadata decodes the properties of a READY command.
+func decodeMetadata(data []byte) (map[string]string, error) {
+	props := make(map[string]string)
+	for len(data) > 0 {
+		n := int(data[0])
+		if len(data) < 1+n+4 {
+			return nil, errBadCommand
+		}
+		name := string(data[1 : 1+n])
+		data = data[1+n:]
+		size := binary.BigEndian.Uint32(data[:4])
+		data = data[4:]
+		if uint64(len(data)) < uint64(size) {
+			return nil, errBadCommand
+		}
+		props[name] = string(data[:size])
+		data = data[size:]
+	}
+	return props, nil
+}
@@ -0,0 +1,84 @@
+package zmq
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags byte
+		body  []byte
+	}{
+		{"empty", 0, []byte{}},
+		{"short", flagMore, []byte("hashblock")},
+		{"long", 0, bytes.Repeat([]byte{0xab}, 1000)},
+		{"command", flagCommand, []byte("\x05READY")},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := writeFrame(&buf, test.flags, test.body)
+		assert.NoError(t, err, test.name)
+		if len(test.body) > 255 {
+			assert.Equal(t, 9+len(test.body), buf.Len(), test.name)
+		} else {
+			assert.Equal(t, 2+len(test.body), buf.Len(), test.name)
+		}
+
+		f, err := readFrame(&buf)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.flags, f.flags, test.name)
+		assert.Equal(t, test.body, f.body, test.name)
+	}
+}
+
+func TestReadFrameTooLong(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write([]byte{flagLong, 0, 0, 0, 0, 0, 1, 0, 1})
+	_, err := readFrame(&buf)
+	assert.Equal(t, errFrameTooLong, err)
+}
+
+func TestGreeting(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, writeGreeting(&buf))
+	assert.Equal(t, greetingSize, buf.Len())
+	assert.NoError(t, readGreeting(&buf))
+
+	greeting := make([]byte, greetingSize)
+	greeting[0] = 0xff
+	greeting[9] = 0x7f
+	greeting[10] = 3
+	copy(greeting[12:], "CURVE")
+	assert.Error(t, readGreeting(bytes.NewReader(greeting)))
+
+	greeting[0] = 0
+	assert.Equal(t, errBadGreeting, readGreeting(bytes.NewReader(greeting)))
+}
+
+func TestCommand(t *testing.T) {
+	var buf bytes.Buffer
+	props := map[string]string{"Socket-Type": "PUB"}
+	assert.NoError(t, writeCommand(&buf, cmdReady, encodeMetadata(props)))
+
+	f, err := readFrame(&buf)
+	assert.NoError(t, err)
+	assert.True(t, f.isCommand())
+
+	name, data, err := parseCommand(f.body)
+	assert.NoError(t, err)
+	assert.Equal(t, cmdReady, name)
+
+	decoded, err := decodeMetadata(data)
+	assert.NoError(t, err)
+	assert.Equal(t, props, decoded)
+
+	_, _, err = parseCommand([]byte{5, 'R'})
+	assert.Equal(t, errBadCommand, err)
+	_, err = decodeMetadata([]byte{4, 'n', 'a', 'm', 'e', 0, 0, 0, 9})
+	assert.Equal(t, errBadCommand, err)
+}
@@ -30,7 +30,7 @@ import (
 
 const (
 	// MaxProtocolVersion is the max protocol version the peer supports.
-	MaxProtocolVersion = wire.FeeFilterVersion
+	MaxProtocolVersion = wire.InvalidCBNoBanVersion
 
 	// minAcceptableProtocolVersion is the lowest protocol version that a
 	// connected peer may support.
@@ -176,6 +176,18 @@ type MessageListeners struct {
 	// message.
 	OnGetHeaders func(p *Peer, msg *wire.MsgGetHeaders)
 
+	// OnGetCFilters is invoked when a peer receives a getcfilters bitcoin
+	// message.
+	OnGetCFilters func(p *Peer, msg *wire.MsgGetCFilters)
+
+	// OnGetCFHeaders is invoked when a peer receives a getcfheaders
+	// bitcoin message.
+	OnGetCFHeaders func(p *Peer, msg *wire.MsgGetCFHeaders)
+
+	// OnGetCFCheckpt is invoked when a peer receives a getcfcheckpt
+	// bitcoin message.
+	OnGetCFCheckpt func(p *Peer, msg *wire.MsgGetCFCheckpt)
+
 	// OnFeeFilter is invoked when a peer receives a feefilter bitcoin message.
 	OnFeeFilter func(p *Peer, msg *wire.MsgFeeFilter)
 
@@ -207,6 +219,22 @@ type MessageListeners struct {
 	// message.
 	OnSendHeaders func(p *Peer, msg *wire.MsgSendHeaders)
 
+	// OnSendCmpct is invoked when a peer receives a sendcmpct bitcoin
+	// message.
+	OnSendCmpct func(p *Peer, msg *wire.MsgSendCmpct)
+
+	// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin
+	// message.
+	OnCmpctBlock func(p *Peer, msg *wire.MsgCmpctBlock, done chan<- struct{})
+
+	// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin
+	// message.
+	OnGetBlockTxn func(p *Peer, msg *wire.MsgGetBlockTxn, done chan<- struct{})
+
+	// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin
+	// message.
+	OnBlockTxn func(p *Peer, msg *wire.MsgBlockTxn, done chan<- struct{})
+
 	// OnRead is invoked when a peer receives a bitcoin message.  It
 	// consists of the number of bytes read, the message, and whether or not
 	// an error in the read occurred.  Typically, callers will opt to use
@@ -460,6 +488,8 @@ type Peer struct {
 	protocolVersion      uint32 // negotiated protocol version
 	sendHeadersPreferred bool   // peer sent a sendheaders message
 	revertToInv          bool   //whether to revert to inv mode for a prefer-header-node
+	supportsCmpctBlocks  bool   // peer sent a sendcmpct message with a version we support
+	cmpctHighBandwidth   bool   // peer asked for new blocks to be announced with cmpctblock
 	verAckReceived       bool
 	isWhitelisted        bool
 
@@ -870,6 +900,43 @@ func (p *Peer) SetPreferHeaders() {
 	p.flagsMtx.Unlock()
 }
 
+// SetSendCmpct records the compact block relay preferences of the peer
+// announced in a sendcmpct message. Messages for versions we do not support
+// are ignored, as required by BIP152.
+func (p *Peer) SetSendCmpct(msg *wire.MsgSendCmpct) {
+	if msg.CmpctBlockVersion != wire.CmpctBlockVersion {
+		return
+	}
+	p.flagsMtx.Lock()
+	p.supportsCmpctBlocks = true
+	p.cmpctHighBandwidth = msg.AnnounceUsingCmpctBlock
+	p.flagsMtx.Unlock()
+}
+
+// SupportsCmpctBlocks returns whether compact blocks may be requested from
+// and sent to the peer.
+//
+// This function is safe for concurrent access.
+func (p *Peer) SupportsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	supportsCmpctBlocks := p.supportsCmpctBlocks
+	p.flagsMtx.Unlock()
+
+	return supportsCmpctBlocks
+}
+
+// WantsCmpctBlocks returns whether the peer asked for new blocks to be
+// announced with cmpctblock messages (BIP152 high bandwidth mode).
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	wants := p.supportsCmpctBlocks && p.cmpctHighBandwidth
+	p.flagsMtx.Unlock()
+
+	return wants
+}
+
 // localVersionMsg creates a version message that can be used to send to the
 // remote peer.
 func (p *Peer) localVersionMsg() (*wire.MsgVersion, error) {
@@ -1038,6 +1105,16 @@ func (p *Peer) PushSendHeadersMsg() {
 	p.QueueMessage(msg, nil)
 }
 
+// PushSendCmpctMsg sends a sendcmpct msg to indicate that we support compact
+// blocks, and whether we want the peer to announce new blocks with them.
+func (p *Peer) PushSendCmpctMsg(announce bool) {
+	if p.ProtocolVersion() < wire.ShortIdsBlocksVersion {
+		return
+	}
+	msg := wire.NewMsgSendCmpct(announce, wire.CmpctBlockVersion)
+	p.QueueMessage(msg, nil)
+}
+
 // PushRejectMsg sends a reject message for the provided command, reject code,
 // reject reason, and hash.  The hash will only be used when the command is a tx
 // or block and should be nil in other cases.  The wait parameter will cause the
@@ -1313,11 +1390,16 @@ func (p *Peer) maybeAddDeadline(pendingResponses map[string]time.Time, msg wire.
 	// 	pendingResponses[wire.CmdInv] = deadline
 
 	case wire.CmdGetData:
-		// Expects a block, merkleblock, tx, or notfound message.
+		// Expects a block, cmpctblock, merkleblock, tx, or notfound message.
 		pendingResponses[wire.CmdBlock] = deadline
+		pendingResponses[wire.CmdCmpctBlock] = deadline
 		pendingResponses[wire.CmdMerkleBlock] = deadline
 		pendingResponses[wire.CmdTx] = deadline
 		pendingResponses[wire.CmdNotFound] = deadline
+
+	case wire.CmdGetBlockTxn:
+		// Expects a blocktxn message.
+		pendingResponses[wire.CmdBlockTxn] = deadline
 	}
 }
 
@@ -1363,12 +1445,15 @@ out:
 				switch msgCmd := msg.message.Command(); msgCmd {
 				case wire.CmdBlock:
 					fallthrough
+				case wire.CmdCmpctBlock:
+					fallthrough
 				case wire.CmdMerkleBlock:
 					fallthrough
 				case wire.CmdTx:
 					fallthrough
 				case wire.CmdNotFound:
 					delete(pendingResponses, wire.CmdBlock)
+					delete(pendingResponses, wire.CmdCmpctBlock)
 					delete(pendingResponses, wire.CmdMerkleBlock)
 					delete(pendingResponses, wire.CmdTx)
 					delete(pendingResponses, wire.CmdNotFound)
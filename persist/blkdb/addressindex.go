@@ -0,0 +1,248 @@
+package blkdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// AddressTypeP2SH32 is the type of the addresses paying to the hash256 of
+	// a script. Cash addresses only tell them apart from P2SH ones by the size
+	// of their hash, so they get a type of their own in the index.
+	AddressTypeP2SH32 = 4
+
+	addressDeltaSize   = 4 + 4 + util.Hash256Size + 4 + 1
+	addressUnspentSize = util.Hash256Size + 4
+
+	// maxAddressScriptSize bounds the script of an unspent output read back
+	// from the address index.
+	maxAddressScriptSize = 10000
+)
+
+var errBadAddressIndexEntry = errors.New("bad address index entry")
+
+// AddressKey identifies the outputs paying to an address by the type of the
+// address and the hash160 of the public key or script it pays to, or the
+// hash256 of the script for AddressTypeP2SH32.
+type AddressKey struct {
+	Type byte
+	Hash [util.Hash256Size]byte
+}
+
+// AddressDelta is a change of the balance of an address made by an output
+// or an input of a confirmed transaction. Index is the output index, or the
+// input index when Spending, and Amount is negative when Spending.
+type AddressDelta struct {
+	AddressKey
+	Height     int32
+	BlockIndex uint32
+	TxID       util.Hash
+	Index      uint32
+	Spending   bool
+	Amount     int64
+}
+
+// AddressUnspent is a confirmed unspent output paying to an address.
+type AddressUnspent struct {
+	AddressKey
+	TxID   util.Hash
+	Index  uint32
+	Amount int64
+	Height int32
+	Script []byte
+}
+
+// size returns the size of the address in index keys.
+func (ak *AddressKey) size() int {
+	if ak.Type == AddressTypeP2SH32 {
+		return 1 + util.Hash256Size
+	}
+	return 1 + 20
+}
+
+func (ak *AddressKey) appendTo(key []byte) []byte {
+	key = append(key, ak.Type)
+	return append(key, ak.Hash[:ak.size()-1]...)
+}
+
+// unserializeAddressKey reads the address of an index key of size bytes past
+// the address, returning the rest of the key.
+func unserializeAddressKey(ak *AddressKey, key []byte, size int) ([]byte, error) {
+	if len(key) < 2 {
+		return nil, errBadAddressIndexEntry
+	}
+	ak.Type = key[1]
+	addressKeySize := ak.size()
+	if len(key) != 1+addressKeySize+size {
+		return nil, errBadAddressIndexEntry
+	}
+	copy(ak.Hash[:], key[2:1+addressKeySize])
+	return key[1+addressKeySize:], nil
+}
+
+// key sorts the deltas of an address by height, then by the position of
+// the transaction in the block.
+func (ad *AddressDelta) key() []byte {
+	key := make([]byte, 0, 1+ad.AddressKey.size()+addressDeltaSize)
+	key = append(key, db.DbAddressIndex)
+	key = ad.AddressKey.appendTo(key)
+	key = appendUint32BE(key, uint32(ad.Height))
+	key = appendUint32BE(key, ad.BlockIndex)
+	key = append(key, ad.TxID[:]...)
+	key = appendUint32BE(key, ad.Index)
+	if ad.Spending {
+		return append(key, 1)
+	}
+	return append(key, 0)
+}
+
+func (ad *AddressDelta) value() []byte {
+	value := make([]byte, 8)
+	binary.LittleEndian.PutUint64(value, uint64(ad.Amount))
+	return value
+}
+
+func unserializeAddressDelta(key, value []byte) (*AddressDelta, error) {
+	if len(value) != 8 {
+		return nil, errBadAddressIndexEntry
+	}
+	ad := &AddressDelta{}
+	key, err := unserializeAddressKey(&ad.AddressKey, key, addressDeltaSize)
+	if err != nil {
+		return nil, err
+	}
+	ad.Height = int32(binary.BigEndian.Uint32(key))
+	ad.BlockIndex = binary.BigEndian.Uint32(key[4:])
+	copy(ad.TxID[:], key[8:])
+	key = key[8+util.Hash256Size:]
+	ad.Index = binary.BigEndian.Uint32(key)
+	ad.Spending = key[4] == 1
+	ad.Amount = int64(binary.LittleEndian.Uint64(value))
+	return ad, nil
+}
+
+func (au *AddressUnspent) key() []byte {
+	key := make([]byte, 0, 1+au.AddressKey.size()+addressUnspentSize)
+	key = append(key, db.DbAddressUnspentIndex)
+	key = au.AddressKey.appendTo(key)
+	key = append(key, au.TxID[:]...)
+	return appendUint32BE(key, au.Index)
+}
+
+func (au *AddressUnspent) value() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, 12+1+len(au.Script)))
+	if err := util.WriteElements(buf, au.Amount, au.Height); err != nil {
+		return nil, err
+	}
+	if err := util.WriteVarBytes(buf, au.Script); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unserializeAddressUnspent(key, value []byte) (*AddressUnspent, error) {
+	au := &AddressUnspent{}
+	key, err := unserializeAddressKey(&au.AddressKey, key, addressUnspentSize)
+	if err != nil {
+		return nil, err
+	}
+	copy(au.TxID[:], key)
+	au.Index = binary.BigEndian.Uint32(key[util.Hash256Size:])
+
+	r := bytes.NewReader(value)
+	if err := util.ReadElements(r, &au.Amount, &au.Height); err != nil {
+		return nil, err
+	}
+	script, err := util.ReadVarBytes(r, maxAddressScriptSize, "script")
+	if err != nil {
+		return nil, err
+	}
+	au.Script = script
+	return au, nil
+}
+
+func appendUint32BE(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// WriteAddressIndex records the address deltas of a connected block, along
+// with the unspent outputs it spends and creates.
+func (blockTreeDB *BlockTreeDB) WriteAddressIndex(deltas []*AddressDelta, spent, created []*AddressUnspent) error {
+	batch := db.NewBatchWrapper(blockTreeDB.dbw)
+	for _, delta := range deltas {
+		batch.Write(delta.key(), delta.value())
+	}
+	// outputs may be spent in the block creating them
+	for _, unspent := range created {
+		value, err := unspent.value()
+		if err != nil {
+			return err
+		}
+		batch.Write(unspent.key(), value)
+	}
+	for _, unspent := range spent {
+		batch.Erase(unspent.key())
+	}
+	return blockTreeDB.dbw.WriteBatch(batch, false)
+}
+
+// EraseAddressIndex reverts WriteAddressIndex for a disconnected block.
+func (blockTreeDB *BlockTreeDB) EraseAddressIndex(deltas []*AddressDelta, spent, created []*AddressUnspent) error {
+	batch := db.NewBatchWrapper(blockTreeDB.dbw)
+	for _, delta := range deltas {
+		batch.Erase(delta.key())
+	}
+	for _, unspent := range spent {
+		value, err := unspent.value()
+		if err != nil {
+			return err
+		}
+		batch.Write(unspent.key(), value)
+	}
+	for _, unspent := range created {
+		batch.Erase(unspent.key())
+	}
+	return blockTreeDB.dbw.WriteBatch(batch, false)
+}
+
+// ReadAddressIndex returns the deltas of an address in the blocks from height
+// start to end inclusive, in chain order.
+func (blockTreeDB *BlockTreeDB) ReadAddressIndex(addr *AddressKey, start, end int32) ([]*AddressDelta, error) {
+	prefix := addr.appendTo([]byte{db.DbAddressIndex})
+	cursor := blockTreeDB.dbw.Prefix(prefix)
+	defer cursor.Close()
+
+	deltas := make([]*AddressDelta, 0)
+	cursor.Seek(appendUint32BE(prefix, uint32(start)))
+	for ; cursor.Valid(); cursor.Next() {
+		delta, err := unserializeAddressDelta(cursor.GetKey(), cursor.GetVal())
+		if err != nil {
+			return nil, err
+		}
+		if delta.Height > end {
+			break
+		}
+		deltas = append(deltas, delta)
+	}
+	return deltas, nil
+}
+
+// ReadAddressUnspent returns the unspent outputs paying to an address.
+func (blockTreeDB *BlockTreeDB) ReadAddressUnspent(addr *AddressKey) ([]*AddressUnspent, error) {
+	cursor := blockTreeDB.dbw.Prefix(addr.appendTo([]byte{db.DbAddressUnspentIndex}))
+	defer cursor.Close()
+
+	unspents := make([]*AddressUnspent, 0)
+	for cursor.SeekToFirst(); cursor.Valid(); cursor.Next() {
+		unspent, err := unserializeAddressUnspent(cursor.GetKey(), cursor.GetVal())
+		if err != nil {
+			return nil, err
+		}
+		unspents = append(unspents, unspent)
+	}
+	return unspents, nil
+}
@@ -0,0 +1,135 @@
+package blkdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+)
+
+func TestWRAddressIndex(t *testing.T) {
+	defer initBlockDB()()
+
+	addr := AddressKey{Type: 0}
+	addr.Hash[0] = 0x11
+	other := AddressKey{Type: 1}
+	other.Hash[0] = 0x11
+
+	txid1 := *util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d011")
+	txid2 := *util.HashFromString("00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048")
+	script := []byte{0x76, 0xa9, 0x14}
+
+	// block 10 creates two outputs of addr and one of other
+	created10 := []*AddressUnspent{
+		{AddressKey: addr, TxID: txid1, Index: 0, Amount: 1000, Height: 10, Script: script},
+		{AddressKey: addr, TxID: txid1, Index: 1, Amount: 500, Height: 10, Script: script},
+		{AddressKey: other, TxID: txid1, Index: 2, Amount: 300, Height: 10, Script: script},
+	}
+	deltas10 := []*AddressDelta{
+		{AddressKey: addr, Height: 10, BlockIndex: 1, TxID: txid1, Index: 0, Amount: 1000},
+		{AddressKey: addr, Height: 10, BlockIndex: 1, TxID: txid1, Index: 1, Amount: 500},
+		{AddressKey: other, Height: 10, BlockIndex: 1, TxID: txid1, Index: 2, Amount: 300},
+	}
+	err := GetInstance().WriteAddressIndex(deltas10, nil, created10)
+	if err != nil {
+		t.Errorf("write address index failed: %v\n", err)
+	}
+
+	// block 12 spends the first output of addr and creates one which is
+	// spent in the same block
+	spent12 := []*AddressUnspent{
+		created10[0],
+		{AddressKey: addr, TxID: txid2, Index: 0, Amount: 900, Height: 12, Script: script},
+	}
+	created12 := []*AddressUnspent{spent12[1]}
+	deltas12 := []*AddressDelta{
+		{AddressKey: addr, Height: 12, BlockIndex: 1, TxID: txid2, Index: 0, Spending: true, Amount: -1000},
+		{AddressKey: addr, Height: 12, BlockIndex: 1, TxID: txid2, Index: 0, Amount: 900},
+	}
+	err = GetInstance().WriteAddressIndex(deltas12, spent12, created12)
+	if err != nil {
+		t.Errorf("write address index failed: %v\n", err)
+	}
+
+	deltas, err := GetInstance().ReadAddressIndex(&addr, 0, 100)
+	if err != nil {
+		t.Errorf("read address index failed: %v\n", err)
+	}
+	want := []*AddressDelta{deltas10[0], deltas10[1], deltas12[1], deltas12[0]}
+	if !reflect.DeepEqual(want, deltas) {
+		t.Errorf("the deltas of addr should be %v: %v\n", want, deltas)
+	}
+	deltas, err = GetInstance().ReadAddressIndex(&addr, 11, 12)
+	if err != nil || len(deltas) != 2 || deltas[0].Height != 12 {
+		t.Errorf("the deltas from height 11 should be the ones of block 12: %v, %v\n", deltas, err)
+	}
+	deltas, err = GetInstance().ReadAddressIndex(&addr, 0, 11)
+	if err != nil || len(deltas) != 2 || deltas[1].Height != 10 {
+		t.Errorf("the deltas up to height 11 should be the ones of block 10: %v, %v\n", deltas, err)
+	}
+
+	unspents, err := GetInstance().ReadAddressUnspent(&addr)
+	if err != nil {
+		t.Errorf("read address unspent failed: %v\n", err)
+	}
+	if !reflect.DeepEqual([]*AddressUnspent{created10[1]}, unspents) {
+		t.Errorf("only the second output of block 10 should be unspent: %v\n", unspents)
+	}
+
+	// disconnecting block 12 brings back the output it spent
+	err = GetInstance().EraseAddressIndex(deltas12, spent12, created12)
+	if err != nil {
+		t.Errorf("erase address index failed: %v\n", err)
+	}
+	deltas, err = GetInstance().ReadAddressIndex(&addr, 0, 100)
+	if err != nil || !reflect.DeepEqual(deltas10[:2], deltas) {
+		t.Errorf("the deltas of block 12 should be erased: %v, %v\n", deltas, err)
+	}
+	unspents, err = GetInstance().ReadAddressUnspent(&addr)
+	if err != nil || len(unspents) != 2 {
+		t.Errorf("both outputs of block 10 should be unspent: %v, %v\n", unspents, err)
+	}
+	unspents, err = GetInstance().ReadAddressUnspent(&other)
+	if err != nil || !reflect.DeepEqual([]*AddressUnspent{created10[2]}, unspents) {
+		t.Errorf("the output of other should be unspent: %v, %v\n", unspents, err)
+	}
+}
+
+func TestWRAddressIndexP2SH32(t *testing.T) {
+	defer initBlockDB()()
+
+	addr := AddressKey{Type: AddressTypeP2SH32}
+	addr.Hash[0] = 0x11
+	addr.Hash[31] = 0x22
+	// a P2SH address with the same first 20 bytes of hash
+	p2sh := AddressKey{Type: 1}
+	copy(p2sh.Hash[:20], addr.Hash[:20])
+
+	txid := *util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d011")
+	script := []byte{0xaa, 0x20}
+	created := []*AddressUnspent{
+		{AddressKey: addr, TxID: txid, Index: 0, Amount: 1000, Height: 10, Script: script},
+		{AddressKey: p2sh, TxID: txid, Index: 1, Amount: 500, Height: 10, Script: script},
+	}
+	deltas := []*AddressDelta{
+		{AddressKey: addr, Height: 10, BlockIndex: 1, TxID: txid, Index: 0, Amount: 1000},
+		{AddressKey: p2sh, Height: 10, BlockIndex: 1, TxID: txid, Index: 1, Amount: 500},
+	}
+	err := GetInstance().WriteAddressIndex(deltas, nil, created)
+	if err != nil {
+		t.Errorf("write address index failed: %v\n", err)
+	}
+
+	readDeltas, err := GetInstance().ReadAddressIndex(&addr, 0, 100)
+	if err != nil || !reflect.DeepEqual(deltas[:1], readDeltas) {
+		t.Errorf("the deltas of addr should be %v: %v, %v\n", deltas[:1], readDeltas, err)
+	}
+	unspents, err := GetInstance().ReadAddressUnspent(&addr)
+	if err != nil || !reflect.DeepEqual(created[:1], unspents) {
+		t.Errorf("the unspent outputs of addr should be %v: %v, %v\n", created[:1], unspents, err)
+	}
+	unspents, err = GetInstance().ReadAddressUnspent(&p2sh)
+	if err != nil || !reflect.DeepEqual(created[1:], unspents) {
+		t.Errorf("the unspent outputs of p2sh should be %v: %v, %v\n", created[1:], unspents, err)
+	}
+}
@@ -166,9 +166,12 @@ func (blockTreeDB *BlockTreeDB) ReadTxIndex(txid *util.Hash) (*block.DiskTxPos,
 	tmp = append(tmp, db.DbTxIndex)
 	tmp = append(tmp, txid[:]...)
 	vdata, err := blockTreeDB.dbw.Read(tmp)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
 	if err != nil {
-		log.Error("Error: ReadTxIndex======%#v", err)
-		panic("Error: ReadTxIndex======")
+		log.Error("blkDB: read tx index of %s failed: %v", txid, err)
+		return nil, err
 	}
 	if vdata == nil {
 		return nil, nil
@@ -205,6 +208,95 @@ func (blockTreeDB *BlockTreeDB) WriteTxIndex(txIndexes map[util.Hash]block.DiskT
 	return blockTreeDB.dbw.WriteBatch(batch, false)
 }
 
+func (blockTreeDB *BlockTreeDB) EraseTxIndex(txids []util.Hash) error {
+	var batch = db.NewBatchWrapper(blockTreeDB.dbw)
+	for i := range txids {
+		key := make([]byte, 0, 1+util.Hash256Size)
+		key = append(key, db.DbTxIndex)
+		key = append(key, txids[i][:]...)
+		batch.Erase(key)
+	}
+	return blockTreeDB.dbw.WriteBatch(batch, false)
+}
+
+// ReadTxIndexBestBlock returns the hash of the last block indexed by the
+// background txindex builder, or nil if the builder has not started yet.
+func (blockTreeDB *BlockTreeDB) ReadTxIndexBestBlock() (*util.Hash, error) {
+	vdata, err := blockTreeDB.dbw.Read([]byte{db.DbTxIndexBestBlock})
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	hash := new(util.Hash)
+	if _, err = hash.Unserialize(bytes.NewBuffer(vdata)); err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+func (blockTreeDB *BlockTreeDB) WriteTxIndexBestBlock(hash *util.Hash) error {
+	if hash == nil {
+		return blockTreeDB.dbw.Erase([]byte{db.DbTxIndexBestBlock}, false)
+	}
+	return blockTreeDB.dbw.Write([]byte{db.DbTxIndexBestBlock}, hash[:], false)
+}
+
+// ReadSnapshotBase returns the base block of the UTXO snapshot the chain
+// state was loaded from along with its chain transaction count, or nil if
+// the chain state was not loaded from a snapshot or has been backfilled.
+func (blockTreeDB *BlockTreeDB) ReadSnapshotBase() (*util.Hash, int32, error) {
+	vdata, err := blockTreeDB.dbw.Read([]byte{db.DbSnapshotBase})
+	if err == leveldb.ErrNotFound {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	hash := new(util.Hash)
+	var chainTxCount int32
+	if err = util.ReadElements(bytes.NewBuffer(vdata), hash, &chainTxCount); err != nil {
+		return nil, 0, err
+	}
+	return hash, chainTxCount, nil
+}
+
+func (blockTreeDB *BlockTreeDB) WriteSnapshotBase(hash *util.Hash, chainTxCount int32) error {
+	if hash == nil {
+		return blockTreeDB.dbw.Erase([]byte{db.DbSnapshotBase}, true)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := util.WriteElements(buf, hash, chainTxCount); err != nil {
+		return err
+	}
+	return blockTreeDB.dbw.Write([]byte{db.DbSnapshotBase}, buf.Bytes(), true)
+}
+
+// ReadFinalizedBlock returns the hash of the last finalized block, or nil if
+// no block has been finalized yet.
+func (blockTreeDB *BlockTreeDB) ReadFinalizedBlock() (*util.Hash, error) {
+	vdata, err := blockTreeDB.dbw.Read([]byte{db.DbFinalizedBlock})
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	hash := new(util.Hash)
+	if _, err = hash.Unserialize(bytes.NewBuffer(vdata)); err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+func (blockTreeDB *BlockTreeDB) WriteFinalizedBlock(hash *util.Hash) error {
+	if hash == nil {
+		return blockTreeDB.dbw.Erase([]byte{db.DbFinalizedBlock}, false)
+	}
+	return blockTreeDB.dbw.Write([]byte{db.DbFinalizedBlock}, hash[:], false)
+}
+
 func (blockTreeDB *BlockTreeDB) WriteFlag(name string, value bool) error {
 	tmp := make([]byte, 0, 100)
 	tmp = append(tmp, db.DbFlag)
@@ -221,7 +313,7 @@ func (blockTreeDB *BlockTreeDB) ReadFlag(name string) bool {
 	tmp = append(tmp, name...)
 	b, err := blockTreeDB.dbw.Read(tmp)
 
-	if b[0] == '1' && err == nil {
+	if err == nil && len(b) > 0 && b[0] == '1' {
 		return true
 	}
 	return false
@@ -64,10 +64,109 @@ func TestWRTxIndex(t *testing.T) {
 	if !reflect.DeepEqual(wantVal, txpos) {
 		t.Errorf("the wantVal not equal except value: %v, %v\n", wantVal, txpos)
 	}
+
+	//test Erase TxIndex
+	err = GetInstance().EraseTxIndex([]util.Hash{*h})
+	if err != nil {
+		t.Errorf("erase tx index failed: %v\n", err)
+	}
+	txpos, err = GetInstance().ReadTxIndex(h)
+	if err != nil || txpos != nil {
+		t.Errorf("the erased tx index should not be found: %v, %v\n", txpos, err)
+	}
+}
+
+func TestWRTxIndexBestBlock(t *testing.T) {
+	defer initBlockDB()()
+
+	hash, err := GetInstance().ReadTxIndexBestBlock()
+	if err != nil || hash != nil {
+		t.Errorf("the best block should be empty: %v, %v\n", hash, err)
+	}
+
+	h := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d011")
+	err = GetInstance().WriteTxIndexBestBlock(h)
+	if err != nil {
+		t.Errorf("write best block failed: %v\n", err)
+	}
+	hash, err = GetInstance().ReadTxIndexBestBlock()
+	if err != nil || hash == nil || *hash != *h {
+		t.Errorf("the best block should be %s: %v, %v\n", h, hash, err)
+	}
+
+	err = GetInstance().WriteTxIndexBestBlock(nil)
+	if err != nil {
+		t.Errorf("erase best block failed: %v\n", err)
+	}
+	hash, err = GetInstance().ReadTxIndexBestBlock()
+	if err != nil || hash != nil {
+		t.Errorf("the best block should be erased: %v, %v\n", hash, err)
+	}
+}
+
+func TestWRSnapshotBase(t *testing.T) {
+	defer initBlockDB()()
+
+	hash, _, err := GetInstance().ReadSnapshotBase()
+	if err != nil || hash != nil {
+		t.Errorf("the snapshot base should be empty: %v, %v\n", hash, err)
+	}
+
+	h := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d011")
+	err = GetInstance().WriteSnapshotBase(h, 1234)
+	if err != nil {
+		t.Errorf("write snapshot base failed: %v\n", err)
+	}
+	hash, chainTxCount, err := GetInstance().ReadSnapshotBase()
+	if err != nil || hash == nil || *hash != *h || chainTxCount != 1234 {
+		t.Errorf("the snapshot base should be %s(1234): %v(%d), %v\n", h, hash, chainTxCount, err)
+	}
+
+	err = GetInstance().WriteSnapshotBase(nil, 0)
+	if err != nil {
+		t.Errorf("erase snapshot base failed: %v\n", err)
+	}
+	hash, _, err = GetInstance().ReadSnapshotBase()
+	if err != nil || hash != nil {
+		t.Errorf("the snapshot base should be erased: %v, %v\n", hash, err)
+	}
+}
+
+func TestWRFinalizedBlock(t *testing.T) {
+	defer initBlockDB()()
+
+	hash, err := GetInstance().ReadFinalizedBlock()
+	if err != nil || hash != nil {
+		t.Errorf("the finalized block should be empty: %v, %v\n", hash, err)
+	}
+
+	h := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d011")
+	err = GetInstance().WriteFinalizedBlock(h)
+	if err != nil {
+		t.Errorf("write finalized block failed: %v\n", err)
+	}
+	hash, err = GetInstance().ReadFinalizedBlock()
+	if err != nil || hash == nil || *hash != *h {
+		t.Errorf("the finalized block should be %s: %v, %v\n", h, hash, err)
+	}
+
+	err = GetInstance().WriteFinalizedBlock(nil)
+	if err != nil {
+		t.Errorf("erase finalized block failed: %v\n", err)
+	}
+	hash, err = GetInstance().ReadFinalizedBlock()
+	if err != nil || hash != nil {
+		t.Errorf("the finalized block should be erased: %v, %v\n", hash, err)
+	}
 }
 
 func TestWriteFlag(t *testing.T) {
 	defer initBlockDB()()
+	//test flag: not written yet
+	if GetInstance().ReadFlag("b") {
+		t.Errorf("the missing flag should is false\n")
+	}
+
 	//test flag: value is false
 	err := GetInstance().WriteFlag("b", false)
 	if err != nil {
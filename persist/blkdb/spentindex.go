@@ -0,0 +1,69 @@
+package blkdb
+
+import (
+	"bytes"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// SpentInfo is the input of a transaction spending an output, Height is -1
+// while the transaction is in the mempool.
+type SpentInfo struct {
+	TxID       util.Hash
+	InputIndex uint32
+	Height     int32
+}
+
+func spentIndexKey(out *outpoint.OutPoint) []byte {
+	key := make([]byte, 0, 1+util.Hash256Size+4)
+	key = append(key, db.DbSpentIndex)
+	key = append(key, out.Hash[:]...)
+	return appendUint32BE(key, out.Index)
+}
+
+// ReadSpentIndex returns the input spending out in the active chain, or nil
+// if it is unspent.
+func (blockTreeDB *BlockTreeDB) ReadSpentIndex(out *outpoint.OutPoint) (*SpentInfo, error) {
+	vdata, err := blockTreeDB.dbw.Read(spentIndexKey(out))
+	if err == leveldb.ErrNotFound || (err == nil && vdata == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Error("blkDB: read spent index of %s failed: %v", out, err)
+		return nil, err
+	}
+	info := &SpentInfo{}
+	err = util.ReadElements(bytes.NewReader(vdata), &info.TxID, &info.InputIndex, &info.Height)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// WriteSpentIndex records the inputs of a connected block by the outputs they
+// spend.
+func (blockTreeDB *BlockTreeDB) WriteSpentIndex(spents map[outpoint.OutPoint]SpentInfo) error {
+	batch := db.NewBatchWrapper(blockTreeDB.dbw)
+	valueBuf := bytes.NewBuffer(make([]byte, 0, util.Hash256Size+8))
+	for out, info := range spents {
+		valueBuf.Reset()
+		if err := util.WriteElements(valueBuf, &info.TxID, info.InputIndex, info.Height); err != nil {
+			return err
+		}
+		batch.Write(spentIndexKey(&out), valueBuf.Bytes())
+	}
+	return blockTreeDB.dbw.WriteBatch(batch, false)
+}
+
+// EraseSpentIndex marks the outputs spent by a disconnected block unspent.
+func (blockTreeDB *BlockTreeDB) EraseSpentIndex(outs []outpoint.OutPoint) error {
+	batch := db.NewBatchWrapper(blockTreeDB.dbw)
+	for i := range outs {
+		batch.Erase(spentIndexKey(&outs[i]))
+	}
+	return blockTreeDB.dbw.WriteBatch(batch, false)
+}
@@ -0,0 +1,46 @@
+package blkdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestWRSpentIndex(t *testing.T) {
+	defer initBlockDB()()
+
+	h := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d011")
+	spender := util.HashFromString("00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048")
+	out0 := outpoint.NewOutPoint(*h, 0)
+	out1 := outpoint.NewOutPoint(*h, 1)
+
+	info, err := GetInstance().ReadSpentIndex(out0)
+	if err != nil || info != nil {
+		t.Errorf("the output should be unspent: %v, %v\n", info, err)
+	}
+
+	wantVal := SpentInfo{TxID: *spender, InputIndex: 2, Height: 100}
+	err = GetInstance().WriteSpentIndex(map[outpoint.OutPoint]SpentInfo{*out0: wantVal})
+	if err != nil {
+		t.Errorf("write spent index failed: %v\n", err)
+	}
+	info, err = GetInstance().ReadSpentIndex(out0)
+	if err != nil || !reflect.DeepEqual(&wantVal, info) {
+		t.Errorf("the spent info should be %v: %v, %v\n", wantVal, info, err)
+	}
+	info, err = GetInstance().ReadSpentIndex(out1)
+	if err != nil || info != nil {
+		t.Errorf("the other output should be unspent: %v, %v\n", info, err)
+	}
+
+	err = GetInstance().EraseSpentIndex([]outpoint.OutPoint{*out0})
+	if err != nil {
+		t.Errorf("erase spent index failed: %v\n", err)
+	}
+	info, err = GetInstance().ReadSpentIndex(out0)
+	if err != nil || info != nil {
+		t.Errorf("the erased spent info should not be found: %v, %v\n", info, err)
+	}
+}
@@ -30,10 +30,29 @@ const (
 	DbReindexFlag byte = 'R'
 	DbLastBlock   byte = 'l'
 
+	DbTxIndexBestBlock byte = 'T'
+	DbSnapshotBase     byte = 'U'
+	DbSnapshotLoading  byte = 'L'
+	DbFinalizedBlock   byte = 'z'
+
+	DbAddressIndex        byte = 'a'
+	DbAddressUnspentIndex byte = 'u'
+	DbSpentIndex          byte = 'p'
+
+	DbBlockFilter          byte = 'g'
+	DbBlockFilterBestBlock byte = 'G'
+
 	DbWalletKey      byte = 'W'
 	DbWalletScript   byte = 'S'
 	DbWalletAddrBook byte = 'A'
 	DbWalletTx       byte = 'X'
+
+	DbWalletCryptedKey byte = 'E'
+	DbWalletMasterKey  byte = 'M'
+
+	DbWalletHDChain byte = 'H'
+	DbWalletKeyMeta byte = 'K'
+	DbWalletKeyPool byte = 'P'
 )
 
 const (
@@ -55,6 +74,7 @@ type DBWrapper struct {
 	db           *lvldb.DB
 	mdb          *memdb.DB
 	name         string
+	path         string
 	obfuscateKey []byte
 }
 
@@ -186,6 +206,7 @@ func NewDBWrapper(do *DBOption) (*DBWrapper, error) {
 		syncOption:  so,
 		db:          db,
 		name:        filepath.Base(do.FilePath),
+		path:        do.FilePath,
 		//obfuscateKey: make([]byte, 8),
 	}
 	if err := writeObfuscateKey(do, dbw); err != nil {
@@ -336,6 +357,56 @@ func (dbw *DBWrapper) Close() {
 	}
 }
 
+// Rewrite copies the records of the database to a new one, which then takes
+// its place. Nothing erased from the database is left in the new files, not
+// even the keys leveldb keeps track of in its manifest.
+func (dbw *DBWrapper) Rewrite() error {
+	if dbw.mdb != nil {
+		return nil
+	}
+
+	newPath := dbw.path + ".rewrite"
+	oldPath := dbw.path + ".old"
+	for _, path := range []string{newPath, oldPath} {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	newDB, err := lvldb.OpenFile(newPath, &dbw.option)
+	if err != nil {
+		return err
+	}
+	batch := new(lvldb.Batch)
+	iter := dbw.db.NewIterator(nil, nil)
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+	}
+	iter.Release()
+	if err = iter.Error(); err == nil {
+		err = newDB.Write(batch, &dbw.syncOption)
+	}
+	if closeErr := newDB.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := dbw.db.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(dbw.path, oldPath); err != nil {
+		return err
+	}
+	if err := os.Rename(newPath, dbw.path); err != nil {
+		return err
+	}
+	if dbw.db, err = lvldb.OpenFile(dbw.path, &dbw.option); err != nil {
+		return err
+	}
+	return os.RemoveAll(oldPath)
+}
+
 func (dbw *DBWrapper) Reset() {
 	if dbw.mdb != nil {
 		dbw.mdb.Reset()
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -587,3 +588,71 @@ func TestDBWrapper_EstimateSize(t *testing.T) {
 		t.Fatalf("the Estimate Size is:%d", num)
 	}
 }
+
+func TestDBWrapperRewrite(t *testing.T) {
+	path, err := ioutil.TempDir("", "dbwtest")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+
+	dbw, err := NewDBWrapper(&DBOption{
+		FilePath:  path,
+		CacheSize: 1 << 20,
+	})
+	if err != nil {
+		t.Fatalf("NewDBWrapper failed: %s\n", err)
+	}
+	defer dbw.Close()
+
+	key := []byte{'k'}
+	in := rand256()
+	erased := append([]byte{'z'}, rand256()[:32]...)
+	if err := dbw.Write(key, in, false); err != nil {
+		t.Fatalf("dbw.Write(): %s", err)
+	}
+	if err := dbw.Write(erased, []byte{}, false); err != nil {
+		t.Fatalf("dbw.Write(): %s", err)
+	}
+	// The compaction leaves the erased key in the manifest, as a compaction
+	// pointer.
+	if err := dbw.Erase(erased, true); err != nil {
+		t.Fatalf("dbw.Erase(): %s", err)
+	}
+	if err := dbw.CompactRange(nil, nil); err != nil {
+		t.Fatalf("compact range err:%v", err)
+	}
+
+	if err := dbw.Rewrite(); err != nil {
+		t.Fatalf("rewrite err:%v", err)
+	}
+	val, err := dbw.Read(key)
+	if err != nil {
+		t.Fatalf("dbw.Read(): %s", err)
+	}
+	if !bytes.Equal(in, val) {
+		t.Fatalf("should read back original data")
+	}
+	if dbw.Exists(erased) {
+		t.Fatalf("erased key should not exist")
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		t.Fatalf("read db dir failed: %s\n", err)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(path, file.Name()))
+		if err != nil {
+			t.Fatalf("read db file failed: %s\n", err)
+		}
+		if bytes.Contains(data, erased) {
+			t.Errorf("erased key left in %s", file.Name())
+		}
+	}
+	for _, other := range []string{path + ".rewrite", path + ".old"} {
+		if _, err := os.Stat(other); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", other)
+		}
+	}
+}
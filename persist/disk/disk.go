@@ -1,11 +1,13 @@
 package disk
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"os"
@@ -26,6 +28,7 @@ import (
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/undo"
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/net/wire"
@@ -46,6 +49,9 @@ const (
 	FlushStateAlways
 )
 
+// CoinCacheUsage is the memory budget of the coins cache, in bytes.
+const CoinCacheUsage = 5000 * 300
+
 func OpenBlockFile(pos *block.DiskBlockPos, fReadOnly bool) *os.File {
 	return OpenDiskFile(*pos, "blk", fReadOnly)
 }
@@ -277,6 +283,40 @@ func ReadBlockFromDisk(pindex *blockindex.BlockIndex, param *model.BitcoinParams
 	return blk, true
 }
 
+// ReadTxFromDisk reads a single transaction located by the txindex, together
+// with the header of the block containing it.
+func ReadTxFromDisk(pos *block.DiskTxPos) (*tx.Tx, *block.BlockHeader, error) {
+	if pos == nil || pos.BlockIn == nil {
+		return nil, nil, errors.New("ErrNullTxPos")
+	}
+	file := OpenBlockFile(pos.BlockIn, true)
+	if file == nil {
+		log.Error("ReadTxFromDisk: OpenBlockFile failed for %s", pos.BlockIn.String())
+		return nil, nil, errors.New("ErrOpenBlockFile")
+	}
+	defer file.Close()
+
+	// skip the leading 4 bytes of block data length
+	if _, err := file.Seek(4, io.SeekCurrent); err != nil {
+		return nil, nil, err
+	}
+	header := block.NewBlockHeader()
+	if err := header.Unserialize(file); err != nil {
+		log.Error("ReadTxFromDisk: Unserialize block header failed - %s at %s", err.Error(), pos.BlockIn.String())
+		return nil, nil, err
+	}
+	if _, err := file.Seek(int64(pos.TxOffsetIn), io.SeekCurrent); err != nil {
+		return nil, nil, err
+	}
+	txn := tx.NewEmptyTx()
+	if err := txn.Unserialize(bufio.NewReader(file)); err != nil {
+		log.Error("ReadTxFromDisk: Unserialize tx failed - %s at %s+%d", err.Error(),
+			pos.BlockIn.String(), pos.TxOffsetIn)
+		return nil, nil, err
+	}
+	return txn, header, nil
+}
+
 func WriteBlockToDisk(block *block.Block, pos *block.DiskBlockPos) bool {
 	// Open history file to append
 	file := OpenBlockFile(pos, false)
@@ -321,7 +361,6 @@ func FlushStateToDisk(mode FlushStateMode, nManualPruneHeight int) error {
 	flushForPrune := false
 	dbPeakUsageFactor := int64(2)
 	maxBlockCoinsDBUsage := float64(dbPeakUsageFactor * 200)
-	coinCacheUsage := 5000 * 300
 	dataBaseWriteInterval := 60 * 60
 	dataBaseFlushInterval := 24 * 60 * 60
 	minBlockCoinsDBUsage := 50 * dbPeakUsageFactor
@@ -359,7 +398,7 @@ func FlushStateToDisk(mode FlushStateMode, nManualPruneHeight int) error {
 	mempoolUsage := mem.GetPoolUsage()
 	mempoolSizeMax := int64(persist.DefaultMaxMemPoolSize) * 1000000
 	cacheSize := coinsTip.DynamicMemoryUsage() * dbPeakUsageFactor
-	totalSpace := float64(coinCacheUsage) + math.Max(float64(mempoolSizeMax-mempoolUsage), 0)
+	totalSpace := float64(CoinCacheUsage) + math.Max(float64(mempoolSizeMax-mempoolUsage), 0)
 	// The cache is large and we're within 10% and 200 MiB or 50% and 50MiB
 	// of the limit, but we have time now (not in the middle of a block processing).
 	x := math.Max(totalSpace/2, totalSpace-float64(minBlockCoinsDBUsage*1024*1024))
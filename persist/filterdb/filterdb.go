@@ -0,0 +1,111 @@
+package filterdb
+
+import (
+	"bytes"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// maxFilterSize bounds the filters read back from the db.
+const maxFilterSize = 1 << 25
+
+// FilterDB stores the basic block filters of BIP158 along with their hashes
+// and headers, by block hash.
+type FilterDB struct {
+	dbw *db.DBWrapper
+}
+
+// FilterEntry is the filter of a block, its hash and its header, which
+// commits to the headers of all the filters before it.
+type FilterEntry struct {
+	Filter     []byte
+	FilterHash util.Hash
+	Header     util.Hash
+}
+
+var filterDb *FilterDB
+
+type FilterDBConfig struct {
+	Do *db.DBOption
+}
+
+func InitFilterDB(fc *FilterDBConfig) {
+	dbw, err := db.NewDBWrapper(fc.Do)
+	if err != nil {
+		panic("init DBWrapper failed..." + err.Error())
+	}
+	filterDb = &FilterDB{
+		dbw: dbw,
+	}
+}
+
+func GetInstance() *FilterDB {
+	if filterDb == nil {
+		panic("filterDb has not init !!!")
+	}
+	return filterDb
+}
+
+func filterKey(blockHash *util.Hash) []byte {
+	key := make([]byte, 0, 1+util.Hash256Size)
+	key = append(key, db.DbBlockFilter)
+	return append(key, blockHash[:]...)
+}
+
+// ReadFilter returns the filter of a block, or nil if it is not indexed.
+func (filterDB *FilterDB) ReadFilter(blockHash *util.Hash) (*FilterEntry, error) {
+	vdata, err := filterDB.dbw.Read(filterKey(blockHash))
+	if err == leveldb.ErrNotFound || (err == nil && vdata == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Error("filterDB: read filter of %s failed: %v", blockHash, err)
+		return nil, err
+	}
+
+	entry := &FilterEntry{}
+	r := bytes.NewReader(vdata)
+	if err := util.ReadElements(r, &entry.FilterHash, &entry.Header); err != nil {
+		return nil, err
+	}
+	entry.Filter, err = util.ReadVarBytes(r, maxFilterSize, "filter")
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// WriteFilter records the filter of a block, which becomes the last one
+// indexed.
+func (filterDB *FilterDB) WriteFilter(blockHash *util.Hash, entry *FilterEntry) error {
+	buf := bytes.NewBuffer(make([]byte, 0, 2*util.Hash256Size+9+len(entry.Filter)))
+	if err := util.WriteElements(buf, &entry.FilterHash, &entry.Header); err != nil {
+		return err
+	}
+	if err := util.WriteVarBytes(buf, entry.Filter); err != nil {
+		return err
+	}
+
+	batch := db.NewBatchWrapper(filterDB.dbw)
+	batch.Write(filterKey(blockHash), buf.Bytes())
+	batch.Write([]byte{db.DbBlockFilterBestBlock}, blockHash[:])
+	return filterDB.dbw.WriteBatch(batch, false)
+}
+
+// ReadBestBlock returns the hash of the last block indexed, or nil if no
+// block has been indexed yet.
+func (filterDB *FilterDB) ReadBestBlock() (*util.Hash, error) {
+	vdata, err := filterDB.dbw.Read([]byte{db.DbBlockFilterBestBlock})
+	if err == leveldb.ErrNotFound || (err == nil && vdata == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var hash util.Hash
+	copy(hash[:], vdata)
+	return &hash, nil
+}
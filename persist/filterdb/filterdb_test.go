@@ -0,0 +1,77 @@
+package filterdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+)
+
+func initFilterDB() (cleanup func()) {
+	path, err := ioutil.TempDir("/tmp", "blockFilter")
+	if err != nil {
+		panic(fmt.Sprintf("generate temp db path failed: %s\n", err))
+	}
+
+	fc := &FilterDBConfig{
+		Do: &db.DBOption{
+			FilePath:  path,
+			CacheSize: 1 << 20,
+		},
+	}
+
+	InitFilterDB(fc)
+
+	return func() {
+		os.RemoveAll(path)
+	}
+}
+
+func TestWRFilter(t *testing.T) {
+	defer initFilterDB()()
+
+	h1 := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d011")
+	h2 := util.HashFromString("00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048")
+
+	best, err := GetInstance().ReadBestBlock()
+	if err != nil || best != nil {
+		t.Errorf("no block should be indexed yet: %v, %v\n", best, err)
+	}
+	entry, err := GetInstance().ReadFilter(h1)
+	if err != nil || entry != nil {
+		t.Errorf("the filter should not be found: %v, %v\n", entry, err)
+	}
+
+	want1 := &FilterEntry{Filter: []byte{0x01, 0x9d, 0xfc, 0xa8}}
+	want1.FilterHash = util.DoubleSha256Hash(want1.Filter)
+	want1.Header[0] = 0x21
+	want2 := &FilterEntry{Filter: []byte{0x00}}
+	want2.FilterHash = util.DoubleSha256Hash(want2.Filter)
+	want2.Header[0] = 0x42
+
+	for _, item := range []struct {
+		hash  *util.Hash
+		entry *FilterEntry
+	}{{h1, want1}, {h2, want2}} {
+		if err := GetInstance().WriteFilter(item.hash, item.entry); err != nil {
+			t.Errorf("write filter of %s failed: %v\n", item.hash, err)
+		}
+		best, err = GetInstance().ReadBestBlock()
+		if err != nil || best == nil || *best != *item.hash {
+			t.Errorf("the best block should be %s: %v, %v\n", item.hash, best, err)
+		}
+	}
+
+	entry, err = GetInstance().ReadFilter(h1)
+	if err != nil || !reflect.DeepEqual(want1, entry) {
+		t.Errorf("the filter should be %v: %v, %v\n", want1, entry, err)
+	}
+	entry, err = GetInstance().ReadFilter(h2)
+	if err != nil || !reflect.DeepEqual(want2, entry) {
+		t.Errorf("the filter should be %v: %v, %v\n", want2, entry, err)
+	}
+}
@@ -0,0 +1,291 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"math"
+	"sort"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/laddrindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/cashaddr"
+)
+
+var addressIndexHandlers = map[string]commandHandler{
+	"getaddressbalance": handleGetAddressBalance,
+	"getaddressdeltas":  handleGetAddressDeltas,
+	"getaddressmempool": handleGetAddressMempool,
+	"getaddresstxids":   handleGetAddressTxIDs,
+	"getaddressutxos":   handleGetAddressUtxos,
+}
+
+// addressKeys decodes the legacy or cash addresses of a request, returning
+// them along with the address strings to report back.
+func addressKeys(addresses []string) ([]blkdb.AddressKey, map[blkdb.AddressKey]string, error) {
+	if len(addresses) == 0 {
+		return nil, nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "No addresses given")
+	}
+	keys := make([]blkdb.AddressKey, 0, len(addresses))
+	names := make(map[blkdb.AddressKey]string, len(addresses))
+	for _, address := range addresses {
+		addrType, hash, rpcErr := decodeAddress(address)
+		if rpcErr != nil {
+			return nil, nil, rpcErr
+		}
+		// token-aware addresses share the outputs of their plain counterparts
+		key := blkdb.AddressKey{Type: byte(cashaddr.P2PKH)}
+		switch {
+		case len(hash) == 20:
+			if addrType.IsScriptHash() {
+				key.Type = byte(cashaddr.P2SH)
+			}
+		case len(hash) == util.Hash256Size && addrType.IsScriptHash():
+			key.Type = blkdb.AddressTypeP2SH32
+		default:
+			return nil, nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+				"Invalid address: "+address)
+		}
+		copy(key.Hash[:], hash)
+		if _, ok := names[key]; ok {
+			continue
+		}
+		keys = append(keys, key)
+		names[key] = address
+	}
+	return keys, names, nil
+}
+
+// heightRange returns the block heights selected by a request, all of them
+// when no end is given.
+func heightRange(request *btcjson.AddressIndexRequest) (int32, int32, error) {
+	start, end := request.Start, request.End
+	if end == 0 {
+		end = math.MaxInt32
+	}
+	if start < 0 || end < start {
+		return 0, 0, btcjson.NewRPCError(btcjson.RPCInvalidParameter,
+			"Start and end are expected to be a range of block heights")
+	}
+	return start, end, nil
+}
+
+// readAddressDeltas reads the confirmed deltas of the addresses, sorted by
+// block height and position in the block. persist.CsMain must be held.
+func readAddressDeltas(keys []blkdb.AddressKey, start, end int32) ([]*blkdb.AddressDelta, error) {
+	btd := blkdb.GetInstance()
+	deltas := make([]*blkdb.AddressDelta, 0)
+	for i := range keys {
+		addrDeltas, err := btd.ReadAddressIndex(&keys[i], start, end)
+		if err != nil {
+			log.Error("read address index failed: %v", err)
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCDatabase, "Unable to read the address index")
+		}
+		deltas = append(deltas, addrDeltas...)
+	}
+	sort.SliceStable(deltas, func(i, j int) bool {
+		if deltas[i].Height != deltas[j].Height {
+			return deltas[i].Height < deltas[j].Height
+		}
+		return deltas[i].BlockIndex < deltas[j].BlockIndex
+	})
+	return deltas, nil
+}
+
+// handleGetAddressBalance implements the getaddressbalance command.
+func handleGetAddressBalance(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressBalanceCmd)
+	keys, _, err := addressKeys(c.Request.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	deltas, err := readAddressDeltas(keys, 0, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	result := &btcjson.GetAddressBalanceResult{}
+	for _, delta := range deltas {
+		result.Balance += delta.Amount
+		if delta.Amount > 0 {
+			result.Received += delta.Amount
+		}
+	}
+	return result, nil
+}
+
+// handleGetAddressTxIDs implements the getaddresstxids command.
+func handleGetAddressTxIDs(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressTxIDsCmd)
+	keys, _, err := addressKeys(c.Request.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := heightRange(&c.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	deltas, err := readAddressDeltas(keys, start, end)
+	if err != nil {
+		return nil, err
+	}
+	txids := make([]string, 0, len(deltas))
+	seen := make(map[string]struct{}, len(deltas))
+	for _, delta := range deltas {
+		txid := delta.TxID.String()
+		if _, ok := seen[txid]; ok {
+			continue
+		}
+		seen[txid] = struct{}{}
+		txids = append(txids, txid)
+	}
+	return txids, nil
+}
+
+// handleGetAddressDeltas implements the getaddressdeltas command.
+func handleGetAddressDeltas(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressDeltasCmd)
+	keys, names, err := addressKeys(c.Request.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := heightRange(&c.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	deltas, err := readAddressDeltas(keys, start, end)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]btcjson.AddressDeltaResult, 0, len(deltas))
+	for _, delta := range deltas {
+		results = append(results, btcjson.AddressDeltaResult{
+			Satoshis:   delta.Amount,
+			TxID:       delta.TxID.String(),
+			Index:      delta.Index,
+			BlockIndex: delta.BlockIndex,
+			Height:     delta.Height,
+			Address:    names[delta.AddressKey],
+		})
+	}
+	if !c.Request.ChainInfo {
+		return results, nil
+	}
+
+	gChain := chain.GetInstance()
+	startIndex := gChain.GetIndex(start)
+	endIndex := gChain.GetIndex(end)
+	if endIndex == nil {
+		endIndex = gChain.Tip()
+	}
+	if startIndex == nil || startIndex.Height > endIndex.Height {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "Start block height out of range")
+	}
+	return &btcjson.GetAddressDeltasResult{
+		Deltas: results,
+		Start: btcjson.AddressChainInfo{
+			Hash:   startIndex.GetBlockHash().String(),
+			Height: startIndex.Height,
+		},
+		End: btcjson.AddressChainInfo{
+			Hash:   endIndex.GetBlockHash().String(),
+			Height: endIndex.Height,
+		},
+	}, nil
+}
+
+// handleGetAddressUtxos implements the getaddressutxos command.
+func handleGetAddressUtxos(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressUtxosCmd)
+	keys, names, err := addressKeys(c.Request.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	btd := blkdb.GetInstance()
+	unspents := make([]*blkdb.AddressUnspent, 0)
+	for i := range keys {
+		addrUnspents, err := btd.ReadAddressUnspent(&keys[i])
+		if err != nil {
+			log.Error("read address unspent index failed: %v", err)
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCDatabase, "Unable to read the address index")
+		}
+		unspents = append(unspents, addrUnspents...)
+	}
+	sort.SliceStable(unspents, func(i, j int) bool {
+		return unspents[i].Height < unspents[j].Height
+	})
+
+	results := make([]btcjson.AddressUtxoResult, 0, len(unspents))
+	for _, unspent := range unspents {
+		results = append(results, btcjson.AddressUtxoResult{
+			Address:     names[unspent.AddressKey],
+			TxID:        unspent.TxID.String(),
+			OutputIndex: unspent.Index,
+			Script:      hex.EncodeToString(unspent.Script),
+			Satoshis:    unspent.Amount,
+			Height:      unspent.Height,
+		})
+	}
+	if !c.Request.ChainInfo {
+		return results, nil
+	}
+
+	tip := chain.GetInstance().Tip()
+	return &btcjson.GetAddressUtxosResult{
+		Utxos:  results,
+		Hash:   tip.GetBlockHash().String(),
+		Height: tip.Height,
+	}, nil
+}
+
+// handleGetAddressMempool implements the getaddressmempool command.
+func handleGetAddressMempool(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressMempoolCmd)
+	keys, names, err := addressKeys(c.Request.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas := laddrindex.MempoolDeltas(keys)
+	results := make([]btcjson.AddressMempoolResult, 0, len(deltas))
+	for _, delta := range deltas {
+		result := btcjson.AddressMempoolResult{
+			Address:   names[delta.AddressKey],
+			TxID:      delta.TxID.String(),
+			Index:     delta.Index,
+			Satoshis:  delta.Amount,
+			Timestamp: delta.Time,
+		}
+		if delta.Spending {
+			prevOut := delta.PrevIndex
+			result.PrevTxID = delta.PrevTxID.String()
+			result.PrevOut = &prevOut
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func registerAddressIndexRPCCommands() {
+	for name, handler := range addressIndexHandlers {
+		appendCommand(name, handler)
+	}
+}
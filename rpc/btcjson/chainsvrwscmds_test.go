@@ -0,0 +1,228 @@
+// Copyright (c) 2014-2017 The btcsuite developers
+// Copyright (c) 2015-2017 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestChainSvrWsCmds tests all of the chain server websocket-specific commands
+// marshal and unmarshal into valid results include handling of optional fields
+// being omitted in the marshalled command, while optional fields with defaults
+// have the default assigned on unmarshalled commands.
+func TestChainSvrWsCmds(t *testing.T) {
+	t.Parallel()
+
+	testID := int(1)
+	tests := []struct {
+		name         string
+		newCmd       func() (interface{}, error)
+		staticCmd    func() interface{}
+		marshalled   string
+		unmarshalled interface{}
+	}{
+		{
+			name: "notifyblocks",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("notifyblocks")
+			},
+			staticCmd: func() interface{} {
+				return NewNotifyBlocksCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyblocks","params":[],"id":1}`,
+			unmarshalled: &NotifyBlocksCmd{},
+		},
+		{
+			name: "stopnotifyblocks",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("stopnotifyblocks")
+			},
+			staticCmd: func() interface{} {
+				return NewStopNotifyBlocksCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyblocks","params":[],"id":1}`,
+			unmarshalled: &StopNotifyBlocksCmd{},
+		},
+		{
+			name: "notifynewtransactions",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("notifynewtransactions")
+			},
+			staticCmd: func() interface{} {
+				return NewNotifyNewTransactionsCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifynewtransactions","params":[],"id":1}`,
+			unmarshalled: &NotifyNewTransactionsCmd{
+				Verbose: Bool(false),
+			},
+		},
+		{
+			name: "notifynewtransactions optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("notifynewtransactions", true)
+			},
+			staticCmd: func() interface{} {
+				return NewNotifyNewTransactionsCmd(Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifynewtransactions","params":[true],"id":1}`,
+			unmarshalled: &NotifyNewTransactionsCmd{
+				Verbose: Bool(true),
+			},
+		},
+		{
+			name: "stopnotifynewtransactions",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("stopnotifynewtransactions")
+			},
+			staticCmd: func() interface{} {
+				return NewStopNotifyNewTransactionsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifynewtransactions","params":[],"id":1}`,
+			unmarshalled: &StopNotifyNewTransactionsCmd{},
+		},
+		{
+			name: "session",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("session")
+			},
+			staticCmd: func() interface{} {
+				return NewSessionCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"session","params":[],"id":1}`,
+			unmarshalled: &SessionCmd{},
+		},
+		{
+			name: "notifyreceived",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("notifyreceived", []string{"1Address"})
+			},
+			staticCmd: func() interface{} {
+				return NewNotifyReceivedCmd([]string{"1Address"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifyreceived","params":[["1Address"]],"id":1}`,
+			unmarshalled: &NotifyReceivedCmd{
+				Addresses: []string{"1Address"},
+			},
+		},
+		{
+			name: "stopnotifyreceived",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("stopnotifyreceived", []string{"1Address"})
+			},
+			staticCmd: func() interface{} {
+				return NewStopNotifyReceivedCmd([]string{"1Address"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"stopnotifyreceived","params":[["1Address"]],"id":1}`,
+			unmarshalled: &StopNotifyReceivedCmd{
+				Addresses: []string{"1Address"},
+			},
+		},
+		{
+			name: "loadtxfilter",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("loadtxfilter", false, `["1Address"]`, `[{"hash":"0000000000000000000000000000000000000000000000000000000000000123","index":0}]`)
+			},
+			staticCmd: func() interface{} {
+				addrs := []string{"1Address"}
+				ops := []OutPoint{{
+					Hash:  "0000000000000000000000000000000000000000000000000000000000000123",
+					Index: 0,
+				}}
+				return NewLoadTxFilterCmd(false, addrs, ops)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"loadtxfilter","params":[false,["1Address"],[{"hash":"0000000000000000000000000000000000000000000000000000000000000123","index":0}]],"id":1}`,
+			unmarshalled: &LoadTxFilterCmd{
+				Reload:    false,
+				Addresses: []string{"1Address"},
+				OutPoints: []OutPoint{{Hash: "0000000000000000000000000000000000000000000000000000000000000123", Index: 0}},
+			},
+		},
+		{
+			name: "rescanblocks",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("rescanblocks", `["0000000000000000000000000000000000000000000000000000000000000123"]`)
+			},
+			staticCmd: func() interface{} {
+				blockhashes := []string{"0000000000000000000000000000000000000000000000000000000000000123"}
+				return NewRescanBlocksCmd(blockhashes)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"rescanblocks","params":[["0000000000000000000000000000000000000000000000000000000000000123"]],"id":1}`,
+			unmarshalled: &RescanBlocksCmd{
+				BlockHashes: []string{"0000000000000000000000000000000000000000000000000000000000000123"},
+			},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Marshal the command as created by the new static command
+		// creation function.
+		marshalled, err := MarshalCmd(testID, test.staticCmd())
+		if err != nil {
+			t.Errorf("MarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !bytes.Equal(marshalled, []byte(test.marshalled)) {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.marshalled)
+			continue
+		}
+
+		// Ensure the command is created without error via the generic
+		// new command creation function.
+		cmd, err := test.newCmd()
+		if err != nil {
+			t.Errorf("Test #%d (%s) unexpected NewCmd error: %v ",
+				i, test.name, err)
+		}
+
+		// Marshal the command as created by the generic new command
+		// creation function.
+		marshalled, err = MarshalCmd(testID, cmd)
+		if err != nil {
+			t.Errorf("MarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !bytes.Equal(marshalled, []byte(test.marshalled)) {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.marshalled)
+			continue
+		}
+
+		var request Request
+		if err := json.Unmarshal(marshalled, &request); err != nil {
+			t.Errorf("Test #%d (%s) unexpected error while "+
+				"unmarshalling JSON-RPC request: %v", i,
+				test.name, err)
+			continue
+		}
+
+		cmd, err = UnmarshalCmd(&request)
+		if err != nil {
+			t.Errorf("UnmarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(cmd, test.unmarshalled) {
+			t.Errorf("Test #%d (%s) unexpected unmarshalled command "+
+				"- got %s, want %s", i, test.name,
+				fmt.Sprintf("(%T) %+[1]v", cmd),
+				fmt.Sprintf("(%T) %+[1]v\n", test.unmarshalled))
+			continue
+		}
+	}
+}
@@ -134,6 +134,94 @@ func NewGetAddedNodeInfoCmd(node *string) *GetAddedNodeInfoCmd {
 	}
 }
 
+// AddressIndexRequest selects the addresses of the getaddress* JSON-RPC
+// commands, along with the range of block heights of the getaddresstxids and
+// getaddressdeltas commands. It can be given as a single address instead.
+type AddressIndexRequest struct {
+	Addresses []string `json:"addresses"`
+	Start     int32    `json:"start,omitempty"`
+	End       int32    `json:"end,omitempty"`
+	ChainInfo bool     `json:"chainInfo,omitempty"`
+}
+
+// UnmarshalJSON provides a custom Unmarshal method for AddressIndexRequest,
+// which accepts a single address string as well.
+func (r *AddressIndexRequest) UnmarshalJSON(data []byte) error {
+	var address string
+	if err := json.Unmarshal(data, &address); err == nil {
+		*r = AddressIndexRequest{Addresses: []string{address}}
+		return nil
+	}
+
+	type addressIndexRequest AddressIndexRequest
+	return json.Unmarshal(data, (*addressIndexRequest)(r))
+}
+
+// GetAddressBalanceCmd defines the getaddressbalance JSON-RPC command.
+type GetAddressBalanceCmd struct {
+	Request AddressIndexRequest
+}
+
+// NewGetAddressBalanceCmd returns a new instance which can be used to issue a
+// getaddressbalance JSON-RPC command.
+func NewGetAddressBalanceCmd(request AddressIndexRequest) *GetAddressBalanceCmd {
+	return &GetAddressBalanceCmd{
+		Request: request,
+	}
+}
+
+// GetAddressDeltasCmd defines the getaddressdeltas JSON-RPC command.
+type GetAddressDeltasCmd struct {
+	Request AddressIndexRequest
+}
+
+// NewGetAddressDeltasCmd returns a new instance which can be used to issue a
+// getaddressdeltas JSON-RPC command.
+func NewGetAddressDeltasCmd(request AddressIndexRequest) *GetAddressDeltasCmd {
+	return &GetAddressDeltasCmd{
+		Request: request,
+	}
+}
+
+// GetAddressMempoolCmd defines the getaddressmempool JSON-RPC command.
+type GetAddressMempoolCmd struct {
+	Request AddressIndexRequest
+}
+
+// NewGetAddressMempoolCmd returns a new instance which can be used to issue a
+// getaddressmempool JSON-RPC command.
+func NewGetAddressMempoolCmd(request AddressIndexRequest) *GetAddressMempoolCmd {
+	return &GetAddressMempoolCmd{
+		Request: request,
+	}
+}
+
+// GetAddressTxIDsCmd defines the getaddresstxids JSON-RPC command.
+type GetAddressTxIDsCmd struct {
+	Request AddressIndexRequest
+}
+
+// NewGetAddressTxIDsCmd returns a new instance which can be used to issue a
+// getaddresstxids JSON-RPC command.
+func NewGetAddressTxIDsCmd(request AddressIndexRequest) *GetAddressTxIDsCmd {
+	return &GetAddressTxIDsCmd{
+		Request: request,
+	}
+}
+
+// GetAddressUtxosCmd defines the getaddressutxos JSON-RPC command.
+type GetAddressUtxosCmd struct {
+	Request AddressIndexRequest
+}
+
+// NewGetAddressUtxosCmd returns a new instance which can be used to issue a
+// getaddressutxos JSON-RPC command.
+func NewGetAddressUtxosCmd(request AddressIndexRequest) *GetAddressUtxosCmd {
+	return &GetAddressUtxosCmd{
+		Request: request,
+	}
+}
+
 // GetBestBlockHashCmd defines the getbestblockhash JSON-RPC command.
 type GetBestBlockHashCmd struct{}
 
@@ -208,6 +296,21 @@ func NewGetBlockHeaderCmd(hash string, verbose *bool) *GetBlockHeaderCmd {
 	}
 }
 
+// GetBlockFilterCmd defines the getblockfilter JSON-RPC command.
+type GetBlockFilterCmd struct {
+	BlockHash  string
+	FilterType *string `jsonrpcdefault:"\"basic\""`
+}
+
+// NewGetBlockFilterCmd returns a new instance which can be used to issue a
+// getblockfilter JSON-RPC command.
+func NewGetBlockFilterCmd(blockHash string, filterType *string) *GetBlockFilterCmd {
+	return &GetBlockFilterCmd{
+		BlockHash:  blockHash,
+		FilterType: filterType,
+	}
+}
+
 // GetChainTxStatsCmd defines the getchaintxstats JSON-RPC command.
 type GetChainTxStatsCmd struct {
 	Blocks    *int32
@@ -484,6 +587,25 @@ func NewGetRawTransactionCmd(txHash string, verbose *bool) *GetRawTransactionCmd
 	}
 }
 
+// SpentInfoRequest selects the output of the getspentinfo JSON-RPC command.
+type SpentInfoRequest struct {
+	TxID  string `json:"txid"`
+	Index uint32 `json:"index"`
+}
+
+// GetSpentInfoCmd defines the getspentinfo JSON-RPC command.
+type GetSpentInfoCmd struct {
+	Request SpentInfoRequest
+}
+
+// NewGetSpentInfoCmd returns a new instance which can be used to issue a
+// getspentinfo JSON-RPC command.
+func NewGetSpentInfoCmd(request SpentInfoRequest) *GetSpentInfoCmd {
+	return &GetSpentInfoCmd{
+		Request: request,
+	}
+}
+
 // GetTxOutCmd defines the gettxout JSON-RPC command.
 type GetTxOutCmd struct {
 	Txid           string
@@ -522,6 +644,19 @@ func NewGetTxOutProofCmd(txIDs []string, blockHash *string) *GetTxOutProofCmd {
 	}
 }
 
+// DumpTxOutSetCmd defines the dumptxoutset JSON-RPC command.
+type DumpTxOutSetCmd struct {
+	Path string
+}
+
+// NewDumpTxOutSetCmd returns a new instance which can be used to issue a
+// dumptxoutset JSON-RPC command.
+func NewDumpTxOutSetCmd(path string) *DumpTxOutSetCmd {
+	return &DumpTxOutSetCmd{
+		Path: path,
+	}
+}
+
 // GetTxOutSetInfoCmd defines the gettxoutsetinfo JSON-RPC command.
 type GetTxOutSetInfoCmd struct{}
 
@@ -583,6 +718,45 @@ func NewInvalidateBlockCmd(blockHash string) *InvalidateBlockCmd {
 	}
 }
 
+// FinalizeBlockCmd defines the finalizeblock JSON-RPC command.
+type FinalizeBlockCmd struct {
+	BlockHash string
+}
+
+// NewFinalizeBlockCmd returns a new instance which can be used to issue a
+// finalizeblock JSON-RPC command.
+func NewFinalizeBlockCmd(blockHash string) *FinalizeBlockCmd {
+	return &FinalizeBlockCmd{
+		BlockHash: blockHash,
+	}
+}
+
+// ParkBlockCmd defines the parkblock JSON-RPC command.
+type ParkBlockCmd struct {
+	BlockHash string
+}
+
+// NewParkBlockCmd returns a new instance which can be used to issue a
+// parkblock JSON-RPC command.
+func NewParkBlockCmd(blockHash string) *ParkBlockCmd {
+	return &ParkBlockCmd{
+		BlockHash: blockHash,
+	}
+}
+
+// UnparkBlockCmd defines the unparkblock JSON-RPC command.
+type UnparkBlockCmd struct {
+	BlockHash string
+}
+
+// NewUnparkBlockCmd returns a new instance which can be used to issue an
+// unparkblock JSON-RPC command.
+func NewUnparkBlockCmd(blockHash string) *UnparkBlockCmd {
+	return &UnparkBlockCmd{
+		BlockHash: blockHash,
+	}
+}
+
 // PingCmd defines the ping JSON-RPC command.
 type PingCmd struct{}
 
@@ -618,6 +792,41 @@ func NewReconsiderBlockCmd(blockHash string) *ReconsiderBlockCmd {
 	}
 }
 
+// LoadTxOutSetCmd defines the loadtxoutset JSON-RPC command.
+type LoadTxOutSetCmd struct {
+	Path string
+}
+
+// NewLoadTxOutSetCmd returns a new instance which can be used to issue a
+// loadtxoutset JSON-RPC command.
+func NewLoadTxOutSetCmd(path string) *LoadTxOutSetCmd {
+	return &LoadTxOutSetCmd{
+		Path: path,
+	}
+}
+
+// ImportBlocksCmd defines the importblocks JSON-RPC command.
+type ImportBlocksCmd struct {
+	Files []string
+}
+
+// NewImportBlocksCmd returns a new instance which can be used to issue an
+// importblocks JSON-RPC command.
+func NewImportBlocksCmd(files []string) *ImportBlocksCmd {
+	return &ImportBlocksCmd{
+		Files: files,
+	}
+}
+
+// SaveMempoolCmd defines the savemempool JSON-RPC command.
+type SaveMempoolCmd struct{}
+
+// NewSaveMempoolCmd returns a new instance which can be used to issue a
+// savemempool JSON-RPC command.
+func NewSaveMempoolCmd() *SaveMempoolCmd {
+	return &SaveMempoolCmd{}
+}
+
 // SearchRawTransactionsCmd defines the searchrawtransactions JSON-RPC command.
 type SearchRawTransactionsCmd struct {
 	Address     string
@@ -864,11 +1073,14 @@ func NewWaitForBlockHeightCmd(height int32, timeout *int) *WaitForBlockHeightCmd
 	}
 }
 
+// WaitForBlockCmd defines the waitforblock JSON-RPC command.
 type WaitForBlockCmd struct {
-	BlockHash *string `json:"height"`
+	BlockHash *string `json:"blockhash"`
 	Timeout   *int    `json:"timeout" jsonrpcdefault:"0"`
 }
 
+// NewWaitForBlockCmd returns a new instance which can be used to issue a
+// waitforblock JSON-RPC command.
 func NewWaitForBlockCmd(blockhash *string, timeout *int) *WaitForBlockCmd {
 	return &WaitForBlockCmd{
 		BlockHash: blockhash,
@@ -876,6 +1088,19 @@ func NewWaitForBlockCmd(blockhash *string, timeout *int) *WaitForBlockCmd {
 	}
 }
 
+// WaitForNewBlockCmd defines the waitfornewblock JSON-RPC command.
+type WaitForNewBlockCmd struct {
+	Timeout *int `json:"timeout" jsonrpcdefault:"0"`
+}
+
+// NewWaitForNewBlockCmd returns a new instance which can be used to issue a
+// waitfornewblock JSON-RPC command.
+func NewWaitForNewBlockCmd(timeout *int) *WaitForNewBlockCmd {
+	return &WaitForNewBlockCmd{
+		Timeout: timeout,
+	}
+}
+
 // GetNewAddressCmd defines the getnewaddress JSON-RPC command.
 type GetNewAddressCmd struct {
 	Account *string `json:"account" jsonrpcdefault:"\"\""`
@@ -1030,6 +1255,97 @@ func NewAddMultiSigAddressCmd(num int, s []string) *AddMultiSigAddressCmd {
 	}
 }
 
+// EncryptWalletCmd defines the encryptwallet JSON-RPC command.
+type EncryptWalletCmd struct {
+	Passphrase string
+}
+
+// NewEncryptWalletCmd returns a new instance which can be used to issue an
+// encryptwallet JSON-RPC command.
+func NewEncryptWalletCmd(passphrase string) *EncryptWalletCmd {
+	return &EncryptWalletCmd{
+		Passphrase: passphrase,
+	}
+}
+
+// WalletPassphraseCmd defines the walletpassphrase JSON-RPC command.
+type WalletPassphraseCmd struct {
+	Passphrase string
+	Timeout    int64
+}
+
+// NewWalletPassphraseCmd returns a new instance which can be used to issue a
+// walletpassphrase JSON-RPC command.
+func NewWalletPassphraseCmd(passphrase string, timeout int64) *WalletPassphraseCmd {
+	return &WalletPassphraseCmd{
+		Passphrase: passphrase,
+		Timeout:    timeout,
+	}
+}
+
+// WalletPassphraseChangeCmd defines the walletpassphrasechange JSON-RPC
+// command.
+type WalletPassphraseChangeCmd struct {
+	OldPassphrase string
+	NewPassphrase string
+}
+
+// NewWalletPassphraseChangeCmd returns a new instance which can be used to
+// issue a walletpassphrasechange JSON-RPC command.
+func NewWalletPassphraseChangeCmd(oldPassphrase, newPassphrase string) *WalletPassphraseChangeCmd {
+	return &WalletPassphraseChangeCmd{
+		OldPassphrase: oldPassphrase,
+		NewPassphrase: newPassphrase,
+	}
+}
+
+// WalletLockCmd defines the walletlock JSON-RPC command.
+type WalletLockCmd struct{}
+
+// NewWalletLockCmd returns a new instance which can be used to issue a
+// walletlock JSON-RPC command.
+func NewWalletLockCmd() *WalletLockCmd {
+	return &WalletLockCmd{}
+}
+
+// GetRawChangeAddressCmd defines the getrawchangeaddress JSON-RPC command.
+type GetRawChangeAddressCmd struct{}
+
+// NewGetRawChangeAddressCmd returns a new instance which can be used to issue
+// a getrawchangeaddress JSON-RPC command.
+func NewGetRawChangeAddressCmd() *GetRawChangeAddressCmd {
+	return &GetRawChangeAddressCmd{}
+}
+
+// KeyPoolRefillCmd defines the keypoolrefill JSON-RPC command.
+type KeyPoolRefillCmd struct {
+	NewSize *uint `jsonrpcdefault:"100"`
+}
+
+// NewKeyPoolRefillCmd returns a new instance which can be used to issue a
+// keypoolrefill JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewKeyPoolRefillCmd(newSize *uint) *KeyPoolRefillCmd {
+	return &KeyPoolRefillCmd{
+		NewSize: newSize,
+	}
+}
+
+// GetAddressInfoCmd defines the getaddressinfo JSON-RPC command.
+type GetAddressInfoCmd struct {
+	Address string
+}
+
+// NewGetAddressInfoCmd returns a new instance which can be used to issue a
+// getaddressinfo JSON-RPC command.
+func NewGetAddressInfoCmd(address string) *GetAddressInfoCmd {
+	return &GetAddressInfoCmd{
+		Address: address,
+	}
+}
+
 func init() {
 	// No special flags for commands in this file.
 	flags := UsageFlag(0)
@@ -1039,12 +1355,18 @@ func init() {
 	MustRegisterCmd("decoderawtransaction", (*DecodeRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decodescript", (*DecodeScriptCmd)(nil), flags)
 	MustRegisterCmd("getaddednodeinfo", (*GetAddedNodeInfoCmd)(nil), flags)
+	MustRegisterCmd("getaddressbalance", (*GetAddressBalanceCmd)(nil), flags)
+	MustRegisterCmd("getaddressdeltas", (*GetAddressDeltasCmd)(nil), flags)
+	MustRegisterCmd("getaddressmempool", (*GetAddressMempoolCmd)(nil), flags)
+	MustRegisterCmd("getaddresstxids", (*GetAddressTxIDsCmd)(nil), flags)
+	MustRegisterCmd("getaddressutxos", (*GetAddressUtxosCmd)(nil), flags)
 	MustRegisterCmd("getbestblockhash", (*GetBestBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblock", (*GetBlockCmd)(nil), flags)
 	MustRegisterCmd("getblockchaininfo", (*GetBlockChainInfoCmd)(nil), flags)
 	MustRegisterCmd("getblockcount", (*GetBlockCountCmd)(nil), flags)
 	MustRegisterCmd("getblockhash", (*GetBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblockheader", (*GetBlockHeaderCmd)(nil), flags)
+	MustRegisterCmd("getblockfilter", (*GetBlockFilterCmd)(nil), flags)
 	MustRegisterCmd("getblocktemplate", (*GetBlockTemplateCmd)(nil), flags)
 	MustRegisterCmd("getchaintips", (*GetChainTipsCmd)(nil), flags)
 	MustRegisterCmd("getchaintxstats", (*GetChainTxStatsCmd)(nil), flags)
@@ -1062,16 +1384,24 @@ func init() {
 	MustRegisterCmd("getpeerinfo", (*GetPeerInfoCmd)(nil), flags)
 	MustRegisterCmd("getrawmempool", (*GetRawMempoolCmd)(nil), flags)
 	MustRegisterCmd("getrawtransaction", (*GetRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("getspentinfo", (*GetSpentInfoCmd)(nil), flags)
 	MustRegisterCmd("gettxout", (*GetTxOutCmd)(nil), flags)
 	MustRegisterCmd("gettxoutproof", (*GetTxOutProofCmd)(nil), flags)
 	MustRegisterCmd("gettxoutsetinfo", (*GetTxOutSetInfoCmd)(nil), flags)
+	MustRegisterCmd("dumptxoutset", (*DumpTxOutSetCmd)(nil), flags)
+	MustRegisterCmd("loadtxoutset", (*LoadTxOutSetCmd)(nil), flags)
+	MustRegisterCmd("importblocks", (*ImportBlocksCmd)(nil), flags)
 	MustRegisterCmd("getwork", (*GetWorkCmd)(nil), flags)
 	MustRegisterCmd("help", (*HelpCmd)(nil), flags)
 	MustRegisterCmd("version", (*VersionCmd)(nil), flags)
 	MustRegisterCmd("invalidateblock", (*InvalidateBlockCmd)(nil), flags)
+	MustRegisterCmd("finalizeblock", (*FinalizeBlockCmd)(nil), flags)
+	MustRegisterCmd("parkblock", (*ParkBlockCmd)(nil), flags)
+	MustRegisterCmd("unparkblock", (*UnparkBlockCmd)(nil), flags)
 	MustRegisterCmd("ping", (*PingCmd)(nil), flags)
 	MustRegisterCmd("preciousblock", (*PreciousBlockCmd)(nil), flags)
 	MustRegisterCmd("reconsiderblock", (*ReconsiderBlockCmd)(nil), flags)
+	MustRegisterCmd("savemempool", (*SaveMempoolCmd)(nil), flags)
 	MustRegisterCmd("searchrawtransactions", (*SearchRawTransactionsCmd)(nil), flags)
 	MustRegisterCmd("sendrawtransaction", (*SendRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("signmessagewithprivkey", (*SignMessageWithPrivkeyCmd)(nil), flags)
@@ -1098,9 +1428,11 @@ func init() {
 	MustRegisterCmd("pruneblockchain", (*PruneBlockChainCmd)(nil), flags)
 	MustRegisterCmd("createmultisig", (*CreateMultiSigCmd)(nil), flags)
 	MustRegisterCmd("estimatefee", (*EstimateFeeCmd)(nil), flags)
+	MustRegisterCmd("estimatesmartfee", (*EstimateSmartFeeCmd)(nil), flags)
 
 	MustRegisterCmd("waitforblockheight", (*WaitForBlockHeightCmd)(nil), flags)
 	MustRegisterCmd("waitforblock", (*WaitForBlockCmd)(nil), flags)
+	MustRegisterCmd("waitfornewblock", (*WaitForNewBlockCmd)(nil), flags)
 	MustRegisterCmd("echo", (*EchoCmd)(nil), flags)
 
 	MustRegisterCmd("getnewaddress", (*GetNewAddressCmd)(nil), flags)
@@ -1112,4 +1444,11 @@ func init() {
 	MustRegisterCmd("sendmany", (*SendManyCmd)(nil), flags)
 	MustRegisterCmd("fundrawtransaction", (*FundRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("addmultisigaddress", (*AddMultiSigAddressCmd)(nil), flags)
+	MustRegisterCmd("encryptwallet", (*EncryptWalletCmd)(nil), flags)
+	MustRegisterCmd("walletpassphrase", (*WalletPassphraseCmd)(nil), flags)
+	MustRegisterCmd("walletpassphrasechange", (*WalletPassphraseChangeCmd)(nil), flags)
+	MustRegisterCmd("walletlock", (*WalletLockCmd)(nil), flags)
+	MustRegisterCmd("getrawchangeaddress", (*GetRawChangeAddressCmd)(nil), flags)
+	MustRegisterCmd("keypoolrefill", (*KeyPoolRefillCmd)(nil), flags)
+	MustRegisterCmd("getaddressinfo", (*GetAddressInfoCmd)(nil), flags)
 }
@@ -124,6 +124,91 @@ func TestChainSvrCmds(t *testing.T) {
 				Node: String("127.0.0.1"),
 			},
 		},
+		{
+			name: "getaddressbalance",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getaddressbalance", `{"addresses":["1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"]}`)
+			},
+			staticCmd: func() interface{} {
+				return NewGetAddressBalanceCmd(AddressIndexRequest{
+					Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"},
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressbalance","params":[{"addresses":["1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"]}],"id":1}`,
+			unmarshalled: &GetAddressBalanceCmd{
+				Request: AddressIndexRequest{Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"}},
+			},
+		},
+		{
+			name: "getaddressdeltas",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getaddressdeltas", `{"addresses":["1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"],"start":10,"end":20,"chainInfo":true}`)
+			},
+			staticCmd: func() interface{} {
+				return NewGetAddressDeltasCmd(AddressIndexRequest{
+					Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"},
+					Start:     10,
+					End:       20,
+					ChainInfo: true,
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressdeltas","params":[{"addresses":["1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"],"start":10,"end":20,"chainInfo":true}],"id":1}`,
+			unmarshalled: &GetAddressDeltasCmd{
+				Request: AddressIndexRequest{
+					Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"},
+					Start:     10,
+					End:       20,
+					ChainInfo: true,
+				},
+			},
+		},
+		{
+			name: "getaddressmempool",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getaddressmempool", `{"addresses":["1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"]}`)
+			},
+			staticCmd: func() interface{} {
+				return NewGetAddressMempoolCmd(AddressIndexRequest{
+					Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"},
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressmempool","params":[{"addresses":["1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"]}],"id":1}`,
+			unmarshalled: &GetAddressMempoolCmd{
+				Request: AddressIndexRequest{Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"}},
+			},
+		},
+		{
+			name: "getaddresstxids single address",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getaddresstxids", `"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"`)
+			},
+			staticCmd: func() interface{} {
+				return NewGetAddressTxIDsCmd(AddressIndexRequest{
+					Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"},
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddresstxids","params":[{"addresses":["1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"]}],"id":1}`,
+			unmarshalled: &GetAddressTxIDsCmd{
+				Request: AddressIndexRequest{Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG"}},
+			},
+		},
+		{
+			name: "getaddressutxos",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getaddressutxos", `{"addresses":["1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG","3QJmV3qfvL9SuYo34YihAf3sRCW3qSinyC"]}`)
+			},
+			staticCmd: func() interface{} {
+				return NewGetAddressUtxosCmd(AddressIndexRequest{
+					Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG", "3QJmV3qfvL9SuYo34YihAf3sRCW3qSinyC"},
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressutxos","params":[{"addresses":["1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG","3QJmV3qfvL9SuYo34YihAf3sRCW3qSinyC"]}],"id":1}`,
+			unmarshalled: &GetAddressUtxosCmd{
+				Request: AddressIndexRequest{
+					Addresses: []string{"1JBSCVF6VM6QjFZyTnbpLjoCJTQEqVbepG", "3QJmV3qfvL9SuYo34YihAf3sRCW3qSinyC"},
+				},
+			},
+		},
 		{
 			name: "getbestblockhash",
 			newCmd: func() (interface{}, error) {
@@ -231,6 +316,20 @@ func TestChainSvrCmds(t *testing.T) {
 				Verbose: Bool(true),
 			},
 		},
+		{
+			name: "getblockfilter",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getblockfilter", "123")
+			},
+			staticCmd: func() interface{} {
+				return NewGetBlockFilterCmd("123", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockfilter","params":["123"],"id":1}`,
+			unmarshalled: &GetBlockFilterCmd{
+				BlockHash:  "123",
+				FilterType: String("basic"),
+			},
+		},
 		{
 			name: "getblocktemplate",
 			newCmd: func() (interface{}, error) {
@@ -545,6 +644,25 @@ func TestChainSvrCmds(t *testing.T) {
 				Verbose: Bool(true),
 			},
 		},
+		{
+			name: "getspentinfo",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getspentinfo", `{"txid":"0437cd7f8525ceed2324359c2d0ba26006d92d856a9c20fa0241106ee5a597c9","index":0}`)
+			},
+			staticCmd: func() interface{} {
+				return NewGetSpentInfoCmd(SpentInfoRequest{
+					TxID:  "0437cd7f8525ceed2324359c2d0ba26006d92d856a9c20fa0241106ee5a597c9",
+					Index: 0,
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getspentinfo","params":[{"txid":"0437cd7f8525ceed2324359c2d0ba26006d92d856a9c20fa0241106ee5a597c9","index":0}],"id":1}`,
+			unmarshalled: &GetSpentInfoCmd{
+				Request: SpentInfoRequest{
+					TxID:  "0437cd7f8525ceed2324359c2d0ba26006d92d856a9c20fa0241106ee5a597c9",
+					Index: 0,
+				},
+			},
+		},
 		{
 			name: "gettxout",
 			newCmd: func() (interface{}, error) {
@@ -616,6 +734,45 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"gettxoutsetinfo","params":[],"id":1}`,
 			unmarshalled: &GetTxOutSetInfoCmd{},
 		},
+		{
+			name: "dumptxoutset",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("dumptxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return NewDumpTxOutSetCmd("utxo.dat")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"dumptxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &DumpTxOutSetCmd{
+				Path: "utxo.dat",
+			},
+		},
+		{
+			name: "loadtxoutset",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("loadtxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return NewLoadTxOutSetCmd("utxo.dat")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"loadtxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &LoadTxOutSetCmd{
+				Path: "utxo.dat",
+			},
+		},
+		{
+			name: "importblocks",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("importblocks", []string{"bootstrap.dat", "blk00000.dat"})
+			},
+			staticCmd: func() interface{} {
+				return NewImportBlocksCmd([]string{"bootstrap.dat", "blk00000.dat"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importblocks","params":[["bootstrap.dat","blk00000.dat"]],"id":1}`,
+			unmarshalled: &ImportBlocksCmd{
+				Files: []string{"bootstrap.dat", "blk00000.dat"},
+			},
+		},
 		{
 			name: "getwork",
 			newCmd: func() (interface{}, error) {
@@ -681,6 +838,45 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "finalizeblock",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("finalizeblock", "123")
+			},
+			staticCmd: func() interface{} {
+				return NewFinalizeBlockCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"finalizeblock","params":["123"],"id":1}`,
+			unmarshalled: &FinalizeBlockCmd{
+				BlockHash: "123",
+			},
+		},
+		{
+			name: "parkblock",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("parkblock", "123")
+			},
+			staticCmd: func() interface{} {
+				return NewParkBlockCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"parkblock","params":["123"],"id":1}`,
+			unmarshalled: &ParkBlockCmd{
+				BlockHash: "123",
+			},
+		},
+		{
+			name: "unparkblock",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("unparkblock", "123")
+			},
+			staticCmd: func() interface{} {
+				return NewUnparkBlockCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"unparkblock","params":["123"],"id":1}`,
+			unmarshalled: &UnparkBlockCmd{
+				BlockHash: "123",
+			},
+		},
 		{
 			name: "ping",
 			newCmd: func() (interface{}, error) {
@@ -718,6 +914,17 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "savemempool",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("savemempool")
+			},
+			staticCmd: func() interface{} {
+				return NewSaveMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"savemempool","params":[],"id":1}`,
+			unmarshalled: &SaveMempoolCmd{},
+		},
 		{
 			name: "searchrawtransactions",
 			newCmd: func() (interface{}, error) {
@@ -1081,6 +1288,32 @@ func TestChainSvrCmds(t *testing.T) {
 				Timeout: Int(1),
 			},
 		},
+		{
+			name: "waitfornewblock",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("waitfornewblock")
+			},
+			staticCmd: func() interface{} {
+				return NewWaitForNewBlockCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"waitfornewblock","params":[],"id":1}`,
+			unmarshalled: &WaitForNewBlockCmd{
+				Timeout: Int(0),
+			},
+		},
+		{
+			name: "waitfornewblock optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("waitfornewblock", 1000)
+			},
+			staticCmd: func() interface{} {
+				return NewWaitForNewBlockCmd(Int(1000))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"waitfornewblock","params":[1000],"id":1}`,
+			unmarshalled: &WaitForNewBlockCmd{
+				Timeout: Int(1000),
+			},
+		},
 		{
 			name: "pruneblockchain",
 			newCmd: func() (interface{}, error) {
@@ -1226,6 +1459,19 @@ func TestChainSvrCmds(t *testing.T) {
 				NumBlocks: 123,
 			},
 		},
+		{
+			name: "estimatesmartfee",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("estimatesmartfee", 6)
+			},
+			staticCmd: func() interface{} {
+				return NewEstimateSmartFeeCmd(6)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimatesmartfee","params":[6],"id":1}`,
+			unmarshalled: &EstimateSmartFeeCmd{
+				ConfTarget: 6,
+			},
+		},
 		{
 			name: "getbestblock",
 			newCmd: func() (interface{}, error) {
@@ -1482,6 +1728,106 @@ func TestChainSvrCmds(t *testing.T) {
 				SubTractFeeFrom: &[]string{"test"},
 			},
 		},
+		{
+			name: "encryptwallet",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("encryptwallet", "pass")
+			},
+			staticCmd: func() interface{} {
+				return NewEncryptWalletCmd("pass")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"encryptwallet","params":["pass"],"id":1}`,
+			unmarshalled: &EncryptWalletCmd{Passphrase: "pass"},
+		},
+		{
+			name: "walletpassphrase",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("walletpassphrase", "pass", 60)
+			},
+			staticCmd: func() interface{} {
+				return NewWalletPassphraseCmd("pass", 60)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"walletpassphrase","params":["pass",60],"id":1}`,
+			unmarshalled: &WalletPassphraseCmd{
+				Passphrase: "pass",
+				Timeout:    60,
+			},
+		},
+		{
+			name: "walletpassphrasechange",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("walletpassphrasechange", "old", "new")
+			},
+			staticCmd: func() interface{} {
+				return NewWalletPassphraseChangeCmd("old", "new")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"walletpassphrasechange","params":["old","new"],"id":1}`,
+			unmarshalled: &WalletPassphraseChangeCmd{
+				OldPassphrase: "old",
+				NewPassphrase: "new",
+			},
+		},
+		{
+			name: "walletlock",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("walletlock")
+			},
+			staticCmd: func() interface{} {
+				return NewWalletLockCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"walletlock","params":[],"id":1}`,
+			unmarshalled: &WalletLockCmd{},
+		},
+		{
+			name: "getrawchangeaddress",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getrawchangeaddress")
+			},
+			staticCmd: func() interface{} {
+				return NewGetRawChangeAddressCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getrawchangeaddress","params":[],"id":1}`,
+			unmarshalled: &GetRawChangeAddressCmd{},
+		},
+		{
+			name: "keypoolrefill",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("keypoolrefill")
+			},
+			staticCmd: func() interface{} {
+				return NewKeyPoolRefillCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"keypoolrefill","params":[],"id":1}`,
+			unmarshalled: &KeyPoolRefillCmd{
+				NewSize: Uint(100),
+			},
+		},
+		{
+			name: "keypoolrefill optional",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("keypoolrefill", 200)
+			},
+			staticCmd: func() interface{} {
+				return NewKeyPoolRefillCmd(Uint(200))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"keypoolrefill","params":[200],"id":1}`,
+			unmarshalled: &KeyPoolRefillCmd{
+				NewSize: Uint(200),
+			},
+		},
+		{
+			name: "getaddressinfo",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("getaddressinfo", "1Address")
+			},
+			staticCmd: func() interface{} {
+				return NewGetAddressInfoCmd("1Address")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressinfo","params":["1Address"],"id":1}`,
+			unmarshalled: &GetAddressInfoCmd{
+				Address: "1Address",
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -28,6 +28,12 @@ type GetBlockHeaderVerboseResult struct {
 	NextHash      string  `json:"nextblockhash,omitempty"`
 }
 
+// GetBlockFilterResult models the data from the getblockfilter command.
+type GetBlockFilterResult struct {
+	Filter string `json:"filter"`
+	Header string `json:"header"`
+}
+
 // GetBlockVerboseResult models the data from the getblock command when the
 // verbose flag is set.  When the verbose flag is not set, getblock returns a
 // hex-encoded string.
@@ -130,13 +136,14 @@ type GetBlockChainInfoResult struct {
 // GetBlockTemplateResultTx models the transactions field of the
 // getblocktemplate command.
 type GetBlockTemplateResultTx struct {
-	Data    string `json:"data"`
-	TxID    string `json:"txid"`
-	Hash    string `json:"hash"`
-	Depends []int  `json:"depends"`
-	Fee     int64  `json:"fee"`
-	SigOps  int64  `json:"sigops"`
-	Weight  int64  `json:"weight"`
+	Data      string `json:"data"`
+	TxID      string `json:"txid"`
+	Hash      string `json:"hash"`
+	Depends   []int  `json:"depends"`
+	Fee       int64  `json:"fee"`
+	SigOps    *int64 `json:"sigops,omitempty"`
+	SigChecks *int64 `json:"sigchecks,omitempty"`
+	Weight    int64  `json:"weight"`
 }
 
 // GetBlockTemplateResultAux models the coinbaseaux field of the
@@ -155,6 +162,7 @@ type GetBlockTemplateResult struct {
 	Height        int64                      `json:"height"`
 	PreviousHash  string                     `json:"previousblockhash"`
 	SigOpLimit    int64                      `json:"sigoplimit,omitempty"`
+	SigCheckLimit int64                      `json:"sigchecklimit,omitempty"`
 	SizeLimit     int64                      `json:"sizelimit,omitempty"`
 	WeightLimit   int64                      `json:"weightlimit,omitempty"`
 	Transactions  []GetBlockTemplateResultTx `json:"transactions"`
@@ -309,12 +317,21 @@ type ScriptPubKeyResult struct {
 	P2SH      string   `json:"p2sh,omitempty"`
 }
 
+// GetSpentInfoResult models the data from the getspentinfo command. Height is
+// -1 when the output is spent by a mempool transaction.
+type GetSpentInfoResult struct {
+	TxID   string `json:"txid"`
+	Index  uint32 `json:"index"`
+	Height int32  `json:"height"`
+}
+
 // GetTxOutResult models the data from the gettxout command.
 type GetTxOutResult struct {
 	BestBlock     string             `json:"bestblock"`
 	Confirmations int32              `json:"confirmations"`
 	Value         string             `json:"value"`
 	ScriptPubKey  ScriptPubKeyResult `json:"scriptPubKey"`
+	TokenData     *TokenDataResult   `json:"tokenData,omitempty"`
 	Coinbase      bool               `json:"coinbase"`
 }
 
@@ -330,6 +347,85 @@ type GetTxOutSetInfoResult struct {
 	TotalAmount    float64 `json:"total_amount"`
 }
 
+// DumpTxOutSetResult models the data from the dumptxoutset command.
+type DumpTxOutSetResult struct {
+	CoinsWritten uint64 `json:"coins_written"`
+	BaseHash     string `json:"base_hash"`
+	BaseHeight   int32  `json:"base_height"`
+	Path         string `json:"path"`
+	TxOutSetHash string `json:"txoutset_hash"`
+	NChainTx     int32  `json:"nchaintx"`
+}
+
+// LoadTxOutSetResult models the data from the loadtxoutset command.
+type LoadTxOutSetResult struct {
+	CoinsLoaded uint64 `json:"coins_loaded"`
+	TipHash     string `json:"tip_hash"`
+	BaseHeight  int32  `json:"base_height"`
+	Path        string `json:"path"`
+}
+
+// GetAddressBalanceResult models the data from the getaddressbalance command.
+type GetAddressBalanceResult struct {
+	Balance  int64 `json:"balance"`
+	Received int64 `json:"received"`
+}
+
+// AddressDeltaResult models a balance change of the getaddressdeltas command.
+type AddressDeltaResult struct {
+	Satoshis   int64  `json:"satoshis"`
+	TxID       string `json:"txid"`
+	Index      uint32 `json:"index"`
+	BlockIndex uint32 `json:"blockindex"`
+	Height     int32  `json:"height"`
+	Address    string `json:"address"`
+}
+
+// AddressChainInfo models a block of the getaddressdeltas and
+// getaddressutxos commands when the chain info is requested.
+type AddressChainInfo struct {
+	Hash   string `json:"hash"`
+	Height int32  `json:"height"`
+}
+
+// GetAddressDeltasResult models the data from the getaddressdeltas command
+// when the chain info is requested.
+type GetAddressDeltasResult struct {
+	Deltas []AddressDeltaResult `json:"deltas"`
+	Start  AddressChainInfo     `json:"start"`
+	End    AddressChainInfo     `json:"end"`
+}
+
+// AddressUtxoResult models an unspent output of the getaddressutxos command.
+type AddressUtxoResult struct {
+	Address     string `json:"address"`
+	TxID        string `json:"txid"`
+	OutputIndex uint32 `json:"outputIndex"`
+	Script      string `json:"script"`
+	Satoshis    int64  `json:"satoshis"`
+	Height      int32  `json:"height"`
+}
+
+// GetAddressUtxosResult models the data from the getaddressutxos command when
+// the chain info is requested.
+type GetAddressUtxosResult struct {
+	Utxos  []AddressUtxoResult `json:"utxos"`
+	Hash   string              `json:"hash"`
+	Height int32               `json:"height"`
+}
+
+// AddressMempoolResult models a balance change of the getaddressmempool
+// command.
+type AddressMempoolResult struct {
+	Address   string  `json:"address"`
+	TxID      string  `json:"txid"`
+	Index     uint32  `json:"index"`
+	Satoshis  int64   `json:"satoshis"`
+	Timestamp int64   `json:"timestamp"`
+	PrevTxID  string  `json:"prevtxid,omitempty"`
+	PrevOut   *uint32 `json:"prevout,omitempty"`
+}
+
 // GetNetTotalsResult models the data returned from the getnettotals command.
 type GetNetTotalsResult struct {
 	TotalBytesRecv uint64       `json:"totalbytesrecv"`
@@ -454,6 +550,21 @@ type Vout struct {
 	Value        float64            `json:"value"`
 	N            uint32             `json:"n"`
 	ScriptPubKey ScriptPubKeyResult `json:"scriptPubKey"`
+	TokenData    *TokenDataResult   `json:"tokenData,omitempty"`
+}
+
+// TokenDataResult models the tokens carried by a transaction output. The
+// amount is a string since it may not fit in a float64.
+type TokenDataResult struct {
+	Category string     `json:"category"`
+	Amount   string     `json:"amount"`
+	NFT      *NFTResult `json:"nft,omitempty"`
+}
+
+// NFTResult models the non-fungible token carried by a transaction output.
+type NFTResult struct {
+	Capability string `json:"capability"`
+	Commitment string `json:"commitment"`
 }
 
 // GetMiningInfoResult models the data from the getmininginfo command.
@@ -469,6 +580,13 @@ type GetMiningInfoResult struct {
 	Chain                   string  `json:"chain"`
 }
 
+// EstimateSmartFeeResult models the data from the estimatesmartfee command.
+type EstimateSmartFeeResult struct {
+	FeeRate *float64 `json:"feerate,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+	Blocks  int64    `json:"blocks"`
+}
+
 // GetWorkResult models the data from the getwork command.
 type GetWorkResult struct {
 	Data     string `json:"data"`
@@ -553,6 +671,21 @@ type ValidateAddressChainResult struct {
 	HDMasterKeyID string `json:"hdmasterkeyid,omitempty"`
 }
 
+// GetAddressInfoResult models the data returned by the getaddressinfo command.
+type GetAddressInfoResult struct {
+	Address       string `json:"address"`
+	ScriptPubKey  string `json:"scriptPubKey"`
+	IsMine        bool   `json:"ismine"`
+	IsWatchOnly   bool   `json:"iswatchonly"`
+	IsScript      bool   `json:"isscript"`
+	PubKey        string `json:"pubkey,omitempty"`
+	IsCompressed  bool   `json:"iscompressed,omitempty"`
+	Account       string `json:"account,omitempty"`
+	TimeStamp     int64  `json:"timestamp,omitempty"`
+	HDKeyPath     string `json:"hdkeypath,omitempty"`
+	HDMasterKeyID string `json:"hdmasterkeyid,omitempty"`
+}
+
 type GetMempoolEntryRelativeInfoVerbose struct {
 	Size             int      `json:"size"`
 	Fee              float64  `json:"fee"`
@@ -680,3 +813,15 @@ type FundRawTransactionResult struct {
 	Changepos int     `json:"changepos"`
 	Fee       float64 `json:"fee"`
 }
+
+// SessionResult models the data from the session command.
+type SessionResult struct {
+	SessionID uint64 `json:"sessionid"`
+}
+
+// RescannedBlock contains the hash and all discovered transactions of a single
+// rescanned block.
+type RescannedBlock struct {
+	Hash         string   `json:"hash"`
+	Transactions []string `json:"transactions"`
+}
@@ -14,6 +14,7 @@ import (
 type helpCacher struct {
 	sync.Mutex
 	usage      string
+	wsUsage    string
 	methodHelp map[string]helpDescInfo
 }
 
@@ -24,6 +25,7 @@ type helpDescInfo struct {
 
 const (
 	DebugCmd           = ""
+	AddressIndexCmd    = "AddressIndex"
 	BlockChainCmd      = "BlockChain"
 	ControlCmd         = "Control"
 	GeneratingCmd      = "Generating"
@@ -32,6 +34,7 @@ const (
 	RawTransactionsCmd = "RawTransactions"
 	UtilCmd            = "Util"
 	WalletCmd          = "Wallet"
+	WebsocketCmd       = "Websockets"
 )
 
 var allMethodHelp = map[string]helpDescInfo{
@@ -41,6 +44,7 @@ var allMethodHelp = map[string]helpDescInfo{
 	"getblock":              {BlockChainCmd, getblockDesc},
 	"getblockhash":          {BlockChainCmd, getblockhashDesc},
 	"getblockheader":        {BlockChainCmd, getblockheader},
+	"getblockfilter":        {BlockChainCmd, getblockfilterDesc},
 	"getchaintips":          {BlockChainCmd, getchaintipsDesc},
 	"getchaintxstats":       {BlockChainCmd, getchaintxstatsDesc},
 	"getdifficulty":         {BlockChainCmd, getdifficultyDesc},
@@ -49,11 +53,17 @@ var allMethodHelp = map[string]helpDescInfo{
 	"getmempoolentry":       {BlockChainCmd, getmempoolentryDesc},
 	"getmempoolinfo":        {BlockChainCmd, getmempoolinfoDesc},
 	"getrawmempool":         {BlockChainCmd, getrawmempoolDesc},
+	"savemempool":           {BlockChainCmd, savemempoolDesc},
 	"gettxout":              {BlockChainCmd, gettxoutDesc},
+	"getspentinfo":          {BlockChainCmd, getspentinfoDesc},
 	"gettxoutsetinfo":       {BlockChainCmd, gettxoutsetinfoDesc},
+	"dumptxoutset":          {BlockChainCmd, dumptxoutsetDesc},
+	"loadtxoutset":          {BlockChainCmd, loadtxoutsetDesc},
+	"importblocks":          {BlockChainCmd, importblocksDesc},
 	"pruneblockchain":       {BlockChainCmd, pruneblockchainDesc},
 	"verifychain":           {BlockChainCmd, verifychainDesc},
 	"preciousblock":         {BlockChainCmd, preciousblockDesc},
+	"finalizeblock":         {BlockChainCmd, finalizeblockDesc},
 	"gettxoutproof":         {BlockChainCmd, gettxoutproofDesc},
 	"verifytxoutproof":      {BlockChainCmd, verifytxoutproofDesc},
 
@@ -61,6 +71,8 @@ var allMethodHelp = map[string]helpDescInfo{
 	"getmininginfo":    {MiningCmd, getmininginfoDesc},
 	"getblocktemplate": {MiningCmd, getblocktemplateDesc},
 	"submitblock":      {MiningCmd, submitblockDesc},
+	"estimatefee":      {MiningCmd, estimatefeeDesc},
+	"estimatesmartfee": {MiningCmd, estimatesmartfeeDesc},
 
 	"generate":          {GeneratingCmd, generateDesc},
 	"generatetoaddress": {GeneratingCmd, generatetoaddressDesc},
@@ -97,6 +109,7 @@ var allMethodHelp = map[string]helpDescInfo{
 	"setexcessiveblock":  {DebugCmd, setexcessiveblockDesc},
 	"waitforblockheight": {DebugCmd, waitforblockheightDesc},
 	"waitforblock":       {DebugCmd, waitforblockDesc},
+	"waitfornewblock":    {DebugCmd, waitfornewblockDesc},
 	"echo":               {DebugCmd, echoDesc},
 
 	"getnewaddress":      {WalletCmd, getnewaddressDesc},
@@ -108,6 +121,31 @@ var allMethodHelp = map[string]helpDescInfo{
 	"sendmany":           {WalletCmd, sendmanyDesc},
 	"fundrawtransaction": {WalletCmd, fundrawtransactionDesc},
 	"addmultisigaddress": {WalletCmd, addmultisigaddressDesc},
+
+	"encryptwallet":          {WalletCmd, encryptwalletDesc},
+	"walletpassphrase":       {WalletCmd, walletpassphraseDesc},
+	"walletpassphrasechange": {WalletCmd, walletpassphrasechangeDesc},
+	"walletlock":             {WalletCmd, walletlockDesc},
+
+	"getrawchangeaddress": {WalletCmd, getrawchangeaddressDesc},
+	"keypoolrefill":       {WalletCmd, keypoolrefillDesc},
+	"getaddressinfo":      {WalletCmd, getaddressinfoDesc},
+
+	"getaddressbalance": {AddressIndexCmd, getaddressbalanceDesc},
+	"getaddressdeltas":  {AddressIndexCmd, getaddressdeltasDesc},
+	"getaddressmempool": {AddressIndexCmd, getaddressmempoolDesc},
+	"getaddresstxids":   {AddressIndexCmd, getaddresstxidsDesc},
+	"getaddressutxos":   {AddressIndexCmd, getaddressutxosDesc},
+
+	"notifyblocks":              {WebsocketCmd, notifyblocksDesc},
+	"stopnotifyblocks":          {WebsocketCmd, stopnotifyblocksDesc},
+	"notifynewtransactions":     {WebsocketCmd, notifynewtransactionsDesc},
+	"stopnotifynewtransactions": {WebsocketCmd, stopnotifynewtransactionsDesc},
+	"notifyreceived":            {WebsocketCmd, notifyreceivedDesc},
+	"stopnotifyreceived":        {WebsocketCmd, stopnotifyreceivedDesc},
+	"loadtxfilter":              {WebsocketCmd, loadtxfilterDesc},
+	"rescanblocks":              {WebsocketCmd, rescanblocksDesc},
+	"session":                   {WebsocketCmd, sessionDesc},
 }
 
 // rpcMethodHelp returns an RPC help string for the provided method.
@@ -125,7 +163,8 @@ func (c *helpCacher) rpcMethodHelp(method string) (string, error) {
 	return help.description, nil
 }
 
-// rpcUsage returns one-line usage for all support RPC commands.
+// rpcUsage returns one-line usage for all support RPC commands.  The
+// websocket-only commands are included when includeWebsockets is set.
 //
 // This function is safe for concurrent access.
 func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
@@ -133,8 +172,12 @@ func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
 	defer c.Unlock()
 
 	// Return the cached usage if it is available.
-	if c.usage != "" {
-		return c.usage, nil
+	usage := &c.usage
+	if includeWebsockets {
+		usage = &c.wsUsage
+	}
+	if *usage != "" {
+		return *usage, nil
 	}
 
 	// Generate a list of one-line usage for every command.
@@ -147,6 +190,12 @@ func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
 		if !conf.Cfg.Wallet.Enable && info.category == WalletCmd {
 			continue
 		}
+		if !conf.Cfg.Chain.AddressIndex && info.category == AddressIndexCmd {
+			continue
+		}
+		if !includeWebsockets && info.category == WebsocketCmd {
+			continue
+		}
 		if _, ok := usageTexts[info.category]; !ok {
 			category := make([]string, 0)
 			usageTexts[info.category] = &category
@@ -166,10 +215,10 @@ func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
 
 	for _, category := range categories {
 		sort.Strings(*usageTexts[category])
-		c.usage += "--- " + category + " ---\n" +
+		*usage += "--- " + category + " ---\n" +
 			strings.Join(*usageTexts[category], "\n") + "\n\n"
 	}
-	return c.usage, nil
+	return *usage, nil
 }
 
 // newHelpCacher returns a new instance of a help cacher which provides help and
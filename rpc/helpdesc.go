@@ -220,6 +220,20 @@ var (
 		HelpExampleCli("getblockheader", "\"00000000c937983704a73af28acdec37b049d214adbda81d7e2a3dd146f6ed09\"") +
 		HelpExampleRPC("getblockheader", "\"00000000c937983704a73af28acdec37b049d214adbda81d7e2a3dd146f6ed09\"")
 
+	getblockfilterDesc = "getblockfilter \"blockhash\" ( \"filtertype\" )\n" +
+		"\nRetrieve a BIP 157 content filter for a particular block.\n" +
+		"\nArguments:\n" +
+		"1. \"blockhash\"      (string, required) The hash of the block\n" +
+		"2. \"filtertype\"     (string, optional, default=basic) The type name of the filter\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"filter\" : (string) the hex-encoded filter data\n" +
+		"  \"header\" : (string) the hex-encoded filter header\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getblockfilter", "\"00000000c937983704a73af28acdec37b049d214adbda81d7e2a3dd146f6ed09\" \"basic\"") +
+		HelpExampleRPC("getblockfilter", "\"00000000c937983704a73af28acdec37b049d214adbda81d7e2a3dd146f6ed09\", \"basic\"")
+
 	getchaintipsDesc = "getchaintips\n" +
 		"Return information about all known tips in the block tree," +
 		" including the main chain as well as orphaned branches.\n" +
@@ -238,19 +252,21 @@ var (
 		"    \"branchlen\": 1          (numeric) length of branch " +
 		"connecting the tip to the main chain\n" +
 		"    \"status\": \"xxxx\"        (string) status of the chain " +
-		"(active, valid-fork, valid-headers, headers-only, invalid)\n" +
+		"(active, valid-fork, valid-headers, headers-only, parked, invalid)\n" +
 		"  }\n" +
 		"]\n" +
 		"Possible values for status:\n" +
 		"1.  \"invalid\"               This branch contains at least one " +
 		"invalid block\n" +
-		"2.  \"headers-only\"          Not all blocks for this branch are " +
+		"2.  \"parked\"                This branch contains at least one " +
+		"parked block\n" +
+		"3.  \"headers-only\"          Not all blocks for this branch are " +
 		"available, but the headers are valid\n" +
-		"3.  \"valid-headers\"         All blocks are available for this " +
+		"4.  \"valid-headers\"         All blocks are available for this " +
 		"branch, but they were never fully validated\n" +
-		"4.  \"valid-fork\"            This branch is not part of the " +
+		"5.  \"valid-fork\"            This branch is not part of the " +
 		"active chain, but is fully validated\n" +
-		"5.  \"active\"                This is the tip of the active main " +
+		"6.  \"active\"                This is the tip of the active main " +
 		"chain, which is certainly valid\n" +
 		"\nExamples:\n" +
 		HelpExampleCli("getchaintips") +
@@ -457,6 +473,12 @@ var (
 		HelpExampleCli("getmempoolinfo") +
 		HelpExampleRPC("getmempoolinfo")
 
+	savemempoolDesc = "savemempool\n" +
+		"\nDumps the mempool to disk.\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("savemempool") +
+		HelpExampleRPC("savemempool")
+
 	getrawmempoolDesc = "getrawmempool ( verbose )\n" +
 		"\nReturns all transaction ids in memory pool as a json array of " +
 		"string transaction ids.\n" +
@@ -506,6 +528,25 @@ var (
 		HelpExampleCli("getrawmempool") +
 		HelpExampleRPC("getrawmempool")
 
+	getspentinfoDesc = "getspentinfo {\"txid\": \"txid\", \"index\": n}\n" +
+		"\nReturns the txid and index where an output is spent (requires " +
+		"spentindex to be enabled).\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"txid\"     (string, required) The hex string of the txid\n" +
+		"  \"index\"    (numeric, required) The output index\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"txid\"     (string) The transaction id\n" +
+		"  \"index\"    (numeric) The spending input index\n" +
+		"  \"height\"   (numeric) The height of the block spending the " +
+		"output, -1 if it is spent in the mempool\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getspentinfo", "'{\"txid\": \"0437cd7f8525ceed2324359c2d0ba26006d92d856a9c20fa0241106ee5a597c9\", \"index\": 0}'") +
+		HelpExampleRPC("getspentinfo", "{\"txid\": \"0437cd7f8525ceed2324359c2d0ba26006d92d856a9c20fa0241106ee5a597c9\", \"index\": 0}")
+
 	gettxoutDesc = "gettxout \"txid\" n ( include_mempool )\n" +
 		"\nReturns details about an unspent transaction output.\n" +
 		"\nArguments:\n" +
@@ -565,6 +606,60 @@ var (
 		HelpExampleCli("gettxoutsetinfo") +
 		HelpExampleRPC("gettxoutsetinfo")
 
+	dumptxoutsetDesc = "dumptxoutset \"path\"\n" +
+		"\nWrite the serialized UTXO set at the chain tip to disk.\n" +
+		"Note this call may take some time.\n" +
+		"\nArguments:\n" +
+		"1. \"path\"    (string, required) Path to the output file. " +
+		"Relative paths are prefixed by the data directory.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"coins_written\": n,         (numeric) The number of coins written in the snapshot\n" +
+		"  \"base_hash\": \"hash\",        (string) The hash of the base of the snapshot\n" +
+		"  \"base_height\": n,           (numeric) The height of the base of the snapshot\n" +
+		"  \"path\": \"path\",             (string) The absolute path that the snapshot was written to\n" +
+		"  \"txoutset_hash\": \"hash\",    (string) The hash of the UTXO set contents, " +
+		"to be configured as assumeutxo\n" +
+		"  \"nchaintx\": n               (numeric) The number of transactions in the chain " +
+		"up to and including the base block\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("dumptxoutset", "\"utxo.dat\"") +
+		HelpExampleRPC("dumptxoutset", "\"utxo.dat\"")
+
+	loadtxoutsetDesc = "loadtxoutset \"path\"\n" +
+		"\nLoad the serialized UTXO set from disk into an empty chain state, " +
+		"and download the blocks below it in the background.\n" +
+		"The snapshot must hash to the configured assumeutxo value, and to the " +
+		"assumeutxo entry of the chain params for its base block.\n" +
+		"Note this call may take some time.\n" +
+		"\nArguments:\n" +
+		"1. \"path\"    (string, required) Path to the snapshot file. " +
+		"Relative paths are prefixed by the data directory.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"coins_loaded\": n,     (numeric) The number of coins loaded from the snapshot\n" +
+		"  \"tip_hash\": \"hash\",    (string) The hash of the base of the snapshot, the new chain tip\n" +
+		"  \"base_height\": n,      (numeric) The height of the base of the snapshot\n" +
+		"  \"path\": \"path\"         (string) The absolute path that the snapshot was loaded from\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("loadtxoutset", "\"utxo.dat\"") +
+		HelpExampleRPC("loadtxoutset", "\"utxo.dat\"")
+
+	importblocksDesc = "importblocks [\"file\",...]\n" +
+		"\nImport blocks from external block files, such as a bootstrap.dat " +
+		"or the blk?????.dat files of another node, in the background.\n" +
+		"Blocks which are already known are skipped, and the progress is logged.\n" +
+		"\nArguments:\n" +
+		"1. \"files\"    (array, required) Paths to the block files. " +
+		"Relative paths are prefixed by the data directory.\n" +
+		"\nResult:\n" +
+		"null    (json null)\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("importblocks", "'[\"bootstrap.dat\"]'") +
+		HelpExampleRPC("importblocks", "[\"bootstrap.dat\"]")
+
 	pruneblockchainDesc = "pruneblockchain\n" +
 		"\nArguments:\n" +
 		"1. \"height\"       (numeric, required) The block height to prune " +
@@ -582,7 +677,7 @@ var (
 		"\nArguments:\n" +
 		"1. checklevel   (numeric, optional, 0-4, default=3)" +
 		" How thorough the block verification is.\n" +
-		"2. nblocks      (numeric, optional, default=6 , 0=all) " +
+		"2. nblocks      (numeric, optional, default=288, 0=all) " +
 		"The number of blocks to check.\n" +
 		"\nResult:\n" +
 		"true|false       (boolean) Verified or not\n" +
@@ -604,6 +699,20 @@ var (
 		HelpExampleCli("preciousblock", "\"blockhash\"") +
 		HelpExampleRPC("preciousblock", "\"blockhash\"")
 
+	finalizeblockDesc = "finalizeblock \"blockhash\"\n" +
+		"\nTreats a block as final. It cannot be reorged. Any chain that " +
+		"does not contain this block is invalid. Used on a less-work chain, " +
+		"it can effectively invalidate the more-work chain.\n" +
+		"\nBlocks of the active chain -maxreorgdepth blocks deep are " +
+		"finalized automatically.\n" +
+		"\nArguments:\n" +
+		"1. \"blockhash\"   (string, required) the hash of the block to " +
+		"mark as final\n" +
+		"\nResult:\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("finalizeblock", "\"blockhash\"") +
+		HelpExampleRPC("finalizeblock", "\"blockhash\"")
+
 	waitforblockheightDesc = "waitforblockheight \"height\" (timeout)\n" +
 		"\nWaits for (at least) block height and returns the height and " +
 		"hash\n" +
@@ -622,6 +731,22 @@ var (
 		HelpExampleCli("waitforblockheight", "\"height\"") +
 		HelpExampleRPC("waitforblockheight", "\"height\"")
 
+	waitfornewblockDesc = "waitfornewblock (timeout)\n" +
+		"\nWaits for any new block and returns useful info about " +
+		"it.\n" +
+		"\nReturns the current block on timeout or exit.\n" +
+		"\nArguments:\n" +
+		"1. timeout (int, optional, default=0) Time in milliseconds to " +
+		"wait for a response. 0 indicates no timeout.\n" +
+		"\nResult:\n" +
+		"{                           (json object)\n" +
+		"  \"hash\" : {       (string) The blockhash\n" +
+		"  \"height\" : {     (int) Block height\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("waitfornewblock", "1000") +
+		HelpExampleRPC("waitfornewblock", "1000")
+
 	waitforblockDesc = "waitforblock <blockhash> (timeout)\n" +
 		"\nWaits for a specific new block and returns useful info about " +
 		"it.\n" +
@@ -780,9 +905,13 @@ var (
 		"key is not present, fee is unknown and clients MUST NOT assume " +
 		"there isn't one\n" +
 		"         \"sigops\" : n,                (numeric) total SigOps " +
-		"cost, as counted for purposes of block limits; if key is not " +
-		"present, sigop cost is unknown and clients MUST NOT assume it is " +
-		"zero\n" +
+		"cost, as counted for purposes of block limits before the phonon " +
+		"upgrade; if key is not present, sigop cost is unknown and clients " +
+		"MUST NOT assume it is zero\n" +
+		"         \"sigchecks\" : n,             (numeric) total SigChecks, " +
+		"as counted for purposes of block limits after the phonon " +
+		"upgrade; if key is not present, sigchecks are unknown and clients " +
+		"MUST NOT assume they are zero\n" +
 		"         \"required\" : true|false      (boolean) if provided and " +
 		"true, this transaction must be in the final block\n" +
 		"      }\n" +
@@ -812,7 +941,9 @@ var (
 		"  \"noncerange\" : \"00000000ffffffff\",(string) A range of valid " +
 		"nonces\n" +
 		"  \"sigoplimit\" : n,                 (numeric) limit of sigops " +
-		"in blocks\n" +
+		"in blocks, before the phonon upgrade\n" +
+		"  \"sigchecklimit\" : n,              (numeric) limit of " +
+		"SigChecks in blocks, after the phonon upgrade\n" +
 		"  \"sizelimit\" : n,                  (numeric) limit of block " +
 		"size\n" +
 		"  \"curtime\" : ttt,                  (numeric) current timestamp " +
@@ -844,6 +975,38 @@ var (
 		HelpExampleCli("submitblock", "\"mydata\"") +
 		HelpExampleRPC("submitblock", "\"mydata\"")
 
+	estimatefeeDesc = "estimatefee nblocks\n" +
+		"\nEstimates the approximate fee per kilobyte needed for a " +
+		"transaction to begin confirmation within nblocks blocks.\n" +
+		"\nArguments:\n" +
+		"1. nblocks     (numeric, required)\n" +
+		"\nResult:\n" +
+		"n              (numeric) estimated fee-per-kilobyte\n" +
+		"\nA negative value is returned if not enough transactions and " +
+		"blocks have been observed to make an estimate.\n" +
+		"\nExample:\n" +
+		HelpExampleCli("estimatefee", "6")
+
+	estimatesmartfeeDesc = "estimatesmartfee conf_target\n" +
+		"\nEstimates the approximate fee per kilobyte needed for a " +
+		"transaction to begin confirmation within conf_target blocks " +
+		"if possible and return the number of blocks for which the " +
+		"estimate is valid.\n" +
+		"\nArguments:\n" +
+		"1. conf_target     (numeric, required) Confirmation target in blocks (1 - 25)\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"feerate\" : x.x,     (numeric, optional) estimate fee rate in " +
+		util.CurrencyUnit + "/kB\n" +
+		"  \"errors\": [ str... ] (json array of strings, optional) Errors " +
+		"encountered during processing\n" +
+		"  \"blocks\" : n         (numeric) block number where estimate was found\n" +
+		"}\n" +
+		"\nWhen there is no estimate for conf_target, the nearest longer " +
+		"target with one is used.\n" +
+		"\nExample:\n" +
+		HelpExampleCli("estimatesmartfee", "6")
+
 	generateDesc = "generate nblocks ( maxtries )\n" +
 		"\nMine up to nblocks blocks immediately (before the RPC call " +
 		"returns)\n" +
@@ -2017,4 +2180,326 @@ var (
 		"\nAs json rpc call\n" +
 		HelpExampleRPC("addmultisigaddress", "2",
 			"\"[\\\"16sSauSf5pF2UkUwvKGq4qjNRzBZYqgEL5\\\",\\\"171sgjn4YtPu27adkKGrdDwzRTxnRkBfKV\\\"]\"")
+
+	encryptwalletDesc = "encryptwallet \"passphrase\"\n" +
+		"\nEncrypts the wallet with 'passphrase'. This is for first time " +
+		"encryption.\n" +
+		"After this, any calls that interact with private keys such as " +
+		"sending or signing \n" +
+		"will require the passphrase to be set prior the making these " +
+		"calls.\n" +
+		"Use the walletpassphrase call for this, and then walletlock " +
+		"call.\n" +
+		"If the wallet is already encrypted, use the " +
+		"walletpassphrasechange call.\n" +
+		"\nArguments:\n" +
+		"1. \"passphrase\"    (string, required) The pass phrase to encrypt " +
+		"the wallet with. It must be at least 1 character, but should be " +
+		"long.\n" +
+		"\nExamples:\n" +
+		"\nEncrypt your wallet\n" +
+		HelpExampleCli("encryptwallet", "\"my pass phrase\"") +
+		"\nNow set the passphrase to use the wallet, such as for signing " +
+		"or sending bitcoin\n" +
+		HelpExampleCli("walletpassphrase", "\"my pass phrase\"", "60") +
+		"\nNow lock the wallet again by removing the passphrase\n" +
+		HelpExampleCli("walletlock") +
+		"\nAs a json rpc call\n" +
+		HelpExampleRPC("encryptwallet", "\"my pass phrase\"")
+
+	walletpassphraseDesc = "walletpassphrase \"passphrase\" timeout\n" +
+		"\nStores the wallet decryption key in memory for 'timeout' " +
+		"seconds.\n" +
+		"This is needed prior to performing transactions related to " +
+		"private keys such as sending bitcoins\n" +
+		"\nArguments:\n" +
+		"1. \"passphrase\"     (string, required) The wallet passphrase\n" +
+		"2. timeout            (numeric, required) The time to keep the " +
+		"decryption key in seconds; capped at 100000000 (~3 years).\n" +
+		"\nNote:\n" +
+		"Issuing the walletpassphrase command while the wallet is already " +
+		"unlocked will set a new unlock\n" +
+		"time that overrides the old one.\n" +
+		"\nExamples:\n" +
+		"\nUnlock the wallet for 60 seconds\n" +
+		HelpExampleCli("walletpassphrase", "\"my pass phrase\"", "60") +
+		"\nLock the wallet again (before 60 seconds)\n" +
+		HelpExampleCli("walletlock") +
+		"\nAs json rpc call\n" +
+		HelpExampleRPC("walletpassphrase", "\"my pass phrase\"", "60")
+
+	walletpassphrasechangeDesc = "walletpassphrasechange \"oldpassphrase\" \"newpassphrase\"\n" +
+		"\nChanges the wallet passphrase from 'oldpassphrase' to " +
+		"'newpassphrase'.\n" +
+		"\nArguments:\n" +
+		"1. \"oldpassphrase\"      (string) The current passphrase\n" +
+		"2. \"newpassphrase\"      (string) The new passphrase\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("walletpassphrasechange", "\"old one\"", "\"new one\"") +
+		HelpExampleRPC("walletpassphrasechange", "\"old one\"", "\"new one\"")
+
+	walletlockDesc = "walletlock\n" +
+		"\nRemoves the wallet encryption key from memory, locking the " +
+		"wallet.\n" +
+		"After calling this method, you will need to call " +
+		"walletpassphrase again\n" +
+		"before being able to call any methods which require the wallet " +
+		"to be unlocked.\n" +
+		"\nExamples:\n" +
+		"\nSet the passphrase for 2 minutes to perform a transaction\n" +
+		HelpExampleCli("walletpassphrase", "\"my pass phrase\"", "120") +
+		"\nPerform a send (requires passphrase set)\n" +
+		HelpExampleCli("sendtoaddress", "\"1M72Sfpbz1BPpXFHz9m3CdqATR44Jvaydd\"", "1.0") +
+		"\nClear the passphrase since we are done before 2 minutes is up\n" +
+		HelpExampleCli("walletlock") +
+		"\nAs json rpc call\n" +
+		HelpExampleRPC("walletlock")
+
+	getrawchangeaddressDesc = "getrawchangeaddress\n" +
+		"\nReturns a new Bitcoin address, for receiving change.\n" +
+		"This is for use with raw transactions, NOT normal use.\n" +
+		"\nResult:\n" +
+		"\"address\"    (string) The address\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getrawchangeaddress") +
+		HelpExampleRPC("getrawchangeaddress")
+
+	keypoolrefillDesc = "keypoolrefill ( newsize )\n" +
+		"\nFills the keypool.\n" +
+		"Requires wallet passphrase to be set with walletpassphrase " +
+		"call.\n" +
+		"\nArguments\n" +
+		"1. newsize     (numeric, optional, default=100) The new keypool " +
+		"size\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("keypoolrefill") +
+		HelpExampleRPC("keypoolrefill")
+
+	getaddressinfoDesc = "getaddressinfo \"address\"\n" +
+		"\nReturn information about the given bitcoin address. Some " +
+		"information requires the address\n" +
+		"to be in the wallet.\n" +
+		"\nArguments:\n" +
+		"1. \"address\"     (string, required) The bitcoin address to get " +
+		"the information of.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"address\" : \"address\",        (string) The bitcoin address " +
+		"validated\n" +
+		"  \"scriptPubKey\" : \"hex\",       (string) The hex encoded " +
+		"scriptPubKey generated by the address\n" +
+		"  \"ismine\" : true|false,        (boolean) If the address is " +
+		"yours or not\n" +
+		"  \"iswatchonly\" : true|false,   (boolean) If the address is " +
+		"watchonly\n" +
+		"  \"isscript\" : true|false,      (boolean) If the key is a " +
+		"script\n" +
+		"  \"pubkey\" : \"publickeyhex\",    (string, optional) The hex " +
+		"value of the raw public key\n" +
+		"  \"iscompressed\" : true|false,  (boolean, optional) If the " +
+		"address is compressed\n" +
+		"  \"account\" : \"account\"         (string) DEPRECATED. The " +
+		"account associated with the address, \"\" is the default account\n" +
+		"  \"timestamp\" : timestamp,      (number, optional) The " +
+		"creation time of the key if available in seconds since epoch (Jan " +
+		"1 1970 GMT)\n" +
+		"  \"hdkeypath\" : \"keypath\"       (string, optional) The HD " +
+		"keypath if the key is HD and available\n" +
+		"  \"hdmasterkeyid\" : \"<hash160>\" (string, optional) The " +
+		"Hash160 of the HD seed\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getaddressinfo", "\"1PSSGeFHDnKNxiEyFrD1wcEaHr9hrQDDWc\"") +
+		HelpExampleRPC("getaddressinfo", "\"1PSSGeFHDnKNxiEyFrD1wcEaHr9hrQDDWc\"")
+
+	getaddressbalanceDesc = "getaddressbalance {\"addresses\": [\"address\",...]}\n" +
+		"\nReturns the balance of addresses (requires addressindex to be enabled).\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"addresses\"    (array, required) The legacy or cash addresses\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"balance\"     (numeric) The current balance in satoshis\n" +
+		"  \"received\"    (numeric) The total number of satoshis received " +
+		"(including change)\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getaddressbalance", "'{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}'") +
+		HelpExampleRPC("getaddressbalance", "{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}")
+
+	getaddressdeltasDesc = "getaddressdeltas {\"addresses\": [\"address\",...], \"start\": n, \"end\": n, \"chainInfo\": bool}\n" +
+		"\nReturns all changes for addresses (requires addressindex to be enabled).\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"addresses\"    (array, required) The legacy or cash addresses\n" +
+		"  \"start\"        (numeric, optional) The start block height\n" +
+		"  \"end\"          (numeric, optional) The end block height\n" +
+		"  \"chainInfo\"    (boolean, optional) Include chain info in " +
+		"results, only applies if start and end specified\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"satoshis\"    (numeric) The difference of satoshis\n" +
+		"    \"txid\"        (string) The related txid\n" +
+		"    \"index\"       (numeric) The related input or output index\n" +
+		"    \"blockindex\"  (numeric) The position of the transaction in the block\n" +
+		"    \"height\"      (numeric) The block height\n" +
+		"    \"address\"     (string) The address\n" +
+		"  }\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getaddressdeltas", "'{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}'") +
+		HelpExampleRPC("getaddressdeltas", "{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}")
+
+	getaddressmempoolDesc = "getaddressmempool {\"addresses\": [\"address\",...]}\n" +
+		"\nReturns all mempool deltas for addresses (requires addressindex to be enabled).\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"addresses\"    (array, required) The legacy or cash addresses\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"address\"     (string) The address\n" +
+		"    \"txid\"        (string) The related txid\n" +
+		"    \"index\"       (numeric) The related input or output index\n" +
+		"    \"satoshis\"    (numeric) The difference of satoshis\n" +
+		"    \"timestamp\"   (numeric) The time the transaction entered the mempool (seconds)\n" +
+		"    \"prevtxid\"    (string) The previous txid (if spending)\n" +
+		"    \"prevout\"     (numeric) The previous transaction output index (if spending)\n" +
+		"  }\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getaddressmempool", "'{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}'") +
+		HelpExampleRPC("getaddressmempool", "{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}")
+
+	getaddresstxidsDesc = "getaddresstxids {\"addresses\": [\"address\",...], \"start\": n, \"end\": n}\n" +
+		"\nReturns the txids for addresses (requires addressindex to be enabled).\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"addresses\"    (array, required) The legacy or cash addresses\n" +
+		"  \"start\"        (numeric, optional) The start block height\n" +
+		"  \"end\"          (numeric, optional) The end block height\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  \"transactionid\"  (string) The transaction id\n" +
+		"  ,...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getaddresstxids", "'{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}'") +
+		HelpExampleRPC("getaddresstxids", "{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}")
+
+	getaddressutxosDesc = "getaddressutxos {\"addresses\": [\"address\",...], \"chainInfo\": bool}\n" +
+		"\nReturns all unspent outputs for addresses (requires addressindex to be enabled).\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"addresses\"    (array, required) The legacy or cash addresses\n" +
+		"  \"chainInfo\"    (boolean, optional) Include chain info with results\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"address\"      (string) The address\n" +
+		"    \"txid\"         (string) The output txid\n" +
+		"    \"outputIndex\"  (numeric) The output index\n" +
+		"    \"script\"       (string) The script hex encoded\n" +
+		"    \"satoshis\"     (numeric) The number of satoshis of the output\n" +
+		"    \"height\"       (numeric) The block height\n" +
+		"  }\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		HelpExampleCli("getaddressutxos", "'{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}'") +
+		HelpExampleRPC("getaddressutxos", "{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrGjn7DKU6Zbke\"]}")
+
+	notifyblocksDesc = "notifyblocks\n" +
+		"\nRequest notifications for whenever a block is connected or " +
+		"disconnected from the main (best) chain.\n" +
+		"Websocket only. The blockconnected, blockdisconnected, " +
+		"filteredblockconnected and filteredblockdisconnected " +
+		"notifications are sent to the client.\n"
+
+	stopnotifyblocksDesc = "stopnotifyblocks\n" +
+		"\nCancel registered notifications for whenever a block is " +
+		"connected or disconnected from the main (best) chain.\n" +
+		"Websocket only.\n"
+
+	notifynewtransactionsDesc = "notifynewtransactions ( verbose )\n" +
+		"\nSend either a txaccepted or a txacceptedverbose notification " +
+		"when a new transaction is accepted into the mempool.\n" +
+		"Websocket only.\n" +
+		"\nArguments:\n" +
+		"1. verbose    (boolean, optional, default=false) Specifies which " +
+		"type of notification to receive. If verbose is true, then the " +
+		"caller receives txacceptedverbose, otherwise the caller receives " +
+		"txaccepted\n"
+
+	stopnotifynewtransactionsDesc = "stopnotifynewtransactions\n" +
+		"\nStop sending either a txaccepted or a txacceptedverbose " +
+		"notification when a new transaction is accepted into the " +
+		"mempool.\n" +
+		"Websocket only.\n"
+
+	notifyreceivedDesc = "notifyreceived [\"address\",...]\n" +
+		"\nSend a recvtx notification when a transaction added to " +
+		"mempool or appears in a newly-attached block contains a txout " +
+		"pkScript sending to any of the passed addresses.\n" +
+		"Websocket only.\n" +
+		"\nArguments:\n" +
+		"1. \"addresses\"    (string, required) A json array of legacy " +
+		"or cashaddr addresses to watch\n"
+
+	stopnotifyreceivedDesc = "stopnotifyreceived [\"address\",...]\n" +
+		"\nCancel registered receive notifications for each passed " +
+		"address.\n" +
+		"Websocket only.\n" +
+		"\nArguments:\n" +
+		"1. \"addresses\"    (string, required) A json array of " +
+		"addresses to no longer watch\n"
+
+	loadtxfilterDesc = "loadtxfilter reload [\"address\",...] " +
+		"[{\"hash\":\"txid\",\"index\":n},...]\n" +
+		"\nLoad, add to, or reload a websocket client's transaction " +
+		"filter for mempool transactions, new blocks and rescanblocks.\n" +
+		"Websocket only. Matching mempool transactions are sent with " +
+		"relevanttxaccepted notifications, matching block transactions " +
+		"with filteredblockconnected notifications.\n" +
+		"\nArguments:\n" +
+		"1. reload       (boolean, required) Load a new filter instead " +
+		"of adding data to an existing one\n" +
+		"2. \"addresses\"  (string, required) A json array of addresses " +
+		"to add to the filter\n" +
+		"3. \"outpoints\"  (string, required) A json array of unspent " +
+		"outpoints to add to the filter\n"
+
+	rescanblocksDesc = "rescanblocks [\"blockhash\",...]\n" +
+		"\nRescan blocks for transactions matching the loaded " +
+		"transaction filter.\n" +
+		"Websocket only. The blocks must be passed in order, each one " +
+		"being the child of the previous one.\n" +
+		"\nArguments:\n" +
+		"1. \"blockhashes\"  (string, required) A json array of the " +
+		"hashes of the blocks to rescan\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"hash\" : \"hash\",        (string) The hash of the " +
+		"rescanned block\n" +
+		"    \"transactions\" : [...]  (array of string) The " +
+		"serialized, hex-encoded matching transactions\n" +
+		"  }\n" +
+		"  ,...\n" +
+		"]\n"
+
+	sessionDesc = "session\n" +
+		"\nReturn details regarding a websocket client's current " +
+		"connection session.\n" +
+		"Websocket only.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"sessionid\" : n    (numeric) The unique session ID for a " +
+		"client's websocket connection\n" +
+		"}\n"
 )
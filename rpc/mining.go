@@ -25,8 +25,11 @@ import (
 	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/service/mining"
 	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
 	"gopkg.in/fatih/set.v0"
 	"math/big"
+	"strconv"
+	"time"
 )
 
 var miningHandlers = map[string]commandHandler{
@@ -36,7 +39,8 @@ var miningHandlers = map[string]commandHandler{
 	"submitblock":       handleSubmitBlock,
 	"generatetoaddress": handleGenerateToAddress,
 	"generate":          handleGenerate,
-	//"estimatefee":       handleEstimateFee,
+	"estimatefee":       handleEstimateFee,
+	"estimatesmartfee":  handleEstimateSmartFee,
 }
 
 func GetNetworkHashPS(lookup int32, height int32) float64 {
@@ -107,6 +111,16 @@ func handleGetMiningInfo(s *Server, cmd interface{}, closeChan <-chan struct{})
 	return result, nil
 }
 
+const (
+	// longPollCheckTxDelay is how long a long polling getblocktemplate waits
+	// for a new block before also returning on new transactions.
+	longPollCheckTxDelay = time.Minute
+
+	// longPollRecheckTxDelay is how often the mempool is looked at after
+	// longPollCheckTxDelay, in case a change was not notified.
+	longPollRecheckTxDelay = 10 * time.Second
+)
+
 // global variable in package rpc
 var (
 	transactionsUpdatedLast uint64
@@ -163,14 +177,11 @@ func handleGetBlockTemplateRequest(s *Server, request *btcjson.TemplateRequest,
 		}
 	}
 
-	//if request != nil && request.LongPollID != "" {
-	// Wait to respond until either the best block changes, OR a minute has
-	// passed and there are more transactions
-	//var hashWatchedChain utils.Hash
-	//checktxtime := time.Now()
-	//transactionsUpdatedLastLP := 0
-	// todo complete
-	//}
+	if request != nil && request.LongPollID != "" {
+		if err := waitForLongPoll(s, request.LongPollID, closeChan); err != nil {
+			return nil, err
+		}
+	}
 
 	persist.CsMain.Lock() //lock chain tip for CreateNewBlock
 	defer persist.CsMain.Unlock()
@@ -211,6 +222,61 @@ func handleGetBlockTemplateRequest(s *Server, request *btcjson.TemplateRequest,
 	return res, err
 }
 
+// waitForLongPoll waits to respond to a long polling getblocktemplate request
+// until either the best block changes, or a minute has passed and there are
+// more transactions.
+func waitForLongPoll(s *Server, longPollID string, closeChan <-chan struct{}) error {
+	if len(longPollID) < 2*util.Hash256Size {
+		return btcjson.NewRPCError(btcjson.RPCInvalidParameter, "Invalid longpollid")
+	}
+	hashWatchedChain, err := util.GetHashFromStr(longPollID[:2*util.Hash256Size])
+	if err != nil {
+		return btcjson.NewRPCError(btcjson.RPCInvalidParameter, "Invalid longpollid")
+	}
+	transactionsUpdatedLastLP, err := strconv.ParseUint(longPollID[2*util.Hash256Size:], 10, 64)
+	if err != nil {
+		return btcjson.NewRPCError(btcjson.RPCInvalidParameter, "Invalid longpollid")
+	}
+
+	gChain := chain.GetInstance()
+	checkTxTime := time.NewTimer(longPollCheckTxDelay)
+	defer checkTxTime.Stop()
+	checkTx := false
+	for {
+		blockChanged := s.blockChange.Wait()
+		mempoolChanged := s.mempoolChange.Wait()
+		if !gChain.Tip().GetBlockHash().IsEqual(hashWatchedChain) {
+			break
+		}
+		if checkTx && mempool.GetInstance().TransactionsUpdated != transactionsUpdatedLastLP {
+			break
+		}
+
+		// The mempool is only looked at once the first delay has passed.
+		if !checkTx {
+			mempoolChanged = nil
+		}
+		select {
+		case <-blockChanged:
+		case <-mempoolChanged:
+		case <-checkTxTime.C:
+			checkTx = true
+			checkTxTime.Reset(longPollRecheckTxDelay)
+		case <-closeChan:
+			return &btcjson.RPCError{
+				Code:    btcjson.ErrRPCClientNotConnected,
+				Message: "Client disconnected",
+			}
+		case <-s.quit:
+			return &btcjson.RPCError{
+				Code:    btcjson.ErrRPCClientNotConnected,
+				Message: "Shutting down",
+			}
+		}
+	}
+	return nil
+}
+
 // blockTemplateResult returns the current block template associated with the
 // state as a btcjson.GetBlockTemplateResult that is ready to be encoded to JSON
 // and returned to the caller.
@@ -252,7 +318,15 @@ func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint
 
 		indexInTemplate := i - 1
 		entry.Fee = int64(blocktemplate.TxFees[indexInTemplate])
-		entry.SigOps = int64(blocktemplate.TxSigOpsCount[indexInTemplate])
+		// Only the counter of the limit the template was assembled for is
+		// reported.
+		if blocktemplate.SigCheckLimit > 0 {
+			sigChecks := int64(blocktemplate.TxSigChecks[indexInTemplate])
+			entry.SigChecks = &sigChecks
+		} else {
+			sigOps := int64(blocktemplate.TxSigOpsCount[indexInTemplate])
+			entry.SigOps = &sigOps
+		}
 
 		transactions = append(transactions, entry)
 	}
@@ -264,7 +338,6 @@ func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint
 
 	coinbaseValue := bt.Block.Txs[0].GetTxOut(0).GetValue()
 	target := pow.CompactToBig(bt.Block.Header.Bits)
-	maxSigOps, _ := consensus.GetMaxBlockSigOpsCount(consensus.DefaultMaxBlockSize)
 	return &btcjson.GetBlockTemplateResult{
 		Capabilities:  []string{"proposal"},
 		Version:       bt.Block.Header.Version,
@@ -278,11 +351,12 @@ func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint
 		Mutable:       mutable,
 		NonceRange:    "00000000ffffffff",
 		// FIXME: Allow for mining block greater than 1M.
-		SigOpLimit: int64(maxSigOps),
-		SizeLimit:  consensus.DefaultMaxBlockSize,
-		CurTime:    int64(bt.Block.Header.Time),
-		Bits:       fmt.Sprintf("%08x", bt.Block.Header.Bits),
-		Height:     int64(indexPrev.Height) + 1,
+		SigOpLimit:    int64(blocktemplate.SigOpLimit),
+		SigCheckLimit: int64(blocktemplate.SigCheckLimit),
+		SizeLimit:     consensus.DefaultMaxBlockSize,
+		CurTime:       int64(bt.Block.Header.Time),
+		Bits:          fmt.Sprintf("%08x", bt.Block.Header.Bits),
+		Height:        int64(indexPrev.Height) + 1,
 	}, nil
 }
 
@@ -554,26 +628,49 @@ func createBlockForCPUMining(ba *mining.BlockAssembler, scriptPK *script.Script,
 }
 
 // handleEstimateFee handles estimatefee commands.
-//func handleEstimateFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-//	c := cmd.(*btcjson.EstimateFeeCmd)
-//
-//	if s.cfg.FeeEstimator == nil {
-//		return nil, errors.New("Fee estimation disabled")
-//	}
-//
-//	if c.NumBlocks <= 0 {
-//		return -1.0, errors.New("Parameter NumBlocks must be positive")
-//	}
-//
-//	feeRate, err := s.cfg.FeeEstimator.EstimateFee(uint32(c.NumBlocks))
-//
-//	if err != nil {
-//		return -1.0, err
-//	}
-//
-//	// Convert to satoshis per kb.
-//	return float64(feeRate), nil
-//}
+func handleEstimateFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateFeeCmd)
+
+	numBlocks := c.NumBlocks
+	if numBlocks < 1 {
+		numBlocks = 1
+	}
+	if numBlocks > mempool.EstimateFeeDepth {
+		return -1.0, nil
+	}
+
+	feeRate, err := mempool.GetFeeEstimator().EstimateFee(uint32(numBlocks))
+	if err != nil || feeRate.SataoshisPerK == 0 {
+		return -1.0, nil
+	}
+
+	return amount.Amount(feeRate.SataoshisPerK).ToBTC(), nil
+}
+
+// handleEstimateSmartFee handles estimatesmartfee commands.
+func handleEstimateSmartFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateSmartFeeCmd)
+
+	if c.ConfTarget < 1 || c.ConfTarget > mempool.EstimateFeeDepth {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Invalid conf_target, must be between 1 - %d", mempool.EstimateFeeDepth),
+		}
+	}
+
+	feeRate, blocks, err := mempool.GetFeeEstimator().EstimateSmartFee(uint32(c.ConfTarget))
+	if err != nil || blocks == 0 {
+		return &btcjson.EstimateSmartFeeResult{
+			Errors: []string{"Insufficient data or no feerate found"},
+		}, nil
+	}
+
+	rate := amount.Amount(feeRate.SataoshisPerK).ToBTC()
+	return &btcjson.EstimateSmartFeeResult{
+		FeeRate: &rate,
+		Blocks:  int64(blocks),
+	}, nil
+}
 
 func registerMiningRPCCommands() {
 	for name, handler := range miningHandlers {
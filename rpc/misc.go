@@ -20,7 +20,6 @@ import (
 	"github.com/copernet/copernicus/service"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/base58"
-	"github.com/copernet/copernicus/util/cashaddr"
 )
 
 // API version constants
@@ -111,12 +110,17 @@ func handleValidateAddress(s *Server, cmd interface{}, closeChan <-chan struct{}
 		result.IsMine = lwallet.IsMine(scriptPubKey)
 		result.IsWatchOnly = false // TODO:NOT support watch-only yet
 		result.Account = lwallet.GetAccountName(keyHash)
-		result.IsScript = addrType == cashaddr.P2SH
+		result.IsScript = addrType.IsScriptHash()
 		if result.IsMine && !result.IsScript {
-			keyPair := lwallet.GetKeyPair(keyHash)
-			if keyPair != nil {
-				result.PubKey = keyPair.GetPublicKey().ToHexString()
-				result.IsCompressed = keyPair.GetPublicKey().Compressed
+			pubKey := lwallet.GetPubKey(keyHash)
+			if pubKey != nil {
+				result.PubKey = pubKey.ToHexString()
+				result.IsCompressed = pubKey.Compressed
+			}
+			if metadata := lwallet.GetKeyMetadata(keyHash); metadata != nil {
+				result.TimeStamp = uint32(metadata.CreateTime)
+				result.HDKeyPath = metadata.HDKeyPath
+				result.HDMasterKeyID = hex.EncodeToString(metadata.HDMasterKeyID)
 			}
 		}
 	}
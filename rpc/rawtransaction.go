@@ -16,6 +16,7 @@ import (
 	"github.com/copernet/copernicus/logic/lmempool"
 	"github.com/copernet/copernicus/logic/lmerkleblock"
 	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/logic/ltxindex"
 	"github.com/copernet/copernicus/logic/lwallet"
 	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/chain"
@@ -23,6 +24,7 @@ import (
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/token"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/txin"
 	"github.com/copernet/copernicus/model/txout"
@@ -63,8 +65,12 @@ func handleGetRawTransaction(s *Server, cmd interface{}, closeChan <-chan struct
 
 	tx, hashBlock, ok := GetTransaction(txHash, true)
 	if !ok {
+		errStr := "No such mempool or blockchain transaction"
+		if !ltxindex.IsEnabled() {
+			errStr = "No such mempool transaction. Use -txindex to enable blockchain transaction queries"
+		}
 		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
-			"No such mempool or blockchain transaction. Use gettransaction for wallet transactions.")
+			errStr+". Use gettransaction for wallet transactions.")
 	}
 
 	buf := bytes.NewBuffer(nil)
@@ -149,6 +155,7 @@ func getVoutList(tx *tx.Tx) []btcjson.Vout {
 			Value:        valueFromAmount(int64(out.GetValue())),
 			N:            uint32(i),
 			ScriptPubKey: *scriptPubKeyJSON,
+			TokenData:    TokenDataToJSON(out.GetTokenData()),
 		}
 	}
 	return voutList
@@ -226,6 +233,15 @@ func ScriptPubKeyToJSON(script *script.Script, includeHex bool) *btcjson.ScriptP
 	t, addresses, required, err := script.ExtractDestinations()
 	result.Type = GetTxnOutputType(t)
 
+	// P2SH32 has no legacy address, only a cashaddr one
+	if script.IsPayToScriptHash32() {
+		if addr, err := cashaddr.ExtractPkScriptAddrs(script.GetData(), chain.GetInstance().GetParams()); err == nil {
+			result.ReqSigs = 1
+			result.Addresses = []string{addr.String()}
+		}
+		return result
+	}
+
 	if err != nil {
 		return result
 	}
@@ -239,6 +255,26 @@ func ScriptPubKeyToJSON(script *script.Script, includeHex bool) *btcjson.ScriptP
 	return result
 }
 
+// TokenDataToJSON returns the JSON form of the tokens of an output, nil if it
+// carries none.
+func TokenDataToJSON(data *token.OutputData) *btcjson.TokenDataResult {
+	if data == nil {
+		return nil
+	}
+
+	result := &btcjson.TokenDataResult{
+		Category: data.Category.String(),
+		Amount:   strconv.FormatInt(data.Amount, 10),
+	}
+	if data.HasNFT {
+		result.NFT = &btcjson.NFTResult{
+			Capability: data.Capability.String(),
+			Commitment: hex.EncodeToString(data.Commitment),
+		}
+	}
+	return result
+}
+
 func GetTxnOutputType(sType int) string {
 	switch sType {
 	case script.ScriptNonStandard:
@@ -264,10 +300,15 @@ func GetTransaction(hash *util.Hash, allowSlow bool) (*tx.Tx, *util.Hash, bool)
 		return entry.Tx, nil, true
 	}
 
-	/* TODO: NOT support txindex yet
-	if chain.GTxIndex {
-		chain.GBlockTree.ReadTxIndex(hash)
-	}*/
+	if ltxindex.IsEnabled() {
+		txn, hashBlock, err := ltxindex.FindTx(hash)
+		if err != nil {
+			log.Error("GetTransaction: look up %s in txindex failed: %v", hash, err)
+		}
+		if txn != nil {
+			return txn, hashBlock, true
+		}
+	}
 
 	if !allowSlow {
 		return nil, nil, false
@@ -462,7 +503,7 @@ func getStandardScriptPubKey(address string, nullData []byte) (*script.Script, *
 		return nil, rpcErr
 	}
 
-	if addrType == cashaddr.P2PKH {
+	if (addrType == cashaddr.P2PKH || addrType == cashaddr.TokenP2PKH) && len(keyHash) == 20 {
 		scriptPubKey, err := generateScript(opcodes.OP_DUP, opcodes.OP_HASH160, keyHash,
 			opcodes.OP_EQUALVERIFY, opcodes.OP_CHECKSIG)
 		if err != nil {
@@ -471,8 +512,13 @@ func getStandardScriptPubKey(address string, nullData []byte) (*script.Script, *
 		}
 		return scriptPubKey, nil
 
-	} else if addrType == cashaddr.P2SH {
-		scriptPubKey, err := generateScript(opcodes.OP_HASH160, keyHash, opcodes.OP_EQUAL)
+	} else if addrType.IsScriptHash() {
+		// P2SH32 addresses commit to the double sha256 of the redeem script
+		hashOp := opcodes.OP_HASH160
+		if len(keyHash) == 32 {
+			hashOp = opcodes.OP_HASH256
+		}
+		scriptPubKey, err := generateScript(hashOp, keyHash, opcodes.OP_EQUAL)
 		if err != nil {
 			log.Error("generateScript error:%s", err.Error())
 			return nil, btcjson.ErrRPCInternal
@@ -790,6 +836,9 @@ func getKeys(privateKeys *[]string, coinsMap *utxo.CoinsMap,
 			keyStore.AddKey(privateKey)
 		}
 	} else if lwallet.IsWalletEnable() {
+		if lwallet.IsWalletLocked() {
+			return nil, walletUnlockNeededRPCError
+		}
 		pubKeyHashList := make([][]byte, 0)
 		for _, coin := range coinsMap.GetMap() {
 			pubKeyHash := getPubKeyHash(coin.GetScriptPubKey())
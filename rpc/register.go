@@ -31,6 +31,9 @@ func registerAllRPCCommands() {
 	if conf.Cfg.Wallet.Enable {
 		registerWalletRPCCommands()
 	}
+	if conf.Cfg.Chain.AddressIndex {
+		registerAddressIndexRPCCommands()
+	}
 }
 
 func init() {
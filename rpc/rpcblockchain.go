@@ -4,7 +4,8 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
-	"math"
+	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -12,8 +13,11 @@ import (
 
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lblockfilter"
 	"github.com/copernet/copernicus/logic/lchain"
 	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/logic/lreindex"
+	"github.com/copernet/copernicus/logic/lspentindex"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/blockindex"
@@ -46,15 +50,24 @@ var blockchainHandlers = map[string]commandHandler{
 	"getmempoolentry":       handleGetMempoolEntry,       // complete
 	"getmempoolinfo":        handleGetMempoolInfo,        // complete
 	"getrawmempool":         handleGetRawMempool,         // complete
+	"savemempool":           handleSaveMempool,           // complete
 	"gettxout":              handleGetTxOut,              // complete
+	"getspentinfo":          handleGetSpentInfo,
+	"getblockfilter":        handleGetBlockFilter,
 	"gettxoutsetinfo":       handleGetTxoutSetInfo,
+	"dumptxoutset":          handleDumpTxOutSet,
+	"loadtxoutset":          handleLoadTxOutSet,
+	"importblocks":          handleImportBlocks,
 	"pruneblockchain":       handlePruneBlockChain, //complete
 	"verifychain":           handleVerifyChain,     //complete
 	"preciousblock":         handlePreciousblock,   //complete
+	"finalizeblock":         handleFinalizeBlock,
 
 	/*not shown in help*/
 	"invalidateblock":    handleInvalidateBlock, //complete
 	"reconsiderblock":    handleReconsiderBlock, //complete
+	"parkblock":          handleParkBlock,
+	"unparkblock":        handleUnparkBlock,
 	"waitfornewblock":    handleWaitForNewBlock,
 	"waitforblock":       handleWaitForBlock,
 	"waitforblockheight": handleWaitForBlockHeight,
@@ -357,6 +370,54 @@ func handleGetBlockHeader(s *Server, cmd interface{}, closeChan <-chan struct{})
 	return blockHeaderReply, nil
 }
 
+func handleGetBlockFilter(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockFilterCmd)
+
+	filterType := "basic"
+	if c.FilterType != nil {
+		filterType = *c.FilterType
+	}
+	if filterType != "basic" {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Unknown filtertype")
+	}
+	if !lblockfilter.IsEnabled() {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Index is not enabled for filtertype " + filterType,
+		}
+	}
+
+	hash, err := util.GetHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
+	persist.CsMain.Lock()
+	blockIndex := chain.GetInstance().FindBlockIndex(*hash)
+	persist.CsMain.Unlock()
+	if blockIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+
+	entry, err := lblockfilter.GetFilter(hash)
+	if err != nil {
+		log.Error("read block filter index failed: %v", err)
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCDatabase, "Unable to read the block filter index")
+	}
+	if entry == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Filter not found. Block filters are still in the process of being indexed.",
+		}
+	}
+	return &btcjson.GetBlockFilterResult{
+		Filter: hex.EncodeToString(entry.Filter),
+		Header: entry.Header.String(),
+	}, nil
+}
+
 func handleGetChainTips(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Idea:  the set of chain tips is chainActive.tip, plus orphan blocks which
 	// do not have another orphan building off of them.
@@ -391,6 +452,9 @@ func handleGetChainTips(s *Server, cmd interface{}, closeChan <-chan struct{}) (
 		} else if bindex.IsInvalid() {
 			// This block or one of its ancestors is invalid.
 			status = "invalid"
+		} else if gchain.IsOnParkedChain(bindex) {
+			// This block or one of its ancestors is parked.
+			status = "parked"
 		} else if bindex.ChainTxCount == 0 {
 			// This block cannot be connected because full block data for it or
 			// one of its parents is missing.
@@ -653,6 +717,17 @@ func handleGetMempoolInfo(s *Server, cmd interface{}, closeChan <-chan struct{})
 	return ret, nil
 }
 
+func handleSaveMempool(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if err := lmempool.DumpMempool(); err != nil {
+		log.Error("savemempool: %v", err)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to dump mempool to disk",
+		}
+	}
+	return nil, nil
+}
+
 func valueFromAmount(sizeLimit int64) float64 {
 	var nAbs int64
 	var strFormat string
@@ -744,12 +819,45 @@ func handleGetTxOut(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 		Confirmations: confirmations,
 		Value:         strconv.FormatFloat(amountValue, 'f', -1, 64),
 		ScriptPubKey:  *scriptPubKeyJSON,
+		TokenData:     TokenDataToJSON(coin.GetTokenData()),
 		Coinbase:      coin.IsCoinBase(),
 	}
 
 	return txOutReply, nil
 }
 
+func handleGetSpentInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetSpentInfoCmd)
+
+	if !lspentindex.IsEnabled() {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Spent index not enabled. Use -spentindex to enable spent output queries",
+		}
+	}
+	hash, err := util.GetHashFromStr(c.Request.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Request.TxID)
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	info, err := lspentindex.GetSpentInfo(outpoint.NewOutPoint(*hash, c.Request.Index))
+	if err != nil {
+		log.Error("read spent index failed: %v", err)
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCDatabase, "Unable to read the spent index")
+	}
+	if info == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Unable to get spent info")
+	}
+	return &btcjson.GetSpentInfoResult{
+		TxID:   info.TxID.String(),
+		Index:  info.InputIndex,
+		Height: info.Height,
+	}, nil
+}
+
 func handleGetTxoutSetInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Write the chain state to disk, if necessary.
 	if err := disk.FlushStateToDisk(disk.FlushStateAlways, 0); err != nil {
@@ -775,6 +883,100 @@ func handleGetTxoutSetInfo(s *Server, cmd interface{}, closeChan <-chan struct{}
 	return reply, nil
 }
 
+// dataDirPath resolves the path of a file given to an RPC call, relative paths
+// being relative to the data directory.
+func dataDirPath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(conf.DataDir, path)
+	}
+	return filepath.Abs(path)
+}
+
+// handleDumpTxOutSet implements the dumptxoutset command.
+func handleDumpTxOutSet(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DumpTxOutSetCmd)
+	path, err := dataDirPath(c.Path)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, err.Error())
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	snapshot, err := lchain.DumpUTXOSnapshot(path)
+	if err != nil {
+		log.Error("dumptxoutset: %v", err)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: err.Error(),
+		}
+	}
+	return &btcjson.DumpTxOutSetResult{
+		CoinsWritten: snapshot.CoinsCount,
+		BaseHash:     snapshot.BaseBlockHash.String(),
+		BaseHeight:   snapshot.BaseHeight,
+		Path:         path,
+		TxOutSetHash: snapshot.HashSerialized.String(),
+		NChainTx:     snapshot.ChainTxCount,
+	}, nil
+}
+
+// handleLoadTxOutSet implements the loadtxoutset command.
+func handleLoadTxOutSet(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.LoadTxOutSetCmd)
+	path, err := dataDirPath(c.Path)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, err.Error())
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	snapshot, err := lchain.LoadUTXOSnapshot(path)
+	if err != nil {
+		log.Error("loadtxoutset: %v", err)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: err.Error(),
+		}
+	}
+	return &btcjson.LoadTxOutSetResult{
+		CoinsLoaded: snapshot.CoinsCount,
+		TipHash:     snapshot.BaseBlockHash.String(),
+		BaseHeight:  snapshot.BaseHeight,
+		Path:        path,
+	}, nil
+}
+
+// handleImportBlocks implements the importblocks command. The blocks are
+// imported in the background, the progress is logged.
+func handleImportBlocks(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ImportBlocksCmd)
+	if len(c.Files) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "No block files given")
+	}
+	files := make([]string, 0, len(c.Files))
+	for _, file := range c.Files {
+		path, err := dataDirPath(file)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, err.Error())
+		}
+		if _, err = os.Stat(path); err != nil {
+			return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, err.Error())
+		}
+		files = append(files, path)
+	}
+	if lreindex.IsImporting() {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: lreindex.ErrImporting.Error(),
+		}
+	}
+
+	go lreindex.ImportBlocks(files)
+	return nil, nil
+}
+
 func getPrunMode() (bool, error) {
 	/*	pruneArg := util.GetArg("-prune", 0)
 		if pruneArg < 0 {
@@ -837,18 +1039,25 @@ func handlePruneBlockChain(s *Server, cmd interface{}, closeChan <-chan struct{}
 
 // handleVerifyChain implements the verifychain command.
 func handleVerifyChain(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	/*	c := cmd.(*btcjson.VerifyChainCmd)
+	c := cmd.(*btcjson.VerifyChainCmd)
 
-		var checkLevel, checkDepth int32
-		if c.CheckLevel != nil {
-			checkLevel = *c.CheckLevel
-		}
-		if c.CheckDepth != nil {
-			checkDepth = *c.CheckDepth
-		}
+	checkLevel := int32(lchain.DefaultCheckLevel)
+	if c.CheckLevel != nil {
+		checkLevel = *c.CheckLevel
+	}
+	checkDepth := int32(lchain.DefaultCheckBlocks)
+	if c.CheckDepth != nil {
+		checkDepth = *c.CheckDepth
+	}
 
-		return VerifyDB(consensus.ActiveNetParams, utxo.GetUtxoCacheInstance(), checkLevel, checkDepth), nil*/ // todo open
-	return nil, nil
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	if err := lchain.VerifyDB(checkLevel, checkDepth); err != nil {
+		log.Error("verifychain: %v", err)
+		return false, nil
+	}
+	return true, nil
 }
 
 func handlePreciousblock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -891,17 +1100,11 @@ func handleInvalidateBlock(s *Server, cmd interface{}, closeChan <-chan struct{}
 		return nil, btcjson.NewRPCError(btcjson.RPCInvalidAddressOrKey, "Block not found")
 	}
 
-	for gchain.FindHashInActive(*bkHash) != nil {
-		lchain.InvalidBlockParentFound(gchain.Tip())
-
-		if err = lchain.DisconnectTip(false); err != nil {
-			log.Error("InvalidateBlock failed during DisconnectTip, " + chainStatus(bkHash))
-			return nil, btcjson.NewRPCError(btcjson.RPCDatabaseError, "disconnect failed")
-		}
+	if err = lchain.InvalidateBlock(bi); err != nil {
+		log.Error("InvalidateBlock failed during DisconnectTip, " + chainStatus(bkHash))
+		return nil, btcjson.NewRPCError(btcjson.RPCDatabaseError, "disconnect failed")
 	}
 
-	lchain.InvalidBlockFound(bi)
-
 	if err = lchain.ActivateBestChain(nil); err != nil {
 		log.Error("InvalidateBlock failed during ActivateBestChain, " + chainStatus(bkHash))
 		return nil, btcjson.NewRPCError(btcjson.RPCDatabaseError, "failed with err:"+err.Error())
@@ -941,92 +1144,154 @@ func handleReconsiderBlock(s *Server, cmd interface{}, closeChan <-chan struct{}
 	return nil, nil
 }
 
-func chainStatus(targetHash *util.Hash) string {
+func handleFinalizeBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c, ok := cmd.(*btcjson.FinalizeBlockCmd)
+	bkHash, err := util.GetHashFromStr(c.BlockHash)
+	if !ok || err != nil {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "malformed request")
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
 	gchain := chain.GetInstance()
-	return fmt.Sprintf("target hash: %s, current tip: %+v", targetHash, gchain.Tip())
-}
+	bi := gchain.FindBlockIndex(*bkHash)
+	if bi == nil {
+		log.Error("FinalizeBlock failed, target block not found. " + chainStatus(bkHash))
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidAddressOrKey, "Block not found")
+	}
 
-func handleWaitForNewBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	log.Debug("FinalizeBlock start: " + chainStatus(bkHash))
+	if err = lchain.FinalizeBlock(bi); err != nil {
+		log.Error("FinalizeBlock failed, " + chainStatus(bkHash))
+		return nil, btcjson.NewRPCError(btcjson.RPCDatabaseError, err.Error())
+	}
+
+	if err = lchain.ActivateBestChain(nil); err != nil {
+		log.Error("FinalizeBlock failed during ActivateBestChain, " + chainStatus(bkHash))
+		return nil, btcjson.NewRPCError(btcjson.RPCDatabaseError, "failed with err:"+err.Error())
+	}
+	log.Debug("FinalizeBlock end: " + chainStatus(bkHash))
 	return nil, nil
 }
 
-func handleWaitForBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	c, ok := cmd.(*btcjson.WaitForBlockCmd)
-	bkHash, err := util.GetHashFromStr(*c.BlockHash)
+func handleParkBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c, ok := cmd.(*btcjson.ParkBlockCmd)
+	bkHash, err := util.GetHashFromStr(c.BlockHash)
 	if !ok || err != nil {
 		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "malformed request")
 	}
 
-	timeout := *c.Timeout
-	if timeout == 0 {
-		//0 indicates no timeout.
-		timeout = math.MaxInt32
-	}
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
 
-	timeEnd := time.Now().Add(time.Duration(timeout) * time.Second)
 	gchain := chain.GetInstance()
-	ret := &btcjson.WaitForBlockHeightResult{}
-
-	for {
-		if time.Now().After(timeEnd) {
-			ret.Hash = gchain.Tip().GetBlockHash().String()
-			ret.Height = gchain.TipHeight()
-			return ret, nil
-		}
+	bi := gchain.FindBlockIndex(*bkHash)
+	if bi == nil {
+		log.Error("ParkBlock failed, target block not found. " + chainStatus(bkHash))
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidAddressOrKey, "Block not found")
+	}
 
-		chainTip := gchain.Tip()
-		tipHash := chainTip.GetBlockHash()
-		if tipHash == bkHash {
-			ret.Hash = *c.BlockHash
-			ret.Height = chainTip.Height
-			return ret, nil
-		}
+	log.Debug("ParkBlock start: " + chainStatus(bkHash))
+	if err = lchain.ParkBlock(bi); err != nil {
+		log.Error("ParkBlock failed during DisconnectTip, " + chainStatus(bkHash))
+		return nil, btcjson.NewRPCError(btcjson.RPCDatabaseError, "disconnect failed")
+	}
 
-		time.Sleep(time.Nanosecond)
+	if err = lchain.ActivateBestChain(nil); err != nil {
+		log.Error("ParkBlock failed during ActivateBestChain, " + chainStatus(bkHash))
+		return nil, btcjson.NewRPCError(btcjson.RPCDatabaseError, "failed with err:"+err.Error())
 	}
+	lmempool.RemoveForReorg(chain.GetInstance().Tip().Height+1, int(tx.StandardLockTimeVerifyFlags))
+	log.Debug("ParkBlock end: " + chainStatus(bkHash))
+	return nil, nil
 }
 
-func handleWaitForBlockHeight(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	//TODO notify tipchange
-	c := cmd.(*btcjson.WaitForBlockHeightCmd)
-	height := c.Height
-	timeout := *c.Timeout
+func handleUnparkBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c, ok := cmd.(*btcjson.UnparkBlockCmd)
+	bkHash, err := util.GetHashFromStr(c.BlockHash)
+	if !ok || err != nil {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "malformed request")
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+
+	gchain := chain.GetInstance()
+	targetBI := gchain.FindBlockIndex(*bkHash)
+	if targetBI == nil {
+		log.Error("UnparkBlock failed, target block not found. " + chainStatus(bkHash))
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidAddressOrKey, "Block not found")
+	}
+
+	log.Debug("UnparkBlock start: " + chainStatus(bkHash))
+	lchain.UnparkBlock(targetBI)
 
-	if timeout == 0 {
-		//0 indicates no timeout.
-		timeout = math.MaxInt32
+	if err = lchain.ActivateBestChain(nil); err != nil {
+		log.Error("UnparkBlock failed, " + chainStatus(bkHash))
+		return nil, btcjson.NewRPCError(btcjson.RPCDatabaseError, "failed with err:"+err.Error())
 	}
 
-	timeEnd := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	log.Debug("UnparkBlock end: " + chainStatus(bkHash))
+	return nil, nil
+}
+
+func chainStatus(targetHash *util.Hash) string {
 	gchain := chain.GetInstance()
-	ret := &btcjson.WaitForBlockHeightResult{}
+	return fmt.Sprintf("target hash: %s, current tip: %+v", targetHash, gchain.Tip())
+}
 
-	if height <= gchain.TipHeight() {
-		ret = &btcjson.WaitForBlockHeightResult{
-			Hash:   gchain.Tip().GetBlockHash().String(),
-			Height: gchain.TipHeight(),
-		}
-		return ret, nil
+func handleWaitForNewBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.WaitForNewBlockCmd)
+
+	tipHash := *chain.GetInstance().Tip().GetBlockHash()
+	tip := s.waitForTip(waitTimeout(c.Timeout), closeChan, func(tip *blockindex.BlockIndex) bool {
+		return !tip.GetBlockHash().IsEqual(&tipHash)
+	})
+	return &btcjson.WaitForBlockHeightResult{
+		Hash:   tip.GetBlockHash().String(),
+		Height: tip.Height,
+	}, nil
+}
+
+func handleWaitForBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.WaitForBlockCmd)
+	if c.BlockHash == nil {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "malformed request")
+	}
+	bkHash, err := util.GetHashFromStr(*c.BlockHash)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "malformed request")
 	}
 
-	for {
-		if height <= gchain.TipHeight() {
-			ret.Hash = gchain.GetIndex(height).GetBlockHash().String()
-			ret.Height = height
-			return ret, nil
-		}
-		if time.Now().After(timeEnd) {
-			if height <= gchain.TipHeight() {
-				ret.Hash = gchain.GetIndex(height).GetBlockHash().String()
-				ret.Height = height
-				return ret, nil
-			}
-			ret.Hash = gchain.Tip().GetBlockHash().String()
-			ret.Height = gchain.TipHeight()
-			return ret, nil
-		}
-		time.Sleep(time.Second)
+	tip := s.waitForTip(waitTimeout(c.Timeout), closeChan, func(tip *blockindex.BlockIndex) bool {
+		return tip.GetBlockHash().IsEqual(bkHash)
+	})
+	return &btcjson.WaitForBlockHeightResult{
+		Hash:   tip.GetBlockHash().String(),
+		Height: tip.Height,
+	}, nil
+}
+
+func handleWaitForBlockHeight(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.WaitForBlockHeightCmd)
+
+	tip := s.waitForTip(waitTimeout(c.Timeout), closeChan, func(tip *blockindex.BlockIndex) bool {
+		return tip.Height >= c.Height
+	})
+	return &btcjson.WaitForBlockHeightResult{
+		Hash:   tip.GetBlockHash().String(),
+		Height: tip.Height,
+	}, nil
+}
+
+// waitTimeout converts the millisecond timeout of the wait commands, zero
+// meaning no timeout.
+func waitTimeout(timeout *int) time.Duration {
+	if timeout == nil || *timeout <= 0 {
+		return 0
 	}
+	return time.Duration(*timeout) * time.Millisecond
 }
 
 func registerBlockchainRPCCommands() {
@@ -22,9 +22,12 @@ import (
 
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/net/server"
 	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/util"
+	"github.com/gorilla/websocket"
 )
 
 const (
@@ -65,6 +68,9 @@ type Server struct {
 	statusLock             sync.RWMutex
 	wg                     sync.WaitGroup
 	helpCacher             *helpCacher
+	ntfnMgr                *wsNotificationManager
+	blockChange            *changeNotifier
+	mempoolChange          *changeNotifier
 	requestProcessShutdown chan struct{}
 	quit                   chan int
 	timeSource             *util.MedianTime
@@ -133,6 +139,8 @@ func (s *Server) Stop() error {
 			return err
 		}
 	}
+	s.ntfnMgr.Shutdown()
+	s.ntfnMgr.WaitForShutdown()
 	close(s.quit)
 	s.wg.Wait()
 	log.Info("RPC server shutdown complete")
@@ -448,6 +456,33 @@ func (s *Server) Start() {
 		s.jsonRPCRead(w, r, isAdmin)
 	})
 
+	// Websocket endpoint.
+	rpcServeMux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		_, _, err := s.checkAuth(r, true)
+		if err != nil {
+			jsonAuthFail(w)
+			return
+		}
+
+		// Attempt to upgrade the connection to a websocket connection
+		// using the default size for read/write buffers.  The upgrader
+		// replies to the client itself when the handshake fails.
+		ws, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			if _, ok := err.(websocket.HandshakeError); !ok {
+				log.Error("Unexpected websocket error: %v", err)
+			}
+			return
+		}
+		s.WebsocketHandler(ws, r.RemoteAddr)
+	})
+
+	// Start the notification manager before subscribing, so the chain and
+	// the mempool never wait on it.
+	s.ntfnMgr.Start()
+	chain.GetInstance().Subscribe(s.handleBlockchainNotification)
+	lmempool.SubscribeTxAccepted(s.handleTxAccepted)
+
 	for _, listener := range s.cfg.Listeners {
 		s.wg.Add(1)
 		go func(listener net.Listener) {
@@ -615,6 +650,8 @@ func NewServer(config *ServerConfig, ts *util.MedianTime) (*Server, error) {
 		//gbtWorkState:           newGbtWorkState(config.TimeSource), // todo open
 		helpCacher:             newHelpCacher(),
 		requestProcessShutdown: make(chan struct{}, 1),
+		blockChange:            newChangeNotifier(),
+		mempoolChange:          newChangeNotifier(),
 		quit:                   make(chan int),
 		timeSource:             ts,
 	}
@@ -628,7 +665,7 @@ func NewServer(config *ServerConfig, ts *util.MedianTime) (*Server, error) {
 		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
 		rpc.limitauthsha = sha256.Sum256([]byte(auth))
 	}
-	//rpc.cfg.Chain.Subscribe(rpc.handleBlockchainNotification)  // todo open
+	rpc.ntfnMgr = newWsNotificationManager(&rpc)
 
 	return &rpc, nil
 }
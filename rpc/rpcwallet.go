@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/ltxindex"
 	"github.com/copernet/copernicus/logic/lwallet"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/script"
@@ -17,6 +18,7 @@ import (
 	"github.com/pkg/errors"
 	"gopkg.in/fatih/set.v0"
 	"strconv"
+	"time"
 )
 
 var walletHandlers = map[string]commandHandler{
@@ -29,13 +31,40 @@ var walletHandlers = map[string]commandHandler{
 	"sendmany":           handleSendMany,
 	"addmultisigaddress": handleAddMultiSigAddress,
 	"fundrawtransaction": handleFundRawTransaction,
+
+	"encryptwallet":          handleEncryptWallet,
+	"walletpassphrase":       handleWalletPassphrase,
+	"walletpassphrasechange": handleWalletPassphraseChange,
+	"walletlock":             handleWalletLock,
+
+	"getrawchangeaddress": handleGetRawChangeAddress,
+	"keypoolrefill":       handleKeyPoolRefill,
+	"getaddressinfo":      handleGetAddressInfo,
 }
 
+// maxWalletUnlockTimeout caps the timeout of walletpassphrase, in seconds.
+const maxWalletUnlockTimeout = 100000000
+
 var walletDisableRPCError = &btcjson.RPCError{
 	Code:    btcjson.ErrRPCMethodNotFound.Code,
 	Message: "Method not found (wallet method is disabled because no wallet is loaded)",
 }
 
+var walletUnlockNeededRPCError = &btcjson.RPCError{
+	Code:    btcjson.RPCWalletUnlockNeeded,
+	Message: "Error: Please enter the wallet passphrase with walletpassphrase first.",
+}
+
+var walletPassphraseIncorrectRPCError = &btcjson.RPCError{
+	Code:    btcjson.RPCWalletPassphraseIncorrect,
+	Message: "Error: The wallet passphrase entered was incorrect.",
+}
+
+var walletKeypoolRanOutRPCError = &btcjson.RPCError{
+	Code:    btcjson.RPCWalletKeypoolRanOut,
+	Message: "Error: Keypool ran out, please call keypoolrefill first",
+}
+
 func handleGetNewAddress(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	if !lwallet.IsWalletEnable() {
 		return nil, walletDisableRPCError
@@ -45,6 +74,9 @@ func handleGetNewAddress(s *Server, cmd interface{}, closeChan <-chan struct{})
 
 	account := *c.Account
 	address, err := lwallet.GetNewAddress(account, false)
+	if err == wallet.ErrKeyPoolRanOut {
+		return nil, walletKeypoolRanOutRPCError
+	}
 	if err != nil {
 		log.Info("GetNewAddress error:%s", err.Error())
 		return nil, btcjson.ErrRPCInternal
@@ -53,6 +85,78 @@ func handleGetNewAddress(s *Server, cmd interface{}, closeChan <-chan struct{})
 	return address, nil
 }
 
+func handleGetRawChangeAddress(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !lwallet.IsWalletEnable() {
+		return nil, walletDisableRPCError
+	}
+
+	address, err := lwallet.GetRawChangeAddress()
+	if err == wallet.ErrKeyPoolRanOut {
+		return nil, walletKeypoolRanOutRPCError
+	}
+	if err != nil {
+		log.Info("GetRawChangeAddress error:%s", err.Error())
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	return address, nil
+}
+
+func handleKeyPoolRefill(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !lwallet.IsWalletEnable() {
+		return nil, walletDisableRPCError
+	}
+
+	c := cmd.(*btcjson.KeyPoolRefillCmd)
+
+	if lwallet.IsWalletLocked() {
+		return nil, walletUnlockNeededRPCError
+	}
+
+	newSize := int(*c.NewSize)
+	if err := lwallet.RefillKeyPool(newSize); err != nil {
+		log.Info("RefillKeyPool error:%s", err.Error())
+		return nil, btcjson.NewRPCError(btcjson.RPCWalletError, "Error refreshing keypool.")
+	}
+	return nil, nil
+}
+
+func handleGetAddressInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !lwallet.IsWalletEnable() {
+		return nil, walletDisableRPCError
+	}
+
+	c := cmd.(*btcjson.GetAddressInfoCmd)
+
+	scriptPubKey, rpcErr := getStandardScriptPubKey(c.Address, nil)
+	if rpcErr != nil {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidAddressOrKey, "Invalid address")
+	}
+	addrType, keyHash, _ := decodeAddress(c.Address)
+
+	result := &btcjson.GetAddressInfoResult{
+		Address:      c.Address,
+		ScriptPubKey: hex.EncodeToString(scriptPubKey.GetData()),
+		IsMine:       lwallet.IsMine(scriptPubKey),
+		IsWatchOnly:  false, // TODO:NOT support watch-only yet
+		IsScript:     addrType.IsScriptHash(),
+		Account:      lwallet.GetAccountName(keyHash),
+	}
+	if result.IsMine && !result.IsScript {
+		if pubKey := lwallet.GetPubKey(keyHash); pubKey != nil {
+			result.PubKey = pubKey.ToHexString()
+			result.IsCompressed = pubKey.Compressed
+		}
+		if metadata := lwallet.GetKeyMetadata(keyHash); metadata != nil {
+			result.TimeStamp = metadata.CreateTime
+			result.HDKeyPath = metadata.HDKeyPath
+			result.HDMasterKeyID = hex.EncodeToString(metadata.HDMasterKeyID)
+		}
+	}
+
+	return result, nil
+}
+
 func handleListUnspent(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	if !lwallet.IsWalletEnable() {
 		return nil, walletDisableRPCError
@@ -177,6 +281,10 @@ func handleSendToAddress(s *Server, cmd interface{}, closeChan <-chan struct{})
 
 	subtractFeeFromAmount := *c.SubtractFeeFromAmount
 
+	if lwallet.IsWalletLocked() {
+		return nil, walletUnlockNeededRPCError
+	}
+
 	txn, rpcErr := sendMoney(scriptPubKey, value, subtractFeeFromAmount, extInfo)
 	if rpcErr != nil {
 		return false, rpcErr
@@ -227,6 +335,13 @@ func handleGetTransaction(s *Server, cmd interface{}, closeChan <-chan struct{})
 	ret.Confirmations = wtx.GetDepthInMainChain()
 	if ret.Confirmations > 0 {
 		index := chain.GetInstance().GetIndex(wtx.GetBlokHeight())
+		if ltxindex.IsEnabled() {
+			if _, hashBlock, err := ltxindex.FindTx(txHash); err == nil && hashBlock != nil {
+				if bindex := chain.GetInstance().FindBlockIndex(*hashBlock); bindex != nil {
+					index = bindex
+				}
+			}
+		}
 		ret.BlockHash = index.GetBlockHash().String()
 		ret.BlockTime = index.GetBlockTime()
 	}
@@ -292,6 +407,9 @@ func handleFundRawTransaction(s *Server, cmd interface{}, closeChan <-chan struc
 		}
 	}
 	pos, feeOut, err := lwallet.FundTransaction(&txn, setSubtractFeeFromOutputs, c.Options)
+	if err == wallet.ErrWalletLocked {
+		return nil, walletUnlockNeededRPCError
+	}
 	if err != nil {
 		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, err.Error())
 	}
@@ -332,6 +450,9 @@ func sendMoney(scriptPubKey *script.Script, value amount.Amount, subtractFeeFrom
 	}
 	changePosRet := -1
 	txn, feeRequired, err := lwallet.CreateTransaction(recipients, &changePosRet, true)
+	if err == wallet.ErrWalletLocked {
+		return nil, walletUnlockNeededRPCError
+	}
 	if err != nil {
 		if !subtractFeeFromAmount && value+feeRequired > curBalance {
 			errMsg := fmt.Sprintf("Error: This transaction requires a "+
@@ -426,6 +547,10 @@ func handleSendMany(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 		return nil, btcjson.NewRPCError(btcjson.RPCWalletInsufficientFunds, "Account has insufficient funds")
 	}
 
+	if lwallet.IsWalletLocked() {
+		return nil, walletUnlockNeededRPCError
+	}
+
 	changePosRet := -1
 	txn, feeRequired, err := lwallet.CreateTransaction(recipients, &changePosRet, true)
 	if err != nil || feeRequired+totalAmount > balance {
@@ -480,6 +605,112 @@ func handleAddMultiSigAddress(s *Server, cmd interface{}, closeChan <-chan struc
 
 }
 
+func handleEncryptWallet(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !lwallet.IsWalletEnable() {
+		return nil, walletDisableRPCError
+	}
+
+	c := cmd.(*btcjson.EncryptWalletCmd)
+
+	pwallet := wallet.GetInstance()
+	if pwallet.IsCrypted() {
+		return nil, btcjson.NewRPCError(btcjson.RPCWalletWrongEncState,
+			"Error: running with an encrypted wallet, but encryptwallet was called.")
+	}
+	if len(c.Passphrase) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "passphrase can not be empty")
+	}
+
+	if err := pwallet.EncryptWallet([]byte(c.Passphrase)); err != nil {
+		log.Error("EncryptWallet error:%s", err.Error())
+		return nil, btcjson.NewRPCError(btcjson.RPCWalletEncryptionFailed,
+			"Error: Failed to encrypt the wallet.")
+	}
+
+	return "wallet encrypted; You need to make a new backup.", nil
+}
+
+func handleWalletPassphrase(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !lwallet.IsWalletEnable() {
+		return nil, walletDisableRPCError
+	}
+
+	c := cmd.(*btcjson.WalletPassphraseCmd)
+
+	pwallet := wallet.GetInstance()
+	if !pwallet.IsCrypted() {
+		return nil, btcjson.NewRPCError(btcjson.RPCWalletWrongEncState,
+			"Error: running with an unencrypted wallet, but walletpassphrase was called.")
+	}
+	if len(c.Passphrase) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "passphrase can not be empty")
+	}
+
+	// Timeout cannot be negative, otherwise it will relock immediately
+	if c.Timeout < 0 {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "Timeout cannot be negative.")
+	}
+	timeout := c.Timeout
+	if timeout > maxWalletUnlockTimeout {
+		timeout = maxWalletUnlockTimeout
+	}
+
+	err := pwallet.Unlock([]byte(c.Passphrase), time.Duration(timeout)*time.Second)
+	if err == wallet.ErrWalletPassphraseIncorrect {
+		return nil, walletPassphraseIncorrectRPCError
+	}
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.RPCWalletError, err.Error())
+	}
+
+	// A zero timeout only checks the passphrase.
+	if timeout == 0 {
+		pwallet.Lock()
+	}
+	return nil, nil
+}
+
+func handleWalletPassphraseChange(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !lwallet.IsWalletEnable() {
+		return nil, walletDisableRPCError
+	}
+
+	c := cmd.(*btcjson.WalletPassphraseChangeCmd)
+
+	pwallet := wallet.GetInstance()
+	if !pwallet.IsCrypted() {
+		return nil, btcjson.NewRPCError(btcjson.RPCWalletWrongEncState,
+			"Error: running with an unencrypted wallet, but walletpassphrasechange was called.")
+	}
+	if len(c.OldPassphrase) == 0 || len(c.NewPassphrase) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.RPCInvalidParameter, "passphrase can not be empty")
+	}
+
+	err := pwallet.ChangePassphrase([]byte(c.OldPassphrase), []byte(c.NewPassphrase))
+	if err == wallet.ErrWalletPassphraseIncorrect {
+		return nil, walletPassphraseIncorrectRPCError
+	}
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.RPCWalletError, err.Error())
+	}
+	return nil, nil
+}
+
+func handleWalletLock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if !lwallet.IsWalletEnable() {
+		return nil, walletDisableRPCError
+	}
+
+	pwallet := wallet.GetInstance()
+	if !pwallet.IsCrypted() {
+		return nil, btcjson.NewRPCError(btcjson.RPCWalletWrongEncState,
+			"Error: running with an unencrypted wallet, but walletlock was called.")
+	}
+
+	pwallet.Lock()
+	return nil, nil
+}
+
 func registerWalletRPCCommands() {
 	for name, handler := range walletHandlers {
 		appendCommand(name, handler)
@@ -0,0 +1,1668 @@
+// Copyright (c) 2013-2017 The btcsuite developers
+// Copyright (c) 2015-2017 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/cashaddr"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// websocketSendBufferSize is the number of elements the send channel
+	// can queue before blocking.  Note that this only applies to requests
+	// handled directly in the websocket client input handler since
+	// notifications have their own queuing mechanism independent of the
+	// send channel buffer.
+	websocketSendBufferSize = 50
+)
+
+type semaphore chan struct{}
+
+func makeSemaphore(n int) semaphore {
+	return make(chan struct{}, n)
+}
+
+func (s semaphore) acquire() { s <- struct{}{} }
+func (s semaphore) release() { <-s }
+
+// timeZeroVal is simply the zero value for a time.Time and is used to avoid
+// creating multiple instances.
+var timeZeroVal time.Time
+
+// wsUpgrader upgrades the HTTP connections made to the websocket endpoint.
+// The origin is not checked since clients have to present the RPC
+// credentials before being upgraded.
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsCommandHandler describes a callback function used to handle a specific
+// command.
+type wsCommandHandler func(*wsClient, interface{}) (interface{}, error)
+
+// wsHandlers maps RPC command strings to appropriate websocket handler
+// functions.  This is set by init because help references wsHandlers and thus
+// causes a dependency loop.
+var wsHandlers map[string]wsCommandHandler
+var wsHandlersBeforeInit = map[string]wsCommandHandler{
+	"loadtxfilter":              handleLoadTxFilter,
+	"help":                      handleWebsocketHelp,
+	"notifyblocks":              handleNotifyBlocks,
+	"notifynewtransactions":     handleNotifyNewTransactions,
+	"notifyreceived":            handleNotifyReceived,
+	"session":                   handleSession,
+	"stopnotifyblocks":          handleStopNotifyBlocks,
+	"stopnotifynewtransactions": handleStopNotifyNewTransactions,
+	"stopnotifyreceived":        handleStopNotifyReceived,
+	"rescanblocks":              handleRescanBlocks,
+}
+
+// WebsocketHandler handles a new websocket client by creating a new wsClient,
+// starting it, and blocking until the connection closes.  Since it blocks, it
+// must be run in a separate goroutine.  It should be invoked from the websocket
+// server handler which runs each new connection in a new goroutine thereby
+// satisfying the requirement.
+func (s *Server) WebsocketHandler(conn *websocket.Conn, remoteAddr string) {
+	// Clear the read deadline that was set before the websocket hijacked
+	// the connection.
+	conn.SetReadDeadline(timeZeroVal)
+
+	// Limit max number of websocket clients.
+	log.Info("New websocket client %s", remoteAddr)
+	if s.ntfnMgr.NumClients()+1 > conf.Cfg.RPC.RPCMaxWebsockets {
+		log.Info("Max websocket clients exceeded [%d] - "+
+			"disconnecting client %s", conf.Cfg.RPC.RPCMaxWebsockets,
+			remoteAddr)
+		conn.Close()
+		return
+	}
+
+	// Create a new websocket client to handle the new websocket connection
+	// and wait for it to shutdown.  Once it has shutdown (and hence
+	// disconnected), remove it and any notifications it registered for.
+	client, err := newWebsocketClient(s, conn, remoteAddr)
+	if err != nil {
+		log.Error("Failed to serve client %s: %v", remoteAddr, err)
+		conn.Close()
+		return
+	}
+	s.ntfnMgr.AddClient(client)
+	client.Start()
+	client.WaitForShutdown()
+	s.ntfnMgr.RemoveClient(client)
+	log.Info("Disconnected websocket client %s", remoteAddr)
+}
+
+// handleBlockchainNotification passes the blocks connected to and disconnected
+// from the main chain to the websocket notification manager, and wakes up the
+// requests waiting for the chain tip to change.
+func (s *Server) handleBlockchainNotification(notification *chain.Notification) {
+	switch notification.Type {
+	case chain.NTBlockConnected, chain.NTBlockDisconnected:
+		blk, ok := notification.Data.(*block.Block)
+		if !ok {
+			log.Warn("Chain notification is not a block.")
+			break
+		}
+
+		// The notification is sent with cs_main held, so the block index
+		// can be looked up safely to find the height of the block.
+		height := int32(-1)
+		if bindex := chain.GetInstance().FindBlockIndex(blk.GetHash()); bindex != nil {
+			height = bindex.Height
+		}
+
+		if notification.Type == chain.NTBlockConnected {
+			s.ntfnMgr.NotifyBlockConnected(blk, height)
+		} else {
+			s.ntfnMgr.NotifyBlockDisconnected(blk, height)
+		}
+		s.blockChange.Notify()
+
+	case chain.NTChainTipUpdated:
+		s.blockChange.Notify()
+	}
+}
+
+// handleTxAccepted passes the transactions accepted into the mempool to the
+// websocket notification manager and wakes up the long polling requests.
+func (s *Server) handleTxAccepted(txe *mempool.TxEntry) {
+	s.ntfnMgr.NotifyMempoolTx(txe.Tx)
+	s.mempoolChange.Notify()
+}
+
+// wsNotificationManager is a connection and notification manager used for
+// websockets.  It allows websocket clients to register for notifications they
+// are interested in.  When an event happens elsewhere in the code such as
+// transactions being added to the memory pool or block connects/disconnects,
+// the notification manager is provided with the relevant details needed to
+// figure out which websocket clients need to be notified based on what they
+// have registered for and notifies them accordingly.  It is also used to keep
+// track of all connected websocket clients.
+type wsNotificationManager struct {
+	// server is the RPC server the notification manager is associated with.
+	server *Server
+
+	// queueNotification queues a notification for handling.
+	queueNotification chan interface{}
+
+	// notificationMsgs feeds notificationHandler with notifications
+	// and client (un)registeration requests from a queue as well as
+	// registeration and unregisteration requests from clients.
+	notificationMsgs chan interface{}
+
+	// Access channel for current number of connected clients.
+	numClients chan int
+
+	// Shutdown handling
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// queueHandler manages a queue of empty interfaces, reading from in and
+// sending the oldest unsent to out.  This handler stops when either of the
+// in or quit channels are closed, and closes out before returning, without
+// waiting to send any variables still remaining in the queue.
+func queueHandler(in <-chan interface{}, out chan<- interface{}, quit <-chan struct{}) {
+	var q []interface{}
+	var dequeue chan<- interface{}
+	skipQueue := out
+	var next interface{}
+out:
+	for {
+		select {
+		case n, ok := <-in:
+			if !ok {
+				// Sender closed input channel.
+				break out
+			}
+
+			// Either send to out immediately if skipQueue is
+			// non-nil (queue is empty) and reader is ready,
+			// or append to the queue and send later.
+			select {
+			case skipQueue <- n:
+			default:
+				q = append(q, n)
+				dequeue = out
+				skipQueue = nil
+				next = q[0]
+			}
+
+		case dequeue <- next:
+			copy(q, q[1:])
+			q[len(q)-1] = nil // avoid leak
+			q = q[:len(q)-1]
+			if len(q) == 0 {
+				dequeue = nil
+				skipQueue = out
+			} else {
+				next = q[0]
+			}
+
+		case <-quit:
+			break out
+		}
+	}
+	close(out)
+}
+
+// queueHandler maintains a queue of notifications and notification handler
+// control messages.
+func (m *wsNotificationManager) queueHandler() {
+	queueHandler(m.queueNotification, m.notificationMsgs, m.quit)
+	m.wg.Done()
+}
+
+// queue passes n to the queue handler.  Since the notifications are sent by
+// the chain and the mempool while the RPC server may no longer be running,
+// the send is abandoned once the manager has begun shutting down.
+func (m *wsNotificationManager) queue(n interface{}) {
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+	}
+}
+
+// NotifyBlockConnected passes a block newly-connected to the best chain
+// to the notification manager for block and transaction notification
+// processing.
+func (m *wsNotificationManager) NotifyBlockConnected(blk *block.Block, height int32) {
+	m.queue(&notificationBlockConnected{block: blk, height: height})
+}
+
+// NotifyBlockDisconnected passes a block disconnected from the best chain
+// to the notification manager for block notification processing.
+func (m *wsNotificationManager) NotifyBlockDisconnected(blk *block.Block, height int32) {
+	m.queue(&notificationBlockDisconnected{block: blk, height: height})
+}
+
+// NotifyMempoolTx passes a transaction accepted by mempool to the
+// notification manager for transaction notification processing.
+func (m *wsNotificationManager) NotifyMempoolTx(txn *tx.Tx) {
+	m.queue((*notificationTxAcceptedByMempool)(txn))
+}
+
+// wsAddrKey identifies an address by its type and hash160 so that the legacy
+// and the cashaddr encodings of the same destination are treated alike.
+type wsAddrKey struct {
+	addrType cashaddr.AddressType
+	hash     [20]byte
+}
+
+// decodeAddrKey decodes a legacy or cashaddr encoded address.
+func decodeAddrKey(addr string) (wsAddrKey, *btcjson.RPCError) {
+	var key wsAddrKey
+
+	addrType, hash160, jsonErr := decodeAddress(addr)
+	if jsonErr != nil {
+		return key, jsonErr
+	}
+	if len(hash160) != len(key.hash) {
+		return key, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+			"Invalid Bitcoin address: "+addr)
+	}
+
+	key.addrType = addrType
+	copy(key.hash[:], hash160)
+	return key, nil
+}
+
+// scriptAddrKeys returns the keys of the addresses a script pays to.  Pay to
+// pubkey outputs are matched by the pubkey hash address of the public key.
+func scriptAddrKeys(s *script.Script) []wsAddrKey {
+	_, addrs, _, err := s.ExtractDestinations()
+	if err != nil {
+		return nil
+	}
+
+	keys := make([]wsAddrKey, 0, len(addrs))
+	for _, addr := range addrs {
+		var key wsAddrKey
+		switch addr.GetVersion() {
+		case script.AddressVerPubKey():
+			key.addrType = cashaddr.P2PKH
+		case script.AddressVerScript():
+			key.addrType = cashaddr.P2SH
+		default:
+			continue
+		}
+		copy(key.hash[:], addr.EncodeToPubKeyHash())
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// wsClientFilter tracks relevant addresses for each websocket client for
+// the `rescanblocks` extension. It is modified by the `loadtxfilter` command.
+//
+// NOTE: This extension was ported from github.com/decred/dcrd
+type wsClientFilter struct {
+	mu sync.Mutex
+
+	// Addresses watched by the client.
+	addrs map[wsAddrKey]struct{}
+
+	// Outpoints of unspent outputs.
+	unspent map[outpoint.OutPoint]struct{}
+}
+
+// newWSClientFilter creates a new, empty wsClientFilter struct to be used
+// for a websocket client.  Addresses that can not be decoded are ignored.
+//
+// NOTE: This extension was ported from github.com/decred/dcrd
+func newWSClientFilter(addresses []string, unspentOutPoints []outpoint.OutPoint) *wsClientFilter {
+	filter := &wsClientFilter{
+		addrs:   make(map[wsAddrKey]struct{}, len(addresses)),
+		unspent: make(map[outpoint.OutPoint]struct{}, len(unspentOutPoints)),
+	}
+
+	for _, s := range addresses {
+		filter.addAddressStr(s)
+	}
+	for i := range unspentOutPoints {
+		filter.addUnspentOutPoint(&unspentOutPoints[i])
+	}
+
+	return filter
+}
+
+// addAddressStr parses an address from a string and then adds it to the
+// wsClientFilter.
+//
+// NOTE: This extension was ported from github.com/decred/dcrd
+func (f *wsClientFilter) addAddressStr(s string) {
+	// If address can't be decoded, no point in saving it since it should also
+	// impossible to create the address from an inspected transaction output
+	// script.
+	key, err := decodeAddrKey(s)
+	if err != nil {
+		return
+	}
+	f.addrs[key] = struct{}{}
+}
+
+// existsAddress returns true if the passed address has been added to the
+// wsClientFilter.
+//
+// NOTE: This extension was ported from github.com/decred/dcrd
+func (f *wsClientFilter) existsAddress(key wsAddrKey) bool {
+	_, ok := f.addrs[key]
+	return ok
+}
+
+// addUnspentOutPoint adds an outpoint to the wsClientFilter.
+//
+// NOTE: This extension was ported from github.com/decred/dcrd
+func (f *wsClientFilter) addUnspentOutPoint(op *outpoint.OutPoint) {
+	f.unspent[*op] = struct{}{}
+}
+
+// existsUnspentOutPoint returns true if the passed outpoint has been added to
+// the wsClientFilter.
+//
+// NOTE: This extension was ported from github.com/decred/dcrd
+func (f *wsClientFilter) existsUnspentOutPoint(op *outpoint.OutPoint) bool {
+	_, ok := f.unspent[*op]
+	return ok
+}
+
+// Notification types
+type notificationBlockConnected struct {
+	block  *block.Block
+	height int32
+}
+type notificationBlockDisconnected struct {
+	block  *block.Block
+	height int32
+}
+type notificationTxAcceptedByMempool tx.Tx
+
+// Notification control requests
+type notificationRegisterClient wsClient
+type notificationUnregisterClient wsClient
+type notificationRegisterBlocks wsClient
+type notificationUnregisterBlocks wsClient
+type notificationRegisterNewMempoolTxs wsClient
+type notificationUnregisterNewMempoolTxs wsClient
+type notificationRegisterAddr struct {
+	wsc   *wsClient
+	addrs []wsAddrKey
+}
+type notificationUnregisterAddr struct {
+	wsc  *wsClient
+	addr wsAddrKey
+}
+
+// notificationHandler reads notifications and control messages from the queue
+// handler and processes one at a time.
+func (m *wsNotificationManager) notificationHandler() {
+	// clients is a map of all currently connected websocket clients.
+	clients := make(map[chan struct{}]*wsClient)
+
+	// Maps used to hold lists of websocket clients to be notified on
+	// certain events.  Each websocket client also keeps maps for the events
+	// which have multiple triggers to make removal from these lists on
+	// connection close less horrendously expensive.
+	//
+	// Where possible, the quit channel is used as the unique id for a client
+	// since it is quite a bit more efficient than using the entire struct.
+	blockNotifications := make(map[chan struct{}]*wsClient)
+	txNotifications := make(map[chan struct{}]*wsClient)
+	watchedAddrs := make(map[wsAddrKey]map[chan struct{}]*wsClient)
+
+out:
+	for {
+		select {
+		case n, ok := <-m.notificationMsgs:
+			if !ok {
+				// queueHandler quit.
+				break out
+			}
+			switch n := n.(type) {
+			case *notificationBlockConnected:
+				// Skip iterating through all txs if no
+				// tx notification requests exist.
+				if len(watchedAddrs) != 0 {
+					for i, txn := range n.block.Txs {
+						m.notifyForTxOuts(watchedAddrs, txn,
+							blockDetails(n.block, n.height, i))
+					}
+				}
+
+				if len(blockNotifications) != 0 {
+					m.notifyBlockConnected(blockNotifications,
+						n.block, n.height)
+					m.notifyFilteredBlockConnected(blockNotifications,
+						n.block, n.height)
+				}
+
+			case *notificationBlockDisconnected:
+				if len(blockNotifications) != 0 {
+					m.notifyBlockDisconnected(blockNotifications,
+						n.block, n.height)
+					m.notifyFilteredBlockDisconnected(blockNotifications,
+						n.block, n.height)
+				}
+
+			case *notificationTxAcceptedByMempool:
+				txn := (*tx.Tx)(n)
+				if len(txNotifications) != 0 {
+					m.notifyForNewTx(txNotifications, txn)
+				}
+				m.notifyForTxOuts(watchedAddrs, txn, nil)
+				m.notifyRelevantTxAccepted(txn, clients)
+
+			case *notificationRegisterBlocks:
+				wsc := (*wsClient)(n)
+				blockNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterBlocks:
+				wsc := (*wsClient)(n)
+				delete(blockNotifications, wsc.quit)
+
+			case *notificationRegisterClient:
+				wsc := (*wsClient)(n)
+				clients[wsc.quit] = wsc
+
+			case *notificationUnregisterClient:
+				wsc := (*wsClient)(n)
+				// Remove any requests made by the client as well as
+				// the client itself.
+				delete(blockNotifications, wsc.quit)
+				delete(txNotifications, wsc.quit)
+				for addr := range wsc.addrRequests {
+					m.removeAddrRequest(watchedAddrs, wsc, addr)
+				}
+				delete(clients, wsc.quit)
+
+			case *notificationRegisterAddr:
+				m.addAddrRequests(watchedAddrs, n.wsc, n.addrs)
+
+			case *notificationUnregisterAddr:
+				m.removeAddrRequest(watchedAddrs, n.wsc, n.addr)
+
+			case *notificationRegisterNewMempoolTxs:
+				wsc := (*wsClient)(n)
+				txNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterNewMempoolTxs:
+				wsc := (*wsClient)(n)
+				delete(txNotifications, wsc.quit)
+
+			default:
+				log.Warn("Unhandled notification type")
+			}
+
+		case m.numClients <- len(clients):
+
+		case <-m.quit:
+			// RPC server shutting down.
+			break out
+		}
+	}
+
+	for _, c := range clients {
+		c.Disconnect()
+	}
+	m.wg.Done()
+}
+
+// NumClients returns the number of clients actively being served.
+func (m *wsNotificationManager) NumClients() (n int) {
+	select {
+	case n = <-m.numClients:
+	case <-m.quit: // Use default n (0) if server has shut down.
+	}
+	return
+}
+
+// RegisterBlockUpdates requests block update notifications to the passed
+// websocket client.
+func (m *wsNotificationManager) RegisterBlockUpdates(wsc *wsClient) {
+	m.queue((*notificationRegisterBlocks)(wsc))
+}
+
+// UnregisterBlockUpdates removes block update notifications for the passed
+// websocket client.
+func (m *wsNotificationManager) UnregisterBlockUpdates(wsc *wsClient) {
+	m.queue((*notificationUnregisterBlocks)(wsc))
+}
+
+// subscribedClients returns the set of all websocket client quit channels that
+// are registered to receive notifications regarding txn, either due to txn
+// spending a watched output or outputting to a watched address.  Matching
+// client's filters are updated based on this transaction's outputs and output
+// addresses that may be relevant for a client.
+func (m *wsNotificationManager) subscribedClients(txn *tx.Tx,
+	clients map[chan struct{}]*wsClient) map[chan struct{}]struct{} {
+
+	// Use a map of client quit channels as keys to prevent duplicates when
+	// multiple inputs and/or outputs are relevant to the client.
+	subscribed := make(map[chan struct{}]struct{})
+
+	for _, input := range txn.GetIns() {
+		for quitChan, wsc := range clients {
+			wsc.Lock()
+			filter := wsc.filterData
+			wsc.Unlock()
+			if filter == nil {
+				continue
+			}
+			filter.mu.Lock()
+			if filter.existsUnspentOutPoint(input.PreviousOutPoint) {
+				subscribed[quitChan] = struct{}{}
+			}
+			filter.mu.Unlock()
+		}
+	}
+
+	for i, output := range txn.GetOuts() {
+		keys := scriptAddrKeys(output.GetScriptPubKey())
+		if len(keys) == 0 {
+			// Clients are not able to subscribe to
+			// nonstandard or non-address outputs.
+			continue
+		}
+		for quitChan, wsc := range clients {
+			wsc.Lock()
+			filter := wsc.filterData
+			wsc.Unlock()
+			if filter == nil {
+				continue
+			}
+			filter.mu.Lock()
+			for _, key := range keys {
+				if filter.existsAddress(key) {
+					subscribed[quitChan] = struct{}{}
+					op := outpoint.OutPoint{
+						Hash:  txn.GetHash(),
+						Index: uint32(i),
+					}
+					filter.addUnspentOutPoint(&op)
+				}
+			}
+			filter.mu.Unlock()
+		}
+	}
+
+	return subscribed
+}
+
+// notifyBlockConnected notifies websocket clients that have registered for
+// block updates when a block is connected to the main chain.
+func (*wsNotificationManager) notifyBlockConnected(clients map[chan struct{}]*wsClient,
+	blk *block.Block, height int32) {
+
+	// Notify interested websocket clients about the connected block.
+	hash := blk.GetHash()
+	ntfn := btcjson.NewBlockConnectedNtfn(hash.String(), height,
+		int64(blk.Header.Time))
+	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	if err != nil {
+		log.Error("Failed to marshal block connected notification: "+
+			"%v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyBlockDisconnected notifies websocket clients that have registered for
+// block updates when a block is disconnected from the main chain (due to a
+// reorganize).
+func (*wsNotificationManager) notifyBlockDisconnected(clients map[chan struct{}]*wsClient,
+	blk *block.Block, height int32) {
+
+	// Notify interested websocket clients about the disconnected block.
+	hash := blk.GetHash()
+	ntfn := btcjson.NewBlockDisconnectedNtfn(hash.String(), height,
+		int64(blk.Header.Time))
+	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	if err != nil {
+		log.Error("Failed to marshal block disconnected "+
+			"notification: %v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyFilteredBlockConnected notifies websocket clients that have registered for
+// block updates when a block is connected to the main chain.
+func (m *wsNotificationManager) notifyFilteredBlockConnected(clients map[chan struct{}]*wsClient,
+	blk *block.Block, height int32) {
+
+	// Create the common portion of the notification that is the same for
+	// every client.
+	var w bytes.Buffer
+	err := blk.Header.Serialize(&w)
+	if err != nil {
+		log.Error("Failed to serialize header for filtered block "+
+			"connected notification: %v", err)
+		return
+	}
+	ntfn := btcjson.NewFilteredBlockConnectedNtfn(height,
+		hex.EncodeToString(w.Bytes()), nil)
+
+	// Search for relevant transactions for each client and save them
+	// serialized in hex encoding for the notification.
+	subscribedTxs := make(map[chan struct{}][]string)
+	for _, txn := range blk.Txs {
+		var txHex string
+		for quitChan := range m.subscribedClients(txn, clients) {
+			if txHex == "" {
+				txHex = txHexString(txn)
+			}
+			subscribedTxs[quitChan] = append(subscribedTxs[quitChan], txHex)
+		}
+	}
+	for quitChan, wsc := range clients {
+		// Add all discovered transactions for this client. For clients
+		// that have no filter, add the empty string slice.
+		ntfn.SubscribedTxs = subscribedTxs[quitChan]
+
+		// Marshal and queue notification.
+		marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+		if err != nil {
+			log.Error("Failed to marshal filtered block "+
+				"connected notification: %v", err)
+			return
+		}
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyFilteredBlockDisconnected notifies websocket clients that have registered for
+// block updates when a block is disconnected from the main chain (due to a
+// reorganize).
+func (*wsNotificationManager) notifyFilteredBlockDisconnected(clients map[chan struct{}]*wsClient,
+	blk *block.Block, height int32) {
+
+	// Notify interested websocket clients about the disconnected block.
+	var w bytes.Buffer
+	err := blk.Header.Serialize(&w)
+	if err != nil {
+		log.Error("Failed to serialize header for filtered block "+
+			"disconnected notification: %v", err)
+		return
+	}
+	ntfn := btcjson.NewFilteredBlockDisconnectedNtfn(height,
+		hex.EncodeToString(w.Bytes()))
+	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	if err != nil {
+		log.Error("Failed to marshal filtered block disconnected "+
+			"notification: %v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// RegisterNewMempoolTxsUpdates requests notifications to the passed websocket
+// client when new transactions are added to the memory pool.
+func (m *wsNotificationManager) RegisterNewMempoolTxsUpdates(wsc *wsClient) {
+	m.queue((*notificationRegisterNewMempoolTxs)(wsc))
+}
+
+// UnregisterNewMempoolTxsUpdates removes notifications to the passed websocket
+// client when new transaction are added to the memory pool.
+func (m *wsNotificationManager) UnregisterNewMempoolTxsUpdates(wsc *wsClient) {
+	m.queue((*notificationUnregisterNewMempoolTxs)(wsc))
+}
+
+// notifyForNewTx notifies websocket clients that have registered for updates
+// when a new transaction is added to the memory pool.
+func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClient, txn *tx.Tx) {
+	txHash := txn.GetHash()
+	ntfn := btcjson.NewTxAcceptedNtfn(txHash.String(),
+		valueFromAmount(int64(txn.GetValueOut())))
+	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	if err != nil {
+		log.Error("Failed to marshal tx notification: %s", err.Error())
+		return
+	}
+
+	var marshalledJSONVerbose []byte
+	for _, wsc := range clients {
+		if wsc.verboseTxUpdates {
+			if marshalledJSONVerbose != nil {
+				wsc.QueueNotification(marshalledJSONVerbose)
+				continue
+			}
+
+			rawTx, jsonErr := getTxRawResult(txn, nil, txHexString(txn))
+			if jsonErr != nil {
+				return
+			}
+
+			verboseNtfn := btcjson.NewTxAcceptedVerboseNtfn(*rawTx)
+			marshalledJSONVerbose, err = btcjson.MarshalCmd(nil, verboseNtfn)
+			if err != nil {
+				log.Error("Failed to marshal verbose tx "+
+					"notification: %s", err.Error())
+				return
+			}
+			wsc.QueueNotification(marshalledJSONVerbose)
+		} else {
+			wsc.QueueNotification(marshalledJSON)
+		}
+	}
+}
+
+// txHexString returns the serialized transaction encoded in hexadecimal.
+func txHexString(txn *tx.Tx) string {
+	buf := bytes.NewBuffer(make([]byte, 0, txn.SerializeSize()))
+	// Ignore Serialize's error, as writing to a bytes.buffer cannot fail.
+	txn.Serialize(buf)
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// blockDetails creates a BlockDetails struct to include in websocket
+// notifications from a block and a transaction's block index.
+func blockDetails(blk *block.Block, height int32, txIndex int) *btcjson.BlockDetails {
+	hash := blk.GetHash()
+	return &btcjson.BlockDetails{
+		Height: height,
+		Hash:   hash.String(),
+		Index:  txIndex,
+		Time:   int64(blk.Header.Time),
+	}
+}
+
+// notifyForTxOuts examines each transaction output, notifying interested
+// websocket clients of the transaction if an output spends to a watched
+// address.  details is nil for the transactions accepted into the mempool.
+func (m *wsNotificationManager) notifyForTxOuts(addrs map[wsAddrKey]map[chan struct{}]*wsClient,
+	txn *tx.Tx, details *btcjson.BlockDetails) {
+
+	// Nothing to do if nobody is listening for address notifications.
+	if len(addrs) == 0 {
+		return
+	}
+
+	var marshalledJSON []byte
+	wscNotified := make(map[chan struct{}]struct{})
+	for _, txOut := range txn.GetOuts() {
+		for _, key := range scriptAddrKeys(txOut.GetScriptPubKey()) {
+			cmap, ok := addrs[key]
+			if !ok {
+				continue
+			}
+
+			if marshalledJSON == nil {
+				ntfn := btcjson.NewRecvTxNtfn(txHexString(txn), details)
+				var err error
+				marshalledJSON, err = btcjson.MarshalCmd(nil, ntfn)
+				if err != nil {
+					log.Error("Failed to marshal recvtx notification: %v", err)
+					return
+				}
+			}
+
+			for wscQuit, wsc := range cmap {
+				if _, ok := wscNotified[wscQuit]; !ok {
+					wscNotified[wscQuit] = struct{}{}
+					wsc.QueueNotification(marshalledJSON)
+				}
+			}
+		}
+	}
+}
+
+// notifyRelevantTxAccepted examines the inputs and outputs of the passed
+// transaction, notifying websocket clients of outputs spending to a watched
+// address and inputs spending a watched outpoint.  Any outputs paying to a
+// watched address result in the output being watched as well for future
+// notifications.
+func (m *wsNotificationManager) notifyRelevantTxAccepted(txn *tx.Tx,
+	clients map[chan struct{}]*wsClient) {
+
+	clientsToNotify := m.subscribedClients(txn, clients)
+
+	if len(clientsToNotify) != 0 {
+		n := btcjson.NewRelevantTxAcceptedNtfn(txHexString(txn))
+		marshalled, err := btcjson.MarshalCmd(nil, n)
+		if err != nil {
+			log.Error("Failed to marshal notification: %v", err)
+			return
+		}
+		for quitChan := range clientsToNotify {
+			clients[quitChan].QueueNotification(marshalled)
+		}
+	}
+}
+
+// RegisterTxOutAddressRequests requests notifications to the passed websocket
+// client when a transaction output spends to the passed address.
+func (m *wsNotificationManager) RegisterTxOutAddressRequests(wsc *wsClient, addrs []wsAddrKey) {
+	m.queue(&notificationRegisterAddr{
+		wsc:   wsc,
+		addrs: addrs,
+	})
+}
+
+// addAddrRequests adds the websocket client wsc to the address to client set
+// addrMap so wsc will be notified for any mempool or block transaction outputs
+// spending to any of the addresses in addrs.
+func (*wsNotificationManager) addAddrRequests(addrMap map[wsAddrKey]map[chan struct{}]*wsClient,
+	wsc *wsClient, addrs []wsAddrKey) {
+
+	for _, addr := range addrs {
+		// Track the request in the client as well so it can be quickly be
+		// removed on disconnect.
+		wsc.addrRequests[addr] = struct{}{}
+
+		// Add the client to the set of clients to notify when the
+		// outpoint is seen.  Create map as needed.
+		cmap, ok := addrMap[addr]
+		if !ok {
+			cmap = make(map[chan struct{}]*wsClient)
+			addrMap[addr] = cmap
+		}
+		cmap[wsc.quit] = wsc
+	}
+}
+
+// UnregisterTxOutAddressRequest removes a request from the passed websocket
+// client to be notified when a transaction spends to the passed address.
+func (m *wsNotificationManager) UnregisterTxOutAddressRequest(wsc *wsClient, addr wsAddrKey) {
+	m.queue(&notificationUnregisterAddr{
+		wsc:  wsc,
+		addr: addr,
+	})
+}
+
+// removeAddrRequest removes the websocket client wsc from the address to
+// client set addrs so it will no longer receive notification updates for
+// any transaction outputs send to addr.
+func (*wsNotificationManager) removeAddrRequest(addrs map[wsAddrKey]map[chan struct{}]*wsClient,
+	wsc *wsClient, addr wsAddrKey) {
+
+	// Remove the request tracking from the client.
+	delete(wsc.addrRequests, addr)
+
+	// Remove the client from the list to notify.
+	cmap, ok := addrs[addr]
+	if !ok {
+		log.Warn("Attempt to remove nonexistent addr request "+
+			"for websocket client %s", wsc.addr)
+		return
+	}
+	delete(cmap, wsc.quit)
+
+	// Remove the map entry altogether if there are no more clients
+	// interested in it.
+	if len(cmap) == 0 {
+		delete(addrs, addr)
+	}
+}
+
+// AddClient adds the passed websocket client to the notification manager.
+func (m *wsNotificationManager) AddClient(wsc *wsClient) {
+	m.queue((*notificationRegisterClient)(wsc))
+}
+
+// RemoveClient removes the passed websocket client and all notifications
+// registered for it.
+func (m *wsNotificationManager) RemoveClient(wsc *wsClient) {
+	m.queue((*notificationUnregisterClient)(wsc))
+}
+
+// Start starts the goroutines required for the manager to queue and process
+// websocket client notifications.
+func (m *wsNotificationManager) Start() {
+	m.wg.Add(2)
+	go m.queueHandler()
+	go m.notificationHandler()
+}
+
+// WaitForShutdown blocks until all notification manager goroutines have
+// finished.
+func (m *wsNotificationManager) WaitForShutdown() {
+	m.wg.Wait()
+}
+
+// Shutdown shuts down the manager, stopping the notification queue and
+// notification handler goroutines.
+func (m *wsNotificationManager) Shutdown() {
+	close(m.quit)
+}
+
+// newWsNotificationManager returns a new notification manager ready for use.
+// See wsNotificationManager for more details.
+func newWsNotificationManager(server *Server) *wsNotificationManager {
+	return &wsNotificationManager{
+		server:            server,
+		queueNotification: make(chan interface{}),
+		notificationMsgs:  make(chan interface{}),
+		numClients:        make(chan int),
+		quit:              make(chan struct{}),
+	}
+}
+
+// wsResponse houses a message to send to a connected websocket client as
+// well as a channel to reply on when the message is sent.
+type wsResponse struct {
+	msg      []byte
+	doneChan chan bool
+}
+
+// wsClient provides an abstraction for handling a websocket client.  The
+// overall data flow is split into 3 main goroutines and a websocket manager
+// which is used to allow things such as broadcasting requested notifications
+// to all connected websocket clients.  Inbound messages are read via the
+// inHandler goroutine and dispatched to their own handler, at most
+// RPCMaxConcurrentReqs at a time.  There are two outbound message types - one
+// for responding to client requests and another for async notifications.
+// Responses to client requests use SendMessage which employs a buffered
+// channel thereby limiting the number of outstanding requests that can be
+// made.  Notifications are sent via QueueNotification which implements a
+// queue via notificationQueueHandler to ensure sending notifications from
+// other subsystems can't block.  Ultimately, all messages are sent via the
+// outHandler.
+type wsClient struct {
+	sync.Mutex
+
+	// server is the RPC server that is servicing the client.
+	server *Server
+
+	// conn is the underlying websocket connection.
+	conn *websocket.Conn
+
+	// disconnected indicated whether or not the websocket client is
+	// disconnected.
+	disconnected bool
+
+	// addr is the remote address of the client.
+	addr string
+
+	// sessionID is a random ID generated for each client when connected.
+	// These IDs may be queried by a client using the session RPC.  A change
+	// to the session ID indicates that the client reconnected.
+	sessionID uint64
+
+	// verboseTxUpdates specifies whether a client has requested verbose
+	// information about all new transactions.
+	verboseTxUpdates bool
+
+	// addrRequests is a set of addresses the caller has requested to be
+	// notified about.  It is maintained here so all requests can be removed
+	// when a wallet disconnects.  Owned by the notification manager.
+	addrRequests map[wsAddrKey]struct{}
+
+	// filterData is the transaction filter used by the `loadtxfilter` and
+	// `rescanblocks` methods.
+	filterData *wsClientFilter
+
+	// Networking infrastructure.
+	serviceRequestSem semaphore
+	ntfnChan          chan []byte
+	sendChan          chan wsResponse
+	quit              chan struct{}
+	wg                sync.WaitGroup
+}
+
+// inHandler handles all incoming messages for the websocket connection.  It
+// must be run as a goroutine.
+func (c *wsClient) inHandler() {
+out:
+	for {
+		// Break out of the loop once the quit channel has been closed.
+		// Use a non-blocking select here so we fall through otherwise.
+		select {
+		case <-c.quit:
+			break out
+		default:
+		}
+
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
+			// Log the error if it's not due to disconnecting.
+			if err != io.EOF && !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				log.Error("Websocket receive error from "+
+					"%s: %v", c.addr, err)
+			}
+			break out
+		}
+
+		var request btcjson.Request
+		err = json.Unmarshal(msg, &request)
+		if err != nil {
+			jsonErr := &btcjson.RPCError{
+				Code:    btcjson.ErrRPCParse.Code,
+				Message: "Failed to parse request: " + err.Error(),
+			}
+			reply, err := createMarshalledReply(nil, nil, jsonErr)
+			if err != nil {
+				log.Error("Failed to marshal parse failure "+
+					"reply: %v", err)
+				continue
+			}
+			c.SendMessage(reply, nil)
+			continue
+		}
+
+		// Requests with no ID (notifications) must not have a response
+		// per the JSON-RPC spec.
+		if request.ID == nil && !(conf.Cfg.RPC.RPCQuirks && request.Jsonrpc == "") {
+			continue
+		}
+
+		cmd := parseCmd(&request, nil)
+		if cmd.err != nil {
+			reply, err := createMarshalledReply(cmd.id, nil, cmd.err)
+			if err != nil {
+				log.Error("Failed to marshal parse failure "+
+					"reply: %v", err)
+				continue
+			}
+			c.SendMessage(reply, nil)
+			continue
+		}
+		log.Debug("Received command <%s> from %s", cmd.method, c.addr)
+
+		// Requests are serviced concurrently, but limited to
+		// RPCMaxConcurrentReqs at a time for each client.
+		c.serviceRequestSem.acquire()
+		go func() {
+			c.serviceRequest(cmd)
+			c.serviceRequestSem.release()
+		}()
+	}
+
+	// Ensure the connection is closed.
+	c.Disconnect()
+	c.wg.Done()
+	log.Trace("Websocket client input handler done for %s", c.addr)
+}
+
+// serviceRequest services a parsed RPC request by looking up and executing the
+// appropriate RPC handler.  The response is marshalled and sent to the
+// websocket client.
+func (c *wsClient) serviceRequest(r *parsedRPCCmd) {
+	var (
+		result interface{}
+		err    error
+	)
+
+	// Lookup the websocket extension for the command and if it doesn't
+	// exist fallback to handling the command as a standard command.
+	wsHandler, ok := wsHandlers[r.method]
+	if ok {
+		result, err = wsHandler(c, r.cmd)
+	} else {
+		result, err = c.server.standardCmdResult(r, c.quit)
+	}
+	reply, err := createMarshalledReply(r.id, result, err)
+	if err != nil {
+		log.Error("Failed to marshal reply for <%s> "+
+			"command: %v", r.method, err)
+		return
+	}
+	c.SendMessage(reply, nil)
+}
+
+// notificationQueueHandler handles the queuing of outgoing notifications for
+// the websocket client.  This runs as a muxer for various sources of input to
+// ensure that queuing up notifications to be sent will not block.  Otherwise,
+// slow clients could bog down the other systems (such as the mempool or the
+// chain) which are queuing the data.  The data is passed on to outHandler to
+// actually be written.  It must be run as a goroutine.
+func (c *wsClient) notificationQueueHandler() {
+	ntfnSentChan := make(chan bool, 1) // nonblocking sync
+
+	// pendingNtfns is used as a queue for notifications that are ready to
+	// be sent once there are no outstanding notifications currently being
+	// sent.  The waiting flag is used over simply checking for items in the
+	// pending list to ensure cleanup knows what has and hasn't been sent
+	// to the outHandler.
+	pendingNtfns := list.New()
+	waiting := false
+out:
+	for {
+		select {
+		// This channel is notified when a message is being queued to
+		// be sent across the network socket.  It will either send the
+		// message immediately if a send is not already in progress, or
+		// queue the message to be sent once the other pending messages
+		// are sent.
+		case msg := <-c.ntfnChan:
+			if !waiting {
+				c.SendMessage(msg, ntfnSentChan)
+			} else {
+				pendingNtfns.PushBack(msg)
+			}
+			waiting = true
+
+		// This channel is notified when a notification has been sent
+		// across the network socket.
+		case <-ntfnSentChan:
+			// No longer waiting if there are no more messages in
+			// the pending messages queue.
+			next := pendingNtfns.Front()
+			if next == nil {
+				waiting = false
+				continue
+			}
+
+			// Notify the outHandler about the next item to
+			// asynchronously send.
+			msg := pendingNtfns.Remove(next).([]byte)
+			c.SendMessage(msg, ntfnSentChan)
+
+		case <-c.quit:
+			break out
+		}
+	}
+
+	// Drain any wait channels before exiting so nothing is left waiting
+	// around to send.
+cleanup:
+	for {
+		select {
+		case <-c.ntfnChan:
+		case <-ntfnSentChan:
+		default:
+			break cleanup
+		}
+	}
+	c.wg.Done()
+	log.Trace("Websocket client notification queue handler done "+
+		"for %s", c.addr)
+}
+
+// outHandler handles all outgoing messages for the websocket connection.  It
+// uses a buffered channel to serialize output messages while allowing the
+// sender to continue running asynchronously.  It must be run as a goroutine.
+func (c *wsClient) outHandler() {
+out:
+	for {
+		// Send any messages ready for send until the quit channel is
+		// closed.
+		select {
+		case r := <-c.sendChan:
+			err := c.conn.WriteMessage(websocket.TextMessage, r.msg)
+			if err != nil {
+				c.Disconnect()
+				break out
+			}
+			if r.doneChan != nil {
+				r.doneChan <- true
+			}
+
+		case <-c.quit:
+			break out
+		}
+	}
+
+	// Drain any wait channels before exiting so nothing is left waiting
+	// around to send.
+cleanup:
+	for {
+		select {
+		case r := <-c.sendChan:
+			if r.doneChan != nil {
+				r.doneChan <- false
+			}
+		default:
+			break cleanup
+		}
+	}
+	c.wg.Done()
+	log.Trace("Websocket client output handler done for %s", c.addr)
+}
+
+// SendMessage sends the passed json to the websocket client.  It is backed
+// by a buffered channel, so it will not block until the send channel is full.
+// Note however that QueueNotification must be used for sending async
+// notifications instead of the this function.  This approach allows a limit to
+// the number of outstanding requests a client can make without preventing or
+// blocking on async notifications.
+func (c *wsClient) SendMessage(marshalledJSON []byte, doneChan chan bool) {
+	// Don't send the message if disconnected.
+	if c.Disconnected() {
+		if doneChan != nil {
+			doneChan <- false
+		}
+		return
+	}
+
+	select {
+	case c.sendChan <- wsResponse{msg: marshalledJSON, doneChan: doneChan}:
+	case <-c.quit:
+		if doneChan != nil {
+			doneChan <- false
+		}
+	}
+}
+
+// ErrClientQuit describes the error where a client send is not processed due
+// to the client having already been disconnected or dropped.
+var ErrClientQuit = errors.New("client quit")
+
+// QueueNotification queues the passed notification to be sent to the websocket
+// client.  This function, as the name implies, is only intended for
+// notifications since it has additional logic to prevent other subsystems, such
+// as the memory pool and the chain, from blocking even when the send channel
+// is full.
+//
+// If the client is in the process of shutting down, this function returns
+// ErrClientQuit.
+func (c *wsClient) QueueNotification(marshalledJSON []byte) error {
+	// Don't queue the message if disconnected.
+	if c.Disconnected() {
+		return ErrClientQuit
+	}
+
+	select {
+	case c.ntfnChan <- marshalledJSON:
+	case <-c.quit:
+		return ErrClientQuit
+	}
+	return nil
+}
+
+// Disconnected returns whether or not the websocket client is disconnected.
+func (c *wsClient) Disconnected() bool {
+	c.Lock()
+	isDisconnected := c.disconnected
+	c.Unlock()
+
+	return isDisconnected
+}
+
+// Disconnect disconnects the websocket client.
+func (c *wsClient) Disconnect() {
+	c.Lock()
+	defer c.Unlock()
+
+	// Nothing to do if already disconnected.
+	if c.disconnected {
+		return
+	}
+
+	log.Trace("Disconnecting websocket client %s", c.addr)
+	close(c.quit)
+	c.conn.Close()
+	c.disconnected = true
+}
+
+// Start begins processing input and output messages.
+func (c *wsClient) Start() {
+	log.Trace("Starting websocket client %s", c.addr)
+
+	// Start processing input and output.
+	c.wg.Add(3)
+	go c.inHandler()
+	go c.notificationQueueHandler()
+	go c.outHandler()
+}
+
+// WaitForShutdown blocks until the websocket client goroutines are stopped
+// and the connection is closed.
+func (c *wsClient) WaitForShutdown() {
+	c.wg.Wait()
+}
+
+// newWebsocketClient returns a new websocket client given the notification
+// manager, websocket connection and remote address.  The returned client is
+// ready to start.  Once started, the client will process incoming and outgoing
+// messages in separate goroutines complete with queuing.
+func newWebsocketClient(server *Server, conn *websocket.Conn, remoteAddr string) (*wsClient, error) {
+	sessionID, err := util.RandomUint64()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &wsClient{
+		conn:              conn,
+		addr:              remoteAddr,
+		sessionID:         sessionID,
+		server:            server,
+		addrRequests:      make(map[wsAddrKey]struct{}),
+		serviceRequestSem: makeSemaphore(conf.Cfg.RPC.RPCMaxConcurrentReqs),
+		ntfnChan:          make(chan []byte, 1), // nonblocking sync
+		sendChan:          make(chan wsResponse, websocketSendBufferSize),
+		quit:              make(chan struct{}),
+	}
+	return client, nil
+}
+
+// handleWebsocketHelp implements the help command for websocket connections.
+func handleWebsocketHelp(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.HelpCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	// Provide a usage overview of all commands when no specific command
+	// was specified.
+	var command string
+	if cmd.Command != nil {
+		command = *cmd.Command
+	}
+	if command == "" {
+		usage, err := wsc.server.helpCacher.rpcUsage(true)
+		if err != nil {
+			context := "Failed to generate RPC usage"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		return usage, nil
+	}
+
+	// Check that the command asked for is supported and implemented.
+	// Search the list of websocket handlers as well as the main list of
+	// handlers since help should only be provided for those cases.
+	valid := true
+	if _, ok := rpcHandlers[command]; !ok {
+		if _, ok := wsHandlers[command]; !ok {
+			valid = false
+		}
+	}
+	if !valid {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Unknown command: " + command,
+		}
+	}
+
+	// Get the help for the command.
+	help, err := wsc.server.helpCacher.rpcMethodHelp(command)
+	if err != nil {
+		context := "Failed to generate help"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return help, nil
+}
+
+// handleLoadTxFilter implements the loadtxfilter command extension for
+// websocket connections.
+//
+// NOTE: This extension is ported from github.com/decred/dcrd
+func handleLoadTxFilter(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*btcjson.LoadTxFilterCmd)
+
+	outPoints := make([]outpoint.OutPoint, len(cmd.OutPoints))
+	for i := range cmd.OutPoints {
+		hash, err := util.GetHashFromStr(cmd.OutPoints[i].Hash)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: err.Error(),
+			}
+		}
+		outPoints[i] = outpoint.OutPoint{
+			Hash:  *hash,
+			Index: cmd.OutPoints[i].Index,
+		}
+	}
+
+	wsc.Lock()
+	if cmd.Reload || wsc.filterData == nil {
+		wsc.filterData = newWSClientFilter(cmd.Addresses, outPoints)
+		wsc.Unlock()
+	} else {
+		wsc.Unlock()
+
+		wsc.filterData.mu.Lock()
+		for _, a := range cmd.Addresses {
+			wsc.filterData.addAddressStr(a)
+		}
+		for i := range outPoints {
+			wsc.filterData.addUnspentOutPoint(&outPoints[i])
+		}
+		wsc.filterData.mu.Unlock()
+	}
+
+	return nil, nil
+}
+
+// handleNotifyBlocks implements the notifyblocks command extension for
+// websocket connections.
+func handleNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterBlockUpdates(wsc)
+	return nil, nil
+}
+
+// handleSession implements the session command extension for websocket
+// connections.
+func handleSession(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	return &btcjson.SessionResult{SessionID: wsc.sessionID}, nil
+}
+
+// handleStopNotifyBlocks implements the stopnotifyblocks command extension for
+// websocket connections.
+func handleStopNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterBlockUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyNewTransations implements the notifynewtransactions command
+// extension for websocket connections.
+func handleNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.NotifyNewTransactionsCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	wsc.verboseTxUpdates = cmd.Verbose != nil && *cmd.Verbose
+	wsc.server.ntfnMgr.RegisterNewMempoolTxsUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyNewTransations implements the stopnotifynewtransactions
+// command extension for websocket connections.
+func handleStopNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterNewMempoolTxsUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyReceived implements the notifyreceived command extension for
+// websocket connections.
+func handleNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.NotifyReceivedCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	keys, err := decodeAddrKeys(cmd.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	wsc.server.ntfnMgr.RegisterTxOutAddressRequests(wsc, keys)
+	return nil, nil
+}
+
+// handleStopNotifyReceived implements the stopnotifyreceived command extension
+// for websocket connections.
+func handleStopNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.StopNotifyReceivedCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	keys, err := decodeAddrKeys(cmd.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		wsc.server.ntfnMgr.UnregisterTxOutAddressRequest(wsc, key)
+	}
+
+	return nil, nil
+}
+
+// decodeAddrKeys decodes each address in the passed string slice.  If any
+// single address fails to decode properly, the function returns an error.
+func decodeAddrKeys(addrs []string) ([]wsAddrKey, error) {
+	keys := make([]wsAddrKey, 0, len(addrs))
+	for _, addr := range addrs {
+		key, jsonErr := decodeAddrKey(addr)
+		if jsonErr != nil {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidAddressOrKey,
+				Message: fmt.Sprintf("Invalid address or key: %v",
+					addr),
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// rescanBlockFilter returns the hex encoded transactions of blk relevant to
+// filter.  Outputs paying to a watched address are added to the filter, so
+// that later transactions spending them are matched as well.
+func rescanBlockFilter(filter *wsClientFilter, blk *block.Block) []string {
+	var transactions []string
+
+	filter.mu.Lock()
+	for _, txn := range blk.Txs {
+		// Keep track of whether the transaction has already been added
+		// to the result.  It shouldn't be added twice.
+		added := false
+
+		// Scan inputs if not a coinbase transaction.
+		if !txn.IsCoinBase() {
+			for _, input := range txn.GetIns() {
+				if !filter.existsUnspentOutPoint(input.PreviousOutPoint) {
+					continue
+				}
+				if !added {
+					transactions = append(transactions, txHexString(txn))
+					added = true
+				}
+			}
+		}
+
+		// Scan outputs.
+		for i, output := range txn.GetOuts() {
+			for _, key := range scriptAddrKeys(output.GetScriptPubKey()) {
+				if !filter.existsAddress(key) {
+					continue
+				}
+
+				op := outpoint.OutPoint{
+					Hash:  txn.GetHash(),
+					Index: uint32(i),
+				}
+				filter.addUnspentOutPoint(&op)
+
+				if !added {
+					transactions = append(transactions, txHexString(txn))
+					added = true
+				}
+			}
+		}
+	}
+	filter.mu.Unlock()
+
+	return transactions
+}
+
+// handleRescanBlocks implements the rescanblocks command extension for
+// websocket connections.
+//
+// NOTE: This extension is ported from github.com/decred/dcrd
+func handleRescanBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.RescanBlocksCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	// Load client's transaction filter.  Must exist in order to continue.
+	wsc.Lock()
+	filter := wsc.filterData
+	wsc.Unlock()
+	if filter == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Transaction filter must be loaded before rescanning",
+		}
+	}
+
+	blockHashes := make([]*util.Hash, len(cmd.BlockHashes))
+	for i := range cmd.BlockHashes {
+		hash, err := util.GetHashFromStr(cmd.BlockHashes[i])
+		if err != nil {
+			return nil, rpcDecodeHexError(cmd.BlockHashes[i])
+		}
+		blockHashes[i] = hash
+	}
+
+	discoveredData := make([]btcjson.RescannedBlock, 0, len(blockHashes))
+
+	// Iterate over each block in the request and rescan.  When a block
+	// contains relevant transactions, add it to the response.
+	gChain := chain.GetInstance()
+	var lastBlockHash *util.Hash
+	for i := range blockHashes {
+		blockIndex := gChain.FindBlockIndex(*blockHashes[i])
+		if blockIndex == nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCBlockNotFound,
+				Message: "Block not found: " + cmd.BlockHashes[i],
+			}
+		}
+		blk, ok := disk.ReadBlockFromDisk(blockIndex, gChain.GetParams())
+		if !ok {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCMisc,
+				Message: "Block not found on disk: " + cmd.BlockHashes[i],
+			}
+		}
+		if lastBlockHash != nil && blk.Header.HashPrevBlock != *lastBlockHash {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: fmt.Sprintf("Block %v is not a child of %v",
+					blockHashes[i], lastBlockHash),
+			}
+		}
+		lastBlockHash = blockHashes[i]
+
+		transactions := rescanBlockFilter(filter, blk)
+		if len(transactions) != 0 {
+			discoveredData = append(discoveredData, btcjson.RescannedBlock{
+				Hash:         cmd.BlockHashes[i],
+				Transactions: transactions,
+			})
+		}
+	}
+
+	return &discoveredData, nil
+}
+
+func init() {
+	wsHandlers = wsHandlersBeforeInit
+}
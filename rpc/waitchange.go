@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+)
+
+// changeNotifier wakes up the RPC handlers waiting for something to change,
+// such as the chain tip or the mempool.
+type changeNotifier struct {
+	mtx     sync.Mutex
+	changed chan struct{}
+}
+
+func newChangeNotifier() *changeNotifier {
+	return &changeNotifier{changed: make(chan struct{})}
+}
+
+// Wait returns a channel which is closed by the next call to Notify. It must
+// be called before looking at the state being waited on, so that a change in
+// between is not missed.
+func (n *changeNotifier) Wait() <-chan struct{} {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	return n.changed
+}
+
+// Notify wakes up all the current waiters.
+func (n *changeNotifier) Notify() {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+// waitForTip waits until done returns true for the chain tip, the timeout
+// expires, the client goes away or the server shuts down, and returns the
+// chain tip at that time. A zero timeout waits without limit.
+func (s *Server) waitForTip(timeout time.Duration, closeChan <-chan struct{},
+	done func(tip *blockindex.BlockIndex) bool) *blockindex.BlockIndex {
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	gChain := chain.GetInstance()
+	for {
+		changed := s.blockChange.Wait()
+		tip := gChain.Tip()
+		if done(tip) {
+			return tip
+		}
+
+		select {
+		case <-changed:
+		case <-expired:
+			return gChain.Tip()
+		case <-closeChan:
+			return gChain.Tip()
+		case <-s.quit:
+			return gChain.Tip()
+		}
+	}
+}
@@ -56,6 +56,13 @@ type BlockTemplate struct {
 	Block         *block.Block
 	TxFees        []amount.Amount
 	TxSigOpsCount []int
+	TxSigChecks   []int
+	// The block sigops limit the template was assembled for before the
+	// phonon upgrade, zero after it.
+	SigOpLimit uint64
+	// The block SigChecks limit the template was assembled for after the
+	// phonon upgrade, zero before it.
+	SigCheckLimit uint64
 }
 
 func newBlockTemplate() *BlockTemplate {
@@ -63,6 +70,7 @@ func newBlockTemplate() *BlockTemplate {
 		Block:         block.NewBlock(),
 		TxFees:        make([]amount.Amount, 0),
 		TxSigOpsCount: make([]int, 0),
+		TxSigChecks:   make([]int, 0),
 	}
 }
 
@@ -74,10 +82,12 @@ type BlockAssembler struct {
 	blockSize             uint64
 	blockTx               uint64
 	blockSigOps           uint64
+	blockSigChecks        uint64
 	fees                  amount.Amount
 	inBlock               map[util.Hash]struct{}
 	height                int32
 	lockTimeCutoff        int64
+	phononEnabled         bool
 	chainParams           *model.BitcoinParams
 }
 
@@ -96,23 +106,30 @@ func (ba *BlockAssembler) resetBlockAssembler() {
 	// Reserve space for coinbase tx.
 	ba.blockSize = 1000
 	ba.blockSigOps = 100
+	ba.blockSigChecks = 100
 
 	// These counters do not include coinbase tx.
 	ba.blockTx = 0
 	ba.fees = 0
 }
 
-func (ba *BlockAssembler) testPackage(packageSize uint64, packageSigOps int64, add *tx.Tx) bool {
+// testPackage checks a package against the block size, and the block sigops
+// limit before the phonon upgrade or the block SigChecks limit after it.
+func (ba *BlockAssembler) testPackage(packageSize uint64, packageSigOps, packageSigChecks int64, add *tx.Tx) bool {
 	blockSizeWithPackage := ba.blockSize + packageSize
 	if blockSizeWithPackage >= ba.maxGeneratedBlockSize {
 		return false
 	}
-	maxSigOps, errSig := consensus.GetMaxBlockSigOpsCount(blockSizeWithPackage)
-	if errSig != nil {
-		log.Error("testPackage err :%v", errSig)
-		return false
+	if !ba.phononEnabled {
+		maxSigOps, errSig := consensus.GetMaxBlockSigOpsCount(blockSizeWithPackage)
+		if errSig != nil {
+			log.Error("testPackage err :%v", errSig)
+			return false
+		}
+		return ba.blockSigOps+uint64(packageSigOps) < maxSigOps
 	}
-	if ba.blockSigOps+uint64(packageSigOps) >= maxSigOps {
+	maxSigChecks := consensus.GetMaxBlockSigChecksCount(ba.maxGeneratedBlockSize)
+	if ba.blockSigChecks+uint64(packageSigChecks) >= maxSigChecks {
 		return false
 	}
 	return true
@@ -122,9 +139,11 @@ func (ba *BlockAssembler) addToBlock(te *mempool.TxEntry) {
 	ba.bt.Block.Txs = append(ba.bt.Block.Txs, te.Tx)
 	ba.bt.TxFees = append(ba.bt.TxFees, amount.Amount(te.TxFee))
 	ba.bt.TxSigOpsCount = append(ba.bt.TxSigOpsCount, te.SigOpCount)
+	ba.bt.TxSigChecks = append(ba.bt.TxSigChecks, te.SigChecks)
 	ba.blockSize += uint64(te.TxSize)
 	ba.blockTx++
 	ba.blockSigOps += uint64(te.SigOpCount)
+	ba.blockSigChecks += uint64(te.SigChecks)
 	ba.fees += amount.Amount(te.TxFee)
 	ba.inBlock[te.Tx.GetHash()] = struct{}{}
 }
@@ -230,6 +249,7 @@ func (ba *BlockAssembler) addPackageTxs(sortRecord map[util.Hash]int) int {
 		packageSize := entry.SumTxSizeWitAncestors
 		packageFee := entry.SumTxFeeWithAncestors
 		packageSigOps := entry.SumTxSigOpCountWithAncestors
+		packageSigChecks := entry.SumTxSigChecksWithAncestors
 
 		// deal with several different mining strategies
 		isEnd := false
@@ -250,7 +270,7 @@ func (ba *BlockAssembler) addPackageTxs(sortRecord map[util.Hash]int) int {
 			break
 		}
 
-		if !ba.testPackage(uint64(packageSize), packageSigOps, nil) {
+		if !ba.testPackage(uint64(packageSize), packageSigOps, packageSigChecks, nil) {
 			consecutiveFailed++
 			if consecutiveFailed > maxConsecutiveFailures &&
 				ba.blockSize > ba.maxGeneratedBlockSize-1000 {
@@ -311,6 +331,8 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 	ba.bt.TxFees = append(ba.bt.TxFees, -1)
 	ba.bt.TxSigOpsCount = make([]int, 0, 100000)
 	ba.bt.TxSigOpsCount = append(ba.bt.TxSigOpsCount, -1)
+	ba.bt.TxSigChecks = make([]int, 0, 100000)
+	ba.bt.TxSigChecks = append(ba.bt.TxSigChecks, -1)
 
 	indexPrev := chain.GetInstance().Tip()
 
@@ -332,6 +354,14 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 	ba.bt.Block.Header.Time = uint32(util.GetAdjustedTimeSec())
 	ba.maxGeneratedBlockSize = computeMaxGeneratedBlockSize()
 	lockTimeCutoff := indexPrev.GetMedianTimePast()
+	ba.phononEnabled = model.IsPhononEnabled(lockTimeCutoff)
+	if ba.phononEnabled {
+		ba.bt.SigOpLimit = 0
+		ba.bt.SigCheckLimit = consensus.GetMaxBlockSigChecksCount(ba.maxGeneratedBlockSize)
+	} else {
+		ba.bt.SigOpLimit, _ = consensus.GetMaxBlockSigOpsCount(ba.maxGeneratedBlockSize)
+		ba.bt.SigCheckLimit = 0
+	}
 	if tx.StandardLockTimeVerifyFlags&consensus.LocktimeMedianTimePast != 0 {
 		ba.lockTimeCutoff = lockTimeCutoff
 	} else {
@@ -346,16 +376,20 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 		sort.Sort(sortTxs(ba.bt.Block.Txs[1:]))
 		sortTxFees := make([]amount.Amount, len(ba.bt.TxFees))
 		sortTxFees[0] = ba.bt.TxFees[0]
-		sortTxSigOpCosts := make([]int, len(ba.bt.TxSigOpsCount))
-		sortTxSigOpCosts[0] = ba.bt.TxSigOpsCount[0]
+		sortTxSigOpsCount := make([]int, len(ba.bt.TxSigOpsCount))
+		sortTxSigOpsCount[0] = ba.bt.TxSigOpsCount[0]
+		sortTxSigChecks := make([]int, len(ba.bt.TxSigChecks))
+		sortTxSigChecks[0] = ba.bt.TxSigChecks[0]
 		for i, tmpTx := range ba.bt.Block.Txs[1:] {
 			offset := sortRecord[tmpTx.GetHash()]
 			sortTxFees[i+1] = ba.bt.TxFees[offset]
-			sortTxSigOpCosts[i+1] = ba.bt.TxSigOpsCount[offset]
+			sortTxSigOpsCount[i+1] = ba.bt.TxSigOpsCount[offset]
+			sortTxSigChecks[i+1] = ba.bt.TxSigChecks[offset]
 		}
 
 		ba.bt.TxFees = sortTxFees
-		ba.bt.TxSigOpsCount = sortTxSigOpCosts
+		ba.bt.TxSigOpsCount = sortTxSigOpsCount
+		ba.bt.TxSigChecks = sortTxSigChecks
 	}
 	time1 := util.GetTimeMicroSec()
 
@@ -383,8 +417,8 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 	ba.bt.TxFees[0] = -1 * ba.fees // coinbase's fee item is equal to tx fee sum for negative value
 
 	serializeSize := ba.bt.Block.SerializeSize()
-	log.Info("CreateNewBlock(): total size: %d txs: %d fees: %d sigops %d\n",
-		serializeSize, ba.blockTx, ba.fees, ba.blockSigOps)
+	log.Info("CreateNewBlock(): total size: %d txs: %d fees: %d sigops %d sigchecks %d\n",
+		serializeSize, ba.blockTx, ba.fees, ba.blockSigOps, ba.blockSigChecks)
 
 	// Fill in header.
 	if indexPrev == nil {
@@ -398,6 +432,8 @@ func (ba *BlockAssembler) CreateNewBlock(scriptPubKey, scriptSig *script.Script)
 	ba.bt.Block.Header.Nonce = 0
 
 	ba.bt.TxSigOpsCount[0] = ba.bt.Block.Txs[0].GetSigOpCountWithoutP2SH(uint32(script.StandardScriptVerifyFlags))
+	// The coinbase has no input script to execute.
+	ba.bt.TxSigChecks[0] = 0
 
 	//check the validity of the block
 	if err := TestBlockValidity(ba.bt.Block, indexPrev, false, false); err != nil {
@@ -465,6 +501,7 @@ func (ba *BlockAssembler) updatePackagesForAdded(txSet mapcontainer.MapContainer
 				item.SumTxSizeWitAncestors -= entry.SumTxSizeWitAncestors
 				item.SumTxFeeWithAncestors -= entry.SumTxFeeWithAncestors
 				item.SumTxSigOpCountWithAncestors -= entry.SumTxSigOpCountWithAncestors
+				item.SumTxSigChecksWithAncestors -= entry.SumTxSigChecksWithAncestors
 				// insert the modified one
 				txSet.ReplaceOrInsert(item)
 			case sortByFeeRate:
@@ -475,6 +512,7 @@ func (ba *BlockAssembler) updatePackagesForAdded(txSet mapcontainer.MapContainer
 				item.SumTxSizeWitAncestors -= entry.SumTxSizeWitAncestors
 				item.SumTxFeeWithAncestors -= entry.SumTxFeeWithAncestors
 				item.SumTxSigOpCountWithAncestors -= entry.SumTxSigOpCountWithAncestors
+				item.SumTxSigChecksWithAncestors -= entry.SumTxSigChecksWithAncestors
 				// insert the modified one
 				txSet.ReplaceOrInsert(item)
 			}
@@ -12,6 +12,7 @@ import (
 	"github.com/copernet/copernicus/logic/ltx"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/outpoint"
@@ -193,7 +194,7 @@ func (t *TestMemPoolEntry) FromTxToEntry(transaction *tx.Tx) *mempool.TxEntry {
 	if t.lp != nil {
 		lp = *(t.lp)
 	}
-	entry := mempool.NewTxentry(transaction, int64(t.Fee), t.Time, t.Height, lp, int(t.SigOpCost), t.SpendsCoinbase)
+	entry := mempool.NewTxentry(transaction, int64(t.Fee), t.Time, t.Height, lp, int(t.SigOpCost), int(t.SigOpCost), t.SpendsCoinbase)
 	return entry
 }
 
@@ -474,4 +475,35 @@ func TestCreateNewBlockByFeeRate(t *testing.T) {
 	if len(ba.bt.Block.Txs) != 5 {
 		t.Error("some transactions are inserted to block error")
 	}
+	assert.Equal(t, len(bt.Block.Txs), len(bt.TxSigOpsCount))
+	assert.Equal(t, uint64(0), bt.SigOpLimit)
+	assert.Equal(t, consensus.GetMaxBlockSigChecksCount(ba.maxGeneratedBlockSize), bt.SigCheckLimit)
+
+	// before phonon, the template is assembled for the sigops limit
+	conf.Args.PhononActivationTime = math.MaxInt64
+	defer func() { conf.Args.PhononActivationTime = -1 }()
+	bt = ba.CreateNewBlock(sc, CoinbaseScriptSig(extraNonce))
+	if bt == nil {
+		t.Fatal("create new block failed")
+	}
+	maxSigOps, _ := consensus.GetMaxBlockSigOpsCount(ba.maxGeneratedBlockSize)
+	assert.Equal(t, maxSigOps, bt.SigOpLimit)
+	assert.Equal(t, uint64(0), bt.SigCheckLimit)
+}
+
+func TestTestPackage(t *testing.T) {
+	ba := &BlockAssembler{maxGeneratedBlockSize: 2 * consensus.OneMegaByte}
+	ba.resetBlockAssembler()
+
+	// before phonon, the block sigops are limited and SigChecks are not
+	maxSigOps, _ := consensus.GetMaxBlockSigOpsCount(ba.blockSize + 1000)
+	maxSigChecks := int64(consensus.GetMaxBlockSigChecksCount(ba.maxGeneratedBlockSize))
+	assert.True(t, ba.testPackage(1000, int64(maxSigOps-ba.blockSigOps)-1, maxSigChecks, nil))
+	assert.False(t, ba.testPackage(1000, int64(maxSigOps-ba.blockSigOps), 0, nil))
+
+	ba.phononEnabled = true
+	assert.True(t, ba.testPackage(1000, int64(maxSigOps), maxSigChecks-int64(ba.blockSigChecks)-1, nil))
+	assert.False(t, ba.testPackage(1000, 0, maxSigChecks-int64(ba.blockSigChecks), nil))
+
+	assert.False(t, ba.testPackage(ba.maxGeneratedBlockSize, 0, 0, nil))
 }
@@ -68,8 +68,22 @@ type AddressType int
 const (
 	P2PKH AddressType = 0
 	P2SH  AddressType = 1
+	// TokenP2PKH and TokenP2SH are the token-aware address types, telling
+	// wallets the address can receive tokens.
+	TokenP2PKH AddressType = 2
+	TokenP2SH  AddressType = 3
 )
 
+// IsScriptHash reports whether the address type pays to a script hash.
+func (t AddressType) IsScriptHash() bool {
+	return t == P2SH || t == TokenP2SH
+}
+
+// IsTokenAware reports whether the address type is a token-aware one.
+func (t AddressType) IsTokenAware() bool {
+	return t == TokenP2PKH || t == TokenP2SH
+}
+
 func init() {
 	Prefixes = make(map[string]string)
 	Prefixes[model.MainNetParams.Name] = "bitcoincash"
@@ -367,16 +381,24 @@ func CheckDecodeCashAddress(input string) (result []byte, prefix string, t Addre
 	if err != nil {
 		return Data, prefix, P2PKH, err
 	}
-	if len(Data) != 21 {
-		return Data, prefix, P2PKH, errors.New("incorrect Data length")
+	if len(Data) == 0 || Data[0]&0x80 != 0 {
+		return Data, prefix, P2PKH, errors.New("incorrect version byte")
+	}
+	// The low 3 bits of the version byte encode the size of the hash, of
+	// which only 160 and 256 bits are in use.
+	var hashSize int
+	switch Data[0] & 0x07 {
+	case 0:
+		hashSize = ripemd160.Size
+	case 3:
+		hashSize = util.Hash256Size
+	default:
+		return Data, prefix, P2PKH, errors.New("incorrect hash size")
 	}
-	switch Data[0] {
-	case 0x00:
-		t = P2PKH
-	case 0x08:
-		t = P2SH
+	if len(Data) != 1+hashSize {
+		return Data, prefix, P2PKH, errors.New("incorrect Data length")
 	}
-	return Data[1:21], prefix, t, nil
+	return Data[1:], prefix, AddressType(Data[0] >> 3), nil
 }
 
 // encodeAddress returns a human-readable payment address given a ripemd160 hash
@@ -413,10 +435,18 @@ func DecodeAddress(addr string, defaultNet *model.BitcoinParams) (Address, error
 	switch len(decoded) {
 	case ripemd160.Size: // P2PKH or P2SH
 		switch typ {
-		case P2PKH:
-			return newCashAddressPubKeyHash(decoded, defaultNet)
-		case P2SH:
-			return newCashAddressScriptHashFromHash(decoded, defaultNet)
+		case P2PKH, TokenP2PKH:
+			return newCashAddressPubKeyHash(decoded, defaultNet, typ.IsTokenAware())
+		case P2SH, TokenP2SH:
+			return newCashAddressScriptHashFromHash(decoded, defaultNet, typ.IsTokenAware())
+		default:
+			return nil, ErrUnknownAddressType
+		}
+
+	case util.Hash256Size: // P2SH32
+		switch typ {
+		case P2SH, TokenP2SH:
+			return newCashAddressScriptHash32FromHash(decoded, defaultNet, typ.IsTokenAware())
 		default:
 			return nil, ErrUnknownAddressType
 		}
@@ -429,14 +459,21 @@ func DecodeAddress(addr string, defaultNet *model.BitcoinParams) (Address, error
 // CashAddressPubKeyHash is an Address for a pay-to-pubkey-hash (P2PKH)
 // transaction.
 type CashAddressPubKeyHash struct {
-	hash   [ripemd160.Size]byte
-	prefix string
+	hash       [ripemd160.Size]byte
+	prefix     string
+	tokenAware bool
 }
 
 // NewCashAddressPubKeyHash returns a new AddressPubKeyHash.  pkHash mustbe 20
 // bytes.
 func NewCashAddressPubKeyHash(pkHash []byte, net *model.BitcoinParams) (*CashAddressPubKeyHash, error) {
-	return newCashAddressPubKeyHash(pkHash, net)
+	return newCashAddressPubKeyHash(pkHash, net, false)
+}
+
+// NewCashAddressTokenPubKeyHash returns a new token-aware AddressPubKeyHash.
+// pkHash must be 20 bytes.
+func NewCashAddressTokenPubKeyHash(pkHash []byte, net *model.BitcoinParams) (*CashAddressPubKeyHash, error) {
+	return newCashAddressPubKeyHash(pkHash, net, true)
 }
 
 // newAddressPubKeyHash is the internal API to create a pubkey hash address
@@ -444,7 +481,7 @@ func NewCashAddressPubKeyHash(pkHash []byte, net *model.BitcoinParams) (*CashAdd
 // it up through its parameters.  This is useful when creating a new address
 // structure from a string encoding where the identifer byte is already
 // known.
-func newCashAddressPubKeyHash(pkHash []byte, net *model.BitcoinParams) (*CashAddressPubKeyHash, error) {
+func newCashAddressPubKeyHash(pkHash []byte, net *model.BitcoinParams, tokenAware bool) (*CashAddressPubKeyHash, error) {
 	// Check for a valid pubkey hash length.
 	if len(pkHash) != ripemd160.Size {
 		return nil, errors.New("pkHash must be 20 bytes")
@@ -455,7 +492,7 @@ func newCashAddressPubKeyHash(pkHash []byte, net *model.BitcoinParams) (*CashAdd
 		return nil, errors.New("unknown network parameters")
 	}
 
-	addr := &CashAddressPubKeyHash{prefix: prefix}
+	addr := &CashAddressPubKeyHash{prefix: prefix, tokenAware: tokenAware}
 	copy(addr.hash[:], pkHash)
 	return addr, nil
 }
@@ -463,6 +500,9 @@ func newCashAddressPubKeyHash(pkHash []byte, net *model.BitcoinParams) (*CashAdd
 // EncodeAddress returns the string encoding of a pay-to-pubkey-hash
 // address.  Part of the Address interface.
 func (a *CashAddressPubKeyHash) EncodeAddress() string {
+	if a.tokenAware {
+		return encodeCashAddress(a.hash[:], a.prefix, TokenP2PKH)
+	}
 	return encodeCashAddress(a.hash[:], a.prefix, P2PKH)
 }
 
@@ -496,23 +536,36 @@ func (a *CashAddressPubKeyHash) Hash160() *[ripemd160.Size]byte {
 	return &a.hash
 }
 
+// IsTokenAware returns whether the address is encoded as able to receive
+// tokens.
+func (a *CashAddressPubKeyHash) IsTokenAware() bool {
+	return a.tokenAware
+}
+
 // CashAddressScriptHash is an Address for a pay-to-script-hash (P2SH)
 // transaction.
 type CashAddressScriptHash struct {
-	hash   [ripemd160.Size]byte
-	prefix string
+	hash       [ripemd160.Size]byte
+	prefix     string
+	tokenAware bool
 }
 
 // NewCashAddressScriptHash returns a new AddressScriptHash.
 func NewCashAddressScriptHash(serializedScript []byte, net *model.BitcoinParams) (*CashAddressScriptHash, error) {
 	scriptHash := util.Hash160(serializedScript)
-	return newCashAddressScriptHashFromHash(scriptHash, net)
+	return newCashAddressScriptHashFromHash(scriptHash, net, false)
 }
 
 // NewCashAddressScriptHashFromHash returns a new AddressScriptHash.  scriptHash
 // must be 20 bytes.
 func NewCashAddressScriptHashFromHash(scriptHash []byte, net *model.BitcoinParams) (*CashAddressScriptHash, error) {
-	return newCashAddressScriptHashFromHash(scriptHash, net)
+	return newCashAddressScriptHashFromHash(scriptHash, net, false)
+}
+
+// NewCashAddressTokenScriptHashFromHash returns a new token-aware
+// AddressScriptHash.  scriptHash must be 20 bytes.
+func NewCashAddressTokenScriptHashFromHash(scriptHash []byte, net *model.BitcoinParams) (*CashAddressScriptHash, error) {
+	return newCashAddressScriptHashFromHash(scriptHash, net, true)
 }
 
 // newAddressScriptHashFromHash is the internal API to create a script hash
@@ -520,7 +573,8 @@ func NewCashAddressScriptHashFromHash(scriptHash []byte, net *model.BitcoinParam
 // looking it up through its parameters.  This is useful when creating a new
 // address structure from a string encoding where the identifer byte is already
 // known.
-func newCashAddressScriptHashFromHash(scriptHash []byte, net *model.BitcoinParams) (*CashAddressScriptHash, error) {
+func newCashAddressScriptHashFromHash(scriptHash []byte, net *model.BitcoinParams,
+	tokenAware bool) (*CashAddressScriptHash, error) {
 	// Check for a valid script hash length.
 	if len(scriptHash) != ripemd160.Size {
 		return nil, errors.New("scriptHash must be 20 bytes")
@@ -531,7 +585,7 @@ func newCashAddressScriptHashFromHash(scriptHash []byte, net *model.BitcoinParam
 		return nil, errors.New("unknown network parameters")
 	}
 
-	addr := &CashAddressScriptHash{prefix: pre}
+	addr := &CashAddressScriptHash{prefix: pre, tokenAware: tokenAware}
 	copy(addr.hash[:], scriptHash)
 	return addr, nil
 }
@@ -539,6 +593,9 @@ func newCashAddressScriptHashFromHash(scriptHash []byte, net *model.BitcoinParam
 // EncodeAddress returns the string encoding of a pay-to-script-hash
 // address.  Part of the Address interface.
 func (a *CashAddressScriptHash) EncodeAddress() string {
+	if a.tokenAware {
+		return encodeCashAddress(a.hash[:], a.prefix, TokenP2SH)
+	}
 	return encodeCashAddress(a.hash[:], a.prefix, P2SH)
 }
 
@@ -572,6 +629,94 @@ func (a *CashAddressScriptHash) Hash160() *[ripemd160.Size]byte {
 	return &a.hash
 }
 
+// IsTokenAware returns whether the address is encoded as able to receive
+// tokens.
+func (a *CashAddressScriptHash) IsTokenAware() bool {
+	return a.tokenAware
+}
+
+// CashAddressScriptHash32 is an Address for a pay-to-script-hash transaction
+// committing to the 32-byte double sha256 of the script (P2SH32).
+type CashAddressScriptHash32 struct {
+	hash       [util.Hash256Size]byte
+	prefix     string
+	tokenAware bool
+}
+
+// NewCashAddressScriptHash32 returns a new AddressScriptHash32.
+func NewCashAddressScriptHash32(serializedScript []byte, net *model.BitcoinParams) (*CashAddressScriptHash32, error) {
+	scriptHash := util.DoubleSha256Bytes(serializedScript)
+	return newCashAddressScriptHash32FromHash(scriptHash, net, false)
+}
+
+// NewCashAddressScriptHash32FromHash returns a new AddressScriptHash32.
+// scriptHash must be 32 bytes.
+func NewCashAddressScriptHash32FromHash(scriptHash []byte, net *model.BitcoinParams) (*CashAddressScriptHash32, error) {
+	return newCashAddressScriptHash32FromHash(scriptHash, net, false)
+}
+
+// NewCashAddressTokenScriptHash32FromHash returns a new token-aware
+// AddressScriptHash32.  scriptHash must be 32 bytes.
+func NewCashAddressTokenScriptHash32FromHash(scriptHash []byte,
+	net *model.BitcoinParams) (*CashAddressScriptHash32, error) {
+	return newCashAddressScriptHash32FromHash(scriptHash, net, true)
+}
+
+func newCashAddressScriptHash32FromHash(scriptHash []byte, net *model.BitcoinParams,
+	tokenAware bool) (*CashAddressScriptHash32, error) {
+	// Check for a valid script hash length.
+	if len(scriptHash) != util.Hash256Size {
+		return nil, errors.New("scriptHash must be 32 bytes")
+	}
+
+	pre, ok := Prefixes[net.Name]
+	if !ok {
+		return nil, errors.New("unknown network parameters")
+	}
+
+	addr := &CashAddressScriptHash32{prefix: pre, tokenAware: tokenAware}
+	copy(addr.hash[:], scriptHash)
+	return addr, nil
+}
+
+// EncodeAddress returns the string encoding of a pay-to-script-hash
+// address.  Part of the Address interface.
+func (a *CashAddressScriptHash32) EncodeAddress() string {
+	if a.tokenAware {
+		return CheckEncodeCashAddress(a.hash[:], a.prefix, TokenP2SH)
+	}
+	return CheckEncodeCashAddress(a.hash[:], a.prefix, P2SH)
+}
+
+// ScriptAddress returns the bytes to be included in a txout script to pay
+// to a script hash.  Part of the Address interface.
+func (a *CashAddressScriptHash32) ScriptAddress() []byte {
+	return a.hash[:]
+}
+
+// IsForNet returns whether or not the pay-to-script-hash address is associated
+// with the passed bitcoin cash network.
+func (a *CashAddressScriptHash32) IsForNet(net *model.BitcoinParams) bool {
+	pre, ok := Prefixes[net.Name]
+	if !ok {
+		return false
+	}
+	return pre == a.prefix
+}
+
+// String returns a human-readable string for the pay-to-script-hash address.
+// This is equivalent to calling EncodeAddress, but is provided so the type can
+// be used as a fmt.Stringer.
+func (a *CashAddressScriptHash32) String() string {
+	return a.EncodeAddress()
+}
+
+// IsTokenAware returns whether the address is encoded as able to receive
+// tokens.
+func (a *CashAddressScriptHash32) IsTokenAware() bool {
+	return a.tokenAware
+}
+
 // CashPayToAddrScript creates a new script to pay a transaction output to a the  specified address.
 func CashPayToAddrScript(addr Address) ([]byte, error) {
 	const nilAddrErrStr = "unable to generate payment script for nil address"
@@ -587,7 +732,13 @@ func CashPayToAddrScript(addr Address) ([]byte, error) {
 		if addr == nil {
 			return nil, errors.New(nilAddrErrStr)
 		}
-		return payToScriptHashScript(addr.ScriptAddress())
+		return payToScriptHashScript(opcodes.OP_HASH160, addr.ScriptAddress())
+
+	case *CashAddressScriptHash32:
+		if addr == nil {
+			return nil, errors.New(nilAddrErrStr)
+		}
+		return payToScriptHashScript(opcodes.OP_HASH256, addr.ScriptAddress())
 	}
 	return nil, fmt.Errorf("unable to generate payment script for unsupported "+
 		"address type %T", addr)
@@ -629,10 +780,11 @@ func payToPubKeyHashScript(pubKeyHash []byte) ([]byte, error) {
 }
 
 // payToScriptHashScript creates a new script to pay a transaction output to a
-// script hash. It is expected that the input is a valid hash.
-func payToScriptHashScript(scriptHash []byte) ([]byte, error) {
+// script hash, hashOp being OP_HASH160 or OP_HASH256 for P2SH32. It is
+// expected that the input is a valid hash.
+func payToScriptHashScript(hashOp int, scriptHash []byte) ([]byte, error) {
 	sc := script.NewEmptyScript()
-	err := sc.PushOpCode(opcodes.OP_HASH160)
+	err := sc.PushOpCode(hashOp)
 	if err != nil {
 		log.Error("push opcode failed: %v", err)
 		return nil, err
@@ -663,6 +815,8 @@ func ExtractPkScriptAddrs(pkScript []byte, chainParams *model.BitcoinParams) (Ad
 		return NewCashAddressScriptHashFromHash(pkScript[2:22], chainParams)
 	} else if len(pkScript) == 1+1+1+20+1+1 && pkScript[0] == 0x76 && pkScript[1] == 0xa9 && pkScript[2] == 0x14 && pkScript[23] == 0x88 && pkScript[24] == 0xac {
 		return NewCashAddressPubKeyHash(pkScript[3:23], chainParams)
+	} else if len(pkScript) == 1+1+32+1 && pkScript[0] == 0xaa && pkScript[1] == 0x20 && pkScript[34] == 0x87 {
+		return NewCashAddressScriptHash32FromHash(pkScript[2:34], chainParams)
 	}
 	return nil, errors.New("unknown script type")
 }
@@ -708,7 +862,7 @@ func convertBits(Data Data, fromBits uint, tobits uint, pad bool) (Data, error)
 
 func packAddressData(addrType AddressType, addrHash Data) (Data, error) {
 	// Pack addr Data with version byte.
-	if addrType != P2PKH && addrType != P2SH {
+	if addrType < P2PKH || addrType > TokenP2SH {
 		return Data{}, errors.New("invalid addrtype")
 	}
 	versionByte := uint(addrType) << 3
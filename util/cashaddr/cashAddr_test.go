@@ -310,3 +310,82 @@ func TestCashPayToAddrScript(t *testing.T) {
 		t.Errorf("the pkScript: %v is not p2sh script", p2pkhScript)
 	}
 }
+
+func TestTokenAwareAddress(t *testing.T) {
+	addr, err := NewCashAddressTokenPubKeyHash(dataElement, &model.MainNetParams)
+	if err != nil {
+		t.Error(err)
+	}
+	if addr.String() != "bitcoincash:zr95sy3j9xwd2ap32xkykttr4cvcu7as4yg2l8d0rh" || !addr.IsTokenAware() {
+		t.Errorf("token-aware p2pkh address %s encoding error", addr.String())
+	}
+
+	addr1, err := NewCashAddressTokenScriptHashFromHash(dataElement2, &model.MainNetParams)
+	if err != nil {
+		t.Error(err)
+	}
+	if addr1.String() != "bitcoincash:rpm2qsznhks23z7629mms6s4cwef74vcwv59yeyr7n" || !addr1.IsTokenAware() {
+		t.Errorf("token-aware p2sh address %s encoding error", addr1.String())
+	}
+
+	for _, str := range []string{addr.String(), addr1.String()} {
+		decoded, err := DecodeAddress(str, &model.MainNetParams)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if decoded.String() != str {
+			t.Errorf("address %s decoded as %s", str, decoded.String())
+		}
+	}
+
+	_, _, typ, err := CheckDecodeCashAddress(addr1.String())
+	if err != nil || typ != TokenP2SH || !typ.IsScriptHash() || !typ.IsTokenAware() {
+		t.Errorf("address %s should decode as a token-aware p2sh address", addr1.String())
+	}
+}
+
+func TestCashAddressScriptHash32(t *testing.T) {
+	addr, err := NewCashAddressScriptHash32(dataElement2, &model.MainNetParams)
+	if err != nil {
+		t.Error(err)
+	}
+	if addr.String() != "bitcoincash:pv0j0k2agpkn3eandjgmatagkur7xvq5tf88hkl2cy2w35gq5y0tk96kk6auc" {
+		t.Errorf("p2sh32 address %s encoding error", addr.String())
+	}
+
+	addr1, err := NewCashAddressTokenScriptHash32FromHash(addr.ScriptAddress(), &model.MainNetParams)
+	if err != nil {
+		t.Error(err)
+	}
+	if addr1.String() != "bitcoincash:rv0j0k2agpkn3eandjgmatagkur7xvq5tf88hkl2cy2w35gq5y0tkhf2hru9n" {
+		t.Errorf("token-aware p2sh32 address %s encoding error", addr1.String())
+	}
+
+	decoded, err := DecodeAddress(addr.String(), &model.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*CashAddressScriptHash32); !ok || decoded.String() != addr.String() {
+		t.Errorf("address %s decoded as %s", addr.String(), decoded.String())
+	}
+
+	pkScript, err := CashPayToAddrScript(addr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !(len(pkScript) == 1+1+32+1 && pkScript[0] == 0xaa && pkScript[1] == 0x20 && pkScript[34] == 0x87) {
+		t.Errorf("the pkScript: %v is not p2sh32 script", pkScript)
+	}
+	extracted, err := ExtractPkScriptAddrs(pkScript, &model.MainNetParams)
+	if err != nil {
+		t.Error(err)
+	}
+	if extracted.String() != addr.String() {
+		t.Errorf("p2sh32Addr:%s parse error", extracted.String())
+	}
+
+	if _, err := NewCashAddressScriptHash32FromHash(dataElement2, &model.MainNetParams); err == nil {
+		t.Errorf("a 20-byte hash should not make a p2sh32 address")
+	}
+}
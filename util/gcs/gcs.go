@@ -0,0 +1,257 @@
+// Package gcs implements the Golomb-coded sets of BIP158, a compact
+// probabilistic structure to test whether an item is in a set.
+package gcs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sort"
+
+	"github.com/copernet/copernicus/util"
+)
+
+// KeySize is the size of the SipHash key used to hash the items of a filter.
+const KeySize = 16
+
+var (
+	// ErrNTooBig is returned when a filter would hold more items than the
+	// 32 bits of N allow.
+	ErrNTooBig = errors.New("N is too big to fit in uint32")
+
+	// ErrPTooBig is returned when the Golomb-Rice parameter is too big for
+	// the remainders to fit in 64 bits.
+	ErrPTooBig = errors.New("P is too big to fit in uint64")
+
+	// errBadFilter is returned when the encoded items of a filter end
+	// unexpectedly.
+	errBadFilter = errors.New("filter data is truncated")
+)
+
+// Filter is a Golomb-coded set of N items hashed into [0, N*M) and encoded
+// with the Golomb-Rice parameter P.
+type Filter struct {
+	n    uint32
+	p    uint8
+	m    uint64
+	data []byte
+}
+
+// hashToRange hashes data with SipHash-2-4 and maps the result uniformly
+// into [0, f).
+func hashToRange(k0, k1 uint64, data []byte, f uint64) uint64 {
+	hash := util.NewSipHasher(k0, k1).Write(data).Finalize()
+	hi, _ := bits.Mul64(hash, f)
+	return hi
+}
+
+func sipKeys(key [KeySize]byte) (uint64, uint64) {
+	return binary.LittleEndian.Uint64(key[0:8]), binary.LittleEndian.Uint64(key[8:16])
+}
+
+// BuildGCSFilter builds a filter of the items of data, which must not hold
+// duplicates, with the Golomb-Rice parameter P and the inverse false positive
+// rate M.
+func BuildGCSFilter(P uint8, M uint64, key [KeySize]byte, data [][]byte) (*Filter, error) {
+	if uint64(len(data)) >= 1<<32 {
+		return nil, ErrNTooBig
+	}
+	if P > 32 {
+		return nil, ErrPTooBig
+	}
+
+	f := &Filter{
+		n: uint32(len(data)),
+		p: P,
+		m: M,
+	}
+	if f.n == 0 {
+		return f, nil
+	}
+
+	k0, k1 := sipKeys(key)
+	modulus := uint64(f.n) * M
+	values := make([]uint64, 0, len(data))
+	for _, d := range data {
+		values = append(values, hashToRange(k0, k1, d, modulus))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	w := &bitWriter{}
+	var last uint64
+	for _, v := range values {
+		delta := v - last
+		last = v
+		// quotient in unary, then the remainder on P bits
+		for q := delta >> P; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, P)
+	}
+	f.data = w.bytes()
+	return f, nil
+}
+
+// FromBytes returns the filter of N items whose Golomb-Rice encoding is d.
+func FromBytes(N uint32, P uint8, M uint64, d []byte) (*Filter, error) {
+	if P > 32 {
+		return nil, ErrPTooBig
+	}
+	data := make([]byte, len(d))
+	copy(data, d)
+	return &Filter{n: N, p: P, m: M, data: data}, nil
+}
+
+// FromNBytes returns the filter serialized as its number of items followed
+// by their encoding, as returned by NBytes.
+func FromNBytes(P uint8, M uint64, d []byte) (*Filter, error) {
+	r := bytes.NewReader(d)
+	n, err := util.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if n >= 1<<32 {
+		return nil, ErrNTooBig
+	}
+	return FromBytes(uint32(n), P, M, d[len(d)-r.Len():])
+}
+
+// N returns the number of items of the filter.
+func (f *Filter) N() uint32 {
+	return f.n
+}
+
+// Bytes returns the Golomb-Rice encoding of the items of the filter.
+func (f *Filter) Bytes() []byte {
+	data := make([]byte, len(f.data))
+	copy(data, f.data)
+	return data
+}
+
+// NBytes returns the number of items of the filter as a compact size,
+// followed by their encoding. It is the serialization of BIP158.
+func (f *Filter) NBytes() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 5+len(f.data)))
+	util.WriteVarInt(buf, uint64(f.n))
+	buf.Write(f.data)
+	return buf.Bytes()
+}
+
+// Match returns whether data is likely in the filter.
+func (f *Filter) Match(key [KeySize]byte, data []byte) (bool, error) {
+	return f.MatchAny(key, [][]byte{data})
+}
+
+// MatchAny returns whether any item of data is likely in the filter.
+func (f *Filter) MatchAny(key [KeySize]byte, data [][]byte) (bool, error) {
+	if f.n == 0 || len(data) == 0 {
+		return false, nil
+	}
+
+	k0, k1 := sipKeys(key)
+	modulus := uint64(f.n) * f.m
+	values := make([]uint64, 0, len(data))
+	for _, d := range data {
+		values = append(values, hashToRange(k0, k1, d, modulus))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	// walk both sorted lists at once
+	r := &bitReader{data: f.data}
+	var value uint64
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := r.readGolombRice(f.p)
+		if err != nil {
+			return false, err
+		}
+		value += delta
+		for len(values) > 0 && values[0] < value {
+			values = values[1:]
+		}
+		if len(values) == 0 {
+			return false, nil
+		}
+		if values[0] == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// bitWriter writes bits most significant first.
+type bitWriter struct {
+	data  []byte
+	nbits uint8
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.nbits == 0 {
+		w.data = append(w.data, 0)
+		w.nbits = 8
+	}
+	w.nbits--
+	if bit {
+		w.data[len(w.data)-1] |= 1 << w.nbits
+	}
+}
+
+// writeBits writes the n least significant bits of v.
+func (w *bitWriter) writeBits(v uint64, n uint8) {
+	for n > 0 {
+		n--
+		w.writeBit(v&(1<<n) != 0)
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	return w.data
+}
+
+// bitReader reads bits most significant first.
+type bitReader struct {
+	data  []byte
+	nbits uint8
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.nbits == 0 {
+		if len(r.data) == 0 {
+			return false, errBadFilter
+		}
+		r.nbits = 8
+	}
+	r.nbits--
+	bit := r.data[0]&(1<<r.nbits) != 0
+	if r.nbits == 0 {
+		r.data = r.data[1:]
+	}
+	return bit, nil
+}
+
+func (r *bitReader) readGolombRice(P uint8) (uint64, error) {
+	var q uint64
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		q++
+	}
+	v := q
+	for i := uint8(0); i < P; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v <<= 1
+		if bit {
+			v |= 1
+		}
+	}
+	return v, nil
+}
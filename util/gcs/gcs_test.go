@@ -0,0 +1,119 @@
+package gcs
+
+import (
+	"bytes"
+	"testing"
+)
+
+var (
+	testKey = [KeySize]byte{0x4c, 0xb1, 0xab, 0x12, 0x57, 0x62, 0x1e, 0x41,
+		0x3b, 0x8b, 0x0e, 0x26, 0x64, 0x8d, 0x4a, 0x15}
+
+	contents = [][]byte{
+		[]byte("Alex"),
+		[]byte("Bob"),
+		[]byte("Charlie"),
+		[]byte("Dick"),
+		[]byte("Ed"),
+		[]byte("Frank"),
+		[]byte("George"),
+		[]byte("Harry"),
+		[]byte("Ilya"),
+		[]byte("John"),
+		[]byte("Kevin"),
+		[]byte("Larry"),
+		[]byte("Michael"),
+		[]byte("Nate"),
+		[]byte("Owen"),
+		[]byte("Paul"),
+		[]byte("Quentin"),
+	}
+)
+
+// TestGCSFilterBuild builds a filter and checks that every item matches.
+func TestGCSFilterBuild(t *testing.T) {
+	filter, err := BuildGCSFilter(19, 784931, testKey, contents)
+	if err != nil {
+		t.Fatalf("Filter build failed: %s", err.Error())
+	}
+	if filter.N() != uint32(len(contents)) {
+		t.Errorf("Filter N: want %d, got %d", len(contents), filter.N())
+	}
+	for _, item := range contents {
+		match, err := filter.Match(testKey, item)
+		if err != nil || !match {
+			t.Errorf("Filter didn't match %s: %v", item, err)
+		}
+	}
+	match, err := filter.MatchAny(testKey, [][]byte{[]byte("Nobody"), []byte("Ilya")})
+	if err != nil || !match {
+		t.Errorf("Filter didn't match any of a list holding Ilya: %v", err)
+	}
+}
+
+// TestGCSFilterMiss checks that items out of the filter are rejected, with
+// a false positive rate far below the one of a P of 19.
+func TestGCSFilterMiss(t *testing.T) {
+	filter, err := BuildGCSFilter(19, 784931, testKey, contents)
+	if err != nil {
+		t.Fatalf("Filter build failed: %s", err.Error())
+	}
+	matches := 0
+	for i := 0; i < 10000; i++ {
+		item := []byte{'x', byte(i), byte(i >> 8)}
+		match, err := filter.Match(testKey, item)
+		if err != nil {
+			t.Fatalf("Filter match failed: %v", err)
+		}
+		if match {
+			matches++
+		}
+	}
+	if matches > 1 {
+		t.Errorf("Filter matched %d items it does not hold", matches)
+	}
+
+	// the key is part of the filter
+	otherKey := testKey
+	otherKey[0]++
+	match, err := filter.Match(otherKey, contents[0])
+	if err != nil || match {
+		t.Errorf("Filter matched with the wrong key: %v", err)
+	}
+}
+
+// TestGCSFilterSerialize checks the filter serialized with its N can be read
+// back.
+func TestGCSFilterSerialize(t *testing.T) {
+	filter, err := BuildGCSFilter(19, 784931, testKey, contents)
+	if err != nil {
+		t.Fatalf("Filter build failed: %s", err.Error())
+	}
+	filter2, err := FromNBytes(19, 784931, filter.NBytes())
+	if err != nil {
+		t.Fatalf("Filter copy failed: %s", err.Error())
+	}
+	if filter2.N() != filter.N() || !bytes.Equal(filter2.Bytes(), filter.Bytes()) {
+		t.Errorf("Filter copy differs: %x, %x", filter2.NBytes(), filter.NBytes())
+	}
+	match, err := filter2.Match(testKey, []byte("Nate"))
+	if err != nil || !match {
+		t.Errorf("Filter copy didn't match Nate: %v", err)
+	}
+
+	// an empty filter matches nothing
+	empty, err := BuildGCSFilter(19, 784931, testKey, nil)
+	if err != nil || !bytes.Equal(empty.NBytes(), []byte{0}) {
+		t.Fatalf("Empty filter build failed: %v, %x", err, empty.NBytes())
+	}
+	match, err = empty.Match(testKey, contents[0])
+	if err != nil || match {
+		t.Errorf("Empty filter matched: %v", err)
+	}
+
+	// a truncated filter is detected
+	truncated, _ := FromBytes(filter.N(), 19, 784931, filter.Bytes()[:4])
+	if _, err := truncated.Match(testKey, []byte("Quentin")); err == nil {
+		t.Errorf("Truncated filter match succeeded")
+	}
+}